@@ -0,0 +1,338 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package checkpoint contains commands to inspect and rewind the
+// checkpoints that record the progress of a target schema.
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer/sequtil"
+	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// services contains the staging and target services needed by the
+// subcommands.
+type services struct {
+	Checkpoints *checkpoint.Checkpoints
+	Leases      types.Leases
+	StagingPool *types.StagingPool
+	Stagers     types.Stagers
+	TargetPool  *types.TargetPool
+	Watchers    types.Watchers
+}
+
+// Command returns the checkpoint command and its subcommands.
+func Command() *cobra.Command {
+	cfg := &Config{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "inspect or rewind checkpoints",
+		Long: `These commands operate on the checkpoints that record how far the
+data for a target schema has been applied. A group of checkpoints is named
+after the target schema that it applies to.`,
+		Use: "checkpoint",
+	}
+	cfg.Bind(cmd.PersistentFlags())
+	cmd.AddCommand(
+		listCommand(cfg),
+		rewindCommand(cfg),
+		showCommand(cfg),
+	)
+	return cmd
+}
+
+func listCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "list checkpoint groups",
+		Use:   "list",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			svc, err := open(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			names, err := svc.Checkpoints.Groups(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "GROUP\tCOMMITTED\tPROPOSED\tPARTITIONS\tPENDING")
+			for _, name := range names {
+				info, err := svc.Checkpoints.Inspect(ctx, name)
+				if err != nil {
+					return err
+				}
+				if info == nil {
+					continue
+				}
+				pending := 0
+				for _, part := range info.Partitions {
+					pending += part.Pending
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n",
+					name.Raw(), formatTime(info.Range.Min()), formatTime(info.Range.Max()),
+					len(info.Partitions), pending)
+			}
+			return errors.WithStack(w.Flush())
+		},
+	}
+}
+
+func rewindCommand(cfg *Config) *cobra.Command {
+	var idle time.Duration
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(2),
+		Short: "rewind a group so that data after the given time will be applied again",
+		Long: `This command moves the committed checkpoint for a target schema back to
+the given time, which may be an RFC3339 timestamp or an HLC timestamp of the
+form nanos.logical. Staged mutations after that time are marked as unapplied,
+so that they will be applied again once Replicator is restarted. Mutations that
+have already been retired from staging, or that were written to the target
+without being staged, cannot be applied again.
+
+Replicator should be stopped before running this command. The command will
+refuse to run if a running instance holds the leases for the target schema or
+if a checkpoint for the schema was applied within the --idle duration.`,
+		Use: "rewind <schema> <time>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			ts, err := parseTime(args[1])
+			if err != nil {
+				return err
+			}
+			svc, err := open(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			group, err := svc.group(args[0])
+			if err != nil {
+				return err
+			}
+
+			info, err := svc.Checkpoints.Inspect(ctx, group.Name)
+			if err != nil {
+				return err
+			}
+			if info == nil {
+				return errors.Errorf("no checkpoints found for %s", group.Name)
+			}
+			if hlc.Compare(ts, info.Range.Min()) >= 0 {
+				return errors.Errorf("%s cannot be rewound to %s, since its committed time is %s",
+					group.Name, formatTime(ts), formatTime(info.Range.Min()))
+			}
+
+			// Every mode of a running conveyor commits checkpoints as
+			// resolved timestamps arrive, so recent activity indicates
+			// that an instance is still operating on the group.
+			if idle > 0 {
+				for _, part := range info.Partitions {
+					if since := time.Since(part.AppliedAt); since < idle {
+						return errors.Errorf("%s appears to be in use by a running instance of "+
+							"Replicator, since a checkpoint was applied %s ago; "+
+							"stop all instances and try again", group.Name, since.Round(time.Second))
+					}
+				}
+			}
+
+			// Prevent a running core sequencer from operating on the
+			// tables while we're rewinding them.
+			lease, err := svc.Leases.Acquire(ctx, sequtil.LeaseNames(group)...)
+			if busy, ok := types.IsLeaseBusy(err); ok {
+				return errors.Errorf("%s is in use by a running instance of Replicator; "+
+					"stop all instances and try again after %s",
+					group.Name, busy.Expiration.Format(time.RFC3339))
+			} else if err != nil {
+				return err
+			}
+			defer lease.Release()
+			leaseCtx := lease.Context()
+
+			// Unapply the staged mutations and rewind the checkpoints
+			// in a single transaction, so that a failure cannot leave
+			// the two out of sync.
+			counts := make([]int64, len(group.Tables))
+			err = retry.Retry(leaseCtx, svc.StagingPool, func(ctx context.Context) error {
+				tx, err := svc.StagingPool.Begin(ctx)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer func() { _ = tx.Rollback(ctx) }()
+
+				for idx, table := range group.Tables {
+					stager, err := svc.Stagers.Get(ctx, table)
+					if err != nil {
+						return err
+					}
+					counts[idx], err = stager.Unapply(ctx, tx, ts)
+					if err != nil {
+						return err
+					}
+				}
+				if err := svc.Checkpoints.Rewind(ctx, tx, group.Name, ts); err != nil {
+					return err
+				}
+				return errors.WithStack(tx.Commit(ctx))
+			})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for idx, table := range group.Tables {
+				if _, err := fmt.Fprintf(out, "%s: marked %d mutations as unapplied\n",
+					table, counts[idx]); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			_, err = fmt.Fprintf(out, "%s: rewound from %s to %s\n",
+				group.Name, formatTime(info.Range.Min()), formatTime(ts))
+			return errors.WithStack(err)
+		},
+	}
+	cmd.Flags().DurationVar(&idle, "idle", time.Minute,
+		"refuse to rewind a group whose checkpoints were applied more recently than this; "+
+			"set to zero to disable the check")
+	return cmd
+}
+
+func showCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "display the partitions within a group",
+		Use:   "show <schema>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			svc, err := open(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			name, err := svc.groupName(args[0])
+			if err != nil {
+				return err
+			}
+			info, err := svc.Checkpoints.Inspect(ctx, name)
+			if err != nil {
+				return err
+			}
+			if info == nil {
+				return errors.Errorf("no checkpoints found for %s", name)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "GROUP\t%s\n", name.Raw())
+			fmt.Fprintf(w, "COMMITTED\t%s\n", formatTime(info.Range.Min()))
+			fmt.Fprintf(w, "PROPOSED\t%s\n\n", formatTime(info.Range.Max()))
+			fmt.Fprintln(w, "PARTITION\tAPPLIED\tAPPLIED AT\tLATEST\tPENDING")
+			for _, part := range info.Partitions {
+				appliedAt := ""
+				if !part.AppliedAt.IsZero() {
+					appliedAt = part.AppliedAt.UTC().Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n",
+					part.Partition.Raw(), formatTime(part.Applied), appliedAt,
+					formatTime(part.Latest), part.Pending)
+			}
+			return errors.WithStack(w.Flush())
+		},
+	}
+}
+
+func open(ctx *stopper.Context, cfg *Config) (*services, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	return newServices(ctx, cfg)
+}
+
+// groupName returns the checkpoint group name that a conveyor uses
+// for the target schema.
+func (s *services) groupName(raw string) (ident.Ident, error) {
+	schema, err := s.expand(raw)
+	if err != nil {
+		return ident.Ident{}, err
+	}
+	return ident.New(schema.Raw()), nil
+}
+
+// group returns a TableGroup containing the tables in the target
+// schema.
+func (s *services) group(raw string) (*types.TableGroup, error) {
+	schema, err := s.expand(raw)
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.Watchers.Get(schema)
+	if err != nil {
+		return nil, err
+	}
+	ret := &types.TableGroup{
+		Enclosing: schema,
+		Name:      ident.New(schema.Raw()),
+	}
+	for tbl := range w.Get().Columns.Keys() {
+		ret.Tables = append(ret.Tables, tbl)
+	}
+	if len(ret.Tables) == 0 {
+		return nil, errors.Errorf("no tables found in %s", schema)
+	}
+	return ret, nil
+}
+
+func (s *services) expand(raw string) (ident.Schema, error) {
+	schema, err := ident.ParseSchema(raw)
+	if err != nil {
+		return ident.Schema{}, err
+	}
+	return s.TargetPool.Product.ExpandSchema(schema)
+}
+
+// parseTime accepts either an RFC3339 timestamp or an HLC timestamp.
+func parseTime(s string) (hlc.Time, error) {
+	if strings.Contains(s, "T") {
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return hlc.Zero(), errors.Wrapf(err, "could not parse time %q", s)
+		}
+		return hlc.From(ts), nil
+	}
+	return hlc.Parse(s)
+}
+
+// formatTime prints an HLC time with its wall-clock equivalent.
+func formatTime(ts hlc.Time) string {
+	if ts == hlc.Zero() {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s)", ts, time.Unix(0, ts.Nanos()).UTC().Format(time.RFC3339Nano))
+}
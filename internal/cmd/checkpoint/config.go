@@ -0,0 +1,53 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package checkpoint
+
+import (
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/spf13/pflag"
+)
+
+// Config contains the settings shared by the checkpoint subcommands.
+// The staging and schema-watch behaviors use their default values.
+type Config struct {
+	SchemaWatch schemawatch.Config
+	Stage       stage.Config
+	Staging     sinkprod.StagingConfig
+	Target      sinkprod.TargetConfig
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.Staging.Bind(f)
+	c.Target.Bind(f)
+}
+
+// Preflight ensures that the configuration is valid.
+func (c *Config) Preflight() error {
+	if err := c.SchemaWatch.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Stage.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Staging.Preflight(); err != nil {
+		return err
+	}
+	return c.Target.Preflight()
+}
@@ -0,0 +1,71 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package checkpoint
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
+	"github.com/cockroachdb/replicator/internal/staging/leases"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/staging/version"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+)
+
+// Injectors from injector.go:
+
+// newServices constructs the services needed to inspect and rewind
+// checkpoints.
+func newServices(ctx *stopper.Context, config *Config) (*services, error) {
+	stagingConfig := &config.Staging
+	diagnostics := diag.New(ctx)
+	targetConfig := &config.Target
+	stagingPool, err := sinkprod.ProvideStagingPool(ctx, stagingConfig, diagnostics, targetConfig)
+	if err != nil {
+		return nil, err
+	}
+	stagingSchema, err := sinkprod.ProvideStagingDB(ctx, stagingConfig, stagingPool)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	stageConfig := &config.Stage
+	stagers := stage.ProvideFactory(stageConfig, stagingPool, stagingSchema, ctx)
+	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	checker := version.ProvideChecker(stagingPool, memoMemo)
+	targetPool, err := sinkprod.ProvideTargetPool(ctx, checker, targetConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	schemawatchConfig := &config.SchemaWatch
+	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	watchers, err := schemawatch.ProvideFactory(ctx, schemawatchConfig, targetPool, diagnostics, backup)
+	if err != nil {
+		return nil, err
+	}
+	checkpointServices := &services{
+		Checkpoints: checkpoints,
+		Leases:      typesLeases,
+		StagingPool: stagingPool,
+		Stagers:     stagers,
+		TargetPool:  targetPool,
+		Watchers:    watchers,
+	}
+	return checkpointServices, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ddl contains a command to print the CREATE TABLE statements
+// that would be used to create missing target tables.
+package ddl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the ddl command.
+func Command() *cobra.Command {
+	var sourceConn, targetProduct string
+	var sourceSchema, targetSchema ident.Schema
+
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "print the DDL for creating target tables from a source schema",
+		Long: `This command reads the tables in a source schema and prints the
+CREATE TABLE statements that would be executed in the target database when
+the --createTables flag is set. No changes are made to either database.`,
+		Use: "ddl",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+
+			product, err := parseProduct(targetProduct)
+			if err != nil {
+				return err
+			}
+			if sourceSchema.Empty() {
+				return errors.New("no source schema specified")
+			}
+			if targetSchema.Empty() {
+				targetSchema = sourceSchema
+			}
+			targetSchema, err = product.ExpandSchema(targetSchema)
+			if err != nil {
+				return err
+			}
+
+			source, err := stdpool.OpenTarget(ctx, sourceConn)
+			if err != nil {
+				return errors.Wrap(err, "could not connect to source database")
+			}
+			tables, err := ddl.ReadSchema(ctx, source, sourceSchema)
+			if err != nil {
+				return err
+			}
+			if tables.Len() == 0 {
+				return errors.Errorf("no tables found in %s", sourceSchema)
+			}
+
+			// Print the tables in a stable order.
+			var names []ident.Table
+			for tbl := range tables.Keys() {
+				names = append(names, tbl)
+			}
+			slices.SortFunc(names, func(a, b ident.Table) int {
+				return strings.Compare(a.Raw(), b.Raw())
+			})
+
+			out := cmd.OutOrStdout()
+			for _, tbl := range names {
+				cols := tables.GetZero(tbl)
+				targetTbl := ident.NewTable(targetSchema, tbl.Table())
+				targetCols, err := ddl.Columns(source.Product, cols)
+				if err != nil {
+					return errors.Wrapf(err, "could not derive schema for %s", tbl)
+				}
+				stmt, err := ddl.CreateTable(product, targetTbl, targetCols)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(out, "%s;\n\n", stmt); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&sourceConn, "sourceConn", "",
+		"the source database's connection string")
+	f.Var(ident.NewSchemaFlag(&sourceSchema), "sourceSchema",
+		"the schema in the source database to read")
+	f.StringVar(&targetProduct, "targetProduct", types.ProductCockroachDB.String(),
+		"the target database product [ CockroachDB, MariaDB, MySQL, Oracle, PostgreSQL, SQLite ]")
+	f.Var(ident.NewSchemaFlag(&targetSchema), "targetSchema",
+		"the schema in the target database; defaults to the source schema")
+	return cmd
+}
+
+// parseProduct returns the product whose name matches the string.
+func parseProduct(name string) (types.Product, error) {
+	for p := types.ProductCockroachDB; p <= types.ProductSQLite; p++ {
+		if strings.EqualFold(p.String(), name) {
+			return p, nil
+		}
+	}
+	return types.ProductUnknown, errors.Errorf("unknown product %q", name)
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package delay contains commands to inspect and adjust the override
+// that controls how far a delayed replica has been advanced.
+package delay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// services contains the staging services needed by the subcommands.
+type services struct {
+	Memo        types.Memo
+	StagingPool *types.StagingPool
+}
+
+// Command returns the delay command and its subcommands.
+func Command() *cobra.Command {
+	cfg := &Config{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "adjust the progress of a delayed replica",
+		Long: `These commands operate on a replica that is running with the
+--replicaDelay flag. An operator may fast-forward the replica to apply data
+up to a given time, or stop the replica short of a given time, such as the
+start of an incident in the source cluster. Running instances of Replicator
+will observe the change within the --replicaDelayRefresh period.`,
+		Use: "delay",
+	}
+	cfg.Bind(cmd.PersistentFlags())
+	cmd.AddCommand(
+		clearCommand(cfg),
+		fastForwardCommand(cfg),
+		showCommand(cfg),
+		stopAtCommand(cfg),
+	)
+	return cmd
+}
+
+func clearCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "remove any override, restoring the configured delay",
+		Use:   "clear",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return update(cmd, cfg, func(o *delay.Override) {
+				*o = delay.Override{}
+			})
+		},
+	}
+}
+
+func fastForwardCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "apply data up to the given RFC3339 time",
+		Use:   "fastforward <time>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ts, err := parseTime(args[0])
+			if err != nil {
+				return err
+			}
+			return update(cmd, cfg, func(o *delay.Override) {
+				o.FastForward = ts
+			})
+		},
+	}
+}
+
+func showCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "display the current override",
+		Use:   "show",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			svc, group, err := open(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			o, err := delay.LoadOverride(ctx, svc.Memo, svc.StagingPool, group)
+			if err != nil {
+				return err
+			}
+			return printOverride(cmd, group, o)
+		},
+	}
+}
+
+func stopAtCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "do not apply data at or after the given RFC3339 time",
+		Use:   "stopat <time>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ts, err := parseTime(args[0])
+			if err != nil {
+				return err
+			}
+			return update(cmd, cfg, func(o *delay.Override) {
+				o.StopAt = ts
+			})
+		},
+	}
+}
+
+// open constructs the staging services and returns the name of the
+// table group that the conveyor uses for the target schema.
+func open(ctx *stopper.Context, cfg *Config) (*services, ident.Ident, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, ident.Ident{}, err
+	}
+	pool, err := stdpool.OpenTarget(ctx, cfg.Target.Conn)
+	if err != nil {
+		return nil, ident.Ident{}, errors.Wrap(err, "could not connect to target database")
+	}
+	schema, err := pool.Product.ExpandSchema(cfg.TargetSchema)
+	if err != nil {
+		return nil, ident.Ident{}, err
+	}
+	svc, err := newServices(ctx, cfg)
+	if err != nil {
+		return nil, ident.Ident{}, err
+	}
+	return svc, ident.New(schema.Raw()), nil
+}
+
+// update applies the function to the stored override.
+func update(cmd *cobra.Command, cfg *Config, fn func(o *delay.Override)) error {
+	// main.go provides a stopper.
+	ctx := stopper.From(cmd.Context())
+	svc, group, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	o, err := delay.LoadOverride(ctx, svc.Memo, svc.StagingPool, group)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		o = &delay.Override{}
+	}
+	fn(o)
+	if err := delay.StoreOverride(ctx, svc.Memo, svc.StagingPool, group, o); err != nil {
+		return err
+	}
+	return printOverride(cmd, group, o)
+}
+
+func parseTime(s string) (time.Time, error) {
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "could not parse time %q", s)
+	}
+	return ts, nil
+}
+
+func printOverride(cmd *cobra.Command, group ident.Ident, o *delay.Override) error {
+	out := cmd.OutOrStdout()
+	if o.IsZero() {
+		_, err := fmt.Fprintf(out, "%s: no override\n", group)
+		return errors.WithStack(err)
+	}
+	if !o.FastForward.IsZero() {
+		if _, err := fmt.Fprintf(out, "%s: fast-forward to %s\n",
+			group, o.FastForward.UTC().Format(time.RFC3339Nano)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if !o.StopAt.IsZero() {
+		if _, err := fmt.Fprintf(out, "%s: stop at %s\n",
+			group, o.StopAt.UTC().Format(time.RFC3339Nano)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
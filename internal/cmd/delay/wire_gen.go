@@ -0,0 +1,41 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package delay
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+)
+
+// Injectors from injector.go:
+
+// newServices constructs the staging services needed to read and
+// write delay overrides.
+func newServices(ctx *stopper.Context, config *Config) (*services, error) {
+	stagingConfig := &config.Staging
+	diagnostics := diag.New(ctx)
+	targetConfig := &config.Target
+	stagingPool, err := sinkprod.ProvideStagingPool(ctx, stagingConfig, diagnostics, targetConfig)
+	if err != nil {
+		return nil, err
+	}
+	stagingSchema, err := sinkprod.ProvideStagingDB(ctx, stagingConfig, stagingPool)
+	if err != nil {
+		return nil, err
+	}
+	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	delayServices := &services{
+		Memo:        memoMemo,
+		StagingPool: stagingPool,
+	}
+	return delayServices, nil
+}
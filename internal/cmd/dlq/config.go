@@ -0,0 +1,78 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dlq
+
+import (
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Config contains the settings shared by the dlq subcommands. Schema
+// changes are never made when replaying entries, so the DDL
+// configuration is not bound to any flags.
+type Config struct {
+	DDL          ddl.Config
+	DLQ          dlq.Config
+	SchemaWatch  schemawatch.Config
+	Script       script.Config
+	Staging      sinkprod.StagingConfig
+	Target       sinkprod.TargetConfig
+	TargetSchema ident.Schema
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.DLQ.Bind(f)
+	c.SchemaWatch.Bind(f)
+	c.Script.Bind(f)
+	c.Staging.Bind(f)
+	c.Target.Bind(f)
+	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
+		"the SQL database schema in the target cluster that contains the dlq table")
+}
+
+// Preflight ensures that the configuration is valid.
+func (c *Config) Preflight() error {
+	if err := c.DDL.Preflight(); err != nil {
+		return err
+	}
+	if err := c.DLQ.Preflight(); err != nil {
+		return err
+	}
+	if err := c.SchemaWatch.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Script.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Staging.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Target.Preflight(); err != nil {
+		return err
+	}
+	if c.TargetSchema.Empty() {
+		return errors.New("no target schema specified")
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dlq contains commands to inspect, purge, and replay the
+// entries in a dead-letter queue table.
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Command returns the dlq command and its subcommands.
+func Command() *cobra.Command {
+	cfg := &Config{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "inspect, purge, or replay dead-letter queue entries",
+		Long: `These commands operate on the dead-letter queue table in the target
+schema. Filtering entries by table and recording the outcome of a replay
+require the optional target_table, replay_status, and replay_error columns
+that are present in the suggested DLQ schemas. Deletions are replayed using
+the optional data_key column or, if it is absent, the before data.`,
+		Use: "dlq",
+	}
+	cfg.Bind(cmd.PersistentFlags())
+	cmd.AddCommand(
+		listCommand(cfg),
+		purgeCommand(cfg),
+		replayCommand(cfg),
+		showCommand(cfg),
+	)
+	return cmd
+}
+
+func listCommand(cfg *Config) *cobra.Command {
+	var filter filterFlags
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "list dead-letter queue entries",
+		Use:   "list",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			q, err := openQueue(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			f, err := filter.build(q.Table().Schema())
+			if err != nil {
+				return err
+			}
+			entries, err := q.List(ctx, f)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "EVENT\tNAME\tTIME\tTABLE\tSTATUS\tCAUSE\tERROR")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					e.Event, e.Name, sourceTime(e), e.Table, e.Status, e.Cause, e.Error)
+			}
+			return errors.WithStack(w.Flush())
+		},
+	}
+	filter.bind(cmd.Flags())
+	cmd.Flags().IntVar(&filter.limit, "limit", 100,
+		"the maximum number of entries to list; 0 for no limit")
+	return cmd
+}
+
+func purgeCommand(cfg *Config) *cobra.Command {
+	var all bool
+	var filter filterFlags
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "delete dead-letter queue entries",
+		Use:   "purge",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			if filter.empty() && !all {
+				return errors.New("refusing to purge all entries without --all")
+			}
+			q, err := openQueue(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			f, err := filter.build(q.Table().Schema())
+			if err != nil {
+				return err
+			}
+			count, err := q.Purge(ctx, f)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "purged %d entries\n", count)
+			return errors.WithStack(err)
+		},
+	}
+	filter.bind(cmd.Flags())
+	cmd.Flags().BoolVar(&all, "all", false, "allow all entries to be purged if no filter is set")
+	return cmd
+}
+
+func replayCommand(cfg *Config) *cobra.Command {
+	var defaultTable string
+	var filter filterFlags
+	opts := &dlq.ReplayOptions{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "apply dead-letter queue entries to the target",
+		Long: `This command sends the selected entries through the userscript, merge,
+and apply logic that a running Replicator would use. Entries that are applied
+are marked as replayed and entries that fail are marked as failed. Only pending
+entries are replayed, unless the --status flag is set.`,
+		Use: "replay",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			if err := cfg.Preflight(); err != nil {
+				return err
+			}
+			p, err := newPipeline(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			schema, err := p.TargetPool.Product.ExpandSchema(cfg.TargetSchema)
+			if err != nil {
+				return err
+			}
+			q, err := dlq.OpenQueue(ctx, p.TargetPool, ident.NewTable(schema, cfg.DLQ.TableName))
+			if err != nil {
+				return err
+			}
+			if defaultTable != "" {
+				opts.Table, _, err = ident.ParseTableRelative(defaultTable, schema)
+				if err != nil {
+					return err
+				}
+			}
+			if filter.status == "" {
+				filter.status = dlq.StatusPending
+			}
+			f, err := filter.build(schema)
+			if err != nil {
+				return err
+			}
+			entries, err := q.List(ctx, f)
+			if err != nil {
+				return err
+			}
+			acc, err := p.acceptor(ctx, schema)
+			if err != nil {
+				return err
+			}
+			res, err := q.Replay(ctx, acc, p.Watchers, entries, opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			verb := "replayed"
+			if opts.DryRun {
+				verb = "would replay"
+			}
+			if _, err := fmt.Fprintf(out, "%s %d entries; %d failed\n",
+				verb, len(res.Replayed), len(res.Failed)); err != nil {
+				return errors.WithStack(err)
+			}
+			for _, e := range res.Failed {
+				if _, err := fmt.Fprintf(out, "%s: %s\n", e.Event, e.Error); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	filter.bind(f)
+	f.IntVar(&filter.limit, "limit", 0,
+		"the maximum number of entries to replay; 0 for no limit")
+	f.IntVar(&opts.BatchSize, "batchSize", 100,
+		"the maximum number of entries to apply in a single transaction")
+	f.StringVar(&defaultTable, "defaultTable", "",
+		"the target table for entries that do not record one")
+	f.BoolVar(&opts.DryRun, "dryRun", false,
+		"apply entries in transactions that are rolled back")
+	f.IntVar(&opts.Parallelism, "parallelism", 4,
+		"the number of tables to replay concurrently")
+	return cmd
+}
+
+func showCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.MinimumNArgs(1),
+		Short: "print dead-letter queue entries as JSON",
+		Use:   "show <event> [event ...]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			q, err := openQueue(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			entries, err := q.List(ctx, &dlq.Filter{Events: args})
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return errors.New("no matching entries")
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			for _, e := range entries {
+				if err := enc.Encode(e); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// filterFlags are shared by the subcommands that select entries.
+type filterFlags struct {
+	events []string
+	limit  int
+	name   string
+	since  string
+	status string
+	table  string
+	until  string
+}
+
+func (f *filterFlags) bind(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&f.events, "event", nil, "select specific entries")
+	flags.StringVar(&f.name, "name", "", "the name of the dead-letter queue")
+	flags.StringVar(&f.since, "since", "",
+		"select entries with a source time at or after this RFC 3339 timestamp")
+	flags.StringVar(&f.status, "status", "",
+		fmt.Sprintf("select entries with a replay status [ %s, %s, %s ]",
+			dlq.StatusPending, dlq.StatusReplayed, dlq.StatusFailed))
+	flags.StringVar(&f.table, "table", "", "select entries for a target table")
+	flags.StringVar(&f.until, "until", "",
+		"select entries with a source time before this RFC 3339 timestamp")
+}
+
+// empty returns true if no entries would be filtered out.
+func (f *filterFlags) empty() bool {
+	return len(f.events) == 0 && f.name == "" && f.since == "" &&
+		f.status == "" && f.table == "" && f.until == ""
+}
+
+// build returns a Filter. Table names are resolved relative to the
+// target schema.
+func (f *filterFlags) build(schema ident.Schema) (*dlq.Filter, error) {
+	ret := &dlq.Filter{
+		Events: f.events,
+		Limit:  f.limit,
+		Name:   f.name,
+		Status: f.status,
+	}
+	var err error
+	if f.since != "" {
+		if ret.Since, err = time.Parse(time.RFC3339Nano, f.since); err != nil {
+			return nil, errors.Wrap(err, "could not parse --since")
+		}
+	}
+	if f.until != "" {
+		if ret.Until, err = time.Parse(time.RFC3339Nano, f.until); err != nil {
+			return nil, errors.Wrap(err, "could not parse --until")
+		}
+	}
+	if f.table != "" {
+		if ret.Table, _, err = ident.ParseTableRelative(f.table, schema); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// openQueue connects only to the target database, since inspecting and
+// purging entries does not require the apply pipeline.
+func openQueue(ctx *stopper.Context, cfg *Config) (*dlq.Queue, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	pool, err := stdpool.OpenTarget(ctx, cfg.Target.Conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to target database")
+	}
+	schema, err := pool.Product.ExpandSchema(cfg.TargetSchema)
+	if err != nil {
+		return nil, err
+	}
+	return dlq.OpenQueue(ctx, pool, ident.NewTable(schema, cfg.DLQ.TableName))
+}
+
+// sourceTime formats the entry's source timestamp.
+func sourceTime(e *dlq.Entry) string {
+	return time.Unix(0, e.Time.Nanos()).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dlq
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/script"
+	"github.com/cockroachdb/replicator/internal/target/history"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+)
+
+// replayGroup names the table group used to bind the userscript. It
+// won't match any configureSource() call, since entries in the DLQ
+// have already been dispatched to a target table.
+var replayGroup = ident.New("replicator_dlq")
+
+// pipeline contains the services needed to send entries through the
+// same userscript, merge, and apply logic as a running Replicator.
+type pipeline struct {
+	Acceptor   *history.Acceptor
+	Script     *script.Sequencer
+	TargetPool *types.TargetPool
+	Watchers   types.Watchers
+}
+
+// acceptor returns an acceptor that applies any configureTable()
+// behaviors in the userscript before writing to the target tables.
+func (p *pipeline) acceptor(
+	ctx *stopper.Context, schema ident.Schema,
+) (types.MultiAcceptor, error) {
+	seq, err := p.Script.Wrap(ctx, direct{})
+	if err != nil {
+		return nil, err
+	}
+	acc, _, err := seq.Start(ctx, &sequencer.StartOptions{
+		Bounds:   &notify.Var[hlc.Range]{},
+		Delegate: types.OrderedAcceptorFrom(p.Acceptor, p.Watchers),
+		Group:    &types.TableGroup{Name: replayGroup, Enclosing: schema},
+	})
+	return acc, err
+}
+
+// direct is a trivial Sequencer that passes mutations straight to the
+// delegate acceptor, so that the userscript shim can be reused.
+type direct struct{}
+
+var _ sequencer.Sequencer = direct{}
+
+// Start implements [sequencer.Sequencer].
+func (direct) Start(
+	_ *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	stat := sequencer.NewStat(opts.Group, &ident.TableMap[hlc.Range]{})
+	return opts.Delegate, notify.VarOf(stat), nil
+}
@@ -0,0 +1,93 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package dlq
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/script"
+	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/staging/version"
+	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
+	"github.com/cockroachdb/replicator/internal/target/load"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+)
+
+// Injectors from injector.go:
+
+// newPipeline constructs the services needed to replay entries.
+func newPipeline(ctx *stopper.Context, config *Config) (*pipeline, error) {
+	targetConfig := &config.Target
+	stagingConfig := &config.Staging
+	diagnostics := diag.New(ctx)
+	stagingPool, err := sinkprod.ProvideStagingPool(ctx, stagingConfig, diagnostics, targetConfig)
+	if err != nil {
+		return nil, err
+	}
+	stagingSchema, err := sinkprod.ProvideStagingDB(ctx, stagingConfig, stagingPool)
+	if err != nil {
+		return nil, err
+	}
+	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	checker := version.ProvideChecker(stagingPool, memoMemo)
+	targetPool, err := sinkprod.ProvideTargetPool(ctx, checker, targetConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	targetStatements, err := sinkprod.ProvideStatementCache(ctx, targetConfig, targetPool, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := applycfg.ProvideConfigs(diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	ddlConfig := &config.DDL
+	schemawatchConfig := &config.SchemaWatch
+	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	watchers, err := schemawatch.ProvideFactory(ctx, schemawatchConfig, targetPool, diagnostics, backup)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig := &config.DLQ
+	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
+	loader, err := load.ProvideLoader(targetStatements, targetPool)
+	if err != nil {
+		return nil, err
+	}
+	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loader, targetStatements, targetPool, watchers)
+	scriptConfig := &config.Script
+	scriptLoader, err := script.ProvideLoader(ctx, configs, scriptConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	sequencer := script2.ProvideSequencer(scriptLoader, targetPool, watchers)
+	dlqPipeline := &pipeline{
+		Acceptor:   historyAcceptor,
+		Script:     sequencer,
+		TargetPool: targetPool,
+		Watchers:   watchers,
+	}
+	return dlqPipeline, nil
+}
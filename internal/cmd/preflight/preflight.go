@@ -98,8 +98,8 @@ func testTargetConnection(ctx *stopper.Context, connString string) error {
 		if result != 1 {
 			return errors.Errorf("SELECT 1 returned %d instead", result)
 		}
-	case types.ProductMariaDB, types.ProductMySQL:
-		log.Info("MySQL/MariaDB detected")
+	case types.ProductMariaDB, types.ProductMySQL, types.ProductSQLite:
+		log.Info("MySQL/MariaDB/SQLite detected")
 		log.Info("Testing basic query")
 		var result int
 		row := pool.DB.QueryRowContext(ctx, "SELECT 1")
@@ -143,9 +143,9 @@ func testStagingConnection(ctx *stopper.Context, connString string) error {
 	log.Info("connected to the database")
 
 	switch pool.Product {
-	case types.ProductCockroachDB:
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
 		var result int
-		log.Info("Cockroach DB detected")
+		log.Info("Cockroach/Postgres DB detected")
 		log.Info("Testing basic query")
 		row := pool.QueryRow(ctx, "SELECT 1")
 		if err := row.Scan(&result); err != nil {
@@ -0,0 +1,183 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package stagekeys contains commands to manage the keys that are used
+// to encrypt staged mutations.
+package stagekeys
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// services contains the staging and target services needed by the
+// subcommands.
+type services struct {
+	StagingPool *types.StagingPool
+	Stagers     types.Stagers
+	TargetPool  *types.TargetPool
+	Watchers    types.Watchers
+}
+
+// Command returns the stagekeys command and its subcommands.
+func Command() *cobra.Command {
+	cfg := &Config{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "manage staging encryption keys",
+		Long: `These commands operate on the data keys that encrypt the mutations staged
+for the tables in a target schema. The data keys are stored in the staging
+database, wrapped by the master keys provided by --stageEncryptionKeyFile.
+
+To rotate a master key, add the new key to the start of the key file, run
+the rewrap command for each target schema, and then remove the old key.
+
+To rotate the data keys, run the rotate command. Existing rows remain
+readable, since older data keys are retained. Running the reencrypt command
+afterwards rewrites the existing rows with the newest data key. The reencrypt
+command will also encrypt rows that were staged before encryption was
+enabled.`,
+		Use: "stagekeys",
+	}
+	cfg.Bind(cmd.PersistentFlags())
+	cmd.AddCommand(
+		reencryptCommand(cfg),
+		rewrapCommand(cfg),
+		rotateCommand(cfg),
+	)
+	return cmd
+}
+
+func reencryptCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "rewrite staged mutations with the newest data key",
+		Long: `This command rewrites the staged mutations for each table in the target
+schema so that they are encrypted with the newest data key. It may be run
+while Replicator is running, although mutations that are staged by an instance
+which has not yet observed a rotation may need to be re-encrypted again.`,
+		Use: "reencrypt <schema>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStager(cmd, cfg, args[0],
+				func(ctx *stopper.Context, table ident.Table, stager types.Stager) (string, error) {
+					count, err := stage.Reencrypt(ctx, stager)
+					return fmt.Sprintf("re-encrypted %d staged mutations", count), err
+				})
+		},
+	}
+}
+
+func rewrapCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "wrap the data keys with the first master key",
+		Use:   "rewrap <schema>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStager(cmd, cfg, args[0],
+				func(ctx *stopper.Context, table ident.Table, stager types.Stager) (string, error) {
+					count, err := stage.RewrapKeys(ctx, stager)
+					return fmt.Sprintf("re-wrapped %d data keys", count), err
+				})
+		},
+	}
+}
+
+func rotateCommand(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "create new data keys for newly-staged mutations",
+		Use:   "rotate <schema>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStager(cmd, cfg, args[0],
+				func(ctx *stopper.Context, table ident.Table, stager types.Stager) (string, error) {
+					id, err := stage.RotateKeys(ctx, stager)
+					return fmt.Sprintf("rotated to data key %d", id), err
+				})
+		},
+	}
+}
+
+// forEachStager invokes the callback with the Stager for each table in
+// the target schema and prints the message that it returns.
+func forEachStager(
+	cmd *cobra.Command,
+	cfg *Config,
+	raw string,
+	fn func(ctx *stopper.Context, table ident.Table, stager types.Stager) (string, error),
+) error {
+	// main.go provides a stopper.
+	ctx := stopper.From(cmd.Context())
+	svc, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	tables, err := svc.tables(raw)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	for _, table := range tables {
+		stager, err := svc.Stagers.Get(ctx, table)
+		if err != nil {
+			return err
+		}
+		msg, err := fn(ctx, table, stager)
+		if err != nil {
+			return errors.Wrap(err, table.String())
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", table, msg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func open(ctx *stopper.Context, cfg *Config) (*services, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	return newServices(ctx, cfg)
+}
+
+// tables returns the tables in the target schema.
+func (s *services) tables(raw string) ([]ident.Table, error) {
+	schema, err := ident.ParseSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+	schema, err = s.TargetPool.Product.ExpandSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.Watchers.Get(schema)
+	if err != nil {
+		return nil, err
+	}
+	var ret []ident.Table
+	for tbl := range w.Get().Columns.Keys() {
+		ret = append(ret, tbl)
+	}
+	if len(ret) == 0 {
+		return nil, errors.Errorf("no tables found in %s", schema)
+	}
+	return ret, nil
+}
@@ -167,6 +167,11 @@ func (c *clientConfig) createTables(ctx *stopper.Context, targetPool *types.Targ
 		}
 		bigType = "NUMBER(38)"
 
+	case types.ProductSQLite:
+		// Tables live in the primary database of the connection.
+		current = "main"
+		bigType = "INTEGER"
+
 	default:
 		return errors.Errorf(
 			"the demo subcommand does not support creating a target schema within %s; "+
@@ -253,11 +258,13 @@ func (c *clientConfig) newServer(
 	if err != nil {
 		return nil, err
 	}
+	stdlogical.AddAdminHandler(svr.GetServeMux(),
+		stdlogical.AdminHandler(svr, svr.GetAuthenticator()))
 	stdlogical.AddHandlers(svr.GetAuthenticator(), svr.GetServeMux(), svr.GetDiagnostics())
 	log.Infof("server listening on %s", svr.GetListener().Addr())
 
 	if c.metricsAddr != "" {
-		cancel, err := stdlogical.MetricsServer(trust.New(), c.metricsAddr, svr.GetDiagnostics())
+		cancel, err := stdlogical.MetricsServer(trust.New(), c.metricsAddr, svr.GetDiagnostics(), nil)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,269 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/httpauth"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// AdminSchema is the pseudo-schema that callers must be authorized for
+// in order to use the admin API.
+var AdminSchema = ident.MustSchema(ident.New("_"), ident.New("admin"))
+
+// A RangeStatus is the serializable form of an [hlc.Range].
+type RangeStatus struct {
+	Min hlc.Time `json:"min"`
+	Max hlc.Time `json:"max"`
+}
+
+// Status describes a Conveyor in the admin API.
+type Status struct {
+	Control   *Control               `json:"control"`
+	Kind      string                 `json:"kind"`
+	Mode      string                 `json:"mode"`
+	Resolving RangeStatus            `json:"resolving"`
+	Schema    string                 `json:"schema"`
+	Tables    map[string]RangeStatus `json:"tables"`
+}
+
+// Status returns a snapshot of the conveyor's state.
+func (c *Conveyor) Status() *Status {
+	mode, _ := c.mode.Get()
+	bounds, _ := c.resolvingRange.Get()
+	ret := &Status{
+		Control:   c.Control(),
+		Kind:      c.factory.kind,
+		Mode:      mode.String(),
+		Resolving: RangeStatus{Min: bounds.Min(), Max: bounds.Max()},
+		Schema:    c.target.Raw(),
+		Tables:    make(map[string]RangeStatus),
+	}
+	if c.stat == nil {
+		return ret
+	}
+	if stat, _ := c.stat.Get(); stat != nil {
+		for tbl, progress := range stat.Progress().All() {
+			ret.Tables[tbl.Raw()] = RangeStatus{Min: progress.Min(), Max: progress.Max()}
+		}
+	}
+	return ret
+}
+
+// registry tracks the conveyors created by a factory and all of its
+// kind-specific copies.
+type registry struct {
+	mu struct {
+		sync.RWMutex
+		conveyors ident.SchemaMap[*Conveyor]
+	}
+}
+
+func (r *registry) get(schema ident.Schema) (*Conveyor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.conveyors.Get(schema)
+}
+
+func (r *registry) put(schema ident.Schema, conveyor *Conveyor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mu.conveyors.Put(schema, conveyor)
+}
+
+// statuses returns the status of all known conveyors, ordered by
+// schema name.
+func (r *registry) statuses() []*Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ret := make([]*Status, 0, r.mu.conveyors.Len())
+	for _, conveyor := range r.mu.conveyors.All() {
+		ret = append(ret, conveyor.Status())
+	}
+	slices.SortFunc(ret, func(a, b *Status) int {
+		return strings.Compare(a.Schema, b.Schema)
+	})
+	return ret
+}
+
+// Handler returns an HTTP handler for the admin API. Changes made
+// through the API are recorded in the staging database, so that they
+// survive restarts and will be observed by other instances of
+// Replicator. The handler expects any path prefix to have been
+// stripped and serves the following endpoints:
+//
+//	GET  /conveyors                      list all conveyors
+//	GET  /conveyors/{schema}             show a single conveyor
+//	POST /conveyors/{schema}/pause       stop applying mutations
+//	POST /conveyors/{schema}/resume      resume applying mutations
+//	POST /conveyors/{schema}/mode/{mode} force a mode, or "auto" to clear
+//	GET  /userscript                     show the userscript generation
+//	POST /userscript/reload              reload the userscript
+//
+// A delayed replica cannot be forced into the best-effort or immediate
+// modes, since they would apply data that the delay is holding back.
+// Unlike the conveyor endpoints, a userscript reload affects only the
+// instance of Replicator that receives the request.
+func (c *Conveyors) Handler(auth types.Authenticator) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /conveyors", func(w http.ResponseWriter, req *http.Request) {
+		writeStatus(w, c.registry.statuses())
+	})
+	mux.HandleFunc("GET /conveyors/{schema}", c.withConveyor(
+		func(w http.ResponseWriter, req *http.Request, conveyor *Conveyor) {
+			writeStatus(w, conveyor.Status())
+		}))
+	mux.HandleFunc("POST /conveyors/{schema}/pause", c.withControl(
+		func(_ *http.Request, _ *Conveyor, ctl *Control) error {
+			ctl.Paused = true
+			return nil
+		}))
+	mux.HandleFunc("POST /conveyors/{schema}/resume", c.withControl(
+		func(_ *http.Request, _ *Conveyor, ctl *Control) error {
+			ctl.Paused = false
+			return nil
+		}))
+	mux.HandleFunc("POST /conveyors/{schema}/mode/{mode}", c.withControl(
+		func(req *http.Request, conveyor *Conveyor, ctl *Control) error {
+			raw := req.PathValue("mode")
+			if strings.EqualFold(raw, "auto") {
+				ctl.Mode = switcher.ModeUnknown
+				return nil
+			}
+			mode, err := switcher.ParseMode(raw)
+			if err != nil {
+				return err
+			}
+			if bypassesDelay(mode) && conveyor.delayed() {
+				return errors.Errorf("%s would bypass the replica delay", mode)
+			}
+			ctl.Mode = mode
+			return nil
+		}))
+	mux.HandleFunc("GET /userscript", c.withLoader(
+		func(w http.ResponseWriter, _ *http.Request, loader *script.Loader) {
+			writeStatus(w, loader.Status())
+		}))
+	mux.HandleFunc("POST /userscript/reload", c.withLoader(
+		func(w http.ResponseWriter, req *http.Request, loader *script.Loader) {
+			status, err := loader.Reload(req.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			log.Infof("userscript reloaded by admin API: generation=%d", status.Generation)
+			writeStatus(w, status)
+		}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ok, err := auth.Check(req.Context(), AdminSchema, httpauth.Token(req))
+		if err != nil {
+			log.WithError(err).Warn("could not authenticate request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+// withConveyor looks up the conveyor named by the schema path value.
+func (c *Conveyors) withConveyor(
+	fn func(w http.ResponseWriter, req *http.Request, conveyor *Conveyor),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		schema, err := ident.ParseSchema(req.PathValue("schema"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conveyor, ok := c.registry.get(schema)
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		fn(w, req, conveyor)
+	}
+}
+
+// withLoader returns a 404 response if no userscript has been
+// configured.
+func (c *Conveyors) withLoader(
+	fn func(w http.ResponseWriter, req *http.Request, loader *script.Loader),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var loader *script.Loader
+		if c.script != nil {
+			loader = c.script.Loader()
+		}
+		if loader == nil || !loader.Configured() {
+			http.NotFound(w, req)
+			return
+		}
+		fn(w, req, loader)
+	}
+}
+
+// withControl applies a modification to the conveyor's Control and
+// then stores it.
+func (c *Conveyors) withControl(
+	fn func(req *http.Request, conveyor *Conveyor, ctl *Control) error,
+) http.HandlerFunc {
+	return c.withConveyor(func(w http.ResponseWriter, req *http.Request, conveyor *Conveyor) {
+		// Errors returned from the callback are the caller's fault.
+		var fnErr error
+		next, err := conveyor.UpdateControl(req.Context(), func(ctl *Control) error {
+			fnErr = fn(req, conveyor, ctl)
+			return fnErr
+		})
+		if fnErr != nil {
+			http.Error(w, fnErr.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.WithError(err).Warnf("could not update controls for %s", conveyor.target)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("controls for %s set by admin API: mode=%s paused=%t",
+			conveyor.target, next.Mode, next.Paused)
+		writeStatus(w, conveyor.Status())
+	})
+}
+
+func writeStatus(w http.ResponseWriter, payload any) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.WithError(err).Warn("could not write admin response")
+	}
+}
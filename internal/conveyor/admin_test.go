@@ -0,0 +1,252 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/auth/reject"
+	"github.com/cockroachdb/replicator/internal/util/auth/trust"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlJSON(t *testing.T) {
+	r := require.New(t)
+
+	ctl := &Control{Mode: switcher.ModeImmediate, Paused: true, Updated: time.Unix(1, 2).UTC()}
+	data, err := json.Marshal(ctl)
+	r.NoError(err)
+	r.JSONEq(`{"mode":"ModeImmediate","paused":true,"updated":"1970-01-01T00:00:01.000000002Z"}`,
+		string(data))
+
+	var decoded Control
+	r.NoError(json.Unmarshal(data, &decoded))
+	r.True(ctl.Equal(&decoded))
+
+	r.Error(json.Unmarshal([]byte(`{"mode":"bogus"}`), &decoded))
+}
+
+func TestAdmin(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	tbl := ident.NewTable(schema, ident.New("tbl"))
+	group := &types.TableGroup{
+		Enclosing: schema,
+		Name:      ident.New(schema.Raw()),
+		Tables:    []ident.Table{tbl},
+	}
+
+	mem := &memo.Memory{}
+	factory := &Conveyors{
+		cfg:      &Config{BestEffortWindow: time.Hour, ControlRefresh: time.Millisecond},
+		kind:     "test",
+		memo:     mem,
+		registry: &registry{},
+	}
+	conv := &Conveyor{
+		factory: factory,
+		group:   group.Name,
+		target:  schema,
+	}
+	progress := &ident.TableMap[hlc.Range]{}
+	progress.Put(tbl, hlc.RangeIncluding(hlc.New(1, 0), hlc.New(2, 0)))
+	conv.stat = notify.VarOf(sequencer.NewStat(group, progress))
+	conv.resolvingRange.Set(hlc.RangeIncluding(hlc.New(time.Now().UnixNano(), 0), hlc.New(time.Now().UnixNano(), 0)))
+	conv.control.Set(&Control{})
+	conv.modeSelector(ctx)
+	conv.refreshControl(ctx)
+	factory.registry.put(schema, conv)
+
+	waitForMode := func(expect switcher.Mode) {
+		r.Eventually(func() bool {
+			mode, _ := conv.mode.Get()
+			return mode == expect
+		}, 10*time.Second, time.Millisecond)
+	}
+	waitForMode(switcher.ModeConsistent)
+
+	handler := factory.Handler(trust.New())
+	call := func(method, path string, expectCode int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		r.Equal(expectCode, w.Code, w.Body.String())
+		return w
+	}
+
+	// List and show.
+	var list []*Status
+	r.NoError(json.Unmarshal(call(http.MethodGet, "/conveyors", http.StatusOK).Body.Bytes(), &list))
+	r.Len(list, 1)
+	r.Equal("test", list[0].Kind)
+	r.Equal(switcher.ModeConsistent.String(), list[0].Mode)
+	r.Equal(hlc.New(1, 0), list[0].Tables[tbl.Raw()].Min)
+
+	var status Status
+	r.NoError(json.Unmarshal(call(http.MethodGet, "/conveyors/db.public", http.StatusOK).Body.Bytes(), &status))
+	r.Equal(schema.Raw(), status.Schema)
+	call(http.MethodGet, "/conveyors/db.other", http.StatusNotFound)
+
+	// Force a mode.
+	call(http.MethodPost, "/conveyors/db.public/mode/immediate", http.StatusOK)
+	waitForMode(switcher.ModeImmediate)
+	stored, err := LoadControl(ctx, mem, nil, group.Name)
+	r.NoError(err)
+	r.Equal(switcher.ModeImmediate, stored.Mode)
+	call(http.MethodPost, "/conveyors/db.public/mode/bogus", http.StatusBadRequest)
+
+	// Clearing the mode returns to automatic selection.
+	call(http.MethodPost, "/conveyors/db.public/mode/auto", http.StatusOK)
+	waitForMode(switcher.ModeConsistent)
+
+	// Pause and resume.
+	closed := make(chan struct{})
+	close(closed)
+	call(http.MethodPost, "/conveyors/db.public/pause", http.StatusOK)
+	r.False(conv.waitForResume(closed))
+	call(http.MethodPost, "/conveyors/db.public/resume", http.StatusOK)
+	r.True(conv.waitForResume(closed))
+
+	// The pause is applied before a target transaction is opened.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	call(http.MethodPost, "/conveyors/db.public/pause", http.StatusOK)
+	_, err = conv.admitUnpaused(canceled, nil)
+	r.ErrorIs(err, context.Canceled)
+	call(http.MethodPost, "/conveyors/db.public/resume", http.StatusOK)
+	release, err := conv.admitUnpaused(canceled, nil)
+	r.NoError(err)
+	release()
+
+	// Concurrent updates are not lost.
+	var wg sync.WaitGroup
+	for _, path := range []string{"/conveyors/db.public/pause", "/conveyors/db.public/mode/immediate"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		}()
+	}
+	wg.Wait()
+	stored, err = LoadControl(ctx, mem, nil, group.Name)
+	r.NoError(err)
+	r.True(stored.Paused)
+	r.Equal(switcher.ModeImmediate, stored.Mode)
+	call(http.MethodPost, "/conveyors/db.public/resume", http.StatusOK)
+	call(http.MethodPost, "/conveyors/db.public/mode/auto", http.StatusOK)
+
+	// Changes made by another instance are observed.
+	r.NoError(StoreControl(ctx, mem, nil, group.Name, &Control{Paused: true, Updated: time.Now()}))
+	r.Eventually(func() bool {
+		return conv.Control().Paused
+	}, 10*time.Second, time.Millisecond)
+
+	// Updates start from the stored value, not the local copy.
+	r.NoError(StoreControl(ctx, mem, nil, group.Name,
+		&Control{Mode: switcher.ModeImmediate, Paused: true, Updated: time.Now()}))
+	call(http.MethodPost, "/conveyors/db.public/resume", http.StatusOK)
+	stored, err = LoadControl(ctx, mem, nil, group.Name)
+	r.NoError(err)
+	r.False(stored.Paused)
+	r.Equal(switcher.ModeImmediate, stored.Mode)
+
+	// No userscript has been configured.
+	call(http.MethodGet, "/userscript", http.StatusNotFound)
+	call(http.MethodPost, "/userscript/reload", http.StatusNotFound)
+
+	// Callers must be authorized.
+	w := httptest.NewRecorder()
+	factory.Handler(reject.New()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/conveyors", nil))
+	r.Equal(http.StatusForbidden, w.Code)
+}
+
+// TestAdminDelayed verifies that a delayed replica remains in a mode
+// that respects the delayed bounds.
+func TestAdminDelayed(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	group := &types.TableGroup{
+		Enclosing: schema,
+		Name:      ident.New(schema.Raw()),
+	}
+
+	mem := &memo.Memory{}
+	cfg := &Config{BestEffortWindow: time.Hour, ControlRefresh: time.Millisecond}
+	cfg.Delay.Delay = time.Hour
+	factory := &Conveyors{
+		cfg:      cfg,
+		kind:     "test",
+		memo:     mem,
+		registry: &registry{},
+	}
+	conv := &Conveyor{
+		factory: factory,
+		group:   group.Name,
+		target:  schema,
+	}
+	conv.stat = notify.VarOf(sequencer.NewStat(group, &ident.TableMap[hlc.Range]{}))
+	// The data is old enough that best-effort mode would be chosen.
+	old := hlc.New(time.Now().Add(-24*time.Hour).UnixNano(), 0)
+	conv.resolvingRange.Set(hlc.RangeIncluding(old, old))
+	// A previously-stored control must not bypass the delay.
+	conv.control.Set(&Control{Mode: switcher.ModeBestEffort})
+	conv.modeSelector(ctx)
+	factory.registry.put(schema, conv)
+
+	mode, _ := conv.mode.Get()
+	r.Equal(switcher.ModeConsistent, mode)
+
+	handler := factory.Handler(trust.New())
+	call := func(path string, expectCode int) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		r.Equal(expectCode, w.Code, w.Body.String())
+	}
+
+	call("/conveyors/db.public/mode/immediate", http.StatusBadRequest)
+	call("/conveyors/db.public/mode/besteffort", http.StatusBadRequest)
+	call("/conveyors/db.public/mode/transactional", http.StatusOK)
+	r.Eventually(func() bool {
+		mode, _ := conv.mode.Get()
+		return mode == switcher.ModeTransactional
+	}, 10*time.Second, time.Millisecond)
+	call("/conveyors/db.public/mode/auto", http.StatusOK)
+	r.Eventually(func() bool {
+		mode, _ := conv.mode.Get()
+		return mode == switcher.ModeConsistent
+	}, 10*time.Second, time.Millisecond)
+}
@@ -19,6 +19,10 @@ package conveyor
 import (
 	"time"
 
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -27,6 +31,10 @@ const (
 	// hour, to switch from BestEffort to Core, if the applied resolved
 	// timestamp is older than this threshold.
 	DefaultBestEffortWindow = time.Hour
+
+	// DefaultControlRefresh is the default value for
+	// Config.ControlRefresh.
+	DefaultControlRefresh = 5 * time.Second
 )
 
 // Config defines the behavior for a Conveyor.
@@ -39,6 +47,17 @@ type Config struct {
 	// Force the use of BestEffort mode.
 	BestEffortOnly bool
 
+	// Pause delivery when the target schema changes in a way that is
+	// incompatible with the data being sent.
+	CompatGate bool
+
+	// How often to check the staging database for operator controls
+	// that have been set through the admin API.
+	ControlRefresh time.Duration
+
+	// Intentionally trail the source.
+	Delay delay.Config
+
 	// Don't use a core changefeed for cross-Replicator notifications
 	// and only use a polling strategy for detecting changes to the
 	// timestamp bounds.
@@ -55,10 +74,20 @@ type Config struct {
 	// if behind by this many checkpoints.
 	LimitLookahead int
 
+	// The target schemas for which each source transaction is applied
+	// as exactly one target transaction, in commit order.
+	PreserveTransactions []string
+
 	// If set, skips the data check that determines if data is moving
 	// in a backwards direction. There are situations where customers
 	// may want to skip this check.
 	SkipBackwardsDataCheck bool
+
+	// Limits the rate at which mutations are written to the target.
+	Throttle throttle.Config
+
+	// Computed by Preflight.
+	preserveTxns *ident.SchemaMap[bool]
 }
 
 // Bind adds configuration flags to the set.
@@ -68,6 +97,12 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 			"is behind; 0 to disable")
 	f.BoolVar(&c.BestEffortOnly, "bestEffortOnly", false,
 		"eventually-consistent mode; useful for high throughput, skew-tolerant schemas with FKs")
+	f.BoolVar(&c.CompatGate, "compatGate", false,
+		"pause delivery when a target column is dropped or narrowed, instead "+
+			"of failing and retrying apply")
+	f.DurationVar(&c.ControlRefresh, "controlRefresh", DefaultControlRefresh,
+		"how often to check for pause or mode controls set through the admin API")
+	c.Delay.Bind(f)
 	f.BoolVar(&c.DisableCheckpointStream, "disableCheckpointStream", false,
 		"disable cross-Replicator checkpoint notifications and rely only on polling")
 	f.BoolVar(&c.Immediate, "immediate", false,
@@ -76,8 +111,12 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 	f.IntVar(&c.LimitLookahead, "limitLookahead", 0,
 		"limit number of checkpoints to be considered when computing the resolving range; "+
 			"may cause replication to stall completely if older mutations cannot be applied")
+	f.StringSliceVar(&c.PreserveTransactions, "preserveTransactions", nil,
+		"target schemas for which each source transaction is applied as exactly one "+
+			"target transaction; transactions that do not conflict are still applied concurrently")
 	f.BoolVar(&c.SkipBackwardsDataCheck, "ignoreBackwardsCheck", false,
 		"skip checks for data moving backwards")
+	c.Throttle.Bind(f)
 
 	// Marking this ignore check functionality as hidden in order to not
 	// encourage customers to use this option since it disables a critical check
@@ -88,7 +127,36 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 	}
 }
 
+// preserveTransactions returns true if source transactions should be
+// preserved when writing to the target schema.
+func (c *Config) preserveTransactions(schema ident.Schema) bool {
+	return c.preserveTxns != nil && c.preserveTxns.GetZero(schema)
+}
+
 // Preflight ensures the Config is in a known-good state.
 func (c *Config) Preflight() error {
-	return nil
+	c.preserveTxns = nil
+	if len(c.PreserveTransactions) > 0 {
+		if c.BestEffortOnly || c.Immediate {
+			return errors.New("preserveTransactions cannot be combined with bestEffortOnly or immediate")
+		}
+		c.preserveTxns = &ident.SchemaMap[bool]{}
+		for _, raw := range c.PreserveTransactions {
+			schema, err := ident.ParseSchema(raw)
+			if err != nil {
+				return errors.Wrapf(err, "could not parse preserveTransactions schema %q", raw)
+			}
+			c.preserveTxns.Put(schema, true)
+		}
+	}
+	if c.ControlRefresh <= 0 {
+		c.ControlRefresh = DefaultControlRefresh
+	}
+	if err := c.Delay.Preflight(); err != nil {
+		return err
+	}
+	if c.Delay.Delay > 0 && (c.BestEffortOnly || c.Immediate) {
+		return errors.New("replicaDelay cannot be combined with bestEffortOnly or immediate")
+	}
+	return c.Throttle.Preflight()
 }
@@ -0,0 +1,53 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreserveTransactions(t *testing.T) {
+	r := require.New(t)
+
+	cfg := &Config{PreserveTransactions: []string{"db.public", "OTHER.Schema"}}
+	r.NoError(cfg.Preflight())
+	r.True(cfg.preserveTransactions(ident.MustSchema(ident.New("db"), ident.Public)))
+	r.True(cfg.preserveTransactions(ident.MustSchema(ident.New("other"), ident.New("schema"))))
+	r.False(cfg.preserveTransactions(ident.MustSchema(ident.New("db"), ident.New("other"))))
+
+	r.False((&Config{}).preserveTransactions(ident.MustSchema(ident.New("db"), ident.Public)))
+
+	cfg = &Config{PreserveTransactions: []string{"db.public"}, Immediate: true}
+	r.ErrorContains(cfg.Preflight(), "immediate")
+}
+
+func TestDelayConflicts(t *testing.T) {
+	r := require.New(t)
+
+	r.NoError((&Config{Delay: delay.Config{Delay: time.Hour}}).Preflight())
+
+	cfg := &Config{Delay: delay.Config{Delay: time.Hour}, BestEffortOnly: true}
+	r.ErrorContains(cfg.Preflight(), "replicaDelay")
+
+	cfg = &Config{Delay: delay.Config{Delay: time.Hour}, Immediate: true}
+	r.ErrorContains(cfg.Preflight(), "replicaDelay")
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// A Control records operator overrides for a Conveyor. Controls are
+// stored in the memo table, so that they survive restarts and will be
+// observed by all instances of Replicator that share a staging
+// database.
+type Control struct {
+	// If set, this mode will be used instead of the one that would
+	// otherwise be selected by the Config.
+	Mode switcher.Mode
+	// If true, no mutations will be applied to the target. Incoming
+	// mutations will continue to be staged, if the mode allows it.
+	Paused bool
+	// The time at which the Control was last changed.
+	Updated time.Time
+}
+
+// controlPayload is the serialized form of a Control. The mode is
+// stored by name so that the encoding does not depend on the order in
+// which the modes are declared.
+type controlPayload struct {
+	Mode    string    `json:"mode,omitempty"`
+	Paused  bool      `json:"paused,omitempty"`
+	Updated time.Time `json:"updated,omitempty"`
+}
+
+// Equal returns true if the Controls are equivalent.
+func (c *Control) Equal(o *Control) bool {
+	return c.Mode == o.Mode && c.Paused == o.Paused && c.Updated.Equal(o.Updated)
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (c *Control) MarshalJSON() ([]byte, error) {
+	payload := controlPayload{Paused: c.Paused, Updated: c.Updated}
+	if c.Mode != switcher.ModeUnknown {
+		payload.Mode = c.Mode.String()
+	}
+	return json.Marshal(payload)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (c *Control) UnmarshalJSON(data []byte) error {
+	var payload controlPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return errors.WithStack(err)
+	}
+	*c = Control{Paused: payload.Paused, Updated: payload.Updated}
+	if payload.Mode != "" {
+		var err error
+		c.Mode, err = switcher.ParseMode(payload.Mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// controlKey returns the key for a group's Control in the memo table.
+func controlKey(group ident.Ident) string {
+	return "conveyor-control-" + group.Raw()
+}
+
+// LoadControl returns the Control for the group. A zero-valued Control
+// will be returned if none has been stored.
+func LoadControl(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, group ident.Ident,
+) (*Control, error) {
+	data, err := memo.Get(ctx, tx, controlKey(group))
+	if err != nil {
+		return nil, err
+	}
+	ret := &Control{}
+	if len(data) == 0 {
+		return ret, nil
+	}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, errors.Wrapf(err, "could not decode conveyor control for %s", group)
+	}
+	return ret, nil
+}
+
+// StoreControl records the Control for the group.
+func StoreControl(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, group ident.Ident, ctl *Control,
+) error {
+	data, err := json.Marshal(ctl)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return memo.Put(ctx, tx, controlKey(group), data)
+}
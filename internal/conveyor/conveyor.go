@@ -19,6 +19,7 @@ package conveyor
 
 import (
 	"context"
+	"iter"
 	"sync"
 	"time"
 
@@ -26,14 +27,21 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -43,12 +51,20 @@ import (
 type Conveyors struct {
 	cfg           *Config                 // Controls the mode of operations.
 	checkpoints   *checkpoint.Checkpoints // Checkpoints factory.
+	compat        *compat.Compat          // Pauses on incompatible schema changes.
+	creator       *ddl.Creator            // Creates missing target tables.
+	delay         *delay.Delay            // Delayed-replica mode.
 	kind          string                  // Used by metrics.
+	memo          types.Memo              // Stores operator controls.
+	registry      *registry               // Shared by all kinds, for the admin API.
 	retire        *retire.Retire          // Removes old mutations.
 	script        *script.Sequencer       // Userscript wrappers.
+	sink          Sink                    // If non-nil, replaces tableAcceptor.
+	stagingPool   *types.StagingPool      // Access to the memo table.
 	stopper       *stopper.Context        // Manages the lifetime of the goroutines.
 	switcher      *switcher.Switcher      // Switches between mode of operations.
-	tableAcceptor *apply.Acceptor         // Writes batches of mutations into target tables.
+	tableAcceptor *history.Acceptor       // Writes batches of mutations into target tables.
+	throttle      *throttle.Throttle      // Limits the rate of writes to the target.
 	watchers      types.Watchers          // Target schema access.
 
 	mu struct {
@@ -74,6 +90,17 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 		return found, nil
 	}
 
+	// Resolved timestamps are not tracked per schema by a sink.
+	if c.sink != nil && c.mu.targets.Len() > 0 {
+		return nil, errors.Errorf(
+			"cannot deliver %s to a sink; only one target schema may be used", schema)
+	}
+
+	// Create any missing tables before the table group is defined.
+	if err := c.creator.EnsureSchema(c.stopper, schema); err != nil {
+		return nil, err
+	}
+
 	w, err := c.watchers.Get(schema)
 	if err != nil {
 		return nil, err
@@ -91,10 +118,18 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 
 	ret := &Conveyor{
 		factory: c,
+		group:   tableGroup.Name,
 		target:  schema,
 		watcher: w,
 	}
 
+	// Load any operator controls before selecting the initial mode.
+	ctl, err := LoadControl(c.stopper, c.memo, c.stagingPool, tableGroup.Name)
+	if err != nil {
+		return nil, err
+	}
+	ret.control.Set(ctl)
+
 	var opts []checkpoint.Option
 	if c.cfg.DisableCheckpointStream {
 		opts = append(opts, checkpoint.DisableStream())
@@ -115,16 +150,40 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 	ret.modeSelector(c.stopper)
 
 	seq := sequencer.Sequencer(c.switcher.WithMode(&ret.mode))
+	seq, err = c.delay.Wrap(c.stopper, seq)
+	if err != nil {
+		return nil, err
+	}
+	seq, err = c.throttle.Wrap(c.stopper, seq)
+	if err != nil {
+		return nil, err
+	}
+	// Wrapped outside of the throttle so that a paused group does not
+	// hold any throttle capacity.
+	if c.cfg.CompatGate {
+		seq, err = c.compat.Wrap(c.stopper, seq)
+		if err != nil {
+			return nil, err
+		}
+	}
 	seq, err = c.script.Wrap(c.stopper, seq)
 	if err != nil {
 		return nil, err
 	}
 
+	delegate := types.OrderedAcceptorFrom(c.tableAcceptor, c.watchers)
+	if c.sink != nil {
+		delegate = c.sink
+		c.sink.ResolvedFrom(c.stopper, &ret.resolvingRange)
+	}
+
 	ret.acceptor, ret.stat, err = seq.Start(
 		c.stopper,
 		&sequencer.StartOptions{
+			// Allow an operator to pause delivery to the target.
+			Admit:    ret.admitUnpaused,
 			Bounds:   &ret.resolvingRange,
-			Delegate: types.OrderedAcceptorFrom(c.tableAcceptor, c.watchers),
+			Delegate: delegate,
 			Group:    tableGroup,
 		})
 	if err != nil {
@@ -148,7 +207,11 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 	// Report timestamps and lag.
 	ret.metrics(c.stopper)
 
+	// Observe changes made by other instances.
+	ret.refreshControl(c.stopper)
+
 	c.mu.targets.Put(schema, ret)
+	c.registry.put(schema, ret)
 	return ret, nil
 }
 
@@ -179,12 +242,20 @@ func (c *Conveyors) clone() *Conveyors {
 	return &Conveyors{
 		cfg:           c.cfg,
 		checkpoints:   c.checkpoints,
+		compat:        c.compat,
+		creator:       c.creator,
+		delay:         c.delay,
 		kind:          c.kind,
+		memo:          c.memo,
+		registry:      c.registry,
 		retire:        c.retire,
 		script:        c.script,
+		sink:          c.sink,
+		stagingPool:   c.stagingPool,
 		stopper:       c.stopper,
 		switcher:      c.switcher,
 		tableAcceptor: c.tableAcceptor,
+		throttle:      c.throttle,
 		watchers:      c.watchers,
 	}
 }
@@ -195,7 +266,10 @@ func (c *Conveyors) clone() *Conveyors {
 type Conveyor struct {
 	acceptor       types.MultiAcceptor         // Possibly-async writes to the target.
 	checkpoint     *checkpoint.Group           // Persistence of checkpoint (fka. resolved) timestamps
+	control        notify.Var[*Control]        // Operator overrides.
+	controlMu      sync.Mutex                  // Serializes calls to UpdateControl.
 	factory        *Conveyors                  // Factory that created this conveyor.
+	group          ident.Ident                 // The name of the table group.
 	mode           notify.Var[switcher.Mode]   // Switchable strategies.
 	resolvingRange notify.Var[hlc.Range]       // Range of resolved timestamps to be processed.
 	stat           *notify.Var[sequencer.Stat] // Processing status.
@@ -215,6 +289,12 @@ func (c *Conveyor) Advance(ctx context.Context, partition ident.Ident, ts hlc.Ti
 	return c.checkpoint.Advance(ctx, partition, ts)
 }
 
+// Control returns the operator overrides for the conveyor.
+func (c *Conveyor) Control() *Control {
+	ret, _ := c.control.Get()
+	return ret
+}
+
 // Ensure that a checkpoint exists for all named partitions.
 func (c *Conveyor) Ensure(ctx context.Context, partitions []ident.Ident) error {
 	return c.checkpoint.Ensure(ctx, partitions)
@@ -230,6 +310,60 @@ func (c *Conveyor) Refresh() {
 	c.checkpoint.Refresh()
 }
 
+// UpdateControl modifies the operator overrides in the memo table and
+// applies them to the conveyor. The stored Control is read, modified,
+// and written back within a serializable staging transaction, so that
+// concurrent updates, including those made by other instances of
+// Replicator, are not lost. Other instances will observe the change
+// within [Config.ControlRefresh].
+func (c *Conveyor) UpdateControl(
+	ctx context.Context, fn func(ctl *Control) error,
+) (*Control, error) {
+	c.controlMu.Lock()
+	defer c.controlMu.Unlock()
+
+	var next *Control
+	update := func(ctx context.Context, tx types.StagingQuerier) error {
+		current, err := LoadControl(ctx, c.factory.memo, tx, c.group)
+		if err != nil {
+			return err
+		}
+		if err := fn(current); err != nil {
+			return err
+		}
+		current.Updated = time.Now().UTC()
+		if err := StoreControl(ctx, c.factory.memo, tx, c.group, current); err != nil {
+			return err
+		}
+		next = current
+		return nil
+	}
+
+	pool := c.factory.stagingPool
+	var err error
+	if pool == nil {
+		// Only in tests, with an in-memory memo.
+		err = update(ctx, nil)
+	} else {
+		err = retry.Retry(ctx, pool, func(ctx context.Context) error {
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer func() { _ = tx.Rollback(context.Background()) }()
+			if err := update(ctx, tx); err != nil {
+				return err
+			}
+			return errors.WithStack(tx.Commit(ctx))
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.control.Set(next)
+	return next, nil
+}
+
 // Stat returns the progress of all tables being managed.
 func (c *Conveyor) Stat() *notify.Var[sequencer.Stat] {
 	return c.stat
@@ -270,16 +404,24 @@ func (c *Conveyor) metrics(ctx *stopper.Context) {
 			})
 		return err
 	})
+	ctx.Go(func(ctx *stopper.Context) error {
+		paused := controlPaused.WithLabelValues(c.factory.kind, c.target.Raw())
+		_, err := stopvar.DoWhenChanged(ctx, nil, &c.control,
+			func(ctx *stopper.Context, _, ctl *Control) error {
+				if ctl.Paused {
+					paused.Set(1)
+				} else {
+					paused.Set(0)
+				}
+				return nil
+			})
+		return err
+	})
 }
 
+// modeSelector chooses the mode of operation whenever the resolving
+// range or the operator controls change.
 func (c *Conveyor) modeSelector(ctx *stopper.Context) {
-	if c.factory.cfg.Immediate {
-		c.mode.Set(switcher.ModeImmediate)
-		return
-	} else if c.factory.cfg.BestEffortOnly {
-		c.mode.Set(switcher.ModeBestEffort)
-		return
-	}
 	// The initial update will be async, so wait for it.
 	_, initialSet := c.mode.Get()
 	ctx.Go(func(ctx *stopper.Context) error {
@@ -288,37 +430,16 @@ func (c *Conveyor) modeSelector(ctx *stopper.Context) {
 			&c.resolvingRange,
 			10*time.Second, // Re-evaluate to allow un-jamming big serial transactions.
 			func(ctx *stopper.Context, _, bounds hlc.Range) error {
-				minTime := time.Unix(0, bounds.Min().Nanos())
-				lag := time.Since(minTime)
-
-				// Sometimes you don't know what you want.
-				want := switcher.ModeUnknown
-				if c.factory.cfg.BestEffortWindow <= 0 {
-					// Force a consistent mode.
-					want = switcher.ModeConsistent
-				} else if lag >= c.factory.cfg.BestEffortWindow {
-					// Fallen behind, switch to best-effort.
-					want = switcher.ModeBestEffort
-				} else if lag <= c.factory.cfg.BestEffortWindow/4 {
-					// Caught up close-enough to the current time.
-					want = switcher.ModeConsistent
-				}
-
-				_, _, _ = c.mode.Update(func(current switcher.Mode) (switcher.Mode, error) {
-					// Pick a reasonable default for uninitialized case.
-					// Choosing BestEffort here allows us to optimize
-					// for the case where a user creates a changefeed
-					// that's going to perform a large backfill.
-					if current == switcher.ModeUnknown && want == switcher.ModeUnknown {
-						want = switcher.ModeBestEffort
-					} else if want == switcher.ModeUnknown || current == want {
-						// No decision above or no change.
-						return current, notify.ErrNoUpdate
-					}
-
-					log.Tracef("setting group %s mode to %s", c.target, want)
-					return want, nil
-				})
+				c.selectMode(bounds)
+				return nil
+			})
+		return err
+	})
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChanged(ctx, nil, &c.control,
+			func(ctx *stopper.Context, _, _ *Control) error {
+				bounds, _ := c.resolvingRange.Get()
+				c.selectMode(bounds)
 				return nil
 			})
 		return err
@@ -326,6 +447,137 @@ func (c *Conveyor) modeSelector(ctx *stopper.Context) {
 	<-initialSet
 }
 
+// selectMode updates the mode variable, based on the operator controls,
+// the Config, and the age of the resolving range.
+func (c *Conveyor) selectMode(bounds hlc.Range) {
+	cfg := c.factory.cfg
+	ctl, _ := c.control.Get()
+	minTime := time.Unix(0, bounds.Min().Nanos())
+	// A delayed replica is not considered to be behind.
+	lag := time.Since(minTime) - cfg.Delay.Delay
+
+	delayed := c.delayed()
+
+	_, _, _ = c.mode.Update(func(current switcher.Mode) (switcher.Mode, error) {
+		// Sometimes you don't know what you want.
+		want := switcher.ModeUnknown
+		switch {
+		case ctl.Mode != switcher.ModeUnknown && !(delayed && bypassesDelay(ctl.Mode)):
+			// An operator has forced a mode.
+			want = ctl.Mode
+		case delayed && cfg.preserveTransactions(c.target):
+			want = switcher.ModeTransactional
+		case delayed:
+			// The delay is enforced by capping the bounds of the
+			// staged data, so we must not leave a staged mode.
+			want = switcher.ModeConsistent
+		case cfg.Immediate:
+			want = switcher.ModeImmediate
+		case cfg.BestEffortOnly:
+			want = switcher.ModeBestEffort
+		case cfg.preserveTransactions(c.target):
+			want = switcher.ModeTransactional
+		case cfg.BestEffortWindow <= 0:
+			// Force a consistent mode.
+			want = switcher.ModeConsistent
+		case lag >= cfg.BestEffortWindow:
+			// Fallen behind, switch to best-effort.
+			want = switcher.ModeBestEffort
+		case lag <= cfg.BestEffortWindow/4:
+			// Caught up close-enough to the current time.
+			want = switcher.ModeConsistent
+		case current == switcher.ModeUnknown:
+			// Pick a reasonable default for uninitialized case.
+			// Choosing BestEffort here allows us to optimize for the
+			// case where a user creates a changefeed that's going to
+			// perform a large backfill.
+			want = switcher.ModeBestEffort
+		case current != switcher.ModeBestEffort && current != switcher.ModeConsistent:
+			// A forced mode has been cleared, so return to a mode
+			// that we would have selected automatically.
+			want = switcher.ModeConsistent
+		}
+
+		if want == switcher.ModeUnknown || current == want {
+			// No decision above or no change.
+			return current, notify.ErrNoUpdate
+		}
+
+		log.Tracef("setting group %s mode to %s", c.target, want)
+		return want, nil
+	})
+}
+
+// delayed returns true if the conveyor is operating as a delayed
+// replica or if an operator has set a delay override.
+func (c *Conveyor) delayed() bool {
+	return c.factory.cfg.Delay.Delay > 0 ||
+		(c.factory.delay != nil && c.factory.delay.Overridden(c.group))
+}
+
+// bypassesDelay returns true if the mode would apply mutations without
+// respecting the bounds imposed by a delayed replica.
+func bypassesDelay(mode switcher.Mode) bool {
+	return mode == switcher.ModeBestEffort || mode == switcher.ModeImmediate
+}
+
+// refreshControl periodically reloads the operator controls from the
+// memo table.
+func (c *Conveyor) refreshControl(ctx *stopper.Context) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		ticker := time.NewTicker(c.factory.cfg.ControlRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Stopping():
+				return nil
+			}
+			next, err := LoadControl(ctx, c.factory.memo, c.factory.stagingPool, c.group)
+			if err != nil {
+				log.WithError(err).Warnf("could not refresh controls for %s; will continue", c.target)
+				continue
+			}
+			_, _, _ = c.control.Update(func(current *Control) (*Control, error) {
+				if current.Equal(next) {
+					return current, notify.ErrNoUpdate
+				}
+				log.Infof("controls for %s changed: mode=%s paused=%t", c.target, next.Mode, next.Paused)
+				return next, nil
+			})
+		}
+	})
+}
+
+// admitUnpaused is a [sequencer.Admission] which blocks while the
+// conveyor has been paused by an operator. It is called before a
+// target transaction is opened, so a paused conveyor does not hold
+// any target resources.
+func (c *Conveyor) admitUnpaused(
+	ctx context.Context, _ iter.Seq2[ident.Table, types.Mutation],
+) (func(), error) {
+	if !c.waitForResume(ctx.Done()) {
+		return nil, errors.WithStack(ctx.Err())
+	}
+	return func() {}, nil
+}
+
+// waitForResume blocks until the conveyor is not paused, returning
+// false if the channel is closed first.
+func (c *Conveyor) waitForResume(done <-chan struct{}) bool {
+	for {
+		ctl, changed := c.control.Get()
+		if !ctl.Paused {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
+
 // updateResolved will monitor the timestamp to which tables in the
 // group have advanced and update the resolved timestamp table.
 func (c *Conveyor) updateResolved(ctx *stopper.Context) {
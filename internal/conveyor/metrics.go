@@ -22,6 +22,10 @@ import (
 )
 
 var (
+	controlPaused = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "conveyor_paused",
+		Help: "set to 1 if delivery to the target schema has been paused by an operator",
+	}, []string{"kind", "target"})
 	mutationsErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "mutations_error_count",
 		Help: "the total number of mutations that encountered an error during processing",
@@ -18,27 +18,44 @@ package conveyor
 
 import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/google/wire"
 )
 
 // Set is used by Wire.
-var Set = wire.NewSet(ProvideConveyors)
+var Set = wire.NewSet(
+	ProvideConveyors,
+	ProvideDelayConfig,
+	ProvideThrottleConfig,
+	delay.Set,
+	throttle.Set,
+)
 
 // ProvideConveyors is called by Wire.
 func ProvideConveyors(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc *history.Acceptor,
 	cfg *Config,
 	checkpoints *checkpoint.Checkpoints,
+	compat *compat.Compat,
+	creator *ddl.Creator,
+	delay *delay.Delay,
+	memo types.Memo,
 	script *script.Sequencer,
 	retire *retire.Retire,
+	sink Sink,
+	stagingPool *types.StagingPool,
 	sw *switcher.Switcher,
+	throttle *throttle.Throttle,
 	watchers types.Watchers,
 ) (*Conveyors, error) {
 	if err := cfg.Preflight(); err != nil {
@@ -47,11 +64,29 @@ func ProvideConveyors(
 	return &Conveyors{
 		cfg:           cfg,
 		checkpoints:   checkpoints,
+		compat:        compat,
+		creator:       creator,
+		delay:         delay,
+		memo:          memo,
+		registry:      &registry{},
 		retire:        retire,
 		script:        script,
+		sink:          sink,
+		stagingPool:   stagingPool,
 		stopper:       ctx,
 		switcher:      sw,
 		tableAcceptor: acc,
+		throttle:      throttle,
 		watchers:      watchers,
 	}, nil
 }
+
+// ProvideDelayConfig is called by Wire.
+func ProvideDelayConfig(cfg *Config) *delay.Config {
+	return &cfg.Delay
+}
+
+// ProvideThrottleConfig is called by Wire.
+func ProvideThrottleConfig(cfg *Config) *throttle.Config {
+	return &cfg.Throttle
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+)
+
+// A Sink receives mutations in place of the tables in the target
+// database, e.g. a webhook or an object store. The target database is
+// still used to determine the tables within each table group.
+type Sink interface {
+	types.MultiAcceptor
+
+	// ResolvedFrom starts a goroutine which delivers a resolved
+	// timestamp whenever the minimum of the bounds advances.
+	ResolvedFrom(ctx *stopper.Context, bounds *notify.Var[hlc.Range])
+}
+
+// ProvideNoSink is called by Wire for commands which always apply
+// mutations to the target database.
+func ProvideNoSink() Sink {
+	return nil
+}
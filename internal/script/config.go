@@ -20,16 +20,27 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
+const defaultReloadPeriod = 5 * time.Second
+
 // Config drives UserScript behavior.
 type Config struct {
 	FS       fs.FS   // A filesystem to load resources fs.
 	MainPath string  // A path, relative to FS that holds the entrypoint.
 	Options  Options // The target for calls to api.setOptions().
 
+	// How often to check the userscript and the modules that it
+	// imports for changes. If negative, the script will only be
+	// reloaded by an explicit request.
+	ReloadPeriod time.Duration
+
+	// A YAML or JSON file containing declarative, per-table transforms.
+	TransformsPath string
+
 	// An external filesystem path. This will be cleared after Preflight
 	// has been called. This symbol is exported for testing.
 	UserScriptPath string
@@ -42,10 +53,17 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 	}
 	f.StringVar(&c.UserScriptPath, "userscript", "",
 		"the path to a configuration script, see userscript subcommand")
+	f.DurationVar(&c.ReloadPeriod, "userscriptReloadPeriod", defaultReloadPeriod,
+		"how often to check the userscript for changes and reload it (-1 to disable)")
+	f.StringVar(&c.TransformsPath, "transforms", "",
+		"the path to a YAML or JSON file of declarative, per-table column transforms")
 }
 
 // Preflight will set FS and MainPath, if UserScriptPath is set.
 func (c *Config) Preflight() error {
+	if c.ReloadPeriod == 0 {
+		c.ReloadPeriod = defaultReloadPeriod
+	}
 	if c.UserScriptPath != "" {
 		path, err := filepath.Abs(c.UserScriptPath)
 		if err != nil {
@@ -25,6 +25,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/field-eng-powertools/workgroup"
@@ -63,6 +64,12 @@ type dispatchJS func(
 	meta map[string]any,
 ) (map[string][]map[string]any, error)
 
+// historyJS is used in the API binding to configure a history table.
+type historyJS struct {
+	Columns []string `goja:"columns"`
+	Table   string   `goja:"table"`
+}
+
 // A simple mapping function.
 //
 //	{ doc } => { doc }
@@ -114,6 +121,11 @@ type targetJS struct {
 	Apply applyJS `goja:"apply"`
 	// Column names.
 	CASColumns []string `goja:"cas"`
+	// For targets with a bulk-copy mechanism, the minimum number of
+	// rows in a batch before the mechanism is used. Only batches that
+	// are applied within a target transaction, as in the consistent
+	// modes, use the mechanism.
+	CopyThreshold int `goja:"copyThreshold"`
 	// Column to duration.
 	Deadlines map[string]string `goja:"deadlines"`
 	// PK to PK mapper.
@@ -122,6 +134,8 @@ type targetJS struct {
 	Exprs map[string]string `goja:"exprs"`
 	// Column name.
 	Extras string `goja:"extras"`
+	// Table name and tracked column names.
+	History historyJS `goja:"history"`
 	// Column names.
 	Ignore map[string]bool `goja:"ignore"`
 	// Mutation to mutation.
@@ -132,6 +146,10 @@ type targetJS struct {
 	// For targets without a bulk-transfer mechanism, the maximum number
 	// of rows to send in a single statement.
 	RowLimit int `goja:"rowLimit"`
+	// Column name.
+	SoftDelete string `goja:"softDelete"`
+	// Update only changed columns.
+	UpdateChanged bool `goja:"updateChanged"`
 }
 
 // Loader is responsible for the first-pass execution of the user
@@ -141,19 +159,47 @@ type targetJS struct {
 // In order to resolve the various table names against a specific
 // target schema, call [Loader.Bind] to return a [UserScript] that
 // operates within the given target schema.
+//
+// The Loader may replace the user script with a newer version of the
+// script; see [Loader.Reload].
 type Loader struct {
-	apiModule    *goja.Object          // The imported replicator module.
-	applyConfigs *applycfg.Configs     // Injected.
-	diags        *diag.Diagnostics     // Injected.
-	fs           fs.FS                 // Used by require.
-	options      Options               // Target of api.setOptions().
-	requireStack []*url.URL            // Allows relative import paths.
-	requireCache map[string]goja.Value // Keys are URLs.
-	rt           *goja.Runtime         // JS Runtime.
-	rtMu         *sync.RWMutex         // Serialize access to the VM.
-	sources      map[string]*sourceJS  // User configuration.
-	targets      map[string]*targetJS  // User configuration.
-	tasks        *workgroup.Group      // Limit concurrency of JS background tasks.
+	applyConfigs *applycfg.Configs // Injected.
+	cfg          *Config           // Used to reload the script.
+	diags        *diag.Diagnostics // Injected.
+	tasks        *workgroup.Group  // Limit concurrency of JS background tasks.
+
+	// Held for reading by [Loader.Hold] and for writing while a
+	// reloaded script is being installed.
+	swap sync.RWMutex
+
+	mu struct {
+		sync.Mutex
+		bindings    []*binding // Calls to Bind, which will be refreshed.
+		current     *loaded    // The most recently loaded script.
+		generation  int64      // Incremented by each successful load.
+		lastAttempt time.Time  // The time of the most recent load.
+		lastError   error      // The outcome of the most recent load.
+		loadedAt    time.Time  // The time that current was loaded.
+		// The files read by the most recent load, used to detect
+		// changes to the script.
+		observed map[string]fileStamp
+	}
+}
+
+// loaded contains the state of a single execution of the user script.
+// Each version of the script has its own JS runtime.
+type loaded struct {
+	apiModule    *goja.Object                // The imported replicator module.
+	files        map[string]fileStamp        // Modules loaded from fs.
+	fs           fs.FS                       // Used by require.
+	options      *pendingOptions             // Target of api.setOptions().
+	requireStack []*url.URL                  // Allows relative import paths.
+	requireCache map[string]goja.Value       // Keys are URLs.
+	rt           *goja.Runtime               // JS Runtime.
+	rtMu         *sync.RWMutex               // Serialize access to the VM.
+	sources      map[string]*sourceJS        // User configuration.
+	targets      map[string]*targetJS        // User configuration.
+	transforms   map[string]*tableTransforms // Declarative configuration.
 }
 
 // Bind resolves the various table names used in the script file to the
@@ -162,21 +208,35 @@ type Loader struct {
 // [stopper.Context].
 //
 // At present, each returned [UserScript] shares a common JS runtime.
+// The returned UserScript will not be updated if the script is
+// reloaded; see [Loader.BindVar].
 func (l *Loader) Bind(
 	ctx *stopper.Context,
 	target ident.Schematic,
 	targetAcceptor types.TableAcceptor,
 	watchers types.Watchers,
+) (*UserScript, error) {
+	v, err := l.BindVar(ctx, target, targetAcceptor, watchers)
+	if err != nil {
+		return nil, err
+	}
+	ret, _ := v.Get()
+	return ret, nil
+}
+
+// bindScript creates a UserScript for the target schema from a loaded
+// version of the user script.
+func (l *Loader) bindScript(
+	cur *loaded, sch ident.Schema, targetAcceptor types.TableAcceptor, watchers types.Watchers,
 ) (*UserScript, error) {
 	// In the unconfigured case, return an unconfigured script.
-	if l.fs == nil {
+	if cur.fs == nil {
 		return &UserScript{
 			Sources: &ident.Map[*Source]{},
 			Targets: &ident.TableMap[*Target]{},
 		}, nil
 	}
 
-	sch := target.Schema()
 	watcher, err := watchers.Get(sch)
 	if err != nil {
 		return nil, err
@@ -186,9 +246,9 @@ func (l *Loader) Bind(
 		Delegate:  targetAcceptor,
 		Sources:   &ident.Map[*Source]{},
 		Targets:   &ident.TableMap[*Target]{},
-		apiModule: l.apiModule,
-		rt:        l.rt,
-		rtMu:      l.rtMu,
+		apiModule: cur.apiModule,
+		rt:        cur.rt,
+		rtMu:      cur.rtMu,
 		target:    sch,
 		tasks:     l.tasks,
 		watcher:   watcher,
@@ -199,29 +259,14 @@ func (l *Loader) Bind(
 	// Provide continuity of, e.g. getTX(), across
 	ret.rt.SetAsyncContextTracker(ret)
 
-	if err := ret.bind(l); err != nil {
+	if err := ret.bind(cur); err != nil {
 		return nil, err
 	}
-
-	diagName := fmt.Sprintf("script-%s-%p", sch.Raw(), ret)
-	if err := l.diags.Register(diagName, ret); err != nil {
-		return nil, err
-	}
-	ctx.Defer(func() {
-		l.diags.Unregister(diagName)
-	})
-
-	for tbl, tblCfg := range ret.Targets.All() {
-		if err := l.applyConfigs.Set(tbl, &tblCfg.Config); err != nil {
-			return nil, errors.Wrap(err, tbl.Raw())
-		}
-	}
-
-	return ret, err
+	return ret, nil
 }
 
 // configureSource is exported to the JS runtime.
-func (l *Loader) configureSource(sourceName string, bag *sourceJS) error {
+func (l *loaded) configureSource(sourceName string, bag *sourceJS) error {
 	if (bag.Dispatch != nil) == (bag.Target != "") {
 		return errors.Errorf("configureSource(%q): one of mapper or target must be set", sourceName)
 	}
@@ -231,7 +276,7 @@ func (l *Loader) configureSource(sourceName string, bag *sourceJS) error {
 }
 
 // configureTable is exported to the JS runtime.
-func (l *Loader) configureTable(tableName string, bag *targetJS) error {
+func (l *loaded) configureTable(tableName string, bag *targetJS) error {
 	l.targets[tableName] = bag
 	return nil
 }
@@ -240,7 +285,7 @@ func (l *Loader) configureTable(tableName string, bag *targetJS) error {
 // of the NodeJS-style require() function. The referenced module
 // contents are loaded, converted to ES5 in CommonJS packaging, and then
 // executed.
-func (l *Loader) require(module string) (goja.Value, error) {
+func (l *loaded) require(module string) (goja.Value, error) {
 	// Look for an exact-match (e.g. the API import).
 	if found, ok := l.requireCache[module]; ok {
 		return found, nil
@@ -291,6 +336,14 @@ func (l *Loader) require(module string) (goja.Value, error) {
 			return nil, errors.Wrap(err, source.Path)
 		}
 		defer f.Close()
+		// Record the file's metadata so that changes to it can be
+		// detected. This is done before reading the file, so that
+		// a concurrent change will trigger a later reload.
+		info, err := f.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, source.Path)
+		}
+		l.files[source.Path[1:]] = stampOf(info)
 		data, err = io.ReadAll(f)
 		if err != nil {
 			return nil, errors.Wrap(err, source.Path)
@@ -354,7 +407,7 @@ var module = {exports: exports};`, key),
 }
 
 // setOptions is an escape-hatch for configuring dialects at runtime.
-func (l *Loader) setOptions(data map[string]string) error {
+func (l *loaded) setOptions(data map[string]string) error {
 	for k, v := range data {
 		if err := l.options.Set(k, v); err != nil {
 			return err
@@ -363,11 +416,20 @@ func (l *Loader) setOptions(data map[string]string) error {
 	return nil
 }
 
+// applyOptions passes any options set by the script to the configured
+// Options.
+func (l *loaded) applyOptions() error {
+	if l.options == nil {
+		return nil
+	}
+	return l.options.apply()
+}
+
 // standardMerge returns a JS object that [UserScript.bindMerge] will
 // detect. This collusion allows us to avoid any goja wiring to pass the
 // data into standardMerge.  Hopefully, the fallback won't need to be
 // called, so we can perform the entire merge in go code.
-func (l *Loader) standardMerge(jsFunc mergeJS) (*goja.Object, error) {
+func (l *loaded) standardMerge(jsFunc mergeJS) (*goja.Object, error) {
 	ret := l.rt.NewObject()
 	if err := ret.SetSymbol(symIsStandardMerge, true); err != nil {
 		return nil, errors.WithStack(err)
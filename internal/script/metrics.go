@@ -28,6 +28,18 @@ var (
 		Help:    "the length of time spent waiting to enter the JS runtime",
 		Buckets: metrics.LatencyBuckets,
 	})
+	scriptGeneration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "script_generation",
+		Help: "the number of times the userscript has been successfully loaded",
+	})
+	scriptReloadErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "script_reload_errors_total",
+		Help: "the number of times the userscript could not be reloaded",
+	})
+	scriptReloads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "script_reloads_total",
+		Help: "the number of times the userscript has been reloaded",
+	})
 	scriptExecTime = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "script_exec_time_seconds",
 		Help:    "the length of time spent executing JS code",
@@ -31,7 +31,12 @@ var NoOptions Options = &noOptions{}
 type noOptions struct{}
 
 // Set always returns an error.
-func (o *noOptions) Set(_, _ string) error {
+func (o *noOptions) Set(key, _ string) error {
+	return o.check(key)
+}
+
+// check implements optionChecker.
+func (o *noOptions) check(string) error {
 	return errors.New("no options are supported by this dialect")
 }
 
@@ -42,9 +47,61 @@ type FlagOptions struct {
 
 // Set implements Options.
 func (o *FlagOptions) Set(key, value string) error {
-	f := o.Flags.Lookup(key)
-	if f == nil {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return errors.Wrapf(o.Flags.Lookup(key).Value.Set(value), "option %q", key)
+}
+
+// check implements optionChecker.
+func (o *FlagOptions) check(key string) error {
+	if o.Flags.Lookup(key) == nil {
 		return errors.Errorf("unknown option %q", key)
 	}
-	return errors.Wrapf(f.Value.Set(value), "option %q", key)
+	return nil
+}
+
+// An optionChecker can reject an unknown option before any value is
+// set.
+type optionChecker interface {
+	check(key string) error
+}
+
+// pendingOptions records calls to api.setOptions() so that they can be
+// applied once the script has been loaded and validated.
+type pendingOptions struct {
+	applied bool // Subsequent calls are passed through.
+	target  Options
+	keys    []string
+	values  []string
+}
+
+var _ Options = (*pendingOptions)(nil)
+
+// Set implements Options. The option will not be passed to the target
+// until apply is called.
+func (o *pendingOptions) Set(key, value string) error {
+	if o.applied {
+		return o.target.Set(key, value)
+	}
+	if checker, ok := o.target.(optionChecker); ok {
+		if err := checker.check(key); err != nil {
+			return err
+		}
+	}
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+	return nil
+}
+
+// apply passes the recorded options to the target, in the order in
+// which they were set.
+func (o *pendingOptions) apply() error {
+	o.applied = true
+	for idx, key := range o.keys {
+		if err := o.target.Set(key, o.values[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
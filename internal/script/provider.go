@@ -18,10 +18,13 @@ package script
 
 import (
 	"context"
+	"maps"
 	"net/url"
 	"runtime"
 	"sync"
+	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/field-eng-powertools/workgroup"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
@@ -47,9 +50,60 @@ func ProvideLoader(
 		return nil, err
 	}
 
+	l := &Loader{
+		applyConfigs: applyConfigs,
+		cfg:          cfg,
+		diags:        diags,
+	}
+	cur, err := load(cfg, make(map[string]fileStamp))
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.applyOptions(); err != nil {
+		return nil, err
+	}
+	l.mu.current = cur
+	l.mu.generation = 1
+	l.mu.lastAttempt = time.Now()
+	l.mu.loadedAt = l.mu.lastAttempt
+	l.mu.observed = maps.Clone(cur.files)
+	scriptGeneration.Set(1)
+
 	// Return an empty version if unconfigured.
 	if cfg.FS == nil {
-		return &Loader{}, nil
+		return l, nil
+	}
+
+	stop := stopper.From(ctx)
+	l.tasks = workgroup.WithSize(ctx, 2*runtime.GOMAXPROCS(0), 100_000)
+	if err := diags.Register("userscript", l); err != nil {
+		return nil, err
+	}
+	stop.Defer(func() { diags.Unregister("userscript") })
+	stop.Go(func(ctx *stopper.Context) error {
+		l.watch(ctx)
+		return nil
+	})
+	return l, nil
+}
+
+// load executes the user script in a new JS runtime. The metadata of
+// each file that is read will be recorded in the map, even if an error
+// is returned. Calls to api.setOptions() are recorded, but will not
+// take effect until [loaded.applyOptions] is called.
+func load(cfg *Config, files map[string]fileStamp) (*loaded, error) {
+	var transforms map[string]*tableTransforms
+	if cfg.TransformsPath != "" {
+		var err error
+		transforms, err = loadTransforms(cfg.TransformsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Return an empty version if unconfigured.
+	if cfg.FS == nil {
+		return &loaded{transforms: transforms}, nil
 	}
 
 	options := cfg.Options
@@ -57,17 +111,16 @@ func ProvideLoader(
 		options = NoOptions
 	}
 
-	l := &Loader{
-		applyConfigs: applyConfigs,
-		diags:        diags,
+	l := &loaded{
+		files:        files,
 		fs:           cfg.FS,
-		options:      options,
+		options:      &pendingOptions{target: options},
 		requireCache: make(map[string]goja.Value),
 		rt:           goja.New(),
 		rtMu:         &sync.RWMutex{},
 		sources:      make(map[string]*sourceJS),
 		targets:      make(map[string]*targetJS),
-		tasks:        workgroup.WithSize(ctx, 2*runtime.GOMAXPROCS(0), 100_000),
+		transforms:   transforms,
 	}
 
 	// Use a "goja" tag on struct fields to control name bindings.
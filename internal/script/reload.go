@@ -0,0 +1,371 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+// This file contains support for replacing the user script while
+// Replicator is running. Each version of the script is executed in its
+// own JS runtime and is validated against every target schema that has
+// been bound before any changes are made. The UserScripts returned by
+// Loader.BindVar and the apply configurations are then replaced while
+// no caller is holding the Loader, which allows callers to switch to
+// the new version of the script between batches of mutations.
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// fileStamp is used to detect changes to a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampOf returns the fileStamp for the file.
+func stampOf(info fs.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// ReloadStatus describes the version of the user script that is in use.
+type ReloadStatus struct {
+	Files       []string  `json:"files"`       // The files that were loaded.
+	Generation  int64     `json:"generation"`  // Incremented by each successful load.
+	LastAttempt time.Time `json:"lastAttempt"` // The time of the most recent load.
+	LastError   string    `json:"lastError,omitempty"`
+	Loaded      time.Time `json:"loaded"` // The time of the most recent successful load.
+}
+
+// A binding records a call to [Loader.BindVar].
+type binding struct {
+	acceptor   types.TableAcceptor
+	configured []ident.Table // Tables with an apply configuration.
+	generation int64
+	script     notify.Var[*UserScript]
+	target     ident.Schema
+	watchers   types.Watchers
+}
+
+var _ diag.Diagnostic = (*binding)(nil)
+
+// Diagnostic implements [diag.Diagnostic].
+func (b *binding) Diagnostic(ctx context.Context) any {
+	scr, _ := b.script.Get()
+	ret := scr.Diagnostic(ctx).(map[string]any)
+	ret["generation"] = b.generation
+	return ret
+}
+
+var _ diag.Diagnostic = (*Loader)(nil)
+
+// Diagnostic implements [diag.Diagnostic].
+func (l *Loader) Diagnostic(_ context.Context) any {
+	return l.Status()
+}
+
+// BindVar is similar to [Loader.Bind], but the returned variable will
+// be updated with a new UserScript whenever the script is reloaded.
+// Callers should retrieve the UserScript from the variable once per
+// batch of mutations.
+func (l *Loader) BindVar(
+	ctx *stopper.Context,
+	target ident.Schematic,
+	targetAcceptor types.TableAcceptor,
+	watchers types.Watchers,
+) (*notify.Var[*UserScript], error) {
+	sch := target.Schema()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cur := l.mu.current
+	scr, err := l.bindScript(cur, sch, targetAcceptor, watchers)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := cur.applyConfigsFor(sch, watchers, scr.Targets)
+	if err != nil {
+		return nil, err
+	}
+	b := &binding{
+		acceptor:   targetAcceptor,
+		generation: l.mu.generation,
+		target:     sch,
+		watchers:   watchers,
+	}
+	b.script.Set(scr)
+
+	// In the unconfigured case, there is nothing to reload.
+	if cur.fs == nil {
+		return &b.script, l.installApplyConfigs(b, configs)
+	}
+
+	diagName := fmt.Sprintf("script-%s-%p", sch.Raw(), b)
+	if err := l.diags.Register(diagName, b); err != nil {
+		return nil, err
+	}
+	l.mu.bindings = append(l.mu.bindings, b)
+	ctx.Defer(func() {
+		l.diags.Unregister(diagName)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.mu.bindings = slices.DeleteFunc(l.mu.bindings,
+			func(elt *binding) bool { return elt == b })
+	})
+
+	return &b.script, l.installApplyConfigs(b, configs)
+}
+
+// Hold prevents a reloaded script from being installed until the
+// returned function is called. Callers should hold the Loader while
+// processing a batch of mutations, and retrieve the UserScript from
+// [Loader.BindVar] after calling Hold, so that the entire batch is
+// processed by one version of the script and its apply configurations.
+func (l *Loader) Hold() (release func()) {
+	l.swap.RLock()
+	return l.swap.RUnlock
+}
+
+// Configured returns true if a userscript has been configured.
+func (l *Loader) Configured() bool {
+	return l.cfg.FS != nil
+}
+
+// Reload executes the user script in a new JS runtime and validates it
+// against the target schemas that have been bound. If successful, the
+// UserScripts returned from [Loader.BindVar] will be replaced.
+// Otherwise, the previous version of the script remains in use. Calls
+// to api.setOptions() made by the new script take effect only if the
+// script is valid.
+func (l *Loader) Reload(ctx context.Context) (*ReloadStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.Configured() {
+		return nil, errors.New("no userscript has been configured")
+	}
+
+	l.mu.lastAttempt = time.Now()
+	err := l.reloadLocked()
+	l.mu.lastError = err
+	if err != nil {
+		scriptReloadErrors.Inc()
+		log.WithError(err).Warnf(
+			"could not reload userscript; generation %d remains in use", l.mu.generation)
+	} else {
+		scriptReloads.Inc()
+		log.Infof("reloaded userscript as generation %d", l.mu.generation)
+	}
+	return l.statusLocked(), err
+}
+
+// reloadLocked implements Reload.
+func (l *Loader) reloadLocked() error {
+	files := make(map[string]fileStamp)
+	next, err := load(l.cfg, files)
+	if err != nil {
+		// Changes to any file that was read should trigger a retry.
+		maps.Copy(l.mu.observed, files)
+		return err
+	}
+
+	// Validate the new script against every bound schema before
+	// making any changes.
+	scripts := make([]*UserScript, len(l.mu.bindings))
+	configs := make([]*ident.TableMap[*applycfg.Config], len(l.mu.bindings))
+	for idx, b := range l.mu.bindings {
+		scripts[idx], err = l.bindScript(next, b.target, b.acceptor, b.watchers)
+		if err != nil {
+			maps.Copy(l.mu.observed, files)
+			return errors.Wrapf(err, "userscript is not valid for %s", b.target)
+		}
+		configs[idx], err = next.applyConfigsFor(b.target, b.watchers, scripts[idx].Targets)
+		if err != nil {
+			maps.Copy(l.mu.observed, files)
+			return errors.Wrapf(err, "userscript is not valid for %s", b.target)
+		}
+	}
+
+	// Wait for in-flight batches to complete before making any changes.
+	l.swap.Lock()
+	defer l.swap.Unlock()
+
+	restore, err := l.installAllApplyConfigs(configs)
+	if err != nil {
+		maps.Copy(l.mu.observed, files)
+		return err
+	}
+
+	// Options are applied last, since they cannot be rolled back.
+	if err := next.applyOptions(); err != nil {
+		restore()
+		maps.Copy(l.mu.observed, files)
+		return err
+	}
+
+	l.mu.current = next
+	l.mu.generation++
+	l.mu.loadedAt = l.mu.lastAttempt
+	l.mu.observed = maps.Clone(files)
+	scriptGeneration.Set(float64(l.mu.generation))
+
+	for idx, b := range l.mu.bindings {
+		b.generation = l.mu.generation
+		b.script.Set(scripts[idx])
+	}
+	return nil
+}
+
+// Status returns the current state of the Loader.
+func (l *Loader) Status() *ReloadStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.statusLocked()
+}
+
+func (l *Loader) statusLocked() *ReloadStatus {
+	ret := &ReloadStatus{
+		Files:       slices.Sorted(maps.Keys(l.mu.current.files)),
+		Generation:  l.mu.generation,
+		LastAttempt: l.mu.lastAttempt,
+		Loaded:      l.mu.loadedAt,
+	}
+	if l.mu.lastError != nil {
+		ret.LastError = l.mu.lastError.Error()
+	}
+	return ret
+}
+
+// changed returns true if any file that was read by the most recent
+// load has been modified since it was last observed.
+func (l *Loader) changed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fsys := l.mu.current.fs
+	ret := false
+	for path, prev := range l.mu.observed {
+		var next fileStamp
+		// A missing file, which may be in the middle of being
+		// replaced, is represented by a zero value.
+		if info, err := fs.Stat(fsys, path); err == nil {
+			next = stampOf(info)
+		}
+		if next != prev {
+			l.mu.observed[path] = next
+			ret = true
+		}
+	}
+	return ret
+}
+
+// installApplyConfigs updates the apply configurations for the tables
+// in the binding's schema. Tables that are no longer configured are
+// reset to a default configuration.
+func (l *Loader) installApplyConfigs(b *binding, configs *ident.TableMap[*applycfg.Config]) error {
+	for _, tbl := range b.configured {
+		if _, ok := configs.Get(tbl); !ok {
+			if err := l.applyConfigs.Set(tbl, nil); err != nil {
+				return errors.Wrap(err, tbl.Raw())
+			}
+		}
+	}
+	b.configured = b.configured[:0]
+	for tbl, cfg := range configs.All() {
+		if err := l.applyConfigs.Set(tbl, cfg); err != nil {
+			return errors.Wrap(err, tbl.Raw())
+		}
+		b.configured = append(b.configured, tbl)
+	}
+	return nil
+}
+
+// installAllApplyConfigs updates the apply configurations for all
+// bindings. If an error occurs, the previous configurations will be
+// restored. Otherwise, a function is returned that will restore the
+// previous configurations if a later step of a reload fails.
+func (l *Loader) installAllApplyConfigs(
+	configs []*ident.TableMap[*applycfg.Config],
+) (restore func(), _ error) {
+	// Record the existing configurations of all affected tables.
+	var prev ident.TableMap[*applycfg.Config]
+	prevConfigured := make([][]ident.Table, len(l.mu.bindings))
+	for idx, b := range l.mu.bindings {
+		prevConfigured[idx] = slices.Clone(b.configured)
+		for _, tbl := range b.configured {
+			cfg, _ := l.applyConfigs.Get(tbl).Get()
+			prev.Put(tbl, cfg)
+		}
+		for tbl := range configs[idx].Keys() {
+			cfg, _ := l.applyConfigs.Get(tbl).Get()
+			prev.Put(tbl, cfg)
+		}
+	}
+	restore = func() {
+		for tbl, cfg := range prev.All() {
+			if err := l.applyConfigs.Set(tbl, cfg); err != nil {
+				log.WithError(err).Warnf("could not restore apply configuration for %s", tbl)
+			}
+		}
+		for idx, b := range l.mu.bindings {
+			b.configured = prevConfigured[idx]
+		}
+	}
+
+	for idx, b := range l.mu.bindings {
+		if err := l.installApplyConfigs(b, configs[idx]); err != nil {
+			restore()
+			return nil, errors.Wrapf(err, "could not install apply configurations for %s", b.target)
+		}
+	}
+	return restore, nil
+}
+
+// watch polls the files that were loaded and reloads the script when
+// they change.
+func (l *Loader) watch(ctx *stopper.Context) {
+	if l.cfg.ReloadPeriod <= 0 {
+		return
+	}
+	ticker := time.NewTicker(l.cfg.ReloadPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Stopping():
+			return
+		case <-ticker.C:
+		}
+		if !l.changed() {
+			continue
+		}
+		log.Info("userscript files have changed; reloading")
+		// Errors are logged by Reload.
+		_, _ = l.Reload(ctx)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadMain = `
+import * as api from "replicator@v1";
+import {table} from "./lib";
+api.configureTable(table, {map: doc => doc});
+`
+
+func TestReload(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	schema := fixture.TargetSchema.Schema()
+	for _, name := range []string{"table1", "table2"} {
+		_, err = fixture.TargetPool.ExecContext(ctx,
+			fmt.Sprintf("CREATE TABLE %s.%s(idx INT PRIMARY KEY)", schema, name))
+		r.NoError(err)
+	}
+	r.NoError(fixture.Watcher.Refresh(ctx, fixture.TargetPool))
+	tbl1 := ident.NewTable(schema, ident.New("table1"))
+	tbl2 := ident.NewTable(schema, ident.New("table2"))
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		r.NoError(os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+	writeFile("main.ts", reloadMain)
+	writeFile("lib.ts", `export const table = "table1";`)
+
+	cfg := &Config{
+		ReloadPeriod:   -1,
+		UserScriptPath: filepath.Join(dir, "main.ts"),
+	}
+	loader, err := ProvideLoader(ctx, fixture.Configs, cfg, fixture.Diagnostics)
+	r.NoError(err)
+
+	v, err := loader.BindVar(ctx, fixture.TargetSchema, fixture.ApplyAcceptor, fixture.Watchers)
+	r.NoError(err)
+	scr, _ := v.Get()
+	_, found := scr.Targets.Get(tbl1)
+	r.True(found)
+	status := loader.Status()
+	r.Equal(int64(1), status.Generation)
+	r.Equal([]string{"lib.ts", "main.ts"}, status.Files)
+
+	// Change an imported module.
+	writeFile("lib.ts", `export const table = "table2";`)
+	status, err = loader.Reload(ctx)
+	r.NoError(err)
+	r.Equal(int64(2), status.Generation)
+	scr, _ = v.Get()
+	_, found = scr.Targets.Get(tbl1)
+	r.False(found)
+	_, found = scr.Targets.Get(tbl2)
+	r.True(found)
+
+	// A script that cannot be bound to the schema should be rejected
+	// and the previous version should remain in use.
+	writeFile("lib.ts", `export const table = "not_a_table";`)
+	_, err = loader.Reload(ctx)
+	r.Error(err)
+	next, _ := v.Get()
+	r.Same(scr, next)
+	status = loader.Status()
+	r.Equal(int64(2), status.Generation)
+	r.NotEmpty(status.LastError)
+
+	// The same is true of a script that cannot be executed.
+	writeFile("main.ts", "this is not valid")
+	_, err = loader.Reload(ctx)
+	r.Error(err)
+	next, _ = v.Get()
+	r.Same(scr, next)
+
+	// Enable polling and fix the script.
+	loader.cfg.ReloadPeriod = 10 * time.Millisecond
+	ctx.Go(func(ctx *stopper.Context) error {
+		loader.watch(ctx)
+		return nil
+	})
+	writeFile("main.ts", reloadMain)
+	writeFile("lib.ts", `export const table = "table1";`)
+	r.Eventually(func() bool {
+		return loader.Status().Generation == 3
+	}, time.Minute, 10*time.Millisecond)
+	scr, _ = v.Get()
+	_, found = scr.Targets.Get(tbl1)
+	r.True(found)
+	r.Empty(loader.Status().LastError)
+
+	// A reloaded script is not installed while the Loader is held.
+	release := loader.Hold()
+	writeFile("lib.ts", `export const table = "table2";`)
+	reloaded := make(chan error, 1)
+	go func() {
+		_, err := loader.Reload(ctx)
+		reloaded <- err
+	}()
+	select {
+	case err := <-reloaded:
+		r.Failf("reload should wait", "%v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	next, _ = v.Get()
+	r.Same(scr, next)
+	release()
+	r.NoError(<-reloaded)
+	scr, _ = v.Get()
+	_, found = scr.Targets.Get(tbl2)
+	r.True(found)
+}
+
+// Options set by a script that fails to load should not take effect.
+func TestReloadOptions(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	dir := t.TempDir()
+	writeFile := func(contents string) {
+		r.NoError(os.WriteFile(filepath.Join(dir, "main.ts"), []byte(contents), 0644))
+	}
+	writeFile(`
+import * as api from "replicator@v1";
+api.setOptions({"hello": "world"});
+`)
+
+	var opts mapOptions
+	cfg := &Config{
+		Options:        &opts,
+		ReloadPeriod:   -1,
+		UserScriptPath: filepath.Join(dir, "main.ts"),
+	}
+	loader, err := ProvideLoader(ctx, &applycfg.Configs{}, cfg, diag.New(ctx))
+	r.NoError(err)
+	r.Equal(map[string]string{"hello": "world"}, opts.data)
+
+	// The script throws after setting an option.
+	writeFile(`
+import * as api from "replicator@v1";
+api.setOptions({"hello": "bad"});
+throw new Error("boom");
+`)
+	_, err = loader.Reload(ctx)
+	r.ErrorContains(err, "boom")
+	r.Equal(map[string]string{"hello": "world"}, opts.data)
+
+	// A valid script updates the options.
+	writeFile(`
+import * as api from "replicator@v1";
+api.setOptions({"hello": "again"});
+`)
+	_, err = loader.Reload(ctx)
+	r.NoError(err)
+	r.Equal(map[string]string{"hello": "again"}, opts.data)
+}
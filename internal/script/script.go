@@ -197,7 +197,7 @@ func (s *UserScript) Resumed(obj any) {
 
 // bind validates the user configuration against the target schema and
 // creates the public facade around JS callbacks.
-func (s *UserScript) bind(loader *Loader) error {
+func (s *UserScript) bind(loader *loaded) error {
 	// Evaluate calls to api.configureSource(). We implement a
 	// last-one-wins approach if there are multiple calls for the same
 	// source name.
@@ -287,6 +287,12 @@ func (s *UserScript) bind(loader *Loader) error {
 		if bag.Extras != "" {
 			tgt.Extras = ident.New(bag.Extras)
 		}
+		if bag.History.Table != "" {
+			tgt.History = ident.New(bag.History.Table)
+			for _, col := range bag.History.Columns {
+				tgt.HistoryCols = append(tgt.HistoryCols, ident.New(col))
+			}
+		}
 		if bag.Map == nil {
 			tgt.Map = identity
 		} else {
@@ -303,7 +309,12 @@ func (s *UserScript) bind(loader *Loader) error {
 				tgt.Ignore.Put(ident.New(k), true)
 			}
 		}
+		tgt.CopyThreshold = bag.CopyThreshold
 		tgt.RowLimit = bag.RowLimit
+		if bag.SoftDelete != "" {
+			tgt.SoftDelete = ident.New(bag.SoftDelete)
+		}
+		tgt.UpdateChanged = bag.UpdateChanged
 	}
 
 	return nil
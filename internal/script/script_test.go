@@ -209,24 +209,33 @@ CREATE TABLE %s.skewed_merge_times(
 	table := ident.NewTable(schema, ident.New("all_features"))
 	if cfg := s.Targets.GetZero(table); a.NotNil(cfg) {
 		expectedApply := applycfg.Config{
-			CASColumns: []ident.Ident{ident.New("cas0"), ident.New("cas1")},
+			CASColumns:    []ident.Ident{ident.New("cas0"), ident.New("cas1")},
+			Casts:         &ident.Map[string]{},
+			Constants:     &ident.Map[any]{},
+			CopyThreshold: 5000,
 			Deadlines: ident.MapOf[time.Duration](
 				ident.New("dl0"), time.Hour,
 				ident.New("dl1"), time.Minute,
 			),
+			Defaults: &ident.Map[any]{},
 			Exprs: ident.MapOf[string](
 				ident.New("expr0"), "fnv32($0::BYTES)",
 				ident.New("expr1"), "Hello Library!",
 			),
-			Extras: ident.New("overflow_column"),
+			Extras:      ident.New("overflow_column"),
+			History:     ident.New("all_features_history"),
+			HistoryCols: []ident.Ident{ident.New("val")},
 			Ignore: ident.MapOf[bool](
 				ident.New("ign0"), true,
 				ident.New("ign1"), true,
 				// The false value is dropped.
 			),
 			// SourceName not used; that can be handled by the function.
-			SourceNames: &ident.Map[applycfg.SourceColumn]{},
-			RowLimit:    99,
+			SourceNames:   &ident.Map[applycfg.SourceColumn]{},
+			RowLimit:      99,
+			SoftDelete:    ident.New("deleted_at"),
+			Templates:     &ident.Map[string]{},
+			UpdateChanged: true,
 		}
 		a.True(expectedApply.Equal(&cfg.Config))
 
@@ -118,6 +118,11 @@ api.configureTable("all_features", {
     },
     // Place unmapped data into JSONB column.
     extras: "overflow_column",
+    // Record versions of each row in a history table.
+    history: {
+        columns: ["val"],
+        table: "all_features_history",
+    },
     // Allow column in target database to be ignored.
     ignore: {
         "ign0": true,
@@ -153,6 +158,13 @@ api.configureTable("all_features", {
     // is mainly needed for ultra-wide tables and databases with a
     // relatively small number of available bind variables.
     rowLimit: 99,
+    // Batches with at least this many rows will be sent using a
+    // bulk-copy mechanism, if the target supports one.
+    copyThreshold: 5000,
+    // Mark rows as deleted instead of removing them.
+    softDelete: "deleted_at",
+    // Only write changed columns when a before value is available.
+    updateChanged: true,
 });
 
 // Elide all deletes for the table, e.g.: for archival use cases.
@@ -205,6 +205,16 @@ declare module "replicator@v1" {
          * A list of columns to enable compare-and-set behavior.
          */
         cas: Column[];
+        /**
+         * The minimum number of rows in a batch before a bulk-copy
+         * mechanism will be used, for targets that support one. A
+         * negative value disables the bulk-copy mechanism. If unset,
+         * a reasonable value will be chosen. The mechanism is only used
+         * for batches that are applied within a target transaction, as
+         * in the consistent modes; the immediate and best-effort modes
+         * always use statements.
+         */
+        copyThreshold: number;
         /**
          * Enable deadlining behavior, to discard mutations when the
          * named timestamp column is older than the given duration.
@@ -230,6 +240,17 @@ declare module "replicator@v1" {
          * stored in.
          */
         extras: Column;
+        /**
+         * Append a version of each row to a history table in the
+         * target schema. The versions record the rows as they were
+         * applied, after any merge or compare-and-set behaviors. Only
+         * changes to the tracked columns, which default to all
+         * columns, will create a new version.
+         */
+        history: {
+            columns?: Column[];
+            table: Table;
+        };
         /**
          * Columns that may be ignored in the input data. This allows,
          * for example, columns to be dropped from the destination
@@ -256,6 +277,23 @@ declare module "replicator@v1" {
          * variables. If unset, a reasonable value will be chosen.
          */
         rowLimit: number;
+        /**
+         * The name of a column that marks rows as deleted. If set,
+         * deletions will update this column instead of removing the
+         * row from the target table. A boolean column is set to true,
+         * an integer column to the source timestamp's nanoseconds, a
+         * date or time column to the source wall time, and any other
+         * column to the source HLC timestamp. An upsert of a deleted
+         * row resets the column to false or NULL.
+         */
+        softDelete: Column;
+        /**
+         * If true, an update whose before value is known will only
+         * write the columns whose values have changed. Rows which are
+         * missing from the target table are upserted instead. This
+         * option has no effect on tables with CAS or deadline columns.
+         */
+        updateChanged: boolean;
     };
 
     /**
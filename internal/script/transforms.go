@@ -0,0 +1,139 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"os"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/transform"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// tableTransforms is the declarative configuration for a single table,
+// as loaded from the file named by [Config.TransformsPath]. The file
+// contains a mapping of table names, which are resolved relative to the
+// target schema, to this type. The fields mirror those in
+// [applycfg.Config].
+type tableTransforms struct {
+	Casts       map[string]string `yaml:"casts"`
+	Constants   map[string]any    `yaml:"constants"`
+	Defaults    map[string]any    `yaml:"defaults"`
+	Exprs       map[string]string `yaml:"exprs"`
+	Filters     []string          `yaml:"filters"`
+	Ignore      []string          `yaml:"ignore"`
+	SourceNames map[string]string `yaml:"sourceNames"`
+	Templates   map[string]string `yaml:"templates"`
+}
+
+// loadTransforms reads the declarative transforms from the file. Since
+// JSON is a subset of YAML, either format may be used.
+func loadTransforms(path string) (map[string]*tableTransforms, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var ret map[string]*tableTransforms
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&ret); err != nil {
+		return nil, errors.Wrapf(err, "could not decode transforms file %s", path)
+	}
+	return ret, nil
+}
+
+// config converts the declarative transforms into an apply
+// configuration.
+func (t *tableTransforms) config() *applycfg.Config {
+	ret := applycfg.NewConfig()
+	if t == nil {
+		return ret
+	}
+	for k, v := range t.Casts {
+		ret.Casts.Put(ident.New(k), v)
+	}
+	for k, v := range t.Constants {
+		ret.Constants.Put(ident.New(k), v)
+	}
+	for k, v := range t.Defaults {
+		ret.Defaults.Put(ident.New(k), v)
+	}
+	for k, v := range t.Exprs {
+		ret.Exprs.Put(ident.New(k), v)
+	}
+	ret.Filters = append(ret.Filters, t.Filters...)
+	for _, k := range t.Ignore {
+		ret.Ignore.Put(ident.New(k), true)
+	}
+	for k, v := range t.SourceNames {
+		ret.SourceNames.Put(ident.New(k), ident.New(v))
+	}
+	for k, v := range t.Templates {
+		ret.Templates.Put(ident.New(k), v)
+	}
+	return ret
+}
+
+// applyConfigsFor resolves the declarative transforms against the
+// target schema and validates them against the schema's column data.
+// The resulting configurations are combined with those defined by the
+// userscript, which take precedence.
+func (l *loaded) applyConfigsFor(
+	sch ident.Schema, watchers types.Watchers, scripted *ident.TableMap[*Target],
+) (*ident.TableMap[*applycfg.Config], error) {
+	var configs ident.TableMap[*applycfg.Config]
+	if len(l.transforms) > 0 {
+		watcher, err := watchers.Get(sch)
+		if err != nil {
+			return nil, err
+		}
+		allCols := watcher.Get().Columns
+		for tableName, spec := range l.transforms {
+			tbl, _, err := ident.ParseTableRelative(tableName, sch)
+			if err != nil {
+				return nil, errors.Wrapf(err, "transforms for %q", tableName)
+			}
+			cols, ok := allCols.Get(tbl)
+			if !ok {
+				return nil, errors.Errorf("transforms for %q reference a non-existent table %s",
+					tableName, tbl)
+			}
+			cfg := spec.config()
+			if _, err := transform.Compile(cfg, cols); err != nil {
+				return nil, errors.Wrapf(err, "transforms for %q", tableName)
+			}
+			configs.Put(tbl, cfg)
+		}
+	}
+
+	if scripted != nil {
+		for tbl, tgt := range scripted.All() {
+			cfg := &tgt.Config
+			if declared, ok := configs.Get(tbl); ok {
+				cfg = declared.Patch(cfg)
+			}
+			configs.Put(tbl, cfg)
+		}
+	}
+
+	return &configs, nil
+}
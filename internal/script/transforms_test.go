@@ -0,0 +1,96 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTransforms(t *testing.T) {
+	expected := applycfg.NewConfig()
+	expected.Casts.Put(ident.New("qty"), "int")
+	expected.Constants.Put(ident.New("region"), "us-east")
+	expected.Defaults.Put(ident.New("status"), map[string]any{"code": 1})
+	expected.Exprs.Put(ident.New("total"), "$0 * 100")
+	expected.Filters = []string{`{{ ne .status "test" }}`}
+	expected.Ignore.Put(ident.New("legacy"), true)
+	expected.SourceNames.Put(ident.New("customer"), ident.New("cust"))
+	expected.Templates.Put(ident.New("full_name"), "{{ .first }} {{ .last }}")
+
+	tcs := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "transforms.yaml",
+			data: `
+orders:
+  casts: {qty: int}
+  constants: {region: us-east}
+  defaults:
+    status: {code: 1}
+  exprs: {total: "$0 * 100"}
+  filters:
+    - '{{ ne .status "test" }}'
+  ignore: [legacy]
+  sourceNames: {customer: cust}
+  templates:
+    full_name: "{{ .first }} {{ .last }}"
+`,
+		},
+		{
+			name: "transforms.json",
+			data: `{"orders": {
+  "casts": {"qty": "int"},
+  "constants": {"region": "us-east"},
+  "defaults": {"status": {"code": 1}},
+  "exprs": {"total": "$0 * 100"},
+  "filters": ["{{ ne .status \"test\" }}"],
+  "ignore": ["legacy"],
+  "sourceNames": {"customer": "cust"},
+  "templates": {"full_name": "{{ .first }} {{ .last }}"}
+}}`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			path := filepath.Join(t.TempDir(), tc.name)
+			r.NoError(os.WriteFile(path, []byte(tc.data), 0644))
+
+			loaded, err := loadTransforms(path)
+			r.NoError(err)
+			r.Len(loaded, 1)
+			cfg := loaded["orders"].config()
+			r.True(expected.Equal(cfg), "%#v", cfg)
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		r := require.New(t)
+		path := filepath.Join(t.TempDir(), "transforms.yaml")
+		r.NoError(os.WriteFile(path, []byte("orders:\n  cast: {qty: int}\n"), 0644))
+		_, err := loadTransforms(path)
+		r.ErrorContains(err, "field cast not found")
+	})
+}
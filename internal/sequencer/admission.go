@@ -0,0 +1,130 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sequencer
+
+import (
+	"context"
+	"iter"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+)
+
+// An Admission is called by a Sequencer before it writes a batch of
+// mutations to the target. Sequencers that open a target transaction
+// must call the Admission before doing so, so that an Admission may
+// block without holding any target resources. The returned function
+// must be called once the batch has been committed or abandoned.
+type Admission func(
+	ctx context.Context, muts iter.Seq2[ident.Table, types.Mutation],
+) (release func(), err error)
+
+// Admit calls the Admission. A nil Admission admits all batches.
+func (a Admission) Admit(
+	ctx context.Context, muts iter.Seq2[ident.Table, types.Mutation],
+) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	return a(ctx, muts)
+}
+
+// Then returns an Admission which calls the receiver before calling
+// next. Shims should use this method to add to
+// [StartOptions.Admit], so that admissions are evaluated in the order
+// in which the shims were started.
+func (a Admission) Then(next Admission) Admission {
+	if a == nil {
+		return next
+	}
+	if next == nil {
+		return a
+	}
+	return func(ctx context.Context, muts iter.Seq2[ident.Table, types.Mutation]) (func(), error) {
+		releaseA, err := a(ctx, muts)
+		if err != nil {
+			return nil, err
+		}
+		releaseNext, err := next(ctx, muts)
+		if err != nil {
+			releaseA()
+			return nil, err
+		}
+		return func() {
+			releaseNext()
+			releaseA()
+		}, nil
+	}
+}
+
+// AdmittingAcceptor returns an acceptor that calls the Admission
+// before calling the delegate. It is intended for use by Sequencers
+// which do not open target transactions themselves.
+func AdmittingAcceptor(admit Admission, delegate types.MultiAcceptor) types.MultiAcceptor {
+	if admit == nil {
+		return delegate
+	}
+	return &admittingAcceptor{admit, delegate}
+}
+
+type admittingAcceptor struct {
+	admit    Admission
+	delegate types.MultiAcceptor
+}
+
+var _ types.MultiAcceptor = (*admittingAcceptor)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *admittingAcceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	release, err := a.admit(ctx, batch.Mutations())
+	if err != nil {
+		return err
+	}
+	defer release()
+	return a.delegate.AcceptMultiBatch(ctx, batch, opts)
+}
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (a *admittingAcceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	release, err := a.admit(ctx, batch.Mutations())
+	if err != nil {
+		return err
+	}
+	defer release()
+	return a.delegate.AcceptTableBatch(ctx, batch, opts)
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (a *admittingAcceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	release, err := a.admit(ctx, batch.Mutations())
+	if err != nil {
+		return err
+	}
+	defer release()
+	return a.delegate.AcceptTemporalBatch(ctx, batch, opts)
+}
+
+// Unwrap is an informal protocol to return the delegate.
+func (a *admittingAcceptor) Unwrap() types.MultiAcceptor {
+	return a.delegate
+}
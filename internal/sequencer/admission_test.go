@@ -0,0 +1,64 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sequencer
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionThen(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	var events []string
+	admission := func(name string, err error) Admission {
+		return func(context.Context, iter.Seq2[ident.Table, types.Mutation]) (func(), error) {
+			if err != nil {
+				events = append(events, name+" failed")
+				return nil, err
+			}
+			events = append(events, name+" admitted")
+			return func() { events = append(events, name+" released") }, nil
+		}
+	}
+
+	// A nil Admission admits everything.
+	var none Admission
+	release, err := none.Admit(ctx, nil)
+	r.NoError(err)
+	release()
+
+	chain := none.Then(admission("a", nil)).Then(admission("b", nil))
+	release, err = chain.Admit(ctx, nil)
+	r.NoError(err)
+	release()
+	r.Equal([]string{"a admitted", "b admitted", "b released", "a released"}, events)
+
+	// An earlier admission is released if a later one fails.
+	events = nil
+	chain = admission("a", nil).Then(admission("b", errors.New("boom")))
+	_, err = chain.Admit(ctx, nil)
+	r.ErrorContains(err, "boom")
+	r.Equal([]string{"a admitted", "b failed", "a released"}, events)
+}
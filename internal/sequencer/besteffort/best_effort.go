@@ -212,6 +212,7 @@ func (s *bestEffort) startGeneration(
 			log.Tracef("enabling direct path for %s", comp.Order[0])
 			subAcc = &directAcceptor{
 				BestEffort: s.BestEffort,
+				admit:      subOpts.Admit,
 				apply:      subOpts.Delegate,
 				fallback:   subAcc,
 			}
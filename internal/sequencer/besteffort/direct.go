@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/lockset"
 	"github.com/cockroachdb/replicator/internal/util/metrics"
@@ -32,6 +33,7 @@ import (
 // target database or will delegate them.
 type directAcceptor struct {
 	*BestEffort
+	admit    sequencer.Admission
 	apply    types.MultiAcceptor
 	fallback types.MultiAcceptor
 }
@@ -70,6 +72,14 @@ func (a *directAcceptor) AcceptTableBatch(
 		return err
 	}
 
+	// The admission may block, so it must be called before opening the
+	// staging transaction.
+	release, err := a.admit.Admit(ctx, batch.Mutations())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Perform work via the scheduler to ensure we can't step on
 	// anyone's toes.
 	outcome := a.scheduler.TableBatch(batch, func() error {
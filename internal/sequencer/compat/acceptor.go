@@ -0,0 +1,72 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compat
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// An observer records the columns present in incoming mutations
+// before passing them along.
+type observer struct {
+	delegate types.MultiAcceptor
+	gate     *gate
+}
+
+var _ types.MultiAcceptor = (*observer)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (o *observer) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	o.gate.observe(batch.Mutations())
+	return o.delegate.AcceptMultiBatch(ctx, batch, opts)
+}
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (o *observer) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	o.gate.observe(batch.Mutations())
+	return o.delegate.AcceptTableBatch(ctx, batch, opts)
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (o *observer) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	o.gate.observe(batch.Mutations())
+	return o.delegate.AcceptTemporalBatch(ctx, batch, opts)
+}
+
+// propertyNames returns the top-level keys of a JSON object.
+func propertyNames(data json.RawMessage) (*ident.Map[struct{}], error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(data, &props); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret := &ident.Map[struct{}]{}
+	for name := range props {
+		ret.Put(ident.New(name), struct{}{})
+	}
+	return ret, nil
+}
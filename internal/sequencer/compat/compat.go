@@ -0,0 +1,142 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package compat provides a sequencer shim that pauses a table group
+// when the target schema changes in a way that is incompatible with
+// the data being sent by the source.
+package compat
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+)
+
+// Compat compares refreshed target schema snapshots to the columns
+// that are being sent for each table in a group. If a table or column
+// that was previously present is dropped, or if a column's type is
+// narrowed, delivery of mutations to the target will be paused until
+// the schema is compatible again. Mutations continue to be accepted
+// into staging while the group is paused.
+type Compat struct {
+	configs    *applycfg.Configs
+	targetPool *types.TargetPool
+	watchers   types.Watchers
+
+	mu struct {
+		sync.Mutex
+		gates ident.Map[*gate]
+	}
+}
+
+var (
+	_ diag.Diagnostic = (*Compat)(nil)
+	_ sequencer.Shim  = (*Compat)(nil)
+)
+
+// Diagnostic implements [diag.Diagnostic]. It reports the status of
+// each table group.
+func (c *Compat) Diagnostic(_ context.Context) any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make(map[string]*Status, c.mu.gates.Len())
+	for name, g := range c.mu.gates.All() {
+		ret[name.Raw()], _ = g.status.Get()
+	}
+	return ret
+}
+
+// Status returns the current status of the named group, or nil if the
+// group has not been started.
+func (c *Compat) Status(group ident.Ident) *Status {
+	c.mu.Lock()
+	g, ok := c.mu.gates.Get(group)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	ret, _ := g.status.Get()
+	return ret
+}
+
+// Wrap implements [sequencer.Shim].
+func (c *Compat) Wrap(
+	_ *stopper.Context, delegate sequencer.Sequencer,
+) (sequencer.Sequencer, error) {
+	return &wrapper{c, delegate}, nil
+}
+
+type wrapper struct {
+	*Compat
+	delegate sequencer.Sequencer
+}
+
+var _ sequencer.Sequencer = (*wrapper)(nil)
+
+// Start injects the compatibility gate into the Sequencer stack.
+func (w *wrapper) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	watcher, err := w.watchers.Get(opts.Group.Enclosing)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := newGate(opts.Group, w.configs, w.targetPool.Product, watcher.Get())
+
+	// Block before a target transaction is opened.
+	opts = opts.Copy()
+	opts.Admit = opts.Admit.Then(g.admit)
+
+	acc, stat, err := w.delegate.Start(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.mu.Lock()
+	w.mu.gates.Put(opts.Group.Name, g)
+	w.mu.Unlock()
+
+	ctx.Go(func(ctx *stopper.Context) error {
+		defer func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if found, ok := w.mu.gates.Get(opts.Group.Name); ok && found == g {
+				w.mu.gates.Delete(opts.Group.Name)
+			}
+		}()
+		_, err := stopvar.DoWhenChanged(ctx, nil, watcher.GetNotify(),
+			func(ctx *stopper.Context, _, next *types.SchemaData) error {
+				g.onSchema(next)
+				return nil
+			})
+		return err
+	})
+
+	return &observer{delegate: acc, gate: g}, stat, nil
+}
+
+// Unwrap is an informal protocol to return the delegate.
+func (w *wrapper) Unwrap() sequencer.Sequencer {
+	return w.delegate
+}
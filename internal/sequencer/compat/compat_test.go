@@ -0,0 +1,194 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+type countingAcceptor struct {
+	types.MultiAcceptor
+	count chan struct{}
+}
+
+func (a *countingAcceptor) AcceptTableBatch(
+	context.Context, *types.TableBatch, *types.AcceptOptions,
+) error {
+	a.count <- struct{}{}
+	return nil
+}
+
+func snapshot(tbl ident.Table, cols ...types.ColData) *types.SchemaData {
+	ret := &types.SchemaData{Columns: &ident.TableMap[[]types.ColData]{}}
+	if len(cols) > 0 {
+		ret.Columns.Put(tbl, cols)
+	}
+	return ret
+}
+
+func TestGate(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	tbl := ident.NewTable(schema, ident.New("tbl"))
+	pk := types.ColData{Name: ident.New("pk"), Primary: true, Type: "INT8"}
+	val := types.ColData{Name: ident.New("val"), Type: "INT8"}
+	other := types.ColData{Name: ident.New("other"), Type: "STRING"}
+
+	g := newGate(&types.TableGroup{Name: ident.New("test"), Enclosing: schema},
+		nil, types.ProductCockroachDB, snapshot(tbl, pk, other, val))
+
+	// Send data for pk and val only.
+	batch := &types.TableBatch{Table: tbl, Time: hlc.New(1, 0)}
+	r.NoError(batch.Accumulate(tbl, types.Mutation{
+		Data: json.RawMessage(`{"pk":1,"val":2}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: hlc.New(1, 0),
+	}))
+
+	acc := &countingAcceptor{count: make(chan struct{}, 1)}
+	gated := sequencer.AdmittingAcceptor(g.admit, acc)
+	obs := &observer{delegate: gated, gate: g}
+	r.NoError(obs.AcceptTableBatch(ctx, batch, &types.AcceptOptions{}))
+	<-acc.count
+
+	// Dropping a column that is not being sent is compatible.
+	g.onSchema(snapshot(tbl, pk, val))
+	r.False(g.isPaused())
+
+	// Widening a column is compatible.
+	g.onSchema(snapshot(tbl, pk, types.ColData{Name: ident.New("val"), Type: "DECIMAL"}))
+	r.False(g.isPaused())
+
+	// Dropping a column that is being sent pauses the group.
+	g.onSchema(snapshot(tbl, pk))
+	st, _ := g.status.Get()
+	r.True(st.Paused)
+	r.Equal([]Problem{{
+		Column: "val",
+		Reason: "column was dropped",
+		Table:  tbl.Raw(),
+	}}, st.Problems)
+
+	// Mutations should be held.
+	errs := make(chan error, 1)
+	go func() { errs <- obs.AcceptTableBatch(ctx, batch, &types.AcceptOptions{}) }()
+	select {
+	case <-acc.count:
+		r.Fail("gate should be closed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Narrowing the restored column is still incompatible.
+	g.onSchema(snapshot(tbl, pk, types.ColData{Name: ident.New("val"), Type: "INT2"}))
+	st, _ = g.status.Get()
+	r.True(st.Paused)
+	r.Equal("type changed from DECIMAL to INT2", st.Problems[0].Reason)
+
+	// Restoring the column resumes delivery.
+	g.onSchema(snapshot(tbl, pk, types.ColData{Name: ident.New("val"), Type: "DECIMAL"}))
+	r.False(g.isPaused())
+	<-acc.count
+	r.NoError(<-errs)
+
+	// Dropping the table pauses the group.
+	g.onSchema(snapshot(tbl))
+	st, _ = g.status.Get()
+	r.True(st.Paused)
+	r.Equal("table was dropped", st.Problems[0].Reason)
+
+	// Recreating the table resumes delivery.
+	g.onSchema(snapshot(tbl, pk, types.ColData{Name: ident.New("val"), Type: "DECIMAL"}))
+	r.False(g.isPaused())
+
+	// The column that was dropped before it was observed is reported
+	// once the source sends it.
+	withOther := &types.TableBatch{Table: tbl, Time: hlc.New(2, 0)}
+	r.NoError(withOther.Accumulate(tbl, types.Mutation{
+		Data: json.RawMessage(`{"pk":1,"val":2,"other":"x"}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: hlc.New(2, 0),
+	}))
+	g.observe(withOther.Mutations())
+	st, _ = g.status.Get()
+	r.True(st.Paused)
+	r.Equal([]Problem{{
+		Column: "other",
+		Reason: "column was dropped",
+		Table:  tbl.Raw(),
+	}}, st.Problems)
+
+	// Restoring the column resumes delivery.
+	g.onSchema(snapshot(tbl, pk, other, types.ColData{Name: ident.New("val"), Type: "DECIMAL"}))
+	r.False(g.isPaused())
+}
+
+func TestGateSourceNames(t *testing.T) {
+	r := require.New(t)
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	tbl := ident.NewTable(schema, ident.New("tbl"))
+	pk := types.ColData{Name: ident.New("pk"), Primary: true, Type: "INT8"}
+	renamed := types.ColData{Name: ident.New("renamed"), Type: "INT8"}
+	val := types.ColData{Name: ident.New("val"), Type: "INT8"}
+
+	// The source sends "val", which is applied to the "renamed"
+	// column. The source also sends "secret", which is ignored.
+	configs := &applycfg.Configs{}
+	cfg := applycfg.NewConfig()
+	cfg.SourceNames.Put(renamed.Name, ident.New("val"))
+	cfg.Ignore.Put(ident.New("secret"), true)
+	r.NoError(configs.Set(tbl, cfg))
+
+	g := newGate(&types.TableGroup{Name: ident.New("test"), Enclosing: schema},
+		configs, types.ProductCockroachDB, snapshot(tbl, pk, renamed, val))
+
+	batch := &types.TableBatch{Table: tbl, Time: hlc.New(1, 0)}
+	r.NoError(batch.Accumulate(tbl, types.Mutation{
+		Data: json.RawMessage(`{"pk":1,"val":2,"secret":3}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: hlc.New(1, 0),
+	}))
+	g.observe(batch.Mutations())
+
+	// Dropping the target column with the same name as the source
+	// property is compatible.
+	g.onSchema(snapshot(tbl, pk, renamed))
+	r.False(g.isPaused())
+
+	// Dropping the target of the rename pauses the group.
+	g.onSchema(snapshot(tbl, pk))
+	st, _ := g.status.Get()
+	r.True(st.Paused)
+	r.Equal([]Problem{{
+		Column: "renamed",
+		Reason: "column was dropped",
+		Table:  tbl.Raw(),
+	}}, st.Problems)
+}
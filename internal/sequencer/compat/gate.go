@@ -0,0 +1,372 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compat
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// A Problem describes a single breaking change to the target schema.
+type Problem struct {
+	Column string `json:"column,omitempty"`
+	Reason string `json:"reason"`
+	Table  string `json:"table"`
+}
+
+// String is used for logging.
+func (p Problem) String() string {
+	if p.Column == "" {
+		return fmt.Sprintf("%s: %s", p.Table, p.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", p.Table, p.Column, p.Reason)
+}
+
+// Status is reported through the diagnostics endpoint.
+type Status struct {
+	Paused   bool      `json:"paused"`
+	Problems []Problem `json:"problems,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// A gate tracks the compatibility of a single table group.
+type gate struct {
+	configs *applycfg.Configs
+	group   *types.TableGroup
+	product types.Product
+	status  *notify.Var[*Status]
+
+	paused prometheus.Gauge
+	pauses prometheus.Counter
+
+	mu struct {
+		sync.Mutex
+		// The last compatible definition of each table. Only the
+		// tables and columns that have been observed are advanced, so
+		// that a change to an unobserved column will be compared to
+		// the original definition once the column is observed.
+		baseline *ident.TableMap[[]types.ColData]
+		latest   *types.SchemaData // The most recent snapshot.
+		// The target column names most recently sent for each table.
+		observed ident.TableMap[*ident.Map[struct{}]]
+	}
+}
+
+func newGate(
+	group *types.TableGroup,
+	configs *applycfg.Configs,
+	product types.Product,
+	initial *types.SchemaData,
+) *gate {
+	labels := metrics.SchemaValues(group.Enclosing)
+	g := &gate{
+		configs: configs,
+		group:   group,
+		product: product,
+		status:  notify.VarOf(&Status{}),
+		paused:  pausedGauge.WithLabelValues(labels...),
+		pauses:  pauseCount.WithLabelValues(labels...),
+	}
+	g.paused.Set(0)
+	g.mu.baseline = &ident.TableMap[[]types.ColData]{}
+	initial.Columns.CopyInto(g.mu.baseline)
+	g.mu.latest = initial
+	return g
+}
+
+// observe records the columns present in the data being sent for a
+// table. Only the first upsert for each table in a batch is examined,
+// to keep the cost of the check low. Property names are mapped to
+// target column names through the table's apply configuration.
+func (g *gate) observe(muts iter.Seq2[ident.Table, types.Mutation]) {
+	var seen ident.TableMap[*ident.Map[struct{}]]
+	for tbl, mut := range muts {
+		if mut.IsDelete() {
+			continue
+		}
+		if _, ok := seen.Get(tbl); ok {
+			continue
+		}
+		props, err := propertyNames(mut.Data)
+		if err != nil {
+			// Malformed payloads will be rejected elsewhere.
+			continue
+		}
+		seen.Put(tbl, g.targetNames(tbl, props))
+	}
+	if seen.Len() == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	changed := false
+	for tbl, props := range seen.All() {
+		if prev, ok := g.mu.observed.Get(tbl); ok && prev.Equal(props, sameKey) {
+			continue
+		}
+		g.mu.observed.Put(tbl, props)
+		changed = true
+	}
+	// A paused group may be able to resume if the source has stopped
+	// sending the affected columns. Conversely, the source may have
+	// started to send a column that was dropped before it was observed.
+	if changed {
+		g.updateLocked()
+	}
+}
+
+// targetNames maps the names of properties sent by the source to the
+// target columns that they will be applied to. Ignored properties are
+// removed.
+func (g *gate) targetNames(tbl ident.Table, props *ident.Map[struct{}]) *ident.Map[struct{}] {
+	if g.configs == nil {
+		return props
+	}
+	cfg, _ := g.configs.Get(tbl).Get()
+	if cfg.Ignore.Len() == 0 && cfg.SourceNames.Len() == 0 {
+		return props
+	}
+	ret := &ident.Map[struct{}]{}
+	for name := range props.Keys() {
+		if ignore, _ := cfg.Ignore.Get(name); ignore {
+			continue
+		}
+		ret.Put(name, struct{}{})
+	}
+	for tgt, src := range cfg.SourceNames.All() {
+		if _, sent := ret.Get(src); sent {
+			ret.Delete(src)
+			ret.Put(tgt, struct{}{})
+		}
+	}
+	return ret
+}
+
+// onSchema is called whenever a new schema snapshot is available.
+func (g *gate) onSchema(next *types.SchemaData) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.mu.latest = next
+	g.updateLocked()
+}
+
+// isPaused returns true if the gate is currently closed.
+func (g *gate) isPaused() bool {
+	st, _ := g.status.Get()
+	return st.Paused
+}
+
+// problemsLocked compares the latest snapshot to the last-known
+// compatible snapshot for the tables and columns that have been
+// observed.
+func (g *gate) problemsLocked() []Problem {
+	var ret []Problem
+	for tbl, props := range g.mu.observed.All() {
+		before, ok := g.mu.baseline.Get(tbl)
+		if !ok {
+			// We've never seen the table, so apply will report on it.
+			continue
+		}
+		after, ok := g.mu.latest.Columns.Get(tbl)
+		if !ok {
+			ret = append(ret, Problem{Table: tbl.Raw(), Reason: "table was dropped"})
+			continue
+		}
+		var afterByName ident.Map[types.ColData]
+		for _, col := range after {
+			afterByName.Put(col.Name, col)
+		}
+		for _, was := range before {
+			if _, sent := props.Get(was.Name); !sent {
+				continue
+			}
+			is, ok := afterByName.Get(was.Name)
+			if !ok {
+				ret = append(ret, Problem{
+					Table:  tbl.Raw(),
+					Column: was.Name.Raw(),
+					Reason: "column was dropped",
+				})
+				continue
+			}
+			if was.Primary != is.Primary {
+				ret = append(ret, Problem{
+					Table:  tbl.Raw(),
+					Column: was.Name.Raw(),
+					Reason: "primary key membership changed",
+				})
+				continue
+			}
+			if strings.EqualFold(was.Type, is.Type) {
+				continue
+			}
+			// Types that we can't reason about are left to apply.
+			wasKind, err := ddl.ParseType(g.product, was.Type)
+			if err != nil {
+				continue
+			}
+			isKind, err := ddl.ParseType(g.product, is.Type)
+			if err != nil {
+				continue
+			}
+			if !isKind.Holds(wasKind) {
+				ret = append(ret, Problem{
+					Table:  tbl.Raw(),
+					Column: was.Name.Raw(),
+					Reason: fmt.Sprintf("type changed from %s to %s", was.Type, is.Type),
+				})
+			}
+		}
+	}
+	slices.SortFunc(ret, func(a, b Problem) int {
+		if c := strings.Compare(a.Table, b.Table); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Column, b.Column)
+	})
+	return ret
+}
+
+// advanceBaselineLocked updates the baseline from the latest snapshot.
+// The definitions of columns that have not been observed are retained,
+// so that dropping a column before the source sends it will still be
+// reported once the source does send it. Tables and columns that are
+// new in the latest snapshot are added.
+func (g *gate) advanceBaselineLocked() {
+	next := &ident.TableMap[[]types.ColData]{}
+	for tbl, after := range g.mu.latest.Columns.All() {
+		before, _ := g.mu.baseline.Get(tbl)
+		props, _ := g.mu.observed.Get(tbl)
+		var afterByName ident.Map[types.ColData]
+		for _, col := range after {
+			afterByName.Put(col.Name, col)
+		}
+		var beforeByName ident.Map[types.ColData]
+		for _, col := range before {
+			beforeByName.Put(col.Name, col)
+		}
+
+		var cols []types.ColData
+		for _, col := range after {
+			if was, ok := beforeByName.Get(col.Name); ok && !observedColumn(props, col.Name) {
+				cols = append(cols, was)
+			} else {
+				cols = append(cols, col)
+			}
+		}
+		// Unobserved columns that were dropped are retained.
+		for _, was := range before {
+			if _, ok := afterByName.Get(was.Name); !ok && !observedColumn(props, was.Name) {
+				cols = append(cols, was)
+			}
+		}
+		next.Put(tbl, cols)
+	}
+	// Unobserved tables that were dropped are retained.
+	for tbl, before := range g.mu.baseline.All() {
+		if _, ok := next.Get(tbl); ok {
+			continue
+		}
+		if _, observed := g.mu.observed.Get(tbl); !observed {
+			next.Put(tbl, before)
+		}
+	}
+	g.mu.baseline = next
+}
+
+// observedColumn returns true if the column name is in the set of
+// observed properties. The set may be nil.
+func observedColumn(props *ident.Map[struct{}], name ident.Ident) bool {
+	if props == nil {
+		return false
+	}
+	_, ok := props.Get(name)
+	return ok
+}
+
+// updateLocked opens or closes the gate.
+func (g *gate) updateLocked() {
+	problems := g.problemsLocked()
+	current, _ := g.status.Get()
+
+	if len(problems) == 0 {
+		g.advanceBaselineLocked()
+		if current.Paused {
+			log.Infof("target schema for %s is compatible again; resuming", g.group)
+			g.paused.Set(0)
+			g.status.Set(&Status{})
+		}
+		return
+	}
+
+	next := &Status{Paused: true, Problems: problems, Since: current.Since}
+	if !current.Paused {
+		next.Since = time.Now()
+		g.paused.Set(1)
+		g.pauses.Inc()
+	} else if slices.Equal(current.Problems, problems) {
+		return
+	}
+	log.Warnf("pausing %s due to incompatible target schema changes: %v", g.group, problems)
+	g.status.Set(next)
+}
+
+// admit is a [sequencer.Admission] which blocks while the gate is
+// closed. It is called before a target transaction is opened.
+func (g *gate) admit(
+	ctx context.Context, _ iter.Seq2[ident.Table, types.Mutation],
+) (func(), error) {
+	if !g.wait(ctx.Done()) {
+		return nil, errors.WithStack(ctx.Err())
+	}
+	return func() {}, nil
+}
+
+// wait blocks until the gate is open, returning false if the channel
+// is closed first.
+func (g *gate) wait(done <-chan struct{}) bool {
+	for {
+		st, changed := g.status.Get()
+		if !st.Paused {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// sameKey is used to compare sets of column names.
+func sameKey(struct{}, struct{}) bool { return true }
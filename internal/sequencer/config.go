@@ -17,16 +17,35 @@
 package sequencer
 
 import (
+	"net/url"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
 // AssumeIdempotent is a flag name.
 const AssumeIdempotent = "assumeIdempotent"
 
+// Values for [Config.FaultIsolation].
+const (
+	// FaultIsolationOff fails the entire target transaction if any
+	// mutation cannot be applied.
+	FaultIsolationOff = "off"
+	// FaultIsolationMutation sends only the mutations that cannot be
+	// applied to the fault DLQ. The remaining mutations from the same
+	// source transaction are committed, so the target will not reflect
+	// the source transaction as a whole.
+	FaultIsolationMutation = "mutation"
+	// FaultIsolationTransaction sends all mutations from a source
+	// transaction to the fault DLQ if any of them cannot be applied.
+	// This preserves the atomicity of source transactions.
+	FaultIsolationTransaction = "transaction"
+)
+
 // Defaults for flag bindings.
 const (
+	DefaultFaultDLQ        = "faults"
 	DefaultFlushPeriod     = 1 * time.Second
 	DefaultFlushSize       = 1_000
 	DefaultTaskGracePeriod = time.Minute
@@ -41,11 +60,14 @@ const (
 // all sequencers necessarily respond to all configuration options.
 type Config struct {
 	Chaos            int           // Set by tests to inject errors this many times per call site.
+	FaultDLQ         string        // The DLQ name used for isolated mutations.
+	FaultIsolation   string        // One of the FaultIsolation constants.
 	FlushPeriod      time.Duration // Don't queue mutations for longer than this.
 	FlushSize        int           // Ideal target database transaction size
 	IdempotentSource bool          // The upstream source is idempotent, disable extra marking.
 	Parallelism      int           // The number of concurrent connections to use.
 	QuiescentPeriod  time.Duration // How often to sweep for queued mutations.
+	RetireArchiveURL string        // If set, archive applied mutations before removal.
 	RetireOffset     time.Duration // Delay removal of applied mutations.
 	ScanSize         int           // Limit on staging-table read queries.
 	TaskGracePeriod  time.Duration // How long to allow previous iteration to clean up.
@@ -54,6 +76,12 @@ type Config struct {
 
 // Bind adds configuration flags to the set.
 func (c *Config) Bind(flags *pflag.FlagSet) {
+	flags.StringVar(&c.FaultDLQ, "faultDLQ", DefaultFaultDLQ,
+		"the name of the DLQ that receives mutations isolated by --faultIsolation")
+	flags.StringVar(&c.FaultIsolation, "faultIsolation", FaultIsolationOff,
+		"isolate mutations that cannot be applied and send them to a DLQ; one of "+
+			"off, transaction (send the entire source transaction), or "+
+			"mutation (send only the failing rows, violating source transaction atomicity)")
 	flags.DurationVar(&c.FlushPeriod, "flushPeriod", DefaultFlushPeriod,
 		"flush queued mutations after this duration")
 	flags.IntVar(&c.FlushSize, "flushSize", DefaultFlushSize,
@@ -64,6 +92,9 @@ func (c *Config) Bind(flags *pflag.FlagSet) {
 		"the number of concurrent database transactions to use")
 	flags.DurationVar(&c.QuiescentPeriod, "quiescentPeriod", DefaultQuiescentPeriod,
 		"how often to retry deferred mutations")
+	flags.StringVar(&c.RetireArchiveURL, "retireArchiveURL", "",
+		"write applied mutations to this file:///path or s3://bucket/path URL before they are "+
+			"removed from staging; the files may be replayed by the objstore source")
 	flags.DurationVar(&c.RetireOffset, "retireOffset", DefaultRetireOffset,
 		"delay removal of applied mutations")
 	flags.IntVar(&c.ScanSize, "scanSize", DefaultScanSize,
@@ -76,6 +107,16 @@ func (c *Config) Bind(flags *pflag.FlagSet) {
 
 // Preflight ensure that the configuration has sane defaults.
 func (c *Config) Preflight() error {
+	if c.FaultDLQ == "" {
+		c.FaultDLQ = DefaultFaultDLQ
+	}
+	switch c.FaultIsolation {
+	case "":
+		c.FaultIsolation = FaultIsolationOff
+	case FaultIsolationOff, FaultIsolationMutation, FaultIsolationTransaction:
+	default:
+		return errors.Errorf("unknown faultIsolation policy %q", c.FaultIsolation)
+	}
 	if c.FlushPeriod == 0 {
 		c.FlushPeriod = DefaultFlushPeriod
 	}
@@ -88,6 +129,15 @@ func (c *Config) Preflight() error {
 	if c.QuiescentPeriod <= 0 {
 		c.QuiescentPeriod = DefaultQuiescentPeriod
 	}
+	if c.RetireArchiveURL != "" {
+		u, err := url.Parse(c.RetireArchiveURL)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse retireArchiveURL %q", c.RetireArchiveURL)
+		}
+		if u.Scheme != "file" && u.Scheme != "s3" {
+			return errors.Errorf("unknown retireArchiveURL scheme %q", u.Scheme)
+		}
+	}
 	// 0 is a valid value for RetireOffset.
 	if c.RetireOffset < 0 {
 		c.RetireOffset = DefaultRetireOffset
@@ -52,13 +52,33 @@ import (
 // [scheduler.Scheduler] and its [lockset.Set] manage the active keys.
 type Core struct {
 	cfg        *sequencer.Config
+	dlqs       types.DLQs
 	leases     types.Leases
 	scheduler  *scheduler.Scheduler
 	targetPool *types.TargetPool
+
+	preserveTxns bool // Set by PreserveTransactions.
 }
 
 var _ sequencer.Sequencer = (*Core)(nil)
 
+// PreserveTransactions returns a copy of the Core that applies each
+// source transaction as exactly one target transaction, rather than
+// coalescing multiple source transactions. Transactions which do not
+// touch the same keys may still be applied concurrently.
+//
+// A source transaction is identified by its timestamp; that is, each
+// [types.TemporalBatch] is assumed to contain exactly one source
+// transaction. This holds for the logical-replication frontends,
+// which assign a distinct timestamp to each transaction that they
+// receive, but not for sources that may commit multiple transactions
+// at the same timestamp.
+func (s *Core) PreserveTransactions() *Core {
+	ret := *s
+	ret.preserveTxns = true
+	return &ret
+}
+
 // Start implements [sequencer.Sequencer].
 func (s *Core) Start(
 	ctx *stopper.Context, opts *sequencer.StartOptions,
@@ -157,6 +177,7 @@ func (s *Core) Start(
 		metricLabels := metrics.SchemaValues(group.Enclosing)
 		template := &round{
 			Core:     s,
+			admit:    opts.Admit,
 			delegate: opts.Delegate,
 			group:    group,
 			poisoned: poisoned,
@@ -164,6 +185,7 @@ func (s *Core) Start(
 			// Metrics.
 			applied:     sweepAppliedCount.WithLabelValues(metricLabels...),
 			duration:    sweepDuration.WithLabelValues(metricLabels...),
+			isolated:    isolatedCount.WithLabelValues(metricLabels...),
 			lastAttempt: sweepLastAttempt.WithLabelValues(metricLabels...),
 			lastSuccess: sweepLastSuccess.WithLabelValues(metricLabels...),
 		}
@@ -205,8 +227,18 @@ func (s *Core) Start(
 		// changefeed resolved timestamp). The Flush callback is where
 		// we launch, possibly concurrent, tasks to apply data to the
 		// target.
+		copierCfg := s.cfg
+		if s.preserveTxns {
+			// A FlushSize of one causes the Copier to flush each
+			// temporal batch, and so each source transaction, on its
+			// own. Segmented transactions will still be accumulated
+			// into a single round.
+			cpy := *s.cfg
+			cpy.FlushSize = 1
+			copierCfg = &cpy
+		}
 		copier := &sequtil.Copier{
-			Config: s.cfg,
+			Config: copierCfg,
 			Source: batchCursors,
 			// We'll get this callback when the copier does not expect
 			// any more data to arrive until the bounds are updated.
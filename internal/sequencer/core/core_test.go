@@ -17,11 +17,18 @@
 package core_test
 
 import (
+	"context"
+	"sync"
 	"testing"
 
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sequencer/seqtest"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCore(t *testing.T) {
@@ -35,3 +42,84 @@ func TestCore(t *testing.T) {
 		},
 		func(t *testing.T, check *seqtest.Check) {})
 }
+
+func TestCorePreserveTransactions(t *testing.T) {
+	var rec *txnRecorder
+	seqtest.CheckSequencer(t,
+		&all.WorkloadConfig{
+			DisableAcceptor: true,
+			DisableStaging:  true,
+		},
+		func(t *testing.T, fixture *all.Fixture, seqFixture *seqtest.Fixture) sequencer.Sequencer {
+			rec = &txnRecorder{delegate: seqFixture.Core.PreserveTransactions()}
+			return rec
+		},
+		func(t *testing.T, check *seqtest.Check) {
+			r := require.New(t)
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			// Each source transaction must have been applied in
+			// exactly one target transaction, and no target
+			// transaction may contain more than one source
+			// transaction. Retried transactions may be observed more
+			// than once.
+			r.Empty(rec.mu.coalesced)
+			r.Len(rec.mu.seen, check.Transactions)
+		})
+}
+
+// txnRecorder is a Sequencer shim that records the source
+// transactions which are passed to the target in each call to the
+// delegate acceptor. The core sequencer uses one target transaction
+// per call.
+type txnRecorder struct {
+	delegate sequencer.Sequencer
+	mu       struct {
+		sync.Mutex
+		coalesced []*types.MultiBatch // Batches with multiple source transactions.
+		seen      map[hlc.Time]struct{}
+	}
+}
+
+var _ sequencer.Sequencer = (*txnRecorder)(nil)
+
+// Start implements [sequencer.Sequencer].
+func (r *txnRecorder) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	opts = opts.Copy()
+	opts.Delegate = &txnRecordingAcceptor{opts.Delegate, r}
+	return r.delegate.Start(ctx, opts)
+}
+
+// txnRecordingAcceptor records the batches that it successfully
+// delivers.
+type txnRecordingAcceptor struct {
+	types.MultiAcceptor
+	rec *txnRecorder
+}
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *txnRecordingAcceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	if err := a.MultiAcceptor.AcceptMultiBatch(ctx, batch, opts); err != nil {
+		return err
+	}
+	if batch.Count() == 0 {
+		return nil
+	}
+	a.rec.mu.Lock()
+	defer a.rec.mu.Unlock()
+	if len(batch.Data) > 1 {
+		a.rec.mu.coalesced = append(a.rec.mu.coalesced, batch)
+	}
+	if a.rec.mu.seen == nil {
+		a.rec.mu.seen = make(map[hlc.Time]struct{})
+	}
+	for _, temp := range batch.Data {
+		// Discard any external data associated with the time.
+		a.rec.mu.seen[hlc.New(temp.Time.Nanos(), temp.Time.Logical())] = struct{}{}
+	}
+	return nil
+}
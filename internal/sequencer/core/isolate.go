@@ -0,0 +1,183 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"context"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// A faultEntry is a single mutation that may be sent to the fault DLQ.
+type faultEntry struct {
+	table ident.Table
+	mut   types.Mutation
+}
+
+// A faultUnit is the smallest group of mutations that will be applied
+// or sent to the fault DLQ together. Its size depends upon the
+// isolation policy.
+type faultUnit []faultEntry
+
+// canIsolate returns true if fault isolation is enabled and the error
+// was reported by the target database. Deferrable errors, such as FK
+// violations, are left to the usual retry behavior, since they may
+// resolve themselves once other data has been applied.
+func (r *round) canIsolate(err error) bool {
+	switch r.cfg.FaultIsolation {
+	case sequencer.FaultIsolationMutation, sequencer.FaultIsolationTransaction:
+	default:
+		return false
+	}
+	if r.targetPool.IsDeferrable(err) {
+		return false
+	}
+	_, ok := r.targetPool.ErrCode(err)
+	return ok
+}
+
+// isolate is called when the batch could not be applied to the target
+// because of the given error. It recursively splits the batch to find
+// the mutations which cannot be applied and sends them to the fault
+// DLQ. The remaining mutations are committed to the target in their
+// original time order.
+//
+// If isolation is interrupted by an error, some portion of the batch
+// may have already been committed or sent to the DLQ. The batch will
+// be poisoned and retried by the caller, which may result in duplicate
+// DLQ entries.
+func (r *round) isolate(ctx *stopper.Context, cause error) error {
+	q, err := r.dlqs.Get(ctx, r.group.Enclosing, r.cfg.FaultDLQ)
+	if err != nil {
+		return errors.Wrapf(cause, "fault isolation unavailable: %v", err)
+	}
+
+	// Depending on the policy, each unit is a source transaction or
+	// an individual mutation. Source transactions are never split if
+	// the Core has been asked to preserve them.
+	perMutation := r.cfg.FaultIsolation == sequencer.FaultIsolationMutation && !r.preserveTxns
+	var units []faultUnit
+	for _, temp := range r.batch.Data {
+		var unit faultUnit
+		for table, mut := range temp.Mutations() {
+			entry := faultEntry{table, mut}
+			if perMutation {
+				units = append(units, faultUnit{entry})
+			} else {
+				unit = append(unit, entry)
+			}
+		}
+		if len(unit) > 0 {
+			units = append(units, unit)
+		}
+	}
+
+	count, err := r.bisect(ctx, q, units, cause)
+	if count > 0 {
+		r.isolated.Add(float64(count))
+		log.Warnf("sent %d mutations from %s to DLQ %q: %v",
+			count, r.group, r.cfg.FaultDLQ, cause)
+	}
+	return err
+}
+
+// bisect is called with units of work that are known to fail with the
+// given cause. It returns the number of mutations that were sent to
+// the DLQ.
+func (r *round) bisect(
+	ctx *stopper.Context, q types.DLQ, units []faultUnit, cause error,
+) (int, error) {
+	if len(units) == 1 {
+		return len(units[0]), r.enqueue(ctx, q, units[0], cause)
+	}
+
+	// Process the halves in order to preserve time-ordering.
+	mid := len(units) / 2
+	count := 0
+	for _, half := range [][]faultUnit{units[:mid], units[mid:]} {
+		err := r.commitUnits(ctx, half)
+		if err == nil {
+			continue
+		}
+		if !r.canIsolate(err) {
+			return count, err
+		}
+		n, err := r.bisect(ctx, q, half, err)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// commitUnits applies the mutations to the target in a dedicated
+// transaction.
+func (r *round) commitUnits(ctx *stopper.Context, units []faultUnit) error {
+	batch := &types.MultiBatch{}
+	for _, unit := range units {
+		for _, entry := range unit {
+			if err := batch.Accumulate(entry.table, entry.mut); err != nil {
+				return err
+			}
+		}
+	}
+	return retry.Retry(ctx, r.targetPool, func(ctx context.Context) error {
+		release, err := r.admit.Admit(ctx, batch.Mutations())
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		targetTx, err := r.targetPool.BeginConnTx(ctx, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer func() { _ = targetTx.Rollback() }()
+		if err := r.delegate.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{
+			TargetQuerier: targetTx,
+		}); err != nil {
+			return err
+		}
+		return errors.WithStack(targetTx.Commit())
+	})
+}
+
+// enqueue writes the mutations to the DLQ in a single transaction.
+func (r *round) enqueue(
+	ctx *stopper.Context, q types.DLQ, unit faultUnit, cause error,
+) error {
+	return retry.Retry(ctx, r.targetPool, func(ctx context.Context) error {
+		tx, err := r.targetPool.BeginTx(ctx, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		for _, entry := range unit {
+			if err := q.Enqueue(ctx, tx, entry.table, entry.mut, cause); err != nil {
+				return err
+			}
+		}
+		return errors.WithStack(tx.Commit())
+	})
+}
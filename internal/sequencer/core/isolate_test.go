@@ -0,0 +1,131 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sinktest/sqlitetest"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsolate applies a batch containing a row that violates a CHECK
+// constraint and verifies which mutations are committed and which are
+// sent to the fault DLQ under each policy.
+func TestIsolate(t *testing.T) {
+	tcs := []struct {
+		policy  string
+		applied []int
+		queued  []int
+	}{
+		{
+			policy:  sequencer.FaultIsolationMutation,
+			applied: []int{1, 2, 4, 5},
+			queued:  []int{3},
+		},
+		{
+			policy:  sequencer.FaultIsolationTransaction,
+			applied: []int{1, 2, 5},
+			queued:  []int{3, 4},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.policy, func(t *testing.T) {
+			r := require.New(t)
+			fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DLQ: ident.New("replicator_dlq")},
+				`CREATE TABLE kv (pk INTEGER PRIMARY KEY, val INTEGER CHECK (val >= 0))`,
+			)
+			r.NoError(err)
+			ctx := fixture.Context
+			pool := fixture.TargetPool
+			dlTable := fixture.DLQTable
+			tbl := fixture.Table("kv")
+
+			// The third row will fail the CHECK constraint. It shares
+			// a source transaction with the fourth row.
+			batch := &types.MultiBatch{}
+			for idx, val := range []int{1, 2, -1, 4, 5} {
+				pk := idx + 1
+				r.NoError(batch.Accumulate(tbl, types.Mutation{
+					Data: json.RawMessage(fmt.Sprintf(`{"pk":%d,"val":%d}`, pk, val)),
+					Key:  json.RawMessage(fmt.Sprintf(`[%d]`, pk)),
+					Time: hlc.New(int64(pk+1)/2, 0),
+				}))
+			}
+
+			rnd := &round{
+				Core: &Core{
+					cfg: &sequencer.Config{
+						FaultDLQ:       sequencer.DefaultFaultDLQ,
+						FaultIsolation: tc.policy,
+					},
+					dlqs:       fixture.DLQs,
+					targetPool: pool,
+				},
+				batch:       batch,
+				delegate:    types.OrderedAcceptorFrom(fixture.Acceptor, fixture.Watchers),
+				group:       &types.TableGroup{Enclosing: fixture.Schema, Name: ident.New("kv")},
+				isolated:    prometheus.NewCounter(prometheus.CounterOpts{}),
+				lastAttempt: prometheus.NewGauge(prometheus.GaugeOpts{}),
+			}
+
+			err = rnd.tryCommit(ctx)
+			r.Error(err)
+			r.True(rnd.canIsolate(err))
+			r.NoError(rnd.isolate(ctx, err))
+
+			rows, err := pool.QueryContext(ctx, "SELECT pk FROM kv ORDER BY pk")
+			r.NoError(err)
+			var applied []int
+			for rows.Next() {
+				var pk int
+				r.NoError(rows.Scan(&pk))
+				applied = append(applied, pk)
+			}
+			r.NoError(rows.Err())
+			r.Equal(tc.applied, applied)
+
+			q, err := dlq.OpenQueue(ctx, pool, dlTable)
+			r.NoError(err)
+			entries, err := q.List(ctx, &dlq.Filter{})
+			r.NoError(err)
+			var queued []int
+			for _, e := range entries {
+				var data struct{ PK int }
+				r.NoError(json.Unmarshal(e.Data, &data))
+				queued = append(queued, data.PK)
+				r.Equal(sequencer.DefaultFaultDLQ, e.Name)
+				r.Equal(tbl.Raw(), e.Table)
+				r.Contains(e.Cause, "CHECK constraint failed")
+			}
+			r.Equal(tc.queued, queued)
+
+			// Disabling isolation should leave the error alone.
+			rnd.cfg.FaultIsolation = sequencer.FaultIsolationOff
+			r.False(rnd.canIsolate(err))
+		})
+	}
+}
@@ -23,6 +23,10 @@ import (
 )
 
 var (
+	isolatedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "core_isolated_mutations_total",
+		Help: "the number of mutations sent to the fault DLQ",
+	}, metrics.SchemaLabels)
 	sweepActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "core_sweep_active_bool",
 		Help: "non-zero if this instance of Replicator is processing the schema",
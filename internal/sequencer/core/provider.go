@@ -29,12 +29,14 @@ var Set = wire.NewSet(ProvideCore)
 // ProvideCore is called by Wire.
 func ProvideCore(
 	cfg *sequencer.Config,
+	dlqs types.DLQs,
 	leases types.Leases,
 	scheduler *scheduler.Scheduler,
 	targetPool *types.TargetPool,
 ) *Core {
 	return &Core{
 		cfg:        cfg,
+		dlqs:       dlqs,
 		leases:     leases,
 		scheduler:  scheduler,
 		targetPool: targetPool,
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/lockset"
@@ -36,6 +37,7 @@ type round struct {
 	*Core
 
 	// Initialized by caller.
+	admit    sequencer.Admission
 	delegate types.MultiAcceptor
 	group    *types.TableGroup
 	poisoned *poisonSet
@@ -54,6 +56,7 @@ type round struct {
 	// Metrics
 	applied     prometheus.Counter
 	duration    prometheus.Observer
+	isolated    prometheus.Counter
 	lastAttempt prometheus.Gauge
 	lastSuccess prometheus.Gauge
 }
@@ -100,6 +103,12 @@ func (r *round) scheduleCommit(
 			return r.tryCommit(stopper.From(ctx))
 		})
 
+		// Find and set aside the mutations which cannot be applied,
+		// if so configured.
+		if err != nil && r.canIsolate(err) {
+			err = r.isolate(ctx, err)
+		}
+
 		// Report successful progress.
 		if err == nil {
 			progressReport <- r.advanceTo
@@ -149,7 +158,17 @@ func (r *round) tryCommit(ctx *stopper.Context) error {
 	log.Tracef("round.tryCommit: beginning for %s to %s", r.group, r.advanceTo)
 	r.lastAttempt.SetToCurrentTime()
 
-	targetTx, err := r.targetPool.BeginTx(ctx, nil)
+	// Wait for the batch to be admitted before opening a transaction,
+	// so that we don't hold target resources while waiting.
+	release, err := r.admit.Admit(ctx, r.batch.Mutations())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Using a dedicated connection allows the acceptor to make use of
+	// driver-specific bulk-transfer mechanisms.
+	targetTx, err := r.targetPool.BeginConnTx(ctx, nil)
 	if err != nil {
 		return errors.WithStack(err)
 	}
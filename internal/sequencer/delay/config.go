@@ -0,0 +1,57 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package delay
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// DefaultRefreshPeriod is the default value for Config.RefreshPeriod.
+const DefaultRefreshPeriod = 5 * time.Second
+
+// Config controls the delayed-replica behavior.
+type Config struct {
+	// The target will trail the source by at least this amount. A zero
+	// value disables the delay.
+	Delay time.Duration
+	// How often to advance the delayed bounds and to check for
+	// operator overrides.
+	RefreshPeriod time.Duration
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.DurationVar(&c.Delay, "replicaDelay", 0,
+		"intentionally trail the source by this amount, retaining newer data in staging; "+
+			"use the delay command to fast-forward or stop short of a point in time")
+	f.DurationVar(&c.RefreshPeriod, "replicaDelayRefresh", DefaultRefreshPeriod,
+		"how often to advance the delayed replica and check for overrides")
+}
+
+// Preflight validates the configuration.
+func (c *Config) Preflight() error {
+	if c.Delay < 0 {
+		return errors.New("replicaDelay must not be negative")
+	}
+	if c.RefreshPeriod <= 0 {
+		c.RefreshPeriod = DefaultRefreshPeriod
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package delay contains a [sequencer.Shim] that causes the target to
+// intentionally trail the source by a configurable interval.
+package delay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delay is a [sequencer.Shim] that caps the maximum of the resolving
+// bounds at the current time minus [Config.Delay]. Data newer than the
+// cap is retained in staging until the cap advances past it. An
+// operator may set an [Override] to fast-forward the replica or to
+// stop short of a point in time (e.g. an incident).
+type Delay struct {
+	cfg         *Config
+	memo        types.Memo
+	stagingPool *types.StagingPool
+
+	mu struct {
+		sync.Mutex
+		status ident.Map[*Status]
+	}
+}
+
+var (
+	_ diag.Diagnostic = (*Delay)(nil)
+	_ sequencer.Shim  = (*Delay)(nil)
+)
+
+// Status reports the state of a delayed group.
+type Status struct {
+	Bounds   hlc.Range `json:"bounds"`
+	Limit    hlc.Time  `json:"limit"`
+	Override *Override `json:"override,omitempty"`
+}
+
+// Diagnostic implements [diag.Diagnostic].
+func (d *Delay) Diagnostic(_ context.Context) any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ret := make(map[string]*Status, d.mu.status.Len())
+	for group, status := range d.mu.status.All() {
+		ret[group.Raw()] = status
+	}
+	return ret
+}
+
+// Overridden returns true if an operator override is in effect for
+// the group.
+func (d *Delay) Overridden(group ident.Ident) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status, ok := d.mu.status.Get(group)
+	return ok && !status.Override.IsZero()
+}
+
+// Wrap implements [sequencer.Shim]. It is a no-op if no delay has
+// been configured.
+func (d *Delay) Wrap(
+	_ *stopper.Context, delegate sequencer.Sequencer,
+) (sequencer.Sequencer, error) {
+	if d.cfg.Delay <= 0 {
+		return delegate, nil
+	}
+	return &delayed{d, delegate}, nil
+}
+
+type delayed struct {
+	*Delay
+	delegate sequencer.Sequencer
+}
+
+var _ sequencer.Sequencer = (*delayed)(nil)
+
+// Start implements [sequencer.Sequencer].
+func (d *delayed) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	group := opts.Group.Name
+	labels := metrics.SchemaValues(opts.Group.Enclosing)
+	limitGauge := delayLimit.WithLabelValues(labels...)
+	overriddenGauge := delayOverridden.WithLabelValues(labels...)
+
+	// Replace the bounds with a capped copy.
+	upstream := opts.Bounds
+	capped := &notify.Var[hlc.Range]{}
+	opts = opts.Copy()
+	opts.Bounds = capped
+
+	refresh := func(ctx *stopper.Context, bounds hlc.Range) {
+		override, err := LoadOverride(ctx, d.memo, d.stagingPool, group)
+		if err != nil {
+			// Leave the current cap in place.
+			log.WithError(err).Warnf("could not load delay override for %s; will retry", group)
+			return
+		}
+		limit := override.Limit(time.Now(), d.cfg.Delay)
+		next := capRange(bounds, limit)
+		_, _, _ = capped.Update(func(old hlc.Range) (hlc.Range, error) {
+			if old == next {
+				return old, notify.ErrNoUpdate
+			}
+			return next, nil
+		})
+
+		limitGauge.Set(float64(limit.Nanos()) / 1e9)
+		if override.IsZero() {
+			overriddenGauge.Set(0)
+		} else {
+			overriddenGauge.Set(1)
+		}
+		d.mu.Lock()
+		d.mu.status.Put(group, &Status{Bounds: next, Limit: limit, Override: override})
+		d.mu.Unlock()
+	}
+
+	// Set the initial value before starting the delegate.
+	initial, _ := upstream.Get()
+	refresh(ctx, initial)
+	ctx.Go(func(ctx *stopper.Context) error {
+		defer func() {
+			d.mu.Lock()
+			d.mu.status.Delete(group)
+			d.mu.Unlock()
+		}()
+		_, err := stopvar.DoWhenChangedOrInterval(ctx, initial, upstream, d.cfg.RefreshPeriod,
+			func(ctx *stopper.Context, _, bounds hlc.Range) error {
+				refresh(ctx, bounds)
+				return nil
+			})
+		return err
+	})
+
+	return d.delegate.Start(ctx, opts)
+}
+
+// Unwrap is an informal protocol to access the delegate.
+func (d *delayed) Unwrap() sequencer.Sequencer {
+	return d.delegate
+}
+
+// capRange limits the maximum of the bounds to the given, exclusive
+// limit. The minimum of the bounds is never changed, since it reflects
+// data which has already been applied.
+func capRange(bounds hlc.Range, limit hlc.Time) hlc.Range {
+	if hlc.Compare(bounds.Max(), limit) <= 0 {
+		return bounds
+	}
+	if hlc.Compare(limit, bounds.Min()) < 0 {
+		limit = bounds.Min()
+	}
+	return hlc.RangeExcluding(bounds.Min(), limit)
+}
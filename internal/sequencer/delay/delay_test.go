@@ -0,0 +1,155 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package delay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder captures the options passed to Start.
+type recorder struct {
+	opts *sequencer.StartOptions
+}
+
+func (r *recorder) Start(
+	_ *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	r.opts = opts
+	return nil, &notify.Var[sequencer.Stat]{}, nil
+}
+
+func TestLimit(t *testing.T) {
+	now := time.Unix(10_000, 0)
+	delay := time.Hour
+	at := func(t time.Time) hlc.Time { return hlc.New(t.UnixNano(), 0) }
+
+	tcs := []struct {
+		name     string
+		override *Override
+		expect   time.Time
+	}{
+		{"none", nil, now.Add(-delay)},
+		{"fast forward", &Override{FastForward: now.Add(-time.Minute)}, now.Add(-time.Minute)},
+		{"fast forward behind", &Override{FastForward: now.Add(-2 * delay)}, now.Add(-delay)},
+		{"stop at", &Override{StopAt: now.Add(-2 * delay)}, now.Add(-2 * delay)},
+		{"stop at ahead", &Override{StopAt: now}, now.Add(-delay)},
+		{
+			"stop at wins",
+			&Override{FastForward: now, StopAt: now.Add(-time.Minute)},
+			now.Add(-time.Minute),
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, at(tc.expect), tc.override.Limit(now, delay))
+		})
+	}
+}
+
+func TestCapRange(t *testing.T) {
+	r := require.New(t)
+	bounds := hlc.RangeExcluding(hlc.New(10, 0), hlc.New(20, 0))
+
+	r.Equal(bounds, capRange(bounds, hlc.New(30, 0)))
+	r.Equal(hlc.RangeExcluding(hlc.New(10, 0), hlc.New(15, 0)), capRange(bounds, hlc.New(15, 0)))
+	// Never move the minimum backwards.
+	r.Equal(hlc.RangeEmptyAt(hlc.New(10, 0)), capRange(bounds, hlc.New(5, 0)))
+}
+
+func TestDelay(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	cfg := &Config{Delay: time.Hour, RefreshPeriod: time.Millisecond}
+	mem := &memo.Memory{}
+	d, err := ProvideDelay(cfg, diag.New(ctx), mem, nil)
+	r.NoError(err)
+
+	// No-op if no delay is configured.
+	rec := &recorder{}
+	seq, err := (&Delay{cfg: &Config{}}).Wrap(ctx, rec)
+	r.NoError(err)
+	r.Same(rec, seq)
+
+	seq, err = d.Wrap(ctx, rec)
+	r.NoError(err)
+
+	now := time.Now()
+	stamp := func(t time.Time) hlc.Time { return hlc.New(t.UnixNano(), 0) }
+	bounds := notify.VarOf(hlc.RangeExcluding(hlc.Zero(), stamp(now)))
+	group := &types.TableGroup{
+		Enclosing: ident.MustSchema(ident.New("db")),
+		Name:      ident.New("db"),
+	}
+	_, _, err = seq.Start(ctx, &sequencer.StartOptions{
+		Bounds: bounds,
+		Group:  group,
+	})
+	r.NoError(err)
+	r.NotSame(bounds, rec.opts.Bounds)
+
+	// The delegate should not see anything newer than the delay.
+	capped, _ := rec.opts.Bounds.Get()
+	r.Equal(hlc.Zero(), capped.Min())
+	r.Less(capped.Max().Nanos(), stamp(now.Add(-time.Hour+time.Minute)).Nanos())
+
+	// Fast-forward to a time within the delay window.
+	ff := now.Add(-time.Minute)
+	r.NoError(StoreOverride(ctx, mem, nil, group.Name, &Override{FastForward: ff}))
+	r.Eventually(func() bool {
+		capped, _ := rec.opts.Bounds.Get()
+		return capped.Max() == stamp(ff)
+	}, time.Second, time.Millisecond)
+
+	// Stop short of an incident.
+	incident := now.Add(-2 * time.Hour)
+	r.NoError(StoreOverride(ctx, mem, nil, group.Name, &Override{StopAt: incident}))
+	r.Eventually(func() bool {
+		capped, _ := rec.opts.Bounds.Get()
+		return capped.Max() == stamp(incident)
+	}, time.Second, time.Millisecond)
+	r.Eventually(func() bool { return d.Overridden(group.Name) }, time.Second, time.Millisecond)
+
+	found, err := LoadOverride(ctx, mem, nil, group.Name)
+	r.NoError(err)
+	r.Equal(incident.UTC(), found.StopAt.UTC())
+
+	// Clearing the override returns to the configured delay.
+	r.NoError(StoreOverride(ctx, mem, nil, group.Name, nil))
+	found, err = LoadOverride(ctx, mem, nil, group.Name)
+	r.NoError(err)
+	r.Nil(found)
+	r.Eventually(func() bool {
+		capped, _ := rec.opts.Bounds.Get()
+		return hlc.Compare(capped.Max(), stamp(incident)) > 0
+	}, time.Second, time.Millisecond)
+	r.Eventually(func() bool { return !d.Overridden(group.Name) }, time.Second, time.Millisecond)
+	r.Contains(d.Diagnostic(ctx), "db")
+}
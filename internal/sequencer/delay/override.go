@@ -0,0 +1,102 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package delay
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// An Override is set by an operator to adjust the point to which a
+// delayed replica will be advanced. Overrides are stored in the memo
+// table, so that they will be observed by all running instances of
+// Replicator.
+type Override struct {
+	// If set, data up to, but not including, this time will be applied
+	// even if it is newer than the configured delay would allow.
+	FastForward time.Time `json:"fastForward,omitempty"`
+	// If set, data at or after this time will not be applied, even if
+	// the configured delay would allow it.
+	StopAt time.Time `json:"stopAt,omitempty"`
+}
+
+// IsZero returns true if the Override has no effect.
+func (o *Override) IsZero() bool {
+	return o == nil || (o.FastForward.IsZero() && o.StopAt.IsZero())
+}
+
+// Limit returns the exclusive upper bound for data to be applied.
+func (o *Override) Limit(now time.Time, delay time.Duration) hlc.Time {
+	limit := now.Add(-delay)
+	if o != nil {
+		if o.FastForward.After(limit) {
+			limit = o.FastForward
+		}
+		if !o.StopAt.IsZero() && o.StopAt.Before(limit) {
+			limit = o.StopAt
+		}
+	}
+	return hlc.New(limit.UnixNano(), 0)
+}
+
+// memoKey returns the key for a group's override in the memo table.
+func memoKey(group ident.Ident) string {
+	return "delay-override-" + group.Raw()
+}
+
+// LoadOverride returns the override for the group. A nil value will be
+// returned if no override has been set.
+func LoadOverride(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, group ident.Ident,
+) (*Override, error) {
+	data, err := memo.Get(ctx, tx, memoKey(group))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	ret := &Override{}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, errors.Wrapf(err, "could not decode delay override for %s", group)
+	}
+	if ret.IsZero() {
+		return nil, nil
+	}
+	return ret, nil
+}
+
+// StoreOverride records the override for the group. A nil or empty
+// Override clears any existing override.
+func StoreOverride(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, group ident.Ident, o *Override,
+) error {
+	if o == nil {
+		o = &Override{}
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return memo.Put(ctx, tx, memoKey(group), data)
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package delay
+
+import (
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(ProvideDelay)
+
+// ProvideDelay is called by Wire.
+func ProvideDelay(
+	cfg *Config, diags *diag.Diagnostics, memo types.Memo, stagingPool *types.StagingPool,
+) (*Delay, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	ret := &Delay{
+		cfg:         cfg,
+		memo:        memo,
+		stagingPool: stagingPool,
+	}
+	return ret, diags.Register("delay", ret)
+}
@@ -72,7 +72,9 @@ func (i *Immediate) Start(
 		return err
 	})
 
-	acc := opts.Delegate
+	// We don't open a target transaction, so the admission can be
+	// checked immediately before calling the delegate.
+	acc := sequencer.AdmittingAcceptor(opts.Admit, opts.Delegate)
 	acc = i.retryTarget.MultiAcceptor(acc)
 	if !i.cfg.IdempotentSource {
 		acc = i.marker.MultiAcceptor(acc)
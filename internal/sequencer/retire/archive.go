@@ -0,0 +1,360 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package retire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// archiveChunkSize is the uncompressed size at which an archive
+	// file will be rolled over.
+	archiveChunkSize = 16 << 20 // 16 MiB
+	manifestDir      = "manifests"
+)
+
+// archiveLine is the encoding of a single mutation within an archive
+// file. It is the same as a CockroachDB changefeed ndjson line.
+type archiveLine struct {
+	After   json.RawMessage `json:"after"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	Key     json.RawMessage `json:"key"`
+	Updated string          `json:"updated"`
+}
+
+// manifest describes the files written when archiving a range of time
+// for a table group.
+type manifest struct {
+	Group    string         `json:"group"`
+	From     string         `json:"from"`     // Exclusive.
+	To       string         `json:"to"`       // Inclusive.
+	Resolved string         `json:"resolved"` // Path of the resolved-timestamp file.
+	Files    []manifestFile `json:"files"`
+}
+
+// manifestFile describes a single archive file.
+type manifestFile struct {
+	Count int    `json:"count"`
+	Max   string `json:"max"`
+	Min   string `json:"min"`
+	Path  string `json:"path"`
+	Table string `json:"table"`
+}
+
+// archive writes applied mutations to an object store before they
+// are retired.
+//
+// The files use the daily layout of a CockroachDB cloud-storage
+// changefeed, with gzip compression, so that the objstore source can
+// replay them. The files for each table group are written within a
+// directory named for the group:
+//
+//	[prefix]/[group]/[date]/[timestamp]-[uniquer]-[topic]-[schema-id].ndjson.gz
+//	[prefix]/[group]/[date]/[timestamp].RESOLVED
+//
+// Once all files for a range of time have been written, a manifest is
+// written to [prefix]/manifests/[group]/[timestamp].json. Mutations are
+// archived at least once; a failure before the manifest has been
+// written may result in duplicate files. After a restart, archiving
+// resumes from the end of the latest manifest.
+type archive struct {
+	bucket  bucket.Bucket
+	pool    *types.StagingPool
+	prefix  string // Path within an S3 bucket.
+	session string // Distinguishes files written by this process.
+	stagers types.Stagers
+
+	mu struct {
+		sync.Mutex
+		fileID int
+	}
+}
+
+// newArchive opens a file:// or s3:// URL.
+func newArchive(
+	storageURL string, pool *types.StagingPool, stagers types.Stagers,
+) (*archive, error) {
+	u, err := url.Parse(storageURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse retireArchiveURL %q", storageURL)
+	}
+	ret := &archive{
+		pool:    pool,
+		stagers: stagers,
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, errors.New("missing path in URL. Must be file:///path")
+		}
+		if err := os.MkdirAll(u.Path, 0755); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ret.bucket, err = local.NewDir(u.Path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case "s3":
+		var cfg *s3.Config
+		cfg, ret.prefix, err = s3.FromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		ret.bucket, err = s3.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unknown scheme %q", u.Scheme)
+	}
+
+	var session [8]byte
+	if _, err := rand.Read(session[:]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret.session = hex.EncodeToString(session[:])
+	return ret, nil
+}
+
+// write archives the applied mutations in the group whose timestamps
+// fall within the range (from, to]. The lower bound is exclusive, since
+// it is the upper bound of the previous archive. It returns only once
+// all files and the manifest have been written to the object store.
+func (a *archive) write(ctx *stopper.Context, group *types.TableGroup, from, to hlc.Time) error {
+	start := time.Now()
+	dir := path.Join(a.prefix, group.Name.Raw())
+	m := &manifest{
+		Group: group.Name.Raw(),
+		From:  from.String(),
+		To:    to.String(),
+	}
+
+	for _, table := range group.Tables {
+		stager, err := a.stagers.Get(ctx, table)
+		if err != nil {
+			return err
+		}
+		chunk := &archiveChunk{topic: table.Table().Raw()}
+		if err := stager.ReadApplied(ctx, a.pool, from.Next(), to,
+			func(muts []types.Mutation) error {
+				for _, mut := range muts {
+					if err := chunk.add(mut); err != nil {
+						return err
+					}
+					if chunk.raw >= archiveChunkSize {
+						if err := a.flush(ctx, dir, table, chunk, m); err != nil {
+							return err
+						}
+						chunk = &archiveChunk{topic: chunk.topic}
+					}
+				}
+				return nil
+			}); err != nil {
+			return err
+		}
+		if err := a.flush(ctx, dir, table, chunk, m); err != nil {
+			return err
+		}
+	}
+
+	if len(m.Files) == 0 {
+		return nil
+	}
+
+	// Write a resolved timestamp, so that the objstore source will
+	// process the files that we have written.
+	resolved, err := json.Marshal(struct {
+		Resolved string `json:"resolved"`
+	}{to.String()})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	m.Resolved = path.Join(datePath(dir, to), formatTime(to)+".RESOLVED")
+	if err := a.put(ctx, m.Resolved, resolved); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	manifestPath := path.Join(a.prefix, manifestDir, group.Name.Raw(), formatTime(to)+".json")
+	if err := a.put(ctx, manifestPath, data); err != nil {
+		return err
+	}
+	archiveDurations.Observe(time.Since(start).Seconds())
+	log.WithFields(log.Fields{
+		"duration": time.Since(start),
+		"files":    len(m.Files),
+		"group":    group,
+	}).Debugf("archived mutations to %s", manifestPath)
+	return nil
+}
+
+// resumeFrom returns the end of the most recent range of time that
+// was archived for the group, as recorded by its latest manifest. A
+// zero time is returned if no manifest has been written.
+func (a *archive) resumeFrom(ctx *stopper.Context, group *types.TableGroup) (hlc.Time, error) {
+	dir := path.Join(a.prefix, manifestDir, group.Name.Raw())
+	// Manifests are named for the end of their range, so the last one
+	// that is listed is the most recent.
+	var last string
+	if err := a.bucket.Walk(ctx, dir, &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, file string) error {
+			if strings.HasSuffix(file, ".json") {
+				last = file
+			}
+			return nil
+		}); err != nil {
+		return hlc.Zero(), errors.Wrapf(err, "could not list %s", dir)
+	}
+	if last == "" {
+		return hlc.Zero(), nil
+	}
+	rd, err := a.bucket.Open(ctx, last)
+	if err != nil {
+		return hlc.Zero(), errors.Wrapf(err, "could not open %s", last)
+	}
+	defer rd.Close()
+	var m manifest
+	if err := json.NewDecoder(rd).Decode(&m); err != nil {
+		return hlc.Zero(), errors.Wrapf(err, "could not decode %s", last)
+	}
+	to, err := hlc.Parse(m.To)
+	if err != nil {
+		return hlc.Zero(), errors.Wrapf(err, "could not parse manifest %s", last)
+	}
+	return to, nil
+}
+
+// flush writes the chunk to the object store and records it in the
+// manifest. This is a no-op if the chunk is empty.
+func (a *archive) flush(
+	ctx *stopper.Context, dir string, table ident.Table, chunk *archiveChunk, m *manifest,
+) error {
+	if chunk.count == 0 {
+		return nil
+	}
+	if err := chunk.gz.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.mu.Lock()
+	fileID := a.mu.fileID
+	a.mu.fileID++
+	a.mu.Unlock()
+
+	name := path.Join(datePath(dir, chunk.min), fmt.Sprintf("%s-%s-1-1-%08x-%s-1.ndjson.gz",
+		formatTime(chunk.min), a.session, fileID, chunk.topic))
+	if err := a.put(ctx, name, chunk.buf.Bytes()); err != nil {
+		return err
+	}
+	archiveBytes.Add(float64(chunk.buf.Len()))
+	archiveMutations.Add(float64(chunk.count))
+	m.Files = append(m.Files, manifestFile{
+		Count: chunk.count,
+		Max:   chunk.max.String(),
+		Min:   chunk.min.String(),
+		Path:  name,
+		Table: table.Raw(),
+	})
+	return nil
+}
+
+// put writes the data to the named object.
+func (a *archive) put(ctx *stopper.Context, name string, data []byte) error {
+	if err := a.bucket.Put(ctx, name, bytes.NewReader(data)); err != nil {
+		archiveErrors.Inc()
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+	return nil
+}
+
+// archiveChunk accumulates compressed lines for a single file.
+type archiveChunk struct {
+	buf   bytes.Buffer
+	count int
+	gz    *gzip.Writer
+	max   hlc.Time
+	min   hlc.Time
+	raw   int // Uncompressed size.
+	topic string
+}
+
+// add appends the mutation to the chunk. Mutations must be added in
+// time order.
+func (c *archiveChunk) add(mut types.Mutation) error {
+	after := mut.Data
+	if mut.IsDelete() {
+		after = json.RawMessage("null")
+	}
+	data, err := json.Marshal(&archiveLine{
+		After:   after,
+		Before:  mut.Before,
+		Key:     mut.Key,
+		Updated: mut.Time.String(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if c.gz == nil {
+		c.gz = gzip.NewWriter(&c.buf)
+		c.min = mut.Time
+	}
+	data = append(data, '\n')
+	if _, err := c.gz.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+	c.count++
+	c.max = mut.Time
+	c.raw += len(data)
+	return nil
+}
+
+// datePath returns the daily partition for the timestamp.
+func datePath(dir string, ts hlc.Time) string {
+	return path.Join(dir, time.Unix(0, ts.Nanos()).UTC().Format("2006-01-02"))
+}
+
+// formatTime encodes the timestamp in the same way as a CockroachDB
+// cloud-storage changefeed: the wall time to the second, followed by
+// nine digits of nanoseconds and ten digits of logical time.
+func formatTime(ts hlc.Time) string {
+	t := time.Unix(0, ts.Nanos()).UTC()
+	return fmt.Sprintf("%s%09d%010d", t.Format("20060102150405"), t.Nanosecond(), ts.Logical())
+}
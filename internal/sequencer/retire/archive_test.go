@@ -0,0 +1,66 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package retire
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResumeFrom verifies that the archive watermark is restored from
+// the most recent manifest.
+func TestResumeFrom(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	dir := t.TempDir()
+	a, err := newArchive("file://"+dir, nil, nil)
+	r.NoError(err)
+	group := &types.TableGroup{Name: ident.New("test")}
+
+	// Nothing has been archived.
+	ts, err := a.resumeFrom(ctx, group)
+	r.NoError(err)
+	r.Equal(hlc.Zero(), ts)
+
+	manifests := filepath.Join(dir, manifestDir, "test")
+	r.NoError(os.MkdirAll(manifests, 0755))
+	for _, to := range []hlc.Time{hlc.New(100, 1), hlc.New(20, 0)} {
+		r.NoError(os.WriteFile(filepath.Join(manifests, formatTime(to)+".json"),
+			[]byte(`{"to":"`+to.String()+`"}`), 0644))
+	}
+	// Files that are not manifests are ignored.
+	r.NoError(os.WriteFile(filepath.Join(manifests, "zzz.txt"), nil, 0644))
+
+	ts, err = a.resumeFrom(ctx, group)
+	r.NoError(err)
+	r.Equal(hlc.New(100, 1), ts)
+
+	// Other groups are unaffected.
+	ts, err = a.resumeFrom(ctx, &types.TableGroup{Name: ident.New("other")})
+	r.NoError(err)
+	r.Equal(hlc.Zero(), ts)
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package retire
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	archiveBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retire_archive_bytes_total",
+		Help: "the number of compressed bytes of retired mutations written to the archive",
+	})
+	archiveDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "retire_archive_duration_seconds",
+		Help:    "the length of time it took to archive a range of retired mutations",
+		Buckets: metrics.LatencyBuckets,
+	})
+	archiveErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retire_archive_errors_total",
+		Help: "the number of failed writes to the archive",
+	})
+	archiveMutations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retire_archive_mutations_total",
+		Help: "the number of retired mutations written to the archive",
+	})
+)
@@ -25,11 +25,23 @@ import (
 // Set is used by Wire.
 var Set = wire.NewSet(ProvideRetire)
 
-// ProvideRetire is called by Wire.
-func ProvideRetire(cfg *sequencer.Config, pool *types.StagingPool, stagers types.Stagers) *Retire {
-	return &Retire{
+// ProvideRetire is called by Wire. If [sequencer.Config.RetireArchiveURL]
+// is set, applied mutations will be archived before they are removed.
+func ProvideRetire(
+	cfg *sequencer.Config, leases types.Leases, pool *types.StagingPool, stagers types.Stagers,
+) (*Retire, error) {
+	ret := &Retire{
 		cfg:     cfg,
+		leases:  leases,
 		pool:    pool,
 		stagers: stagers,
 	}
+	if cfg.RetireArchiveURL != "" {
+		var err error
+		ret.archive, err = newArchive(cfg.RetireArchiveURL, pool, stagers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
 }
@@ -18,6 +18,8 @@
 package retire
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -32,55 +34,131 @@ import (
 
 // Retire implements a utility process for removing old mutations.
 type Retire struct {
+	archive *archive // Nil unless archiving is enabled.
 	cfg     *sequencer.Config
+	leases  types.Leases
 	pool    *types.StagingPool
 	stagers types.Stagers
 }
 
+// archiveLeaseName returns the name of the lease that ensures that only
+// one instance of Replicator archives the mutations in a group.
+func archiveLeaseName(group *types.TableGroup) string {
+	return fmt.Sprintf("retire.Archive.%s", group.Name.Canonical().Raw())
+}
+
 // Start a goroutine to ensure that old mutations are eventually
 // discarded. Any staged mutations whose timestamp is less than the
 // minimum value will be purged. This method will return a notification
 // variable that emits the time before which all applied, staged
-// mutations will have been purged.
+// mutations will have been purged. If archiving is enabled, the
+// mutations will be removed only after they have been written to the
+// archive and only the instance of Replicator that holds a lease on
+// the group will archive and remove mutations.
 func (r *Retire) Start(
 	ctx *stopper.Context, group *types.TableGroup, bounds *notify.Var[hlc.Range],
 ) *notify.Var[hlc.Time] {
 	ret := &notify.Var[hlc.Time]{}
-	ctx.Go(func(ctx *stopper.Context) error {
-		for {
-			_, err := stopvar.DoWhenChangedOrInterval(ctx, hlc.RangeEmpty(), bounds, time.Minute,
-				func(ctx *stopper.Context, _, bounds hlc.Range) error {
-					before := bounds.Min()
-					before = hlc.New(before.Nanos()-r.cfg.RetireOffset.Nanoseconds(), before.Logical())
-					if hlc.Compare(before, hlc.Zero()) <= 0 {
-						return nil
-					}
+	if r.archive == nil {
+		ctx.Go(func(ctx *stopper.Context) error {
+			r.loop(ctx, group, bounds, ret)
+			return nil
+		})
+		return ret
+	}
+
+	// Other instances may delete mutations only after they have been
+	// archived, so the entire loop runs within the lease.
+	ctx.Go(func(outer *stopper.Context) error {
+		names := []string{archiveLeaseName(group)}
+		for !outer.IsStopping() {
+			r.leases.Singleton(outer, names, func(leaseCtx context.Context) error {
+				log.Debugf("acquired archive lease for %s", group)
+				defer log.Debugf("released archive lease for %s", group)
+
+				sub := stopper.WithContext(leaseCtx)
+				_ = sub.Call(func(ctx *stopper.Context) error {
+					r.loop(ctx, group, bounds, ret)
+					return nil
+				})
+				sub.Stop(time.Second)
+				<-sub.Done()
+
+				if outer.IsStopping() {
+					return types.ErrCancelSingleton
+				}
+				return leaseCtx.Err()
+			})
+		}
+		return nil
+	})
+	return ret
+}
+
+// loop archives and retires mutations until the context is stopped.
+func (r *Retire) loop(
+	ctx *stopper.Context,
+	group *types.TableGroup,
+	bounds *notify.Var[hlc.Range],
+	ret *notify.Var[hlc.Time],
+) {
+	// The time through which mutations have been archived. This is
+	// restored from the archive's manifests on the first pass, since
+	// another instance may have archived mutations before we acquired
+	// the lease.
+	var archived hlc.Time
+	resumed := false
+	for {
+		_, err := stopvar.DoWhenChangedOrInterval(ctx, hlc.RangeEmpty(), bounds, time.Minute,
+			func(ctx *stopper.Context, _, bounds hlc.Range) error {
+				before := bounds.Min()
+				before = hlc.New(before.Nanos()-r.cfg.RetireOffset.Nanoseconds(), before.Logical())
+				if hlc.Compare(before, hlc.Zero()) <= 0 {
+					return nil
+				}
 
-					log.Tracef("retiring mutations in %s <= %s (%s offset)", group, before, r.cfg.RetireOffset)
-					for _, tbl := range group.Tables {
-						stager, err := r.stagers.Get(ctx, tbl)
+				log.Tracef("retiring mutations in %s <= %s (%s offset)", group, before, r.cfg.RetireOffset)
+				if r.archive != nil {
+					if !resumed {
+						var err error
+						archived, err = r.archive.resumeFrom(ctx, group)
 						if err != nil {
-							return errors.Wrapf(err, "could not acquire stager")
+							return errors.Wrapf(err, "could not resume archive of %s", group)
 						}
-						if err := stager.Retire(ctx, r.pool, before); err != nil {
-							return errors.Wrapf(err, "could not retire mutations in %s", tbl.Raw())
+						resumed = true
+						log.Debugf("resuming archive of %s after %s", group, archived)
+					}
+					if hlc.Compare(before, archived) > 0 {
+						if err := r.archive.write(ctx, group, archived, before); err != nil {
+							return errors.Wrapf(err, "could not archive mutations in %s", group)
 						}
 					}
-					// Notify listeners of success.
-					ret.Set(before)
-					log.Tracef("retired mutations in %s <= %s (%s offset)", group, before, r.cfg.RetireOffset)
-					return nil
-				})
-			if err != nil {
-				log.WithError(err).Warn("error when trying to purge old mutations; will continue")
-			}
-			select {
-			case <-ctx.Stopping():
+				}
+				for _, tbl := range group.Tables {
+					stager, err := r.stagers.Get(ctx, tbl)
+					if err != nil {
+						return errors.Wrapf(err, "could not acquire stager")
+					}
+					if err := stager.Retire(ctx, r.pool, before); err != nil {
+						return errors.Wrapf(err, "could not retire mutations in %s", tbl.Raw())
+					}
+				}
+				if hlc.Compare(before, archived) > 0 {
+					archived = before
+				}
+				// Notify listeners of success.
+				ret.Set(before)
+				log.Tracef("retired mutations in %s <= %s (%s offset)", group, before, r.cfg.RetireOffset)
 				return nil
-			case <-time.After(time.Second):
-				// Delay to prevent log spam.
-			}
+			})
+		if err != nil {
+			log.WithError(err).Warn("error when trying to purge old mutations; will continue")
 		}
-	})
-	return ret
+		select {
+		case <-ctx.Stopping():
+			return
+		case <-time.After(time.Second):
+			// Delay to prevent log spam.
+		}
+	}
 }
@@ -17,18 +17,29 @@
 package retire_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/seqtest"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/sinktest/recorder"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/eventproc"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/stretchr/testify/require"
@@ -100,3 +111,168 @@ func TestRetire(t *testing.T) {
 	r.NoError(stopvar.WaitForValue(ctx, hlc.New(unstageStart+unstageCount, 0), progress))
 	r.Equal(rowcount-unstageCount, countStaged())
 }
+
+// TestRetireArchive verifies that applied mutations are written to an
+// archive which can be replayed by the objstore source before they are
+// removed from staging.
+func TestRetireArchive(t *testing.T) {
+	r := require.New(t)
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+	dir := t.TempDir()
+	seqCfg := &sequencer.Config{
+		Parallelism:      2,
+		QuiescentPeriod:  time.Second,
+		RetireArchiveURL: "file://" + dir,
+	}
+	seqFixture, err := seqtest.NewSequencerFixture(fixture, seqCfg, &script.Config{})
+	r.NoError(err)
+	ctx := fixture.Context
+
+	tblInfo, err := fixture.CreateTargetTable(ctx, "CREATE TABLE %s (pk INT PRIMARY KEY)")
+	r.NoError(err)
+	group := &types.TableGroup{
+		Name:   ident.New("test"),
+		Tables: []ident.Table{tblInfo.Name()},
+	}
+	bounds := &notify.Var[hlc.Range]{}
+	firstCtx := stopper.WithContext(ctx)
+	progress := seqFixture.Retire.Start(firstCtx, group, bounds)
+
+	const rowcount = 100
+	const appliedCount = 25
+	muts := make([]types.Mutation, rowcount)
+	for idx := range muts {
+		muts[idx] = types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d}`, idx)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, idx)),
+			Time: hlc.New(int64(idx+1), idx),
+		}
+	}
+	stager, err := fixture.Stagers.Get(ctx, tblInfo.Name())
+	r.NoError(err)
+	r.NoError(stager.Stage(ctx, fixture.StagingPool, muts))
+	r.NoError(stager.MarkApplied(ctx, fixture.StagingPool, muts[:appliedCount]))
+
+	// Stub entries are not archived.
+	stub := types.Mutation{Key: json.RawMessage(`[-1]`), Time: hlc.New(1, 0)}
+	r.NoError(stager.MarkApplied(ctx, fixture.StagingPool, []types.Mutation{stub}))
+
+	end := muts[appliedCount-1].Time
+	bounds.Set(hlc.RangeIncluding(end, hlc.New(rowcount*2, 0)))
+	r.NoError(stopvar.WaitForValue(ctx, end, progress))
+
+	b, err := local.New(os.DirFS(dir))
+	r.NoError(err)
+	var data, manifests []string
+	var resolved string
+	r.NoError(b.Walk(ctx, "", &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, file string) error {
+			switch {
+			case strings.HasPrefix(file, "manifests/test/"):
+				manifests = append(manifests, file)
+			case strings.HasSuffix(file, ".RESOLVED"):
+				resolved = file
+			default:
+				r.True(strings.HasPrefix(file, "test/"), file)
+				data = append(data, file)
+			}
+			return nil
+		}))
+	r.Len(manifests, 1)
+	r.NotEmpty(data)
+	r.NotEmpty(resolved)
+
+	// The manifest should describe the files that were written.
+	rd, err := b.Open(ctx, manifests[0])
+	r.NoError(err)
+	buf, err := io.ReadAll(rd)
+	r.NoError(rd.Close())
+	r.NoError(err)
+	var m struct {
+		Files []struct {
+			Count int
+			Path  string
+		}
+		Resolved string
+		To       string
+	}
+	r.NoError(json.Unmarshal(buf, &m))
+	r.Equal(resolved, m.Resolved)
+	r.Equal(end.String(), m.To)
+	r.Len(m.Files, len(data))
+
+	// Replay the data files in the same way as the objstore source.
+	parser, err := cdcjson.New(bufio.MaxScanTokenSize)
+	r.NoError(err)
+	rec := &recorder.Recorder{}
+	proc := eventproc.NewLocal(rec, b, parser, tblInfo.Name().Schema())
+	for _, file := range data {
+		r.NoError(proc.Process(ctx, file))
+	}
+	r.Equal(appliedCount, rec.Count())
+	for _, call := range rec.Calls() {
+		for tbl, mut := range call.Multi.Mutations() {
+			r.True(ident.Equal(tblInfo.Name(), tbl))
+			r.Equal(muts[mut.Time.Nanos()-1].Data, mut.Data)
+		}
+	}
+
+	// The archived mutations were removed.
+	staged, err := fixture.PeekStaged(ctx, tblInfo.Name(),
+		hlc.RangeIncluding(hlc.Zero(), hlc.New(rowcount*2, 0)))
+	r.NoError(err)
+	r.Len(staged, rowcount-appliedCount)
+
+	// A new instance resumes from the last manifest, instead of
+	// starting from the beginning of time. It will not archive any
+	// mutations until the first instance releases its lease.
+	resumed, err := retire.ProvideRetire(seqCfg, fixture.Leases, fixture.StagingPool, fixture.Stagers)
+	r.NoError(err)
+	r.NoError(stager.MarkApplied(ctx, fixture.StagingPool, muts[appliedCount:2*appliedCount]))
+	nextEnd := muts[2*appliedCount-1].Time
+	nextBounds := &notify.Var[hlc.Range]{}
+	nextProgress := resumed.Start(ctx, group, nextBounds)
+	nextBounds.Set(hlc.RangeIncluding(nextEnd, hlc.New(rowcount*2, 0)))
+	time.Sleep(100 * time.Millisecond)
+	_, changed := nextProgress.Get()
+	select {
+	case <-changed:
+		r.Fail("archive should wait for the lease")
+	default:
+	}
+	firstCtx.Stop(time.Second)
+	<-firstCtx.Done()
+	r.NoError(stopvar.WaitForValue(ctx, nextEnd, nextProgress))
+
+	manifests = nil
+	r.NoError(b.Walk(ctx, "manifests/test", &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, file string) error {
+			manifests = append(manifests, file)
+			return nil
+		}))
+	r.Len(manifests, 2)
+	rd, err = b.Open(ctx, manifests[1])
+	r.NoError(err)
+	buf, err = io.ReadAll(rd)
+	r.NoError(rd.Close())
+	r.NoError(err)
+	var next struct {
+		Files []struct {
+			Count int
+		}
+		From string
+		To   string
+	}
+	r.NoError(json.Unmarshal(buf, &next))
+	r.Equal(end.String(), next.From)
+	r.Equal(nextEnd.String(), next.To)
+
+	// The lower bound is exclusive, so the mutation at the end of the
+	// first range is not archived again.
+	archivedCount := 0
+	for _, file := range next.Files {
+		archivedCount += file.Count
+	}
+	r.Equal(appliedCount, archivedCount)
+}
@@ -64,36 +64,30 @@ func (w *wrapper) Start(
 		return nil, nil, err
 	}
 
-	scr, err := w.loader.Bind(ctx, schema, opts.Delegate, w.watchers)
+	scr, err := w.loader.BindVar(ctx, schema, opts.Delegate, w.watchers)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// The script is empty, but any declarative transforms will have
+	// been installed by the call to BindVar.
+	if !w.loader.Configured() {
+		return w.delegate.Start(ctx, opts)
+	}
+
 	// Install the target-phase acceptor into the options chain. This
 	// will be invoked for mutations which have passed through the
-	// sequencer stack.
-	if scr.Targets.Len() > 0 {
-		// If the userscript has defined an apply function, we need to
-		// ensure that a database transaction will be available to support
-		// the api.getTX() function. This is mainly relevant to immediate
-		// mode, in which the sequencer caller won't necessarily have
-		// provided a transaction.
-		ensureTX := false
-		for tgt := range scr.Targets.Values() {
-			if tgt.UserAcceptor != nil {
-				ensureTX = true
-				break
-			}
-		}
-
-		opts = opts.Copy()
-		opts.Delegate = types.OrderedAcceptorFrom(&targetAcceptor{
-			delegate:   opts.Delegate,
-			ensureTX:   ensureTX,
-			group:      opts.Group,
-			targetPool: w.targetPool,
-			userScript: scr,
-		}, w.watchers)
+	// sequencer stack. The acceptor is installed even if the current
+	// version of the userscript does not configure any targets, since
+	// a reloaded script may do so.
+	opts = opts.Copy()
+	opts.Delegate = &targetAcceptor{
+		delegate:   opts.Delegate,
+		group:      opts.Group,
+		loader:     w.loader,
+		targetPool: w.targetPool,
+		userScript: scr,
+		watchers:   w.watchers,
 	}
 
 	// Initialize downstream sequencer.
@@ -105,22 +99,25 @@ func (w *wrapper) Start(
 	// Install the source-phase acceptor. This provides the user with
 	// the opportunity to rewrite mutations before they are presented to
 	// the upstream sequencer.
-	if sourceBindings, ok := scr.Sources.Get(opts.Group.Name); ok {
-		watcher, err := w.watchers.Get(opts.Group.Enclosing)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		acc = &sourceAcceptor{
-			delegate:       acc,
-			group:          opts.Group,
-			sourceBindings: sourceBindings,
-			watcher:        watcher,
-		}
+	watcher, err := w.watchers.Get(opts.Group.Enclosing)
+	if err != nil {
+		return nil, nil, err
+	}
+	acc = &sourceAcceptor{
+		delegate:   acc,
+		group:      opts.Group,
+		userScript: scr,
+		watcher:    watcher,
 	}
 	return acc, stat, nil
 }
 
+// Loader returns the userscript loader, which will be nil if no
+// userscript has been configured.
+func (s *Sequencer) Loader() *script.Loader {
+	return s.loader
+}
+
 // Unwrap is an informal protocol to return the delegate.
 func (w *wrapper) Unwrap() sequencer.Sequencer {
 	return w.delegate
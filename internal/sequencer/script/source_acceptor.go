@@ -19,6 +19,7 @@ package script
 import (
 	"context"
 
+	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -30,10 +31,10 @@ import (
 // concerned with routing incoming mutations to the correct
 // staging and/or target table.
 type sourceAcceptor struct {
-	delegate       types.MultiAcceptor
-	group          *types.TableGroup
-	sourceBindings *script.Source
-	watcher        types.Watcher
+	delegate   types.MultiAcceptor
+	group      *types.TableGroup
+	userScript *notify.Var[*script.UserScript]
+	watcher    types.Watcher
 }
 
 var _ types.MultiAcceptor = (*sourceAcceptor)(nil)
@@ -41,29 +42,54 @@ var _ types.MultiAcceptor = (*sourceAcceptor)(nil)
 func (a *sourceAcceptor) AcceptMultiBatch(
 	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
 ) error {
-	return acceptBatch(ctx, a, batch, opts)
+	bindings, ok := a.sourceBindings()
+	if !ok {
+		return a.delegate.AcceptMultiBatch(ctx, batch, opts)
+	}
+	return acceptBatch(ctx, a, bindings, batch, opts)
 }
 
 func (a *sourceAcceptor) AcceptTableBatch(
 	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
-	return acceptBatch(ctx, a, batch, opts)
+	bindings, ok := a.sourceBindings()
+	if !ok {
+		return a.delegate.AcceptTableBatch(ctx, batch, opts)
+	}
+	return acceptBatch(ctx, a, bindings, batch, opts)
 }
 
 func (a *sourceAcceptor) AcceptTemporalBatch(
 	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
 ) error {
-	return acceptBatch(ctx, a, batch, opts)
+	bindings, ok := a.sourceBindings()
+	if !ok {
+		return a.delegate.AcceptTemporalBatch(ctx, batch, opts)
+	}
+	return acceptBatch(ctx, a, bindings, batch, opts)
+}
+
+// sourceBindings returns the configureSource() bindings for the group
+// from the current version of the userscript. The userscript is
+// retrieved once per batch, so that a reloaded script will be used
+// starting with the next batch.
+func (a *sourceAcceptor) sourceBindings() (*script.Source, bool) {
+	scr, _ := a.userScript.Get()
+	return scr.Sources.Get(a.group.Name)
 }
 
 // acceptBatch wants to be a generic method.
 func acceptBatch[B types.Batch[B]](
-	ctx context.Context, a *sourceAcceptor, batch B, opts *types.AcceptOptions,
+	ctx context.Context,
+	a *sourceAcceptor,
+	bindings *script.Source,
+	batch B,
+	opts *types.AcceptOptions,
 ) error {
 	nextBatch := &types.MultiBatch{}
 
 	for table, mut := range batch.Mutations() {
-		if err := a.acceptOne(ctx, nextBatch, table, mut); err != nil {
+		if err := a.acceptOne(ctx, bindings, nextBatch, table, mut); err != nil {
 			return err
 		}
 	}
@@ -78,17 +104,21 @@ func acceptBatch[B types.Batch[B]](
 }
 
 func (a *sourceAcceptor) acceptOne(
-	ctx context.Context, acc *types.MultiBatch, table ident.Table, mutToDispatch types.Mutation,
+	ctx context.Context,
+	bindings *script.Source,
+	acc *types.MultiBatch,
+	table ident.Table,
+	mutToDispatch types.Mutation,
 ) error {
 	script.AddMeta(a.group.Name.Raw(), table, &mutToDispatch)
 
 	isDelete := mutToDispatch.IsDelete()
 
-	dispatch := a.sourceBindings.Dispatch
+	dispatch := bindings.Dispatch
 	fnName := "dispatch"
 	if isDelete {
 		// Same underlying func signature.
-		dispatch = script.Dispatch(a.sourceBindings.DeletesTo)
+		dispatch = script.Dispatch(bindings.DeletesTo)
 		fnName = "deletesTo"
 	}
 
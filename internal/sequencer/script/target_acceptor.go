@@ -20,6 +20,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/pkg/errors"
@@ -31,33 +32,89 @@ import (
 // interacts with user-defined apply functions or final data fixups.
 type targetAcceptor struct {
 	delegate   types.TableAcceptor
-	ensureTX   bool
 	group      *types.TableGroup
+	loader     *script.Loader
 	targetPool *types.TargetPool
+	userScript *notify.Var[*script.UserScript]
+	watchers   types.Watchers
+}
+
+var _ types.MultiAcceptor = (*targetAcceptor)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *targetAcceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	acc, release := a.hold()
+	defer release()
+	return acc.AcceptMultiBatch(ctx, batch, opts)
+}
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (a *targetAcceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	acc, release := a.hold()
+	defer release()
+	return acc.AcceptTableBatch(ctx, batch, opts)
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (a *targetAcceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	acc, release := a.hold()
+	defer release()
+	return acc.AcceptTemporalBatch(ctx, batch, opts)
+}
+
+// hold prevents the userscript from being reloaded until the returned
+// function is called. The returned acceptor uses the current version
+// of the userscript for every table in the batch.
+func (a *targetAcceptor) hold() (types.MultiAcceptor, func()) {
+	release := a.loader.Hold()
+	scr, _ := a.userScript.Get()
+	return types.OrderedAcceptorFrom(&pinnedAcceptor{a, scr}, a.watchers), release
+}
+
+// A pinnedAcceptor applies a single version of the userscript.
+type pinnedAcceptor struct {
+	parent     *targetAcceptor
 	userScript *script.UserScript
 }
 
-var _ types.TableAcceptor = (*targetAcceptor)(nil)
+var _ types.TableAcceptor = (*pinnedAcceptor)(nil)
 
 // AcceptTableBatch implements [types.TableAcceptor]. It will invoke
 // user-defined dispatch and/or map functions.
-func (a *targetAcceptor) AcceptTableBatch(
+func (a *pinnedAcceptor) AcceptTableBatch(
 	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
-	if _, isTX := opts.TargetQuerier.(*sql.Tx); a.ensureTX && !isTX {
-		return a.acceptWithTransaction(ctx, batch, opts)
+	target, ok := a.userScript.Targets.Get(batch.Table)
+	if !ok {
+		// No target configuration.
+		return a.parent.delegate.AcceptTableBatch(ctx, batch, opts)
+	}
+
+	// If the userscript has defined an apply function, we need to
+	// ensure that a database transaction will be available to support
+	// the api.getTX() function. This is mainly relevant to immediate
+	// mode, in which the sequencer caller won't necessarily have
+	// provided a transaction.
+	if _, isTX := opts.TargetQuerier.(*sql.Tx); target.UserAcceptor != nil && !isTX {
+		return a.acceptWithTransaction(ctx, target, batch, opts)
 	}
-	return a.doMap(ctx, batch, opts)
+	return a.doMap(ctx, target, batch, opts)
 }
 
 // acceptWithTransaction creates a database transaction and calls
 // AcceptTableBatch. This code path is used in immediate mode when the
 // userscript has a user-defined accept function callback.
-func (a *targetAcceptor) acceptWithTransaction(
-	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+func (a *pinnedAcceptor) acceptWithTransaction(
+	ctx context.Context, target *script.Target, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
 	log.Trace("creating target transaction for user-defined apply function")
-	tx, err := a.targetPool.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := a.parent.targetPool.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -67,22 +124,16 @@ func (a *targetAcceptor) acceptWithTransaction(
 	opts.TargetQuerier = tx
 
 	// Return to our usual dispatch.
-	if err := a.AcceptTableBatch(ctx, batch, opts); err != nil {
+	if err := a.doMap(ctx, target, batch, opts); err != nil {
 		return err
 	}
 
 	return errors.WithStack(tx.Commit())
 }
 
-func (a *targetAcceptor) doMap(
-	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+func (a *pinnedAcceptor) doMap(
+	ctx context.Context, target *script.Target, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
-	target, ok := a.userScript.Targets.Get(batch.Table)
-	if !ok {
-		// No target configuration.
-		return a.delegate.AcceptTableBatch(ctx, batch, opts)
-	}
-
 	if target.Map != nil {
 		mapped := batch.Empty()
 		mapped.Data = make([]types.Mutation, 0, len(batch.Data))
@@ -101,7 +152,7 @@ func (a *targetAcceptor) doMap(
 				}
 				continue
 			}
-			script.AddMeta(a.group.Name.Raw(), batch.Table, &mut)
+			script.AddMeta(a.parent.group.Name.Raw(), batch.Table, &mut)
 			next, keep, err := target.Map(ctx, mut)
 			if err != nil {
 				return err
@@ -121,5 +172,5 @@ func (a *targetAcceptor) doMap(
 	}
 
 	// Otherwise, continue down the standard path.
-	return a.delegate.AcceptTableBatch(ctx, batch, opts)
+	return a.parent.delegate.AcceptTableBatch(ctx, batch, opts)
 }
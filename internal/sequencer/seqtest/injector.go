@@ -37,7 +37,7 @@ func NewSequencerFixture(*all.Fixture, *sequencer.Config, *userScript.Config) (*
 		wire.Bind(new(context.Context), new(*stopper.Context)),
 
 		wire.FieldsOf(new(*all.Fixture),
-			"Configs", "Diagnostics", "Fixture", "Stagers", "Watchers"),
+			"Configs", "DLQs", "Diagnostics", "Fixture", "Stagers", "Watchers"),
 
 		retire.Set,
 		switcher.Set,
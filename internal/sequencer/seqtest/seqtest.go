@@ -68,6 +68,8 @@ func (f *Fixture) SequencerFor(
 		return f.Staging.Wrap(ctx, f.Core)
 	case switcher.ModeImmediate:
 		return f.Immediate, nil
+	case switcher.ModeTransactional:
+		return f.Staging.Wrap(ctx, f.Core.PreserveTransactions())
 	default:
 		return nil, errors.Errorf("unimplemented, %s", mode)
 	}
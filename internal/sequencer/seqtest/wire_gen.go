@@ -38,18 +38,22 @@ func NewSequencerFixture(fixture *all.Fixture, config *sequencer.Config, scriptC
 	chaosChaos := &chaos.Chaos{
 		Config: config,
 	}
+	dlQs := fixture.DLQs
 	stagingSchema := baseFixture.StagingDB
 	leases, err := provideLeases(context, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
 	targetPool := baseFixture.TargetPool
-	coreCore := core.ProvideCore(config, leases, schedulerScheduler, targetPool)
+	coreCore := core.ProvideCore(config, dlQs, leases, schedulerScheduler, targetPool)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(config, targetPool, marker, once, retryTarget, stagers)
-	retireRetire := retire.ProvideRetire(config, stagingPool, stagers)
+	retireRetire, err := retire.ProvideRetire(config, leases, stagingPool, stagers)
+	if err != nil {
+		return nil, err
+	}
 	configs := fixture.Configs
 	diagnostics := fixture.Diagnostics
 	loader, err := script.ProvideLoader(context, configs, scriptConfig, diagnostics)
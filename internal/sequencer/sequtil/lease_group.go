@@ -28,6 +28,16 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// LeaseNames returns the names of the leases that [LeaseGroup] will
+// acquire for the group.
+func LeaseNames(group *types.TableGroup) []string {
+	names := make([]string, len(group.Tables))
+	for idx, table := range group.Tables {
+		names[idx] = fmt.Sprintf("sequtil.Lease.%s", table.Canonical().Raw())
+	}
+	return names
+}
+
 // LeaseGroup ensures that multiple sequencers do not operate on the
 // same tables. This function will create a goroutine within the context
 // that acquires a lease based on the tables in the group. The callback
@@ -48,10 +58,7 @@ func LeaseGroup(
 	// Start a goroutine in the outer context.
 	outer.Go(func(outer *stopper.Context) error {
 		// Acquire a lease on each table in the group.
-		names := make([]string, len(group.Tables))
-		for idx, table := range group.Tables {
-			names[idx] = fmt.Sprintf("sequtil.Lease.%s", table.Canonical().Raw())
-		}
+		names := LeaseNames(group)
 
 		// Run this in a loop in case of non-renewal. This is likely
 		// caused by database overload or any other case where we can't
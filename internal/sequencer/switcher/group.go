@@ -139,6 +139,8 @@ func (g *groupSequencer) switchModeLocked(
 		opts.BatchReader = nil
 	case ModeConsistent:
 		nextSeq, err = g.staging.Wrap(ctx, g.core)
+	case ModeTransactional:
+		nextSeq, err = g.staging.Wrap(ctx, g.core.PreserveTransactions())
 	default:
 		return errors.Errorf("unimplemented: %v", next)
 	}
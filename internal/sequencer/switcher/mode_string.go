@@ -12,12 +12,13 @@ func _() {
 	_ = x[ModeBestEffort-1]
 	_ = x[ModeConsistent-2]
 	_ = x[ModeImmediate-3]
+	_ = x[ModeTransactional-4]
 	_ = x[MinMode-1]
 }
 
-const _Mode_name = "ModeUnknownModeBestEffortModeConsistentModeImmediate"
+const _Mode_name = "ModeUnknownModeBestEffortModeConsistentModeImmediateModeTransactional"
 
-var _Mode_index = [...]uint8{0, 11, 25, 39, 52}
+var _Mode_index = [...]uint8{0, 11, 25, 39, 52, 69}
 
 func (i Mode) String() string {
 	if i < 0 || i >= Mode(len(_Mode_index)-1) {
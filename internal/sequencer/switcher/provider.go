@@ -19,6 +19,7 @@ package switcher
 import (
 	"github.com/cockroachdb/replicator/internal/sequencer/besteffort"
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
@@ -34,6 +35,7 @@ import (
 var Set = wire.NewSet(
 	besteffort.Set,
 	chaos.Set,
+	compat.Set,
 	core.Set,
 	immediate.Set,
 	decorators.Set,
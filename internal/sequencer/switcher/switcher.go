@@ -20,6 +20,7 @@ package switcher
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/field-eng-powertools/stopper"
@@ -44,11 +45,24 @@ const (
 	ModeBestEffort
 	ModeConsistent
 	ModeImmediate
+	ModeTransactional
 
 	MaxMode = iota - 1 // Used for testing all modes.
 	MinMode = Mode(1)  // Used for testing all modes.
 )
 
+// ParseMode returns the Mode with the given name. The comparison is
+// case-insensitive and the "Mode" prefix is optional.
+func ParseMode(s string) (Mode, error) {
+	for m := ModeUnknown; m <= MaxMode; m++ {
+		name := m.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, strings.TrimPrefix(name, "Mode")) {
+			return m, nil
+		}
+	}
+	return ModeUnknown, errors.Errorf("unknown mode %q", s)
+}
+
 // Switcher switches between delegate sequencers. It also adds script
 // bindings into the sequencer stack.
 type Switcher struct {
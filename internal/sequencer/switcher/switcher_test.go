@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSwitcher(t *testing.T) {
@@ -67,3 +68,18 @@ func TestSwitcher(t *testing.T) {
 		},
 		func(t *testing.T, check *seqtest.Check) {})
 }
+
+func TestParseMode(t *testing.T) {
+	r := require.New(t)
+	for m := switcher.ModeUnknown; m <= switcher.MaxMode; m++ {
+		found, err := switcher.ParseMode(m.String())
+		r.NoError(err)
+		r.Equal(m, found)
+	}
+	found, err := switcher.ParseMode("bestEffort")
+	r.NoError(err)
+	r.Equal(switcher.ModeBestEffort, found)
+
+	_, err = switcher.ParseMode("bogus")
+	r.Error(err)
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package throttle
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Limits are the throttling thresholds applied to a target schema or
+// table. Zero values are unlimited.
+type Limits struct {
+	BytesPerSecond int64 `json:"bytesPerSecond,omitempty"`
+	RowsPerSecond  int64 `json:"rowsPerSecond,omitempty"`
+	Transactions   int   `json:"transactions,omitempty"`
+}
+
+// IsZero returns true if no limits are set.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Config controls the rate at which mutations are written to the
+// target. The flags bound by [Config.Bind] may be set while Replicator
+// is running (e.g. by calling api.setOptions() from a userscript) and
+// the new limits will take effect immediately.
+type Config struct {
+	BytesPerSecond int64    // Per target schema.
+	RowsPerSecond  int64    // Per target schema.
+	TableLimits    []string // Per-table limits: TABLE=rows:N,bytes:N,txns:N
+	Transactions   int      // Concurrent target transactions per schema.
+
+	// Shared with any copies of the Config. This is created lazily so
+	// that a Config remains a comparable value.
+	live *liveState
+}
+
+// liveState holds the parsed settings.
+type liveState struct {
+	mu      sync.Mutex            // Serializes updates from flags.
+	current notify.Var[*settings] // Updated by publishLocked.
+}
+
+// liveMu guards the lazy initialization of Config.live.
+var liveMu sync.Mutex
+
+// settings is the parsed form of the Config.
+type settings struct {
+	schema Limits
+	tables ident.TableMap[Limits]
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	tmp := pflag.NewFlagSet("throttle", pflag.ContinueOnError)
+	tmp.Int64Var(&c.BytesPerSecond, "throttleBytesPerSecond", 0,
+		"limit the rate at which mutation payloads are written to each target schema; 0 to disable")
+	tmp.Int64Var(&c.RowsPerSecond, "throttleRowsPerSecond", 0,
+		"limit the rate at which rows are written to each target schema; 0 to disable")
+	tmp.Var((*tableLimitsValue)(&c.TableLimits), "throttleTable",
+		"limit writes to specific tables, in addition to the schema limits; "+
+			"specified as a semicolon-separated list of db.schema.table=rows:N,bytes:N,txns:N; "+
+			"setting the flag replaces all table limits, so an empty value removes them")
+	tmp.IntVar(&c.Transactions, "throttleTransactions", 0,
+		"limit the number of concurrent target transactions for each target schema; 0 to disable")
+
+	// Re-publish the settings whenever a flag is set.
+	tmp.VisitAll(func(flag *pflag.Flag) {
+		flag.Value = &liveValue{c, flag.Value}
+		f.AddFlag(flag)
+	})
+}
+
+// Preflight validates the configuration.
+func (c *Config) Preflight() error {
+	live := c.liveState()
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	return c.publishLocked()
+}
+
+// liveState returns the state that is shared with copies of the
+// Config, creating it if necessary.
+func (c *Config) liveState() *liveState {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+	if c.live == nil {
+		c.live = &liveState{}
+	}
+	return c.live
+}
+
+// settings returns the current, parsed settings.
+func (c *Config) settings() *settings {
+	live := c.liveState()
+	if ret, _ := live.current.Get(); ret != nil {
+		return ret
+	}
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	// Fall back to unlimited if the configuration is invalid. This
+	// can only happen if Preflight was not called.
+	if err := c.publishLocked(); err != nil {
+		live.current.Set(&settings{})
+	}
+	ret, _ := live.current.Get()
+	return ret
+}
+
+// publishLocked parses the configuration and makes it available. The
+// caller must hold the lock on the live state.
+func (c *Config) publishLocked() error {
+	if c.BytesPerSecond < 0 || c.RowsPerSecond < 0 || c.Transactions < 0 {
+		return errors.New("throttle limits must not be negative")
+	}
+	next := &settings{
+		schema: Limits{
+			BytesPerSecond: c.BytesPerSecond,
+			RowsPerSecond:  c.RowsPerSecond,
+			Transactions:   c.Transactions,
+		},
+	}
+	for _, spec := range c.TableLimits {
+		table, limits, err := parseTableLimits(spec)
+		if err != nil {
+			return err
+		}
+		next.tables.Put(table, limits)
+	}
+	c.live.current.Set(next)
+	return nil
+}
+
+// parseTableLimits parses a string of the form
+// db.schema.table=rows:N,bytes:N,txns:N.
+func parseTableLimits(spec string) (ident.Table, Limits, error) {
+	var limits Limits
+	name, values, ok := strings.Cut(spec, "=")
+	if !ok {
+		return ident.Table{}, limits, errors.Errorf("expecting TABLE=LIMITS, got %q", spec)
+	}
+	table, err := ident.ParseTable(strings.TrimSpace(name))
+	if err != nil {
+		return ident.Table{}, limits, err
+	}
+	for _, part := range strings.Split(values, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return ident.Table{}, limits, errors.Errorf("expecting KEY:VALUE in %q", spec)
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return ident.Table{}, limits, errors.Errorf("invalid value for %s in %q", key, spec)
+		}
+		switch key {
+		case "bytes":
+			limits.BytesPerSecond = n
+		case "rows":
+			limits.RowsPerSecond = n
+		case "txns":
+			limits.Transactions = int(n)
+		default:
+			return ident.Table{}, limits, errors.Errorf("unknown limit %q in %q", key, spec)
+		}
+	}
+	return table, limits, nil
+}
+
+// tableLimitsValue is a flag value that contains semicolon-separated
+// table limits. Unlike a string array, each call to Set replaces the
+// previous value, so that limits may be changed or removed while
+// Replicator is running.
+type tableLimitsValue []string
+
+var _ pflag.Value = (*tableLimitsValue)(nil)
+
+// Set implements [pflag.Value].
+func (v *tableLimitsValue) Set(s string) error {
+	var next []string
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			next = append(next, part)
+		}
+	}
+	*v = next
+	return nil
+}
+
+// String implements [pflag.Value].
+func (v *tableLimitsValue) String() string {
+	return strings.Join(*v, ";")
+}
+
+// Type implements [pflag.Value].
+func (v *tableLimitsValue) Type() string {
+	return "string"
+}
+
+// liveValue republishes the Config's settings whenever the flag value
+// is changed.
+type liveValue struct {
+	cfg *Config
+	pflag.Value
+}
+
+// Set implements [pflag.Value].
+func (v *liveValue) Set(s string) error {
+	live := v.cfg.liveState()
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	prev := v.Value.String()
+	if err := v.Value.Set(s); err != nil {
+		return err
+	}
+	if err := v.cfg.publishLocked(); err != nil {
+		// Restore the previous value, so that a later update to
+		// another flag does not observe the invalid value.
+		_ = v.Value.Set(prev)
+		return err
+	}
+	return nil
+}
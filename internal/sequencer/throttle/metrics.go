@@ -0,0 +1,59 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package throttle
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	activeTransactions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "throttle_active_transactions",
+		Help: "the number of target transactions admitted by the throttle",
+	}, metrics.SchemaLabels)
+	bytesWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_bytes_wait_seconds_total",
+		Help: "the length of time spent waiting for the bytes-per-second limit",
+	}, metrics.SchemaLabels)
+	rowsWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_rows_wait_seconds_total",
+		Help: "the length of time spent waiting for the rows-per-second limit",
+	}, metrics.SchemaLabels)
+	transactionsWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_transactions_wait_seconds_total",
+		Help: "the length of time spent waiting for the concurrent transaction limit",
+	}, metrics.SchemaLabels)
+
+	tableActiveTransactions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "throttle_table_active_transactions",
+		Help: "the number of target transactions admitted by a table-specific throttle",
+	}, metrics.TableLabels)
+	tableBytesWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_table_bytes_wait_seconds_total",
+		Help: "the length of time spent waiting for a table's bytes-per-second limit",
+	}, metrics.TableLabels)
+	tableRowsWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_table_rows_wait_seconds_total",
+		Help: "the length of time spent waiting for a table's rows-per-second limit",
+	}, metrics.TableLabels)
+	tableTransactionsWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "throttle_table_transactions_wait_seconds_total",
+		Help: "the length of time spent waiting for a table's concurrent transaction limit",
+	}, metrics.TableLabels)
+)
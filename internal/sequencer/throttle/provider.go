@@ -0,0 +1,49 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package throttle
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(ProvideThrottle)
+
+// ProvideThrottle is called by Wire.
+func ProvideThrottle(
+	ctx *stopper.Context, cfg *Config, diags *diag.Diagnostics,
+) (*Throttle, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	ret := &Throttle{cfg: cfg}
+
+	// Apply changes to existing buckets, so that any waiting callers
+	// will observe the new limits.
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChanged(ctx, nil, &cfg.liveState().current,
+			func(ctx *stopper.Context, _, next *settings) error {
+				ret.refresh(next)
+				return nil
+			})
+		return err
+	})
+	return ret, diags.Register("throttle", ret)
+}
@@ -0,0 +1,420 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package throttle contains a [sequencer.Shim] that limits the rate at
+// which mutations are written to the target.
+package throttle
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// Throttle is a [sequencer.Shim] that enforces limits on the number of
+// rows, bytes, and concurrent transactions that are written to each
+// target schema and, optionally, to individual tables. The time spent
+// waiting for a limit is reported in metrics, to distinguish throttling
+// from a slow target.
+type Throttle struct {
+	cfg *Config
+
+	mu struct {
+		sync.Mutex
+		schemas ident.SchemaMap[*bucket]
+		tables  ident.TableMap[*bucket]
+	}
+}
+
+var (
+	_ diag.Diagnostic = (*Throttle)(nil)
+	_ sequencer.Shim  = (*Throttle)(nil)
+)
+
+// Diagnostic implements [diag.Diagnostic].
+func (t *Throttle) Diagnostic(_ context.Context) any {
+	settings := t.cfg.settings()
+	ret := struct {
+		Active map[string]int    `json:"active,omitempty"`
+		Schema Limits            `json:"schema"`
+		Tables map[string]Limits `json:"tables,omitempty"`
+	}{
+		Active: make(map[string]int),
+		Schema: settings.schema,
+		Tables: make(map[string]Limits),
+	}
+	for table, limits := range settings.tables.All() {
+		ret.Tables[table.Raw()] = limits
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for schema, b := range t.mu.schemas.All() {
+		ret.Active[schema.Raw()] = b.txns.count()
+	}
+	for table, b := range t.mu.tables.All() {
+		ret.Active[table.Raw()] = b.txns.count()
+	}
+	return &ret
+}
+
+// refresh applies the settings to all existing buckets.
+func (t *Throttle) refresh(settings *settings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range t.mu.schemas.All() {
+		b.update(settings.schema)
+	}
+	for table, b := range t.mu.tables.All() {
+		limits, _ := settings.tables.Get(table)
+		b.update(limits)
+	}
+}
+
+// Wrap implements [sequencer.Shim].
+func (t *Throttle) Wrap(
+	_ *stopper.Context, delegate sequencer.Sequencer,
+) (sequencer.Sequencer, error) {
+	return &throttle{t, delegate}, nil
+}
+
+// schemaBucket returns the bucket for the schema, updated to reflect
+// the current settings.
+func (t *Throttle) schemaBucket(schema ident.Schema, settings *settings) *bucket {
+	t.mu.Lock()
+	ret, ok := t.mu.schemas.Get(schema)
+	if !ok {
+		ret = newBucket(
+			activeTransactions.WithLabelValues(metrics.SchemaValues(schema)...),
+			bytesWaitSeconds.WithLabelValues(metrics.SchemaValues(schema)...),
+			rowsWaitSeconds.WithLabelValues(metrics.SchemaValues(schema)...),
+			transactionsWaitSeconds.WithLabelValues(metrics.SchemaValues(schema)...),
+		)
+		t.mu.schemas.Put(schema, ret)
+	}
+	t.mu.Unlock()
+	ret.update(settings.schema)
+	return ret
+}
+
+// tableBucket returns the bucket for the table, if it has been
+// configured with specific limits.
+func (t *Throttle) tableBucket(table ident.Table, settings *settings) (*bucket, bool) {
+	limits, ok := settings.tables.Get(table)
+	t.mu.Lock()
+	ret, found := t.mu.tables.Get(table)
+	if !found && ok {
+		ret = newBucket(
+			tableActiveTransactions.WithLabelValues(metrics.TableValues(table)...),
+			tableBytesWaitSeconds.WithLabelValues(metrics.TableValues(table)...),
+			tableRowsWaitSeconds.WithLabelValues(metrics.TableValues(table)...),
+			tableTransactionsWaitSeconds.WithLabelValues(metrics.TableValues(table)...),
+		)
+		t.mu.tables.Put(table, ret)
+	}
+	t.mu.Unlock()
+	if ret == nil {
+		return nil, false
+	}
+	// If the limits for the table were removed, ensure that any
+	// waiters are released.
+	ret.update(limits)
+	return ret, ok
+}
+
+type throttle struct {
+	*Throttle
+	delegate sequencer.Sequencer
+}
+
+var _ sequencer.Sequencer = (*throttle)(nil)
+
+// Start implements [sequencer.Sequencer].
+func (t *throttle) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	// Throttle the batches before they are written to the target. The
+	// downstream sequencer will wait for the limits to be satisfied
+	// before opening a target transaction.
+	opts = opts.Copy()
+	opts.Admit = opts.Admit.Then(t.acquire)
+	return t.delegate.Start(ctx, opts)
+}
+
+// Unwrap is an informal protocol to access the delegate.
+func (t *throttle) Unwrap() sequencer.Sequencer {
+	return t.delegate
+}
+
+// usage tallies the rows and bytes for a schema or table.
+type usage struct {
+	bucket *bucket
+	bytes  int
+	rows   int
+}
+
+// acquire blocks until the batch of mutations may be written to the
+// target. The returned function must be called once the batch has
+// been written. It is installed as a [sequencer.Admission].
+func (t *Throttle) acquire(
+	ctx context.Context, muts iter.Seq2[ident.Table, types.Mutation],
+) (func(), error) {
+	settings := t.cfg.settings()
+
+	var schemas ident.SchemaMap[*usage]
+	var tables ident.TableMap[*usage]
+	for table, mut := range muts {
+		size := len(mut.Data) + len(mut.Before)
+		u, ok := schemas.Get(table.Schema())
+		if !ok {
+			u = &usage{bucket: t.schemaBucket(table.Schema(), settings)}
+			schemas.Put(table.Schema(), u)
+		}
+		u.bytes += size
+		u.rows++
+
+		u, ok = tables.Get(table)
+		if !ok {
+			b, limited := t.tableBucket(table, settings)
+			if !limited {
+				b = nil
+			}
+			u = &usage{bucket: b}
+			tables.Put(table, u)
+		}
+		if u.bucket != nil {
+			u.bytes += size
+			u.rows++
+		}
+	}
+
+	// Acquire transaction slots in a consistent order: schemas, then
+	// tables, each sorted by name.
+	var ordered []*usage
+	for _, schema := range sortedKeys(schemas.Keys()) {
+		u, _ := schemas.Get(schema)
+		ordered = append(ordered, u)
+	}
+	for _, table := range sortedKeys(tables.Keys()) {
+		if u, _ := tables.Get(table); u.bucket != nil {
+			ordered = append(ordered, u)
+		}
+	}
+
+	var held []*bucket
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].release()
+		}
+	}
+	for _, u := range ordered {
+		if err := u.bucket.acquire(ctx); err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, u.bucket)
+	}
+	for _, u := range ordered {
+		if err := u.bucket.wait(ctx, u.rows, u.bytes); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+func sortedKeys[K ident.Identifier](keys iter.Seq[K]) []K {
+	return slices.SortedFunc(keys, func(a, b K) int { return ident.Compare(a, b) })
+}
+
+// A bucket enforces the limits for a single schema or table.
+type bucket struct {
+	bytes *rate.Limiter
+	rows  *rate.Limiter
+	txns  *txnLimiter
+
+	// Metrics.
+	active    prometheus.Gauge
+	bytesWait prometheus.Counter
+	rowsWait  prometheus.Counter
+	txnsWait  prometheus.Counter
+
+	mu struct {
+		sync.Mutex
+		limits Limits
+	}
+}
+
+func newBucket(active prometheus.Gauge, bytesWait, rowsWait, txnsWait prometheus.Counter) *bucket {
+	return &bucket{
+		bytes: rate.NewLimiter(rate.Inf, 0),
+		rows:  rate.NewLimiter(rate.Inf, 0),
+		txns:  &txnLimiter{},
+
+		active:    active,
+		bytesWait: bytesWait,
+		rowsWait:  rowsWait,
+		txnsWait:  txnsWait,
+	}
+}
+
+// acquire waits for a transaction slot.
+func (b *bucket) acquire(ctx context.Context) error {
+	start := time.Now()
+	if err := b.txns.acquire(ctx); err != nil {
+		return err
+	}
+	b.txnsWait.Add(time.Since(start).Seconds())
+	b.active.Inc()
+	return nil
+}
+
+// release returns a transaction slot.
+func (b *bucket) release() {
+	b.active.Dec()
+	b.txns.release()
+}
+
+// update applies new limits to the bucket.
+func (b *bucket) update(limits Limits) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.mu.limits == limits {
+		return
+	}
+	b.mu.limits = limits
+	setRate(b.bytes, limits.BytesPerSecond)
+	setRate(b.rows, limits.RowsPerSecond)
+	b.txns.setMax(limits.Transactions)
+}
+
+// wait blocks until the rows and bytes may be written.
+func (b *bucket) wait(ctx context.Context, rows, bytes int) error {
+	start := time.Now()
+	if err := waitN(ctx, b.rows, rows); err != nil {
+		return err
+	}
+	b.rowsWait.Add(time.Since(start).Seconds())
+
+	start = time.Now()
+	if err := waitN(ctx, b.bytes, bytes); err != nil {
+		return err
+	}
+	b.bytesWait.Add(time.Since(start).Seconds())
+	return nil
+}
+
+// setRate configures the limiter to allow up to one second's worth of
+// burst capacity.
+func setRate(lim *rate.Limiter, perSecond int64) {
+	if perSecond <= 0 {
+		lim.SetLimit(rate.Inf)
+		return
+	}
+	lim.SetLimit(rate.Limit(perSecond))
+	lim.SetBurst(int(perSecond))
+}
+
+// waitN consumes n tokens from the limiter. Requests larger than the
+// limiter's burst size are satisfied incrementally.
+func waitN(ctx context.Context, lim *rate.Limiter, n int) error {
+	for n > 0 {
+		if lim.Limit() == rate.Inf {
+			return nil
+		}
+		chunk := min(n, lim.Burst())
+		if err := lim.WaitN(ctx, chunk); err != nil {
+			return errors.WithStack(err)
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// txnLimiter is a semaphore whose capacity may be changed. A maximum
+// of zero is unlimited.
+type txnLimiter struct {
+	mu struct {
+		sync.Mutex
+		active  int
+		changed chan struct{} // Closed when active or max changes.
+		max     int
+	}
+}
+
+func (l *txnLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	for l.mu.max > 0 && l.mu.active >= l.mu.max {
+		ch := l.changedLocked()
+		l.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		}
+		l.mu.Lock()
+	}
+	l.mu.active++
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *txnLimiter) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mu.active
+}
+
+func (l *txnLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mu.active--
+	l.notifyLocked()
+}
+
+func (l *txnLimiter) setMax(max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mu.max = max
+	l.notifyLocked()
+}
+
+func (l *txnLimiter) changedLocked() <-chan struct{} {
+	if l.mu.changed == nil {
+		l.mu.changed = make(chan struct{})
+	}
+	return l.mu.changed
+}
+
+func (l *txnLimiter) notifyLocked() {
+	if l.mu.changed != nil {
+		close(l.mu.changed)
+		l.mu.changed = nil
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package throttle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// blocker is a MultiAcceptor that counts calls and waits for a channel
+// to be closed before returning.
+type blocker struct {
+	entered atomic.Int32
+	wait    chan struct{}
+}
+
+func (b *blocker) accept(ctx context.Context) error {
+	b.entered.Add(1)
+	select {
+	case <-b.wait:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *blocker) AcceptMultiBatch(
+	ctx context.Context, _ *types.MultiBatch, _ *types.AcceptOptions,
+) error {
+	return b.accept(ctx)
+}
+
+func (b *blocker) AcceptTableBatch(
+	ctx context.Context, _ *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	return b.accept(ctx)
+}
+
+func (b *blocker) AcceptTemporalBatch(
+	ctx context.Context, _ *types.TemporalBatch, _ *types.AcceptOptions,
+) error {
+	return b.accept(ctx)
+}
+
+func newFixture(t *testing.T, args ...string) (*Throttle, *pflag.FlagSet) {
+	t.Helper()
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	t.Cleanup(func() { ctx.Stop(0) })
+
+	cfg := &Config{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.Bind(flags)
+	r.NoError(flags.Parse(args))
+
+	thr, err := ProvideThrottle(ctx, cfg, diag.New(ctx))
+	r.NoError(err)
+	return thr, flags
+}
+
+func tableBatch(table ident.Table, rows int) *types.TableBatch {
+	batch := &types.TableBatch{Table: table, Time: hlc.New(1, 0)}
+	for i := range rows {
+		batch.Data = append(batch.Data, types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d}`, i)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, i)),
+			Time: batch.Time,
+		})
+	}
+	return batch
+}
+
+func TestConfig(t *testing.T) {
+	r := require.New(t)
+
+	thr, flags := newFixture(t,
+		"--throttleRowsPerSecond", "100",
+		"--throttleTable", "db.public.tbl=rows:5,bytes:1024,txns:1")
+	settings := thr.cfg.settings()
+	r.Equal(Limits{RowsPerSecond: 100}, settings.schema)
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.New("public")), ident.New("tbl"))
+	found, ok := settings.tables.Get(tbl)
+	r.True(ok)
+	r.Equal(Limits{BytesPerSecond: 1024, RowsPerSecond: 5, Transactions: 1}, found)
+
+	// Changes made after startup, e.g. by api.setOptions(), are
+	// published immediately.
+	r.NoError(flags.Set("throttleRowsPerSecond", "0"))
+	r.True(thr.cfg.settings().schema.IsZero())
+
+	// Setting the table limits replaces the previous value.
+	other := ident.NewTable(tbl.Schema(), ident.New("other"))
+	r.NoError(flags.Set("throttleTable", "db.public.tbl=txns:2; db.public.other=rows:1"))
+	found, ok = thr.cfg.settings().tables.Get(tbl)
+	r.True(ok)
+	r.Equal(Limits{Transactions: 2}, found)
+	found, ok = thr.cfg.settings().tables.Get(other)
+	r.True(ok)
+	r.Equal(Limits{RowsPerSecond: 1}, found)
+	r.Len(thr.cfg.TableLimits, 2)
+
+	r.NoError(flags.Set("throttleTable", "db.public.other=rows:2"))
+	_, ok = thr.cfg.settings().tables.Get(tbl)
+	r.False(ok)
+	r.Len(thr.cfg.TableLimits, 1)
+
+	// An invalid value is rejected and the previous limits remain.
+	r.Error(flags.Set("throttleTable", "db.public.tbl"))
+	found, ok = thr.cfg.settings().tables.Get(other)
+	r.True(ok)
+	r.Equal(Limits{RowsPerSecond: 2}, found)
+	r.Equal([]string{"db.public.other=rows:2"}, thr.cfg.TableLimits)
+
+	// An empty value removes all table limits.
+	r.NoError(flags.Set("throttleTable", ""))
+	r.Equal(0, thr.cfg.settings().tables.Len())
+
+	for _, bad := range []string{
+		"db.public.tbl",
+		"db.public.tbl=rows",
+		"db.public.tbl=rows:-1",
+		"db.public.tbl=widgets:1",
+	} {
+		_, _, err := parseTableLimits(bad)
+		r.Error(err, bad)
+	}
+	r.Error(flags.Set("throttleTransactions", "-1"))
+}
+
+func TestRows(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	thr, _ := newFixture(t, "--throttleRowsPerSecond", "100")
+	delegate := &blocker{wait: make(chan struct{})}
+	close(delegate.wait)
+	acc := sequencer.AdmittingAcceptor(thr.acquire, delegate)
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db")), ident.New("tbl"))
+
+	// The first 100 rows are within the burst capacity. The next 50
+	// must wait for half a second.
+	start := time.Now()
+	r.NoError(acc.AcceptTableBatch(ctx, tableBatch(tbl, 150), &types.AcceptOptions{}))
+	r.GreaterOrEqual(time.Since(start), 400*time.Millisecond)
+
+	// A canceled context should interrupt the wait.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	r.Error(acc.AcceptTableBatch(canceled, tableBatch(tbl, 150), &types.AcceptOptions{}))
+	r.Equal(int32(1), delegate.entered.Load())
+}
+
+func TestTransactions(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	thr, flags := newFixture(t, "--throttleTransactions", "1")
+	delegate := &blocker{wait: make(chan struct{})}
+	acc := sequencer.AdmittingAcceptor(thr.acquire, delegate)
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db")), ident.New("tbl"))
+
+	// Start two transactions; only one should be admitted.
+	for range 2 {
+		ctx.Go(func(ctx *stopper.Context) error {
+			return acc.AcceptTableBatch(ctx, tableBatch(tbl, 1), &types.AcceptOptions{})
+		})
+	}
+	r.Eventually(func() bool { return delegate.entered.Load() == 1 },
+		time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	r.Equal(int32(1), delegate.entered.Load())
+
+	// Raising the limit at runtime admits the waiting transaction.
+	r.NoError(flags.Set("throttleTransactions", "2"))
+	r.Eventually(func() bool { return delegate.entered.Load() == 2 },
+		time.Second, time.Millisecond)
+
+	close(delegate.wait)
+	ctx.Stop(time.Second)
+	r.NoError(ctx.Wait())
+	b, ok := thr.mu.schemas.Get(tbl.Schema())
+	r.True(ok)
+	r.Zero(b.txns.count())
+}
+
+// TestTableMetrics verifies that table-specific limits are reported
+// with table labels, rather than adding to the schema's metrics.
+func TestTableMetrics(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	thr, _ := newFixture(t, "--throttleTable", "db.public.tbl=txns:1")
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.New("public")), ident.New("tbl"))
+
+	release, err := thr.acquire(ctx, tableBatch(tbl, 1).Mutations())
+	r.NoError(err)
+	defer release()
+
+	schemaBucket, ok := thr.mu.schemas.Get(tbl.Schema())
+	r.True(ok)
+	r.True(schemaBucket.active ==
+		activeTransactions.WithLabelValues(metrics.SchemaValues(tbl.Schema())...))
+
+	tableBucket, ok := thr.mu.tables.Get(tbl)
+	r.True(ok)
+	r.True(tableBucket.active ==
+		tableActiveTransactions.WithLabelValues(metrics.TableValues(tbl)...))
+	r.Equal(1, tableBucket.txns.count())
+}
@@ -80,6 +80,11 @@ func ProvideStagingDB(
 		return ident.StagingSchema(config.Schema), nil
 	}
 
+	// The legacy schema name predates PostgreSQL staging support.
+	if pool.Product == types.ProductPostgreSQL {
+		return ident.StagingSchema(StagingSchemaDefault), nil
+	}
+
 	// Check for existence of legacy schema.
 	var exists bool
 	if err := pool.QueryRow(ctx,
@@ -128,11 +133,29 @@ func ProvideStagingPool(
 	}
 	config.Schema = sch
 
+	// PostgreSQL connections cannot refer to objects in other
+	// databases, so the connection string must name the database that
+	// contains the staging schema.
+	if ret.Product == types.ProductPostgreSQL {
+		var current string
+		if err := ret.QueryRow(ctx, "SELECT current_database()").Scan(&current); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		db := config.Schema.Idents(nil)[0]
+		if !ident.Equal(db, ident.New(current)) {
+			return nil, errors.Errorf(
+				"the staging connection string must refer to the %s database; saw %s",
+				db, current)
+		}
+	}
+
 	if config.CreateSchema {
-		if _, err := ret.Exec(ctx, fmt.Sprintf(
-			"CREATE DATABASE IF NOT EXISTS %s",
-			config.Schema.Idents(nil)[0])); err != nil {
-			return nil, err
+		if ret.Product == types.ProductCockroachDB {
+			if _, err := ret.Exec(ctx, fmt.Sprintf(
+				"CREATE DATABASE IF NOT EXISTS %s",
+				config.Schema.Idents(nil)[0])); err != nil {
+				return nil, err
+			}
 		}
 
 		if _, err := ret.Exec(ctx, fmt.Sprintf(
@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
 	"github.com/cockroachdb/replicator/internal/target"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/types"
@@ -43,6 +44,7 @@ var TestSet = wire.NewSet(
 	staging.Set,
 	target.Set,
 
+	ProvideDDLConfig,
 	ProvideDLQConfig,
 	ProvideSchemaWatchConfig,
 	ProvideStageConfig,
@@ -61,6 +63,7 @@ var TestSetBase = wire.NewSet(
 	staging.Set,
 	target.Set,
 
+	ProvideDDLConfig,
 	ProvideDLQConfig,
 	ProvideSchemaWatchConfig,
 	ProvideStageConfig,
@@ -70,6 +73,12 @@ var TestSetBase = wire.NewSet(
 	wire.Struct(new(Fixture), "*"),
 )
 
+// ProvideDDLConfig emits a default configuration.
+func ProvideDDLConfig() (*ddl.Config, error) {
+	cfg := &ddl.Config{}
+	return cfg, cfg.Preflight()
+}
+
 // ProvideDLQConfig emits a default configuration.
 func ProvideDLQConfig() (*dlq.Config, error) {
 	cfg := &dlq.Config{}
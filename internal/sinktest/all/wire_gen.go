@@ -14,6 +14,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/stage"
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
@@ -69,7 +70,7 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	config, err := ProvideDLQConfig()
+	config, err := ProvideDDLConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -87,12 +88,20 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlQs := dlq.ProvideDLQs(config, targetPool, watchers)
+	creator, err := ddl.ProvideCreator(config, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig, err := ProvideDLQConfig()
+	if err != nil {
+		return nil, err
+	}
+	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, diagnostics, dlQs, loader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +129,7 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 		Checkpoints:    checkpoints,
 		Configs:        configs,
 		Diagnostics:    diagnostics,
-		DLQConfig:      config,
+		DLQConfig:      dlqConfig,
 		DLQs:           dlQs,
 		Leases:         typesLeases,
 		Loader:         loader,
@@ -148,7 +157,7 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	config, err := ProvideDLQConfig()
+	config, err := ProvideDDLConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -169,12 +178,20 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlQs := dlq.ProvideDLQs(config, targetPool, watchers)
+	creator, err := ddl.ProvideCreator(config, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig, err := ProvideDLQConfig()
+	if err != nil {
+		return nil, err
+	}
+	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, diagnostics, dlQs, loader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +220,7 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 		Checkpoints:    checkpoints,
 		Configs:        configs,
 		Diagnostics:    diagnostics,
-		DLQConfig:      config,
+		DLQConfig:      dlqConfig,
 		DLQs:           dlQs,
 		Leases:         typesLeases,
 		Loader:         loader,
@@ -264,7 +281,7 @@ func NewFixtureWithRefresh(t testing.TB, d RefreshDelay) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	config, err := ProvideDLQConfig()
+	config, err := ProvideDDLConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -281,12 +298,20 @@ func NewFixtureWithRefresh(t testing.TB, d RefreshDelay) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlQs := dlq.ProvideDLQs(config, targetPool, watchers)
+	creator, err := ddl.ProvideCreator(config, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig, err := ProvideDLQConfig()
+	if err != nil {
+		return nil, err
+	}
+	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, diagnostics, dlQs, loader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +339,7 @@ func NewFixtureWithRefresh(t testing.TB, d RefreshDelay) (*Fixture, error) {
 		Checkpoints:    checkpoints,
 		Configs:        configs,
 		Diagnostics:    diagnostics,
-		DLQConfig:      config,
+		DLQConfig:      dlqConfig,
 		DLQs:           dlQs,
 		Leases:         typesLeases,
 		Loader:         loader,
@@ -236,11 +236,31 @@ func ProvideStagingSchema(
 	ctx *stopper.Context, pool *types.StagingPool,
 ) (ident.StagingSchema, error) {
 	ret, err := provideSchema(ctx, pool, "cdc")
-	return ident.StagingSchema(ret), err
+	if err != nil {
+		return ident.StagingSchema(ret), err
+	}
+
+	// As with the target pool below, PostgreSQL connections must be
+	// re-opened to use the newly-created database.
+	if pool.Product == types.ProductPostgreSQL {
+		db, _ := ret.Split()
+		conn := fmt.Sprintf("%s/%s", pool.ConnectionString, db.Raw())
+		next, err := stdpool.OpenPgxAsStaging(ctx, conn,
+			stdpool.WithConnectionLifetime(time.Minute, 15*time.Second, 5*time.Second),
+			stdpool.WithPoolSize(32),
+			stdpool.WithTransactionTimeout(2*time.Minute), // Aligns with test case timeout.
+		)
+		if err != nil {
+			return ident.StagingSchema{}, err
+		}
+		pool.ConnectionString = conn
+		pool.Pool = next.Pool
+	}
+	return ident.StagingSchema(ret), nil
 }
 
-// ProvideStagingPool opens a connection to the CockroachDB staging
-// cluster under test.
+// ProvideStagingPool opens a connection to the CockroachDB or
+// PostgreSQL staging cluster under test.
 func ProvideStagingPool(ctx *stopper.Context) (*types.StagingPool, error) {
 	tgt := *stagingConn
 	log.Infof("staging connect string: %s", tgt)
@@ -256,6 +276,11 @@ func ProvideStagingPool(ctx *stopper.Context) (*types.StagingPool, error) {
 		return nil, err
 	}
 
+	// PostgreSQL uses LISTEN/NOTIFY instead of changefeeds.
+	if pool.Product == types.ProductPostgreSQL {
+		return pool, nil
+	}
+
 	// Ensure that core changefeeds are enabled on staging. We don't
 	// need enterprise changefeeds here.
 	var enabled bool
@@ -49,6 +49,14 @@ func (c *chaosBucket) Open(ctx *stopper.Context, path string) (io.ReadCloser, er
 	return c.delegate.Open(ctx, path)
 }
 
+// Put implements bucket.Bucket.
+func (c *chaosBucket) Put(ctx *stopper.Context, path string, r io.Reader) error {
+	if rand.Float32() <= c.probTransientError {
+		return bucket.ErrTransient
+	}
+	return c.delegate.Put(ctx, path, r)
+}
+
 // Walk implements bucket.Bucket.
 func (c *chaosBucket) Walk(
 	ctx *stopper.Context,
@@ -0,0 +1,140 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sqlitetest contains a test fixture that applies mutations to
+// a SQLite database. SQLite requires no external services, so tests
+// which use this fixture will run in any environment.
+package sqlitetest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/load"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/cockroachdb/replicator/internal/util/stmtcache"
+	"github.com/pkg/errors"
+)
+
+// Options customize the services in a [Fixture].
+type Options struct {
+	// If set, a [ddl.Creator] will be provided to the acceptor.
+	DDL *ddl.Config
+	// If set, a DLQ table with this name will be created and the
+	// acceptor will write unapplied mutations to it.
+	DLQ ident.Ident
+}
+
+// A Fixture contains the services needed to apply mutations to a
+// SQLite database.
+type Fixture struct {
+	Acceptor    *apply.Acceptor
+	Configs     *applycfg.Configs
+	Context     *stopper.Context
+	Creator     *ddl.Creator // Nil unless Options.DDL is set.
+	Diagnostics *diag.Diagnostics
+	DLQs        types.DLQs  // Nil unless Options.DLQ is set.
+	DLQTable    ident.Table // Empty unless Options.DLQ is set.
+	Loader      *load.Loader
+	Schema      ident.Schema // The primary database of the connection.
+	Statements  *types.TargetStatements
+	TargetPool  *types.TargetPool
+	Watchers    types.Watchers
+}
+
+// NewFixture creates a SQLite database in a temporary directory and
+// executes the statements before constructing the services. The
+// options may be nil. The Fixture's Context will be stopped when the
+// test has finished.
+func NewFixture(t testing.TB, opts *Options, stmts ...string) (*Fixture, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	ctx := stopper.WithContext(context.Background())
+	t.Cleanup(func() {
+		ctx.Stop(10 * time.Millisecond)
+		_ = ctx.Wait()
+	})
+
+	pool, err := stdpool.OpenTarget(ctx, "sqlite://"+filepath.Join(t.TempDir(), "target.db"))
+	if err != nil {
+		return nil, err
+	}
+	ret := &Fixture{
+		Context:     ctx,
+		Diagnostics: diag.New(ctx),
+		Schema:      ident.MustSchema(ident.New("main")),
+		Statements:  &types.TargetStatements{Cache: stmtcache.New[string](ctx, pool.DB, 128)},
+		TargetPool:  pool,
+	}
+
+	if !opts.DLQ.Empty() {
+		ret.DLQTable = ident.NewTable(ret.Schema, opts.DLQ)
+		stmts = append(stmts, fmt.Sprintf(dlq.BasicSchemas[types.ProductSQLite], ret.DLQTable))
+	}
+	for _, stmt := range stmts {
+		if _, err := pool.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.Wrap(err, stmt)
+		}
+	}
+
+	ret.Configs, err = applycfg.ProvideConfigs(ret.Diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	ret.Loader, err = load.ProvideLoader(ret.Statements, pool)
+	if err != nil {
+		return nil, err
+	}
+	ret.Watchers, err = schemawatch.ProvideFactory(ctx, &schemawatch.Config{}, pool,
+		ret.Diagnostics, schemawatch.ProvideBackup(&memo.Memory{}, nil))
+	if err != nil {
+		return nil, err
+	}
+	if opts.DDL != nil {
+		ret.Creator, err = ddl.ProvideCreator(opts.DDL, ret.Diagnostics, pool, ret.Watchers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !opts.DLQ.Empty() {
+		ret.DLQs = dlq.ProvideDLQs(&dlq.Config{TableName: opts.DLQ}, pool, ret.Watchers)
+	}
+	ret.Acceptor, err = apply.ProvideAcceptor(ctx, ret.Statements, ret.Configs, ret.Creator,
+		ret.Diagnostics, ret.DLQs, ret.Loader, pool, ret.Watchers)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Table returns a table within the fixture's schema.
+func (f *Fixture) Table(name string) ident.Table {
+	return ident.NewTable(f.Schema, ident.New(name))
+}
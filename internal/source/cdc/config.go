@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	log "github.com/sirupsen/logrus"
@@ -38,6 +39,7 @@ const (
 // Config adds CDC-specific configuration to the core logical loop.
 type Config struct {
 	ConveyorConfig  conveyor.Config
+	DDLConfig       ddl.Config
 	DLQConfig       dlq.Config
 	SequencerConfig sequencer.Config
 	SchemaWatch     schemawatch.Config
@@ -62,6 +64,7 @@ type Config struct {
 // Bind adds configuration flags to the set.
 func (c *Config) Bind(f *pflag.FlagSet) {
 	c.ConveyorConfig.Bind(f)
+	c.DDLConfig.Bind(f)
 	c.DLQConfig.Bind(f)
 	c.SchemaWatch.Bind(f)
 	c.ScriptConfig.Bind(f)
@@ -83,6 +86,9 @@ func (c *Config) Preflight() error {
 	if err := c.ConveyorConfig.Preflight(); err != nil {
 		return err
 	}
+	if err := c.DDLConfig.Preflight(); err != nil {
+		return err
+	}
 	if err := c.DLQConfig.Preflight(); err != nil {
 		return err
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/types"
@@ -31,6 +32,7 @@ import (
 var Set = wire.NewSet(
 	ProvideHandler,
 	ProvideConveyorConfig,
+	ProvideDDLConfig,
 	ProvideDLQConfig,
 	ProvideSchemaWatchConfig,
 	ProvideScriptConfig,
@@ -43,6 +45,11 @@ func ProvideConveyorConfig(cfg *Config) *conveyor.Config {
 	return &cfg.ConveyorConfig
 }
 
+// ProvideDDLConfig is called by Wire.
+func ProvideDDLConfig(cfg *Config) *ddl.Config {
+	return &cfg.DDLConfig
+}
+
 // ProvideDLQConfig is called by Wire.
 func ProvideDLQConfig(cfg *Config) *dlq.Config {
 	return &cfg.DLQConfig
@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/source/cdc"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/sink"
 	"github.com/cockroachdb/replicator/internal/util/stdserver"
 	"github.com/spf13/pflag"
 )
@@ -33,6 +34,7 @@ type EagerConfig Config
 type Config struct {
 	CDC     cdc.Config
 	HTTP    stdserver.Config
+	Sink    sink.Config            // Deliver to a webhook or object store.
 	Stage   stage.Config           // Staging table configuration.
 	Staging sinkprod.StagingConfig // Staging database configuration.
 	Target  sinkprod.TargetConfig
@@ -42,6 +44,7 @@ type Config struct {
 func (c *Config) Bind(flags *pflag.FlagSet) {
 	c.CDC.Bind(flags)
 	c.HTTP.Bind(flags)
+	c.Sink.Bind(flags)
 	c.Stage.Bind(flags)
 	c.Staging.Bind(flags)
 	c.Target.Bind(flags)
@@ -55,6 +58,9 @@ func (c *Config) Preflight() error {
 	if err := c.HTTP.Preflight(); err != nil {
 		return err
 	}
+	if err := c.Sink.Preflight(); err != nil {
+		return err
+	}
 	if err := c.Stage.Preflight(); err != nil {
 		return err
 	}
@@ -30,6 +30,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/source/cdc"
 	"github.com/cockroachdb/replicator/internal/staging"
 	"github.com/cockroachdb/replicator/internal/target"
+	"github.com/cockroachdb/replicator/internal/target/sink"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/google/wire"
 )
@@ -41,6 +42,7 @@ var completeSet = wire.NewSet(
 	retire.Set,
 	script.Set,
 	sinkprod.Set,
+	sink.Set,
 	staging.Set,
 	switcher.Set,
 	target.Set,
@@ -51,7 +53,7 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 		completeSet,
 		wire.Bind(new(context.Context), new(*stopper.Context)),
 		wire.FieldsOf(new(*Config), "CDC"),
-		wire.FieldsOf(new(*EagerConfig), "Stage", "Staging", "Target"),
+		wire.FieldsOf(new(*EagerConfig), "Sink", "Stage", "Staging", "Target"),
 		wire.Struct(new(Server), "*"),
 	))
 }
@@ -24,6 +24,7 @@ import (
 	"net/http"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/source/cdc"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
@@ -31,6 +32,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/secure"
+	"github.com/cockroachdb/replicator/internal/util/stdlogical"
 	"github.com/cockroachdb/replicator/internal/util/stdserver"
 	"github.com/google/wire"
 )
@@ -50,11 +52,19 @@ var Set = wire.NewSet(
 type Server struct {
 	*stdserver.Server
 	Checkpoints   *checkpoint.Checkpoints
+	Conveyors     *conveyor.Conveyors
 	StagingSchema ident.StagingSchema
 	StagingPool   *types.StagingPool
 	TargetPool    *types.TargetPool
 }
 
+var _ stdlogical.HasAdminHandler = (*Server)(nil)
+
+// GetAdminHandler implements [stdlogical.HasAdminHandler].
+func (s *Server) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return s.Conveyors.Handler(auth)
+}
+
 // ProvideAuthenticator is called by Wire to construct a JWT-based
 // authenticator, or a no-op authenticator if Config.DisableAuth has
 // been set.
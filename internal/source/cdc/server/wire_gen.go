@@ -11,14 +11,17 @@ import (
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/besteffort"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/staging"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/source/cdc"
 	staging2 "github.com/cockroachdb/replicator/internal/staging"
@@ -29,9 +32,14 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target"
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/load"
+	"github.com/cockroachdb/replicator/internal/target/objstore"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/target/sink"
+	"github.com/cockroachdb/replicator/internal/target/webhook"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
@@ -90,49 +98,87 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
+	ddlConfig := cdc.ProvideDDLConfig(cdcConfig)
 	schemawatchConfig := cdc.ProvideSchemaWatchConfig(cdcConfig)
 	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, schemawatchConfig, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
 	}
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
 	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loadLoader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, diagnostics, dlQs, loadLoader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, creator, diagnostics, dlQs, loadLoader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loadLoader, targetStatements, targetPool, watchers)
 	conveyorConfig := cdc.ProvideConveyorConfig(cdcConfig)
 	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
+	compatCompat, err := compat.ProvideCompat(configs, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	delayConfig := conveyor.ProvideDelayConfig(conveyorConfig)
+	delayDelay, err := delay.ProvideDelay(delayConfig, diagnostics, memoMemo, stagingPool)
+	if err != nil {
+		return nil, err
+	}
 	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
 	sequencerConfig := cdc.ProvideSequencerConfig(cdcConfig)
+	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
 	stageConfig := &eagerConfig.Stage
 	stagers := stage.ProvideFactory(stageConfig, stagingPool, stagingSchema, ctx)
-	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
-	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
+	retireRetire, err := retire.ProvideRetire(sequencerConfig, typesLeases, stagingPool, stagers)
 	if err != nil {
 		return nil, err
 	}
-	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	sinkConfig := &eagerConfig.Sink
+	objstoreConfig := sink.ProvideObjStoreConfig(sinkConfig)
+	objstoreAcceptor, err := objstore.ProvideAcceptor(ctx, objstoreConfig)
+	if err != nil {
+		return nil, err
+	}
+	webhookConfig := sink.ProvideWebhookConfig(sinkConfig)
+	webhookAcceptor, err := webhook.ProvideAcceptor(webhookConfig)
+	if err != nil {
+		return nil, err
+	}
+	conveyorSink, err := sink.ProvideSink(sinkConfig, objstoreAcceptor, webhookAcceptor)
 	if err != nil {
 		return nil, err
 	}
-	coreCore := core.ProvideCore(sequencerConfig, typesLeases, schedulerScheduler, targetPool)
+	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
+	coreCore := core.ProvideCore(sequencerConfig, dlQs, typesLeases, schedulerScheduler, targetPool)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
 	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(ctx, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	throttleConfig := conveyor.ProvideThrottleConfig(conveyorConfig)
+	throttleThrottle, err := throttle.ProvideThrottle(ctx, throttleConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	conveyors, err := conveyor.ProvideConveyors(ctx, historyAcceptor, conveyorConfig, checkpoints, compatCompat, creator, delayDelay, memoMemo, sequencer, retireRetire, conveyorSink, stagingPool, switcherSwitcher, throttleThrottle, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +195,7 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	serverServer := &Server{
 		Server:        server,
 		Checkpoints:   checkpoints,
+		Conveyors:     conveyors,
 		StagingSchema: stagingSchema,
 		StagingPool:   stagingPool,
 		TargetPool:    targetPool,
@@ -194,27 +241,42 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 	if err != nil {
 		return nil, nil, err
 	}
-	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
+	ddlConfig := cdc.ProvideDDLConfig(cdcConfig)
 	schemawatchConfig := cdc.ProvideSchemaWatchConfig(cdcConfig)
 	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(context, schemawatchConfig, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, nil, err
 	}
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
 	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, diagnostics, dlQs, loader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
 	if err != nil {
 		return nil, nil, err
 	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loader, targetStatements, targetPool, watchers)
 	conveyorConfig := cdc.ProvideConveyorConfig(cdcConfig)
 	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, nil, err
 	}
+	compatCompat, err := compat.ProvideCompat(configs, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	delayConfig := conveyor.ProvideDelayConfig(conveyorConfig)
+	delayDelay, err := delay.ProvideDelay(delayConfig, diagnostics, memoMemo, stagingPool)
+	if err != nil {
+		return nil, nil, err
+	}
 	scriptConfig := cdc.ProvideScriptConfig(cdcConfig)
 	scriptLoader, err := script.ProvideLoader(context, configs, scriptConfig, diagnostics)
 	if err != nil {
@@ -222,26 +284,49 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 	}
 	sequencer := script2.ProvideSequencer(scriptLoader, targetPool, watchers)
 	sequencerConfig := cdc.ProvideSequencerConfig(cdcConfig)
+	typesLeases, err := leases.ProvideLeases(context, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, nil, err
+	}
 	stageConfig := &config.Stage
 	stagers := stage.ProvideFactory(stageConfig, stagingPool, stagingSchema, context)
-	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
-	schedulerScheduler, err := scheduler.ProvideScheduler(context, sequencerConfig)
+	retireRetire, err := retire.ProvideRetire(sequencerConfig, typesLeases, stagingPool, stagers)
 	if err != nil {
 		return nil, nil, err
 	}
-	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(context, stagingPool, stagingSchema)
+	sinkConfig := &config.Sink
+	objstoreConfig := sink.ProvideObjStoreConfig(sinkConfig)
+	objstoreAcceptor, err := objstore.ProvideAcceptor(context, objstoreConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhookConfig := sink.ProvideWebhookConfig(sinkConfig)
+	webhookAcceptor, err := webhook.ProvideAcceptor(webhookConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	conveyorSink, err := sink.ProvideSink(sinkConfig, objstoreAcceptor, webhookAcceptor)
 	if err != nil {
 		return nil, nil, err
 	}
-	coreCore := core.ProvideCore(sequencerConfig, typesLeases, schedulerScheduler, targetPool)
+	schedulerScheduler, err := scheduler.ProvideScheduler(context, sequencerConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
+	coreCore := core.ProvideCore(sequencerConfig, dlQs, typesLeases, schedulerScheduler, targetPool)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
 	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(context, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	throttleConfig := conveyor.ProvideThrottleConfig(conveyorConfig)
+	throttleThrottle, err := throttle.ProvideThrottle(context, throttleConfig, diagnostics)
+	if err != nil {
+		return nil, nil, err
+	}
+	conveyors, err := conveyor.ProvideConveyors(context, historyAcceptor, conveyorConfig, checkpoints, compatCompat, creator, delayDelay, memoMemo, sequencer, retireRetire, conveyorSink, stagingPool, switcherSwitcher, throttleThrottle, watchers)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -279,7 +364,7 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 // injector.go:
 
 var completeSet = wire.NewSet(
-	Set, cdc.Set, diag.New, retire.Set, script.Set, sinkprod.Set, staging2.Set, switcher.Set, target.Set,
+	Set, cdc.Set, diag.New, retire.Set, script.Set, sinkprod.Set, sink.Set, staging2.Set, switcher.Set, target.Set,
 )
 
 // test_fixture.go:
@@ -55,6 +55,7 @@ func newTestFixture(*all.Fixture, *Config) (*testFixture, error) {
 		diag.New,
 		leases.Set,
 		checkpoint.Set,
+		conveyor.ProvideNoSink,
 		retire.Set,
 		script.Set,
 		switcher.Set,
@@ -10,19 +10,24 @@ import (
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/besteffort"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/staging"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
 	"github.com/cockroachdb/replicator/internal/staging/leases"
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/auth/trust"
@@ -53,41 +58,65 @@ func newTestFixture(fixture *all.Fixture, config *Config) (*testFixture, error)
 	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
 	targetStatements := baseFixture.TargetCache
 	configs := fixture.Configs
+	ddlConfig := ProvideDDLConfig(config)
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
 	dlqConfig := ProvideDLQConfig(config)
 	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, diagnostics, dlQs, loader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(context, targetStatements, configs, creator, diagnostics, dlQs, loader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loader, targetStatements, targetPool, watchers)
 	conveyorConfig := ProvideConveyorConfig(config)
 	stagingSchema := baseFixture.StagingDB
 	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
+	compatCompat, err := compat.ProvideCompat(configs, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	delayConfig := conveyor.ProvideDelayConfig(conveyorConfig)
+	delayDelay, err := delay.ProvideDelay(delayConfig, diagnostics, memo, stagingPool)
+	if err != nil {
+		return nil, err
+	}
 	scriptConfig := ProvideScriptConfig(config)
 	scriptLoader, err := script.ProvideLoader(context, configs, scriptConfig, diagnostics)
 	if err != nil {
 		return nil, err
 	}
 	sequencer := script2.ProvideSequencer(scriptLoader, targetPool, watchers)
-	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
 	typesLeases, err := leases.ProvideLeases(context, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	coreCore := core.ProvideCore(sequencerConfig, typesLeases, schedulerScheduler, targetPool)
+	retireRetire, err := retire.ProvideRetire(sequencerConfig, typesLeases, stagingPool, stagers)
+	if err != nil {
+		return nil, err
+	}
+	sink := conveyor.ProvideNoSink()
+	coreCore := core.ProvideCore(sequencerConfig, dlQs, typesLeases, schedulerScheduler, targetPool)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
 	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(context, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	throttleConfig := conveyor.ProvideThrottleConfig(conveyorConfig)
+	throttleThrottle, err := throttle.ProvideThrottle(context, throttleConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	conveyors, err := conveyor.ProvideConveyors(context, historyAcceptor, conveyorConfig, checkpoints, compatCompat, creator, delayDelay, memo, sequencer, retireRetire, sink, stagingPool, switcherSwitcher, throttleThrottle, watchers)
 	if err != nil {
 		return nil, err
 	}
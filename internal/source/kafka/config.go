@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -48,6 +49,7 @@ type EagerConfig Config
 // replication connection. ServerID and SourceConn are mandatory.
 type Config struct {
 	Conveyor    conveyor.Config
+	DDL         ddl.Config
 	DLQ         dlq.Config
 	SchemaWatch schemawatch.Config
 	Script      script.Config
@@ -91,6 +93,7 @@ type SASLConfig struct {
 // Bind adds flags to the set. It delegates to the embedded Config.Bind.
 func (c *Config) Bind(f *pflag.FlagSet) {
 	c.Conveyor.Bind(f)
+	c.DDL.Bind(f)
 	c.DLQ.Bind(f)
 	c.SchemaWatch.Bind(f)
 	c.Script.Bind(f)
@@ -142,6 +145,9 @@ func (c *Config) Preflight(ctx context.Context) error {
 	if err := c.Conveyor.Preflight(); err != nil {
 		return err
 	}
+	if err := c.DDL.Preflight(); err != nil {
+		return err
+	}
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
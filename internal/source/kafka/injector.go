@@ -41,8 +41,9 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 		wire.Bind(new(context.Context), new(*stopper.Context)),
 		wire.Struct(new(Kafka), "*"),
 		wire.FieldsOf(new(*Config), "Script"),
-		wire.FieldsOf(new(*EagerConfig), "DLQ", "Sequencer", "Stage", "Staging", "Target"),
+		wire.FieldsOf(new(*EagerConfig), "DDL", "DLQ", "Sequencer", "Stage", "Staging", "Target"),
 		Set,
+		conveyor.ProvideNoSink,
 		conveyor.Set,
 		diag.New,
 		retire.Set,
@@ -19,6 +19,10 @@
 package kafka
 
 import (
+	"net/http"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/stdlogical"
 )
@@ -26,13 +30,20 @@ import (
 // Kafka is a kafka logical replication loop.
 type Kafka struct {
 	Conn        *Conn
+	Conveyors   *conveyor.Conveyors
 	Diagnostics *diag.Diagnostics
 }
 
 var (
-	_ stdlogical.HasDiagnostics = (*Kafka)(nil)
+	_ stdlogical.HasAdminHandler = (*Kafka)(nil)
+	_ stdlogical.HasDiagnostics  = (*Kafka)(nil)
 )
 
+// GetAdminHandler implements [stdlogical.HasAdminHandler].
+func (k *Kafka) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return k.Conveyors.Handler(auth)
+}
+
 // GetDiagnostics implements [stdlogical.HasDiagnostics].
 func (k *Kafka) GetDiagnostics() *diag.Diagnostics {
 	return k.Diagnostics
@@ -11,14 +11,17 @@ import (
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/besteffort"
+	"github.com/cockroachdb/replicator/internal/sequencer/compat"
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
+	"github.com/cockroachdb/replicator/internal/sequencer/delay"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/staging"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
+	"github.com/cockroachdb/replicator/internal/sequencer/throttle"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
 	"github.com/cockroachdb/replicator/internal/staging/leases"
@@ -26,7 +29,9 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/stage"
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -72,49 +77,73 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlqConfig := &eagerConfig.DLQ
+	ddlConfig := &eagerConfig.DDL
 	schemawatchConfig := ProvideSchemaWatchConfig(config)
 	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, schemawatchConfig, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
 	}
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig := &eagerConfig.DLQ
 	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loadLoader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, diagnostics, dlQs, loadLoader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, creator, diagnostics, dlQs, loadLoader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loadLoader, targetStatements, targetPool, watchers)
 	conveyorConfig := ProvideConveyorConfig(config)
 	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
+	compatCompat, err := compat.ProvideCompat(configs, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	delayConfig := conveyor.ProvideDelayConfig(conveyorConfig)
+	delayDelay, err := delay.ProvideDelay(delayConfig, diagnostics, memoMemo, stagingPool)
+	if err != nil {
+		return nil, err
+	}
 	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
 	sequencerConfig := &eagerConfig.Sequencer
+	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
 	stageConfig := &eagerConfig.Stage
 	stagers := stage.ProvideFactory(stageConfig, stagingPool, stagingSchema, ctx)
-	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
-	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
+	retireRetire, err := retire.ProvideRetire(sequencerConfig, typesLeases, stagingPool, stagers)
 	if err != nil {
 		return nil, err
 	}
-	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	sink := conveyor.ProvideNoSink()
+	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
 	if err != nil {
 		return nil, err
 	}
-	coreCore := core.ProvideCore(sequencerConfig, typesLeases, schedulerScheduler, targetPool)
+	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
+	coreCore := core.ProvideCore(sequencerConfig, dlQs, typesLeases, schedulerScheduler, targetPool)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
 	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(ctx, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	throttleConfig := conveyor.ProvideThrottleConfig(conveyorConfig)
+	throttleThrottle, err := throttle.ProvideThrottle(ctx, throttleConfig, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+	conveyors, err := conveyor.ProvideConveyors(ctx, historyAcceptor, conveyorConfig, checkpoints, compatCompat, creator, delayDelay, memoMemo, sequencer, retireRetire, sink, stagingPool, switcherSwitcher, throttleThrottle, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +153,7 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 	}
 	kafka := &Kafka{
 		Conn:        conn,
+		Conveyors:   conveyors,
 		Diagnostics: diagnostics,
 	}
 	return kafka, nil
@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -42,6 +43,7 @@ type EagerConfig Config
 // Config contains the configuration necessary for creating a
 // replication connection. ServerID and SourceConn are mandatory.
 type Config struct {
+	DDL         ddl.Config
 	DLQ         dlq.Config
 	SchemaWatch schemawatch.Config
 	Script      script.Config
@@ -69,6 +71,7 @@ type Config struct {
 
 // Bind adds flags to the set. It delegates to the embedded Config.Bind.
 func (c *Config) Bind(f *pflag.FlagSet) {
+	c.DDL.Bind(f)
 	c.DLQ.Bind(f)
 	c.SchemaWatch.Bind(f)
 	c.Script.Bind(f)
@@ -141,6 +144,9 @@ func newClientTLSConfig(
 // error if there are missing options for which a default cannot be
 // provided.
 func (c *Config) Preflight() error {
+	if err := c.DDL.Preflight(); err != nil {
+		return err
+	}
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
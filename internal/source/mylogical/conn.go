@@ -36,6 +36,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -63,6 +64,8 @@ type conn struct {
 	columns *ident.TableMap[[]types.ColData]
 	// The connector configuration.
 	config *Config
+	// Creates missing target tables, if enabled.
+	creator *ddl.Creator
 	// Flavor is one of the mysql.MySQLFlavor or mysql.MariaDBFlavor constants
 	flavor string
 	// Persistent storage for WAL data.
@@ -259,7 +262,7 @@ func (c *conn) accumulateBatch(
 		}
 
 	case *replication.TableMapEvent:
-		if err := c.onRelation(e); err != nil {
+		if err := c.onRelation(ctx, e); err != nil {
 			return batch, err
 		}
 
@@ -484,9 +487,10 @@ func (c *conn) getColNames(table ident.Table) ([][]byte, [][]byte, []uint64, err
 // onRelation updates the source database namespace mappings.
 // Columns names are only available if
 // set global binlog_row_metadata = full;
-func (c *conn) onRelation(msg *replication.TableMapEvent) error {
+func (c *conn) onRelation(ctx context.Context, msg *replication.TableMapEvent) error {
 	targetTbl := ident.NewTable(c.target, ident.New(string(msg.Table)))
 	log.Tracef("Learned %+v", targetTbl)
+	_, known := c.relations[msg.TableID]
 	columnNames, primaryKeys := msg.ColumnName, msg.PrimaryKey
 	columnTypes := make([][]byte, 0, msg.ColumnCount)
 	// In case we need to fetch the metadata directly from the
@@ -526,7 +530,68 @@ func (c *conn) onRelation(msg *replication.TableMapEvent) error {
 		}
 	}
 	c.columns.Put(targetTbl, colData)
-	return nil
+
+	if known || !c.creator.Enabled() {
+		return nil
+	}
+	sourceCols := make([]types.ColData, len(colData))
+	for idx, col := range colData {
+		sourceCols[idx] = col
+		if len(columnTypes) > 0 {
+			sourceCols[idx].Type = string(columnTypes[idx])
+		} else {
+			sourceCols[idx].Type = binlogTypeName(msg.ColumnType[idx], col.IsSigned)
+		}
+	}
+	return c.creator.Ensure(ctx, types.ProductMySQL, targetTbl, sourceCols)
+}
+
+// binlogTypeName returns a MySQL type name for a binlog column type.
+// Text and binary columns are indistinguishable in the binlog, but
+// their values are decoded as strings, so a text type is returned.
+func binlogTypeName(typ byte, signed bool) string {
+	var name string
+	switch typ {
+	case mysql.MYSQL_TYPE_TINY:
+		name = "tinyint"
+	case mysql.MYSQL_TYPE_SHORT, mysql.MYSQL_TYPE_YEAR:
+		name = "smallint"
+	case mysql.MYSQL_TYPE_INT24:
+		name = "mediumint"
+	case mysql.MYSQL_TYPE_LONG:
+		name = "int"
+	case mysql.MYSQL_TYPE_LONGLONG:
+		name = "bigint"
+	case mysql.MYSQL_TYPE_FLOAT:
+		return "float"
+	case mysql.MYSQL_TYPE_DOUBLE:
+		return "double"
+	case mysql.MYSQL_TYPE_DECIMAL, mysql.MYSQL_TYPE_NEWDECIMAL:
+		return "decimal"
+	case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE:
+		return "date"
+	case mysql.MYSQL_TYPE_TIME, mysql.MYSQL_TYPE_TIME2:
+		return "time"
+	case mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_DATETIME2:
+		return "datetime"
+	case mysql.MYSQL_TYPE_TIMESTAMP, mysql.MYSQL_TYPE_TIMESTAMP2:
+		return "timestamp"
+	case mysql.MYSQL_TYPE_JSON:
+		return "json"
+	case mysql.MYSQL_TYPE_BIT:
+		return "bit"
+	case mysql.MYSQL_TYPE_ENUM, mysql.MYSQL_TYPE_SET,
+		mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING, mysql.MYSQL_TYPE_STRING,
+		mysql.MYSQL_TYPE_TINY_BLOB, mysql.MYSQL_TYPE_MEDIUM_BLOB,
+		mysql.MYSQL_TYPE_LONG_BLOB, mysql.MYSQL_TYPE_BLOB:
+		return "longtext"
+	default:
+		return fmt.Sprintf("%d", typ)
+	}
+	if !signed {
+		name += " unsigned"
+	}
+	return name
 }
 
 func isColumnSigned(columnTypes [][]byte, bitmap []byte, columnIndex int) bool {
@@ -329,7 +329,7 @@ func TestOnRelation(t *testing.T) {
 				relations: make(map[uint64]ident.Table),
 				target:    tt.targetSchema,
 			}
-			err := c.onRelation(tt.tableEvent)
+			err := c.onRelation(context.Background(), tt.tableEvent)
 			a.NoError(err)
 			a.Equal(tt.wantTable.Raw(), c.relations[tt.tableEvent.TableID].Raw())
 			cols, ok := c.columns.Get(tt.wantTable)
@@ -24,7 +24,8 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	scriptSeq "github.com/cockroachdb/replicator/internal/sequencer/script"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -45,9 +46,10 @@ var Set = wire.NewSet(
 // the script loader so that flags can be evaluated first.
 func ProvideConn(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc *history.Acceptor,
 	chaos *chaos.Chaos,
 	config *Config,
+	creator *ddl.Creator,
 	imm *immediate.Immediate,
 	memo types.Memo,
 	scriptSeq *scriptSeq.Sequencer,
@@ -98,6 +100,7 @@ func ProvideConn(
 		acceptor:     connAcceptor,
 		columns:      &ident.TableMap[[]types.ColData]{},
 		config:       config,
+		creator:      creator,
 		memo:         memo,
 		flavor:       flavor,
 		relations:    make(map[uint64]ident.Table),
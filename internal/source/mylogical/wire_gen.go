@@ -18,7 +18,9 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/stage"
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -67,22 +69,28 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 	if err != nil {
 		return nil, err
 	}
-	dlqConfig := &eagerConfig.DLQ
+	ddlConfig := &eagerConfig.DDL
 	schemawatchConfig := ProvideSchemaWatchConfig(config)
 	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, schemawatchConfig, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
 	}
+	creator, err := ddl.ProvideCreator(ddlConfig, diagnostics, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	dlqConfig := &eagerConfig.DLQ
 	dlQs := dlq.ProvideDLQs(dlqConfig, targetPool, watchers)
 	loadLoader, err := load.ProvideLoader(targetStatements, targetPool)
 	if err != nil {
 		return nil, err
 	}
-	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, diagnostics, dlQs, loadLoader, targetPool, watchers)
+	acceptor, err := apply.ProvideAcceptor(ctx, targetStatements, configs, creator, diagnostics, dlQs, loadLoader, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
+	historyAcceptor := history.ProvideAcceptor(acceptor, configs, loadLoader, targetStatements, targetPool, watchers)
 	sequencerConfig := &eagerConfig.Sequencer
 	chaosChaos := &chaos.Chaos{
 		Config: sequencerConfig,
@@ -94,7 +102,7 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
-	mylogicalConn, err := ProvideConn(ctx, acceptor, chaosChaos, config, immediateImmediate, memoMemo, sequencer, stagingPool, targetPool, watchers)
+	mylogicalConn, err := ProvideConn(ctx, historyAcceptor, chaosChaos, config, creator, immediateImmediate, memoMemo, sequencer, stagingPool, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
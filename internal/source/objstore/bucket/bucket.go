@@ -46,10 +46,15 @@ type WalkOptions struct {
 	StartAfter string // Iterators will returns entries lexically after this.
 }
 
-// Bucket provides read access to an object storage bucket.
+// Bucket provides access to an object storage bucket.
 type Bucket interface {
 	// Open returns a reader for the object at the named path.
 	Open(ctx *stopper.Context, path string) (io.ReadCloser, error)
+	// Put stores the contents of the reader at the named path. The
+	// write must be atomic: concurrent readers will observe either
+	// the previous object, if any, or the complete new object.
+	// Providers that do not support writes return ErrReadOnly.
+	Put(ctx *stopper.Context, path string, r io.Reader) error
 	// Walk calls f for each entry in the given prefix. The argument
 	// to f is the full object name including the prefix of the
 	// inspected directory. Entries are passed to function in sorted
@@ -24,6 +24,9 @@ var (
 	ErrNoSuchBucket = errors.New("bucket not found")
 	// ErrNoSuchKey must be returned if there is no object with the given key.
 	ErrNoSuchKey = errors.New("key not found")
+	// ErrReadOnly must be returned by Put if the bucket does not
+	// support writes.
+	ErrReadOnly = errors.New("bucket is read-only")
 	// ErrTransient represent an error that can be retried.
 	ErrTransient = errors.New("the operation causing the error may be retried")
 	// ErrSkipAll signal that Walk can stop process the follow entries.
@@ -34,6 +34,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -43,8 +44,6 @@ import (
 	"github.com/spf13/pflag"
 )
 
-const defaultEndpoint = "https://s3.amazonaws.com"
-
 var (
 	defaultBufferSize           = bufio.MaxScanTokenSize // 64K
 	defaultFetchDelay           = 100 * time.Millisecond
@@ -134,6 +133,7 @@ var Providers = map[string]Provider{
 // connection to an object store.
 type Config struct {
 	Conveyor    conveyor.Config
+	DDL         ddl.Config
 	DLQ         dlq.Config
 	SchemaWatch schemawatch.Config
 	Script      script.Config
@@ -167,6 +167,7 @@ type Config struct {
 // Bind adds flags to the set. It delegates to the embedded Config.Bind.
 func (c *Config) Bind(f *pflag.FlagSet) {
 	c.Conveyor.Bind(f)
+	c.DDL.Bind(f)
 	c.DLQ.Bind(f)
 	c.SchemaWatch.Bind(f)
 	c.Script.Bind(f)
@@ -209,6 +210,9 @@ cluster_logical_timestamp().`)
 // error if there are missing options for which a default cannot be
 // provided.
 func (c *Config) Preflight(ctx context.Context) error {
+	if err := c.DDL.Preflight(); err != nil {
+		return err
+	}
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
@@ -260,49 +264,18 @@ func (c *Config) preflight() error {
 		c.local = os.DirFS(u.Path)
 		c.identifier = fmt.Sprintf("objstore:file///%s", u.Path)
 	case S3Storage:
-		if u.Host != "" {
-			c.bucketName = u.Host
-			// Extract provider configuration from the storage URL
-			c.prefix = strings.TrimPrefix(u.Path, "/")
-		} else {
-			return errors.New("missing bucket name in URL. Must be s3://bucket/folder")
-		}
-		c.identifier = fmt.Sprintf("objstore:%s//%s/%s", u.Scheme, u.Host, u.Path)
-		params := u.Query()
-		endpointURL := paramValue(params, "AWS_ENDPOINT")
-		// The minio API require a endpoint to be set.
-		// We will be using AWS S3 as the default.
-		if endpointURL == "" {
-			endpointURL = defaultEndpoint
-		}
-		endpointParsed, err := url.Parse(endpointURL)
+		c.s3, c.prefix, err = s3.FromURL(u)
 		if err != nil {
 			return err
 		}
+		c.bucketName = c.s3.Bucket
+		c.identifier = fmt.Sprintf("objstore:%s//%s/%s", u.Scheme, u.Host, u.Path)
 		// if the mode is immediate, we need to process files sequentially.
 		if c.Conveyor.Immediate {
 			c.Workers = 1
 		}
-		c.s3 = &s3.Config{
-			AccessKey:    paramValue(params, "AWS_ACCESS_KEY_ID"),
-			Bucket:       c.bucketName,
-			Endpoint:     endpointParsed.Host,
-			Insecure:     endpointParsed.Scheme == "http",
-			SecretKey:    paramValue(params, "AWS_SECRET_ACCESS_KEY"),
-			SessionToken: paramValue(params, "AWS_SESSION_TOKEN"),
-		}
 	default:
 		return errors.Errorf("unknown scheme %s", u.Scheme)
 	}
 	return nil
 }
-
-// paramValue gets the value for the specified parameter from the URL.
-// If not present in the URL, it retrieves a value from the environment.
-func paramValue(params url.Values, key string) string {
-	value := params.Get(key)
-	if value != "" {
-		return value
-	}
-	return os.Getenv(key)
-}
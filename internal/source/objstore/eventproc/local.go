@@ -17,6 +17,7 @@
 package eventproc
 
 import (
+	"compress/gzip"
 	"context"
 	"io"
 
@@ -72,8 +73,18 @@ func (c *localProcessor) Process(
 	}
 	defer buff.Close()
 
+	var content io.Reader = buff
+	if isGzipped(path) {
+		gz, err := gzip.NewReader(buff)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decompress %s", path)
+		}
+		defer gz.Close()
+		content = gz
+	}
+
 	// Parse the mutations inside the file into a Batch.
-	batch, err := c.parser.Parse(table, filteredReader(filters...), buff)
+	batch, err := c.parser.Parse(table, filteredReader(filters...), content)
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse %s", path)
 	}
@@ -18,6 +18,8 @@ package eventproc
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"path/filepath"
@@ -83,6 +85,7 @@ var _ types.MultiAcceptor = &erroringAcceptor{}
 
 type entry struct {
 	content string
+	gzip    bool // Compress the content.
 	path    string
 }
 
@@ -107,6 +110,12 @@ func TestLocalProcess(t *testing.T) {
 			{"after": {"p": 4, "v": "1"}, "before": null, "key": [4], "updated": "3.0"}`,
 			path: `202405031553360274750000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson`,
 		},
+		{ // compressed
+			content: `
+			{"after": {"p": 1, "v": "1"}, "before": null, "key": [1], "updated": "4.0"}`,
+			gzip: true,
+			path: `202405031553360274790000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson.gz`,
+		},
 		{ // invalid path
 			content: `1,2,3`,
 			path:    `202405031553360274760000000000000-08779498965a12e2-1-2-00000000-mytable-2.cvs`,
@@ -158,6 +167,13 @@ func TestLocalProcess(t *testing.T) {
 			wantTimestamps: []hlc.Time{hlc.New(2, 0), hlc.New(3, 0)},
 			wantTable:      ident.NewTable(ident.MustSchema(ident.Public), ident.New("mytable")),
 		},
+		{
+			name:           "gzipped",
+			acceptor:       newAcceptor(nil),
+			path:           `202405031553360274790000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson.gz`,
+			wantTimestamps: []hlc.Time{hlc.New(4, 0)},
+			wantTable:      ident.NewTable(ident.MustSchema(ident.Public), ident.New("mytable")),
+		},
 		{
 			name:     "acceptor error",
 			acceptor: newAcceptor(ErrMock),
@@ -218,8 +234,20 @@ func TestLocalProcess(t *testing.T) {
 
 func addContent(fs fstest.MapFS, dir string, entries []*entry) error {
 	for _, entry := range entries {
+		data := []byte(entry.content)
+		if entry.gzip {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			if _, err := gz.Write(data); err != nil {
+				return err
+			}
+			if err := gz.Close(); err != nil {
+				return err
+			}
+			data = buf.Bytes()
+		}
 		fs[filepath.Join(dir, entry.path)] = &fstest.MapFile{
-			Data:    []byte(entry.content),
+			Data:    data,
 			Mode:    0777,
 			ModTime: time.Now(),
 		}
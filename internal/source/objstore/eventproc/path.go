@@ -19,16 +19,25 @@ package eventproc
 import (
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/pkg/errors"
 )
 
 var (
-	ndjsonRegex = regexp.MustCompile(`^(?P<prelude>([^-]+-){5})(?P<topic>.+)-(?P<schema_id>[^-]+).ndjson$`)
+	ndjsonRegex = regexp.MustCompile(`^(?P<prelude>([^-]+-){5})(?P<topic>.+)-(?P<schema_id>[^-]+).ndjson(\.gz)?$`)
 	ndjsonTopic = ndjsonRegex.SubexpIndex("topic")
 )
 
+// gzipSuffix is added to the names of files that are compressed.
+const gzipSuffix = ".gz"
+
+// isGzipped returns true if the file has been compressed.
+func isGzipped(path string) bool {
+	return strings.HasSuffix(path, gzipSuffix)
+}
+
 // getTableName extracts the table name from a changefeed cloud storage ndjson file.
 func getTableName(path string) (ident.Ident, error) {
 	res := ndjsonRegex.FindStringSubmatch(filepath.Base(path))
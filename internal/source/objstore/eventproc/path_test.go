@@ -35,6 +35,11 @@ func TestGetTableName(t *testing.T) {
 			path: "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson",
 			want: ident.New("mytable"),
 		},
+		{
+			name: "gzipped",
+			path: "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson.gz",
+			want: ident.New("mytable"),
+		},
 		{
 			name:    "invalid suffix",
 			path:    "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.csv",
@@ -41,8 +41,9 @@ func Start(ctx *stopper.Context, config *Config) (*Objstore, error) {
 		wire.Struct(new(Objstore), "*"),
 		wire.FieldsOf(new(*Config), "Script"),
 		wire.FieldsOf(new(*EagerConfig),
-			"Conveyor", "DLQ", "Sequencer", "Stage", "Staging", "Target"),
+			"Conveyor", "DDL", "DLQ", "Sequencer", "Stage", "Staging", "Target"),
 		Set,
+		conveyor.ProvideNoSink,
 		conveyor.Set,
 		diag.New,
 		retire.Set,
@@ -19,6 +19,10 @@
 package objstore
 
 import (
+	"net/http"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/stdlogical"
 )
@@ -26,13 +30,20 @@ import (
 // Objstore is a logical replication loop that uses cloud storage.
 type Objstore struct {
 	Conn        *Conn
+	Conveyors   *conveyor.Conveyors
 	Diagnostics *diag.Diagnostics
 }
 
 var (
-	_ stdlogical.HasDiagnostics = (*Objstore)(nil)
+	_ stdlogical.HasAdminHandler = (*Objstore)(nil)
+	_ stdlogical.HasDiagnostics  = (*Objstore)(nil)
 )
 
+// GetAdminHandler implements [stdlogical.HasAdminHandler].
+func (k *Objstore) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return k.Conveyors.Handler(auth)
+}
+
 // GetDiagnostics implements [stdlogical.HasDiagnostics].
 func (k *Objstore) GetDiagnostics() *diag.Diagnostics {
 	return k.Diagnostics
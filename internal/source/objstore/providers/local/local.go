@@ -21,6 +21,7 @@ import (
 	"errors"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -28,15 +29,35 @@ import (
 	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 )
 
-// New creates a bucket reader for a local filesystem.
+// tempPrefix identifies in-flight writes. Walk ignores these files.
+const tempPrefix = ".put-"
+
+// New creates a read-only bucket for a local filesystem.
 func New(fs fs.FS) (bucket.Bucket, error) {
 	return &localBucket{
 		filesystem: fs,
 	}, nil
 }
 
+// NewDir creates a bucket that can read and write files within the
+// given directory.
+func NewDir(dir string) (bucket.Bucket, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dir + " is not a directory")
+	}
+	return &localBucket{
+		dir:        dir,
+		filesystem: os.DirFS(dir),
+	}, nil
+}
+
 // localBucket is a bucket backed by a filesystem.
 type localBucket struct {
+	dir        string // If set, the bucket is writable.
 	filesystem fs.FS
 }
 
@@ -59,6 +80,9 @@ func (b *localBucket) Walk(
 			if options.Limit > 0 && count >= options.Limit {
 				return fs.SkipAll
 			}
+			if d != nil && strings.HasPrefix(d.Name(), tempPrefix) {
+				return nil
+			}
 			if d != nil && d.IsDir() {
 				if options.Recursive {
 					return nil
@@ -83,6 +107,40 @@ func (b *localBucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, er
 	return r, err
 }
 
+// Put implements bucket.Bucket. The contents are written to a
+// temporary file, which is then renamed into place.
+func (b *localBucket) Put(_ *stopper.Context, file string, r io.Reader) error {
+	if b.dir == "" {
+		return bucket.ErrReadOnly
+	}
+	dest := filepath.Join(b.dir, filepath.FromSlash(filepath.Clean(file)))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	// Cleanup is a no-op once the file has been renamed.
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
 // fs returns the underlying filesystem.
 func (b *localBucket) fs() fs.FS {
 	return b.filesystem
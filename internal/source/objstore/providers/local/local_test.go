@@ -18,11 +18,15 @@ package local
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"testing/fstest"
 	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/storetest"
 	"github.com/stretchr/testify/require"
 )
@@ -46,6 +50,22 @@ func (m *mockFS) Store(ctx context.Context, name string, buf []byte) error {
 	return nil
 }
 
+// dirWriter stores files in a directory.
+type dirWriter struct {
+	dir string
+}
+
+var _ storetest.Writer = &dirWriter{}
+
+// Store implements validate.Writer.
+func (w *dirWriter) Store(ctx context.Context, name string, buf []byte) error {
+	name = filepath.Join(w.dir, name)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf, 0644)
+}
+
 // TestOpen verifies that we can get an object content from the store.
 func TestOpen(t *testing.T) {
 	suite(t).Open(t)
@@ -56,6 +76,29 @@ func TestOverwrite(t *testing.T) {
 	suite(t).Overwrite(t)
 }
 
+// TestPut verifies that we can write objects to a directory.
+func TestPut(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	b, err := NewDir(dir)
+	r.NoError(err)
+	suite := &storetest.Suite{
+		Reader: b,
+		Writer: &dirWriter{dir: dir},
+	}
+	suite.Put(t)
+	suite.Open(t)
+}
+
+// TestReadOnly verifies that a bucket backed by an fs.FS rejects writes.
+func TestReadOnly(t *testing.T) {
+	r := require.New(t)
+	b, err := New(make(fstest.MapFS))
+	r.NoError(err)
+	err = b.Put(stopper.Background(), "test.txt", strings.NewReader("test"))
+	r.ErrorIs(err, bucket.ErrReadOnly)
+}
+
 // TestWalk verifies that we can list objects in the store.
 func TestWalk(t *testing.T) {
 	suite(t).Walk(t)
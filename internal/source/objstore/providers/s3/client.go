@@ -47,3 +47,15 @@ func (c *client) ListObjects(
 ) <-chan minio.ObjectInfo {
 	return c.ref.ListObjects(ctx, bucketName, opts)
 }
+
+// PutObject implements s3Access.
+func (c *client) PutObject(
+	ctx context.Context,
+	bucketName string,
+	objectName string,
+	reader io.Reader,
+	objectSize int64,
+	opts minio.PutObjectOptions,
+) (minio.UploadInfo, error) {
+	return c.ref.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
@@ -72,6 +72,8 @@ type s3Access interface {
 	GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (io.ReadCloser, error)
 	// ListObjects scans the entries in the bucket.
 	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
+	// PutObject stores the content of the reader in the named object.
+	PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
 }
 
 // New returns a bucket reader backed by a S3 provider.
@@ -183,3 +185,22 @@ func (b *s3Bucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, error
 	}
 	return r, err
 }
+
+// Put implements bucket.Bucket. S3 objects become visible only once
+// the upload has completed, so the write is atomic.
+func (b *s3Bucket) Put(ctx *stopper.Context, file string, r io.Reader) error {
+	file = strings.TrimPrefix(file, b.bucket+Delimiter)
+	// A size of -1 allows the SDK to stream the contents as a
+	// multipart upload.
+	_, err := b.client.PutObject(ctx, b.bucket, file, r, -1, minio.PutObjectOptions{})
+	if err != nil {
+		resp := minio.ToErrorResponse(err)
+		if resp.StatusCode == http.StatusNotFound {
+			return errors.Join(bucket.ErrNoSuchBucket, err)
+		}
+		if slices.Contains(RetriableErrors, resp.StatusCode) {
+			return errors.Join(bucket.ErrTransient, err)
+		}
+	}
+	return err
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
@@ -28,6 +29,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/storetest"
 	minio "github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
 )
 
 // mockS3 is in memory S3 bucket.
@@ -90,12 +92,63 @@ func (m *mockS3) ListObjects(
 	return ch
 }
 
+// PutObject implements s3Access.
+func (m *mockS3) PutObject(
+	ctx context.Context,
+	bucketName string,
+	objectName string,
+	reader io.Reader,
+	objectSize int64,
+	opts minio.PutObjectOptions,
+) (minio.UploadInfo, error) {
+	if bucketName != m.bucketName {
+		return minio.UploadInfo{}, bucket.ErrNoSuchBucket
+	}
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	m.files.Store(objectName, buf)
+	return minio.UploadInfo{Key: objectName, Size: int64(len(buf))}, nil
+}
+
 // Store implements validate.Writer.
 func (m *mockS3) Store(ctx context.Context, name string, buf []byte) error {
 	m.files.Store(name, buf)
 	return nil
 }
 
+func TestFromURL(t *testing.T) {
+	r := require.New(t)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "from-env")
+
+	u, err := url.Parse("s3://bucket/path/to?AWS_ACCESS_KEY_ID=key&AWS_ENDPOINT=http://localhost:9000")
+	r.NoError(err)
+	cfg, prefix, err := FromURL(u)
+	r.NoError(err)
+	r.Equal("path/to", prefix)
+	r.Equal(&Config{
+		AccessKey: "key",
+		Bucket:    "bucket",
+		Endpoint:  "localhost:9000",
+		Insecure:  true,
+		SecretKey: "from-env",
+	}, cfg)
+
+	u, err = url.Parse("s3://bucket")
+	r.NoError(err)
+	cfg, prefix, err = FromURL(u)
+	r.NoError(err)
+	r.Empty(prefix)
+	r.Equal("s3.amazonaws.com", cfg.Endpoint)
+	r.False(cfg.Insecure)
+
+	u, err = url.Parse("s3:///path")
+	r.NoError(err)
+	_, _, err = FromURL(u)
+	r.ErrorContains(err, "bucket")
+}
+
 func TestOpen(t *testing.T) {
 	suite().Open(t)
 }
@@ -104,6 +157,10 @@ func TestOverwrite(t *testing.T) {
 	suite().Overwrite(t)
 }
 
+func TestPut(t *testing.T) {
+	suite().Put(t)
+}
+
 func TestWalk(t *testing.T) {
 	suite().Walk(t)
 }
@@ -0,0 +1,65 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package s3
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEndpoint is used when a URL does not specify an AWS_ENDPOINT.
+// The minio API requires an endpoint to be set.
+const DefaultEndpoint = "https://s3.amazonaws.com"
+
+// FromURL extracts the connection parameters from a URL of the form
+// s3://bucket/prefix?AWS_ENDPOINT=...&AWS_ACCESS_KEY_ID=... and returns
+// the path within the bucket. Parameters that are not present in the
+// URL are read from the environment.
+func FromURL(u *url.URL) (*Config, string, error) {
+	if u.Host == "" {
+		return nil, "", errors.New("missing bucket name in URL. Must be s3://bucket/folder")
+	}
+	params := u.Query()
+	endpointURL := ParamValue(params, "AWS_ENDPOINT")
+	if endpointURL == "" {
+		endpointURL = DefaultEndpoint
+	}
+	endpoint, err := url.Parse(endpointURL)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "could not parse AWS_ENDPOINT %q", endpointURL)
+	}
+	return &Config{
+		AccessKey:    ParamValue(params, "AWS_ACCESS_KEY_ID"),
+		Bucket:       u.Host,
+		Endpoint:     endpoint.Host,
+		Insecure:     endpoint.Scheme == "http",
+		SecretKey:    ParamValue(params, "AWS_SECRET_ACCESS_KEY"),
+		SessionToken: ParamValue(params, "AWS_SESSION_TOKEN"),
+	}, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// ParamValue gets the value for the specified parameter from the URL.
+// If not present in the URL, it retrieves a value from the environment.
+func ParamValue(params url.Values, key string) string {
+	if value := params.Get(key); value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
@@ -92,6 +92,31 @@ func (v *Suite) Overwrite(t *testing.T) {
 	}
 }
 
+// Put validates bucket.Bucket.Put
+func (v *Suite) Put(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+	r := require.New(t)
+	a := assert.New(t)
+	for _, path := range []string{"put.txt", "000/put.txt", "000/001/put.txt"} {
+		for _, s := range []string{"v0", "v1"} {
+			r.NoError(v.Reader.Put(stop, path, strings.NewReader(s)))
+			got, err := v.read(stop, path)
+			r.NoError(err)
+			a.Equal(s, got)
+		}
+	}
+	// Only the objects that we wrote should be visible.
+	var res []string
+	r.NoError(v.Reader.Walk(stop, "", &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, s string) error {
+			res = append(res, s)
+			return nil
+		}))
+	a.Equal([]string{"000/001/put.txt", "000/put.txt", "put.txt"}, res)
+}
+
 // Walk validates bucket.Bucket.Walk
 func (v *Suite) Walk(t *testing.T) {
 	r := require.New(t)
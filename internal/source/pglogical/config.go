@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/stage"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -43,6 +44,7 @@ type EagerConfig Config
 // replication connection. All field, other than TestControls, are
 // mandatory unless explicitly indicated.
 type Config struct {
+	DDL         ddl.Config
 	DLQ         dlq.Config
 	SchemaWatch schemawatch.Config
 	Script      script.Config
@@ -66,6 +68,7 @@ type Config struct {
 
 // Bind adds flags to the set.
 func (c *Config) Bind(f *pflag.FlagSet) {
+	c.DDL.Bind(f)
 	c.DLQ.Bind(f)
 	c.SchemaWatch.Bind(f)
 	c.Script.Bind(f)
@@ -101,6 +104,9 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 // error if there are missing options for which a default cannot be
 // provided.
 func (c *Config) Preflight() error {
+	if err := c.DDL.Preflight(); err != nil {
+		return err
+	}
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
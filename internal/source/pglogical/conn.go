@@ -30,6 +30,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -37,6 +38,7 @@ import (
 	"github.com/jackc/pglogrepl"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -49,6 +51,8 @@ type Conn struct {
 	acceptor types.TemporalAcceptor
 	// Columns, as ordered by the source database.
 	columns *ident.TableMap[[]types.ColData]
+	// Creates missing target tables, if enabled.
+	creator *ddl.Creator
 	// Persistent storage for WAL data.
 	memo types.Memo
 	// Ensure the timestamps we generate always march forward.
@@ -130,7 +134,9 @@ func (c *Conn) accumulateBatch(
 		// The replication protocol says that we'll see these
 		// descriptors before any use of the relation id in the
 		// stream. We'll map the int value to our table identifiers.
-		c.onRelation(msg)
+		if err := c.onRelation(ctx, msg); err != nil {
+			return nil, err
+		}
 		return batch, nil
 
 	case *pglogrepl.BeginMessage:
@@ -418,7 +424,7 @@ func (c *Conn) onDataTuple(
 }
 
 // learn updates the source database namespace mappings.
-func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
+func (c *Conn) onRelation(ctx context.Context, msg *pglogrepl.RelationMessage) error {
 	// The replication protocol says that we'll see these
 	// descriptors before any use of the relation id in the
 	// stream. We'll map the int value to our table identifiers.
@@ -442,6 +448,21 @@ func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
 		"RelationID": msg.RelationID,
 		"Table":      tbl,
 	}).Trace("learned relation")
+
+	if !c.creator.Enabled() {
+		return nil
+	}
+	// The relation message only contains type OIDs and modifiers, so
+	// we'll look up the names of the built-in types.
+	typeMap := pgtype.NewMap()
+	sourceCols := make([]types.ColData, len(colNames))
+	for idx, col := range msg.Columns {
+		sourceCols[idx] = colNames[idx]
+		if typ, ok := typeMap.TypeForOID(col.DataType); ok {
+			sourceCols[idx].Type = ddl.PostgresTypeName(typ.Name, col.TypeModifier)
+		}
+	}
+	return c.creator.Ensure(ctx, types.ProductPostgreSQL, tbl, sourceCols)
 }
 
 // persistWALOffset loads an existing value from memo into walOffset. It
@@ -24,7 +24,8 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -49,9 +50,10 @@ var Set = wire.NewSet(
 // evaluated first.
 func ProvideConn(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc *history.Acceptor,
 	chaos *chaos.Chaos,
 	config *Config,
+	creator *ddl.Creator,
 	imm *immediate.Immediate,
 	memo types.Memo,
 	scriptSeq *script.Sequencer,
@@ -128,6 +130,7 @@ func ProvideConn(
 	conn := &Conn{
 		acceptor:        connAcceptor,
 		columns:         &ident.TableMap[[]types.ColData]{},
+		creator:         creator,
 		memo:            memo,
 		publicationName: config.Publication,
 		relations:       make(map[uint32]ident.Table),
@@ -36,6 +36,13 @@ import (
 type Checkpoints struct {
 	pool      *types.StagingPool
 	metaTable ident.Table
+
+	// Product-specific SQL templates.
+	templates struct {
+		advance    string
+		ensure     string
+		rewindMark string
+	}
 }
 
 // Start a background goroutine to update the provided bounds variable.
@@ -103,11 +110,12 @@ func (r *Checkpoints) newGroup(
 	}
 	// This query may indeed require a full table scan.
 	ret.sql.refresh = fmt.Sprintf(refreshTemplate, r.metaTable, limit)
+	ret.sql.listen = fmt.Sprintf(listenTemplate, ident.New(notifyChannel))
 	ret.sql.stream = fmt.Sprintf(streamTemplate, r.metaTable)
 
 	hinted := r.pool.HintNoFTS(r.metaTable)
-	ret.sql.advance = fmt.Sprintf(advanceTemplate, hinted)
-	ret.sql.ensure = fmt.Sprintf(ensureTemplate, hinted)
+	ret.sql.advance = fmt.Sprintf(r.templates.advance, hinted)
+	ret.sql.ensure = fmt.Sprintf(r.templates.ensure, hinted)
 	ret.sql.commit = fmt.Sprintf(commitTemplate, hinted)
 	return ret
 }
@@ -158,6 +158,73 @@ func TestResolved(t *testing.T) {
 	})
 }
 
+func TestRewind(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := base.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	r.NoError(err)
+
+	name := ident.New("rewind")
+	bounds := &notify.Var[hlc.Range]{}
+	g, err := chk.Start(ctx, &types.TableGroup{Name: name}, bounds)
+	r.NoError(err)
+
+	parts := []ident.Ident{ident.New("p1"), ident.New("p2")}
+	for _, part := range parts {
+		for i := int64(1); i <= 10; i++ {
+			r.NoError(g.Advance(ctx, part, hlc.New(i*10, 0)))
+		}
+	}
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(80, 0))))
+
+	names, err := chk.Groups(ctx)
+	r.NoError(err)
+	r.Contains(names, name)
+
+	info, err := chk.Inspect(ctx, name)
+	r.NoError(err)
+	r.Equal(hlc.New(80, 0), info.Range.Min())
+	r.Equal(hlc.New(100, 0), info.Range.Max())
+	r.Len(info.Partitions, 2)
+	for _, part := range info.Partitions {
+		r.Equal(hlc.New(80, 0), part.Applied)
+		r.Equal(hlc.New(100, 0), part.Latest)
+		r.Equal(2, part.Pending)
+	}
+
+	// Cannot rewind forward.
+	r.ErrorContains(chk.Rewind(ctx, fixture.StagingPool, name, hlc.New(90, 0)), "cannot be rewound")
+
+	// Rewind to a time between checkpoints.
+	r.NoError(chk.Rewind(ctx, fixture.StagingPool, name, hlc.New(35, 0)))
+	info, err = chk.Inspect(ctx, name)
+	r.NoError(err)
+	r.Equal(hlc.New(35, 0), info.Range.Min())
+	r.Equal(hlc.New(100, 0), info.Range.Max())
+	for _, part := range info.Partitions {
+		r.Equal(hlc.New(35, 0), part.Applied)
+		r.Equal(7, part.Pending)
+	}
+
+	// A running group will observe the new range when restarted.
+	bounds = &notify.Var[hlc.Range]{}
+	_, err = chk.Start(ctx, &types.TableGroup{Name: name}, bounds)
+	r.NoError(err)
+	rng, _ := bounds.Get()
+	r.Equal(hlc.New(35, 0), rng.Min())
+
+	// Unknown groups.
+	info, err = chk.Inspect(ctx, ident.New("unknown"))
+	r.NoError(err)
+	r.Nil(info)
+	r.Error(chk.Rewind(ctx, fixture.StagingPool, ident.New("unknown"), hlc.New(1, 0)))
+}
+
 func TestLimitLookahead(t *testing.T) {
 	const minNanos = int64(1)
 	const maxNanos = int64(10)
@@ -52,6 +52,33 @@ CREATE TABLE IF NOT EXISTS %[1]s (
   INDEX (group_name, target_applied_at, partition)
 )`
 
+// The PostgreSQL schema omits the virtual columns and uses a trigger to
+// emit notifications in place of a changefeed. Keep in sync with
+// stream.go.
+//
+// %[1]s = checkpoints table
+// %[2]s = secondary index name
+// %[3]s = notification function
+// %[4]s = notification trigger name
+const schemaPG = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+  group_name        TEXT        NOT NULL,
+  source_hlc        DECIMAL     NOT NULL,
+  partition         TEXT        NOT NULL,
+  first_seen        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  target_applied_at TIMESTAMPTZ,
+  PRIMARY KEY (group_name, source_hlc, partition)
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (group_name, target_applied_at, partition);
+CREATE OR REPLACE FUNCTION %[3]s() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_notify('` + notifyChannel + `', NEW.group_name);
+  RETURN NULL;
+END $$;
+CREATE OR REPLACE TRIGGER %[4]s AFTER INSERT OR UPDATE ON %[1]s
+  FOR EACH ROW EXECUTE FUNCTION %[3]s();
+`
+
 // Group provides durable storage of the checkpoint (FKA resolved)
 // timestamps associated with a [types.TableGroup].
 //
@@ -90,6 +117,7 @@ type Group struct {
 		advance string
 		commit  string
 		ensure  string
+		listen  string
 		refresh string
 		stream  string
 	}
@@ -117,6 +145,24 @@ to_insert AS (
 UPSERT INTO %[1]s (group_name, partition, source_hlc)
 SELECT * FROM to_insert`
 
+// The PostgreSQL version of advanceTemplate.
+const advanceTemplatePG = `
+WITH
+not_before AS (
+  SELECT source_hlc FROM %[1]s
+  WHERE group_name=$1 AND partition=$2
+  ORDER BY source_hlc DESC
+  LIMIT 1
+  FOR UPDATE),
+to_insert AS (
+  SELECT $1::TEXT, $2::TEXT, $3::DECIMAL
+  WHERE (SELECT count(*) FROM not_before) = 0
+     OR ($3::DECIMAL) >= (SELECT source_hlc FROM not_before))
+INSERT INTO %[1]s AS c (group_name, partition, source_hlc)
+SELECT * FROM to_insert
+ON CONFLICT (group_name, source_hlc, partition)
+DO UPDATE SET first_seen = c.first_seen`
+
 // Advance extends the proposed checkpoint timestamp associated with the
 // partition of the Group. It is an error if the timestamp does not
 // advance beyond its current point, as this will indicate a violation
@@ -195,6 +241,14 @@ SELECT $1, $2, 1.0000000001, now()
  WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE group_name=$1 AND partition=$2)
 `
 
+// The PostgreSQL version of ensureTemplate.
+const ensureTemplatePG = `
+INSERT INTO %[1]s (group_name, partition, source_hlc, target_applied_at)
+SELECT $1::TEXT, $2::TEXT, 1.0000000001, now()
+ WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE group_name=$1 AND partition=$2)
+ON CONFLICT (group_name, source_hlc, partition) DO NOTHING
+`
+
 // Ensure that a checkpoint exists for all named partitions. If no
 // checkpoint exists for a given partition, an applied, minimum-valued
 // checkpoint will be created. This method can be used to expand the
@@ -0,0 +1,192 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// GroupInfo describes the persistent state of a checkpoint group.
+type GroupInfo struct {
+	Name       ident.Ident      // The name of the group.
+	Range      hlc.Range        // The committed and proposed times.
+	Partitions []*PartitionInfo // Ordered by name.
+}
+
+// PartitionInfo describes the checkpoints within a partition.
+type PartitionInfo struct {
+	Partition ident.Ident // The name of the partition.
+	Applied   hlc.Time    // The latest applied checkpoint.
+	AppliedAt time.Time   // The time at which a checkpoint was last applied.
+	Latest    hlc.Time    // The latest checkpoint that has been proposed.
+	Pending   int         // The number of unapplied checkpoints.
+}
+
+const listGroupsTemplate = `
+SELECT DISTINCT group_name
+FROM %[1]s
+ORDER BY group_name
+`
+
+// Groups returns the names of all groups that have checkpoints.
+func (r *Checkpoints) Groups(ctx context.Context) ([]ident.Ident, error) {
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(listGroupsTemplate, r.metaTable))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+	var ret []ident.Ident
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ret = append(ret, ident.New(name))
+	}
+	return ret, errors.WithStack(rows.Err())
+}
+
+// $1 = group_name
+const inspectTemplate = `
+SELECT partition,
+       max(source_hlc) FILTER (WHERE target_applied_at IS NOT NULL),
+       max(target_applied_at),
+       max(source_hlc),
+       count(*) FILTER (WHERE target_applied_at IS NULL)
+  FROM %[1]s
+ WHERE group_name = $1
+ GROUP BY partition
+ ORDER BY partition
+`
+
+// Inspect returns the state of the named group. A nil value will be
+// returned if the group has no checkpoints.
+func (r *Checkpoints) Inspect(ctx context.Context, name ident.Ident) (*GroupInfo, error) {
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(inspectTemplate, r.metaTable),
+		name.Canonical().Raw())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	ret := &GroupInfo{Name: name}
+	for rows.Next() {
+		var part string
+		var applied sql.Null[hlc.Time]
+		var appliedAt sql.NullTime
+		info := &PartitionInfo{}
+		if err := rows.Scan(&part, &applied, &appliedAt, &info.Latest, &info.Pending); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		info.Partition = ident.New(part)
+		info.Applied = applied.V
+		info.AppliedAt = appliedAt.Time
+		ret.Partitions = append(ret.Partitions, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(ret.Partitions) == 0 {
+		return nil, nil
+	}
+
+	// Use the same query as a running Group to compute the bounds.
+	g := r.newGroup(&types.TableGroup{Name: name}, &notify.Var[hlc.Range]{}, 0, false)
+	ret.Range, err = g.refreshQuery(ctx, hlc.Zero())
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// $1 = group_name
+// $2 = source_hlc
+const rewindTemplate = `
+UPDATE %[1]s
+   SET target_applied_at = NULL
+ WHERE group_name = $1
+   AND source_hlc > $2
+   AND target_applied_at IS NOT NULL
+`
+
+// Place an applied checkpoint at the rewind time in each partition, so
+// that it will become the new committed time.
+//
+// $1 = group_name
+// $2 = source_hlc
+const rewindMarkTemplate = `
+UPSERT INTO %[1]s (group_name, partition, source_hlc, target_applied_at)
+SELECT DISTINCT $1::STRING, partition, $2::DECIMAL, now()
+  FROM %[1]s
+ WHERE group_name = $1
+`
+
+// The PostgreSQL version of rewindMarkTemplate.
+const rewindMarkTemplatePG = `
+INSERT INTO %[1]s (group_name, partition, source_hlc, target_applied_at)
+SELECT DISTINCT $1::TEXT, partition, $2::DECIMAL, now()
+  FROM %[1]s
+ WHERE group_name = $1
+ON CONFLICT (group_name, source_hlc, partition)
+DO UPDATE SET target_applied_at = excluded.target_applied_at
+`
+
+// Rewind moves the committed time of the named group backwards to the
+// given time. Checkpoints after the time will be marked as unapplied,
+// so that they will be processed again. It is an error to rewind a
+// group to a time at or after its current committed time.
+//
+// This method does not coordinate with running instances of
+// Replicator, which cache the committed time in memory. Callers should
+// ensure that no instance is operating on the group. The caller is
+// also responsible for marking the corresponding staged mutations as
+// unapplied, ideally within the same transaction.
+func (r *Checkpoints) Rewind(
+	ctx context.Context, db types.StagingQuerier, name ident.Ident, ts hlc.Time,
+) error {
+	info, err := r.Inspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return errors.Errorf("no checkpoints found for group %s", name)
+	}
+	if hlc.Compare(ts, info.Range.Min()) >= 0 {
+		return errors.Errorf("group %s cannot be rewound to %s, since its committed time is %s",
+			name, ts, info.Range.Min())
+	}
+
+	groupName := name.Canonical().Raw()
+	if _, err := db.Exec(ctx, fmt.Sprintf(rewindTemplate, r.metaTable), groupName, ts); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := db.Exec(ctx, fmt.Sprintf(r.templates.rewindMark, r.metaTable), groupName, ts); err != nil {
+		return errors.WithStack(err)
+	}
+	log.Infof("rewound checkpoints for group %s from %s to %s", name, info.Range.Min(), ts)
+	return nil
+}
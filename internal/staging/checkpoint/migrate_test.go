@@ -49,6 +49,10 @@ func TestMigration(t *testing.T) {
 
 	ctx := fixture.Context
 
+	if fixture.StagingPool.Product != types.ProductCockroachDB {
+		t.Skip("the legacy schema only exists in CockroachDB")
+	}
+
 	oldTable := ident.NewTable(fixture.StagingDB.Schema(),
 		ident.New("resolved_timestamps"))
 	_, err = fixture.StagingPool.Exec(ctx, fmt.Sprintf(oldSchema, oldTable))
@@ -34,19 +34,46 @@ func ProvideCheckpoints(
 	ctx context.Context, pool *types.StagingPool, meta ident.StagingSchema,
 ) (*Checkpoints, error) {
 	metaTable := ident.NewTable(meta.Schema(), ident.New("checkpoints"))
-	if _, err := pool.Exec(ctx, fmt.Sprintf(schema, metaTable)); err != nil {
-		return nil, errors.WithStack(err)
+	ret := &Checkpoints{
+		pool:      pool,
+		metaTable: metaTable,
 	}
 
-	// Perform migration from old resolved_timestamps schema.
-	if err := migrate(ctx, pool,
-		ident.NewTable(meta.Schema(), ident.New("resolved_timestamps")),
-		metaTable); err != nil {
-		return nil, err
+	switch pool.Product {
+	case types.ProductCockroachDB:
+		if _, err := pool.Exec(ctx, fmt.Sprintf(schema, metaTable)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		// Perform migration from old resolved_timestamps schema.
+		if err := migrate(ctx, pool,
+			ident.NewTable(meta.Schema(), ident.New("resolved_timestamps")),
+			metaTable); err != nil {
+			return nil, err
+		}
+
+		ret.templates.advance = advanceTemplate
+		ret.templates.ensure = ensureTemplate
+		ret.templates.rewindMark = rewindMarkTemplate
+
+	case types.ProductPostgreSQL:
+		// There is no legacy schema to migrate from.
+		if _, err := pool.Exec(ctx, fmt.Sprintf(schemaPG,
+			metaTable,
+			ident.New("checkpoints_group_name_target_applied_at_partition_idx"),
+			ident.NewTable(meta.Schema(), ident.New("checkpoints_notify")),
+			ident.New("checkpoints_notify"),
+		)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		ret.templates.advance = advanceTemplatePG
+		ret.templates.ensure = ensureTemplatePG
+		ret.templates.rewindMark = rewindMarkTemplatePG
+
+	default:
+		return nil, errors.Errorf("unsupported staging product %s", pool.Product)
 	}
 
-	return &Checkpoints{
-		pool:      pool,
-		metaTable: metaTable,
-	}, nil
+	return ret, nil
 }
@@ -22,30 +22,48 @@ import (
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
-const streamTemplate = "EXPERIMENTAL CHANGEFEED FOR %s WITH no_initial_scan, resolved='10s'"
+const (
+	listenTemplate = "LISTEN %s"
+	streamTemplate = "EXPERIMENTAL CHANGEFEED FOR %s WITH no_initial_scan, resolved='10s'"
+)
+
+// notifyChannel is used by the PostgreSQL trigger on the checkpoints
+// table. The payload of each notification is the group name.
+const notifyChannel = "replicator_checkpoints"
 
 // streamJob opens a core changefeed over the checkpoints table to
-// provide a cross-instance notification channel.
+// provide a cross-instance notification channel. When PostgreSQL is
+// used for staging, notifications are received via LISTEN instead.
 func (r *Group) streamJob(ctx *stopper.Context) {
-	// This is a defensive check; this should almost always be the case.
-	var enabled bool
-	if err := r.pool.QueryRow(ctx, "SHOW CLUSTER SETTING kv.rangefeed.enabled").Scan(&enabled); err != nil {
-		log.WithError(err).Warn(
-			"could not determine if rangefeeds are enabled; polling checkpoints table")
-		return
-	}
-	if !enabled {
-		log.Warn("rangefeeds not enabled on staging cluster; polling checkpoints table")
+	stream := r.doStream
+	switch r.pool.Product {
+	case types.ProductCockroachDB:
+		// This is a defensive check; this should almost always be the case.
+		var enabled bool
+		if err := r.pool.QueryRow(ctx, "SHOW CLUSTER SETTING kv.rangefeed.enabled").Scan(&enabled); err != nil {
+			log.WithError(err).Warn(
+				"could not determine if rangefeeds are enabled; polling checkpoints table")
+			return
+		}
+		if !enabled {
+			log.Warn("rangefeeds not enabled on staging cluster; polling checkpoints table")
+			return
+		}
+	case types.ProductPostgreSQL:
+		stream = r.doListen
+	default:
+		log.Warnf("notifications not supported by %s; polling checkpoints table", r.pool.Product)
 		return
 	}
 	ctx.Go(func(ctx *stopper.Context) error {
 		for !ctx.IsStopping() {
-			if err := r.doStream(ctx); err != nil {
+			if err := stream(ctx); err != nil {
 				if !errors.Is(err, context.Canceled) {
 					log.WithError(err).Warnf("notification stream error for %s", r.target.Name)
 				}
@@ -154,3 +172,52 @@ func (r *Group) doStream(ctx *stopper.Context) error {
 	}
 	return errors.WithStack(rows.Err())
 }
+
+// doListen receives notifications sent by the trigger on the
+// checkpoints table.
+func (r *Group) doListen(ctx *stopper.Context) error {
+	// LISTEN is a session-level operation, so we need a dedicated
+	// connection.
+	pooled, err := r.pool.Pool.Acquire(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	conn := pooled.Hijack()
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	// Exposed for testing.
+	if r.streamConn != nil {
+		r.streamConn.Set(conn)
+	}
+
+	dbCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx.Go(func(ctx *stopper.Context) error {
+		select {
+		case <-ctx.Stopping():
+			cancel()
+		case <-dbCtx.Done():
+		}
+		return nil
+	})
+
+	if _, err := conn.Exec(dbCtx, r.sql.listen); err != nil {
+		return errors.Wrap(err, r.sql.listen)
+	}
+
+	for {
+		note, err := conn.WaitForNotification(dbCtx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// Ignore updates for other groups.
+		if !ident.Equal(ident.New(note.Payload), r.target.Name) {
+			continue
+		}
+
+		// The refresh job loop debounces multiple notifications.
+		log.Tracef("notification wakeup for %s", r.target.Name)
+		r.fastWakeup.Notify()
+	}
+}
@@ -122,6 +122,13 @@ CREATE TABLE IF NOT EXISTS %s (
   expires TIMESTAMP NOT NULL,
   nonce UUID NOT NULL,
   hostname STRING NOT NULL
+)`
+	schemaPG = `
+CREATE TABLE IF NOT EXISTS %s (
+  name TEXT PRIMARY KEY,
+  expires TIMESTAMP NOT NULL,
+  nonce UUID NOT NULL,
+  hostname TEXT NOT NULL
 )`
 )
 
@@ -130,24 +137,41 @@ func New(ctx context.Context, cfg Config) (types.Leases, error) {
 	if err := cfg.sanitize(); err != nil {
 		return nil, err
 	}
-	_, err := cfg.Pool.Exec(ctx, fmt.Sprintf(schema, cfg.Target.Base))
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+	l := &leases{cfg: cfg}
+	switch cfg.Pool.Product {
+	case types.ProductCockroachDB:
+		_, err := cfg.Pool.Exec(ctx, fmt.Sprintf(schema, cfg.Target.Base))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 
-	// Upgrade schema in place.
-	_, err = cfg.Pool.Exec(ctx, fmt.Sprintf(
-		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS "+
-			"hostname STRING NOT NULL", cfg.Target.Base))
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+		// Upgrade schema in place.
+		_, err = cfg.Pool.Exec(ctx, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS "+
+				"hostname STRING NOT NULL", cfg.Target.Base))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 
-	l := &leases{cfg: cfg}
-	l.sql.acquire = fmt.Sprintf(acquireTemplate, cfg.Target)
-	l.sql.release = fmt.Sprintf(releaseTemplate, cfg.Target)
-	l.sql.renew = fmt.Sprintf(renewTemplate, cfg.Target)
+		l.sql.acquire = fmt.Sprintf(acquireTemplate, cfg.Target)
+		l.sql.release = fmt.Sprintf(releaseTemplate, cfg.Target)
+		l.sql.renew = fmt.Sprintf(renewTemplate, cfg.Target)
+
+	case types.ProductPostgreSQL:
+		_, err := cfg.Pool.Exec(ctx, fmt.Sprintf(schemaPG, cfg.Target.Base))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		l.sql.acquire = fmt.Sprintf(acquireTemplatePG, cfg.Target)
+		l.sql.release = fmt.Sprintf(releaseTemplatePG, cfg.Target)
+		l.sql.renew = fmt.Sprintf(renewTemplatePG, cfg.Target)
+
+	default:
+		return nil, errors.Errorf("unsupported staging product %s", cfg.Pool.Product)
+	}
 
+	var err error
 	if l.hostname, err = os.Hostname(); err == nil {
 		log.Tracef("lease hostname: %s", l.hostname)
 	} else {
@@ -407,6 +431,35 @@ RETURNING nonce
 SELECT (SELECT max(expires) FROM blocking), (SELECT DISTINCT nonce FROM acquired)
 `
 
+// The PostgreSQL version of acquireTemplate.
+const acquireTemplatePG = `
+WITH
+nonce (nonce) AS (
+SELECT gen_random_uuid()
+),
+proposed (name, expires, nonce, hostname) AS (
+SELECT unnest($1::TEXT[]), $2::TIMESTAMP, nonce, $3::TEXT
+  FROM nonce
+),
+blocking AS (
+SELECT x.expires
+  FROM %[1]s x
+  JOIN proposed USING (name)
+ WHERE x.expires > $4::TIMESTAMP
+   FOR UPDATE OF x
+),
+acquired AS (
+INSERT INTO %[1]s (name, expires, nonce, hostname)
+SELECT name, expires, nonce, hostname
+  FROM proposed
+ WHERE NOT EXISTS (SELECT * FROM blocking)
+    ON CONFLICT (name) DO UPDATE
+   SET expires = excluded.expires, nonce = excluded.nonce, hostname = excluded.hostname
+RETURNING nonce
+)
+SELECT (SELECT max(expires) FROM blocking), (SELECT DISTINCT nonce FROM acquired)
+`
+
 // tryAcquire returns the current state of the named lease in the
 // database. The ok value will be true if this call acquired the lease.
 func (l *leases) tryAcquire(
@@ -454,6 +507,13 @@ DELETE FROM %s
    AND nonce=$2::UUID
 `
 
+// The PostgreSQL version of releaseTemplate.
+const releaseTemplatePG = `
+DELETE FROM %s
+ WHERE name IN (SELECT unnest($1::TEXT[]))
+   AND nonce=$2::UUID
+`
+
 // tryRelease deletes the lease from the database.
 func (l *leases) tryRelease(ctx context.Context, rel lease) (ok bool, err error) {
 	tag, err := l.cfg.Pool.Exec(ctx, l.sql.release, rel.names, rel.nonce)
@@ -499,6 +559,25 @@ UPDATE %[1]s AS l
    AND (SELECT count(*) FROM proposed) = (SELECT count(*) FROM matches)
 `
 
+// The PostgreSQL version of renewTemplate.
+const renewTemplatePG = `
+WITH
+proposed(expires, name, nonce) AS (
+SELECT $1::TIMESTAMP, unnest($2::TEXT[]), $3::UUID
+),
+matches (name) AS (
+SELECT name
+  FROM %[1]s
+  JOIN proposed USING (name, nonce)
+   FOR UPDATE
+)
+UPDATE %[1]s AS l
+   SET expires=$1::TIMESTAMP
+  FROM matches m
+ WHERE l.name = m.name
+   AND (SELECT count(*) FROM proposed) = (SELECT count(*) FROM matches)
+`
+
 // tryRenew updates the lease record in the database. If successful, the
 // input lease struct will be updated with the new expiration time and
 // returned to the caller. The boolean return value will be false if the
@@ -39,10 +39,15 @@ func TestLeases(t *testing.T) {
 
 	ctx := fixture.Context
 
-	enclosing, err := base.CreateSchema(ctx, fixture.StagingPool, "leases_")
-	r.NoError(err)
-
-	tbl, err := base.CreateTable(ctx, fixture.StagingPool, enclosing, schema)
+	enclosing, tableSchema := fixture.StagingDB.Schema(), schemaPG
+	if fixture.StagingPool.Product == types.ProductCockroachDB {
+		// PostgreSQL connections cannot refer to other databases.
+		enclosing, err = base.CreateSchema(ctx, fixture.StagingPool, "leases_")
+		r.NoError(err)
+		tableSchema = schema
+	}
+
+	tbl, err := base.CreateTable(ctx, fixture.StagingPool, enclosing, tableSchema)
 	r.NoError(err)
 
 	intf, err := New(ctx, Config{
@@ -353,3 +358,15 @@ func TestSanitize(t *testing.T) {
 	a.Equal(defaultPoll, cfg.Poll)
 	a.Equal(defaultRetry, cfg.RetryDelay)
 }
+
+func TestUnsupportedProduct(t *testing.T) {
+	r := require.New(t)
+
+	_, err := New(context.Background(), Config{
+		Pool: &types.StagingPool{PoolInfo: types.PoolInfo{Product: types.ProductMySQL}},
+		Target: ident.WithHint(
+			ident.NewTable(ident.MustSchema(ident.New("db"), ident.Public),
+				ident.New("tbl")), "hinted"),
+	})
+	r.EqualError(err, "unsupported staging product MySQL")
+}
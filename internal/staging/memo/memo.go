@@ -45,6 +45,15 @@ CREATE TABLE IF NOT EXISTS %[1]s (
 )`
 	updateTemplate = `UPSERT INTO %[1]s (key, value) VALUES ($1, $2)`
 	getTemplate    = `SELECT value FROM %[1]s WHERE key = $1`
+
+	schemaPG = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+  key   TEXT  NOT NULL PRIMARY KEY,
+  value BYTEA NOT NULL
+)`
+	updateTemplatePG = `
+INSERT INTO %[1]s (key, value) VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE SET value = excluded.value`
 )
 
 // Get retrieves a value given a key or nil if it does not exist.
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/retry"
 	"github.com/google/wire"
+	"github.com/pkg/errors"
 )
 
 // Set is used by Wire.
@@ -37,11 +38,20 @@ func ProvideMemo(
 	ctx context.Context, db *types.StagingPool, staging ident.StagingSchema,
 ) (*Memo, error) {
 	target := ident.NewTable(staging.Schema(), ident.New("memo"))
-	if err := retry.Execute(ctx, db, fmt.Sprintf(schema, target)); err != nil {
+	var createTemplate, update string
+	switch db.Product {
+	case types.ProductCockroachDB:
+		createTemplate, update = schema, updateTemplate
+	case types.ProductPostgreSQL:
+		createTemplate, update = schemaPG, updateTemplatePG
+	default:
+		return nil, errors.Errorf("unsupported staging product %s", db.Product)
+	}
+	if err := retry.Execute(ctx, db, fmt.Sprintf(createTemplate, target)); err != nil {
 		return nil, err
 	}
 	ret := &Memo{stagingPool: db}
 	ret.sql.get = fmt.Sprintf(getTemplate, target)
-	ret.sql.update = fmt.Sprintf(updateTemplate, target)
+	ret.sql.update = fmt.Sprintf(update, target)
 	return ret, nil
 }
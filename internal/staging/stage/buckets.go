@@ -0,0 +1,418 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+// This file contains support for storing staged mutations in a
+// collection of tables, or buckets, that each hold a fixed-width range
+// of time. Retiring a bucket whose range has been fully resolved can
+// then drop the entire table, rather than deleting individual rows.
+//
+// The buckets for each target table are recorded in a catalog table
+// within the staging schema. A pre-existing staging table is migrated
+// by recording it as a bucket which holds all times up to the first
+// bucket boundary after the Replicator process has started.
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// bucketCatalog is the name of the table that records the buckets
+// that have been created for each target.
+var bucketCatalog = ident.New("stage_buckets")
+
+const bucketCatalogSchema = `
+CREATE TABLE IF NOT EXISTS %s (
+       target STRING NOT NULL,
+  start_nanos INT8 NOT NULL,
+    end_nanos INT8 NOT NULL,
+       bucket STRING NOT NULL,
+  PRIMARY KEY (target, start_nanos)
+)`
+
+const bucketDeleteTemplate = `DELETE FROM %s WHERE target = $1 AND start_nanos = $2`
+
+const bucketInsertTemplate = `
+INSERT INTO %s (target, start_nanos, end_nanos, bucket)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING`
+
+const bucketLoadTemplate = `
+SELECT start_nanos, end_nanos, bucket
+  FROM %s
+ WHERE target = $1
+ ORDER BY start_nanos`
+
+const hasUnappliedTemplate = `SELECT EXISTS (SELECT 1 FROM %s WHERE NOT applied)`
+
+const maxNanosTemplate = `SELECT max(nanos) FROM %s`
+
+// bucket is a staging table that holds the mutations whose timestamps
+// fall within a half-open range of nanoseconds.
+type bucket struct {
+	start int64 // Inclusive.
+	end   int64 // Exclusive.
+	sql   stageSQL
+	table ident.Table
+}
+
+// contains returns true if the nanosecond timestamp falls within the
+// bucket.
+func (b *bucket) contains(nanos int64) bool {
+	return nanos >= b.start && nanos < b.end
+}
+
+// bucketGroup associates a subset of an input slice of mutations with
+// the bucket that contains them.
+type bucketGroup struct {
+	*bucket
+	indices []int // The positions of the mutations in the input.
+	muts    []types.Mutation
+}
+
+// alignBucket returns the start of the bucket of the given width which
+// contains the timestamp.
+func alignBucket(nanos int64, width time.Duration) int64 {
+	w := int64(width)
+	ret := nanos / w * w
+	if ret > nanos {
+		ret -= w
+	}
+	return ret
+}
+
+// findBucket returns the bucket that contains the timestamp, or nil.
+// The buckets must be sorted.
+func findBucket(buckets []*bucket, nanos int64) *bucket {
+	idx := sort.Search(len(buckets), func(i int) bool {
+		return buckets[i].end > nanos
+	})
+	if idx < len(buckets) && buckets[idx].contains(nanos) {
+		return buckets[idx]
+	}
+	return nil
+}
+
+// bucketed returns true if mutations are split across multiple tables.
+func (s *stage) bucketed() bool {
+	return s.cfg.BucketWidth > 0
+}
+
+// initBuckets creates the staging table or tables. If bucketing is
+// enabled, a background task will create buckets ahead of time.
+func (s *stage) initBuckets(ctx *stopper.Context) error {
+	base := s.stage.Base
+	if !s.bucketed() {
+		if err := createStagingTable(ctx, s.stagingDB, base); err != nil {
+			return err
+		}
+		s.buckets.data = []*bucket{{
+			start: math.MinInt64,
+			end:   math.MaxInt64,
+			sql:   newStageSQL(s.stagingDB, base),
+			table: base,
+		}}
+		return nil
+	}
+
+	catalog := ident.NewTable(base.Schema(), bucketCatalog)
+	schema := bucketCatalogSchema
+	if s.stagingDB.Product == types.ProductPostgreSQL {
+		schema = bucketCatalogSchemaPG
+	}
+	if err := retry.Execute(ctx, s.stagingDB, fmt.Sprintf(schema, catalog)); err != nil {
+		return errors.WithStack(err)
+	}
+	s.bucketSQL.delete = fmt.Sprintf(bucketDeleteTemplate, catalog)
+	s.bucketSQL.insert = fmt.Sprintf(bucketInsertTemplate, catalog)
+	s.bucketSQL.load = fmt.Sprintf(bucketLoadTemplate, catalog)
+
+	if err := s.loadBuckets(ctx); err != nil {
+		return err
+	}
+	if err := s.migrateBase(ctx); err != nil {
+		return err
+	}
+	if err := s.ensureBucketsAhead(ctx); err != nil {
+		return err
+	}
+
+	// Create future buckets on a periodic basis. This also picks up
+	// changes made by other instances of Replicator.
+	ctx.Go(func(ctx *stopper.Context) error {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+			if err := s.loadBuckets(ctx); err != nil {
+				log.WithError(err).Warnf("could not refresh staging buckets for %s", base)
+				continue
+			}
+			if err := s.ensureBucketsAhead(ctx); err != nil {
+				log.WithError(err).Warnf("could not create staging buckets for %s", base)
+			}
+		}
+	})
+	return nil
+}
+
+// migrateBase records a staging table created before bucketing was
+// enabled as a bucket that holds all times up to the next bucket
+// boundary. This is a no-op if any buckets have been recorded or if
+// there is no pre-existing staging table.
+func (s *stage) migrateBase(ctx context.Context) error {
+	s.buckets.RLock()
+	count := len(s.buckets.data)
+	s.buckets.RUnlock()
+	if count > 0 {
+		return nil
+	}
+
+	base := s.stage.Base
+	var maxNanos *int64
+	err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+		return s.stagingDB.QueryRow(ctx,
+			fmt.Sprintf(maxNanosTemplate, base)).Scan(&maxNanos)
+	})
+	if code, ok := s.stagingDB.ErrCode(err); ok && code == "42P01" /* undefined_table */ {
+		return nil
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Apply any schema upgrades to the existing table.
+	if err := createStagingTable(ctx, s.stagingDB, base); err != nil {
+		return err
+	}
+
+	last := time.Now().UnixNano()
+	if maxNanos != nil && *maxNanos > last {
+		last = *maxNanos
+	}
+	end := alignBucket(last, s.cfg.BucketWidth) + int64(s.cfg.BucketWidth)
+	if err := retry.Execute(ctx, s.stagingDB, s.bucketSQL.insert,
+		base.Table().Raw(), int64(math.MinInt64), end, base.Table().Raw()); err != nil {
+		return errors.WithStack(err)
+	}
+	log.Infof("migrated staging table %s to hold mutations before %s",
+		base, time.Unix(0, end).UTC())
+	return s.loadBuckets(ctx)
+}
+
+// ensureBucketsAhead creates the buckets for the current time and
+// those which follow it.
+func (s *stage) ensureBucketsAhead(ctx context.Context) error {
+	now := time.Now().UnixNano()
+	for i := 0; i <= s.cfg.BucketsAhead; i++ {
+		if _, err := s.createBucket(ctx, now+int64(i)*int64(s.cfg.BucketWidth)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadBuckets refreshes the cached list of buckets from the catalog.
+func (s *stage) loadBuckets(ctx context.Context) error {
+	var next []*bucket
+	err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+		next = next[:0]
+		rows, err := s.stagingDB.Query(ctx, s.bucketSQL.load, s.stage.Base.Table().Raw())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			b := &bucket{}
+			if err := rows.Scan(&b.start, &b.end, &name); err != nil {
+				return err
+			}
+			b.table = ident.NewTable(s.stage.Base.Schema(), ident.New(name))
+			b.sql = newStageSQL(s.stagingDB, b.table)
+			next = append(next, b)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.buckets.Lock()
+	s.buckets.data = next
+	s.buckets.Unlock()
+	s.bucketCount.Set(float64(len(next)))
+	return nil
+}
+
+// createBucket ensures that a bucket exists which contains the
+// timestamp. Newly-created buckets are aligned to the bucket width,
+// but will be trimmed to avoid overlapping any existing buckets.
+func (s *stage) createBucket(ctx context.Context, nanos int64) (*bucket, error) {
+	start := alignBucket(nanos, s.cfg.BucketWidth)
+	end := start + int64(s.cfg.BucketWidth)
+
+	s.buckets.RLock()
+	for _, b := range s.buckets.data {
+		if b.contains(nanos) {
+			s.buckets.RUnlock()
+			return b, nil
+		}
+		if b.end <= nanos && b.end > start {
+			start = b.end
+		}
+		if b.start > nanos && b.start < end {
+			end = b.start
+		}
+	}
+	s.buckets.RUnlock()
+
+	base := s.stage.Base
+	name := ident.New(fmt.Sprintf("%s_%d", base.Table().Raw(), start/int64(time.Second)))
+	table := ident.NewTable(base.Schema(), name)
+	if err := createStagingTable(ctx, s.stagingDB, table); err != nil {
+		return nil, err
+	}
+	if err := retry.Execute(ctx, s.stagingDB, s.bucketSQL.insert,
+		base.Table().Raw(), start, end, name.Raw()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	log.Debugf("created staging bucket %s", table)
+	if err := s.loadBuckets(ctx); err != nil {
+		return nil, err
+	}
+
+	s.buckets.RLock()
+	defer s.buckets.RUnlock()
+	if ret := findBucket(s.buckets.data, nanos); ret != nil {
+		return ret, nil
+	}
+	return nil, errors.Errorf("staging bucket %s does not contain %d", table, nanos)
+}
+
+// dropBucket removes the bucket from the catalog and drops its table.
+func (s *stage) dropBucket(ctx context.Context, db types.StagingQuerier, b *bucket) error {
+	if _, err := db.Exec(ctx, s.bucketSQL.delete, s.stage.Base.Table().Raw(), b.start); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := db.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", b.table)); err != nil {
+		return errors.WithStack(err)
+	}
+	s.bucketDrops.Inc()
+	log.Debugf("dropped staging bucket %s", b.table)
+	return nil
+}
+
+// bucketsIn returns the buckets that may contain timestamps within the
+// inclusive range of nanoseconds. If refresh is true, the list of
+// buckets will be reloaded from the catalog.
+func (s *stage) bucketsIn(ctx context.Context, from, to int64, refresh bool) ([]*bucket, error) {
+	if refresh && s.bucketed() {
+		if err := s.loadBuckets(ctx); err != nil {
+			return nil, err
+		}
+	}
+	s.buckets.RLock()
+	defer s.buckets.RUnlock()
+	var ret []*bucket
+	for _, b := range s.buckets.data {
+		if b.end > from && b.start <= to {
+			ret = append(ret, b)
+		}
+	}
+	return ret, nil
+}
+
+// group partitions the mutations by the bucket that contains them. The
+// cached list of buckets will be refreshed if a mutation falls outside
+// of any known bucket. If create is true, missing buckets will be
+// created; otherwise the mutations that do not belong to any bucket
+// will be omitted from the returned groups.
+func (s *stage) group(
+	ctx context.Context, muts []types.Mutation, create bool,
+) ([]*bucketGroup, error) {
+	var ret []*bucketGroup
+	byBucket := make(map[*bucket]*bucketGroup)
+	add := func(b *bucket, idx int) {
+		g := byBucket[b]
+		if g == nil {
+			g = &bucketGroup{bucket: b}
+			byBucket[b] = g
+			ret = append(ret, g)
+		}
+		g.indices = append(g.indices, idx)
+		g.muts = append(g.muts, muts[idx])
+	}
+
+	var missing []int
+	s.buckets.RLock()
+	for idx, mut := range muts {
+		if b := findBucket(s.buckets.data, mut.Time.Nanos()); b != nil {
+			add(b, idx)
+		} else {
+			missing = append(missing, idx)
+		}
+	}
+	s.buckets.RUnlock()
+
+	if len(missing) > 0 {
+		// Another instance may have created the buckets.
+		if err := s.loadBuckets(ctx); err != nil {
+			return nil, err
+		}
+		for _, idx := range missing {
+			nanos := muts[idx].Time.Nanos()
+			s.buckets.RLock()
+			b := findBucket(s.buckets.data, nanos)
+			s.buckets.RUnlock()
+			if b == nil && create {
+				var err error
+				b, err = s.createBucket(ctx, nanos)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if b != nil {
+				// Buckets may have been reloaded, so we'll look for an
+				// existing group for the same range of time.
+				for existing := range byBucket {
+					if existing.start == b.start {
+						b = existing
+						break
+					}
+				}
+				add(b, idx)
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].start < ret[j].start })
+	return ret, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignBucket(t *testing.T) {
+	a := assert.New(t)
+	w := time.Hour
+	h := int64(time.Hour)
+
+	a.Equal(int64(0), alignBucket(0, w))
+	a.Equal(int64(0), alignBucket(h-1, w))
+	a.Equal(h, alignBucket(h, w))
+	a.Equal(-h, alignBucket(-1, w))
+	a.Equal(-h, alignBucket(-h, w))
+}
+
+func TestFindBucket(t *testing.T) {
+	a := assert.New(t)
+
+	buckets := []*bucket{
+		{start: math.MinInt64, end: 10},
+		{start: 10, end: 20},
+		// Gap between 20 and 30.
+		{start: 30, end: 40},
+	}
+
+	a.Same(buckets[0], findBucket(buckets, math.MinInt64))
+	a.Same(buckets[0], findBucket(buckets, 9))
+	a.Same(buckets[1], findBucket(buckets, 10))
+	a.Same(buckets[1], findBucket(buckets, 19))
+	a.Nil(findBucket(buckets, 20))
+	a.Nil(findBucket(buckets, 29))
+	a.Same(buckets[2], findBucket(buckets, 30))
+	a.Nil(findBucket(buckets, 40))
+	a.Nil(findBucket(nil, 0))
+}
@@ -17,12 +17,25 @@
 package stage
 
 import (
+	"os"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
+// The compression codecs for staged payloads.
 const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+const (
+	defaultBucketsAhead         = 2
+	defaultEncryptionKeyPeriod  = time.Minute
+	defaultZstdDictPeriod       = time.Hour
+	defaultZstdDictSamples      = 1_000
+	defaultZstdThreshold        = 64
 	defaultMarkAppliedBatchSize = 100_000
 	defaultSanityCheckPeriod    = 10 * time.Minute
 	defaultSanityCheckWindow    = time.Hour
@@ -31,14 +44,66 @@ const (
 
 // Config sets tuneables when interacting with the staging tables.
 type Config struct {
+	// If positive, staged mutations are stored in tables that each
+	// hold a time range of this width. Retiring mutations will then
+	// drop entire tables instead of deleting rows. Bucketing must not
+	// be disabled while the buckets contain unapplied mutations.
+	BucketWidth time.Duration
+	// The number of future buckets to create ahead of time.
+	BucketsAhead int
+	// The codec used to compress staged payloads. Payloads that have
+	// been compressed by any codec can be read, regardless of this
+	// setting.
+	Compression string
+	// Payloads that are no larger than this many bytes are stored
+	// uncompressed. If zero, a default for the codec is used.
+	CompressionThreshold int
+	// If set, a file containing base64-encoded, 256-bit master keys,
+	// one per line. The first key is used to wrap new data keys; the
+	// remaining keys are retained to unwrap existing data keys. If
+	// unset, keys may be provided by the encryptionKeysEnv
+	// environment variable.
+	EncryptionKeyFile string
+	// How often to reload the data keys, to pick up keys that were
+	// rotated by another process. If negative, the keys are only
+	// reloaded when a payload uses an unknown key.
+	EncryptionKeyPeriod time.Duration
+
 	MarkAppliedLimit  int           // Maximum batch size for the MarkApplied method.
 	SanityCheckPeriod time.Duration // If positive, refresh [stageConsistencyErrors].
 	SanityCheckWindow time.Duration // If positive, limit time range of records.
 	UnappliedPeriod   time.Duration // If positive, report number of unapplied mutations.
+	// How often to train a new zstd dictionary for each target. If
+	// negative, a dictionary will be trained only once.
+	ZstdDictPeriod time.Duration
+	// The number of payloads to sample when training a zstd
+	// dictionary. If negative, dictionaries will not be trained.
+	ZstdDictSamples int
+
+	masterKeys *masterKeys // Loaded by Preflight.
 }
 
 // Bind adds configuration flags to the set.
 func (c *Config) Bind(f *pflag.FlagSet) {
+	f.DurationVar(&c.BucketWidth, "stageBucketWidth", 0,
+		"if set, store staged mutations in tables that each hold this much time, "+
+			"so that retiring mutations drops entire tables; all instances "+
+			"sharing a staging database should use the same value")
+	f.IntVar(&c.BucketsAhead, "stageBucketsAhead", defaultBucketsAhead,
+		"the number of staging buckets to create ahead of time")
+	f.StringVar(&c.Compression, "stageCompression", CompressionGzip,
+		"the codec used to compress staged mutations [ gzip, zstd ]")
+	f.IntVar(&c.CompressionThreshold, "stageCompressionThreshold", 0,
+		"staged mutations no larger than this many bytes are not compressed "+
+			"(default 1024 for gzip and 64 for zstd)")
+	f.StringVar(&c.EncryptionKeyFile, "stageEncryptionKeyFile", "",
+		"a file containing base64-encoded, 256-bit master keys, one per line, "+
+			"used to encrypt staged mutations; the first key is active and the "+
+			"others are retained for decryption. Keys may also be provided "+
+			"in the "+encryptionKeysEnv+" environment variable")
+	f.DurationVar(&c.EncryptionKeyPeriod, "stageEncryptionKeyPeriod", defaultEncryptionKeyPeriod,
+		"how often to reload the data keys, so that rotated keys are used to encrypt "+
+			"new staged mutations (-1 to disable)")
 	f.IntVar(&c.MarkAppliedLimit, "stageMarkAppliedLimit", defaultMarkAppliedBatchSize,
 		"limit the number of mutations to be marked applied in a single statement")
 	f.DurationVar(&c.SanityCheckPeriod, "stageSanityCheckPeriod", defaultSanityCheckPeriod,
@@ -47,10 +112,68 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 		"how far back to look when validating staging table apply order")
 	f.DurationVar(&c.UnappliedPeriod, "stageUnappliedPeriod", defaultUnappliedPeriod,
 		"how often to report the number of unapplied mutations in staging tables (-1 to disable)")
+	f.DurationVar(&c.ZstdDictPeriod, "stageZstdDictPeriod", defaultZstdDictPeriod,
+		"how often to train a new zstd dictionary for each staging table (-1 to train only once)")
+	f.IntVar(&c.ZstdDictSamples, "stageZstdDictSamples", defaultZstdDictSamples,
+		"the number of staged mutations to sample when training a zstd dictionary (-1 to disable)")
 }
 
 // Preflight ensures the Config is in a known-good state.
 func (c *Config) Preflight() error {
+	if c.BucketWidth < 0 {
+		return errors.New("stageBucketWidth must not be negative")
+	}
+	if c.BucketWidth > 0 {
+		if c.BucketWidth < time.Minute {
+			return errors.New("stageBucketWidth must be at least one minute")
+		}
+		if c.BucketWidth%time.Second != 0 {
+			return errors.New("stageBucketWidth must be a whole number of seconds")
+		}
+	}
+	if c.BucketsAhead == 0 {
+		c.BucketsAhead = defaultBucketsAhead
+	}
+	if c.BucketsAhead < 0 {
+		return errors.New("stageBucketsAhead must not be negative")
+	}
+	switch c.Compression {
+	case "":
+		c.Compression = CompressionGzip
+	case CompressionGzip, CompressionZstd:
+	default:
+		return errors.Errorf("unknown stageCompression %q", c.Compression)
+	}
+	if c.CompressionThreshold < 0 {
+		return errors.New("stageCompressionThreshold must not be negative")
+	}
+	if c.CompressionThreshold == 0 {
+		if c.Compression == CompressionZstd {
+			c.CompressionThreshold = defaultZstdThreshold
+		} else {
+			c.CompressionThreshold = gzipMinSize
+		}
+	}
+	c.masterKeys = nil
+	if c.EncryptionKeyFile != "" {
+		buf, err := os.ReadFile(c.EncryptionKeyFile)
+		if err != nil {
+			return errors.Wrap(err, "stageEncryptionKeyFile")
+		}
+		c.masterKeys, err = parseMasterKeys(string(buf))
+		if err != nil {
+			return errors.Wrap(err, "stageEncryptionKeyFile")
+		}
+	} else if env := os.Getenv(encryptionKeysEnv); env != "" {
+		var err error
+		c.masterKeys, err = parseMasterKeys(env)
+		if err != nil {
+			return errors.Wrap(err, encryptionKeysEnv)
+		}
+	}
+	if c.EncryptionKeyPeriod == 0 {
+		c.EncryptionKeyPeriod = defaultEncryptionKeyPeriod
+	}
 	if c.MarkAppliedLimit == 0 {
 		c.MarkAppliedLimit = defaultMarkAppliedBatchSize
 	}
@@ -63,5 +186,17 @@ func (c *Config) Preflight() error {
 	if c.UnappliedPeriod == 0 {
 		c.UnappliedPeriod = defaultUnappliedPeriod
 	}
+	if c.ZstdDictPeriod == 0 {
+		c.ZstdDictPeriod = defaultZstdDictPeriod
+	}
+	if c.ZstdDictSamples == 0 {
+		c.ZstdDictSamples = defaultZstdDictSamples
+	}
 	return nil
 }
+
+// Encrypted returns true if Preflight loaded master keys that will be
+// used to encrypt staged mutations.
+func (c *Config) Encrypted() bool {
+	return c.masterKeys != nil
+}
@@ -19,7 +19,9 @@ package stage
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/types"
@@ -55,13 +57,18 @@ func (s *stage) CheckConsistency(
 ) (int, error) {
 	var aost, predicate string
 	var args []any
-	if followerRead {
+	pg := s.stagingDB.Product == types.ProductPostgreSQL
+	if followerRead && !pg {
 		aost = "AS OF SYSTEM TIME follower_read_timestamp()"
 	}
 	if len(muts) > 0 {
 		// If there are specific keys to check, we ignore any time
 		// limits imposed.
-		predicate = "WHERE key IN (SELECT unnest($1::STRING[]))"
+		if pg {
+			predicate = "WHERE key IN (SELECT unnest($1::TEXT[]))"
+		} else {
+			predicate = "WHERE key IN (SELECT unnest($1::STRING[]))"
+		}
 
 		keys := make([]string, len(muts))
 		for idx, mut := range muts {
@@ -74,7 +81,23 @@ func (s *stage) CheckConsistency(
 		predicate = "WHERE nanos >= ($1::INT8)"
 		args = []any{time.Now().Add(-s.cfg.SanityCheckWindow).UnixNano()}
 	}
-	q := fmt.Sprintf(checkTemplate, s.stage.Base, predicate, aost)
+	buckets, err := s.bucketsIn(ctx, math.MinInt64, math.MaxInt64, false)
+	if err != nil {
+		return 0, err
+	}
+	var source string
+	if len(buckets) == 1 {
+		source = buckets[0].table.String()
+	} else {
+		// Mutations for a key may span multiple buckets.
+		parts := make([]string, len(buckets))
+		for idx, b := range buckets {
+			parts[idx] = fmt.Sprintf(
+				"SELECT nanos, logical, key, applied_at FROM %s", b.table)
+		}
+		source = fmt.Sprintf("(%s) AS staged", strings.Join(parts, " UNION ALL "))
+	}
+	q := fmt.Sprintf(checkTemplate, source, predicate, aost)
 	rows, err := db.Query(ctx, q, args...)
 	if err != nil {
 		return 0, errors.Wrap(err, q)
@@ -0,0 +1,488 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+// This file contains an envelope-encryption codec for the payloads of
+// staged mutations. Each target table has one or more randomly
+// generated data keys, which are stored in a catalog table in the
+// staging schema after being wrapped (encrypted) by a master key. The
+// master keys never leave the Replicator process.
+//
+// Encrypted payloads carry the id of the data key that was used to
+// encrypt them. Rotating the data keys creates a new key with a larger
+// id which will be used for newly-staged mutations once each instance
+// of Replicator has reloaded its keys. Existing rows
+// remain readable since the older data keys are retained. Rotating a
+// master key only requires the data keys to be re-wrapped.
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// encryptionKeysEnv names an environment variable that may contain
+// master keys if a key file has not been configured.
+const encryptionKeysEnv = "REPLICATOR_STAGE_KEYS"
+
+// encryptionKeySize is the length of master and data keys, which are
+// used with AES-256.
+const encryptionKeySize = 32
+
+// encryptedMagic prefixes an encrypted payload. A JSON document or a
+// gzip stream will never start with a zero byte. The last byte is a
+// format version.
+var encryptedMagic = []byte{0x00, 'R', 'E', 0x01}
+
+// encryptedHeaderSize is the length of the magic prefix, the data key
+// id, and the nonce.
+const encryptedHeaderSize = 4 + 4 + 12
+
+// keyCatalog is the name of the table that stores the wrapped data
+// keys for each target.
+var keyCatalog = ident.New("stage_keys")
+
+const keyCatalogSchema = `
+CREATE TABLE IF NOT EXISTS %s (
+     target STRING NOT NULL,
+     key_id INT8 NOT NULL,
+  master_id STRING NOT NULL,
+    wrapped BYTES NOT NULL,
+ created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (target, key_id)
+)`
+
+const keyInsertTemplate = `
+INSERT INTO %s (target, key_id, master_id, wrapped)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING`
+
+const keyLoadTemplate = `
+SELECT key_id, master_id, wrapped
+  FROM %s
+ WHERE target = $1
+ ORDER BY key_id`
+
+const keyRewrapTemplate = `
+UPDATE %s SET master_id = $3, wrapped = $4
+ WHERE target = $1 AND key_id = $2 AND master_id = $5`
+
+// masterKey wraps the data keys.
+type masterKey struct {
+	aead cipher.AEAD
+	id   string // A digest of the key material.
+}
+
+// masterKeys is an ordered set of master keys. The first key will be
+// used to wrap new data keys.
+type masterKeys struct {
+	active *masterKey
+	byID   map[string]*masterKey
+}
+
+// parseMasterKeys reads base64-encoded keys that are separated by
+// newlines or commas. Blank lines and lines starting with a # are
+// ignored.
+func parseMasterKeys(text string) (*masterKeys, error) {
+	ret := &masterKeys{byID: make(map[string]*masterKey)}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			key, err := base64.StdEncoding.DecodeString(part)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not decode master key")
+			}
+			if len(key) != encryptionKeySize {
+				return nil, errors.Errorf(
+					"master keys must be %d bytes, got %d", encryptionKeySize, len(key))
+			}
+			aead, err := newAEAD(key)
+			if err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(key)
+			mk := &masterKey{aead: aead, id: hex.EncodeToString(sum[:8])}
+			if _, dup := ret.byID[mk.id]; dup {
+				continue
+			}
+			ret.byID[mk.id] = mk
+			if ret.active == nil {
+				ret.active = mk
+			}
+		}
+	}
+	if ret.active == nil {
+		return nil, errors.New("no master keys were provided")
+	}
+	return ret, nil
+}
+
+// newAEAD returns an AES-GCM cipher.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.WithStack(err)
+}
+
+// dataKey encrypts the payloads of staged mutations.
+type dataKey struct {
+	aead     cipher.AEAD
+	id       uint32
+	masterID string
+	wrapped  []byte
+}
+
+// dataKeys manages the data keys for a single target.
+type dataKeys struct {
+	db      *types.StagingPool
+	masters *masterKeys
+	target  string // Used as additional authenticated data.
+	sql     struct {
+		insert string
+		load   string
+		rewrap string
+	}
+
+	mu struct {
+		sync.RWMutex
+		active *dataKey // The key with the largest id.
+		byID   map[uint32]*dataKey
+	}
+}
+
+// newDataKeys creates the key catalog and ensures that the target has
+// a data key. A background task will periodically reload the keys, so
+// that a rotation performed by another process will be used to encrypt
+// new payloads.
+func newDataKeys(
+	ctx *stopper.Context, cfg *Config, db *types.StagingPool, stagingDB ident.Schema, target string,
+) (*dataKeys, error) {
+	masters := cfg.masterKeys
+	catalog := ident.NewTable(stagingDB, keyCatalog)
+	schema := keyCatalogSchema
+	if db.Product == types.ProductPostgreSQL {
+		schema = keyCatalogSchemaPG
+	}
+	if err := retry.Execute(ctx, db, fmt.Sprintf(schema, catalog)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	k := &dataKeys{db: db, masters: masters, target: target}
+	k.sql.insert = fmt.Sprintf(keyInsertTemplate, catalog)
+	k.sql.load = fmt.Sprintf(keyLoadTemplate, catalog)
+	k.sql.rewrap = fmt.Sprintf(keyRewrapTemplate, catalog)
+	k.mu.byID = make(map[uint32]*dataKey)
+
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	hasKey := k.mu.active != nil
+	k.mu.RUnlock()
+	if !hasKey {
+		// Another instance of Replicator may race to create the first
+		// key. The conflict clause ensures that only one key is used.
+		if err := k.create(ctx, 1); err != nil {
+			return nil, err
+		}
+		if err := k.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.EncryptionKeyPeriod < 0 {
+		return k, nil
+	}
+
+	ctx.Go(func(ctx *stopper.Context) error {
+		ticker := time.NewTicker(cfg.EncryptionKeyPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+			if err := k.load(ctx); err != nil {
+				log.WithError(err).Warnf("could not refresh data keys for %s", target)
+			}
+		}
+	})
+	return k, nil
+}
+
+// activeID returns the id of the data key used to encrypt new payloads.
+func (k *dataKeys) activeID() uint32 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.mu.active.id
+}
+
+// create generates and stores a new data key with the given id.
+func (k *dataKeys) create(ctx context.Context, id uint32) error {
+	raw := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return errors.WithStack(err)
+	}
+	wrapped, err := k.wrap(k.masters.active, id, raw)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(retry.Execute(ctx, k.db, k.sql.insert,
+		k.target, int64(id), k.masters.active.id, wrapped))
+}
+
+// decrypt reverses encrypt. If the payload uses a data key that has not
+// been seen, the keys will be reloaded to pick up a rotation performed
+// by another process.
+func (k *dataKeys) decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if len(data) < encryptedHeaderSize {
+		return nil, errors.New("encrypted payload is truncated")
+	}
+	id := binary.BigEndian.Uint32(data[len(encryptedMagic):])
+	k.mu.RLock()
+	key := k.mu.byID[id]
+	k.mu.RUnlock()
+	if key == nil {
+		if err := k.load(ctx); err != nil {
+			return nil, err
+		}
+		k.mu.RLock()
+		key = k.mu.byID[id]
+		k.mu.RUnlock()
+		if key == nil {
+			return nil, errors.Errorf("unknown data key %d for %s", id, k.target)
+		}
+	}
+	nonce := data[len(encryptedMagic)+4 : encryptedHeaderSize]
+	ret, err := key.aead.Open(nil, nonce, data[encryptedHeaderSize:], []byte(k.target))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decrypt payload for %s", k.target)
+	}
+	return ret, nil
+}
+
+// encrypt seals the data with the active data key.
+func (k *dataKeys) encrypt(data []byte) ([]byte, error) {
+	k.mu.RLock()
+	key := k.mu.active
+	k.mu.RUnlock()
+
+	ret := make([]byte, encryptedHeaderSize, encryptedHeaderSize+len(data)+key.aead.Overhead())
+	copy(ret, encryptedMagic)
+	binary.BigEndian.PutUint32(ret[len(encryptedMagic):], key.id)
+	nonce := ret[len(encryptedMagic)+4 : encryptedHeaderSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return key.aead.Seal(ret, nonce, data, []byte(k.target)), nil
+}
+
+// load refreshes the data keys from the catalog.
+func (k *dataKeys) load(ctx context.Context) error {
+	byID := make(map[uint32]*dataKey)
+	var active *dataKey
+	err := retry.Retry(ctx, k.db, func(ctx context.Context) error {
+		clear(byID)
+		active = nil
+		rows, err := k.db.Query(ctx, k.sql.load, k.target)
+		if err != nil {
+			return errors.Wrap(err, k.sql.load)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			key := &dataKey{}
+			if err := rows.Scan(&id, &key.masterID, &key.wrapped); err != nil {
+				return errors.WithStack(err)
+			}
+			key.id = uint32(id)
+			byID[key.id] = key
+			active = key // Ordered by id.
+		}
+		return errors.WithStack(rows.Err())
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range byID {
+		master := k.masters.byID[key.masterID]
+		if master == nil {
+			return errors.Errorf(
+				"data key %d for %s is wrapped by unknown master key %s",
+				key.id, k.target, key.masterID)
+		}
+		raw, err := k.unwrap(master, key.id, key.wrapped)
+		if err != nil {
+			return err
+		}
+		key.aead, err = newAEAD(raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.mu.active = active
+	k.mu.byID = byID
+	return nil
+}
+
+// rewrap updates any data keys that are not wrapped by the active
+// master key. It returns the number of keys that were updated.
+func (k *dataKeys) rewrap(ctx context.Context) (int, error) {
+	if err := k.load(ctx); err != nil {
+		return 0, err
+	}
+	k.mu.RLock()
+	var stale []*dataKey
+	for _, key := range k.mu.byID {
+		if key.masterID != k.masters.active.id {
+			stale = append(stale, key)
+		}
+	}
+	k.mu.RUnlock()
+
+	for _, key := range stale {
+		raw, err := k.unwrap(k.masters.byID[key.masterID], key.id, key.wrapped)
+		if err != nil {
+			return 0, err
+		}
+		wrapped, err := k.wrap(k.masters.active, key.id, raw)
+		if err != nil {
+			return 0, err
+		}
+		if err := retry.Execute(ctx, k.db, k.sql.rewrap, k.target, int64(key.id),
+			k.masters.active.id, wrapped, key.masterID); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	return len(stale), k.load(ctx)
+}
+
+// rotate re-wraps the existing data keys and creates a new data key
+// that will be used for newly-staged mutations. It returns the id of
+// the new key.
+func (k *dataKeys) rotate(ctx context.Context) (uint32, error) {
+	if _, err := k.rewrap(ctx); err != nil {
+		return 0, err
+	}
+	next := k.activeID() + 1
+	if err := k.create(ctx, next); err != nil {
+		return 0, err
+	}
+	if err := k.load(ctx); err != nil {
+		return 0, err
+	}
+	return k.activeID(), nil
+}
+
+// unwrap decrypts a data key.
+func (k *dataKeys) unwrap(master *masterKey, id uint32, wrapped []byte) ([]byte, error) {
+	size := master.aead.NonceSize()
+	if len(wrapped) < size {
+		return nil, errors.Errorf("wrapped data key %d for %s is truncated", id, k.target)
+	}
+	ret, err := master.aead.Open(nil, wrapped[:size], wrapped[size:], k.wrapAAD(id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not unwrap data key %d for %s", id, k.target)
+	}
+	return ret, nil
+}
+
+// wrap encrypts a data key with a master key.
+func (k *dataKeys) wrap(master *masterKey, id uint32, raw []byte) ([]byte, error) {
+	nonce := make([]byte, master.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return master.aead.Seal(nonce, nonce, raw, k.wrapAAD(id)), nil
+}
+
+// wrapAAD binds a wrapped data key to its target and id.
+func (k *dataKeys) wrapAAD(id uint32) []byte {
+	return []byte(fmt.Sprintf("%s/%d", k.target, id))
+}
+
+// isEncrypted returns true if the payload was produced by encrypt.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// encryptedKeyID returns the id of the data key used to encrypt the
+// payload.
+func encryptedKeyID(data []byte) (uint32, bool) {
+	if !isEncrypted(data) || len(data) < encryptedHeaderSize {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(data[len(encryptedMagic):]), true
+}
+
+// decode reverses encode.
+func (s *stage) decode(ctx context.Context, data []byte) ([]byte, error) {
+	if isEncrypted(data) {
+		if s.keys == nil {
+			return nil, errors.Errorf(
+				"staged mutations for %s are encrypted, but no master keys were configured",
+				s.stage.Base)
+		}
+		var err error
+		data, err = s.keys.decrypt(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.decompress(ctx, data)
+}
+
+// encode compresses the payload and, if master keys have been
+// configured, encrypts it.
+func (s *stage) encode(data []byte) ([]byte, error) {
+	data, err := s.compress(data)
+	if err != nil || s.keys == nil || len(data) == 0 {
+		return data, err
+	}
+	return s.keys.encrypt(data)
+}
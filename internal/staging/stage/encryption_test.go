@@ -0,0 +1,151 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKey returns a base64-encoded key.
+func newTestKey(t *testing.T) string {
+	buf := make([]byte, encryptionKeySize)
+	_, err := rand.Read(buf)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	r := require.New(t)
+	first, second := newTestKey(t), newTestKey(t)
+
+	keys, err := parseMasterKeys("# comment\n" + first + "\n\n" + second + ",\n")
+	r.NoError(err)
+	r.Len(keys.byID, 2)
+	other, err := parseMasterKeys(first)
+	r.NoError(err)
+	r.Equal(other.active.id, keys.active.id)
+
+	_, err = parseMasterKeys("")
+	r.ErrorContains(err, "no master keys")
+	_, err = parseMasterKeys("not base64!")
+	r.ErrorContains(err, "decode")
+	_, err = parseMasterKeys(base64.StdEncoding.EncodeToString([]byte("short")))
+	r.ErrorContains(err, "must be 32 bytes")
+}
+
+// This test doesn't require a database, so the data keys are
+// constructed in memory.
+func TestEncryptionCodec(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	masters, err := parseMasterKeys(newTestKey(t))
+	r.NoError(err)
+	keys := &dataKeys{masters: masters, target: "target"}
+	keys.mu.byID = make(map[uint32]*dataKey)
+	addKey := func(id uint32) {
+		raw := make([]byte, encryptionKeySize)
+		_, err := rand.Read(raw)
+		r.NoError(err)
+		wrapped, err := keys.wrap(masters.active, id, raw)
+		r.NoError(err)
+		unwrapped, err := keys.unwrap(masters.active, id, wrapped)
+		r.NoError(err)
+		r.Equal(raw, unwrapped)
+		// A wrapped key cannot be moved to another id.
+		_, err = keys.unwrap(masters.active, id+1, wrapped)
+		r.Error(err)
+
+		aead, err := newAEAD(unwrapped)
+		r.NoError(err)
+		key := &dataKey{aead: aead, id: id, masterID: masters.active.id}
+		keys.mu.byID[id] = key
+		keys.mu.active = key
+	}
+	addKey(1)
+
+	s := newCodecStage(t, &Config{})
+	s.keys = keys
+	small := []byte(`{"pk":1}`)
+	large := []byte(`{"pk":1,"v":"` + strings.Repeat("x", 2*gzipMinSize) + `"}`)
+
+	for _, data := range [][]byte{small, large} {
+		enc, err := s.encode(data)
+		r.NoError(err)
+		r.True(isEncrypted(enc))
+		id, ok := encryptedKeyID(enc)
+		r.True(ok)
+		r.Equal(uint32(1), id)
+		r.NotContains(string(enc), `"pk"`)
+
+		dec, err := s.decode(ctx, enc)
+		r.NoError(err)
+		r.Equal(data, dec)
+
+		// The payload is bound to the target.
+		other := &stage{keys: &dataKeys{target: "other"}}
+		other.keys.mu.byID = keys.mu.byID
+		_, err = other.keys.decrypt(ctx, enc)
+		r.Error(err)
+	}
+
+	// Empty values are not encrypted.
+	enc, err := s.encode(nil)
+	r.NoError(err)
+	r.Nil(enc)
+
+	// Plaintext payloads, staged before encryption was enabled, remain
+	// readable.
+	dec, err := s.decode(ctx, small)
+	r.NoError(err)
+	r.Equal(small, dec)
+
+	// Rotating the data key leaves older payloads readable.
+	old, err := s.encode(small)
+	r.NoError(err)
+	addKey(2)
+	enc, err = s.encode(small)
+	r.NoError(err)
+	id, _ := encryptedKeyID(enc)
+	r.Equal(uint32(2), id)
+	dec, err = s.decode(ctx, old)
+	r.NoError(err)
+	r.Equal(small, dec)
+
+	// Re-encoding moves a payload to the active key.
+	enc, changed, err := s.reencode(ctx, old)
+	r.NoError(err)
+	r.True(changed)
+	id, _ = encryptedKeyID(enc)
+	r.Equal(uint32(2), id)
+	_, changed, err = s.reencode(ctx, enc)
+	r.NoError(err)
+	r.False(changed)
+	_, changed, err = s.reencode(ctx, []byte("null"))
+	r.NoError(err)
+	r.False(changed)
+
+	// Encrypted payloads cannot be read without keys.
+	_, err = newCodecStage(t, &Config{}).decode(ctx, enc)
+	r.ErrorContains(err, "no master keys")
+}
@@ -93,10 +93,13 @@ var _ types.BatchReader = (*stagingReader)(nil)
 
 func (r *stagingReader) Read(ctx *stopper.Context) (<-chan *types.BatchCursor, error) {
 	// Ensure all staging tables exist.
-	for _, table := range r.Group.Tables {
-		if _, err := r.Get(ctx, table); err != nil {
+	stages := make([]*stage, len(r.Group.Tables))
+	for idx, table := range r.Group.Tables {
+		s, err := r.Get(ctx, table)
+		if err != nil {
 			return nil, err
 		}
+		stages[idx] = s.(*stage)
 	}
 
 	// Set up a task to read data from each table.
@@ -105,7 +108,7 @@ func (r *stagingReader) Read(ctx *stopper.Context) (<-chan *types.BatchCursor, e
 		ch := make(chan *tableCursor, 2)
 		tableChans[idx] = ch
 		tableReader := newTableReader(
-			r.Bounds, r.db, r.FragmentSize, ch, r.stagingDB, target)
+			r.Bounds, r.db, r.FragmentSize, ch, stages[idx], target)
 		ctx.Go(func(ctx *stopper.Context) error {
 			tableReader.run(ctx)
 			return nil
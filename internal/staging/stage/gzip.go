@@ -34,7 +34,12 @@ func maybeGZip(data []byte) ([]byte, error) {
 	if len(data) <= gzipMinSize {
 		return data, nil
 	}
+	return gzipPayload(data)
+}
 
+// gzipPayload compresses the data, unless doing so would not reduce
+// its size.
+func gzipPayload(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
 	gzWriter := gzip.NewWriter(&buf)
 	if _, err := gzWriter.Write(data); err != nil {
@@ -49,10 +54,16 @@ func maybeGZip(data []byte) ([]byte, error) {
 	return data, nil
 }
 
+// isGzipped looks for the GZip magic numbers, which would not be
+// present in JSON.
+func isGzipped(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
 // maybeGunzip looks for GZip magic numbers and decompresses the data if
 // they're present. The magic numbers would not be present in JSON.
 func maybeGunzip(data []byte) ([]byte, error) {
-	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+	if !isGzipped(data) {
 		return data, nil
 	}
 	r, err := gzip.NewReader(bytes.NewReader(data))
@@ -23,10 +23,31 @@ import (
 )
 
 var (
+	stageBucketCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stage_bucket_count",
+		Help: "the number of time-bucketed staging tables for a target",
+	}, metrics.TableLabels)
+	stageBucketDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stage_bucket_drops_total",
+		Help: "the number of time-bucketed staging tables dropped when retiring mutations",
+	}, metrics.TableLabels)
+	stageCompressionRatio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "stage_compression_ratio",
+		Help:    "the compressed size of staged payloads relative to their original size",
+		Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+	}, metrics.TableLabels)
+	stageCompressSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stage_compress_seconds_total",
+		Help: "the CPU time spent compressing staged payloads",
+	}, metrics.TableLabels)
 	stageConsistencyErrors = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "stage_consistency_error_count",
 		Help: "the number of staging table rows with inconsistent source and apply times",
 	}, metrics.TableLabels)
+	stageDecompressSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stage_decompress_seconds_total",
+		Help: "the CPU time spent decompressing staged payloads",
+	}, metrics.TableLabels)
 	stageFilterAppliedDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "stage_filter_applied_duration_seconds",
 		Help:    "the SQL statement execution time to detect already-applied mutations",
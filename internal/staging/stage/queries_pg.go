@@ -0,0 +1,198 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+// This file contains the PostgreSQL versions of the staging queries.
+// Each template accepts the same parameters as its CockroachDB
+// counterpart, but avoids UPSERT, column families, and mutation
+// statements with a LIMIT clause. Set-returning functions
+// are evaluated after window functions in PostgreSQL, so WITH
+// ORDINALITY is used to number input rows.
+
+const tableSchemaPG = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+       nanos BIGINT NOT NULL,
+     logical BIGINT NOT NULL,
+         key TEXT NOT NULL,
+         mut BYTEA NOT NULL,
+      before BYTEA NULL,
+     applied BOOL NOT NULL DEFAULT false,
+  applied_at TIMESTAMPTZ NULL,
+    deletion BOOL NOT NULL DEFAULT false,
+  PRIMARY KEY (nanos, logical, key)
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (key) INCLUDE (applied)`
+
+const filterAppliedTemplatePG = `
+WITH ids (key, nanos, logical, idx) AS (
+  SELECT * FROM unnest($1::TEXT[], $2::BIGINT[], $3::BIGINT[]) WITH ORDINALITY)
+SELECT idx FROM ids
+JOIN %s
+USING (key, nanos, logical)
+WHERE applied
+`
+
+const markAppliedTemplatePG = `
+WITH t (key, nanos, logical) AS (
+  SELECT * FROM unnest($1::TEXT[], $2::BIGINT[], $3::BIGINT[]))
+INSERT INTO %s (key, nanos, logical, applied, applied_at, mut)
+SELECT t.key, t.nanos, t.logical, true, now(), '%s' FROM t
+ON CONFLICT (key, nanos, logical)
+DO UPDATE SET applied = true, applied_at = now()
+`
+
+const readTableTemplatePG = `
+SELECT nanos, logical, key, mut, before, deletion
+FROM %s
+WHERE (nanos, logical, key) > ($1::BIGINT, $2::BIGINT, COALESCE($3::TEXT, ''))
+AND (nanos, logical) < ($4::BIGINT, $5::BIGINT)
+AND NOT applied
+ORDER BY nanos, logical, key
+LIMIT $6
+`
+
+const retireTemplatePG = `
+WITH d AS (
+     DELETE FROM %[1]s
+      WHERE (nanos, logical, key) IN (
+            SELECT nanos, logical, key
+              FROM %[1]s
+             WHERE (nanos, logical) BETWEEN ($1, $2) AND ($3, $4) AND applied
+          ORDER BY nanos, logical
+             LIMIT $5)
+  RETURNING nanos, logical)
+SELECT nanos, logical
+  FROM d
+ ORDER BY nanos DESC, logical DESC
+ LIMIT 1`
+
+const stageTemplatePG = `
+INSERT INTO %s (nanos, logical, key, mut, before, deletion)
+SELECT * FROM unnest(
+       $1::BIGINT[],
+       $2::BIGINT[],
+       $3::TEXT[],
+       $4::BYTEA[],
+       $5::BYTEA[],
+       $6::BOOL[])
+ON CONFLICT DO NOTHING`
+
+const stageIfExistsTemplatePG = `
+WITH
+proposed (nanos, logical, key, mut, before, deletion, idx) AS (
+  SELECT * FROM unnest(
+    $1::BIGINT[],
+    $2::BIGINT[],
+    $3::TEXT[],
+    $4::BYTEA[],
+    $5::BYTEA[],
+    $6::BOOL[]) WITH ORDINALITY),
+existing AS (
+  SELECT DISTINCT proposed.key
+  FROM proposed
+  JOIN %[1]s existing
+  ON (proposed.key = existing.key AND NOT existing.applied)),
+action AS (
+  INSERT INTO %[1]s (nanos, logical, key, mut, before, deletion)
+  SELECT nanos, logical, key, mut, before, deletion
+  FROM proposed
+  JOIN existing USING (key)
+  ON CONFLICT (nanos, logical, key)
+  DO UPDATE SET mut = excluded.mut, before = excluded.before, deletion = excluded.deletion
+  RETURNING true)
+SELECT idx FROM proposed
+JOIN existing USING (key)
+`
+
+const unapplyTemplatePG = `
+UPDATE %[1]s
+   SET applied = false, applied_at = NULL
+ WHERE (nanos, logical, key) IN (
+       SELECT nanos, logical, key
+         FROM %[1]s
+        WHERE (nanos, logical) > ($1, $2) AND applied AND mut != '%[2]s'
+        LIMIT $3)`
+
+const bucketCatalogSchemaPG = `
+CREATE TABLE IF NOT EXISTS %s (
+       target TEXT NOT NULL,
+  start_nanos BIGINT NOT NULL,
+    end_nanos BIGINT NOT NULL,
+       bucket TEXT NOT NULL,
+  PRIMARY KEY (target, start_nanos)
+)`
+
+const unappliedKeysTemplatePG = `
+SELECT DISTINCT key FROM %s
+WHERE key IN (SELECT unnest($1::TEXT[])) AND NOT applied`
+
+const upsertTemplatePG = `
+INSERT INTO %s (nanos, logical, key, mut, before, deletion)
+SELECT * FROM unnest(
+       $1::BIGINT[],
+       $2::BIGINT[],
+       $3::TEXT[],
+       $4::BYTEA[],
+       $5::BYTEA[],
+       $6::BOOL[])
+ON CONFLICT (nanos, logical, key)
+DO UPDATE SET mut = excluded.mut, before = excluded.before, deletion = excluded.deletion`
+
+const readAppliedTemplatePG = `
+SELECT nanos, logical, key, mut, before, deletion
+FROM %s
+WHERE (nanos, logical, key) > ($1::BIGINT, $2::BIGINT, COALESCE($3::TEXT, ''))
+AND (nanos, logical) <= ($4::BIGINT, $5::BIGINT)
+AND applied AND mut != '%s'
+ORDER BY nanos, logical, key
+LIMIT $6
+`
+
+const keyCatalogSchemaPG = `
+CREATE TABLE IF NOT EXISTS %s (
+     target TEXT NOT NULL,
+     key_id BIGINT NOT NULL,
+  master_id TEXT NOT NULL,
+    wrapped BYTEA NOT NULL,
+ created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (target, key_id)
+)`
+
+const readPayloadsTemplatePG = `
+SELECT nanos, logical, key, mut, before
+FROM %s
+WHERE (nanos, logical, key) > ($1::BIGINT, $2::BIGINT, $3::TEXT)
+ORDER BY nanos, logical, key
+LIMIT $4
+`
+
+const writePayloadsTemplatePG = `
+WITH u (nanos, logical, key, mut, before) AS (
+  SELECT * FROM unnest($1::BIGINT[], $2::BIGINT[], $3::TEXT[], $4::BYTEA[], $5::BYTEA[]))
+UPDATE %s AS t SET mut = u.mut, before = u.before
+FROM u
+WHERE t.nanos = u.nanos AND t.logical = u.logical AND t.key = u.key
+`
+
+const dictCatalogSchemaPG = `
+CREATE TABLE IF NOT EXISTS %s (
+     target TEXT NOT NULL,
+    dict_id BIGINT NOT NULL,
+       dict BYTEA NOT NULL,
+ created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (target, dict_id)
+)`
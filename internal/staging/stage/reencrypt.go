@@ -0,0 +1,202 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"bytes"
+	"context"
+	"math"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// reencryptBatchSize is the number of rows that are read and rewritten
+// at a time.
+const reencryptBatchSize = 1_000
+
+const readPayloadsTemplate = `
+SELECT nanos, logical, key, mut, before
+FROM %s
+WHERE (nanos, logical, key) > ($1::INT8, $2::INT8, $3::STRING)
+ORDER BY nanos, logical, key
+LIMIT $4
+`
+
+const writePayloadsTemplate = `
+WITH u (nanos, logical, key, mut, before) AS (
+  SELECT unnest($1::INT8[]), unnest($2::INT8[]), unnest($3::STRING[]),
+         unnest($4::BYTES[]), unnest($5::BYTES[]))
+UPDATE %s AS t SET mut = u.mut, before = u.before
+FROM u
+WHERE t.nanos = u.nanos AND t.logical = u.logical AND t.key = u.key
+`
+
+// Reencrypt rewrites the staged mutations for the Stager's target so
+// that they are encrypted with the active data key. Plaintext rows,
+// which were staged before encryption was enabled, will be encrypted.
+// This allows older data keys to be discarded without re-staging
+// mutations. It returns the number of rows that were rewritten.
+func Reencrypt(ctx context.Context, stager types.Stager) (int, error) {
+	s, err := asStage(stager)
+	if err != nil {
+		return 0, err
+	}
+	buckets, err := s.bucketsIn(ctx, math.MinInt64, math.MaxInt64, true)
+	if err != nil {
+		return 0, err
+	}
+	var ret int
+	for _, b := range buckets {
+		count, err := s.reencryptBucket(ctx, b)
+		if code, ok := s.stagingDB.ErrCode(err); ok && code == "42P01" /* undefined_table */ {
+			// The bucket was dropped by a concurrent call to Retire.
+			continue
+		}
+		ret += count
+		if err != nil {
+			return ret, err
+		}
+	}
+	return ret, nil
+}
+
+// RewrapKeys re-wraps any of the data keys for the Stager's target that
+// were wrapped by an older master key. Once this has been done for all
+// targets, the older master keys may be discarded. It returns the
+// number of data keys that were updated.
+func RewrapKeys(ctx context.Context, stager types.Stager) (int, error) {
+	s, err := asStage(stager)
+	if err != nil {
+		return 0, err
+	}
+	return s.keys.rewrap(ctx)
+}
+
+// RotateKeys re-wraps the data keys for the Stager's target and
+// creates a new data key that will be used to encrypt newly-staged
+// mutations. Existing rows remain readable. It returns the id of the
+// new data key.
+func RotateKeys(ctx context.Context, stager types.Stager) (uint32, error) {
+	s, err := asStage(stager)
+	if err != nil {
+		return 0, err
+	}
+	return s.keys.rotate(ctx)
+}
+
+// asStage ensures that the Stager supports encryption.
+func asStage(stager types.Stager) (*stage, error) {
+	s, ok := stager.(*stage)
+	if !ok {
+		return nil, errors.Errorf("unsupported Stager type %T", stager)
+	}
+	if s.keys == nil {
+		return nil, errors.New("no staging encryption keys were configured")
+	}
+	return s, nil
+}
+
+// reencryptBucket pages through the rows in a bucket and rewrites any
+// payloads that do not use the active data key.
+func (s *stage) reencryptBucket(ctx context.Context, b *bucket) (int, error) {
+	var ret int
+	cursorNanos, cursorLogical, cursorKey := int64(math.MinInt64), int64(math.MinInt64), ""
+	for {
+		var nanos, logical []int64
+		var keys []string
+		var muts, befores [][]byte
+		var lastNanos, lastLogical int64
+		var lastKey string
+		var read int
+		err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+			nanos, logical, keys, muts, befores = nil, nil, nil, nil, nil
+			read = 0
+			rows, err := s.stagingDB.Query(ctx, b.sql.readPayloads,
+				cursorNanos, cursorLogical, cursorKey, reencryptBatchSize)
+			if err != nil {
+				return errors.Wrap(err, b.sql.readPayloads)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var mut, before []byte
+				if err := rows.Scan(&lastNanos, &lastLogical, &lastKey, &mut, &before); err != nil {
+					return errors.WithStack(err)
+				}
+				read++
+				// Stub entries are compared by value in SQL.
+				if bytes.Equal(mut, stubSentinel) {
+					continue
+				}
+				mut, mutChanged, err := s.reencode(ctx, mut)
+				if err != nil {
+					return err
+				}
+				before, beforeChanged, err := s.reencode(ctx, before)
+				if err != nil {
+					return err
+				}
+				if !mutChanged && !beforeChanged {
+					continue
+				}
+				nanos = append(nanos, lastNanos)
+				logical = append(logical, lastLogical)
+				keys = append(keys, lastKey)
+				muts = append(muts, mut)
+				befores = append(befores, before)
+			}
+			return errors.WithStack(rows.Err())
+		})
+		if err != nil {
+			return ret, err
+		}
+		if len(keys) > 0 {
+			if err := retry.Execute(ctx, s.stagingDB, b.sql.writePayloads,
+				nanos, logical, keys, muts, befores); err != nil {
+				return ret, errors.Wrap(err, b.sql.writePayloads)
+			}
+			ret += len(keys)
+			log.Tracef("re-encrypted %d rows in %s", ret, b.table)
+		}
+		if read < reencryptBatchSize {
+			return ret, nil
+		}
+		cursorNanos, cursorLogical, cursorKey = lastNanos, lastLogical, lastKey
+	}
+}
+
+// reencode encrypts the payload with the active data key. It returns
+// false if the payload does not need to be rewritten.
+func (s *stage) reencode(ctx context.Context, data []byte) ([]byte, bool, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return data, false, nil
+	}
+	if id, ok := encryptedKeyID(data); ok {
+		if id == s.keys.activeID() {
+			return data, false, nil
+		}
+		var err error
+		data, err = s.keys.decrypt(ctx, data)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	ret, err := s.keys.encrypt(data)
+	return ret, err == nil, err
+}
@@ -22,9 +22,12 @@ package stage
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -62,34 +65,62 @@ type stage struct {
 	stage      *ident.Hinted[ident.Table] // The staging table that holds the mutations.
 	stagingDB  *types.StagingPool
 	retireFrom notify.Var[hlc.Time] // Makes subsequent calls to Retire() a bit faster.
+	keys       *dataKeys            // Nil if encryption is disabled.
+	zstd       *zstdCodec
 
-	consistencyError prometheus.Gauge
-	filterApplied    prometheus.Observer
-	filterCount      prometheus.Counter
-	markDuration     prometheus.Observer
-	retireDuration   prometheus.Observer
-	retireError      prometheus.Counter
-	selectCount      prometheus.Counter
-	selectDuration   prometheus.Observer
-	selectError      prometheus.Counter
-	staleCount       prometheus.Gauge
-	stageCount       prometheus.Counter
-	stageDupes       prometheus.Counter
-	stageDuration    prometheus.Observer
-	stageError       prometheus.Counter
-
-	// Compute SQL fragments exactly once on startup.
-	sql struct {
-		filterApplied string // Select mutation keys that have been applied.
-		markApplied   string // Mark mutations as having been applied.
-		retire        string // Delete a batch of staged mutations.
-		stage         string // General-purpose upsert into staging table.
-		stageExists   string // Stage a mutation if one already exists.
-		unapplied     string // Count stale, unapplied mutations.
-		unappliedAOST string // Count stale, unapplied mutations.
+	bucketCount       prometheus.Gauge
+	bucketDrops       prometheus.Counter
+	compressRatio     prometheus.Observer
+	compressSeconds   prometheus.Counter
+	consistencyError  prometheus.Gauge
+	decompressSeconds prometheus.Counter
+	filterApplied     prometheus.Observer
+	filterCount       prometheus.Counter
+	markDuration      prometheus.Observer
+	retireDuration    prometheus.Observer
+	retireError       prometheus.Counter
+	selectCount       prometheus.Counter
+	selectDuration    prometheus.Observer
+	selectError       prometheus.Counter
+	staleCount        prometheus.Gauge
+	stageCount        prometheus.Counter
+	stageDupes        prometheus.Counter
+	stageDuration     prometheus.Observer
+	stageError        prometheus.Counter
+
+	buckets struct {
+		sync.RWMutex
+		data []*bucket // Ordered by time.
+	}
+	// The catalog of buckets, if bucketing is enabled.
+	bucketTable ident.Table
+	bucketSQL   struct {
+		delete string
+		insert string
+		load   string
 	}
 }
 
+// stageSQL contains the SQL statements that operate upon a single
+// staging table. These are computed once per table.
+type stageSQL struct {
+	filterApplied string // Select mutation keys that have been applied.
+	hasUnapplied  string // Determine if a table has any unapplied mutations.
+	markApplied   string // Mark mutations as having been applied.
+	readApplied   string // Read a page of applied mutations.
+	readPayloads  string // Read a page of raw payloads to re-encrypt.
+	readTable     string // Read a page of unapplied mutations.
+	retire        string // Delete a batch of staged mutations.
+	stage         string // General-purpose upsert into staging table.
+	stageExists   string // Stage a mutation if one already exists.
+	unapply       string // Mark a batch of mutations as unapplied.
+	unapplied     string // Count stale, unapplied mutations.
+	unappliedAOST string // Count stale, unapplied mutations.
+	unappliedKeys string // Select keys that have unapplied mutations.
+	upsert        string // Unconditionally stage mutations.
+	writePayloads string // Replace re-encrypted payloads.
+}
+
 var _ types.Stager = (*stage)(nil)
 
 const tableSchema = `
@@ -114,82 +145,50 @@ CREATE TABLE IF NOT EXISTS %[1]s (
 func (f *factory) newStage(target ident.Table) (*stage, error) {
 	ctx := f.stop
 	table := stagingTable(f.stagingDB, target)
-	keyIdx := ident.New(table.Table().Raw() + "_key_applied")
-	// Try to create the staging table with a helper virtual column. We
-	// never query for it, so it should have essentially no cost.
-	if err := retry.Execute(ctx, f.db, fmt.Sprintf(tableSchema, table,
-		`source_time TIMESTAMPTZ AS (to_timestamp(nanos::float/1e9)) VIRTUAL,`,
-		keyIdx)); err != nil {
 
-		// Old versions of CRDB don't know about to_timestamp(). Try
-		// again without the helper column.
-		if code, ok := f.db.ErrCode(err); ok && code == "42883" /* unknown function */ {
-			err = retry.Execute(ctx, f.db, fmt.Sprintf(tableSchema, table, "", keyIdx))
-		}
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
+	labels := metrics.TableValues(target)
+	s := &stage{
+		cfg:               f.cfg,
+		stage:             f.db.HintNoFTS(table),
+		stagingDB:         f.db,
+		bucketCount:       stageBucketCount.WithLabelValues(labels...),
+		bucketDrops:       stageBucketDrops.WithLabelValues(labels...),
+		compressRatio:     stageCompressionRatio.WithLabelValues(labels...),
+		compressSeconds:   stageCompressSeconds.WithLabelValues(labels...),
+		consistencyError:  stageConsistencyErrors.WithLabelValues(labels...),
+		decompressSeconds: stageDecompressSeconds.WithLabelValues(labels...),
+		filterApplied:     stageFilterAppliedDuration.WithLabelValues(labels...),
+		filterCount:       stageFilterCount.WithLabelValues(labels...),
+		markDuration:      stageMarkDuration.WithLabelValues(labels...),
+		retireDuration:    stageRetireDurations.WithLabelValues(labels...),
+		retireError:       stageRetireErrors.WithLabelValues(labels...),
+		selectCount:       stageSelectCount.WithLabelValues(labels...),
+		selectDuration:    stageSelectDurations.WithLabelValues(labels...),
+		selectError:       stageSelectErrors.WithLabelValues(labels...),
+		staleCount:        stageStaleMutations.WithLabelValues(labels...),
+		stageCount:        stageCount.WithLabelValues(labels...),
+		stageDupes:        stageDuplicateCount.WithLabelValues(labels...),
+		stageDuration:     stageDuration.WithLabelValues(labels...),
+		stageError:        stageErrors.WithLabelValues(labels...),
 	}
 
-	// Transparently upgrade older staging tables. This avoids needing
-	// to add a breaking change to the Versions slice.
-	log.Tracef("upgrading schema for %s", table)
-	if err := retry.Execute(ctx, f.db, fmt.Sprintf(`
-ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS before BYTES NULL
-`, table)); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	if err := retry.Execute(ctx, f.db, fmt.Sprintf(`
-CREATE INDEX IF NOT EXISTS %[1]s ON %[2]s (key) STORING (applied)
-`, keyIdx, table)); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	// We're not going to worry about trying to backfill this, since
-	// old, applied mutations are retired on a regular basis.
-	if err := retry.Execute(ctx, f.db, fmt.Sprintf(`
-ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied_at TIMESTAMPTZ NULL
-`, table)); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	if err := retry.Execute(ctx, f.db, fmt.Sprintf(`
-ALTER TABLE %s ADD COLUMN IF NOT EXISTS deletion BOOL NULL
-`, table)); err != nil {
-		return nil, errors.WithStack(err)
+	if err := s.initBuckets(ctx); err != nil {
+		return nil, err
 	}
-	log.Tracef("completed schema upgrades for %s", table)
 
-	labels := metrics.TableValues(target)
-	s := &stage{
-		cfg:              f.cfg,
-		stage:            f.db.HintNoFTS(table),
-		stagingDB:        f.db,
-		consistencyError: stageConsistencyErrors.WithLabelValues(labels...),
-		filterApplied:    stageFilterAppliedDuration.WithLabelValues(labels...),
-		filterCount:      stageFilterCount.WithLabelValues(labels...),
-		markDuration:     stageMarkDuration.WithLabelValues(labels...),
-		retireDuration:   stageRetireDurations.WithLabelValues(labels...),
-		retireError:      stageRetireErrors.WithLabelValues(labels...),
-		selectCount:      stageSelectCount.WithLabelValues(labels...),
-		selectDuration:   stageSelectDurations.WithLabelValues(labels...),
-		selectError:      stageSelectErrors.WithLabelValues(labels...),
-		staleCount:       stageStaleMutations.WithLabelValues(labels...),
-		stageCount:       stageCount.WithLabelValues(labels...),
-		stageDupes:       stageDuplicateCount.WithLabelValues(labels...),
-		stageDuration:    stageDuration.WithLabelValues(labels...),
-		stageError:       stageErrors.WithLabelValues(labels...),
+	if f.cfg.masterKeys != nil {
+		var err error
+		s.keys, err = newDataKeys(ctx, f.cfg, f.db, table.Schema(), table.Raw())
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Prevent these hot-path queries from being planned with a full
-	// table scan if statistics are stale.
-	tableHinted := f.db.HintNoFTS(table)
-	s.sql.filterApplied = fmt.Sprintf(filterAppliedTemplate, tableHinted)
-	s.sql.markApplied = fmt.Sprintf(markAppliedTemplate, tableHinted, stubSentinel)
-	s.sql.retire = fmt.Sprintf(retireTemplate, tableHinted)
-	s.sql.stage = fmt.Sprintf(stageTemplate, tableHinted)
-	s.sql.stageExists = fmt.Sprintf(stageIfExistsTemplate, tableHinted)
-	s.sql.unapplied = fmt.Sprintf(countTemplate, tableHinted, "")
-	s.sql.unappliedAOST = fmt.Sprintf(countTemplate, tableHinted,
-		"AS OF SYSTEM TIME follower_read_timestamp()")
+	var err error
+	s.zstd, err = newZstdCodec(ctx, f.cfg, f.db, table.Schema(), table.Raw(), s.keys)
+	if err != nil {
+		return nil, err
+	}
 
 	// Report unapplied mutations on a periodic basis.
 	ctx.Go(func(ctx *stopper.Context) error {
@@ -264,6 +263,114 @@ ALTER TABLE %s ADD COLUMN IF NOT EXISTS deletion BOOL NULL
 	return s, nil
 }
 
+// createStagingTable creates a staging table using the dialect of the
+// staging database.
+func createStagingTable(ctx context.Context, db *types.StagingPool, table ident.Table) error {
+	keyIdx := ident.New(table.Table().Raw() + "_key_applied")
+	switch db.Product {
+	case types.ProductCockroachDB:
+		return createCockroachTable(ctx, db, table, keyIdx)
+	case types.ProductPostgreSQL:
+		return errors.WithStack(
+			retry.Execute(ctx, db, fmt.Sprintf(tableSchemaPG, table, keyIdx)))
+	default:
+		return errors.Errorf("unsupported staging product %s", db.Product)
+	}
+}
+
+// createCockroachTable creates the staging table and upgrades any
+// staging tables created by older versions of Replicator.
+func createCockroachTable(
+	ctx context.Context, db *types.StagingPool, table ident.Table, keyIdx ident.Ident,
+) error {
+	// Try to create the staging table with a helper virtual column. We
+	// never query for it, so it should have essentially no cost.
+	if err := retry.Execute(ctx, db, fmt.Sprintf(tableSchema, table,
+		`source_time TIMESTAMPTZ AS (to_timestamp(nanos::float/1e9)) VIRTUAL,`,
+		keyIdx)); err != nil {
+
+		// Old versions of CRDB don't know about to_timestamp(). Try
+		// again without the helper column.
+		if code, ok := db.ErrCode(err); ok && code == "42883" /* unknown function */ {
+			err = retry.Execute(ctx, db, fmt.Sprintf(tableSchema, table, "", keyIdx))
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// Transparently upgrade older staging tables. This avoids needing
+	// to add a breaking change to the Versions slice.
+	log.Tracef("upgrading schema for %s", table)
+	if err := retry.Execute(ctx, db, fmt.Sprintf(`
+ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS before BYTES NULL
+`, table)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := retry.Execute(ctx, db, fmt.Sprintf(`
+CREATE INDEX IF NOT EXISTS %[1]s ON %[2]s (key) STORING (applied)
+`, keyIdx, table)); err != nil {
+		return errors.WithStack(err)
+	}
+	// We're not going to worry about trying to backfill this, since
+	// old, applied mutations are retired on a regular basis.
+	if err := retry.Execute(ctx, db, fmt.Sprintf(`
+ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied_at TIMESTAMPTZ NULL
+`, table)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := retry.Execute(ctx, db, fmt.Sprintf(`
+ALTER TABLE %s ADD COLUMN IF NOT EXISTS deletion BOOL NULL
+`, table)); err != nil {
+		return errors.WithStack(err)
+	}
+	log.Tracef("completed schema upgrades for %s", table)
+	return nil
+}
+
+// newStageSQL computes the SQL statements for a staging table.
+func newStageSQL(db *types.StagingPool, table ident.Table) stageSQL {
+	var ret stageSQL
+	// Prevent these hot-path queries from being planned with a full
+	// table scan if statistics are stale.
+	tableHinted := db.HintNoFTS(table)
+	ret.hasUnapplied = fmt.Sprintf(hasUnappliedTemplate, table)
+	if db.Product == types.ProductPostgreSQL {
+		ret.filterApplied = fmt.Sprintf(filterAppliedTemplatePG, tableHinted)
+		ret.markApplied = fmt.Sprintf(markAppliedTemplatePG, tableHinted, stubSentinel)
+		ret.readApplied = fmt.Sprintf(readAppliedTemplatePG, table, stubSentinel)
+		ret.readPayloads = fmt.Sprintf(readPayloadsTemplatePG, table)
+		ret.readTable = fmt.Sprintf(readTableTemplatePG, table)
+		ret.retire = fmt.Sprintf(retireTemplatePG, tableHinted)
+		ret.stage = fmt.Sprintf(stageTemplatePG, tableHinted)
+		ret.stageExists = fmt.Sprintf(stageIfExistsTemplatePG, tableHinted)
+		ret.unapply = fmt.Sprintf(unapplyTemplatePG, tableHinted, stubSentinel)
+		ret.unapplied = fmt.Sprintf(countTemplate, tableHinted, "")
+		// PostgreSQL has no follower reads.
+		ret.unappliedAOST = ret.unapplied
+		ret.unappliedKeys = fmt.Sprintf(unappliedKeysTemplatePG, tableHinted)
+		ret.upsert = fmt.Sprintf(upsertTemplatePG, tableHinted)
+		ret.writePayloads = fmt.Sprintf(writePayloadsTemplatePG, table)
+	} else {
+		ret.filterApplied = fmt.Sprintf(filterAppliedTemplate, tableHinted)
+		ret.markApplied = fmt.Sprintf(markAppliedTemplate, tableHinted, stubSentinel)
+		ret.readApplied = fmt.Sprintf(readAppliedTemplate, table, stubSentinel)
+		ret.readPayloads = fmt.Sprintf(readPayloadsTemplate, table)
+		ret.readTable = fmt.Sprintf(readTableTemplate, table)
+		ret.retire = fmt.Sprintf(retireTemplate, tableHinted)
+		ret.stage = fmt.Sprintf(stageTemplate, tableHinted)
+		ret.stageExists = fmt.Sprintf(stageIfExistsTemplate, tableHinted)
+		ret.unapply = fmt.Sprintf(unapplyTemplate, tableHinted, stubSentinel)
+		ret.unapplied = fmt.Sprintf(countTemplate, tableHinted, "")
+		ret.unappliedAOST = fmt.Sprintf(countTemplate, tableHinted,
+			"AS OF SYSTEM TIME follower_read_timestamp()")
+		ret.unappliedKeys = fmt.Sprintf(unappliedKeysTemplate, tableHinted)
+		ret.upsert = fmt.Sprintf(upsertTemplate, tableHinted)
+		ret.writePayloads = fmt.Sprintf(writePayloadsTemplate, table)
+	}
+	return ret
+}
+
 const countTemplate = `
 SELECT count(*) FROM %s %s
 WHERE (nanos, logical) < ($1, $2) AND NOT applied
@@ -274,18 +381,30 @@ WHERE (nanos, logical) < ($1, $2) AND NOT applied
 func (s *stage) CountUnapplied(
 	ctx context.Context, db types.StagingQuerier, before hlc.Time, aost bool,
 ) (int, error) {
-	var q string
-	if aost {
-		q = s.sql.unappliedAOST
-	} else {
-		q = s.sql.unapplied
+	buckets, err := s.bucketsIn(ctx, math.MinInt64, before.Nanos(), false)
+	if err != nil {
+		return 0, err
 	}
 
 	var ret int
-	err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
-		return db.QueryRow(ctx, q, before.Nanos(), before.Logical()).Scan(&ret)
-	})
-	return ret, errors.Wrap(err, q)
+	for _, b := range buckets {
+		var q string
+		if aost {
+			q = b.sql.unappliedAOST
+		} else {
+			q = b.sql.unapplied
+		}
+
+		var count int
+		err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+			return db.QueryRow(ctx, q, before.Nanos(), before.Logical()).Scan(&count)
+		})
+		if err != nil {
+			return 0, errors.Wrap(err, q)
+		}
+		ret += count
+	}
+	return ret, nil
 }
 
 // This query returns the input indices of mutations that have already
@@ -309,28 +428,42 @@ func (s *stage) FilterApplied(
 ) ([]types.Mutation, error) {
 	start := time.Now()
 
-	keys := make([]string, len(muts))
-	nanos := make([]int64, len(muts))
-	logical := make([]int, len(muts))
-	for idx, mut := range muts {
-		keys[idx] = string(mut.Key)
-		nanos[idx] = mut.Time.Nanos()
-		logical[idx] = mut.Time.Logical()
-	}
-
-	rows, err := db.Query(ctx, s.sql.filterApplied, keys, nanos, logical)
+	// Mutations that don't belong to any bucket cannot have been
+	// applied, so we don't need to create buckets for them.
+	groups, err := s.group(ctx, muts, false)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 
 	applied := make(map[int]bool, len(muts))
-	for rows.Next() {
-		var idx int
-		if err := rows.Scan(&idx); err != nil {
+	for _, g := range groups {
+		keys := make([]string, len(g.muts))
+		nanos := make([]int64, len(g.muts))
+		logical := make([]int, len(g.muts))
+		for idx, mut := range g.muts {
+			keys[idx] = string(mut.Key)
+			nanos[idx] = mut.Time.Nanos()
+			logical[idx] = mut.Time.Logical()
+		}
+
+		rows, err := db.Query(ctx, g.sql.filterApplied, keys, nanos, logical)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for rows.Next() {
+			var idx int
+			if err := rows.Scan(&idx); err != nil {
+				rows.Close()
+				return nil, errors.WithStack(err)
+			}
+			// Returned indices are 1-based.
+			applied[g.indices[idx-1]] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
 			return nil, errors.WithStack(err)
 		}
-		// Returned indices are 1-based.
-		applied[idx-1] = true
 	}
 
 	var ret []types.Mutation
@@ -367,6 +500,16 @@ SELECT unnest($1::INT[]),
        unnest($6::BOOL[])
 ON CONFLICT DO NOTHING`
 
+// This query unconditionally stages mutations.
+const upsertTemplate = `
+UPSERT INTO %s (nanos, logical, key, mut, before, deletion)
+SELECT unnest($1::INT[]),
+       unnest($2::INT[]),
+       unnest($3::STRING[]),
+       unnest($4::BYTES[]),
+       unnest($5::BYTES[]),
+       unnest($6::BOOL[])`
+
 // Stage implements [types.Stager].
 func (s *stage) Stage(
 	ctx context.Context, db types.StagingQuerier, mutations []types.Mutation,
@@ -375,25 +518,16 @@ func (s *stage) Stage(
 
 	mutations = msort.UniqueByTimeKey(mutations)
 
-	// If we're working with a pool, and not a transaction, we'll stage
-	// the data in a concurrent manner.
-	var err error
-	if _, isPool := db.(*types.StagingPool); isPool {
-		eg, errCtx := errgroup.WithContext(ctx)
-		err = batches.Batch(len(mutations), func(begin, end int) error {
-			eg.Go(func() error {
-				return s.stageOneBatch(errCtx, db, mutations[begin:end])
-			})
-			return nil
-		})
-		if err != nil {
-			return err
+	_, isPool := db.(*types.StagingPool)
+	err := s.stageGroups(ctx, db, mutations, isPool)
+	// A bucket may have been retired by another instance of
+	// Replicator. We'll refresh the list of buckets and try again,
+	// unless we're in a transaction that has been aborted.
+	if code, ok := s.stagingDB.ErrCode(err); ok && isPool && s.bucketed() &&
+		code == "42P01" /* undefined_table */ {
+		if err = s.loadBuckets(ctx); err == nil {
+			err = s.stageGroups(ctx, db, mutations, isPool)
 		}
-		err = eg.Wait()
-	} else {
-		err = batches.Batch(len(mutations), func(begin, end int) error {
-			return s.stageOneBatch(ctx, db, mutations[begin:end])
-		})
 	}
 
 	if err != nil {
@@ -412,6 +546,43 @@ func (s *stage) Stage(
 	return nil
 }
 
+// stageGroups stages the mutations into the buckets that contain them.
+// If concurrent is true, the batches will be staged in parallel.
+func (s *stage) stageGroups(
+	ctx context.Context, db types.StagingQuerier, mutations []types.Mutation, concurrent bool,
+) error {
+	groups, err := s.group(ctx, mutations, true)
+	if err != nil {
+		return err
+	}
+
+	// If we're working with a pool, and not a transaction, we'll stage
+	// the data in a concurrent manner.
+	if concurrent {
+		eg, errCtx := errgroup.WithContext(ctx)
+		for _, g := range groups {
+			err := batches.Batch(len(g.muts), func(begin, end int) error {
+				eg.Go(func() error {
+					return s.stageOneBatch(errCtx, db, g.bucket, g.muts[begin:end])
+				})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return eg.Wait()
+	}
+	for _, g := range groups {
+		if err := batches.Batch(len(g.muts), func(begin, end int) error {
+			return s.stageOneBatch(ctx, db, g.bucket, g.muts[begin:end])
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const stageIfExistsTemplate = `
 WITH
 proposed (idx, nanos, logical, key, mut, before, deletion) AS ( 
@@ -442,12 +613,20 @@ JOIN existing USING (key)
 func (s *stage) StageIfExists(
 	ctx context.Context, db types.StagingQuerier, mutations []types.Mutation,
 ) ([]types.Mutation, error) {
+	buckets, err := s.bucketsIn(ctx, math.MinInt64, math.MaxInt64, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(buckets) != 1 {
+		return s.stageIfExistsBucketed(ctx, db, buckets, mutations)
+	}
+
 	nanos, logical, keys, jsons, befores, deletions, err := s.packArgs(ctx, mutations)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := db.Query(ctx, s.sql.stageExists,
+	rows, err := db.Query(ctx, buckets[0].sql.stageExists,
 		nanos, logical, keys, jsons, befores, deletions)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -479,6 +658,73 @@ func (s *stage) StageIfExists(
 	return ret, nil
 }
 
+// This query returns the keys which have unapplied mutations.
+const unappliedKeysTemplate = `
+SELECT DISTINCT key FROM %s
+WHERE key IN (SELECT unnest($1::STRING[])) AND NOT applied`
+
+// stageIfExistsBucketed implements StageIfExists when the unapplied
+// mutations for a key may be spread across multiple buckets.
+func (s *stage) stageIfExistsBucketed(
+	ctx context.Context, db types.StagingQuerier, buckets []*bucket, mutations []types.Mutation,
+) ([]types.Mutation, error) {
+	keys := make([]string, len(mutations))
+	for idx, mut := range mutations {
+		keys[idx] = string(mut.Key)
+	}
+
+	existing := make(map[string]bool)
+	for _, b := range buckets {
+		rows, err := db.Query(ctx, b.sql.unappliedKeys, keys)
+		if err != nil {
+			return nil, errors.Wrap(err, b.sql.unappliedKeys)
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, errors.WithStack(err)
+			}
+			existing[key] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// We want to return a new slice and not mangle the input in case
+	// the caller needs to re-use the input (e.g. FK retries).
+	var toStage []types.Mutation
+	ret := make([]types.Mutation, 0, len(mutations))
+	for _, mut := range mutations {
+		if existing[string(mut.Key)] {
+			toStage = append(toStage, mut)
+		} else {
+			ret = append(ret, mut)
+		}
+	}
+	if len(toStage) == 0 {
+		return ret, nil
+	}
+
+	groups, err := s.group(ctx, toStage, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		nanos, logical, keys, jsons, befores, deletions, err := s.packArgs(ctx, g.muts)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.Exec(ctx, g.sql.upsert,
+			nanos, logical, keys, jsons, befores, deletions); err != nil {
+			return nil, errors.Wrap(err, g.sql.upsert)
+		}
+	}
+	return ret, nil
+}
+
 // packArgs converts a slice of mutations into the various slices that
 // we'll send to the staging database.
 func (s *stage) packArgs(
@@ -515,12 +761,12 @@ func (s *stage) packArgs(
 				logical[idx] = mut.Time.Logical()
 				keys[idx] = string(mut.Key)
 				deletions[idx] = mut.IsDelete()
-				befores[idx], err = maybeGZip(mut.Before)
+				befores[idx], err = s.encode(mut.Before)
 				if err != nil {
 					return err
 				}
 				if mut.HasData() {
-					jsons[idx], err = maybeGZip(mut.Data)
+					jsons[idx], err = s.encode(mut.Data)
 					if err != nil {
 						return err
 					}
@@ -536,17 +782,17 @@ func (s *stage) packArgs(
 	return
 }
 
-// stageOneBatch appends the mutations to the staging table.
+// stageOneBatch appends the mutations to the bucket's staging table.
 func (s *stage) stageOneBatch(
-	ctx context.Context, db types.StagingQuerier, mutations []types.Mutation,
+	ctx context.Context, db types.StagingQuerier, b *bucket, mutations []types.Mutation,
 ) error {
 	nanos, logical, keys, jsons, befores, deletions, err := s.packArgs(ctx, mutations)
 	if err != nil {
 		return err
 	}
-	tag, err := db.Exec(ctx, s.sql.stage, nanos, logical, keys, jsons, befores, deletions)
+	tag, err := db.Exec(ctx, b.sql.stage, nanos, logical, keys, jsons, befores, deletions)
 	if err != nil {
-		return errors.Wrap(err, s.sql.stage)
+		return errors.Wrap(err, b.sql.stage)
 	}
 
 	// Track re-delivered mutations. Some small number are normal since
@@ -607,22 +853,27 @@ func (s *stage) MarkApplied(
 		// the memory being used during this step in the case there
 		// are millions or more rows.
 		if err := batches.Window(s.cfg.MarkAppliedLimit, len(muts), func(begin, end int) error {
-			lenWindow := end - begin
-			keys := make([]json.RawMessage, lenWindow)
-			nanos := make([]int64, lenWindow)
-			logical := make([]int, lenWindow)
-			for idx, mut := range muts[begin:end] {
-				keys[idx] = mut.Key
-				nanos[idx] = mut.Time.Nanos()
-				logical[idx] = mut.Time.Logical()
-			}
-
-			tag, err := db.Exec(ctx, s.sql.markApplied, keys, nanos, logical)
+			groups, err := s.group(ctx, muts[begin:end], true)
 			if err != nil {
-				return errors.Wrap(err, s.sql.markApplied)
+				return err
 			}
+			for _, g := range groups {
+				keys := make([]json.RawMessage, len(g.muts))
+				nanos := make([]int64, len(g.muts))
+				logical := make([]int, len(g.muts))
+				for idx, mut := range g.muts {
+					keys[idx] = mut.Key
+					nanos[idx] = mut.Time.Nanos()
+					logical[idx] = mut.Time.Logical()
+				}
+
+				tag, err := db.Exec(ctx, g.sql.markApplied, keys, nanos, logical)
+				if err != nil {
+					return errors.Wrap(err, g.sql.markApplied)
+				}
 
-			log.Tracef("MarkApplied: %s marked %d mutations", s.stage, tag.RowsAffected())
+				log.Tracef("MarkApplied: %s marked %d mutations", g.table, tag.RowsAffected())
+			}
 			return nil
 		}); err != nil {
 			return err
@@ -645,6 +896,103 @@ func (s *stage) MarkApplied(
 	})
 }
 
+// readAppliedPageSize is the number of mutations that ReadApplied will
+// load from a bucket in a single query.
+const readAppliedPageSize = 10000
+
+// Basic pagination-style query.
+//   - ($1, $2, $3): Start position, nanos, logical key
+//   - ($4, $5): Inclusive end position nanos, logical
+//   - $6: Row limit
+const readAppliedTemplate = `
+SELECT nanos, logical, key, mut, before, deletion
+FROM %s
+WHERE (nanos, logical, key) > ($1::INT8, $2::INT8, COALESCE($3::STRING, ''))
+AND (nanos, logical) <= ($4::INT8, $5::INT8)
+AND applied AND mut != '%s'
+ORDER BY nanos, logical, key
+LIMIT $6
+`
+
+// ReadApplied implements [types.Stager].
+func (s *stage) ReadApplied(
+	ctx context.Context, db types.StagingQuerier, from, end hlc.Time, fn func([]types.Mutation) error,
+) error {
+	buckets, err := s.bucketsIn(ctx, from.Nanos(), end.Nanos(), s.bucketed())
+	if err != nil {
+		return err
+	}
+	for _, b := range buckets {
+		cursor := from
+		var cursorKey json.RawMessage
+		for {
+			var page []types.Mutation
+			err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+				var err error
+				page, err = s.readAppliedPage(ctx, db, b, cursor, cursorKey, end, readAppliedPageSize)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(page) == 0 {
+				break
+			}
+			if err := fn(page); err != nil {
+				return err
+			}
+			if len(page) < readAppliedPageSize {
+				break
+			}
+			last := page[len(page)-1]
+			cursor, cursorKey = last.Time, last.Key
+		}
+	}
+	return nil
+}
+
+// readAppliedPage returns a page of applied mutations from the bucket.
+func (s *stage) readAppliedPage(
+	ctx context.Context,
+	db types.StagingQuerier,
+	b *bucket,
+	from hlc.Time,
+	fromKey json.RawMessage,
+	end hlc.Time,
+	limit int,
+) ([]types.Mutation, error) {
+	rows, err := db.Query(ctx, b.sql.readApplied,
+		from.Nanos(), from.Logical(), fromKey, end.Nanos(), end.Logical(), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, b.sql.readApplied)
+	}
+	defer rows.Close()
+
+	var ret []types.Mutation
+	for rows.Next() {
+		var mut types.Mutation
+		var nanos int64
+		var logical int
+		var deletion sql.NullBool // Could be migrated.
+		if err := rows.Scan(&nanos, &logical, &mut.Key,
+			&mut.Data, &mut.Before, &deletion); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		mut.Deletion = deletion.Valid && deletion.Bool
+		mut.Time = hlc.New(nanos, logical)
+		mut.Before, err = s.decode(ctx, mut.Before)
+		if err != nil {
+			return nil, err
+		}
+		mut.Data, err = s.decode(ctx, mut.Data)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, mut)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
+
 const retireTemplate = `
 WITH d AS (
      DELETE FROM %s
@@ -656,30 +1004,43 @@ SELECT last_value(nanos) OVER (), last_value(logical) OVER ()
   FROM d
  LIMIT 1`
 
-// Retire deletes staged data up to the given end time.
+// Retire deletes staged data up to the given end time. Buckets which
+// lie entirely before the end time and contain no unapplied mutations
+// will be dropped.
 func (s *stage) Retire(ctx context.Context, db types.StagingQuerier, end hlc.Time) error {
 	start := time.Now()
 	err := retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+		buckets, err := s.bucketsIn(ctx, math.MinInt64, end.Nanos(), s.bucketed())
+		if err != nil {
+			return err
+		}
+
 		from, _ := s.retireFrom.Get()
-		for hlc.Compare(from, end) < 0 {
-			var lastNanos int64
-			var lastLogical int
-			err := db.QueryRow(ctx, s.sql.retire,
-				from.Nanos(),
-				from.Logical(),
-				end.Nanos(),
-				end.Logical(),
-				10000, // Make configurable?
-			).Scan(&lastNanos, &lastLogical)
-
-			if errors.Is(err, pgx.ErrNoRows) {
-				break
+		dropped := false
+		for _, b := range buckets {
+			if s.bucketed() && b.end <= end.Nanos() {
+				var hasUnapplied bool
+				if err := db.QueryRow(ctx, b.sql.hasUnapplied).Scan(&hasUnapplied); err != nil {
+					return errors.Wrap(err, b.sql.hasUnapplied)
+				}
+				if !hasUnapplied {
+					if err := s.dropBucket(ctx, db, b); err != nil {
+						return err
+					}
+					dropped = true
+					continue
+				}
 			}
-			if err != nil {
-				return errors.WithStack(err)
+			if err := s.retireBucket(ctx, db, b, from, end); err != nil {
+				return err
 			}
-			from = hlc.New(lastNanos, lastLogical)
 		}
+		if dropped {
+			if err := s.loadBuckets(ctx); err != nil {
+				return err
+			}
+		}
+
 		// If there was nothing to delete, still advance the marker.
 		if hlc.Compare(from, end) < 0 {
 			from = end
@@ -694,3 +1055,66 @@ func (s *stage) Retire(ctx context.Context, db types.StagingQuerier, end hlc.Tim
 	}
 	return err
 }
+
+// retireBucket deletes applied mutations from the bucket in batches.
+func (s *stage) retireBucket(
+	ctx context.Context, db types.StagingQuerier, b *bucket, from, end hlc.Time,
+) error {
+	for hlc.Compare(from, end) < 0 {
+		var lastNanos int64
+		var lastLogical int
+		err := db.QueryRow(ctx, b.sql.retire,
+			from.Nanos(),
+			from.Logical(),
+			end.Nanos(),
+			end.Logical(),
+			10000, // Make configurable?
+		).Scan(&lastNanos, &lastLogical)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		from = hlc.New(lastNanos, lastLogical)
+	}
+	return nil
+}
+
+// unapplyBatchSize is the number of mutations that Unapply will update
+// in a single statement.
+const unapplyBatchSize = 10000
+
+const unapplyTemplate = `
+UPDATE %s
+   SET applied = false, applied_at = NULL
+ WHERE (nanos, logical) > ($1, $2) AND applied AND mut != '%s'
+ LIMIT $3`
+
+// Unapply marks staged data after the given time as unapplied.
+func (s *stage) Unapply(
+	ctx context.Context, db types.StagingQuerier, after hlc.Time,
+) (int64, error) {
+	buckets, err := s.bucketsIn(ctx, after.Nanos(), math.MaxInt64, s.bucketed())
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, b := range buckets {
+		for {
+			// The caller is responsible for retrying, since db may be
+			// a transaction that cannot be reused after an error.
+			tag, err := db.Exec(ctx, b.sql.unapply, after.Nanos(), after.Logical(), unapplyBatchSize)
+			if err != nil {
+				return total, errors.WithStack(err)
+			}
+			count := tag.RowsAffected()
+			total += count
+			if count < unapplyBatchSize {
+				break
+			}
+		}
+	}
+	return total, nil
+}
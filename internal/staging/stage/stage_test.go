@@ -19,8 +19,12 @@ package stage_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"sync/atomic"
 	"testing"
@@ -31,6 +35,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
 	"github.com/cockroachdb/replicator/internal/sinktest/mutations"
+	"github.com/cockroachdb/replicator/internal/staging/stage"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -82,6 +87,39 @@ func TestFilterStubs(t *testing.T) {
 }
 
 // TestPutAndDrain will insert and mark a batch of Mutations.
+// TestUnapply ensures that staged mutations after a given time are
+// marked as unapplied, while stub entries are left alone.
+func TestUnapply(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	pool := fixture.StagingPool
+	dummyTarget := ident.NewTable(fixture.StagingDB.Schema(), ident.New("target"))
+
+	s, err := fixture.Stagers.Get(ctx, dummyTarget)
+	r.NoError(err)
+
+	staged := []types.Mutation{
+		{Data: json.RawMessage(`{"pk":1}`), Key: json.RawMessage(`[ 1 ]`), Time: hlc.New(1, 0)},
+		{Data: json.RawMessage(`{"pk":1}`), Key: json.RawMessage(`[ 1 ]`), Time: hlc.New(2, 0)},
+		{Data: json.RawMessage(`{"pk":2}`), Key: json.RawMessage(`[ 2 ]`), Time: hlc.New(3, 0)},
+	}
+	stub := types.Mutation{Key: json.RawMessage(`[ 3 ]`), Time: hlc.New(4, 0)}
+	r.NoError(s.Stage(ctx, pool, staged))
+	r.NoError(s.MarkApplied(ctx, pool, append(staged, stub)))
+
+	count, err := s.Unapply(ctx, pool, hlc.New(1, 0))
+	r.NoError(err)
+	r.Equal(int64(2), count)
+
+	filtered, err := s.FilterApplied(ctx, pool, append(staged, stub))
+	r.NoError(err)
+	r.Equal(staged[1:], filtered)
+}
+
 func TestPutAndDrain(t *testing.T) {
 	tc := []struct {
 		name           string
@@ -467,3 +505,246 @@ func benchmarkStage(b *testing.B, batchSize int) {
 	b.SetBytes(allBytes.Load())
 
 }
+
+// TestBuckets ensures that mutations may be spread across time-bucketed
+// staging tables and that retiring mutations will drop buckets.
+func TestBuckets(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	pool := fixture.StagingPool
+	cfg := &stage.Config{BucketWidth: time.Hour}
+	r.NoError(cfg.Preflight())
+	stagers := stage.ProvideFactory(cfg, pool, fixture.StagingDB, ctx)
+
+	dummyTarget := ident.NewTable(fixture.StagingDB.Schema(), ident.New("target"))
+	s, err := stagers.Get(ctx, dummyTarget)
+	r.NoError(err)
+	baseTable := s.(interface{ GetTable() ident.Table }).GetTable()
+	catalog := ident.NewTable(fixture.StagingDB.Schema(), ident.New("stage_buckets"))
+
+	countBuckets := func(before int64) int {
+		var count int
+		r.NoError(pool.QueryRow(ctx, fmt.Sprintf(
+			"SELECT count(*) FROM %s WHERE target = $1 AND end_nanos <= $2", catalog),
+			baseTable.Table().Raw(), before).Scan(&count))
+		return count
+	}
+
+	// Future buckets are created ahead of time.
+	r.Zero(countBuckets(time.Now().Add(-time.Hour).UnixNano()))
+	r.GreaterOrEqual(countBuckets(time.Now().Add(4*time.Hour).UnixNano()), 3)
+
+	current := time.Now().Truncate(time.Hour)
+	staged := []types.Mutation{
+		{Data: json.RawMessage(`{"pk":1}`), Key: json.RawMessage(`[ 1 ]`),
+			Time: hlc.New(current.Add(-3*time.Hour).UnixNano(), 0)},
+		{Data: json.RawMessage(`{"pk":2}`), Key: json.RawMessage(`[ 2 ]`),
+			Time: hlc.New(current.Add(-2*time.Hour).UnixNano(), 0)},
+		{Data: json.RawMessage(`{"pk":3}`), Key: json.RawMessage(`[ 3 ]`),
+			Time: hlc.New(current.UnixNano()+1, 0)},
+	}
+	r.NoError(s.Stage(ctx, pool, staged))
+	r.Equal(2, countBuckets(current.UnixNano()))
+
+	// The unapplied mutation for key 1 is in an older bucket.
+	exists := []types.Mutation{
+		{Data: json.RawMessage(`{"pk":1}`), Key: json.RawMessage(`[ 1 ]`),
+			Time: hlc.New(current.UnixNano()+2, 0)},
+		{Data: json.RawMessage(`{"pk":4}`), Key: json.RawMessage(`[ 4 ]`),
+			Time: hlc.New(current.UnixNano()+2, 0)},
+	}
+	pending, err := s.StageIfExists(ctx, pool, exists)
+	r.NoError(err)
+	r.Equal(exists[1:], pending)
+	staged = append(staged, exists[0])
+
+	// Apply the mutations in the older buckets.
+	r.NoError(s.MarkApplied(ctx, pool, staged[:2]))
+	filtered, err := s.FilterApplied(ctx, pool, staged)
+	r.NoError(err)
+	r.Equal(staged[2:], filtered)
+
+	count, err := s.Unapply(ctx, pool, staged[0].Time)
+	r.NoError(err)
+	r.Equal(int64(1), count)
+	r.NoError(s.MarkApplied(ctx, pool, staged[1:2]))
+
+	// Retiring should drop the older buckets.
+	r.NoError(s.Retire(ctx, pool, hlc.New(current.UnixNano(), 0)))
+	r.Zero(countBuckets(current.UnixNano()))
+
+	// Mutations in the current bucket are unaffected.
+	r.NoError(s.MarkApplied(ctx, pool, staged[2:]))
+	filtered, err = s.FilterApplied(ctx, pool, staged[2:])
+	r.NoError(err)
+	r.Empty(filtered)
+}
+
+// TestEncryption verifies that staged payloads are encrypted, that
+// rotating the data keys preserves existing rows, and that existing
+// rows can be re-encrypted with the active data key.
+func TestEncryption(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	pool := fixture.StagingPool
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	r.NoError(err)
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	r.NoError(os.WriteFile(keyFile,
+		[]byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+
+	cfg := &stage.Config{EncryptionKeyFile: keyFile}
+	r.NoError(cfg.Preflight())
+	stagers := stage.ProvideFactory(cfg, pool, fixture.StagingDB, ctx)
+
+	dummyTarget := ident.NewTable(fixture.StagingDB.Schema(), ident.New("target"))
+	s, err := stagers.Get(ctx, dummyTarget)
+	r.NoError(err)
+	table := s.(interface{ GetTable() ident.Table }).GetTable()
+
+	// Return the data key ids of the staged payloads.
+	keyIDs := func() []int {
+		rows, err := pool.Query(ctx, fmt.Sprintf(
+			"SELECT mut FROM %s ORDER BY nanos, logical, key", table))
+		r.NoError(err)
+		defer rows.Close()
+		var ret []int
+		for rows.Next() {
+			var mut []byte
+			r.NoError(rows.Scan(&mut))
+			r.Greater(len(mut), 8)
+			r.Equal(byte(0), mut[0], "payload is not encrypted")
+			ret = append(ret, int(mut[7]))
+		}
+		r.NoError(rows.Err())
+		return ret
+	}
+
+	muts := []types.Mutation{
+		{Before: json.RawMessage(`{"pk":1,"v":0}`), Data: json.RawMessage(`{"pk":1,"v":1}`),
+			Key: json.RawMessage(`[ 1 ]`), Time: hlc.New(1, 0)},
+	}
+	r.NoError(s.Stage(ctx, pool, muts))
+	r.Equal([]int{1}, keyIDs())
+
+	// New mutations use the new key.
+	id, err := stage.RotateKeys(ctx, s)
+	r.NoError(err)
+	r.Equal(uint32(2), id)
+	muts = append(muts, types.Mutation{
+		Data: json.RawMessage(`{"pk":2,"v":1}`), Key: json.RawMessage(`[ 2 ]`), Time: hlc.New(2, 0)})
+	r.NoError(s.Stage(ctx, pool, muts[1:]))
+	r.Equal([]int{1, 2}, keyIDs())
+
+	// Only the row using the older key is rewritten.
+	count, err := stage.Reencrypt(ctx, s)
+	r.NoError(err)
+	r.Equal(1, count)
+	r.Equal([]int{2, 2}, keyIDs())
+
+	// The payloads are decrypted when read.
+	r.NoError(s.MarkApplied(ctx, pool, muts))
+	var read []types.Mutation
+	r.NoError(s.ReadApplied(ctx, pool, hlc.Zero(), hlc.New(3, 0),
+		func(page []types.Mutation) error {
+			read = append(read, page...)
+			return nil
+		}))
+	r.Len(read, 2)
+	for idx := range read {
+		r.Equal(string(muts[idx].Data), string(read[idx].Data))
+		r.Equal(string(muts[idx].Before), string(read[idx].Before))
+	}
+
+	// Applied stubs are left in plaintext.
+	stubs := []types.Mutation{{Key: json.RawMessage(`[ 3 ]`), Time: hlc.New(3, 0)}}
+	r.NoError(s.MarkApplied(ctx, pool, stubs))
+	count, err = stage.Reencrypt(ctx, s)
+	r.NoError(err)
+	r.Zero(count)
+
+	// Another instance will periodically pick up a rotation.
+	otherCfg := &stage.Config{EncryptionKeyFile: keyFile, EncryptionKeyPeriod: 10 * time.Millisecond}
+	r.NoError(otherCfg.Preflight())
+	other, err := stage.ProvideFactory(otherCfg, pool, fixture.StagingDB, ctx).Get(ctx, dummyTarget)
+	r.NoError(err)
+	id, err = stage.RotateKeys(ctx, s)
+	r.NoError(err)
+	r.Equal(uint32(3), id)
+	r.Eventually(func() bool {
+		r.NoError(other.Stage(ctx, pool, []types.Mutation{{
+			Data: json.RawMessage(`{"pk":4,"v":1}`), Key: json.RawMessage(`[ 4 ]`), Time: hlc.New(4, 0)}}))
+		var mut []byte
+		r.NoError(pool.QueryRow(ctx, fmt.Sprintf(
+			"SELECT mut FROM %s WHERE nanos = 4", table)).Scan(&mut))
+		return mut[7] == 3
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+// TestZstdDictionaryTraining verifies that a zstd dictionary is
+// trained from staged payloads and that payloads compressed with and
+// without the dictionary can be read.
+func TestZstdDictionaryTraining(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	pool := fixture.StagingPool
+	cfg := &stage.Config{
+		Compression:          stage.CompressionZstd,
+		CompressionThreshold: 16,
+		ZstdDictSamples:      100,
+	}
+	r.NoError(cfg.Preflight())
+	stagers := stage.ProvideFactory(cfg, pool, fixture.StagingDB, ctx)
+
+	dummyTarget := ident.NewTable(fixture.StagingDB.Schema(), ident.New("target"))
+	s, err := stagers.Get(ctx, dummyTarget)
+	r.NoError(err)
+	table := s.(interface{ GetTable() ident.Table }).GetTable()
+	catalog := ident.NewTable(fixture.StagingDB.Schema(), ident.New("stage_dicts"))
+
+	var muts []types.Mutation
+	for i := 0; i < 200; i++ {
+		muts = append(muts, types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(
+				`{"id":%d,"email":"customer%d@example.com","status":"active"}`, i, i)),
+			Key:  json.RawMessage(fmt.Sprintf(`[ %d ]`, i)),
+			Time: hlc.New(int64(i+1), 0),
+		})
+	}
+	r.NoError(s.Stage(ctx, pool, muts[:100]))
+
+	r.Eventually(func() bool {
+		var count int
+		r.NoError(pool.QueryRow(ctx, fmt.Sprintf(
+			"SELECT count(*) FROM %s WHERE target = $1", catalog),
+			table.Raw()).Scan(&count))
+		return count == 1
+	}, time.Minute, 10*time.Millisecond)
+	r.NoError(s.Stage(ctx, pool, muts[100:]))
+
+	r.NoError(s.MarkApplied(ctx, pool, muts))
+	var read []types.Mutation
+	r.NoError(s.ReadApplied(ctx, pool, hlc.Zero(), hlc.New(1_000, 0),
+		func(page []types.Mutation) error {
+			read = append(read, page...)
+			return nil
+		}))
+	r.Len(read, len(muts))
+	for idx := range read {
+		r.Equal(string(muts[idx].Data), string(read[idx].Data))
+	}
+}
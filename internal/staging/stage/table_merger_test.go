@@ -113,7 +113,7 @@ func TestTableMerger(t *testing.T) {
 			fixture.StagingPool,
 			fragmentSize,
 			out,
-			fixture.StagingDB.Schema(),
+			stages[idx],
 			table)
 		ctx.Go(func(ctx *stopper.Context) error {
 			reader.run(ctx)
@@ -21,7 +21,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -52,7 +51,7 @@ type tableReader struct {
 	out          chan<- *tableCursor    // Communicate to the caller.
 	scanBounds   hlc.Range              // The remaining range of data to scan.
 	scanKey      json.RawMessage        // Position within the table.
-	sqlQ         string                 // The SQL query that drives the tableReader.
+	source       *stage                 // The staging tables to read from.
 	table        ident.Table            // Names of target table.
 
 	readCount     prometheus.Counter
@@ -80,7 +79,7 @@ func newTableReader(
 	db *types.StagingPool,
 	fragmentSize int,
 	out chan<- *tableCursor,
-	stagingDB ident.Schema,
+	source *stage,
 	target ident.Table,
 ) *tableReader {
 	labels := metrics.TableValues(target)
@@ -89,8 +88,8 @@ func newTableReader(
 		bounds:       bounds,
 		db:           db,
 		fragmentSize: fragmentSize,
-		sqlQ:         fmt.Sprintf(readTableTemplate, stagingTable(stagingDB, target)),
 		out:          out,
+		source:       source,
 		table:        target,
 
 		readCount:     stageReadRows.WithLabelValues(labels...),
@@ -198,13 +197,13 @@ func (r *tableReader) nextCursor(ctx *stopper.Context) *tableCursor {
 	for _, mut := range muts {
 		var err error
 		// The data retrieved from the database may have been
-		// compressed. We want to decompress it outside the database
-		// query to release the connection sooner.
-		mut.Before, err = maybeGunzip(mut.Before)
+		// compressed or encrypted. We want to decode it outside the
+		// database query to release the connection sooner.
+		mut.Before, err = r.source.decode(ctx, mut.Before)
 		if err != nil {
 			return &tableCursor{Error: err}
 		}
-		mut.Data, err = maybeGunzip(mut.Data)
+		mut.Data, err = r.source.decode(ctx, mut.Data)
 		if err != nil {
 			return &tableCursor{Error: err}
 		}
@@ -222,21 +221,51 @@ func (r *tableReader) nextCursor(ctx *stopper.Context) *tableCursor {
 	return ret
 }
 
-// queryOnce retrieves a limited number of rows.
+// queryOnce retrieves a limited number of rows. The staging buckets
+// are read in order until enough rows have been retrieved.
 func (r *tableReader) queryOnce(ctx context.Context) ([]types.Mutation, error) {
 	start := time.Now()
 	ret := make([]types.Mutation, 0, r.fragmentSize)
 
+	// Always refresh, since another instance of Replicator may have
+	// created a bucket that we haven't seen.
+	buckets, err := r.source.bucketsIn(ctx,
+		r.scanBounds.Min().Nanos(), r.scanBounds.Max().Nanos(), r.source.bucketed())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range buckets {
+		if len(ret) >= r.fragmentSize {
+			break
+		}
+		if err := r.queryBucket(ctx, b, &ret); err != nil {
+			// A bucket that was dropped by another instance of
+			// Replicator contained no unapplied mutations.
+			if code, ok := r.db.ErrCode(err); ok && r.source.bucketed() &&
+				code == "42P01" /* undefined_table */ {
+				continue
+			}
+			return nil, err
+		}
+	}
+	r.readCount.Add(float64(len(ret)))
+	r.readDurations.Observe(time.Since(start).Seconds())
+	return ret, nil
+}
+
+// queryBucket appends rows from the bucket to the slice.
+func (r *tableReader) queryBucket(ctx context.Context, b *bucket, ret *[]types.Mutation) error {
 	rows, err := r.db.Query(ctx,
-		r.sqlQ,
+		b.sql.readTable,
 		r.scanBounds.Min().Nanos(),
 		r.scanBounds.Min().Logical(),
 		r.scanKey,
 		r.scanBounds.Max().Nanos(),
 		r.scanBounds.Max().Logical(),
-		r.fragmentSize)
+		r.fragmentSize-len(*ret))
 	if err != nil {
-		return nil, errors.Wrap(err, r.sqlQ)
+		return errors.Wrap(err, b.sql.readTable)
 	}
 	defer rows.Close()
 
@@ -247,19 +276,14 @@ func (r *tableReader) queryOnce(ctx context.Context) ([]types.Mutation, error) {
 		var deletion sql.NullBool // Could be migrated.
 		if err := rows.Scan(&nanos, &logical, &mut.Key,
 			&mut.Data, &mut.Before, &deletion); err != nil {
-			return nil, errors.WithStack(err)
+			return errors.WithStack(err)
 		}
 		mut.Deletion = deletion.Valid && deletion.Bool
 		mut.Time = hlc.New(nanos, logical)
 
-		ret = append(ret, mut)
+		*ret = append(*ret, mut)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	r.readCount.Add(float64(len(ret)))
-	r.readDurations.Observe(time.Since(start).Seconds())
-	return ret, nil
+	return errors.WithStack(rows.Err())
 }
 
 // updateBounds ensures that the state of the reader can satisfy all
@@ -93,7 +93,7 @@ func TestTableReader(t *testing.T) {
 		fixture.StagingPool,
 		fragmentSize,
 		out,
-		fixture.StagingDB.Schema(),
+		stage,
 		info.Name())
 	ctx.Go(func(ctx *stopper.Context) error {
 		reader.run(ctx)
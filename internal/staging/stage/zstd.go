@@ -0,0 +1,420 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+// This file contains a zstd codec for the payloads of staged
+// mutations. Small JSON payloads compress poorly on their own, so each
+// target table may have a sequence of dictionaries that are trained
+// from a sample of the payloads being staged. The dictionaries are
+// stored in a catalog table within the staging schema and are
+// identified by an increasing id, which is embedded in each zstd
+// frame. Older dictionaries are retained so that existing rows remain
+// readable.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// zstdDictMaxSize limits the amount of sampled content that will be
+// used as the history of a dictionary.
+const zstdDictMaxSize = 64 * 1024
+
+// zstdSampleMaxSize limits the size of any one sampled payload.
+const zstdSampleMaxSize = 16 * 1024
+
+// zstdMagic is the start of a zstd frame. An opening parenthesis would
+// not start a JSON document.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// dictCatalog is the name of the table that stores the zstd
+// dictionaries for each target.
+var dictCatalog = ident.New("stage_dicts")
+
+const dictCatalogSchema = `
+CREATE TABLE IF NOT EXISTS %s (
+     target STRING NOT NULL,
+    dict_id INT8 NOT NULL,
+       dict BYTES NOT NULL,
+ created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (target, dict_id)
+)`
+
+const dictInsertTemplate = `
+INSERT INTO %s (target, dict_id, dict)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING`
+
+const dictLoadTemplate = `
+SELECT dict_id, dict
+  FROM %s
+ WHERE target = $1
+ ORDER BY dict_id`
+
+// zstdCodec compresses the payloads for a single target using the
+// most recently trained dictionary.
+type zstdCodec struct {
+	cfg    *Config
+	db     *types.StagingPool
+	keys   *dataKeys // Encrypts the stored dictionaries; may be nil.
+	stop   *stopper.Context
+	target string
+	sql    struct {
+		insert string
+		load   string
+	}
+
+	mu struct {
+		sync.RWMutex
+		activeID uint32 // Zero if no dictionary has been trained.
+		decoder  *zstd.Decoder
+		encoder  *zstd.Encoder
+	}
+
+	// Payloads are sampled when this is set.
+	sampling atomic.Bool
+	samples  struct {
+		sync.Mutex
+		data [][]byte
+	}
+}
+
+// newZstdCodec loads any existing dictionaries for the target. If zstd
+// compression has been configured, a background task will train new
+// dictionaries. Otherwise, the codec is only used to read payloads
+// that were staged by other instances of Replicator.
+func newZstdCodec(
+	ctx *stopper.Context,
+	cfg *Config,
+	db *types.StagingPool,
+	stagingDB ident.Schema,
+	target string,
+	keys *dataKeys,
+) (*zstdCodec, error) {
+	catalog := ident.NewTable(stagingDB, dictCatalog)
+	c := &zstdCodec{cfg: cfg, db: db, keys: keys, stop: ctx, target: target}
+	c.sql.insert = fmt.Sprintf(dictInsertTemplate, catalog)
+	c.sql.load = fmt.Sprintf(dictLoadTemplate, catalog)
+	if err := c.install(nil, nil); err != nil {
+		return nil, err
+	}
+
+	if cfg.Compression != CompressionZstd {
+		return c, nil
+	}
+
+	schema := dictCatalogSchema
+	if db.Product == types.ProductPostgreSQL {
+		schema = dictCatalogSchemaPG
+	}
+	if err := retry.Execute(ctx, db, fmt.Sprintf(schema, catalog)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	if cfg.ZstdDictSamples < 0 {
+		return c, nil
+	}
+	c.sampling.Store(c.activeID() == 0)
+
+	// Periodically pick up dictionaries trained by other instances
+	// and start sampling for the next dictionary.
+	ctx.Go(func(ctx *stopper.Context) error {
+		period := cfg.ZstdDictPeriod
+		if period < 0 {
+			period = time.Minute
+		}
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+			if err := c.load(ctx); err != nil {
+				log.WithError(err).Warnf("could not refresh zstd dictionaries for %s", target)
+				continue
+			}
+			if cfg.ZstdDictPeriod > 0 || c.activeID() == 0 {
+				c.sampling.Store(true)
+			}
+		}
+	})
+	return c, nil
+}
+
+// activeID returns the id of the dictionary used for compression.
+func (c *zstdCodec) activeID() uint32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mu.activeID
+}
+
+// buildDict trains a dictionary from the samples.
+func buildDict(id uint32, samples [][]byte) ([]byte, error) {
+	// Prefer the most recent samples for the history.
+	start, size := len(samples), 0
+	for start > 0 && size+len(samples[start-1]) <= zstdDictMaxSize {
+		start--
+		size += len(samples[start])
+	}
+	history := bytes.Join(samples[start:], nil)
+	dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+		Contents: samples,
+		History:  history,
+		ID:       id,
+		Level:    zstd.SpeedDefault,
+		Offsets:  [3]int{1, 4, 8},
+	})
+	return dict, errors.Wrap(err, "could not build zstd dictionary")
+}
+
+// compress encodes the data with the active dictionary. The data will
+// be sampled if a new dictionary is being trained.
+func (c *zstdCodec) compress(data []byte) []byte {
+	if c.sampling.Load() {
+		c.sample(data)
+	}
+	// Hold the lock while encoding, since install closes the previous
+	// encoder.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mu.encoder.EncodeAll(data, nil)
+}
+
+// decompress reverses compress. If the payload uses a dictionary that
+// has not been seen, the dictionaries will be reloaded to pick up one
+// trained by another process.
+func (c *zstdCodec) decompress(ctx context.Context, data []byte) ([]byte, error) {
+	ret, err := c.decodeAll(data)
+	if errors.Is(err, zstd.ErrUnknownDictionary) {
+		if err := c.load(ctx); err != nil {
+			return nil, err
+		}
+		ret, err = c.decodeAll(data)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decompress payload for %s", c.target)
+	}
+	return ret, nil
+}
+
+// decodeAll decodes the data with the current decoder. The lock is held
+// while decoding, since install closes the previous decoder.
+func (c *zstdCodec) decodeAll(data []byte) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mu.decoder.DecodeAll(data, nil)
+}
+
+// install replaces the encoder and decoder. The last dictionary will be
+// used for compression. The previous encoder and decoder are closed to
+// release their resources.
+func (c *zstdCodec) install(ids []uint32, dicts [][]byte) error {
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dicts...))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.SpeedDefault)}
+	var activeID uint32
+	if len(dicts) > 0 {
+		activeID = ids[len(ids)-1]
+		opts = append(opts, zstd.WithEncoderDict(dicts[len(dicts)-1]))
+	}
+	enc, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.mu.Lock()
+	oldDec, oldEnc := c.mu.decoder, c.mu.encoder
+	c.mu.activeID = activeID
+	c.mu.decoder = dec
+	c.mu.encoder = enc
+	c.mu.Unlock()
+
+	if oldDec != nil {
+		oldDec.Close()
+	}
+	if oldEnc != nil {
+		if err := oldEnc.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// load refreshes the dictionaries from the catalog.
+func (c *zstdCodec) load(ctx context.Context) error {
+	var ids []uint32
+	var dicts [][]byte
+	err := retry.Retry(ctx, c.db, func(ctx context.Context) error {
+		ids, dicts = nil, nil
+		rows, err := c.db.Query(ctx, c.sql.load, c.target)
+		if err != nil {
+			return errors.Wrap(err, c.sql.load)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var dict []byte
+			if err := rows.Scan(&id, &dict); err != nil {
+				return errors.WithStack(err)
+			}
+			ids = append(ids, uint32(id))
+			dicts = append(dicts, dict)
+		}
+		return errors.WithStack(rows.Err())
+	})
+	if code, ok := c.db.ErrCode(err); ok && code == "42P01" /* undefined_table */ {
+		// No instance of Replicator has been configured to use zstd.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Dictionaries contain fragments of the payloads.
+	for idx, dict := range dicts {
+		if !isEncrypted(dict) {
+			continue
+		}
+		if c.keys == nil {
+			return errors.Errorf(
+				"zstd dictionaries for %s are encrypted, but no master keys were configured",
+				c.target)
+		}
+		dicts[idx], err = c.keys.decrypt(ctx, dict)
+		if err != nil {
+			return err
+		}
+	}
+	return c.install(ids, dicts)
+}
+
+// sample retains a copy of the data. Once enough samples have been
+// collected, a new dictionary will be trained in the background.
+func (c *zstdCodec) sample(data []byte) {
+	if len(data) > zstdSampleMaxSize {
+		data = data[:zstdSampleMaxSize]
+	}
+	c.samples.Lock()
+	defer c.samples.Unlock()
+	if !c.sampling.Load() {
+		return
+	}
+	c.samples.data = append(c.samples.data, bytes.Clone(data))
+	if len(c.samples.data) < c.cfg.ZstdDictSamples {
+		return
+	}
+	c.sampling.Store(false)
+	samples := c.samples.data
+	c.samples.data = nil
+	c.stop.Go(func(ctx *stopper.Context) error {
+		if err := c.train(ctx, samples); err != nil {
+			log.WithError(err).Warnf("could not train zstd dictionary for %s", c.target)
+		}
+		return nil
+	})
+}
+
+// train builds and stores a new dictionary. Another instance of
+// Replicator may race to store a dictionary with the same id, in which
+// case, only one will be used.
+func (c *zstdCodec) train(ctx context.Context, samples [][]byte) error {
+	id := c.activeID() + 1
+	dict, err := buildDict(id, samples)
+	if err != nil {
+		return err
+	}
+	stored := dict
+	if c.keys != nil {
+		stored, err = c.keys.encrypt(dict)
+		if err != nil {
+			return err
+		}
+	}
+	if err := retry.Execute(ctx, c.db, c.sql.insert, c.target, int64(id), stored); err != nil {
+		return errors.WithStack(err)
+	}
+	log.Debugf("trained zstd dictionary %d for %s from %d samples", id, c.target, len(samples))
+	return c.load(ctx)
+}
+
+// isZstd returns true if the data starts with a zstd frame.
+func isZstd(data []byte) bool {
+	return bytes.HasPrefix(data, zstdMagic)
+}
+
+// compress applies the configured codec to payloads that are larger
+// than the threshold. The payload is returned as-is if compressing it
+// would not reduce its size.
+func (s *stage) compress(data []byte) ([]byte, error) {
+	if len(data) <= s.cfg.CompressionThreshold {
+		return data, nil
+	}
+	start := time.Now()
+	var ret []byte
+	switch s.cfg.Compression {
+	case CompressionZstd:
+		ret = s.zstd.compress(data)
+	default:
+		var err error
+		ret, err = gzipPayload(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.compressSeconds.Add(time.Since(start).Seconds())
+	s.compressRatio.Observe(float64(len(ret)) / float64(len(data)))
+	if len(ret) >= len(data) {
+		return data, nil
+	}
+	return ret, nil
+}
+
+// decompress reverses compress, using the codec that was used to
+// compress the payload.
+func (s *stage) decompress(ctx context.Context, data []byte) ([]byte, error) {
+	var ret []byte
+	var err error
+	start := time.Now()
+	switch {
+	case isZstd(data):
+		ret, err = s.zstd.decompress(ctx, data)
+	case isGzipped(data):
+		ret, err = maybeGunzip(data)
+	default:
+		return data, nil
+	}
+	s.decompressSeconds.Add(time.Since(start).Seconds())
+	return ret, err
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// newCodecStage returns a stage that can encode and decode payloads
+// without a staging database.
+func newCodecStage(t *testing.T, cfg *Config) *stage {
+	require.NoError(t, cfg.Preflight())
+	table := ident.NewTable(ident.MustSchema(ident.New("db")), ident.New("target"))
+	labels := metrics.TableValues(table)
+	codec := &zstdCodec{
+		cfg:    cfg,
+		stop:   stopper.WithContext(context.Background()),
+		target: table.Raw(),
+	}
+	require.NoError(t, codec.install(nil, nil))
+	return &stage{
+		cfg:               cfg,
+		stage:             &ident.Hinted[ident.Table]{Base: table},
+		zstd:              codec,
+		compressRatio:     stageCompressionRatio.WithLabelValues(labels...),
+		compressSeconds:   stageCompressSeconds.WithLabelValues(labels...),
+		decompressSeconds: stageDecompressSeconds.WithLabelValues(labels...),
+	}
+}
+
+// samplePayload returns a small, repetitive JSON document.
+func samplePayload(idx int) []byte {
+	return []byte(fmt.Sprintf(
+		`{"id":%d,"name":"customer %d","email":"customer%d@example.com","status":"active","region":"us-east-1"}`,
+		idx, idx, idx))
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	for _, codec := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(codec, func(t *testing.T) {
+			s := newCodecStage(t, &Config{Compression: codec, CompressionThreshold: 64})
+			small := []byte(`{"pk":1}`)
+			enc, err := s.compress(small)
+			r.NoError(err)
+			r.Equal(small, enc)
+
+			large := []byte(`{"pk":1,"v":"` + strings.Repeat("x", 256) + `"}`)
+			enc, err = s.compress(large)
+			r.NoError(err)
+			r.Less(len(enc), len(large))
+			r.Equal(codec == CompressionZstd, isZstd(enc))
+			r.Equal(codec == CompressionGzip, isGzipped(enc))
+
+			// Either codec can read the other's payloads.
+			other := newCodecStage(t, &Config{})
+			dec, err := other.decompress(ctx, enc)
+			r.NoError(err)
+			r.Equal(large, dec)
+		})
+	}
+}
+
+func TestZstdDictionaries(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	s := newCodecStage(t, &Config{Compression: CompressionZstd, CompressionThreshold: 16})
+	payload := samplePayload(1_000_000)
+
+	plain, err := s.compress(payload)
+	r.NoError(err)
+
+	var samples [][]byte
+	for i := 0; i < 1_000; i++ {
+		samples = append(samples, samplePayload(i))
+	}
+	first, err := buildDict(1, samples)
+	r.NoError(err)
+	r.NoError(s.zstd.install([]uint32{1}, [][]byte{first}))
+	r.Equal(uint32(1), s.zstd.activeID())
+
+	withDict, err := s.compress(payload)
+	r.NoError(err)
+	r.Less(len(withDict), len(plain))
+	t.Logf("%d -> %d without dictionary, %d with dictionary",
+		len(payload), len(plain), len(withDict))
+
+	// Payloads compressed by older dictionaries remain readable.
+	second, err := buildDict(2, samples[500:])
+	r.NoError(err)
+	previous := s.zstd.mu.decoder
+	r.NoError(s.zstd.install([]uint32{1, 2}, [][]byte{first, second}))
+	r.Equal(uint32(2), s.zstd.activeID())
+
+	// The replaced decoder should have been closed.
+	_, err = previous.DecodeAll(withDict, nil)
+	r.ErrorIs(err, zstd.ErrDecoderClosed)
+	for _, enc := range [][]byte{plain, withDict} {
+		dec, err := s.decompress(ctx, enc)
+		r.NoError(err)
+		r.Equal(payload, dec)
+	}
+
+	// A codec without the dictionary will need to reload.
+	other := newCodecStage(t, &Config{})
+	_, err = other.zstd.mu.decoder.DecodeAll(withDict, nil)
+	r.ErrorIs(err, zstd.ErrUnknownDictionary)
+}
@@ -22,6 +22,7 @@ package apply
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -29,6 +30,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -37,6 +39,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/metrics"
 	"github.com/cockroachdb/replicator/internal/util/msort"
 	"github.com/cockroachdb/replicator/internal/util/pjson"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
@@ -45,24 +48,33 @@ import (
 // apply will upsert mutations and deletions into a target table.
 type apply struct {
 	cache   *types.TargetStatements
+	creator *ddl.Creator
 	dlqs    types.DLQs
 	loader  *load.Loader
 	product types.Product
+	stop    *stopper.Context
 	target  *ident.Hinted[ident.Table]
 
-	ages      prometheus.Observer
-	conflicts prometheus.Counter
-	deletes   prometheus.Counter
-	durations prometheus.Observer
-	errors    prometheus.Counter
-	resolves  prometheus.Counter
-	upserts   prometheus.Counter
+	ages          prometheus.Observer
+	conflicts     prometheus.Counter
+	copyDurations prometheus.Observer
+	copyRows      prometheus.Counter
+	deletes       prometheus.Counter
+	durations     prometheus.Observer
+	errors        prometheus.Counter
+	filtered      prometheus.Counter
+	resolves      prometheus.Counter
+	stmtDurations prometheus.Observer
+	stmtRows      prometheus.Counter
+	updates       prometheus.Counter
+	upserts       prometheus.Counter
 
 	mu struct {
 		sync.RWMutex
-		bagSpec   *merge.BagSpec
-		gen       int // Use for prepared-statement cache invalidation.
-		templates *templates
+		bagSpec    *merge.BagSpec
+		gen        int // Use for prepared-statement cache invalidation.
+		templates  *templates
+		transforms *transform.Transforms // May be nil.
 	}
 }
 
@@ -92,18 +104,26 @@ func (f *factory) newApply(
 	labelValues := metrics.TableValues(target)
 	a := &apply{
 		cache:   f.cache,
+		creator: f.creator,
 		dlqs:    f.dlqs,
 		loader:  f.loader,
 		product: poolInfo.Product,
+		stop:    ctx,
 		target:  poolInfo.HintNoFTS(target),
 
-		ages:      applyMutationAge.WithLabelValues(labelValues...),
-		conflicts: applyConflicts.WithLabelValues(labelValues...),
-		deletes:   applyDeletes.WithLabelValues(labelValues...),
-		durations: applyDurations.WithLabelValues(labelValues...),
-		errors:    applyErrors.WithLabelValues(labelValues...),
-		resolves:  applyResolves.WithLabelValues(labelValues...),
-		upserts:   applyUpserts.WithLabelValues(labelValues...),
+		ages:          applyMutationAge.WithLabelValues(labelValues...),
+		conflicts:     applyConflicts.WithLabelValues(labelValues...),
+		copyDurations: applyStrategyDurations.WithLabelValues(append(labelValues, strategyCopy)...),
+		copyRows:      applyStrategyRows.WithLabelValues(append(labelValues, strategyCopy)...),
+		deletes:       applyDeletes.WithLabelValues(labelValues...),
+		durations:     applyDurations.WithLabelValues(labelValues...),
+		errors:        applyErrors.WithLabelValues(labelValues...),
+		filtered:      applyFiltered.WithLabelValues(labelValues...),
+		resolves:      applyResolves.WithLabelValues(labelValues...),
+		stmtDurations: applyStrategyDurations.WithLabelValues(append(labelValues, strategyStatement)...),
+		stmtRows:      applyStrategyRows.WithLabelValues(append(labelValues, strategyStatement)...),
+		updates:       applyUpdates.WithLabelValues(labelValues...),
+		upserts:       applyUpserts.WithLabelValues(labelValues...),
 	}
 
 	configHandle := f.configs.Get(target)
@@ -177,22 +197,52 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 		return errors.Errorf("no ColumnData available for %s", a.target)
 	}
 
+	// Large batches may be copied into a staging table, in which case
+	// the number of rows per statement is not limited. A custom upsert
+	// forces a flush, so we don't try to copy upserts in that case.
+	deleteLimit := a.mu.templates.RowLimit
+	upsertLimit := a.mu.templates.RowLimit
+	if _, useCopy := a.copyConnLocked(tx, len(muts)); useCopy {
+		var deleteCount, upsertCount int
+		var hasCustom bool
+		for i := range muts {
+			if muts[i].IsDelete() {
+				deleteCount++
+			} else if _, custom := muts[i].Meta[types.CustomUpsert]; custom {
+				hasCustom = true
+			} else {
+				upsertCount++
+			}
+		}
+		if _, useCopy := a.copyConnLocked(tx, deleteCount); useCopy {
+			deleteLimit = deleteCount
+		}
+		if _, useCopy := a.copyConnLocked(tx, upsertCount); useCopy && !hasCustom {
+			upsertLimit = upsertCount
+		}
+	}
+
 	// If the generated SQL doesn't depend on the number of rows being
 	// inserted, we don't need to do any incremental batching.
 	var deletes []types.Mutation
 	if a.mu.templates.BulkDelete {
 		deletes = make([]types.Mutation, 0, len(muts))
 	} else {
-		deletes = make([]types.Mutation, 0, a.mu.templates.RowLimit)
+		deletes = make([]types.Mutation, 0, deleteLimit)
 	}
 
 	var upserts []types.Mutation
 	if a.mu.templates.BulkUpsert {
 		upserts = make([]types.Mutation, 0, len(muts))
 	} else {
-		upserts = make([]types.Mutation, 0, a.mu.templates.RowLimit)
+		upserts = make([]types.Mutation, 0, upsertLimit)
 	}
 
+	// Mutations with a before value may update only their changed
+	// columns. These are grouped by shape in updateLocked, which
+	// applies its own row limit.
+	var updates []types.Mutation
+
 	// Accumulate mutations and flush incrementally.
 	for i := range muts {
 		if muts[i].IsDelete() {
@@ -217,6 +267,8 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 			if err := a.upsertLocked(ctx, tx, []types.Mutation{muts[i]}, template); err != nil {
 				return countError(err)
 			}
+		} else if a.isUpdateLocked(muts[i]) {
+			updates = append(updates, muts[i])
 		} else {
 			upserts = append(upserts, muts[i])
 			if len(upserts) == cap(upserts) {
@@ -235,6 +287,9 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 	if err := a.upsertLocked(ctx, tx, upserts, ""); err != nil {
 		return countError(err)
 	}
+	if err := a.updateLocked(ctx, tx, updates); err != nil {
+		return countError(err)
+	}
 
 	endNanos := time.Now().UnixNano()
 	a.durations.Observe(time.Duration(endNanos - start.UnixNano()).Seconds())
@@ -275,7 +330,7 @@ func (a *apply) deleteLocked(
 	// relevant PK column values. If we only have a key, the best we can
 	// do is to ensure that we have the same number of columns.
 	pkCols := a.mu.templates.PKDelete
-	allArgs := make([]any, 0, len(pkCols)*len(muts))
+	allArgs := make([]any, 0, a.mu.templates.DeleteParameterCount*len(muts))
 	for i := range bodies {
 		body := &bodies[i] // ident.Map is a no-copy type.
 		var keyGroup []any
@@ -316,31 +371,53 @@ func (a *apply) deleteLocked(
 		}
 
 		allArgs = append(allArgs, keyGroup...)
+
+		// In soft-delete mode, the row is marked with the time of the
+		// deletion instead of being removed.
+		if col := a.mu.templates.SoftDelete; col != nil {
+			allArgs = append(allArgs, softDeleteValue(col, muts[i].Time))
+		}
 	}
 
-	// The statement and its cache key will vary if the target supports
-	// bulk deletions. In bulk mode, the number of rows does not impact
-	// the generated SQL, since we rely on the driver to transfer
-	// multiple arrays of column values.
-	var stmtCacheKey string
-	if a.mu.templates.BulkDelete {
-		var err error
-		allArgs, err = toColumns(len(a.mu.templates.PKDelete), len(muts), allArgs)
+	// Large batches are copied into a staging table, instead of being
+	// passed as substitution parameters.
+	start := time.Now()
+	raw, useCopy := a.copyConnLocked(db, len(muts))
+	var stmt applyStmt
+	if useCopy {
+		copied, err := a.copyDeleteLocked(ctx, db, raw, allArgs)
 		if err != nil {
 			return err
 		}
-		stmtCacheKey = fmt.Sprintf("delete-%s-%d", a.target, a.mu.gen)
+		defer func() { _ = copied.Drop(ctx) }()
+		allArgs = nil
+		stmt = copied
 	} else {
-		stmtCacheKey = fmt.Sprintf("delete-%s-%d-%d", a.target, a.mu.gen, len(muts))
-	}
-	stmt, err := a.cache.Prepare(ctx,
-		db,
-		stmtCacheKey,
-		func() (string, error) {
-			return a.mu.templates.deleteExpr(len(muts))
-		})
-	if err != nil {
-		return err
+		// The statement and its cache key will vary if the target
+		// supports bulk deletions. In bulk mode, the number of rows
+		// does not impact the generated SQL, since we rely on the
+		// driver to transfer multiple arrays of column values.
+		var stmtCacheKey string
+		if a.mu.templates.BulkDelete {
+			var err error
+			allArgs, err = toColumns(a.mu.templates.DeleteParameterCount, len(muts), allArgs)
+			if err != nil {
+				return err
+			}
+			stmtCacheKey = fmt.Sprintf("delete-%s-%d", a.target, a.mu.gen)
+		} else {
+			stmtCacheKey = fmt.Sprintf("delete-%s-%d-%d", a.target, a.mu.gen, len(muts))
+		}
+		var err error
+		stmt, err = a.cache.Prepare(ctx,
+			db,
+			stmtCacheKey,
+			func() (string, error) {
+				return a.mu.templates.deleteExpr(len(muts))
+			})
+		if err != nil {
+			return err
+		}
 	}
 
 	tag, err := stmt.ExecContext(ctx, allArgs...)
@@ -352,6 +429,7 @@ func (a *apply) deleteLocked(
 		return errors.WithStack(err)
 	}
 
+	a.observeStrategy(useCopy, len(muts), start)
 	a.deletes.Add(float64(affected))
 	log.WithFields(log.Fields{
 		"applied":  affected,
@@ -379,6 +457,19 @@ func (a *apply) upsertLocked(
 		return err
 	}
 
+	// Apply declarative transforms, which may discard some rows.
+	muts, allPayloadData, err := a.transformLocked(muts, allPayloadData)
+	if err != nil {
+		return err
+	}
+	// Add columns for new properties, if enabled.
+	if err := a.addColumnsLocked(allPayloadData); err != nil {
+		return err
+	}
+	for _, bag := range allPayloadData {
+		a.undeleteLocked(bag)
+	}
+
 	// Load data from sparse payloads. In the ideal case, this call to
 	// Load is a no-op, since all known properties will have valid
 	// values.
@@ -390,7 +481,8 @@ func (a *apply) upsertLocked(
 }
 
 // upsertArgsLocked shuffles the contents of the property bags into the
-// arguments that will be passed to the SQL command.
+// arguments that will be passed to the SQL command. The arguments are
+// always returned in a row-major layout.
 func (a *apply) upsertArgsLocked(bags []*merge.Bag) ([]any, error) {
 	// Allocate a slice for all mutation data. We'll reset the length
 	// once we know how many elements we actually have.
@@ -407,6 +499,10 @@ func (a *apply) upsertArgsLocked(bags []*merge.Bag) ([]any, error) {
 				"payload object offset %d", a.target, idx)
 		}
 
+		// An upsert always revives a soft-deleted row. This is applied
+		// here, too, since the bag may have come from a merge function.
+		a.undeleteLocked(rowData)
+
 		// Assign each mapped value to the argument slice we're going to
 		// send to the database.
 		for colName, entry := range rowData.Mapped.All() {
@@ -471,18 +567,7 @@ func (a *apply) upsertArgsLocked(bags []*merge.Bag) ([]any, error) {
 
 		argIdx += a.mu.templates.UpsertParameterCount
 	}
-	allArgs = allArgs[:argIdx]
-
-	// Pivot to columnar data layout if the target supports a
-	// bulk-transfer statement.
-	if a.mu.templates.BulkUpsert {
-		var err error
-		allArgs, err = toColumns(a.mu.templates.UpsertParameterCount, len(bags), allArgs)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return allArgs, nil
+	return allArgs[:argIdx], nil
 }
 
 // upsertBagsLocked contains the apply/merge functionality. The bags
@@ -506,35 +591,62 @@ func (a *apply) upsertBagsLocked(
 	start := time.Now()
 
 	// Converts the property bags into the expected argument layout.
-	allArgs, err := a.upsertArgsLocked(bags)
+	rowArgs, err := a.upsertArgsLocked(bags)
 	if err != nil {
 		return err
 	}
+	// Pivot to columnar data layout if the target supports a
+	// bulk-transfer statement.
+	allArgs := rowArgs
+	if a.mu.templates.BulkUpsert {
+		allArgs, err = toColumns(a.mu.templates.UpsertParameterCount, len(bags), rowArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Large batches are copied into a staging table, instead of being
+	// passed as substitution parameters. Custom templates always use
+	// substitution parameters.
+	raw, useCopy := a.copyConnLocked(db, len(bags))
+	useCopy = useCopy && template == ""
 
-	// Get a prepared statement handle that's attached to the
-	// transaction. The statement and its cache key will vary if the
-	// target supports bulk upserts. In bulk mode, the number of rows
-	// does not impact the generated SQL, since we rely on the driver to
-	// transfer multiple arrays of column values.
+	var stmt applyStmt
 	var stmtCacheKey string
-	if a.mu.templates.BulkUpsert {
-		stmtCacheKey = fmt.Sprintf("upsert-%s-%d-%v-%s", a.target, a.mu.gen, mode, template)
+	if useCopy {
+		copied, err := a.copyUpsertLocked(ctx, db, raw, mode, rowArgs)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = copied.Drop(ctx) }()
+		allArgs = nil
+		stmt = copied
+		stmtCacheKey = copied.source
 	} else {
-		stmtCacheKey = fmt.Sprintf("upsert-%s-%d-%d-%v-%s", a.target, a.mu.gen, len(bags), mode, template)
-	}
-	stmt, err := a.cache.Prepare(ctx,
-		db,
-		stmtCacheKey,
-		func() (string, error) {
-			if template != "" {
-				// We only support applyUnconditional with custom templates.
-				mode = applyUnconditional
-				return a.mu.templates.customExpr(len(bags), template, mode)
-			}
-			return a.mu.templates.upsertExpr(len(bags), mode)
-		})
-	if err != nil {
-		return err
+		// Get a prepared statement handle that's attached to the
+		// transaction. The statement and its cache key will vary if
+		// the target supports bulk upserts. In bulk mode, the number
+		// of rows does not impact the generated SQL, since we rely on
+		// the driver to transfer multiple arrays of column values.
+		if a.mu.templates.BulkUpsert {
+			stmtCacheKey = fmt.Sprintf("upsert-%s-%d-%v-%s", a.target, a.mu.gen, mode, template)
+		} else {
+			stmtCacheKey = fmt.Sprintf("upsert-%s-%d-%d-%v-%s", a.target, a.mu.gen, len(bags), mode, template)
+		}
+		stmt, err = a.cache.Prepare(ctx,
+			db,
+			stmtCacheKey,
+			func() (string, error) {
+				if template != "" {
+					// We only support applyUnconditional with custom templates.
+					mode = applyUnconditional
+					return a.mu.templates.customExpr(len(bags), template, mode)
+				}
+				return a.mu.templates.upsertExpr(len(bags), mode)
+			})
+		if err != nil {
+			return err
+		}
 	}
 
 	merger := a.mu.templates.Merger
@@ -567,9 +679,11 @@ func (a *apply) upsertBagsLocked(
 			conflicts = 0
 		}
 		a.conflicts.Add(float64(conflicts))
+		a.observeStrategy(useCopy, len(bags), start)
 		a.upserts.Add(float64(upserted))
 		log.WithFields(log.Fields{
 			"conflicts": conflicts,
+			"copy":      useCopy,
 			"duration":  time.Since(start),
 			"proposed":  len(bags),
 			"target":    a.target,
@@ -578,7 +692,15 @@ func (a *apply) upsertBagsLocked(
 		return nil
 	}
 
-	conflictingRows, err := stmt.QueryContext(ctx, allArgs...)
+	// Targets that can't return rows from an upsert statement will
+	// first select the blocking rows. These targets will execute the
+	// conditional upsert once the conflicting rows have been read.
+	var conflictingRows *sql.Rows
+	if a.mu.templates.conflicts == nil {
+		conflictingRows, err = stmt.QueryContext(ctx, allArgs...)
+	} else {
+		conflictingRows, err = a.queryConflictsLocked(ctx, db, len(bags), rowArgs)
+	}
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -602,6 +724,11 @@ func (a *apply) upsertBagsLocked(
 		if err := conflictingRows.Scan(scanPtrs...); err != nil {
 			return errors.WithStack(err)
 		}
+		// Some targets return, and lock, existing rows that do not
+		// block the proposed row.
+		if sourceIdx < 0 {
+			continue
+		}
 
 		// The conflict will have at least the blocking data and the
 		// conflicting properties.
@@ -634,10 +761,36 @@ func (a *apply) upsertBagsLocked(
 	if err := conflictingRows.Err(); err != nil {
 		return errors.WithStack(err)
 	}
+	if a.mu.templates.conflicts != nil {
+		tag, err := stmt.ExecContext(ctx, allArgs...)
+		if err != nil {
+			return errors.Wrap(err, stmtCacheKey)
+		}
+		// The conflicting rows were locked when they were read, but a
+		// concurrent writer could have inserted a new row before the
+		// upsert executed. Oracle reports one affected row for each
+		// merged row, so a shortfall indicates that a proposed row was
+		// skipped without being reported as a conflict. Returning an
+		// error will cause the batch to be retried.
+		if a.product == types.ProductOracle {
+			upserted, err := tag.RowsAffected()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if expected := int64(len(bags) - len(conflicts)); upserted < expected {
+				return errors.Errorf(
+					"%s: expected to upsert %d rows, but upserted %d; "+
+						"the table was modified concurrently",
+					a.target, expected, upserted)
+			}
+		}
+	}
 
+	a.observeStrategy(useCopy, len(bags), start)
 	a.upserts.Add(float64(len(bags)))
 	log.WithFields(log.Fields{
 		"conflicts": len(conflicts),
+		"copy":      useCopy,
 		"duration":  time.Since(start),
 		"proposed":  len(bags),
 		"target":    a.target,
@@ -668,7 +821,7 @@ func (a *apply) upsertBagsLocked(
 			if err != nil {
 				return err
 			}
-			if err := q.Enqueue(ctx, db, conflictMuts[idx]); err != nil {
+			if err := q.Enqueue(ctx, db, a.target.Base, conflictMuts[idx], nil); err != nil {
 				return err
 			}
 		case resolution.Apply != nil:
@@ -685,6 +838,26 @@ func (a *apply) upsertBagsLocked(
 	return a.upsertBagsLocked(ctx, db, applyUnconditional, nil, fixups, template)
 }
 
+// queryConflictsLocked executes the conflicts query for targets that
+// cannot return the blocking rows from the conditional upsert. The
+// query must be executed before the conditional upsert, within the same
+// transaction, to identify the rows that will not be applied. The
+// rowArgs must be in row-major layout, even if the target supports bulk
+// upserts.
+func (a *apply) queryConflictsLocked(
+	ctx context.Context, db types.TargetQuerier, rowCount int, rowArgs []any,
+) (*sql.Rows, error) {
+	stmtCacheKey := fmt.Sprintf("conflicts-%s-%d-%d", a.target, a.mu.gen, rowCount)
+	stmt, err := a.cache.Prepare(ctx, db, stmtCacheKey, func() (string, error) {
+		return a.mu.templates.conflictsExpr(rowCount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, rowArgs...)
+	return rows, errors.Wrap(err, stmtCacheKey)
+}
+
 // newBagLocked constructs a new property bag using cached metadata.
 func (a *apply) newBagLocked() *merge.Bag {
 	return merge.NewBag(a.mu.bagSpec)
@@ -709,6 +882,12 @@ func (a *apply) refreshUnlocked(configData *applycfg.Config, schemaData []types.
 		return err
 	}
 
+	// Compile declarative transforms.
+	transforms, err := transform.Compile(configData, schemaData)
+	if err != nil {
+		return errors.Wrapf(err, "invalid transform for table %s", a.target)
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.mu.bagSpec = &merge.BagSpec{
@@ -717,6 +896,7 @@ func (a *apply) refreshUnlocked(configData *applycfg.Config, schemaData []types.
 	}
 	a.mu.gen++
 	a.mu.templates = tmpl
+	a.mu.transforms = transforms
 	return nil
 }
 
@@ -851,12 +1031,13 @@ func toDBTypes(colData []types.ColData, values []any) error {
 
 // IsMergeSupported returns true if the applier supports three-way
 // merges for the given product.
-//
-// Work items in https://github.com/cockroachdb/replicator/issues/487
 func IsMergeSupported(product types.Product) bool {
 	switch product {
 	case types.ProductCockroachDB, types.ProductPostgreSQL:
 		return true
+	case types.ProductMariaDB, types.ProductMySQL, types.ProductOracle, types.ProductSQLite:
+		// These targets use a separate query to find conflicts.
+		return true
 	default:
 		return false
 	}
@@ -32,6 +32,8 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
 	"github.com/cockroachdb/replicator/internal/sinktest/mutations"
+	"github.com/cockroachdb/replicator/internal/sinktest/sqlitetest"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/batches"
@@ -40,6 +42,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/merge"
 	"github.com/cockroachdb/replicator/internal/util/retry"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1448,6 +1451,188 @@ func TestDeleteByBody(t *testing.T) {
 	})
 }
 
+// TestSoftDelete executes soft deletions against the target database.
+// A composite PK is used to ensure that each row's arguments include
+// both the key columns and the soft-delete marker, which is
+// significant for targets that perform bulk deletions.
+func TestSoftDelete(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	var schema string
+	switch fixture.TargetPool.Product {
+	case types.ProductMariaDB, types.ProductMySQL:
+		schema = `CREATE TABLE %s (a INT, b INT, c INT, deleted_at TIMESTAMP NULL, PRIMARY KEY (a, b))`
+	default:
+		schema = `CREATE TABLE %s (a INT, b INT, c INT, deleted_at TIMESTAMP, PRIMARY KEY (a, b))`
+	}
+	table, err := fixture.CreateTargetTable(ctx, schema)
+	r.NoError(err)
+
+	cfg := applycfg.NewConfig()
+	cfg.SoftDelete = ident.New("deleted_at")
+	r.NoError(fixture.Configs.Set(table.Name(), cfg))
+
+	const count = 10
+	muts := make([]types.Mutation, count)
+	for i := range muts {
+		muts[i] = types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"a":%d,"b":%d,"c":%d}`, i, i+1, i+2)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d,%d]`, i, i+1)),
+			Time: hlc.New(1, 0),
+		}
+	}
+
+	apply := fixture.Applier(ctx, table.Name())
+	r.NoError(apply(muts))
+
+	deletedAt := time.Unix(1_700_000_000, 0).UTC()
+	for i := range muts {
+		muts[i].Data = nil
+		muts[i].Deletion = true
+		muts[i].Time = hlc.New(deletedAt.UnixNano(), 0)
+	}
+	r.NoError(apply(muts))
+
+	// The rows should remain, but be marked as deleted.
+	ct, err := table.RowCount(ctx)
+	r.NoError(err)
+	r.Equal(count, ct)
+
+	var marked int
+	r.NoError(fixture.TargetPool.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT count(*) FROM %s WHERE deleted_at IS NOT NULL", table.Name())).Scan(&marked))
+	r.Equal(count, marked)
+}
+
+// TestCopyApply lowers the copy threshold so that batches are loaded
+// into PostgreSQL using the COPY protocol and verifies the rows that
+// were written.
+func TestCopyApply(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+	if fixture.TargetPool.Product != types.ProductPostgreSQL {
+		t.Skip("the copy strategy is only used with PostgreSQL")
+	}
+
+	ctx := fixture.Context
+	table, err := fixture.CreateTargetTable(ctx,
+		`CREATE TABLE %s (pk INT PRIMARY KEY, val VARCHAR(2048), doc JSONB)`)
+	r.NoError(err)
+
+	cfg := applycfg.NewConfig()
+	cfg.CopyThreshold = 10
+	r.NoError(fixture.Configs.Set(table.Name(), cfg))
+
+	// The copy strategy requires a transaction with a dedicated
+	// connection.
+	apply := func(muts []types.Mutation) {
+		tx, err := fixture.TargetPool.BeginConnTx(ctx, nil)
+		r.NoError(err)
+		defer func() { _ = tx.Rollback() }()
+		r.NoError(fixture.ApplyAcceptor.AcceptTableBatch(ctx,
+			sinktest.TableBatchOf(table.Name(), hlc.New(1, 0), muts),
+			&types.AcceptOptions{TargetQuerier: tx}))
+		r.NoError(tx.Commit())
+	}
+
+	const count = 100
+	muts := make([]types.Mutation, count)
+	for i := range muts {
+		// Include values that must be escaped and NULLs.
+		val, err := json.Marshal(fmt.Sprintf("tab\there\\%d", i))
+		r.NoError(err)
+		doc := fmt.Sprintf(`{"i":%d}`, i)
+		if i%2 == 0 {
+			doc = "null"
+		}
+		muts[i] = types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d,"val":%s,"doc":%s}`, i, val, doc)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, i)),
+			Time: hlc.New(1, 0),
+		}
+	}
+	before := copiedRows(t, table.Name())
+	apply(muts)
+	r.Equal(float64(count), copiedRows(t, table.Name())-before)
+
+	ct, err := table.RowCount(ctx)
+	r.NoError(err)
+	r.Equal(count, ct)
+	for _, i := range []int{0, 1, count - 1} {
+		var val string
+		var doc sql.NullString
+		r.NoError(fixture.TargetPool.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT val, doc FROM %s WHERE pk = $1", table.Name()), i).Scan(&val, &doc))
+		r.Equal(fmt.Sprintf("tab\there\\%d", i), val)
+		if i%2 == 0 {
+			r.False(doc.Valid)
+		} else {
+			r.JSONEq(fmt.Sprintf(`{"i":%d}`, i), doc.String)
+		}
+	}
+
+	// Update half of the rows and delete the other half.
+	for i := range muts {
+		if i%2 == 0 {
+			muts[i].Data = json.RawMessage(fmt.Sprintf(`{"pk":%d,"val":"updated","doc":null}`, i))
+		} else {
+			muts[i].Data = nil
+			muts[i].Deletion = true
+		}
+		muts[i].Time = hlc.New(2, 0)
+	}
+	before = copiedRows(t, table.Name())
+	apply(muts)
+	r.Equal(float64(count), copiedRows(t, table.Name())-before)
+
+	ct, err = table.RowCount(ctx)
+	r.NoError(err)
+	r.Equal(count/2, ct)
+	var updated int
+	r.NoError(fixture.TargetPool.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT count(*) FROM %s WHERE val = 'updated' AND pk %% 2 = 0", table.Name())).Scan(&updated))
+	r.Equal(count/2, updated)
+
+	// Batches below the threshold use statements.
+	before = copiedRows(t, table.Name())
+	apply(muts[:5])
+	r.Equal(before, copiedRows(t, table.Name()))
+}
+
+// copiedRows returns the number of rows that have been sent to the
+// table using the copy strategy.
+func copiedRows(t *testing.T, table ident.Table) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "apply_strategy_rows_total" {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			want := map[string]string{
+				"schema":   table.Schema().Raw(),
+				"table":    table.Table().Raw(),
+				"strategy": "copy",
+			}
+			for _, label := range metric.GetLabel() {
+				if expect, ok := want[label.GetName()]; ok && expect != label.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
 // Verifies "constant" and substitution params, including cases where
 // the PK is subject to rewriting.
 func TestExpressionColumns(t *testing.T) {
@@ -1581,16 +1766,7 @@ func TestMergeWiring(t *testing.T) {
 	})
 	r.NoError(fixture.Configs.Set(tblName, configData))
 
-	// Helper function to improve readability below. This will skip the
-	// test if a merge operation is not supported by the target
-	// database.
-	apply := func(t *testing.T, muts []types.Mutation) error {
-		err := fixture.Applier(ctx, tblName)(muts)
-		if err != nil && strings.HasPrefix(err.Error(), "merge operation not implemented") {
-			t.Skip(err.Error())
-		}
-		return err
-	}
+	apply := fixture.Applier(ctx, tblName)
 
 	now := time.Now()
 	const pk = 42
@@ -1605,7 +1781,7 @@ func TestMergeWiring(t *testing.T) {
 		}
 		data, err := json.Marshal(blocking)
 		r.NoError(err)
-		r.NoError(apply(t, []types.Mutation{
+		r.NoError(apply([]types.Mutation{
 			{
 				Data: data,
 				Key:  []byte(fmt.Sprintf("[%d]", pk)),
@@ -1643,7 +1819,7 @@ func TestMergeWiring(t *testing.T) {
 		shouldDiscard.Store(true)
 		defer shouldDiscard.Store(false)
 
-		r.NoError(apply(t, stale))
+		r.NoError(apply(stale))
 
 		var val int
 		r.NoError(fixture.TargetPool.QueryRowContext(ctx,
@@ -1660,7 +1836,7 @@ func TestMergeWiring(t *testing.T) {
 		dlqTable, err := fixture.CreateDLQTable(ctx)
 		r.NoError(err)
 
-		r.NoError(apply(t, stale))
+		r.NoError(apply(stale))
 
 		var val int
 		r.NoError(fixture.TargetPool.QueryRowContext(ctx,
@@ -1676,7 +1852,7 @@ func TestMergeWiring(t *testing.T) {
 	// Insert a "stale" value representing a delta of 1.
 	t.Run("merge_record", func(t *testing.T) {
 		r := require.New(t)
-		r.NoError(apply(t, stale))
+		r.NoError(apply(stale))
 
 		var val int
 		r.NoError(fixture.TargetPool.QueryRowContext(ctx,
@@ -1690,7 +1866,7 @@ func TestMergeWiring(t *testing.T) {
 		r := require.New(t)
 		shouldTwoWay.Store(true)
 		defer shouldTwoWay.Store(false)
-		r.NoError(apply(t, stale))
+		r.NoError(apply(stale))
 
 		var val int
 		r.NoError(fixture.TargetPool.QueryRowContext(ctx,
@@ -1703,7 +1879,7 @@ func TestMergeWiring(t *testing.T) {
 		r := require.New(t)
 		shouldTwoWay.Store(true)
 		defer shouldTwoWay.Store(false)
-		r.NoError(apply(t, stale))
+		r.NoError(apply(stale))
 
 		var val int
 		r.NoError(fixture.TargetPool.QueryRowContext(ctx,
@@ -1724,7 +1900,7 @@ func TestMergeWiring(t *testing.T) {
 		}
 		data, err := json.Marshal(accepted)
 		r.NoError(err)
-		r.NoError(apply(t, []types.Mutation{
+		r.NoError(apply([]types.Mutation{
 			{
 				Data: data,
 				Key:  []byte(fmt.Sprintf("[%d]", pk)),
@@ -1740,6 +1916,436 @@ func TestMergeWiring(t *testing.T) {
 	r.NoError(fixture.Diagnostics.Write(ctx, io.Discard, false))
 }
 
+// TestMergeSQLite exercises the separate conflict-detection query used
+// by targets other than CockroachDB and PostgreSQL. SQLite requires no
+// external services, so this test will run in any environment.
+func TestMergeSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, nil,
+		`CREATE TABLE kv (pk INTEGER PRIMARY KEY, val INTEGER, version INTEGER)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	acc := fixture.Acceptor
+	tbl := fixture.Table("kv")
+
+	var shouldDiscard atomic.Bool
+	valKey := ident.New("val")
+	configData := applycfg.NewConfig()
+	configData.CASColumns = ident.Idents{ident.New("version")}
+	configData.Merger = merge.Func(func(ctx context.Context, con *merge.Conflict) (*merge.Resolution, error) {
+		if shouldDiscard.Load() {
+			return &merge.Resolution{Drop: true}, nil
+		}
+		// Apply the delta from the proposed change to the target.
+		start := coerceToInt(r, con.Before.GetZero(valKey))
+		end := coerceToInt(r, con.Proposed.GetZero(valKey))
+		existing := coerceToInt(r, con.Target.GetZero(valKey))
+		con.Target.Put(valKey, existing+end-start)
+		return &merge.Resolution{Apply: con.Target}, nil
+	})
+	r.NoError(configs.Set(tbl, configData))
+
+	apply := func(muts ...types.Mutation) {
+		r.NoError(acc.AcceptTableBatch(ctx, sinktest.TableBatchOf(tbl, hlc.Zero(), muts),
+			&types.AcceptOptions{TargetQuerier: pool}))
+	}
+	readVal := func(pk int) int {
+		var val int
+		r.NoError(pool.QueryRowContext(ctx, "SELECT val FROM kv WHERE pk = ?", pk).Scan(&val))
+		return val
+	}
+
+	apply(types.Mutation{
+		Data: []byte(`{"pk":1,"val":10,"version":5}`),
+		Key:  []byte(`[1]`),
+	})
+	r.Equal(10, readVal(1))
+
+	// This update is stale relative to the blocking row.
+	stale := types.Mutation{
+		Before: []byte(`{"pk":1,"val":1,"version":1}`),
+		Data:   []byte(`{"pk":1,"val":3,"version":2}`),
+		Key:    []byte(`[1]`),
+	}
+	// A non-conflicting row in the same batch should be applied.
+	fresh := types.Mutation{
+		Data: []byte(`{"pk":2,"val":20,"version":1}`),
+		Key:  []byte(`[2]`),
+	}
+
+	shouldDiscard.Store(true)
+	apply(stale, fresh)
+	r.Equal(10, readVal(1))
+	r.Equal(20, readVal(2))
+
+	shouldDiscard.Store(false)
+	apply(stale)
+	r.Equal(12, readVal(1))
+
+	// A newer version is applied without calling the merge function.
+	apply(types.Mutation{
+		Data: []byte(`{"pk":1,"val":100,"version":6}`),
+		Key:  []byte(`[1]`),
+	})
+	r.Equal(100, readVal(1))
+}
+
+// TestSoftDeleteSQLite verifies that deletions are converted into
+// updates of a marker column, that the FK-ordered acceptor can soft
+// delete parent and child rows, and that a re-inserted row is revived.
+func TestSoftDeleteSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, nil,
+		`CREATE TABLE parent (pk INTEGER PRIMARY KEY, val INTEGER, deleted_at TIMESTAMP)`,
+		`CREATE TABLE child (pk INTEGER PRIMARY KEY, parent INTEGER REFERENCES parent(pk),
+is_deleted BOOLEAN NOT NULL DEFAULT false)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	watchers := fixture.Watchers
+	acc := fixture.Acceptor
+	schema := fixture.Schema
+	parent := ident.NewTable(schema, ident.New("parent"))
+	child := ident.NewTable(schema, ident.New("child"))
+	ordered := types.OrderedAcceptorFrom(acc, watchers)
+
+	parentCfg := applycfg.NewConfig()
+	parentCfg.SoftDelete = ident.New("deleted_at")
+	r.NoError(configs.Set(parent, parentCfg))
+	childCfg := applycfg.NewConfig()
+	childCfg.SoftDelete = ident.New("is_deleted")
+	r.NoError(configs.Set(child, childCfg))
+
+	apply := func(parentMuts, childMuts []types.Mutation) {
+		batch := &types.MultiBatch{}
+		for _, mut := range parentMuts {
+			r.NoError(batch.Accumulate(parent, mut))
+		}
+		for _, mut := range childMuts {
+			r.NoError(batch.Accumulate(child, mut))
+		}
+		r.NoError(ordered.AcceptMultiBatch(ctx, batch,
+			&types.AcceptOptions{TargetQuerier: pool}))
+	}
+
+	apply(
+		[]types.Mutation{{
+			Data: []byte(`{"pk":1,"val":10}`),
+			Key:  []byte(`[1]`),
+			Time: hlc.New(1, 0),
+		}},
+		[]types.Mutation{{
+			Data: []byte(`{"pk":1,"parent":1}`),
+			Key:  []byte(`[1]`),
+			Time: hlc.New(1, 0),
+		}},
+	)
+
+	// Delete both rows. The rows should remain, but be marked.
+	deletedAt := time.Unix(1_700_000_000, 0).UTC()
+	apply(
+		[]types.Mutation{{
+			Key:  []byte(`[1]`),
+			Time: hlc.New(deletedAt.UnixNano(), 0),
+		}},
+		[]types.Mutation{{
+			Key:  []byte(`[1]`),
+			Time: hlc.New(deletedAt.UnixNano(), 0),
+		}},
+	)
+
+	var deleted sql.NullTime
+	var val int
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT val, deleted_at FROM parent WHERE pk = 1").Scan(&val, &deleted))
+	r.Equal(10, val)
+	r.True(deleted.Valid)
+	r.True(deletedAt.Equal(deleted.Time))
+
+	var isDeleted bool
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT is_deleted FROM child WHERE pk = 1").Scan(&isDeleted))
+	r.True(isDeleted)
+
+	// Re-inserting the rows should undelete them.
+	apply(
+		[]types.Mutation{{
+			Data: []byte(`{"pk":1,"val":20}`),
+			Key:  []byte(`[1]`),
+			Time: hlc.New(deletedAt.UnixNano()+1, 0),
+		}},
+		[]types.Mutation{{
+			Data: []byte(`{"pk":1,"parent":1}`),
+			Key:  []byte(`[1]`),
+			Time: hlc.New(deletedAt.UnixNano()+1, 0),
+		}},
+	)
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT val, deleted_at FROM parent WHERE pk = 1").Scan(&val, &deleted))
+	r.Equal(20, val)
+	r.False(deleted.Valid)
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT is_deleted FROM child WHERE pk = 1").Scan(&isDeleted))
+	r.False(isDeleted)
+}
+
+// TestTransformsSQLite verifies that declarative transforms are applied
+// to upserts and that filtered rows are not written.
+func TestTransformsSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, nil,
+		`CREATE TABLE orders (pk INTEGER PRIMARY KEY, qty INTEGER, full_name TEXT,
+region TEXT, status TEXT)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	acc := fixture.Acceptor
+	tbl := fixture.Table("orders")
+
+	cfg := applycfg.NewConfig()
+	cfg.Casts.Put(ident.New("qty"), "int")
+	cfg.Constants.Put(ident.New("region"), "us-east")
+	cfg.Defaults.Put(ident.New("status"), "new")
+	cfg.Filters = []string{`{{ ne .status "test" }}`}
+	cfg.Ignore.Put(ident.New("first"), true)
+	cfg.Ignore.Put(ident.New("last"), true)
+	cfg.Templates.Put(ident.New("full_name"), "{{ .first }} {{ .last }}")
+	r.NoError(configs.Set(tbl, cfg))
+
+	batch := &types.TableBatch{Table: tbl, Time: hlc.New(1, 0)}
+	for key, data := range map[string]string{
+		`[1]`: `{"pk":1,"qty":"5","first":"Jane","last":"Doe"}`,
+		`[2]`: `{"pk":2,"qty":"7","first":"John","last":"Doe","status":"test"}`,
+		`[3]`: `{"pk":3,"qty":"9.0","first":"Alex","last":"Roe","status":"shipped"}`,
+	} {
+		r.NoError(batch.Accumulate(tbl, types.Mutation{
+			Data: []byte(data),
+			Key:  []byte(key),
+			Time: hlc.New(1, 0),
+		}))
+	}
+	r.NoError(acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool}))
+
+	type row struct {
+		PK, Qty                  int
+		FullName, Region, Status string
+	}
+	readRows := func() []row {
+		rows, err := pool.QueryContext(ctx,
+			"SELECT pk, qty, full_name, region, status FROM orders ORDER BY pk")
+		r.NoError(err)
+		defer rows.Close()
+		var found []row
+		for rows.Next() {
+			var next row
+			r.NoError(rows.Scan(&next.PK, &next.Qty, &next.FullName, &next.Region, &next.Status))
+			found = append(found, next)
+		}
+		r.NoError(rows.Err())
+		return found
+	}
+	r.Equal([]row{
+		{1, 5, "Jane Doe", "us-east", "new"},
+		{3, 9, "Alex Roe", "us-east", "shipped"},
+	}, readRows())
+
+	// Deletions only carry the key, so they must find the transformed
+	// rows that were upserted above.
+	batch = &types.TableBatch{Table: tbl, Time: hlc.New(2, 0)}
+	r.NoError(batch.Accumulate(tbl, types.Mutation{
+		Key:  []byte(`[1]`),
+		Time: hlc.New(2, 0),
+	}))
+	r.NoError(acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool}))
+	r.Equal([]row{
+		{3, 9, "Alex Roe", "us-east", "shipped"},
+	}, readRows())
+}
+
+// TestUpdateChangedSQLite verifies that only changed columns are
+// written when a before value is present, and that missing rows are
+// upserted.
+func TestUpdateChangedSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, nil,
+		`CREATE TABLE wide (pk INTEGER PRIMARY KEY, a INTEGER, b INTEGER, c INTEGER)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	acc := fixture.Acceptor
+	tbl := fixture.Table("wide")
+
+	cfg := applycfg.NewConfig()
+	cfg.UpdateChanged = true
+	r.NoError(configs.Set(tbl, cfg))
+
+	apply := func(muts ...types.Mutation) {
+		batch := &types.TableBatch{Table: tbl, Time: muts[0].Time}
+		for _, mut := range muts {
+			r.NoError(batch.Accumulate(tbl, mut))
+		}
+		r.NoError(acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool}))
+	}
+
+	apply(types.Mutation{
+		Data: []byte(`{"pk":1,"a":1,"b":1,"c":1}`),
+		Key:  []byte(`[1]`),
+		Time: hlc.New(1, 0),
+	})
+
+	// Modify the target row, so we can tell which columns are written.
+	_, err = pool.ExecContext(ctx, `UPDATE wide SET a = 100, c = 100 WHERE pk = 1`)
+	r.NoError(err)
+
+	apply(
+		// Only b has changed.
+		types.Mutation{
+			Before: []byte(`{"pk":1,"a":1,"b":1,"c":1}`),
+			Data:   []byte(`{"pk":1,"a":1,"b":2,"c":1}`),
+			Key:    []byte(`[1]`),
+			Time:   hlc.New(2, 0),
+		},
+		// The row is missing, so it should be upserted.
+		types.Mutation{
+			Before: []byte(`{"pk":2,"a":2,"b":2,"c":2}`),
+			Data:   []byte(`{"pk":2,"a":2,"b":2,"c":3}`),
+			Key:    []byte(`[2]`),
+			Time:   hlc.New(2, 0),
+		},
+		// Nothing has changed, but the row is also missing.
+		types.Mutation{
+			Before: []byte(`{"pk":3,"a":3,"b":3,"c":3}`),
+			Data:   []byte(`{"pk":3,"a":3,"b":3,"c":3}`),
+			Key:    []byte(`[3]`),
+			Time:   hlc.New(2, 0),
+		},
+	)
+
+	rows, err := pool.QueryContext(ctx, "SELECT pk, a, b, c FROM wide ORDER BY pk")
+	r.NoError(err)
+	defer rows.Close()
+	var found [][4]int
+	for rows.Next() {
+		var next [4]int
+		r.NoError(rows.Scan(&next[0], &next[1], &next[2], &next[3]))
+		found = append(found, next)
+	}
+	r.NoError(rows.Err())
+	r.Equal([][4]int{
+		{1, 100, 2, 100},
+		{2, 2, 2, 3},
+		{3, 3, 3, 3},
+	}, found)
+}
+
+// TestAddColumnsSQLite verifies that unknown properties in the
+// incoming data are added as columns in the target and that the
+// batch can be applied once the schema has been refreshed.
+func TestAddColumnsSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DDL: &ddl.Config{AddColumns: true}},
+		`CREATE TABLE kv (pk INTEGER PRIMARY KEY, val INTEGER)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	acc := fixture.Acceptor
+	tbl := fixture.Table("kv")
+
+	batch := &types.TableBatch{Table: tbl, Time: hlc.New(1, 0)}
+	r.NoError(batch.Accumulate(tbl, types.Mutation{
+		Data: []byte(`{"pk":1,"val":1,"name":"one","score":1.5,"unknown":null}`),
+		Key:  []byte(`[1]`),
+		Time: hlc.New(1, 0),
+	}))
+
+	// The first attempt reports the drift and the columns are added
+	// in the background.
+	err = acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool})
+	r.ErrorContains(err, "schema drift detected")
+
+	// The null-valued property cannot be typed, so the batch is
+	// rejected until a non-null value is seen.
+	r.Eventually(func() bool {
+		err := acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool})
+		return err != nil && strings.Contains(err.Error(), "unexpected columns: unknown")
+	}, 10*time.Second, 10*time.Millisecond)
+
+	r.NoError(configs.Set(tbl, func() *applycfg.Config {
+		cfg := applycfg.NewConfig()
+		cfg.Ignore.Put(ident.New("unknown"), true)
+		return cfg
+	}()))
+	r.Eventually(func() bool {
+		return acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool}) == nil
+	}, 10*time.Second, 10*time.Millisecond)
+
+	var name string
+	var score float64
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT name, score FROM kv WHERE pk = 1").Scan(&name, &score))
+	r.Equal("one", name)
+	r.Equal(1.5, score)
+}
+
+// TestAddColumnsIgnoredSQLite verifies that columns are not added for
+// properties that the user has chosen to ignore or which are stored in
+// an extras column.
+func TestAddColumnsIgnoredSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DDL: &ddl.Config{AddColumns: true}},
+		`CREATE TABLE ignored (pk INTEGER PRIMARY KEY, val INTEGER)`,
+		`CREATE TABLE extras (pk INTEGER PRIMARY KEY, val INTEGER, extra TEXT)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	acc := fixture.Acceptor
+	schema := fixture.Schema
+	ignoredTbl := ident.NewTable(schema, ident.New("ignored"))
+	extrasTbl := ident.NewTable(schema, ident.New("extras"))
+
+	ignoredCfg := applycfg.NewConfig()
+	ignoredCfg.Ignore.Put(ident.New("secret"), true)
+	r.NoError(configs.Set(ignoredTbl, ignoredCfg))
+	extrasCfg := applycfg.NewConfig()
+	extrasCfg.Extras = ident.New("extra")
+	r.NoError(configs.Set(extrasTbl, extrasCfg))
+
+	for _, tbl := range []ident.Table{ignoredTbl, extrasTbl} {
+		batch := &types.TableBatch{Table: tbl, Time: hlc.New(1, 0)}
+		r.NoError(batch.Accumulate(tbl, types.Mutation{
+			Data: []byte(`{"pk":1,"val":1,"secret":"hidden"}`),
+			Key:  []byte(`[1]`),
+			Time: hlc.New(1, 0),
+		}))
+		// Schema drift would be reported if a column were added.
+		r.NoError(acc.AcceptTableBatch(ctx, batch, &types.AcceptOptions{TargetQuerier: pool}))
+	}
+
+	for _, tbl := range []string{"ignored", "extras"} {
+		var count int
+		r.NoError(pool.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT count(*) FROM pragma_table_info('%s') WHERE name = 'secret'", tbl)).Scan(&count))
+		r.Zero(count, tbl)
+	}
+	var extra string
+	r.NoError(pool.QueryRowContext(ctx,
+		"SELECT extra FROM extras WHERE pk = 1").Scan(&extra))
+	r.JSONEq(`{"secret":"hidden"}`, extra)
+}
+
 // This tests ignoring a primary key column, an extant db column,
 // and a column which only exists in the incoming payload.
 func TestIgnoredColumns(t *testing.T) {
@@ -37,6 +37,7 @@ import (
 type columnMapping struct {
 	Conditions           []types.ColData              // The version-like fields for CAS ops.
 	Columns              []types.ColData              // All columns named in an upsert statement.
+	CopyThreshold        int                          // Minimum batch size for a bulk copy.
 	Data                 []types.ColData              // Non-PK, non-ignored columns.
 	Deadlines            types.Deadlines              // Allow too-old data to just be dropped.
 	DeleteParameterCount int                          // The number of SQL arguments.
@@ -50,7 +51,9 @@ type columnMapping struct {
 	PKDelete             []types.ColData              // The names of the PK columns to delete.
 	Renames              *ident.Map[ident.Ident]      // External (source) names to target names.
 	RowLimit             int                          // Limits number of generated bind variables.
+	SoftDelete           *types.ColData               // If set, mark deleted rows in this column.
 	TableName            *ident.Hinted[ident.Table]   // The target table.
+	UpdateChanged        bool                         // Update only the changed columns of existing rows.
 	UpsertParameterCount int                          // The number of SQL arguments.
 }
 
@@ -70,17 +73,21 @@ func newColumnMapping(
 	table *ident.Hinted[ident.Table],
 ) (*columnMapping, error) {
 	ret := &columnMapping{
-		Conditions:   make([]types.ColData, len(cfg.CASColumns)),
-		Deadlines:    &ident.Map[time.Duration]{},
-		Exprs:        &ident.Map[string]{},
-		ExtrasColIdx: -1,
-		Positions:    &ident.Map[positionalColumn]{},
-		Product:      product,
-		Renames:      &ident.Map[ident.Ident]{},
-		RowLimit:     cfg.RowLimit,
-		TableName:    table,
+		Conditions:    make([]types.ColData, len(cfg.CASColumns)),
+		CopyThreshold: cfg.CopyThreshold,
+		Deadlines:     &ident.Map[time.Duration]{},
+		Exprs:         &ident.Map[string]{},
+		ExtrasColIdx:  -1,
+		Positions:     &ident.Map[positionalColumn]{},
+		Product:       product,
+		Renames:       &ident.Map[ident.Ident]{},
+		RowLimit:      cfg.RowLimit,
+		TableName:     table,
 	}
 
+	if ret.CopyThreshold == 0 {
+		ret.CopyThreshold = applycfg.DefaultCopyThreshold
+	}
 	if ret.RowLimit <= 0 {
 		ret.RowLimit = applycfg.DefaultRowLimit
 	}
@@ -91,6 +98,11 @@ func newColumnMapping(
 		ret.Merger = cfg.Merger
 	}
 
+	// Changed-column updates bypass the conditional upsert, so they're
+	// only used if the table has no CAS or deadline columns.
+	ret.UpdateChanged = cfg.UpdateChanged &&
+		len(cfg.CASColumns) == 0 && cfg.Deadlines.Len() == 0
+
 	// Map cas column names to their order in the comparison tuple.
 	var casMap ident.Map[int]
 	for idx, name := range cfg.CASColumns {
@@ -187,6 +199,23 @@ func newColumnMapping(
 	ret.DeleteParameterCount = len(ret.PKDelete)
 	ret.UpsertParameterCount = currentParameterIndex
 
+	// In soft-delete mode, each deleted row has an additional argument
+	// to set the marker column's value.
+	if !cfg.SoftDelete.Empty() {
+		found, ok := ret.Positions.Get(cfg.SoftDelete)
+		if !ok {
+			return nil, errors.Errorf("soft-delete column %s not found in %s",
+				cfg.SoftDelete, table)
+		}
+		if found.Primary || found.UpsertIndex < 0 {
+			return nil, errors.Errorf("soft-delete column %s in %s must be "+
+				"a non-PK column that is not ignored or computed",
+				cfg.SoftDelete, table)
+		}
+		ret.SoftDelete = &found.ColData
+		ret.DeleteParameterCount++
+	}
+
 	// We also allow the user to force non-existent columns to be
 	// ignored (e.g. to drop a column).
 	for tgt := range cfg.Ignore.Keys() {
@@ -215,3 +244,25 @@ func newColumnMapping(
 
 	return ret, nil
 }
+
+// updateMapping returns a copy of the columnMapping which describes an
+// UPDATE of the given columns. The PK columns are followed by the
+// changed columns, each of which has a single substitution parameter.
+func (m *columnMapping) updateMapping(changed []types.ColData) *columnMapping {
+	ret := *m
+	ret.Columns = make([]types.ColData, 0, len(m.PK)+len(changed))
+	ret.Columns = append(ret.Columns, m.PK...)
+	ret.Columns = append(ret.Columns, changed...)
+	ret.Data = changed
+	ret.Positions = &ident.Map[positionalColumn]{}
+	for idx, col := range ret.Columns {
+		ret.Positions.Put(col.Name, positionalColumn{
+			ColData:       col,
+			DeleteIndex:   -1,
+			UpsertIndex:   idx,
+			ValidityIndex: -1,
+		})
+	}
+	ret.UpsertParameterCount = len(ret.Columns)
+	return &ret
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/sinktest/base"
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/target/load"
+	"github.com/cockroachdb/replicator/internal/target/schemawatch"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConflictsLockMatchedRows verifies that the conflicts query locks
+// every existing row that matches a proposed row, so that a concurrent
+// writer cannot turn a non-blocking row into a blocking row before the
+// conditional upsert is executed.
+func TestConflictsLockMatchedRows(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := base.NewFixture(t)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+
+	switch pool.Product {
+	case types.ProductMariaDB, types.ProductMySQL, types.ProductOracle:
+	default:
+		t.Skip("the conflicts query is only used by targets that cannot return rows from an upsert")
+	}
+
+	tbl, err := fixture.CreateTargetTable(ctx, "CREATE TABLE %s (pk INT PRIMARY KEY, ver INT)")
+	r.NoError(err)
+	for _, stmt := range []string{
+		"INSERT INTO %s (pk, ver) VALUES (1, 10)", // Blocks the proposed row.
+		"INSERT INTO %s (pk, ver) VALUES (2, 1)",  // Does not block the proposed row.
+	} {
+		_, err := pool.ExecContext(ctx, fmt.Sprintf(stmt, tbl.Name()))
+		r.NoError(err)
+	}
+
+	diags := diag.New(ctx)
+	configs, err := applycfg.ProvideConfigs(diags)
+	r.NoError(err)
+	cfg := applycfg.NewConfig()
+	cfg.CASColumns = ident.Idents{ident.New("ver")}
+	r.NoError(configs.Set(tbl.Name(), cfg))
+	loader, err := load.ProvideLoader(fixture.TargetCache, pool)
+	r.NoError(err)
+	watchers, err := schemawatch.ProvideFactory(ctx, &schemawatch.Config{}, pool, diags,
+		schemawatch.ProvideBackup(&memo.Memory{}, nil))
+	r.NoError(err)
+	acc, err := ProvideAcceptor(ctx, fixture.TargetCache, configs, nil, diags, nil, loader, pool, watchers)
+	r.NoError(err)
+	a, err := acc.factory.Get(ctx, tbl.Name())
+	r.NoError(err)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var bags []*merge.Bag
+	for _, data := range []string{`{"pk":1,"ver":5}`, `{"pk":2,"ver":5}`} {
+		bag := a.newBagLocked()
+		r.NoError(bag.UnmarshalJSON([]byte(data)))
+		bags = append(bags, bag)
+	}
+	rowArgs, err := a.upsertArgsLocked(bags)
+	r.NoError(err)
+
+	tx, err := pool.BeginTx(ctx, nil)
+	r.NoError(err)
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := a.queryConflictsLocked(ctx, tx, len(bags), rowArgs)
+	r.NoError(err)
+	found := make(map[int]int) // pk -> __idx__
+	for rows.Next() {
+		var idx int
+		var pk, ver any
+		r.NoError(rows.Scan(&idx, &pk, &ver))
+		found[int(coerceInt(r, pk))] = idx
+	}
+	r.NoError(rows.Err())
+	r.NoError(rows.Close())
+	r.Equal(map[int]int{1: 0, 2: -1}, found)
+
+	// A concurrent writer must wait for the non-blocking row to be
+	// released by the transaction.
+	written := make(chan error, 1)
+	go func() {
+		_, err := pool.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET ver = 20 WHERE pk = 2", tbl.Name()))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		r.Failf("concurrent write was not blocked", "error: %v", err)
+	case <-time.After(250 * time.Millisecond):
+	}
+	r.NoError(tx.Rollback())
+	select {
+	case err := <-written:
+		r.NoError(err)
+	case <-time.After(30 * time.Second):
+		r.Fail("concurrent write did not complete")
+	}
+}
+
+// coerceInt converts the numeric types returned by the drivers.
+func coerceInt(r *require.Assertions, value any) int64 {
+	switch t := value.(type) {
+	case int64:
+		return t
+	case []byte:
+		var ret int64
+		_, err := fmt.Sscan(string(t), &ret)
+		r.NoError(err)
+		return ret
+	case string:
+		var ret int64
+		_, err := fmt.Sscan(t, &ret)
+		r.NoError(err)
+		return ret
+	default:
+		r.Failf("unexpected type", "%T", value)
+		return 0
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// copyTypesQuery converts parameter type OIDs into type names that can
+// be used to declare the columns of a staging table. Parameters whose
+// type could not be inferred are declared as TEXT.
+const copyTypesQuery = `
+SELECT array_agg(CASE WHEN o = 0 THEN 'TEXT' ELSE format_type(o, NULL) END ORDER BY n)
+  FROM unnest($1::OID[]) WITH ORDINALITY AS u(o, n)`
+
+// copySeq is used to give each staging table a unique name.
+var copySeq atomic.Int64
+
+// rawConner is implemented by [types.ConnTx] to provide access to the
+// underlying driver connection. Only the sequencers that open a target
+// transaction use a ConnTx, so batches applied directly to the target
+// pool (e.g. in immediate or best-effort mode) never use the bulk copy.
+type rawConner interface {
+	Raw(func(driverConn any) error) error
+}
+
+// pgxConner is implemented by the pgx stdlib driver connection.
+type pgxConner interface {
+	Conn() *pgx.Conn
+}
+
+// copyColumn returns the name of the staging-table column that holds
+// the value of a 1-based substitution parameter.
+func copyColumn(param int) string {
+	return "p" + strconv.Itoa(param)
+}
+
+// copyConnLocked returns a connection that may be used to copy data
+// into a staging table, if the bulk-copy strategy should be used for a
+// batch of the given size.
+func (a *apply) copyConnLocked(db types.TargetQuerier, rowCount int) (rawConner, bool) {
+	if a.product != types.ProductPostgreSQL {
+		return nil, false
+	}
+	threshold := a.mu.templates.CopyThreshold
+	if threshold < 0 || rowCount < threshold {
+		return nil, false
+	}
+	raw, ok := db.(rawConner)
+	return raw, ok
+}
+
+// observeStrategy records the time taken to send some number of rows
+// to the target, using either the bulk-copy or statement strategy.
+func (a *apply) observeStrategy(useCopy bool, rowCount int, start time.Time) {
+	if useCopy {
+		a.copyDurations.Observe(time.Since(start).Seconds())
+		a.copyRows.Add(float64(rowCount))
+	} else {
+		a.stmtDurations.Observe(time.Since(start).Seconds())
+		a.stmtRows.Add(float64(rowCount))
+	}
+}
+
+// copyLocked creates a temporary staging table and uses the COPY
+// protocol to load the row-major arguments into it. The name of the
+// staging table is returned. The staging table has a zero-based
+// __idx__ column, followed by one column per substitution parameter.
+//
+// The probe statement is used to infer the types of the substitution
+// parameters. This ensures that the values are interpreted in the same
+// way as they would be if they had been passed as arguments to the
+// probe statement.
+func (a *apply) copyLocked(
+	ctx context.Context, raw rawConner, probe string, paramCount int, rowArgs []any,
+) (string, error) {
+	source := fmt.Sprintf("_replicator_copy_%d", copySeq.Add(1))
+	err := raw.Raw(func(driverConn any) error {
+		std, ok := driverConn.(pgxConner)
+		if !ok {
+			return errors.Errorf("unexpected driver connection type %T", driverConn)
+		}
+		conn := std.Conn()
+
+		desc, err := conn.PgConn().Prepare(ctx, "", probe, nil)
+		if err != nil {
+			return errors.Wrap(err, probe)
+		}
+		oids := make([]uint32, paramCount)
+		copy(oids, desc.ParamOIDs)
+
+		var typeNames []string
+		if err := conn.QueryRow(ctx, copyTypesQuery, oids).Scan(&typeNames); err != nil {
+			return errors.WithStack(err)
+		}
+
+		var create strings.Builder
+		fmt.Fprintf(&create, "CREATE TEMPORARY TABLE %s (__idx__ INT8", source)
+		for idx, typeName := range typeNames {
+			fmt.Fprintf(&create, ", %s %s", copyColumn(idx+1), typeName)
+		}
+		create.WriteString(") ON COMMIT DROP")
+		if _, err := conn.Exec(ctx, create.String()); err != nil {
+			return errors.Wrap(err, create.String())
+		}
+
+		data, err := copyText(conn.TypeMap(), oids, rowArgs)
+		if err != nil {
+			return err
+		}
+		_, err = conn.PgConn().CopyFrom(ctx,
+			bytes.NewReader(data), fmt.Sprintf("COPY %s FROM STDIN", source))
+		return errors.WithStack(err)
+	})
+	return source, err
+}
+
+// copyDeleteLocked copies the row-major delete arguments into a
+// staging table and returns a statement that will delete the rows.
+func (a *apply) copyDeleteLocked(
+	ctx context.Context, db types.TargetQuerier, raw rawConner, rowArgs []any,
+) (*copyStmt, error) {
+	probe, err := a.mu.templates.deleteExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	source, err := a.copyLocked(ctx, raw, probe, a.mu.templates.DeleteParameterCount, rowArgs)
+	if err != nil {
+		return nil, err
+	}
+	q, err := a.mu.templates.copyDeleteExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	return &copyStmt{db: db, source: source, sql: q}, nil
+}
+
+// copyUpsertLocked copies the row-major upsert arguments into a
+// staging table and returns a statement that will upsert the rows.
+func (a *apply) copyUpsertLocked(
+	ctx context.Context, db types.TargetQuerier, raw rawConner, mode applyMode, rowArgs []any,
+) (*copyStmt, error) {
+	probe, err := a.mu.templates.upsertExpr(1, mode)
+	if err != nil {
+		return nil, err
+	}
+	source, err := a.copyLocked(ctx, raw, probe, a.mu.templates.UpsertParameterCount, rowArgs)
+	if err != nil {
+		return nil, err
+	}
+	q, err := a.mu.templates.copyUpsertExpr(source, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &copyStmt{db: db, source: source, sql: q}, nil
+}
+
+// applyStmt contains the methods of [sql.Stmt] that are used when
+// applying data. It is also implemented by copyStmt.
+type applyStmt interface {
+	ExecContext(ctx context.Context, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, args ...any) (*sql.Rows, error)
+}
+
+var (
+	_ applyStmt = (*copyStmt)(nil)
+	_ applyStmt = (*sql.Stmt)(nil)
+)
+
+// copyStmt is a statement that reads from a staging table. Since the
+// staging table has a unique name, the statement is not cached.
+type copyStmt struct {
+	db     types.TargetQuerier
+	source string // The name of the staging table.
+	sql    string
+}
+
+// Drop drops the staging table. Staging tables are also dropped when
+// the transaction commits, but eagerly dropping them releases the
+// storage when many batches are applied in a single transaction.
+func (s *copyStmt) Drop(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", s.source))
+	return errors.WithStack(err)
+}
+
+// ExecContext implements applyStmt.
+func (s *copyStmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.sql, args...)
+}
+
+// QueryContext implements applyStmt.
+func (s *copyStmt) QueryContext(ctx context.Context, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.sql, args...)
+}
+
+// copyText encodes the row-major arguments using the text format of
+// the COPY protocol. The OIDs determine the text representation of
+// each parameter. Each row is prefixed with its zero-based index.
+func copyText(m *pgtype.Map, oids []uint32, rowArgs []any) ([]byte, error) {
+	var buf bytes.Buffer
+	// Encode returns a nil slice for SQL NULL values, so we want to
+	// start from a non-nil slice.
+	scratch := make([]byte, 0, 64)
+	for row := 0; row*len(oids) < len(rowArgs); row++ {
+		buf.WriteString(strconv.Itoa(row))
+		for col, oid := range oids {
+			buf.WriteByte('\t')
+			value := rowArgs[row*len(oids)+col]
+			var err error
+			scratch, err = m.Encode(oid, pgtype.TextFormatCode, value, scratch[:0])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if scratch == nil {
+				buf.WriteString(`\N`)
+				scratch = make([]byte, 0, 64)
+				continue
+			}
+			copyEscape(&buf, scratch)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// copyEscape writes the value to the buffer, escaping the characters
+// that have special meaning in the COPY text format.
+func copyEscape(buf *bytes.Buffer, value []byte) {
+	for _, b := range value {
+		switch b {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteByte(b)
+		}
+	}
+}
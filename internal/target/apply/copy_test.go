@@ -0,0 +1,71 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyText(t *testing.T) {
+	r := require.New(t)
+
+	oids := []uint32{pgtype.Int8OID, pgtype.TextOID, pgtype.JSONBOID, pgtype.TextArrayOID}
+	data, err := copyText(pgtype.NewMap(), oids, []any{
+		"1", "hello", map[string]any{"k": "v"}, []any{"a", "b"},
+		"2", "tab\there\\", nil, nil,
+		"3", "", []any{1}, []any{},
+	})
+	r.NoError(err)
+	r.Equal(
+		"0\t1\thello\t{\"k\":\"v\"}\t{a,b}\n"+
+			"1\t2\ttab\\there\\\\\t\\N\t\\N\n"+
+			"2\t3\t\t[1]\t{}\n",
+		string(data))
+}
+
+func TestCopyConn(t *testing.T) {
+	r := require.New(t)
+
+	a := &apply{product: types.ProductPostgreSQL}
+	a.mu.templates = &templates{columnMapping: &columnMapping{CopyThreshold: 10}}
+
+	conn := &types.ConnTx{}
+	_, ok := a.copyConnLocked(conn, 9)
+	r.False(ok)
+	_, ok = a.copyConnLocked(conn, 10)
+	r.True(ok)
+
+	// Regular transactions don't expose a driver connection.
+	_, ok = a.copyConnLocked((*sql.Tx)(nil), 10)
+	r.False(ok)
+
+	// Negative values disable the copy strategy.
+	a.mu.templates.CopyThreshold = -1
+	_, ok = a.copyConnLocked(conn, 10)
+	r.False(ok)
+
+	// Only PostgreSQL is supported.
+	a.mu.templates.CopyThreshold = 10
+	a.product = types.ProductCockroachDB
+	_, ok = a.copyConnLocked(conn, 10)
+	r.False(ok)
+}
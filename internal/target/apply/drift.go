@@ -0,0 +1,83 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// addColumnsLocked looks for properties in the decoded upserts that do
+// not map to a column in the target table. If columns may be added to
+// the target, the column types are inferred from the values and an
+// error is returned so that the batch will be retried. Properties that
+// are ignored by the user or which will be stored in an extras column
+// do not cause columns to be added.
+//
+// The schema change is made asynchronously, since it may not be able
+// to complete while the caller's transaction remains open. Once the
+// change has been made, the schema watcher will be refreshed, which
+// will update the applier.
+func (a *apply) addColumnsLocked(bags []*merge.Bag) error {
+	if !a.creator.AddsColumns() || a.mu.templates.ExtrasColIdx >= 0 {
+		return nil
+	}
+	var ignored ident.Map[bool]
+	for _, name := range a.mu.templates.Ignore {
+		ignored.Put(name, true)
+	}
+	values := &ident.Map[[]any]{}
+	for _, bag := range bags {
+		for name, value := range bag.Unmapped.All() {
+			if ignored.GetZero(name) {
+				continue
+			}
+			values.Put(name, append(values.GetZero(name), value))
+		}
+	}
+	var cols []ddl.Column
+	var names []string
+	for name, vals := range values.All() {
+		kind := ddl.InferKind(vals...)
+		// We can't infer a type if we've only seen nulls. The
+		// property will be handled as though the policy was disabled.
+		if kind == ddl.KindUnknown {
+			continue
+		}
+		cols = append(cols, ddl.Column{Name: name, Kind: kind})
+		names = append(names, name.String())
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	table := a.target.Base
+	a.stop.Go(func(ctx *stopper.Context) error {
+		if err := a.creator.AddColumns(ctx, table, cols); err != nil {
+			log.WithError(err).WithField("table", table).Warn("could not add columns")
+		}
+		return nil
+	})
+	return errors.Errorf("schema drift detected in %s: adding columns %s; will retry",
+		a.target, strings.Join(names, ", "))
+}
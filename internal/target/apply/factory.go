@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -32,6 +33,7 @@ import (
 type factory struct {
 	cache    *types.TargetStatements
 	configs  *applycfg.Configs
+	creator  *ddl.Creator
 	dlqs     types.DLQs
 	loader   *load.Loader
 	poolInfo *types.PoolInfo
@@ -17,6 +17,7 @@
 package apply
 
 import (
+	"slices"
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/util/metrics"
@@ -24,7 +25,17 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// Values for the strategy label.
+const (
+	strategyCopy      = "copy"
+	strategyStatement = "statement"
+)
+
 var (
+	// strategyLabels extend the table labels with the mechanism used
+	// to send rows to the target.
+	strategyLabels = append(slices.Clip(metrics.TableLabels), "strategy")
+
 	applyConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "apply_conflicts_total",
 		Help: "the number of rows that experienced a CAS conflict",
@@ -42,6 +53,10 @@ var (
 		Name: "apply_errors_total",
 		Help: "the number of times an error was encountered while applying mutations",
 	}, metrics.TableLabels)
+	applyFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apply_filtered_total",
+		Help: "the number of upserted rows discarded by a filter",
+	}, metrics.TableLabels)
 	applyMutationAge = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name: "apply_mutation_age_seconds",
 		Help: "the age of the mutation when it was applied; " +
@@ -52,6 +67,19 @@ var (
 		Name: "apply_resolves_total",
 		Help: "the number of rows that experienced a CAS conflict and which were resolved",
 	}, metrics.TableLabels)
+	applyStrategyDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "apply_strategy_duration_seconds",
+		Help:    "the length of time it took to send a batch of rows to the target, by strategy",
+		Buckets: metrics.LatencyBuckets,
+	}, strategyLabels)
+	applyStrategyRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apply_strategy_rows_total",
+		Help: "the number of rows sent to the target, by strategy",
+	}, strategyLabels)
+	applyUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apply_updates_total",
+		Help: "the number of rows updated by changed-column updates",
+	}, metrics.TableLabels)
 	applyUpserts = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "apply_upserts_total",
 		Help: "the number of rows upserted",
@@ -18,6 +18,7 @@ package apply
 
 import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -36,6 +37,7 @@ func ProvideAcceptor(
 	ctx *stopper.Context,
 	cache *types.TargetStatements,
 	configs *applycfg.Configs,
+	creator *ddl.Creator,
 	diags *diag.Diagnostics,
 	dlqs types.DLQs,
 	loader *load.Loader,
@@ -45,6 +47,7 @@ func ProvideAcceptor(
 	f := &factory{
 		cache:    cache,
 		configs:  configs,
+		creator:  creator,
 		dlqs:     dlqs,
 		loader:   loader,
 		poolInfo: target.Info(),
@@ -0,0 +1,66 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+)
+
+// softDeleteValue returns the value to store in a soft-delete column
+// when a row is deleted at the given time. The kind of value is
+// inferred from the column's type:
+//   - boolean (or MySQL TINYINT) columns are set to true
+//   - date or time columns are set to the wall time
+//   - other integer columns are set to the wall time in nanoseconds
+//   - all other columns are set to the HLC timestamp
+func softDeleteValue(col *types.ColData, ts hlc.Time) any {
+	typ := strings.ToLower(col.Type)
+	switch {
+	case strings.Contains(typ, "bool"), strings.HasPrefix(typ, "tinyint"):
+		return true
+	case strings.Contains(typ, "time"), strings.Contains(typ, "date"):
+		return time.Unix(0, ts.Nanos()).UTC()
+	case strings.Contains(typ, "int") && !strings.Contains(typ, "interval"):
+		return ts.Nanos()
+	default:
+		return ts.String()
+	}
+}
+
+// softDeleteLive returns the value of a soft-delete column for a row
+// which has not been deleted.
+func softDeleteLive(col *types.ColData) any {
+	if _, isBool := softDeleteValue(col, hlc.Zero()).(bool); isBool {
+		return false
+	}
+	return nil
+}
+
+// undeleteLocked sets the soft-delete column, if one is configured, to
+// its live value. This ensures that a re-inserted row is undeleted and
+// also prevents the soft-delete column from being treated as missing
+// from a sparse payload.
+func (a *apply) undeleteLocked(bag *merge.Bag) {
+	if col := a.mu.templates.SoftDelete; col != nil {
+		bag.Put(col.Name, softDeleteLive(col))
+	}
+}
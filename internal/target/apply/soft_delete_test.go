@@ -0,0 +1,58 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDeleteValue(t *testing.T) {
+	ts := hlc.New(1_700_000_000_123_456_789, 2)
+	wall := time.Unix(0, ts.Nanos()).UTC()
+
+	tcs := []struct {
+		typ  string
+		live any
+		dead any
+	}{
+		{"BOOL", false, true},
+		{"boolean", false, true},
+		{"tinyint", false, true},
+		{"TIMESTAMPTZ", nil, wall},
+		{"datetime", nil, wall},
+		{"DATE", nil, wall},
+		{"INT8", nil, ts.Nanos()},
+		{"bigint", nil, ts.Nanos()},
+		{"DECIMAL", nil, ts.String()},
+		{"INTERVAL", nil, ts.String()},
+		{"VARCHAR(256)", nil, ts.String()},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.typ, func(t *testing.T) {
+			r := require.New(t)
+			col := &types.ColData{Type: tc.typ}
+			r.Equal(tc.dead, softDeleteValue(col, ts))
+			r.Equal(tc.live, softDeleteLive(col))
+		})
+	}
+}
@@ -111,10 +111,11 @@ var (
 		},
 	}
 
-	tmplCRDB *template.Template
-	tmplOra  *template.Template
-	tmplMy   *template.Template
-	tmplPG   *template.Template
+	tmplCRDB   *template.Template
+	tmplOra    *template.Template
+	tmplMy     *template.Template
+	tmplPG     *template.Template
+	tmplSQLite *template.Template
 )
 
 // The error handling in this init function is panicky, since this
@@ -170,6 +171,10 @@ func loadTemplates(from fs.FS, override string) error {
 		return err
 	}
 	tmplPG, err = load("pg")
+	if err != nil {
+		return err
+	}
+	tmplSQLite, err = load("sqlite")
 	return err
 }
 
@@ -184,13 +189,19 @@ type templates struct {
 	BulkUpsert bool
 
 	conditional *template.Template
-	delete      *template.Template
-	upsert      *template.Template
+	// If present, the conditional template cannot return the rows
+	// which blocked the upsert, so this template is used to select
+	// them before the conditional upsert is executed.
+	conflicts *template.Template
+	delete    *template.Template
+	update    *template.Template
+	upsert    *template.Template
 
 	tmpl *template.Template
 	// The variables below here are updated during evaluation.
-	ForDelete bool // True if we only iterate over PKs to delete
-	RowCount  int  // The number of rows to be applied.
+	CopySource string // If set, a staging table that contains the data.
+	ForDelete  bool   // True if we only iterate over PKs to delete
+	RowCount   int    // The number of rows to be applied.
 }
 
 // newTemplates constructs a new templates instance, performing some
@@ -203,28 +214,42 @@ func newTemplates(mapping *columnMapping) (*templates, error) {
 	case types.ProductCockroachDB:
 		ret.conditional = tmplCRDB.Lookup("conditional.tmpl")
 		ret.delete = tmplCRDB.Lookup("delete.tmpl")
+		ret.update = tmplCRDB.Lookup("update.tmpl")
 		ret.upsert = tmplCRDB.Lookup("upsert.tmpl")
 		ret.tmpl = tmplCRDB
 
 	case types.ProductMariaDB, types.ProductMySQL:
 		ret.conditional = tmplMy.Lookup("conditional.tmpl")
+		ret.conflicts = tmplMy.Lookup("conflicts.tmpl")
 		ret.delete = tmplMy.Lookup("delete.tmpl")
+		ret.update = tmplMy.Lookup("update.tmpl")
 		ret.upsert = tmplMy.Lookup("upsert.tmpl")
 		ret.tmpl = tmplMy
 
 	case types.ProductOracle:
 		ret.BulkDelete = true
 		ret.BulkUpsert = true
+		ret.conditional = tmplOra.Lookup("conditional.tmpl")
+		ret.conflicts = tmplOra.Lookup("conflicts.tmpl")
 		ret.delete = tmplOra.Lookup("delete.tmpl")
+		ret.update = tmplOra.Lookup("update.tmpl")
 		ret.upsert = tmplOra.Lookup("upsert.tmpl")
-		ret.conditional = ret.upsert
 		ret.tmpl = tmplOra
 	case types.ProductPostgreSQL:
 		ret.conditional = tmplPG.Lookup("conditional.tmpl")
 		ret.delete = tmplPG.Lookup("delete.tmpl")
+		ret.update = tmplPG.Lookup("update.tmpl")
 		ret.upsert = tmplPG.Lookup("upsert.tmpl")
 		ret.tmpl = tmplPG
 
+	case types.ProductSQLite:
+		ret.conditional = tmplSQLite.Lookup("conditional.tmpl")
+		ret.conflicts = tmplSQLite.Lookup("conflicts.tmpl")
+		ret.delete = tmplSQLite.Lookup("delete.tmpl")
+		ret.update = tmplSQLite.Lookup("update.tmpl")
+		ret.upsert = tmplSQLite.Lookup("upsert.tmpl")
+		ret.tmpl = tmplSQLite
+
 	default:
 		return nil, errors.Errorf("unsupported product %s", mapping.Product)
 	}
@@ -249,6 +274,23 @@ type varPair struct {
 	// for the column was present in the original payload. A zero
 	// value means the template should always expect a value.
 	ValidityParam int
+
+	copySource bool // See ParamRef.
+}
+
+// ParamRef returns a PostgreSQL reference to the substitution
+// parameter. If the data is being read from a copy source, this will
+// be the name of the staging-table column that holds the parameter.
+func (v varPair) ParamRef() string { return v.ref(v.Param) }
+
+// ValidityRef is analogous to ParamRef for the ValidityParam.
+func (v varPair) ValidityRef() string { return v.ref(v.ValidityParam) }
+
+func (v varPair) ref(param int) string {
+	if v.copySource {
+		return copyColumn(param)
+	}
+	return fmt.Sprintf("$%d", param)
 }
 
 // Vars is a generator function that returns windows of 1-based
@@ -264,7 +306,7 @@ func (t *templates) Vars() ([][]varPair, error) {
 	for row := range ret {
 		ret[row] = make([]varPair, len(cols))
 		for colIdx, col := range cols {
-			vp := varPair{Column: col}
+			vp := varPair{Column: col, copySource: t.CopySource != ""}
 
 			positions := t.Positions.GetZero(col.Name)
 			if t.ForDelete {
@@ -288,11 +330,13 @@ func (t *templates) Vars() ([][]varPair, error) {
 				var reference string
 				switch t.Product {
 				case types.ProductCockroachDB, types.ProductPostgreSQL:
-					reference = fmt.Sprintf("$%d", vp.Param)
+					reference = vp.ParamRef()
 				case types.ProductMariaDB, types.ProductMySQL:
 					reference = "?"
 				case types.ProductOracle:
 					reference = fmt.Sprintf(":ref%d", vp.Param)
+				case types.ProductSQLite:
+					reference = fmt.Sprintf("?%d", vp.Param)
 				default:
 					return nil, errors.Errorf("unimplemented product %s", t.Product)
 				}
@@ -306,6 +350,77 @@ func (t *templates) Vars() ([][]varPair, error) {
 	return ret, nil
 }
 
+// SoftDeleteVars returns the delete substitution parameters for each
+// row, followed by the parameter for the soft-delete column's value.
+func (t *templates) SoftDeleteVars() ([][]varPair, error) {
+	if t.SoftDelete == nil {
+		return nil, errors.New("soft-delete column not configured")
+	}
+	ret, err := t.Vars()
+	if err != nil {
+		return nil, err
+	}
+	for row := range ret {
+		ret[row] = append(ret[row], varPair{
+			Column:     *t.SoftDelete,
+			Param:      row*t.DeleteParameterCount + len(t.PKDelete) + 1,
+			copySource: t.CopySource != "",
+		})
+	}
+	return ret, nil
+}
+
+// copyDeleteExpr returns a statement which deletes the rows whose keys
+// have been copied into the source table.
+func (t *templates) copyDeleteExpr(source string) (string, error) {
+	// Make a copy that we can tweak.
+	cpy := *t
+	cpy.CopySource = source
+	cpy.ForDelete = true
+	cpy.RowCount = 1
+
+	var buf strings.Builder
+	err := t.delete.Execute(&buf, &cpy)
+	return buf.String(), errors.WithStack(err)
+}
+
+// copyUpsertExpr is analogous to upsertExpr, but reads the data from
+// the source table, instead of from substitution parameters.
+func (t *templates) copyUpsertExpr(source string, mode applyMode) (string, error) {
+	// Make a copy that we can tweak.
+	cpy := *t
+	cpy.CopySource = source
+	cpy.RowCount = 1
+
+	var buf strings.Builder
+	var err error
+	if mode == applyUnconditional || len(cpy.Conditions) == 0 && cpy.Deadlines.Len() == 0 {
+		err = t.upsert.Execute(&buf, &cpy)
+	} else {
+		err = t.conditional.Execute(&buf, &cpy)
+	}
+	return buf.String(), errors.WithStack(err)
+}
+
+// conflictsExpr returns a query that selects the rows in the target
+// table which would prevent the conditional upsert of the proposed
+// rows. The query uses the same substitution parameters as a
+// conditional upsert of rowCount rows. Since the query must return one
+// result row per blocking row, it is never a bulk statement.
+func (t *templates) conflictsExpr(rowCount int) (string, error) {
+	if t.conflicts == nil {
+		return "", errors.Errorf("conflict query not supported for %s", t.Product)
+	}
+
+	// Make a copy that we can tweak.
+	cpy := *t
+	cpy.RowCount = rowCount
+
+	var buf strings.Builder
+	err := t.conflicts.Execute(&buf, &cpy)
+	return buf.String(), errors.WithStack(err)
+}
+
 func (t *templates) deleteExpr(rowCount int) (string, error) {
 	if t.BulkDelete {
 		rowCount = 1
@@ -341,6 +456,24 @@ func (t *templates) customExpr(rowCount int, name string, mode applyMode) (strin
 	return buf.String(), errors.WithStack(err)
 }
 
+// updateExpr returns a statement which updates the changed columns of
+// existing rows. The substitution parameters for each row are the PK
+// columns, followed by the changed columns.
+func (t *templates) updateExpr(changed []types.ColData, rowCount int) (string, error) {
+	if t.BulkUpsert {
+		rowCount = 1
+	}
+
+	// Make a copy that we can tweak.
+	cpy := *t
+	cpy.columnMapping = t.updateMapping(changed)
+	cpy.RowCount = rowCount
+
+	var buf strings.Builder
+	err := t.update.Execute(&buf, &cpy)
+	return buf.String(), errors.WithStack(err)
+}
+
 // upsertExpr may return a conditional sql statement, unless forceUpsert
 // is set to true.
 func (t *templates) upsertExpr(rowCount int, mode applyMode) (string, error) {
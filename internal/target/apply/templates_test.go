@@ -17,6 +17,8 @@
 package apply
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"os"
@@ -24,9 +26,11 @@ import (
 	"testing/fstest"
 	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
 	"github.com/stretchr/testify/require"
 )
 
@@ -156,6 +160,21 @@ func TestQueryTemplatesMy(t *testing.T) {
 				),
 			},
 		},
+		{
+			// Deletions update a column instead of removing rows.
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete: ident.New("val1"),
+			},
+		},
+		{
+			// Updates write only the changed columns.
+			name: "updateChanged",
+			cfg: &applycfg.Config{
+				Exprs:         ident.MapOf[string](ident.New("val1"), `$0||'foobar'`),
+				UpdateChanged: true,
+			},
+		},
 		{
 			// Verify user-configured expressions, with zero, one, and
 			// multiple uses of the substitution position.
@@ -277,6 +296,21 @@ func TestQueryTemplatesOra(t *testing.T) {
 				),
 			},
 		},
+		{
+			// Deletions update a column instead of removing rows.
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete: ident.New("val1"),
+			},
+		},
+		{
+			// Updates write only the changed columns.
+			name: "updateChanged",
+			cfg: &applycfg.Config{
+				Exprs:         ident.MapOf[string](ident.New("val1"), `$0||'foobar'`),
+				UpdateChanged: true,
+			},
+		},
 		{
 			// Verify user-configured expressions, with zero, one, and
 			// multiple uses of the substitution position.
@@ -428,6 +462,21 @@ func testQueryTemplates(t *testing.T, product types.Product) {
 				),
 			},
 		},
+		{
+			// Deletions update a column instead of removing rows.
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete: ident.New("val1"),
+			},
+		},
+		{
+			// Updates write only the changed columns.
+			name: "updateChanged",
+			cfg: &applycfg.Config{
+				Exprs:         ident.MapOf[string](ident.New("val1"), `$0||'foobar'`),
+				UpdateChanged: true,
+			},
+		},
 		{
 			// Verify user-configured expressions, with zero, one, and
 			// multiple uses of the substitution position.
@@ -453,8 +502,135 @@ func testQueryTemplates(t *testing.T, product types.Product) {
 	}
 }
 
+// TestQueryTemplatesSQLite also executes the generated SQL against an
+// in-memory database, since that requires no external services.
+func TestQueryTemplatesSQLite(t *testing.T) {
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	pool, err := stdpool.OpenTarget(ctx, "sqlite::memory:")
+	require.NoError(t, err)
+	_, err = pool.ExecContext(ctx, `CREATE TABLE "table" (
+pk0 TEXT, pk1 INTEGER, val0 TEXT, val1 TEXT, ignored_val INTEGER,
+has_default INTEGER DEFAULT (abs(-1)),
+PRIMARY KEY (pk0, pk1))`)
+	require.NoError(t, err)
+
+	global := &templateGlobal{
+		cols: []types.ColData{
+			{
+				Name:    ident.New("pk0"),
+				Primary: true,
+				Type:    "TEXT",
+			},
+			{
+				Name:    ident.New("pk1"),
+				Primary: true,
+				Type:    "INTEGER",
+			},
+			{
+				Name: ident.New("val0"),
+				Type: "TEXT",
+			},
+			{
+				Name: ident.New("val1"),
+				Type: "TEXT",
+			},
+			{
+				Ignored: true,
+				Name:    ident.New("ignored_val"),
+				Primary: false,
+				Type:    "INTEGER",
+			},
+			{
+				Name:        ident.New("has_default"),
+				Type:        "INTEGER",
+				DefaultExpr: "abs(-1)",
+			},
+		},
+		db:      pool.DB,
+		dir:     "sqlite",
+		product: types.ProductSQLite,
+		tableID: ident.NewTable(
+			ident.MustSchema(ident.New("main")),
+			ident.New("table")),
+	}
+
+	tcs := []*templateTestCase{
+		{
+			name: "base",
+		},
+		{
+			name: "cas",
+			cfg: &applycfg.Config{
+				CASColumns: []ident.Ident{ident.New("val1"), ident.New("val0")},
+			},
+		},
+		{
+			name: "deadline",
+			cfg: &applycfg.Config{
+				Deadlines: ident.MapOf[time.Duration](
+					ident.New("val1"), time.Second,
+					ident.New("val0"), time.Hour,
+				),
+			},
+		},
+		{
+			name: "casDeadline",
+			cfg: &applycfg.Config{
+				CASColumns: []ident.Ident{ident.New("val1"), ident.New("val0")},
+				Deadlines: ident.MapOf[time.Duration](
+					ident.New("val0"), time.Hour,
+					ident.New("val1"), time.Second,
+				),
+			},
+		},
+		{
+			// This ignore setup results in a PK-only table.
+			name: "ignore",
+			cfg: &applycfg.Config{
+				Ignore: ident.MapOf[bool](
+					"val0", true,
+					"val1", true,
+				)},
+		},
+		{
+			// Deletions update a column instead of removing rows.
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete: ident.New("val1"),
+			},
+		},
+		{
+			// Updates write only the changed columns.
+			name: "updateChanged",
+			cfg: &applycfg.Config{
+				Exprs:         ident.MapOf[string](ident.New("val1"), `$0||'foobar'`),
+				UpdateChanged: true,
+			},
+		},
+		{
+			name: "expr",
+			cfg: &applycfg.Config{
+				Exprs: ident.MapOf[string](
+					ident.New("val0"), `'fixed'`, // Doesn't consume a parameter slot.
+					ident.New("val1"), `$0||'foobar'`,
+					ident.New("pk1"), `$0+$0`,
+				),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTemplate(t, global, tc)
+		})
+	}
+}
+
 type templateGlobal struct {
 	cols    []types.ColData
+	db      *sql.DB // If present, the generated SQL will be executed.
 	dir     string
 	product types.Product
 	tableID ident.Table
@@ -491,7 +667,19 @@ func checkTemplate(t *testing.T, global *templateGlobal, tc *templateTestCase) {
 		checkFile(t,
 			fmt.Sprintf("testdata/%s/%s.upsert.sql", global.dir, tc.name),
 			s)
+		checkExec(t, global.db, s, 2*tmpls.UpsertParameterCount)
 	})
+	if tmpls.conflicts != nil && (len(tmpls.Conditions) > 0 || tmpls.Deadlines.Len() > 0) {
+		t.Run("conflicts", func(t *testing.T) {
+			r := require.New(t)
+			s, err := tmpls.conflictsExpr(2)
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.conflicts.sql", global.dir, tc.name),
+				s)
+			checkExec(t, global.db, s, 2*tmpls.UpsertParameterCount)
+		})
+	}
 	t.Run("delete", func(t *testing.T) {
 		r := require.New(t)
 		s, err := tmpls.deleteExpr(2)
@@ -499,7 +687,56 @@ func checkTemplate(t *testing.T, global *templateGlobal, tc *templateTestCase) {
 		checkFile(t,
 			fmt.Sprintf("testdata/%s/%s.delete.sql", global.dir, tc.name),
 			s)
+		checkExec(t, global.db, s, 2*tmpls.DeleteParameterCount)
 	})
+	if tmpls.UpdateChanged {
+		t.Run("update", func(t *testing.T) {
+			r := require.New(t)
+			s, err := tmpls.updateExpr(tmpls.Data, 2)
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.update.sql", global.dir, tc.name),
+				s)
+			checkExec(t, global.db, s, 2*len(tmpls.Columns))
+		})
+	}
+	if global.product == types.ProductPostgreSQL {
+		t.Run("copyUpsert", func(t *testing.T) {
+			r := require.New(t)
+			s, err := tmpls.copyUpsertExpr("staging", applyConditional)
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.copyUpsert.sql", global.dir, tc.name),
+				s)
+		})
+		t.Run("copyDelete", func(t *testing.T) {
+			r := require.New(t)
+			s, err := tmpls.copyDeleteExpr("staging")
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.copyDelete.sql", global.dir, tc.name),
+				s)
+		})
+	}
+}
+
+// checkExec executes the statement within a transaction that is rolled
+// back, if a database is present.
+func checkExec(t *testing.T, db *sql.DB, stmt string, argCount int) {
+	t.Helper()
+	if db == nil {
+		return
+	}
+	r := require.New(t)
+	args := make([]any, argCount)
+	for i := range args {
+		args[i] = i + 1
+	}
+	tx, err := db.Begin()
+	r.NoError(err)
+	defer func() { _ = tx.Rollback() }()
+	_, err = tx.Exec(stmt, args...)
+	r.NoError(err, stmt)
 }
 
 func checkFile(t *testing.T, path string, contents string) {
@@ -0,0 +1,4 @@
+UPDATE "database"."schema"."table"@{NO_FULL_SCAN} SET "val1"=x."val1"
+FROM (VALUES ($1::STRING,$2::INT8,$3::STRING,$4::STRING),
+($5::STRING,$6::INT8,$7::STRING,$8::STRING)) AS x("pk0","pk1","ignored_pk","val1")
+WHERE ("table"."pk0","table"."pk1","table"."ignored_pk")=(x."pk0",x."pk1",x."ignored_pk")
\ No newline at end of file
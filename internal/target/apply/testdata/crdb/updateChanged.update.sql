@@ -0,0 +1,4 @@
+UPDATE "database"."schema"."table"@{NO_FULL_SCAN} SET "val0"=x."val0","val1"=x."val1","geom"=x."geom","geog"=x."geog","enum"=x."enum","has_default"=x."has_default"
+FROM (VALUES ($1::STRING,$2::INT8,$3::STRING,($4||'foobar')::STRING,st_geomfromgeojson($5::JSONB),st_geogfromgeojson($6::JSONB),$7::"database"."schema"."MyEnum",$8::INT8),
+($9::STRING,$10::INT8,$11::STRING,($12||'foobar')::STRING,st_geomfromgeojson($13::JSONB),st_geogfromgeojson($14::JSONB),$15::"database"."schema"."MyEnum",$16::INT8)) AS x("pk0","pk1","val0","val1","geom","geog","enum","has_default")
+WHERE ("table"."pk0","table"."pk1")=(x."pk0",x."pk1")
\ No newline at end of file
@@ -0,0 +1,20 @@
+WITH data (__idx__,"pk0","pk1","val0","val1","has_default") AS (
+  SELECT 0,?,?,?,?,CASE WHEN ? = 1 THEN ? ELSE expr() END
+  UNION ALL SELECT 1,?,?,?,?,CASE WHEN ? = 1 THEN ? ELSE expr() END),
+deadlined AS (SELECT * FROM data WHERE("val0"> now()- INTERVAL '3600' SECOND)AND("val1"> now()- INTERVAL '1' SECOND)),
+current AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "schema"."table"
+JOIN deadlined
+USING ("pk0","pk1")),
+action AS (
+SELECT deadlined.* FROM deadlined
+LEFT JOIN current
+USING ("pk0","pk1")
+WHERE current."pk0" IS NULL OR
+(deadlined."val1",deadlined."val0") > (current."val1",current."val0"))
+SELECT CASE WHEN x."pk0" IS NULL THEN data.__idx__ ELSE -1 END,
+t."pk0",t."pk1",t."val0",t."val1",t."has_default" FROM "schema"."table" t
+JOIN data ON (t."pk0" = data."pk0" AND t."pk1" = data."pk1")
+LEFT JOIN action x ON (x."pk0" = data."pk0" AND x."pk1" = data."pk1")
+FOR UPDATE
\ No newline at end of file
@@ -0,0 +1,4 @@
+UPDATE "schema"."table" JOIN (SELECT ? AS "pk0",? AS "pk1",? AS "val1"
+UNION ALL SELECT ?,?,?) AS x
+ON ("table"."pk0","table"."pk1")=(x."pk0",x."pk1")
+SET "schema"."table"."val1"=x."val1"
\ No newline at end of file
@@ -0,0 +1,4 @@
+UPDATE "schema"."table" JOIN (SELECT ? AS "pk0",? AS "pk1",? AS "val0",(?||'foobar') AS "val1",? AS "has_default"
+UNION ALL SELECT ?,?,?,(?||'foobar'),?) AS x
+ON ("table"."pk0","table"."pk1")=(x."pk0",x."pk1")
+SET "schema"."table"."val0"=x."val0","schema"."table"."val1"=x."val1","schema"."table"."has_default"=x."has_default"
\ No newline at end of file
@@ -0,0 +1,17 @@
+WITH data ("__idx__","pk0","pk1","val0","val1","has_default") AS (
+SELECT 0, CAST(:1 AS VARCHAR(256)), CAST(:2 AS INT), CAST(:3 AS VARCHAR(256)), CAST(:4 AS VARCHAR(256)), CASE WHEN :5 = 1 THEN CAST(:6 AS INT8) ELSE expr() END FROM DUAL UNION ALL 
+SELECT 1, CAST(:7 AS VARCHAR(256)), CAST(:8 AS INT), CAST(:9 AS VARCHAR(256)), CAST(:10 AS VARCHAR(256)), CASE WHEN :11 = 1 THEN CAST(:12 AS INT8) ELSE expr() END FROM DUAL
+),
+deadlined AS (SELECT * FROM data WHERE("val0"> (CURRENT_TIMESTAMP - NUMTODSINTERVAL(3600, 'SECOND')))AND("val1"> (CURRENT_TIMESTAMP - NUMTODSINTERVAL(1, 'SECOND')))),
+active AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "schema"."table" JOIN deadlined USING ("pk0","pk1")),
+action AS (
+SELECT "pk0","pk1", deadlined."val0",deadlined."val1",deadlined."has_default" FROM deadlined
+LEFT JOIN active USING ("pk0","pk1") WHERE active."val1" IS NULL OR
+(deadlined."val1",deadlined."val0") > (active."val1",active."val0"))
+SELECT CASE WHEN x."pk0" IS NULL THEN data."__idx__" ELSE -1 END,
+t."pk0",t."pk1",t."val0",t."val1",t."has_default" FROM "schema"."table" t
+JOIN data ON (t."pk0" = data."pk0" AND t."pk1" = data."pk1")
+LEFT JOIN action x ON (x."pk0" = data."pk0" AND x."pk1" = data."pk1")
+FOR UPDATE OF t."pk0"
\ No newline at end of file
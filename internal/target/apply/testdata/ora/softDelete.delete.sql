@@ -0,0 +1,2 @@
+UPDATE "schema"."table" SET "val1"=CAST(:4 AS VARCHAR(256))
+WHERE ("pk0","pk1","ignored_pk")IN((CAST(:1 AS VARCHAR(256)),CAST(:2 AS INT),CAST(:3 AS INT)))
\ No newline at end of file
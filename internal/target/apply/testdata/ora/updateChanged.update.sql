@@ -0,0 +1,2 @@
+UPDATE "schema"."table" SET "val0"=CAST(:3 AS VARCHAR(256)),"val1"=CAST(:ref4||'foobar' AS VARCHAR(256)),"has_default"=CAST(:5 AS INT8)
+WHERE ("pk0","pk1")IN((CAST(:1 AS VARCHAR(256)),CAST(:2 AS INT)))
\ No newline at end of file
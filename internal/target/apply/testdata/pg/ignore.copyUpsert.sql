@@ -0,0 +1,6 @@
+INSERT INTO "database"."schema"."table" (
+"pk0","pk1","val0","val1","enum","has_default"
+)
+SELECT p1::STRING,p2::INT8,p3::STRING,p4::STRING,p5::"database"."schema"."MyEnum",CASE WHEN p6::INT = 1 THEN p7::INT8 ELSE expr() END FROM staging
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","enum","has_default") = ROW(excluded."val0",excluded."val1",excluded."enum",excluded."has_default")
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE "database"."schema"."table" SET "val1"=x."val1"
+FROM (SELECT p1::STRING,p2::INT8,p3::STRING,p4::STRING FROM staging) AS x("pk0","pk1","ignored_pk","val1")
+WHERE ("table"."pk0","table"."pk1","table"."ignored_pk")=(x."pk0",x."pk1",x."ignored_pk")
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM "database"."schema"."table" WHERE ("pk0","pk1","ignored_pk")IN(SELECT p1::STRING,p2::INT8,p3::STRING FROM staging)
\ No newline at end of file
@@ -0,0 +1,6 @@
+INSERT INTO "database"."schema"."table" (
+"pk0","pk1","val0","val1","geom","geog","enum","has_default"
+)
+SELECT p1::STRING,p2::INT8,p3::STRING,p4::STRING,st_geomfromgeojson(p5::JSONB),st_geogfromgeojson(p6::JSONB),p7::"database"."schema"."MyEnum",CASE WHEN p8::INT = 1 THEN p9::INT8 ELSE expr() END FROM staging
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","geom","geog","enum","has_default") = ROW(excluded."val0",excluded."val1",excluded."geom",excluded."geog",excluded."enum",excluded."has_default")
\ No newline at end of file
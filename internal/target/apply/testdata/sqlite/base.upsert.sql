@@ -0,0 +1,7 @@
+INSERT INTO "main"."table" (
+"pk0","pk1","val0","val1","has_default"
+) VALUES
+(?1,?2,?3,?4,CASE WHEN ?5 = 1 THEN ?6 ELSE abs(-1) END),
+(?7,?8,?9,?10,CASE WHEN ?11 = 1 THEN ?12 ELSE abs(-1) END)
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","has_default") = (excluded."val0",excluded."val1",excluded."has_default")
\ No newline at end of file
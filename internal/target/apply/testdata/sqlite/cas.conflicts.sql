@@ -0,0 +1,19 @@
+WITH data(__idx__,"pk0","pk1","val0","val1","has_default") AS (
+VALUES
+(0,?1,?2,?3,?4,CASE WHEN ?5 = 1 THEN ?6 ELSE abs(-1) END),
+(1,?7,?8,?9,?10,CASE WHEN ?11 = 1 THEN ?12 ELSE abs(-1) END)),
+current AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "main"."table"
+JOIN data
+USING ("pk0","pk1")),
+action AS (
+SELECT data.* FROM data
+LEFT JOIN current
+USING ("pk0","pk1")
+WHERE current."pk0" IS NULL OR
+(data."val1",data."val0") > (current."val1",current."val0"))
+SELECT data.__idx__, t."pk0",t."pk1",t."val0",t."val1",t."has_default" FROM "main"."table" t
+JOIN data ON (t."pk0" = data."pk0" AND t."pk1" = data."pk1")
+LEFT JOIN action x ON (x."pk0" = data."pk0" AND x."pk1" = data."pk1")
+WHERE x."pk0" IS NULL
\ No newline at end of file
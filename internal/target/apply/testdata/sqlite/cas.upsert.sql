@@ -0,0 +1,19 @@
+WITH data("pk0","pk1","val0","val1","has_default") AS (
+VALUES
+(?1,?2,?3,?4,CASE WHEN ?5 = 1 THEN ?6 ELSE abs(-1) END),
+(?7,?8,?9,?10,CASE WHEN ?11 = 1 THEN ?12 ELSE abs(-1) END)),
+current AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "main"."table"
+JOIN data
+USING ("pk0","pk1")),
+action AS (
+SELECT data.* FROM data
+LEFT JOIN current
+USING ("pk0","pk1")
+WHERE current."pk0" IS NULL OR
+(data."val1",data."val0") > (current."val1",current."val0"))
+INSERT INTO "main"."table" ("pk0","pk1","val0","val1","has_default")
+SELECT "pk0","pk1","val0","val1","has_default" FROM action WHERE true
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","has_default") = (excluded."val0",excluded."val1",excluded."has_default")
\ No newline at end of file
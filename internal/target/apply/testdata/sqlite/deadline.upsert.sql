@@ -0,0 +1,9 @@
+WITH data("pk0","pk1","val0","val1","has_default") AS (
+VALUES
+(?1,?2,?3,?4,CASE WHEN ?5 = 1 THEN ?6 ELSE abs(-1) END),
+(?7,?8,?9,?10,CASE WHEN ?11 = 1 THEN ?12 ELSE abs(-1) END)),
+deadlined AS (SELECT * FROM data WHERE(julianday("val0")>julianday('now','-3600 seconds'))AND(julianday("val1")>julianday('now','-1 seconds')))
+INSERT INTO "main"."table" ("pk0","pk1","val0","val1","has_default")
+SELECT "pk0","pk1","val0","val1","has_default" FROM deadlined WHERE true
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","has_default") = (excluded."val0",excluded."val1",excluded."has_default")
\ No newline at end of file
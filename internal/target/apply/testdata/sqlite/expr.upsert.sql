@@ -0,0 +1,7 @@
+INSERT INTO "main"."table" (
+"pk0","pk1","val0","val1","has_default"
+) VALUES
+(?1,(?2+?2),('fixed'),(?3||'foobar'),CASE WHEN ?4 = 1 THEN ?5 ELSE abs(-1) END),
+(?6,(?7+?7),('fixed'),(?8||'foobar'),CASE WHEN ?9 = 1 THEN ?10 ELSE abs(-1) END)
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","has_default") = (excluded."val0",excluded."val1",excluded."has_default")
\ No newline at end of file
@@ -0,0 +1,4 @@
+WITH x("pk0","pk1","val1") AS (VALUES (?1,?2,?3),
+(?4,?5,?6))
+UPDATE "main"."table" SET "val1"=x."val1" FROM x
+WHERE ("table"."pk0","table"."pk1")=(x."pk0",x."pk1")
\ No newline at end of file
@@ -0,0 +1,4 @@
+WITH x("pk0","pk1","val0","val1","has_default") AS (VALUES (?1,?2,?3,(?4||'foobar'),?5),
+(?6,?7,?8,(?9||'foobar'),?10))
+UPDATE "main"."table" SET "val0"=x."val0","val1"=x."val1","has_default"=x."has_default" FROM x
+WHERE ("table"."pk0","table"."pk1")=(x."pk0",x."pk1")
\ No newline at end of file
@@ -0,0 +1,52 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/pkg/errors"
+)
+
+// transformLocked applies any declarative transforms to the decoded
+// upserts. Rows which are discarded by a filter are removed from both
+// of the returned slices, which may share storage with the inputs.
+func (a *apply) transformLocked(
+	muts []types.Mutation, bags []*merge.Bag,
+) ([]types.Mutation, []*merge.Bag, error) {
+	t := a.mu.transforms
+	if t == nil {
+		return muts, bags, nil
+	}
+	keptMuts := make([]types.Mutation, 0, len(muts))
+	keptBags := bags[:0]
+	for idx, bag := range bags {
+		keep, err := t.Apply(bag)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not transform %s at "+
+				"payload object offset %d", a.target, idx)
+		}
+		if keep {
+			keptMuts = append(keptMuts, muts[idx])
+			keptBags = append(keptBags, bag)
+		}
+	}
+	if filtered := len(bags) - len(keptBags); filtered > 0 {
+		a.filtered.Add(float64(filtered))
+	}
+	return keptMuts, keptBags, nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/pjson"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// An updateShape groups rows which have the same set of changed
+// columns, so that they can be applied with a single statement.
+type updateShape struct {
+	changed []types.ColData
+	key     string
+	rows    []int // Indexes into the mutations being applied.
+}
+
+// isUpdateLocked returns true if the mutation should be applied by updating
+// only its changed columns.
+func (a *apply) isUpdateLocked(mut types.Mutation) bool {
+	return a.mu.templates.UpdateChanged &&
+		len(mut.Before) > 0 &&
+		!bytes.Equal(mut.Before, []byte("null"))
+}
+
+// updateLocked compares the before and after values of the mutations
+// and updates only the columns whose values have changed. The rows are
+// grouped by the set of changed columns so that the generated SQL can
+// be reused. Rows which have no changes that can be expressed as an
+// update, or which are missing from the target table, are upserted.
+func (a *apply) updateLocked(
+	ctx context.Context, db types.TargetQuerier, muts []types.Mutation,
+) error {
+	if len(muts) == 0 {
+		return nil
+	}
+
+	afters := make([]*merge.Bag, len(muts))
+	if err := pjson.Decode(ctx, afters, func(i int) []byte {
+		afters[i] = a.newBagLocked()
+		return muts[i].Data
+	}); err != nil {
+		return err
+	}
+	befores := make([]*merge.Bag, len(muts))
+	if err := pjson.Decode(ctx, befores, func(i int) []byte {
+		befores[i] = a.newBagLocked()
+		return muts[i].Before
+	}); err != nil {
+		return err
+	}
+
+	var fallback []types.Mutation
+	var shapes []*updateShape
+	shapesByKey := make(map[string]*updateShape)
+	for idx := range muts {
+		// Both values are transformed, so that the comparison is
+		// made between values of the same form.
+		if t := a.mu.transforms; t != nil {
+			keep, err := t.Apply(afters[idx])
+			if err != nil {
+				return errors.Wrapf(err, "could not transform %s at "+
+					"payload object offset %d", a.target, idx)
+			}
+			if !keep {
+				a.filtered.Inc()
+				continue
+			}
+			if _, err := t.Apply(befores[idx]); err != nil {
+				return errors.Wrapf(err, "could not transform %s at "+
+					"before object offset %d", a.target, idx)
+			}
+		}
+		a.undeleteLocked(afters[idx])
+
+		changed, ok := a.changedColumnsLocked(befores[idx], afters[idx])
+		if !ok {
+			fallback = append(fallback, muts[idx])
+			continue
+		}
+		var sb strings.Builder
+		for _, col := range changed {
+			sb.WriteString(col.Name.Raw())
+			sb.WriteByte(0)
+		}
+		key := sb.String()
+		shape, found := shapesByKey[key]
+		if !found {
+			shape = &updateShape{changed: changed, key: key}
+			shapesByKey[key] = shape
+			shapes = append(shapes, shape)
+		}
+		shape.rows = append(shape.rows, idx)
+	}
+
+	for _, shape := range shapes {
+		limit := len(shape.rows)
+		if !a.mu.templates.BulkUpsert {
+			limit = a.mu.templates.RowLimit
+		}
+		for start := 0; start < len(shape.rows); start += limit {
+			end := min(start+limit, len(shape.rows))
+			rows := shape.rows[start:end]
+			applied, err := a.updateShapeLocked(ctx, db, shape, rows, afters)
+			if err != nil {
+				return err
+			}
+			// We can't tell which rows were missing, so we'll upsert
+			// all of them. This is idempotent for the rows that were
+			// just updated.
+			if !applied {
+				for _, idx := range rows {
+					fallback = append(fallback, muts[idx])
+				}
+			}
+		}
+	}
+
+	return a.upsertLocked(ctx, db, fallback, "")
+}
+
+// changedColumnsLocked returns the columns whose values differ between
+// the before and after bags. It returns false if the row should be
+// upserted instead.
+func (a *apply) changedColumnsLocked(before, after *merge.Bag) ([]types.ColData, bool) {
+	for _, ignored := range a.mu.templates.Ignore {
+		before.Delete(ignored)
+		after.Delete(ignored)
+	}
+
+	// Unmapped properties will be reported or stored in an extras
+	// column by the upsert path.
+	if after.Unmapped.Len() > 0 {
+		return nil, false
+	}
+
+	// A missing or modified PK is handled by the upsert path.
+	for _, col := range a.mu.templates.PK {
+		afterEntry, _ := after.Mapped.Get(col.Name)
+		beforeEntry, _ := before.Mapped.Get(col.Name)
+		if !afterEntry.Valid || !beforeEntry.Valid ||
+			!reflect.DeepEqual(afterEntry.Value, beforeEntry.Value) {
+			return nil, false
+		}
+	}
+
+	var changed []types.ColData
+	for _, col := range a.mu.templates.Data {
+		// Skip columns which don't receive a value from the payload,
+		// such as those with a fixed expression.
+		if pos, ok := a.mu.templates.Positions.Get(col.Name); !ok || pos.UpsertIndex < 0 {
+			continue
+		}
+		// An absent property in a sparse payload is unchanged.
+		afterEntry, _ := after.Mapped.Get(col.Name)
+		if !afterEntry.Valid {
+			continue
+		}
+		beforeEntry, _ := before.Mapped.Get(col.Name)
+		if !beforeEntry.Valid || !reflect.DeepEqual(afterEntry.Value, beforeEntry.Value) {
+			changed = append(changed, col)
+		}
+	}
+
+	// There's nothing to update, but we don't know if the row exists.
+	if len(changed) == 0 {
+		return nil, false
+	}
+	return changed, true
+}
+
+// updateShapeLocked executes an UPDATE of the changed columns for the
+// given rows. It returns false if fewer rows were updated than were
+// proposed.
+func (a *apply) updateShapeLocked(
+	ctx context.Context, db types.TargetQuerier, shape *updateShape, rows []int, afters []*merge.Bag,
+) (bool, error) {
+	start := time.Now()
+	cols := make([]types.ColData, 0, len(a.mu.templates.PK)+len(shape.changed))
+	cols = append(cols, a.mu.templates.PK...)
+	cols = append(cols, shape.changed...)
+
+	args := make([]any, 0, len(cols)*len(rows))
+	for _, idx := range rows {
+		for _, col := range cols {
+			entry, _ := afters[idx].Mapped.Get(col.Name)
+			value, err := toDBType(entry.Column, entry.Value)
+			if err != nil {
+				return false, err
+			}
+			args = append(args, value)
+		}
+	}
+
+	var stmtCacheKey string
+	if a.mu.templates.BulkUpsert {
+		var err error
+		args, err = toColumns(len(cols), len(rows), args)
+		if err != nil {
+			return false, err
+		}
+		stmtCacheKey = fmt.Sprintf("update-%s-%d-%q", a.target, a.mu.gen, shape.key)
+	} else {
+		stmtCacheKey = fmt.Sprintf("update-%s-%d-%d-%q", a.target, a.mu.gen, len(rows), shape.key)
+	}
+	stmt, err := a.cache.Prepare(ctx,
+		db,
+		stmtCacheKey,
+		func() (string, error) {
+			return a.mu.templates.updateExpr(shape.changed, len(rows))
+		})
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return false, errors.Wrap(err, stmtCacheKey)
+	}
+	updated, err := tag.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	a.observeStrategy(false, len(rows), start)
+	a.updates.Add(float64(updated))
+	log.WithFields(log.Fields{
+		"changed":  len(shape.changed),
+		"duration": time.Since(start),
+		"proposed": len(rows),
+		"target":   a.target,
+		"updated":  updated,
+	}).Debug("updated rows")
+	return updated >= int64(len(rows)), nil
+}
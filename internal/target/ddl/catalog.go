@@ -0,0 +1,118 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// These queries return the table name, column name, type name, and
+// primary-key status of every column in the tables within a schema.
+// The type names include any length, precision, or scale.
+const (
+	catalogMySQL = `
+SELECT c.table_name, c.column_name, c.column_type, c.column_key = 'PRI'
+  FROM information_schema.columns c
+  JOIN information_schema.tables t
+    ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+ WHERE c.table_schema = ? AND t.table_type = 'BASE TABLE'
+ ORDER BY c.table_name, c.ordinal_position`
+	catalogPG = `
+SELECT c.table_name, c.column_name,
+       CASE
+       WHEN c.character_maximum_length IS NOT NULL
+       THEN c.data_type || '(' || CAST(c.character_maximum_length AS TEXT) || ')'
+       WHEN c.data_type = 'numeric' AND c.numeric_precision IS NOT NULL
+       THEN c.data_type || '(' || CAST(c.numeric_precision AS TEXT) || ',' ||
+            CAST(COALESCE(c.numeric_scale, 0) AS TEXT) || ')'
+       ELSE c.data_type
+       END, EXISTS (
+       SELECT 1
+         FROM information_schema.table_constraints tc
+         JOIN information_schema.key_column_usage k
+           ON k.constraint_schema = tc.constraint_schema
+          AND k.constraint_name = tc.constraint_name
+        WHERE tc.constraint_type = 'PRIMARY KEY'
+          AND k.table_schema = c.table_schema
+          AND k.table_name = c.table_name
+          AND k.column_name = c.column_name)
+  FROM information_schema.columns c
+  JOIN information_schema.tables t
+    ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+ WHERE c.table_schema = $1 AND t.table_type = 'BASE TABLE'
+ ORDER BY c.table_name, c.ordinal_position`
+	catalogSQLite = `
+SELECT m.name, p.name, p.type, p.pk > 0
+  FROM sqlite_master m
+  JOIN pragma_table_info(m.name) p
+ WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+ ORDER BY m.name, p.cid`
+)
+
+// ReadSchema returns the column metadata for all tables within the
+// schema of a source database. This allows the DDL for a table to be
+// reviewed before any data has been replicated.
+func ReadSchema(
+	ctx context.Context, pool *types.TargetPool, schema ident.Schema,
+) (*ident.TableMap[[]types.ColData], error) {
+	// The connection string will have selected the database, so we
+	// only need the innermost part of the schema name.
+	parts := schema.Idents(nil)
+	if len(parts) == 0 {
+		return nil, errors.New("empty schema")
+	}
+	args := []any{parts[len(parts)-1].Raw()}
+
+	var q string
+	switch pool.Product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		q = catalogPG
+	case types.ProductMariaDB, types.ProductMySQL:
+		q = catalogMySQL
+	case types.ProductSQLite:
+		// SQLite has only a single namespace per file.
+		q, args = catalogSQLite, nil
+	default:
+		return nil, errors.Errorf("reading the schema from %s is not supported", pool.Product)
+	}
+
+	rows, err := pool.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	ret := &ident.TableMap[[]types.ColData]{}
+	for rows.Next() {
+		var tableName, colName, colType string
+		var primary bool
+		if err := rows.Scan(&tableName, &colName, &colType, &primary); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tbl := ident.NewTable(schema, ident.New(tableName))
+		ret.Put(tbl, append(ret.GetZero(tbl), types.ColData{
+			Name:    ident.New(colName),
+			Primary: primary,
+			Type:    colType,
+		}))
+	}
+	return ret, errors.WithStack(rows.Err())
+}
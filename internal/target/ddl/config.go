@@ -0,0 +1,70 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Config controls the automatic creation of target tables and columns.
+type Config struct {
+	// If true, nullable columns will be added to existing target
+	// tables when new source columns are detected. Existing columns
+	// are never altered or dropped.
+	AddColumns bool
+	// If true, tables that are reported by the source, but which do
+	// not exist in the target schema, will be created. This requires
+	// a source that reports schema metadata or a SourceConn.
+	CreateTables bool
+	// A connection string for the source database. If set, the schema
+	// of the source will be read when a target schema is first used,
+	// so that tables can be created for sources, such as changefeeds,
+	// which do not report schema metadata.
+	SourceConn string
+	// The schema in the source database to read. If empty, the name
+	// of the target schema will be used.
+	SourceSchema ident.Schema
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.BoolVar(&c.AddColumns, "addColumns", false,
+		"add nullable columns to target tables when new source columns are detected; "+
+			"existing columns are never altered or dropped")
+	f.BoolVar(&c.CreateTables, "createTables", false,
+		"create missing target tables using the schema reported by a logical replication "+
+			"source (mylogical, pglogical) or read using createTablesSourceConn; "+
+			"use the ddl command to review the generated statements")
+	f.StringVar(&c.SourceConn, "createTablesSourceConn", "",
+		"a connection string for the source database, whose schema is read to create "+
+			"missing target tables for sources which do not report schema metadata (e.g. changefeeds)")
+	f.Var(ident.NewSchemaFlag(&c.SourceSchema), "createTablesSourceSchema",
+		"the schema in the source database to read; defaults to the target schema")
+}
+
+// Preflight validates the configuration.
+func (c *Config) Preflight() error {
+	if c.SourceConn != "" && !c.CreateTables {
+		return errors.New("createTablesSourceConn requires createTables")
+	}
+	if !c.SourceSchema.Empty() && c.SourceConn == "" {
+		return errors.New("createTablesSourceSchema requires createTablesSourceConn")
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The number of schema changes to report in the diagnostics.
+const maxChanges = 128
+
+// A Change records a schema change that was made, or refused, by the
+// Creator.
+type Change struct {
+	At        time.Time `json:"at"`
+	Refused   string    `json:"refused,omitempty"` // The reason the change was not made.
+	Statement string    `json:"statement,omitempty"`
+	Table     string    `json:"table"`
+}
+
+// A Creator will create target tables and columns that are reported
+// by a source, but which are not yet known to the target's schema
+// watcher.
+type Creator struct {
+	cfg        *Config
+	targetPool *types.TargetPool
+	watchers   types.Watchers
+
+	// Serializes schema changes.
+	mu struct {
+		sync.Mutex
+		changes    []Change          // Ring buffer of recent changes.
+		sourcePool *types.TargetPool // Opened on demand from Config.SourceConn.
+	}
+}
+
+var _ diag.Diagnostic = (*Creator)(nil)
+
+// AddsColumns returns true if new columns should be added to existing
+// tables.
+func (c *Creator) AddsColumns() bool {
+	return c != nil && c.cfg.AddColumns
+}
+
+// Diagnostic implements [diag.Diagnostic]. It reports recent schema
+// changes.
+func (c *Creator) Diagnostic(_ context.Context) any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Change(nil), c.mu.changes...)
+}
+
+// Enabled returns true if any schema changes may be made.
+func (c *Creator) Enabled() bool {
+	return c != nil && (c.cfg.AddColumns || c.cfg.CreateTables)
+}
+
+// Ensure creates the table in the target if table creation is enabled
+// and the table is not already known. If the table does exist and
+// column addition is enabled, any missing columns will be added. The
+// columns must be described using type names from the source product.
+func (c *Creator) Ensure(
+	ctx context.Context, source types.Product, tbl ident.Table, cols []types.ColData,
+) error {
+	if !c.Enabled() {
+		return nil
+	}
+	w, err := c.watchers.Get(tbl.Schema())
+	if err != nil {
+		return err
+	}
+	// Source metadata is reported infrequently, so we'll always take
+	// the slow path if columns may need to be added.
+	if _, found := w.Get().Columns.Get(tbl); found && !c.cfg.AddColumns {
+		return nil
+	}
+
+	targetCols, err := Columns(source, cols)
+	if err != nil {
+		return errors.Wrapf(err, "could not derive schema for %s", tbl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The schema data may be stale, so refresh before deciding.
+	if err := w.Refresh(ctx, c.targetPool); err != nil {
+		return err
+	}
+	existing, found := w.Get().Columns.Get(tbl)
+	if found {
+		if !c.cfg.AddColumns {
+			return nil
+		}
+		c.reportDroppedLocked(tbl, existing, targetCols)
+		return c.addColumnsLocked(ctx, w, tbl, existing, targetCols)
+	}
+	if !c.cfg.CreateTables {
+		return nil
+	}
+
+	stmt, err := CreateTable(c.targetPool.Product, tbl, targetCols)
+	if err != nil {
+		return err
+	}
+	log.WithField("table", tbl).Infof("creating target table:\n%s", stmt)
+	if _, err := c.targetPool.ExecContext(ctx, stmt); err != nil {
+		return errors.Wrap(err, stmt)
+	}
+	createdTables.WithLabelValues(metrics.TableValues(tbl)...).Inc()
+	c.recordLocked(Change{Statement: stmt, Table: tbl.Raw()})
+	return w.Refresh(ctx, c.targetPool)
+}
+
+// EnsureSchema creates the tables that exist in the source schema, but
+// which are not known to the target schema. This allows tables to be
+// created for sources, such as changefeeds, which do not report schema
+// metadata. The source schema is read in the same manner as the ddl
+// command. This method is a no-op unless table creation is enabled and
+// [Config.SourceConn] has been set. The stopper governs the lifetime of
+// the connection to the source database.
+func (c *Creator) EnsureSchema(ctx *stopper.Context, target ident.Schema) error {
+	if c == nil || !c.cfg.CreateTables || c.cfg.SourceConn == "" {
+		return nil
+	}
+	source, err := c.sourcePool(ctx)
+	if err != nil {
+		return err
+	}
+	sourceSchema := c.cfg.SourceSchema
+	if sourceSchema.Empty() {
+		sourceSchema = target
+	}
+	tables, err := ReadSchema(ctx, source, sourceSchema)
+	if err != nil {
+		return err
+	}
+	for tbl, cols := range tables.All() {
+		targetTbl := ident.NewTable(target, tbl.Table())
+		if err := c.Ensure(ctx, source.Product, targetTbl, cols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddColumns adds nullable columns to an existing target table if
+// column addition is enabled. Changes to existing columns which would
+// narrow their type and new primary-key columns are refused and
+// reported.
+func (c *Creator) AddColumns(ctx context.Context, tbl ident.Table, cols []Column) error {
+	if !c.AddsColumns() {
+		return nil
+	}
+	w, err := c.watchers.Get(tbl.Schema())
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := w.Refresh(ctx, c.targetPool); err != nil {
+		return err
+	}
+	existing, found := w.Get().Columns.Get(tbl)
+	if !found {
+		return errors.Errorf("unknown table %s", tbl)
+	}
+	return c.addColumnsLocked(ctx, w, tbl, existing, cols)
+}
+
+// sourcePool returns a connection to the source database.
+func (c *Creator) sourcePool(ctx *stopper.Context) (*types.TargetPool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.sourcePool != nil {
+		return c.mu.sourcePool, nil
+	}
+	pool, err := stdpool.OpenTarget(ctx, c.cfg.SourceConn)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to source database")
+	}
+	c.mu.sourcePool = pool
+	return pool, nil
+}
+
+func (c *Creator) addColumnsLocked(
+	ctx context.Context, w types.Watcher, tbl ident.Table, existing []types.ColData, cols []Column,
+) error {
+	product := c.targetPool.Product
+	byName := &ident.Map[types.ColData]{}
+	for _, col := range existing {
+		byName.Put(col.Name, col)
+	}
+
+	changed := false
+	for _, col := range cols {
+		if found, ok := byName.Get(col.Name); ok {
+			if compatible(product, found, col) {
+				continue
+			}
+			want, err := col.SQL(product)
+			if err != nil {
+				want = col.Kind.String()
+			}
+			c.refuseLocked(tbl, fmt.Sprintf(
+				"column %s has type %s, which would be narrower than the source's %s",
+				col.Name, found.Type, want))
+			continue
+		}
+		if col.Primary {
+			c.refuseLocked(tbl, fmt.Sprintf(
+				"column %s would be added to the primary key", col.Name))
+			continue
+		}
+		typ, err := col.SQL(product)
+		if err != nil {
+			return errors.Wrapf(err, "column %s", col.Name)
+		}
+		stmt := AddColumn(product, tbl, col.Name, typ)
+		log.WithField("table", tbl).Infof("adding target column: %s", stmt)
+		if _, err := c.targetPool.ExecContext(ctx, stmt); err != nil {
+			return errors.Wrap(err, stmt)
+		}
+		addedColumns.WithLabelValues(metrics.TableValues(tbl)...).Inc()
+		c.recordLocked(Change{Statement: stmt, Table: tbl.Raw()})
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return w.Refresh(ctx, c.targetPool)
+}
+
+// recordLocked adds the change to the diagnostic report.
+func (c *Creator) recordLocked(change Change) {
+	change.At = time.Now().UTC()
+	if len(c.mu.changes) == maxChanges {
+		c.mu.changes = c.mu.changes[1:]
+	}
+	c.mu.changes = append(c.mu.changes, change)
+}
+
+// refuseLocked logs and reports a change that will not be made.
+func (c *Creator) refuseLocked(tbl ident.Table, reason string) {
+	log.WithField("table", tbl).Warnf("refusing schema change: %s", reason)
+	refusedChanges.WithLabelValues(metrics.TableValues(tbl)...).Inc()
+	c.recordLocked(Change{Refused: reason, Table: tbl.Raw()})
+}
+
+// reportDroppedLocked reports any target columns which are no longer
+// present in the source. These columns are never dropped.
+func (c *Creator) reportDroppedLocked(tbl ident.Table, existing []types.ColData, cols []Column) {
+	source := &ident.Map[struct{}]{}
+	for _, col := range cols {
+		source.Put(col.Name, struct{}{})
+	}
+	for _, col := range existing {
+		if _, ok := source.Get(col.Name); !ok && !col.Ignored {
+			c.refuseLocked(tbl, fmt.Sprintf(
+				"column %s is not present in the source and will not be dropped", col.Name))
+		}
+	}
+}
+
+// compatible returns true if the existing target column can hold the
+// values of the source column without narrowing.
+func compatible(product types.Product, existing types.ColData, col Column) bool {
+	// We don't know how to describe some target types, so we'll leave
+	// them alone.
+	kind, err := ParseType(product, existing.Type)
+	if err != nil {
+		return true
+	}
+	// Compare against the type that we would have used, since a
+	// target may not have a distinct type for every kind.
+	want := col.Kind
+	if typ, err := col.Kind.SQL(product, existing.Primary); err == nil {
+		if found, err := ParseType(product, typ); err == nil {
+			want = found
+		}
+	}
+	if !kind.Holds(want) {
+		return false
+	}
+	if kind != want {
+		return true
+	}
+	// The kinds are the same, so check the type modifiers.
+	target := Column{Kind: kind}
+	target.parseModifiers(existing.Type)
+	return !target.narrows(col)
+}
@@ -0,0 +1,408 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ddl derives CREATE TABLE statements for target tables from
+// the schema metadata that is reported by a source database.
+//
+// As a rule, Replicator does not create or modify schema elements in
+// the target database. The [Creator] is an opt-in exception to that
+// rule, intended to make it easier to bootstrap a new replication
+// stream.
+//
+// Tables are created only for the logical replication sources, which
+// report column types. CockroachDB changefeeds do not include column
+// types in their JSON payloads, so tables are not created for them;
+// the ddl command can instead read the schema of a CockroachDB source
+// directly. New columns may still be added to existing tables using
+// types inferred from the payloads.
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// A Kind is a dialect-neutral data type that sits between the type
+// names reported by a source database and those used in a target.
+type Kind int
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Kind -trimprefix Kind
+
+// The kinds of data that can be mapped between products.
+const (
+	KindUnknown Kind = iota
+	KindBool
+	KindBytes
+	KindDate
+	KindDecimal
+	KindFloat32
+	KindFloat64
+	KindInt16
+	KindInt32
+	KindInt64
+	KindJSON
+	KindString
+	KindTime
+	KindTimestamp
+	KindTimestampTZ
+	KindUint64
+	KindUUID
+)
+
+// A Column describes a column to be created in a target table.
+type Column struct {
+	Name    ident.Ident
+	Kind    Kind
+	Primary bool
+
+	// Type modifiers reported by the source. A zero value means that
+	// the source type is unbounded, or that the modifier is unknown.
+	Length    int // Maximum length of a string or bytes column.
+	Precision int // Total number of digits in a decimal column.
+	Scale     int // Number of fractional digits in a decimal column.
+}
+
+// typeArgs matches the length, precision, or scale arguments in a
+// type name (e.g. the "(6)" in "timestamp(6) with time zone").
+var typeArgs = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// parseModifiers sets the length, precision, or scale of the column
+// from the arguments of the source type name (e.g. "varchar(32)" or
+// "numeric(10,2)"). Arguments which are not integers are ignored.
+func (c *Column) parseModifiers(typ string) {
+	found := typeArgs.FindString(typ)
+	if found == "" {
+		return
+	}
+	found = strings.Trim(strings.TrimSpace(found), "()")
+	var args []int
+	for _, arg := range strings.Split(found, ",") {
+		// Discard length semantics, e.g. Oracle's "32 CHAR".
+		fields := strings.Fields(arg)
+		if len(fields) == 0 {
+			return
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			return
+		}
+		args = append(args, n)
+	}
+	switch c.Kind {
+	case KindBytes, KindString:
+		if len(args) == 1 {
+			c.Length = args[0]
+		}
+	case KindDecimal:
+		if len(args) > 2 || args[0] == 0 {
+			return
+		}
+		c.Precision = args[0]
+		if len(args) == 2 {
+			c.Scale = args[1]
+		}
+	}
+}
+
+// SQL returns the type to use for the column in the target product.
+// The source's type modifiers are retained if the target supports
+// them, otherwise the unbounded type for the column's Kind is used.
+func (c Column) SQL(target types.Product) (string, error) {
+	base, err := c.Kind.SQL(target, c.Primary)
+	if err != nil {
+		return "", err
+	}
+	switch c.Kind {
+	case KindBytes:
+		if c.Length <= 0 {
+			break
+		}
+		switch target {
+		case types.ProductMariaDB, types.ProductMySQL:
+			if c.Length <= myMaxKeyBytes || (!c.Primary && c.Length <= myMaxVarBytes) {
+				return fmt.Sprintf("VARBINARY(%d)", c.Length), nil
+			}
+		case types.ProductOracle:
+			if c.Length <= oraMaxRaw {
+				return fmt.Sprintf("RAW(%d)", c.Length), nil
+			}
+		}
+	case KindDecimal:
+		if c.Precision <= 0 || c.Scale > c.Precision {
+			break
+		}
+		switch target {
+		case types.ProductCockroachDB:
+			return fmt.Sprintf("DECIMAL(%d,%d)", c.Precision, c.Scale), nil
+		case types.ProductMariaDB, types.ProductMySQL:
+			if c.Precision <= myMaxPrecision && c.Scale <= myMaxScale {
+				return fmt.Sprintf("DECIMAL(%d,%d)", c.Precision, c.Scale), nil
+			}
+		case types.ProductOracle:
+			if c.Precision <= oraMaxPrecision {
+				return fmt.Sprintf("NUMBER(%d,%d)", c.Precision, c.Scale), nil
+			}
+		case types.ProductPostgreSQL:
+			return fmt.Sprintf("NUMERIC(%d,%d)", c.Precision, c.Scale), nil
+		}
+	case KindString:
+		if c.Length <= 0 {
+			break
+		}
+		switch target {
+		case types.ProductCockroachDB, types.ProductPostgreSQL:
+			return fmt.Sprintf("VARCHAR(%d)", c.Length), nil
+		case types.ProductMariaDB, types.ProductMySQL:
+			if c.Length <= myMaxKeyChars || (!c.Primary && c.Length <= myMaxVarChars) {
+				return fmt.Sprintf("VARCHAR(%d)", c.Length), nil
+			}
+		case types.ProductOracle:
+			if c.Length <= oraMaxVarchar {
+				return fmt.Sprintf("VARCHAR2(%d CHAR)", c.Length), nil
+			}
+		}
+	}
+	return base, nil
+}
+
+// narrows returns true if the modifiers of the column would prevent it
+// from holding all values of the other column. Unbounded modifiers are
+// not compared, since the source may not report them.
+func (c Column) narrows(o Column) bool {
+	switch c.Kind {
+	case KindBytes, KindString:
+		return c.Length > 0 && o.Length > c.Length
+	case KindDecimal:
+		if c.Precision <= 0 || o.Precision <= 0 {
+			return false
+		}
+		return o.Scale > c.Scale || o.Precision-o.Scale > c.Precision-c.Scale
+	default:
+		return false
+	}
+}
+
+// PostgresTypeName appends the length, precision, and scale encoded in
+// a PostgreSQL type modifier (atttypmod) to the name of a type. This
+// is used to describe the columns in a logical replication relation
+// message, which reports only a type's OID and its modifier.
+func PostgresTypeName(name string, typmod int32) string {
+	// The modifier is offset by the size of a varlena header.
+	const varHdrSz = 4
+	if typmod < varHdrSz {
+		return name
+	}
+	typmod -= varHdrSz
+	switch name {
+	case "bpchar", "varchar":
+		return fmt.Sprintf("%s(%d)", name, typmod)
+	case "numeric":
+		return fmt.Sprintf("%s(%d,%d)", name, (typmod>>16)&0xffff, typmod&0xffff)
+	default:
+		return name
+	}
+}
+
+// ParseType converts a type name reported by the source product into a
+// Kind. Type arguments, such as lengths, are discarded except where
+// they change the meaning of the type (e.g. MySQL's TINYINT(1)).
+func ParseType(source types.Product, typ string) (Kind, error) {
+	raw := strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasSuffix(raw, "[]") || strings.HasPrefix(raw, "_") {
+		return KindUnknown, errors.Errorf("array type %q is not supported", typ)
+	}
+	name := typeArgs.ReplaceAllString(raw, "")
+	unsigned := false
+	if trimmed, ok := strings.CutSuffix(name, " unsigned"); ok {
+		name, unsigned = trimmed, true
+	}
+	name = strings.TrimSuffix(name, " zerofill")
+
+	var found Kind
+	switch source {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		found = pgKinds[name]
+		// In CockroachDB, INT is an alias for INT8.
+		if source == types.ProductCockroachDB && (name == "int" || name == "integer") {
+			found = KindInt64
+		}
+	case types.ProductMariaDB, types.ProductMySQL:
+		found = myKinds[name]
+		if raw == "tinyint(1)" {
+			found = KindBool
+		} else if unsigned {
+			found = widenUnsigned(found)
+		}
+	case types.ProductOracle:
+		found = oraKinds[name]
+	case types.ProductSQLite:
+		found = sqliteKinds[name]
+	default:
+		return KindUnknown, errors.Errorf("unsupported source product %s", source)
+	}
+	if found == KindUnknown {
+		return KindUnknown, errors.Errorf("%s type %q has no mapping", source, typ)
+	}
+	return found, nil
+}
+
+// widenUnsigned returns a Kind that can hold the range of the unsigned
+// variant of an integer kind.
+func widenUnsigned(k Kind) Kind {
+	switch k {
+	case KindInt16:
+		return KindInt32
+	case KindInt32:
+		return KindInt64
+	case KindInt64:
+		return KindUint64
+	default:
+		return k
+	}
+}
+
+// intRanks orders the integral kinds by width.
+var intRanks = map[Kind]int{
+	KindInt16:  1,
+	KindInt32:  2,
+	KindInt64:  3,
+	KindUint64: 4,
+}
+
+// Holds returns true if a column of this kind can store all values of
+// the other kind without narrowing.
+func (k Kind) Holds(o Kind) bool {
+	if k == o {
+		return true
+	}
+	switch k {
+	case KindDecimal:
+		return intRanks[o] > 0
+	case KindFloat64:
+		return o == KindFloat32
+	case KindInt16, KindInt32, KindInt64:
+		// Signed types cannot hold large unsigned values.
+		return o != KindUint64 && intRanks[o] > 0 && intRanks[o] < intRanks[k]
+	case KindString:
+		return o == KindUUID
+	default:
+		return false
+	}
+}
+
+// SQL returns the name of the type to use in the target product. Key
+// columns may require a bounded type in some products.
+func (k Kind) SQL(target types.Product, primary bool) (string, error) {
+	var found string
+	switch target {
+	case types.ProductCockroachDB:
+		found = crdbTypes[k]
+	case types.ProductMariaDB, types.ProductMySQL:
+		if primary {
+			found = myKeyTypes[k]
+		}
+		if found == "" {
+			found = myTypes[k]
+		}
+	case types.ProductOracle:
+		if primary {
+			found = oraKeyTypes[k]
+		}
+		if found == "" {
+			found = oraTypes[k]
+		}
+	case types.ProductPostgreSQL:
+		found = pgTypes[k]
+	case types.ProductSQLite:
+		found = sqliteTypes[k]
+	default:
+		return "", errors.Errorf("unsupported target product %s", target)
+	}
+	if found == "" {
+		return "", errors.Errorf("%s has no %s type", target, k)
+	}
+	return found, nil
+}
+
+// Columns converts the source column metadata into target columns.
+func Columns(source types.Product, data []types.ColData) ([]Column, error) {
+	ret := make([]Column, 0, len(data))
+	for _, col := range data {
+		if col.Ignored {
+			continue
+		}
+		kind, err := ParseType(source, col.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %s", col.Name)
+		}
+		next := Column{Name: col.Name, Kind: kind, Primary: col.Primary}
+		next.parseModifiers(col.Type)
+		ret = append(ret, next)
+	}
+	return ret, nil
+}
+
+// CreateTable returns a CREATE TABLE statement in the target product's
+// dialect. The primary key columns are declared in the order in which
+// they appear. Other columns will be nullable, since the source
+// metadata does not reliably report nullability.
+func CreateTable(target types.Product, tbl ident.Table, cols []Column) (string, error) {
+	if len(cols) == 0 {
+		return "", errors.Errorf("no columns defined for %s", tbl)
+	}
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE ")
+	// Oracle only supports IF NOT EXISTS in 23c and later.
+	if target != types.ProductOracle {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	sb.WriteString(tbl.String())
+	sb.WriteString(" (\n")
+
+	var pks []string
+	for _, col := range cols {
+		typ, err := col.SQL(target)
+		if err != nil {
+			return "", errors.Wrapf(err, "column %s", col.Name)
+		}
+		_, _ = fmt.Fprintf(&sb, "  %s %s", col.Name, typ)
+		if col.Primary {
+			sb.WriteString(" NOT NULL")
+			pks = append(pks, col.Name.String())
+		}
+		sb.WriteString(",\n")
+	}
+	if len(pks) == 0 {
+		return "", errors.Errorf("no primary key columns defined for %s", tbl)
+	}
+	_, _ = fmt.Fprintf(&sb, "  PRIMARY KEY (%s)\n)", strings.Join(pks, ", "))
+	return sb.String(), nil
+}
+
+// AddColumn returns an ALTER TABLE statement that adds a nullable
+// column, with no default, to the table.
+func AddColumn(target types.Product, tbl ident.Table, col ident.Ident, typ string) string {
+	if target == types.ProductOracle {
+		return fmt.Sprintf("ALTER TABLE %s ADD (%s %s)", tbl, col, typ)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tbl, col, typ)
+}
@@ -0,0 +1,386 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/sinktest/sqlitetest"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseType(t *testing.T) {
+	tcs := []struct {
+		source types.Product
+		typ    string
+		kind   ddl.Kind
+		err    string
+	}{
+		{types.ProductCockroachDB, "INT", ddl.KindInt64, ""},
+		{types.ProductCockroachDB, "STRING", ddl.KindString, ""},
+		{types.ProductCockroachDB, "INT8[]", ddl.KindUnknown, "array type"},
+		{types.ProductPostgreSQL, "integer", ddl.KindInt32, ""},
+		{types.ProductPostgreSQL, "character varying(32)", ddl.KindString, ""},
+		{types.ProductPostgreSQL, "numeric(10,2)", ddl.KindDecimal, ""},
+		{types.ProductPostgreSQL, "timestamp with time zone", ddl.KindTimestampTZ, ""},
+		{types.ProductPostgreSQL, "_int4", ddl.KindUnknown, "array type"},
+		{types.ProductPostgreSQL, "tsvector", ddl.KindUnknown, "has no mapping"},
+		{types.ProductMySQL, "tinyint(1)", ddl.KindBool, ""},
+		{types.ProductMySQL, "tinyint(4)", ddl.KindInt16, ""},
+		{types.ProductMySQL, "int unsigned", ddl.KindInt64, ""},
+		{types.ProductMySQL, "bigint(20) unsigned", ddl.KindUint64, ""},
+		{types.ProductMariaDB, "varchar(255)", ddl.KindString, ""},
+		{types.ProductOracle, "TIMESTAMP(6) WITH TIME ZONE", ddl.KindTimestampTZ, ""},
+		{types.ProductSQLite, "INTEGER", ddl.KindInt64, ""},
+		{types.ProductUnknown, "INTEGER", ddl.KindUnknown, "unsupported source"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.source.String()+"/"+tc.typ, func(t *testing.T) {
+			r := require.New(t)
+			kind, err := ddl.ParseType(tc.source, tc.typ)
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.kind, kind)
+		})
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.Public), ident.New("tbl"))
+	cols := []ddl.Column{
+		{Name: ident.New("pk"), Kind: ddl.KindString, Primary: true},
+		{Name: ident.New("ts"), Kind: ddl.KindTimestampTZ},
+		{Name: ident.New("doc"), Kind: ddl.KindJSON},
+	}
+
+	tcs := []struct {
+		target types.Product
+		expect string
+	}{
+		{types.ProductCockroachDB, `CREATE TABLE IF NOT EXISTS "db"."public"."tbl" (
+  "pk" STRING NOT NULL,
+  "ts" TIMESTAMPTZ,
+  "doc" JSONB,
+  PRIMARY KEY ("pk")
+)`},
+		{types.ProductMySQL, `CREATE TABLE IF NOT EXISTS "db"."public"."tbl" (
+  "pk" VARCHAR(255) NOT NULL,
+  "ts" DATETIME(6),
+  "doc" JSON,
+  PRIMARY KEY ("pk")
+)`},
+		{types.ProductOracle, `CREATE TABLE "db"."public"."tbl" (
+  "pk" VARCHAR2(4000) NOT NULL,
+  "ts" TIMESTAMP WITH TIME ZONE,
+  "doc" CLOB,
+  PRIMARY KEY ("pk")
+)`},
+		{types.ProductPostgreSQL, `CREATE TABLE IF NOT EXISTS "db"."public"."tbl" (
+  "pk" TEXT NOT NULL,
+  "ts" TIMESTAMPTZ,
+  "doc" JSONB,
+  PRIMARY KEY ("pk")
+)`},
+		{types.ProductSQLite, `CREATE TABLE IF NOT EXISTS "db"."public"."tbl" (
+  "pk" TEXT NOT NULL,
+  "ts" TEXT,
+  "doc" TEXT,
+  PRIMARY KEY ("pk")
+)`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.target.String(), func(t *testing.T) {
+			r := require.New(t)
+			stmt, err := ddl.CreateTable(tc.target, tbl, cols)
+			r.NoError(err)
+			r.Equal(tc.expect, stmt)
+		})
+	}
+
+	t.Run("no_pk", func(t *testing.T) {
+		_, err := ddl.CreateTable(types.ProductPostgreSQL, tbl, cols[1:])
+		require.ErrorContains(t, err, "no primary key")
+	})
+}
+
+// TestTypeModifiers verifies that the length, precision, and scale of
+// source types are carried into the target types.
+func TestTypeModifiers(t *testing.T) {
+	r := require.New(t)
+
+	cols, err := ddl.Columns(types.ProductPostgreSQL, []types.ColData{
+		{Name: ident.New("pk"), Primary: true, Type: "character varying(32)"},
+		{Name: ident.New("amount"), Type: "numeric(10,2)"},
+		{Name: ident.New("whole"), Type: "numeric(12)"},
+		{Name: ident.New("code"), Type: "bpchar(3)"},
+		{Name: ident.New("body"), Type: "text"},
+		{Name: ident.New("ts"), Type: "timestamp(6) with time zone"},
+	})
+	r.NoError(err)
+	r.Equal(32, cols[0].Length)
+	r.Equal(10, cols[1].Precision)
+	r.Equal(2, cols[1].Scale)
+	r.Equal(12, cols[2].Precision)
+	r.Equal(0, cols[2].Scale)
+	r.Equal(3, cols[3].Length)
+	r.Equal(0, cols[4].Length)
+	r.Equal(0, cols[5].Length)
+
+	// Arguments which are not lengths are ignored.
+	cols, err = ddl.Columns(types.ProductMySQL, []types.ColData{
+		{Name: ident.New("e"), Type: "enum('a','b')"},
+		{Name: ident.New("i"), Type: "int(11)"},
+		{Name: ident.New("b"), Type: "varbinary(16)"},
+	})
+	r.NoError(err)
+	r.Equal(0, cols[0].Length)
+	r.Equal(0, cols[1].Length)
+	r.Equal(16, cols[2].Length)
+
+	cols, err = ddl.Columns(types.ProductOracle, []types.ColData{
+		{Name: ident.New("s"), Type: "VARCHAR2(20 CHAR)"},
+		{Name: ident.New("n"), Type: "NUMBER(*,2)"},
+	})
+	r.NoError(err)
+	r.Equal(20, cols[0].Length)
+	r.Equal(0, cols[1].Precision)
+
+	str := ddl.Column{Kind: ddl.KindString, Length: 32}
+	dec := ddl.Column{Kind: ddl.KindDecimal, Precision: 10, Scale: 2}
+	bin := ddl.Column{Kind: ddl.KindBytes, Length: 16}
+	wide := ddl.Column{Kind: ddl.KindDecimal, Precision: 80, Scale: 2}
+	tcs := []struct {
+		target                 types.Product
+		str, dec, bin, wideDec string
+	}{
+		{types.ProductCockroachDB, "VARCHAR(32)", "DECIMAL(10,2)", "BYTES", "DECIMAL(80,2)"},
+		{types.ProductMySQL, "VARCHAR(32)", "DECIMAL(10,2)", "VARBINARY(16)", "DECIMAL(65,30)"},
+		{types.ProductOracle, "VARCHAR2(32 CHAR)", "NUMBER(10,2)", "RAW(16)", "NUMBER"},
+		{types.ProductPostgreSQL, "VARCHAR(32)", "NUMERIC(10,2)", "BYTEA", "NUMERIC(80,2)"},
+		{types.ProductSQLite, "TEXT", "NUMERIC", "BLOB", "NUMERIC"},
+	}
+	for _, tc := range tcs {
+		cols := []ddl.Column{str, dec, bin, wide}
+		for idx, expect := range []string{tc.str, tc.dec, tc.bin, tc.wideDec} {
+			typ, err := cols[idx].SQL(tc.target)
+			r.NoError(err)
+			r.Equal(expect, typ, "%s %+v", tc.target, cols[idx])
+		}
+	}
+
+	// Long keys fall back to the bounded key type in MySQL.
+	typ, err := ddl.Column{Kind: ddl.KindString, Length: 1000, Primary: true}.SQL(types.ProductMySQL)
+	r.NoError(err)
+	r.Equal("VARCHAR(255)", typ)
+}
+
+func TestPostgresTypeName(t *testing.T) {
+	r := require.New(t)
+	r.Equal("varchar(32)", ddl.PostgresTypeName("varchar", 36))
+	r.Equal("bpchar(3)", ddl.PostgresTypeName("bpchar", 7))
+	r.Equal("numeric(10,2)", ddl.PostgresTypeName("numeric", (10<<16|2)+4))
+	r.Equal("numeric", ddl.PostgresTypeName("numeric", -1))
+	r.Equal("varchar", ddl.PostgresTypeName("varchar", -1))
+	r.Equal("int8", ddl.PostgresTypeName("int8", -1))
+}
+
+// TestCreatorSQLite reads the schema of a source database and verifies
+// that the Creator will create the tables in the target.
+func TestCreatorSQLite(t *testing.T) {
+	r := require.New(t)
+	cfg := &ddl.Config{}
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DDL: cfg})
+	r.NoError(err)
+	ctx := fixture.Context
+	schema := fixture.Schema
+	creator := fixture.Creator
+
+	source, err := stdpool.OpenTarget(ctx, "sqlite://"+filepath.Join(t.TempDir(), "source.db"))
+	r.NoError(err)
+	_, err = source.ExecContext(ctx,
+		`CREATE TABLE kv (k INTEGER, v TEXT, data BLOB, PRIMARY KEY (k))`)
+	r.NoError(err)
+	sourceSchema, err := ddl.ReadSchema(ctx, source, schema)
+	r.NoError(err)
+	r.Equal(1, sourceSchema.Len())
+	tbl := ident.NewTable(schema, ident.New("kv"))
+	cols, ok := sourceSchema.Get(tbl)
+	r.True(ok)
+	r.Len(cols, 3)
+	r.True(cols[0].Primary)
+
+	w, err := fixture.Watchers.Get(schema)
+	r.NoError(err)
+
+	// Nothing should happen unless creation is enabled.
+	r.NoError(creator.Ensure(ctx, source.Product, tbl, cols))
+	_, ok = w.Get().Columns.Get(tbl)
+	r.False(ok)
+
+	cfg.CreateTables = true
+	r.NoError(creator.Ensure(ctx, source.Product, tbl, cols))
+	created, ok := w.Get().Columns.Get(tbl)
+	r.True(ok)
+	r.Len(created, 3)
+	r.True(created[0].Primary)
+	r.True(ident.Equal(ident.New("k"), created[0].Name))
+
+	// Calling again is a no-op.
+	r.NoError(creator.Ensure(ctx, source.Product, tbl, cols))
+}
+
+// TestEnsureSchemaSQLite verifies that tables can be created from a
+// source connection, for sources which do not report schema metadata.
+func TestEnsureSchemaSQLite(t *testing.T) {
+	r := require.New(t)
+	cfg := &ddl.Config{CreateTables: true}
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DDL: cfg},
+		`CREATE TABLE parent (p INTEGER PRIMARY KEY)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	schema := fixture.Schema
+	creator := fixture.Creator
+
+	sourceConn := "sqlite://" + filepath.Join(t.TempDir(), "source.db")
+	source, err := stdpool.OpenTarget(ctx, sourceConn)
+	r.NoError(err)
+	for _, stmt := range []string{
+		`CREATE TABLE parent (p INTEGER PRIMARY KEY, v TEXT)`,
+		`CREATE TABLE child (c INTEGER PRIMARY KEY, p INTEGER)`,
+	} {
+		_, err = source.ExecContext(ctx, stmt)
+		r.NoError(err)
+	}
+
+	w, err := fixture.Watchers.Get(schema)
+	r.NoError(err)
+
+	// A source connection requires table creation.
+	r.ErrorContains((&ddl.Config{SourceConn: sourceConn}).Preflight(), "createTables")
+	r.ErrorContains((&ddl.Config{CreateTables: true, SourceSchema: schema}).Preflight(),
+		"createTablesSourceConn")
+
+	// Nothing should happen without a source connection.
+	r.NoError(cfg.Preflight())
+	r.NoError(creator.EnsureSchema(ctx, schema))
+	r.Equal(1, w.Get().Columns.Len())
+
+	// The missing table is created and the existing table is left
+	// unchanged.
+	cfg.SourceConn = sourceConn
+	r.NoError(cfg.Preflight())
+	r.NoError(creator.EnsureSchema(ctx, schema))
+	r.Equal(2, w.Get().Columns.Len())
+	child, ok := w.Get().Columns.Get(ident.NewTable(schema, ident.New("child")))
+	r.True(ok)
+	r.Len(child, 2)
+	r.True(child[0].Primary)
+	parent, ok := w.Get().Columns.Get(ident.NewTable(schema, ident.New("parent")))
+	r.True(ok)
+	r.Len(parent, 1)
+
+	// Calling again is a no-op.
+	r.NoError(creator.EnsureSchema(ctx, schema))
+	r.Len(creator.Diagnostic(ctx).([]ddl.Change), 1)
+}
+
+// TestAddColumnsSQLite verifies that new source columns are added to
+// the target, while narrowing changes are refused.
+func TestAddColumnsSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DDL: &ddl.Config{AddColumns: true}},
+		`CREATE TABLE kv (k INTEGER PRIMARY KEY, v TEXT, old TEXT, short VARCHAR(4))`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	creator := fixture.Creator
+	tbl := fixture.Table("kv")
+	w, err := fixture.Watchers.Get(fixture.Schema)
+	r.NoError(err)
+
+	// The source has added columns, changed the type of v, widened
+	// short, and dropped the old column.
+	r.NoError(creator.Ensure(ctx, types.ProductPostgreSQL, tbl, []types.ColData{
+		{Name: ident.New("k"), Primary: true, Type: "int8"},
+		{Name: ident.New("v"), Type: "float8"},
+		{Name: ident.New("short"), Type: "varchar(8)"},
+		{Name: ident.New("added"), Type: "timestamptz"},
+		{Name: ident.New("pk2"), Primary: true, Type: "int8"},
+	}))
+	cols, ok := w.Get().Columns.Get(tbl)
+	r.True(ok)
+	var names []string
+	for _, col := range cols {
+		names = append(names, col.Name.Raw())
+	}
+	r.Equal([]string{"k", "added", "old", "short", "v"}, names)
+
+	// Verify the report.
+	changes := creator.Diagnostic(ctx).([]ddl.Change)
+	r.Len(changes, 5)
+	var refused, made int
+	for _, change := range changes {
+		if change.Refused != "" {
+			refused++
+		} else {
+			made++
+			r.Equal(`ALTER TABLE "main"."kv" ADD COLUMN "added" TEXT`, change.Statement)
+		}
+	}
+	r.Equal(4, refused)
+	r.Equal(1, made)
+
+	// Columns inferred from JSON values can be added directly.
+	r.NoError(creator.AddColumns(ctx, tbl, []ddl.Column{
+		{Name: ident.New("doc"), Kind: ddl.InferKind(map[string]any{"a": 1})},
+	}))
+	cols, _ = w.Get().Columns.Get(tbl)
+	r.Len(cols, 6)
+}
+
+func TestInferKind(t *testing.T) {
+	r := require.New(t)
+	r.Equal(ddl.KindUnknown, ddl.InferKind())
+	r.Equal(ddl.KindUnknown, ddl.InferKind(nil, nil))
+	r.Equal(ddl.KindBool, ddl.InferKind(true, nil))
+	r.Equal(ddl.KindInt64, ddl.InferKind(json.Number("1"), json.Number("2")))
+	r.Equal(ddl.KindDecimal, ddl.InferKind(json.Number("1"), json.Number("1.5")))
+	r.Equal(ddl.KindString, ddl.InferKind("a", json.Number("1")))
+	r.Equal(ddl.KindJSON, ddl.InferKind([]any{1}, "a"))
+}
+
+func TestHolds(t *testing.T) {
+	r := require.New(t)
+	r.True(ddl.KindInt64.Holds(ddl.KindInt32))
+	r.False(ddl.KindInt32.Holds(ddl.KindInt64))
+	r.False(ddl.KindInt64.Holds(ddl.KindUint64))
+	r.True(ddl.KindDecimal.Holds(ddl.KindUint64))
+	r.True(ddl.KindFloat64.Holds(ddl.KindFloat32))
+	r.False(ddl.KindString.Holds(ddl.KindJSON))
+}
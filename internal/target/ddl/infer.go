@@ -0,0 +1,69 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+import "encoding/json"
+
+// InferKind chooses a Kind that can hold all the JSON-decoded values.
+// This is used when the source does not report any type information.
+// Null values are ignored and KindUnknown will be returned if no
+// non-null values are present.
+func InferKind(values ...any) Kind {
+	ret := KindUnknown
+	for _, value := range values {
+		var next Kind
+		switch t := value.(type) {
+		case nil:
+			continue
+		case bool:
+			next = KindBool
+		case json.Number:
+			next = KindInt64
+			if _, err := t.Int64(); err != nil {
+				next = KindDecimal
+			}
+		case float32, float64:
+			next = KindDecimal
+		case int, int8, int16, int32, int64, uint8, uint16, uint32:
+			next = KindInt64
+		case string:
+			next = KindString
+		case map[string]any, []any:
+			next = KindJSON
+		default:
+			next = KindString
+		}
+		ret = widest(ret, next)
+	}
+	return ret
+}
+
+// widest returns a kind that can hold values of both kinds.
+func widest(a, b Kind) Kind {
+	switch {
+	case a == KindUnknown:
+		return b
+	case a.Holds(b):
+		return a
+	case b.Holds(a):
+		return b
+	case a == KindJSON || b == KindJSON:
+		return KindJSON
+	default:
+		return KindString
+	}
+}
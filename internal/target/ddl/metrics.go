@@ -0,0 +1,38 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	addedColumns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ddl_added_columns_total",
+		Help: "the number of columns added to target tables",
+	}, metrics.TableLabels)
+	createdTables = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ddl_created_tables_total",
+		Help: "the number of target tables created from source schema metadata",
+	}, metrics.TableLabels)
+	refusedChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ddl_refused_changes_total",
+		Help: "the number of destructive or narrowing schema changes that were not made",
+	}, metrics.TableLabels)
+)
@@ -0,0 +1,250 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ddl
+
+// Limits on the type modifiers that will be used in a target.
+const (
+	myMaxKeyBytes   = 3072  // The maximum InnoDB index key length.
+	myMaxKeyChars   = 768   // myMaxKeyBytes in utf8mb4.
+	myMaxPrecision  = 65    // DECIMAL(M,D).
+	myMaxScale      = 30    // DECIMAL(M,D).
+	myMaxVarBytes   = 65535 // The maximum row size.
+	myMaxVarChars   = 16383 // myMaxVarBytes in utf8mb4.
+	oraMaxPrecision = 38    // NUMBER(p,s).
+	oraMaxRaw       = 2000  // RAW(n).
+	oraMaxVarchar   = 4000  // VARCHAR2(n).
+)
+
+// These maps convert the names of source types into kinds. The names
+// have already been lower-cased and had any type arguments removed.
+var (
+	myKinds = map[string]Kind{
+		"bigint":     KindInt64,
+		"binary":     KindBytes,
+		"bit":        KindString, // Decoded into a string of binary digits.
+		"blob":       KindBytes,
+		"bool":       KindBool,
+		"boolean":    KindBool,
+		"char":       KindString,
+		"date":       KindDate,
+		"datetime":   KindTimestamp,
+		"dec":        KindDecimal,
+		"decimal":    KindDecimal,
+		"double":     KindFloat64,
+		"enum":       KindString,
+		"float":      KindFloat32,
+		"int":        KindInt32,
+		"integer":    KindInt32,
+		"json":       KindJSON,
+		"longblob":   KindBytes,
+		"longtext":   KindString,
+		"mediumblob": KindBytes,
+		"mediumint":  KindInt32,
+		"mediumtext": KindString,
+		"numeric":    KindDecimal,
+		"real":       KindFloat64,
+		"set":        KindString,
+		"smallint":   KindInt16,
+		"text":       KindString,
+		"time":       KindTime,
+		"timestamp":  KindTimestampTZ,
+		"tinyblob":   KindBytes,
+		"tinyint":    KindInt16,
+		"tinytext":   KindString,
+		"varbinary":  KindBytes,
+		"varchar":    KindString,
+		"year":       KindInt16,
+	}
+	oraKinds = map[string]Kind{
+		"binary_double":                  KindFloat64,
+		"binary_float":                   KindFloat32,
+		"blob":                           KindBytes,
+		"char":                           KindString,
+		"clob":                           KindString,
+		"date":                           KindTimestamp, // Oracle dates have a time component.
+		"float":                          KindFloat64,
+		"integer":                        KindDecimal,
+		"json":                           KindJSON,
+		"nchar":                          KindString,
+		"nclob":                          KindString,
+		"number":                         KindDecimal,
+		"nvarchar2":                      KindString,
+		"raw":                            KindBytes,
+		"timestamp":                      KindTimestamp,
+		"timestamp with local time zone": KindTimestampTZ,
+		"timestamp with time zone":       KindTimestampTZ,
+		"varchar":                        KindString,
+		"varchar2":                       KindString,
+	}
+	pgKinds = map[string]Kind{
+		"bigint":                      KindInt64,
+		"bool":                        KindBool,
+		"boolean":                     KindBool,
+		"bpchar":                      KindString,
+		"bytea":                       KindBytes,
+		"bytes":                       KindBytes,
+		"char":                        KindString,
+		"character":                   KindString,
+		"character varying":           KindString,
+		"citext":                      KindString,
+		"date":                        KindDate,
+		"decimal":                     KindDecimal,
+		"double precision":            KindFloat64,
+		"float":                       KindFloat64,
+		"float4":                      KindFloat32,
+		"float8":                      KindFloat64,
+		"int":                         KindInt32,
+		"int2":                        KindInt16,
+		"int4":                        KindInt32,
+		"int8":                        KindInt64,
+		"integer":                     KindInt32,
+		"json":                        KindJSON,
+		"jsonb":                       KindJSON,
+		"name":                        KindString,
+		"numeric":                     KindDecimal,
+		"real":                        KindFloat32,
+		"smallint":                    KindInt16,
+		"string":                      KindString,
+		"text":                        KindString,
+		"time":                        KindTime,
+		"time without time zone":      KindTime,
+		"timestamp":                   KindTimestamp,
+		"timestamp with time zone":    KindTimestampTZ,
+		"timestamp without time zone": KindTimestamp,
+		"timestamptz":                 KindTimestampTZ,
+		"uuid":                        KindUUID,
+		"varchar":                     KindString,
+	}
+	sqliteKinds = map[string]Kind{
+		"bigint":   KindInt64,
+		"blob":     KindBytes,
+		"boolean":  KindBool,
+		"double":   KindFloat64,
+		"float":    KindFloat64,
+		"int":      KindInt64,
+		"integer":  KindInt64,
+		"numeric":  KindDecimal,
+		"real":     KindFloat64,
+		"text":     KindString,
+		"varchar":  KindString,
+		"datetime": KindTimestamp,
+	}
+)
+
+// These maps provide the type names to use in a target product.
+var (
+	crdbTypes = map[Kind]string{
+		KindBool:        "BOOL",
+		KindBytes:       "BYTES",
+		KindDate:        "DATE",
+		KindDecimal:     "DECIMAL",
+		KindFloat32:     "FLOAT4",
+		KindFloat64:     "FLOAT8",
+		KindInt16:       "INT2",
+		KindInt32:       "INT4",
+		KindInt64:       "INT8",
+		KindJSON:        "JSONB",
+		KindString:      "STRING",
+		KindTime:        "TIME",
+		KindTimestamp:   "TIMESTAMP",
+		KindTimestampTZ: "TIMESTAMPTZ",
+		KindUint64:      "DECIMAL(20)",
+		KindUUID:        "UUID",
+	}
+	myTypes = map[Kind]string{
+		KindBool:        "BOOLEAN",
+		KindBytes:       "LONGBLOB",
+		KindDate:        "DATE",
+		KindDecimal:     "DECIMAL(65,30)",
+		KindFloat32:     "FLOAT",
+		KindFloat64:     "DOUBLE",
+		KindInt16:       "SMALLINT",
+		KindInt32:       "INT",
+		KindInt64:       "BIGINT",
+		KindJSON:        "JSON",
+		KindString:      "LONGTEXT",
+		KindTime:        "TIME(6)",
+		KindTimestamp:   "DATETIME(6)",
+		KindTimestampTZ: "DATETIME(6)", // TIMESTAMP is limited to 2038.
+		KindUint64:      "BIGINT UNSIGNED",
+		KindUUID:        "CHAR(36)",
+	}
+	// MySQL requires a length for indexed string columns.
+	myKeyTypes = map[Kind]string{
+		KindBytes:  "VARBINARY(255)",
+		KindString: "VARCHAR(255)",
+	}
+	oraTypes = map[Kind]string{
+		KindBool:        "NUMBER(1)",
+		KindBytes:       "BLOB",
+		KindDate:        "DATE",
+		KindDecimal:     "NUMBER",
+		KindFloat32:     "BINARY_FLOAT",
+		KindFloat64:     "BINARY_DOUBLE",
+		KindInt16:       "NUMBER(5)",
+		KindInt32:       "NUMBER(10)",
+		KindInt64:       "NUMBER(19)",
+		KindJSON:        "CLOB",
+		KindString:      "CLOB",
+		KindTime:        "VARCHAR2(32)",
+		KindTimestamp:   "TIMESTAMP",
+		KindTimestampTZ: "TIMESTAMP WITH TIME ZONE",
+		KindUint64:      "NUMBER(20)",
+		KindUUID:        "VARCHAR2(36)",
+	}
+	// LOB types cannot be used in an Oracle primary key.
+	oraKeyTypes = map[Kind]string{
+		KindBytes:  "RAW(2000)",
+		KindString: "VARCHAR2(4000)",
+	}
+	pgTypes = map[Kind]string{
+		KindBool:        "BOOLEAN",
+		KindBytes:       "BYTEA",
+		KindDate:        "DATE",
+		KindDecimal:     "NUMERIC",
+		KindFloat32:     "REAL",
+		KindFloat64:     "DOUBLE PRECISION",
+		KindInt16:       "SMALLINT",
+		KindInt32:       "INTEGER",
+		KindInt64:       "BIGINT",
+		KindJSON:        "JSONB",
+		KindString:      "TEXT",
+		KindTime:        "TIME",
+		KindTimestamp:   "TIMESTAMP",
+		KindTimestampTZ: "TIMESTAMPTZ",
+		KindUint64:      "NUMERIC(20)",
+		KindUUID:        "UUID",
+	}
+	sqliteTypes = map[Kind]string{
+		KindBool:        "BOOLEAN",
+		KindBytes:       "BLOB",
+		KindDate:        "TEXT",
+		KindDecimal:     "NUMERIC",
+		KindFloat32:     "REAL",
+		KindFloat64:     "REAL",
+		KindInt16:       "INTEGER",
+		KindInt32:       "INTEGER",
+		KindInt64:       "INTEGER",
+		KindJSON:        "TEXT",
+		KindString:      "TEXT",
+		KindTime:        "TEXT",
+		KindTimestamp:   "TEXT",
+		KindTimestampTZ: "TEXT",
+		KindUint64:      "NUMERIC",
+		KindUUID:        "TEXT",
+	}
+)
@@ -22,6 +22,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -36,14 +38,19 @@ Consider using the following schema:
 `
 
 type dlq struct {
-	name string
-	stmt *sql.Stmt
+	name      string
+	stmt      *sql.Stmt
+	withError bool // The DLQ table has an apply_error column.
+	withKey   bool // The DLQ table has a data_key column.
+	withTable bool // The DLQ table has a target_table column.
 }
 
 var _ types.DLQ = (*dlq)(nil)
 
 // Enqueue implements [types.DLQ].
-func (d *dlq) Enqueue(ctx context.Context, tx types.TargetQuerier, mut types.Mutation) error {
+func (d *dlq) Enqueue(
+	ctx context.Context, tx types.TargetQuerier, table ident.Table, mut types.Mutation, cause error,
+) error {
 	stmt := d.stmt
 	// Bind the prepared statement to the current transaction.
 	if sqlTx, ok := tx.(*sql.Tx); ok {
@@ -60,7 +67,26 @@ func (d *dlq) Enqueue(ctx context.Context, tx types.TargetQuerier, mut types.Mut
 	if len(before) == 0 {
 		before = "null"
 	}
-	_, err := stmt.ExecContext(ctx, d.name, mut.Time.Nanos(), mut.Time.Logical(), after, before)
+	args := []any{d.name, mut.Time.Nanos(), mut.Time.Logical(), after, before}
+	if d.withTable {
+		args = append(args, table.Raw())
+	}
+	if d.withKey {
+		// A deletion can only be replayed if its key is known.
+		var key any
+		if len(mut.Key) > 0 {
+			key = string(mut.Key)
+		}
+		args = append(args, key)
+	}
+	if d.withError {
+		var msg any
+		if cause != nil {
+			msg = cause.Error()
+		}
+		args = append(args, msg)
+	}
+	_, err := stmt.ExecContext(ctx, args...)
 	return errors.WithStack(err)
 }
 
@@ -126,18 +152,38 @@ func (d *dlqs) Get(ctx context.Context, target ident.Schema, name string) (types
 			tbl, missing.String())
 	}
 
+	// Record the target table, the mutation's key, and the cause of
+	// the failure if the DLQ table has been defined with the optional
+	// columns.
+	_, withError := knownCols.Get(colApplyError)
+	_, withKey := knownCols.Get(colDataKey)
+	_, withTable := knownCols.Get(colTargetTable)
+	insertCols := slices.Clone(expectedColumns)
+	if withTable {
+		insertCols = append(insertCols, colTargetTable)
+	}
+	if withKey {
+		insertCols = append(insertCols, colDataKey)
+	}
+	if withError {
+		insertCols = append(insertCols, colApplyError)
+	}
+
 	// The query differs only in the argument syntax.
-	var q string
 	switch d.targetPool.Product {
-	case types.ProductCockroachDB, types.ProductPostgreSQL:
-		q = qBase + argsPG
-	case types.ProductOracle:
-		q = qBase + argsOra
-	case types.ProductMariaDB, types.ProductMySQL:
-		q = qBase + argsMySQL
+	case types.ProductCockroachDB, types.ProductPostgreSQL, types.ProductOracle,
+		types.ProductMariaDB, types.ProductMySQL, types.ProductSQLite:
 	default:
 		return nil, errors.Errorf("dlq unimplemented for product %s", d.targetPool.Product)
 	}
+	names := make([]string, len(insertCols))
+	params := make([]string, len(insertCols))
+	for idx, col := range insertCols {
+		names[idx] = col.Raw()
+		params[idx] = placeholder(d.targetPool.Product, idx+1)
+	}
+	q := fmt.Sprintf("INSERT INTO %%s (%s) VALUES (%s)",
+		strings.Join(names, ", "), strings.Join(params, ", "))
 
 	// Attach a prepared statement to the pool. It will be bound to a
 	// future transaction as necessary.
@@ -147,9 +193,25 @@ func (d *dlqs) Get(ctx context.Context, target ident.Schema, name string) (types
 	}
 
 	ret := &dlq{
-		name: name,
-		stmt: stmt,
+		name:      name,
+		stmt:      stmt,
+		withError: withError,
+		withKey:   withKey,
+		withTable: withTable,
 	}
 	d.mu.validated.Put(tbl, ret)
 	return ret, nil
 }
+
+// placeholder returns the n-th (1-based) query placeholder for the
+// target product.
+func placeholder(product types.Product, n int) string {
+	switch product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		return "$" + strconv.Itoa(n)
+	case types.ProductOracle:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
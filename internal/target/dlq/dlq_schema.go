@@ -34,11 +34,18 @@ var expectedColumns = []ident.Ident{
 	ident.New("data_before"),
 }
 
-const (
-	qBase     = `INSERT INTO %s (dlq_name, source_nanos, source_logical, data_after, data_before) VALUES `
-	argsPG    = `($1, $2, $3, $4, $5)`
-	argsMySQL = `(?, ?, ?, ?, ?)`
-	argsOra   = `(:1, :2, :3, :4, :5)`
+// These columns are not required for enqueuing mutations, but they are
+// used to record why a mutation could not be applied and by the dlq
+// command to identify entries, to filter entries by table, to replay
+// deletions, and to record the outcome of replaying an entry. Like the required columns,
+// they are referred to without quotes.
+var (
+	colApplyError   = ident.New("apply_error")
+	colDataKey      = ident.New("data_key")
+	colEvent        = ident.New("event")
+	colReplayError  = ident.New("replay_error")
+	colReplayStatus = ident.New("replay_status")
+	colTargetTable  = ident.New("target_table")
 )
 
 // These constants define a plausible reference schema that can be used
@@ -51,7 +58,12 @@ dlq_name TEXT NOT NULL,
 source_nanos INT8 NOT NULL,
 source_logical INT8 NOT NULL,
 data_after JSONB NOT NULL,
-data_before JSONB NOT NULL
+data_before JSONB NOT NULL,
+data_key JSONB,
+target_table TEXT,
+apply_error TEXT,
+replay_status TEXT,
+replay_error TEXT
 )`
 	basicMySQLSchema = `CREATE TABLE %[1]s (
 event binary(16) DEFAULT (uuid()) PRIMARY KEY,
@@ -59,7 +71,12 @@ dlq_name TEXT NOT NULL,
 source_nanos INT8 NOT NULL,
 source_logical INT8 NOT NULL,
 data_after JSON NOT NULL,
-data_before JSON NOT NULL
+data_before JSON NOT NULL,
+data_key JSON,
+target_table TEXT,
+apply_error TEXT,
+replay_status TEXT,
+replay_error TEXT
 )`
 	basicOraSchema = `CREATE TABLE %[1]s (
 event INTEGER GENERATED ALWAYS AS IDENTITY,
@@ -67,7 +84,25 @@ dlq_name VARCHAR(256) NOT NULL,
 source_nanos INTEGER NOT NULL,
 source_logical INTEGER NOT NULL,
 data_after CLOB NOT NULL,
-data_before CLOB NOT NULL
+data_before CLOB NOT NULL,
+data_key CLOB,
+target_table VARCHAR(512),
+apply_error CLOB,
+replay_status VARCHAR(16),
+replay_error CLOB
+)`
+	basicSQLiteSchema = `CREATE TABLE %[1]s (
+event INTEGER PRIMARY KEY AUTOINCREMENT,
+dlq_name TEXT NOT NULL,
+source_nanos INTEGER NOT NULL,
+source_logical INTEGER NOT NULL,
+data_after TEXT NOT NULL,
+data_before TEXT NOT NULL,
+data_key TEXT,
+target_table TEXT,
+apply_error TEXT,
+replay_status TEXT,
+replay_error TEXT
 )`
 	basicPGSchema = `CREATE TABLE %[1]s (
 event SERIAL PRIMARY KEY,
@@ -75,7 +110,12 @@ dlq_name TEXT NOT NULL,
 source_nanos INT8 NOT NULL,
 source_logical INT8 NOT NULL,
 data_after JSONB NOT NULL,
-data_before JSONB NOT NULL
+data_before JSONB NOT NULL,
+data_key JSONB,
+target_table TEXT,
+apply_error TEXT,
+replay_status TEXT,
+replay_error TEXT
 )`
 )
 
@@ -88,4 +128,5 @@ var BasicSchemas = map[types.Product]string{
 	types.ProductMySQL:       basicMySQLSchema,
 	types.ProductOracle:      basicOraSchema,
 	types.ProductPostgreSQL:  basicPGSchema,
+	types.ProductSQLite:      basicSQLiteSchema,
 }
@@ -19,8 +19,11 @@ package dlq_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/sinktest/sqlitetest"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -44,6 +47,7 @@ func TestDLQ(t *testing.T) {
 
 	out, err := fixture.DLQs.Get(ctx, fixture.TargetSchema.Schema(), "my_dlq")
 	r.NoError(err)
+	tbl := ident.NewTable(fixture.TargetSchema.Schema(), ident.New("tbl"))
 
 	muts := []types.Mutation{
 		{
@@ -60,7 +64,7 @@ func TestDLQ(t *testing.T) {
 	}
 
 	for _, mut := range muts {
-		r.NoError(out.Enqueue(ctx, fixture.TargetPool.DB, mut))
+		r.NoError(out.Enqueue(ctx, fixture.TargetPool.DB, tbl, mut, nil))
 	}
 
 	var ct int
@@ -105,3 +109,119 @@ func TestMissingTable(t *testing.T) {
 
 	r.ErrorContains(err, "must be created")
 }
+
+// TestQueueSQLite enqueues entries into a SQLite DLQ table and then
+// lists, replays, and purges them.
+func TestQueueSQLite(t *testing.T) {
+	r := require.New(t)
+	fixture, err := sqlitetest.NewFixture(t, &sqlitetest.Options{DLQ: ident.New("replicator_dlq")},
+		`CREATE TABLE kv (pk INTEGER PRIMARY KEY, val INTEGER)`,
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	dlqs := fixture.DLQs
+	dlTable := fixture.DLQTable
+	schema := fixture.Schema
+	tbl := fixture.Table("kv")
+	watchers := fixture.Watchers
+	acc := types.OrderedAcceptorFrom(fixture.Acceptor, watchers)
+
+	out, err := dlqs.Get(ctx, schema, "my_dlq")
+	r.NoError(err)
+	for idx, data := range []string{
+		`{"pk":1,"val":1}`,
+		`{"pk":2,"val":2}`,
+		`{"pk":3,"unknown":3}`, // Will fail to apply.
+		`{"val":4}`,            // Cannot derive a key.
+	} {
+		r.NoError(out.Enqueue(ctx, pool, tbl, types.Mutation{
+			Data: []byte(data),
+			Time: hlc.New(int64(idx+1)*int64(time.Second), 0),
+		}, nil))
+	}
+
+	q, err := dlq.OpenQueue(ctx, pool, dlTable)
+	r.NoError(err)
+
+	entries, err := q.List(ctx, &dlq.Filter{})
+	r.NoError(err)
+	r.Len(entries, 4)
+	for _, e := range entries {
+		r.Equal("my_dlq", e.Name)
+		r.Equal(dlq.StatusPending, e.Status)
+		r.Equal(tbl.Raw(), e.Table)
+	}
+
+	found, err := q.List(ctx, &dlq.Filter{Since: time.Unix(2, 0), Until: time.Unix(4, 0)})
+	r.NoError(err)
+	r.Len(found, 2)
+	r.Equal(hlc.New(2*int64(time.Second), 0), found[0].Time)
+
+	found, err = q.List(ctx, &dlq.Filter{Events: []string{entries[1].Event}})
+	r.NoError(err)
+	r.Len(found, 1)
+	r.JSONEq(`{"pk":2,"val":2}`, string(found[0].Data))
+
+	found, err = q.List(ctx, &dlq.Filter{Table: ident.NewTable(schema, ident.New("other"))})
+	r.NoError(err)
+	r.Empty(found)
+
+	countRows := func() int {
+		var ct int
+		r.NoError(pool.QueryRowContext(ctx, "SELECT count(*) FROM kv").Scan(&ct))
+		return ct
+	}
+
+	// A dry run should not change the target or the entries.
+	res, err := q.Replay(ctx, acc, watchers, entries, &dlq.ReplayOptions{BatchSize: 10, DryRun: true})
+	r.NoError(err)
+	r.Len(res.Replayed, 2)
+	r.Len(res.Failed, 2)
+	r.Zero(countRows())
+	found, err = q.List(ctx, &dlq.Filter{Status: dlq.StatusPending})
+	r.NoError(err)
+	r.Len(found, 4)
+
+	res, err = q.Replay(ctx, acc, watchers, found, &dlq.ReplayOptions{BatchSize: 10})
+	r.NoError(err)
+	r.Len(res.Replayed, 2)
+	r.Len(res.Failed, 2)
+	r.Equal(2, countRows())
+
+	failed, err := q.List(ctx, &dlq.Filter{Status: dlq.StatusFailed})
+	r.NoError(err)
+	r.Len(failed, 2)
+	r.Contains(failed[0].Error, "unknown")
+	r.Contains(failed[1].Error, "missing primary key column")
+
+	// Deletions are replayed using the recorded key or a key derived
+	// from the before data.
+	for idx, mut := range []types.Mutation{
+		{Key: []byte(`[1]`)},
+		{Before: []byte(`{"pk":2,"val":2}`)},
+		{}, // Cannot derive a key.
+	} {
+		mut.Time = hlc.New(int64(idx+5)*int64(time.Second), 0)
+		r.NoError(out.Enqueue(ctx, pool, tbl, mut, nil))
+	}
+	found, err = q.List(ctx, &dlq.Filter{Status: dlq.StatusPending})
+	r.NoError(err)
+	r.Len(found, 3)
+	r.JSONEq(`[1]`, string(found[0].Key))
+	r.Nil(found[0].Data)
+	r.Nil(found[1].Key)
+	res, err = q.Replay(ctx, acc, watchers, found, &dlq.ReplayOptions{BatchSize: 10})
+	r.NoError(err)
+	r.Len(res.Replayed, 2)
+	r.Len(res.Failed, 1)
+	r.Contains(res.Failed[0].Error, "no key or data")
+	r.Zero(countRows())
+
+	count, err := q.Purge(ctx, &dlq.Filter{Status: dlq.StatusReplayed})
+	r.NoError(err)
+	r.Equal(int64(4), count)
+	found, err = q.List(ctx, &dlq.Filter{})
+	r.NoError(err)
+	r.Len(found, 3)
+}
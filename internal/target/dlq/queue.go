@@ -0,0 +1,303 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dlq
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// These values are stored in the replay_status column. Entries which
+// have not been replayed have a NULL status.
+const (
+	StatusFailed   = "failed"
+	StatusPending  = "pending"
+	StatusReplayed = "replayed"
+)
+
+// An Entry is a row in the DLQ table.
+type Entry struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	Cause  string          `json:"cause,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Event  string          `json:"event"`
+	Key    json.RawMessage `json:"key,omitempty"`
+	Name   string          `json:"name"`
+	Status string          `json:"status"`
+	Table  string          `json:"table,omitempty"`
+	Time   hlc.Time        `json:"time"`
+}
+
+// A Filter selects entries in the DLQ table. Zero-valued fields are
+// ignored.
+type Filter struct {
+	Events []string    // Specific entries to select.
+	Limit  int         // The maximum number of entries to list.
+	Name   string      // The name of the queue.
+	Since  time.Time   // Entries with a source time at or after.
+	Status string      // One of the Status constants.
+	Table  ident.Table // The table the entry was destined for.
+	Until  time.Time   // Entries with a source time before.
+}
+
+// A Queue provides management access to the entries in a DLQ table.
+type Queue struct {
+	cols       ident.Map[struct{}]
+	table      ident.Table
+	targetPool *types.TargetPool
+}
+
+// OpenQueue inspects the DLQ table to determine which of the optional
+// columns are present.
+func OpenQueue(ctx context.Context, pool *types.TargetPool, table ident.Table) (*Queue, error) {
+	rows, err := pool.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1=0", table))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not inspect dlq table %s", table)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ret := &Queue{table: table, targetPool: pool}
+	for _, name := range names {
+		ret.cols.Put(ident.New(name), struct{}{})
+	}
+	var missing []string
+	for _, col := range append([]ident.Ident{colEvent}, expectedColumns...) {
+		if !ret.has(col) {
+			missing = append(missing, col.Raw())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.Errorf("dlq table %s is missing the following columns: %s",
+			table, strings.Join(missing, ", "))
+	}
+	return ret, nil
+}
+
+// List returns the entries which match the filter, in source time
+// order.
+func (q *Queue) List(ctx context.Context, filter *Filter) ([]*Entry, error) {
+	where, args, err := q.where(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s, dlq_name, source_nanos, source_logical, data_after, data_before",
+		q.eventExpr())
+	for _, col := range []ident.Ident{
+		colTargetTable, colReplayStatus, colReplayError, colApplyError, colDataKey,
+	} {
+		if q.has(col) {
+			fmt.Fprintf(&sb, ", %s", col.Raw())
+		} else {
+			sb.WriteString(", NULL")
+		}
+	}
+	fmt.Fprintf(&sb, " FROM %s%s ORDER BY source_nanos, source_logical, event", q.table, where)
+	if filter.Limit > 0 {
+		if q.targetPool.Product == types.ProductOracle {
+			fmt.Fprintf(&sb, " FETCH FIRST %d ROWS ONLY", filter.Limit)
+		} else {
+			fmt.Fprintf(&sb, " LIMIT %d", filter.Limit)
+		}
+	}
+
+	rows, err := q.targetPool.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, sb.String())
+	}
+	defer rows.Close()
+
+	var ret []*Entry
+	for rows.Next() {
+		var nanos, logical int64
+		var after, before string
+		var table, status, msg, cause, key sql.NullString
+		e := &Entry{}
+		if err := rows.Scan(&e.Event, &e.Name, &nanos, &logical,
+			&after, &before, &table, &status, &msg, &cause, &key); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		e.Data = nullable(after)
+		e.Before = nullable(before)
+		e.Cause = cause.String
+		e.Error = msg.String
+		e.Key = nullable(key.String)
+		e.Status = status.String
+		if e.Status == "" {
+			e.Status = StatusPending
+		}
+		e.Table = table.String
+		e.Time = hlc.New(nanos, int(logical))
+		ret = append(ret, e)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
+
+// Mark records the outcome of replaying an entry.
+func (q *Queue) Mark(ctx context.Context, tx types.TargetQuerier, event, status, msg string) error {
+	if !q.has(colReplayStatus) || !q.has(colReplayError) {
+		return errors.Errorf("dlq table %s must have %s and %s columns to record replays",
+			q.table, colReplayStatus, colReplayError)
+	}
+	var errArg any
+	if msg != "" {
+		errArg = msg
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET %s = %s, %s = %s WHERE %s = %s",
+		q.table, colReplayStatus.Raw(), q.arg(1), colReplayError.Raw(), q.arg(2),
+		q.eventExpr(), q.arg(3))
+	_, err := tx.ExecContext(ctx, stmt, status, errArg, q.eventValue(event))
+	return errors.Wrap(err, stmt)
+}
+
+// Purge deletes the entries which match the filter. The Limit field is
+// ignored.
+func (q *Queue) Purge(ctx context.Context, filter *Filter) (int64, error) {
+	where, args, err := q.where(filter)
+	if err != nil {
+		return 0, err
+	}
+	stmt := fmt.Sprintf("DELETE FROM %s%s", q.table, where)
+	res, err := q.targetPool.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, stmt)
+	}
+	count, err := res.RowsAffected()
+	return count, errors.WithStack(err)
+}
+
+// Table returns the name of the DLQ table.
+func (q *Queue) Table() ident.Table {
+	return q.table
+}
+
+// arg returns the n-th (1-based) placeholder for the target product.
+func (q *Queue) arg(n int) string {
+	return placeholder(q.targetPool.Product, n)
+}
+
+// eventExpr returns an expression to represent the event column as a
+// string. The suggested schemas use a variety of types for the column.
+func (q *Queue) eventExpr() string {
+	switch q.targetPool.Product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		return "event::TEXT"
+	case types.ProductMariaDB, types.ProductMySQL:
+		return "HEX(event)"
+	case types.ProductOracle:
+		return "TO_CHAR(event)"
+	default:
+		return "CAST(event AS TEXT)"
+	}
+}
+
+// eventValue normalizes a user-provided event id to match eventExpr.
+func (q *Queue) eventValue(event string) string {
+	switch q.targetPool.Product {
+	case types.ProductMariaDB, types.ProductMySQL:
+		return strings.ToUpper(event)
+	default:
+		return event
+	}
+}
+
+// has returns true if the DLQ table has the named column.
+func (q *Queue) has(col ident.Ident) bool {
+	_, ok := q.cols.Get(col)
+	return ok
+}
+
+// where returns a WHERE clause and its arguments.
+func (q *Queue) where(filter *Filter) (string, []any, error) {
+	var args []any
+	var clauses []string
+	add := func(clause string, arg any) {
+		args = append(args, arg)
+		clauses = append(clauses, fmt.Sprintf(clause, q.arg(len(args))))
+	}
+
+	if len(filter.Events) > 0 {
+		params := make([]string, len(filter.Events))
+		for idx, event := range filter.Events {
+			args = append(args, q.eventValue(event))
+			params[idx] = q.arg(len(args))
+		}
+		clauses = append(clauses,
+			fmt.Sprintf("%s IN (%s)", q.eventExpr(), strings.Join(params, ", ")))
+	}
+	if filter.Name != "" {
+		add("dlq_name = %s", filter.Name)
+	}
+	if !filter.Since.IsZero() {
+		add("source_nanos >= %s", filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		add("source_nanos < %s", filter.Until.UnixNano())
+	}
+	if !filter.Table.Empty() {
+		if !q.has(colTargetTable) {
+			return "", nil, errors.Errorf(
+				"dlq table %s must have a %s column to filter by table", q.table, colTargetTable)
+		}
+		add(colTargetTable.Raw()+" = %s", filter.Table.Raw())
+	}
+	switch filter.Status {
+	case "":
+	case StatusPending:
+		// Every entry is pending if replays can't be recorded.
+		if q.has(colReplayStatus) {
+			clauses = append(clauses, colReplayStatus.Raw()+" IS NULL")
+		}
+	case StatusFailed, StatusReplayed:
+		if !q.has(colReplayStatus) {
+			return "", nil, errors.Errorf(
+				"dlq table %s must have a %s column to filter by status", q.table, colReplayStatus)
+		}
+		add(colReplayStatus.Raw()+" = %s", filter.Status)
+	default:
+		return "", nil, errors.Errorf("unknown status %q", filter.Status)
+	}
+
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args, nil
+}
+
+// nullable converts the literal null token that is written by Enqueue
+// to an empty message.
+func nullable(s string) json.RawMessage {
+	if s == "" || s == "null" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
@@ -0,0 +1,264 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// ReplayOptions controls the behavior of [Queue.Replay].
+type ReplayOptions struct {
+	BatchSize   int         // The maximum number of entries per transaction.
+	DryRun      bool        // Roll back all changes to the target.
+	Parallelism int         // The number of tables to replay concurrently.
+	Table       ident.Table // Used for entries that don't record a table.
+}
+
+// ReplayResult summarizes a call to [Queue.Replay].
+type ReplayResult struct {
+	Failed   []*Entry // The Error field will be populated.
+	Replayed []*Entry
+}
+
+// Replay sends the entries to the acceptor, which is expected to
+// perform the usual userscript, merge, and apply steps. Entries are
+// grouped by table and applied in source time order. Each batch of
+// entries is applied in a single transaction. If a batch fails, its
+// entries are retried individually so that the failing entries can be
+// identified. Entries without data are replayed as deletions. The
+// outcome of each entry is recorded in the DLQ table, unless a dry run
+// has been requested.
+//
+// A replayed mutation that is once again sent to a DLQ by the merge
+// function will appear as a new entry.
+func (q *Queue) Replay(
+	ctx context.Context,
+	acc types.MultiAcceptor,
+	watchers types.Watchers,
+	entries []*Entry,
+	opts *ReplayOptions,
+) (*ReplayResult, error) {
+	if !opts.DryRun && (!q.has(colReplayStatus) || !q.has(colReplayError)) {
+		return nil, errors.Errorf("dlq table %s must have %s and %s columns to record replays",
+			q.table, colReplayStatus, colReplayError)
+	}
+	batchSize := max(opts.BatchSize, 1)
+
+	// Group the entries by their destination.
+	var byTable ident.TableMap[[]*Entry]
+	for _, e := range entries {
+		tbl := opts.Table
+		if e.Table != "" {
+			parsed, _, err := ident.ParseTableRelative(e.Table, q.table.Schema())
+			if err != nil {
+				return nil, errors.Wrapf(err, "entry %s", e.Event)
+			}
+			tbl = parsed
+		}
+		if tbl.Empty() {
+			return nil, errors.Errorf("entry %s does not record a target table", e.Event)
+		}
+		byTable.Put(tbl, append(byTable.GetZero(tbl), e))
+	}
+
+	ret := &ReplayResult{}
+	var mu sync.Mutex
+	report := func(replayed, failed []*Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		ret.Failed = append(ret.Failed, failed...)
+		ret.Replayed = append(ret.Replayed, replayed...)
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(opts.Parallelism, 1))
+	for tbl, tblEntries := range byTable.All() {
+		eg.Go(func() error {
+			r := &replayer{acc: acc, opts: opts, queue: q, table: tbl}
+			var err error
+			r.keys, err = primaryKeys(watchers, tbl)
+			if err != nil {
+				return err
+			}
+			for start := 0; start < len(tblEntries); start += batchSize {
+				batch := tblEntries[start:min(start+batchSize, len(tblEntries))]
+				replayed, failed, err := r.replay(egCtx, batch)
+				if err != nil {
+					return err
+				}
+				report(replayed, failed)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// A replayer sends entries to a single target table.
+type replayer struct {
+	acc   types.MultiAcceptor
+	keys  []ident.Ident
+	opts  *ReplayOptions
+	queue *Queue
+	table ident.Table
+}
+
+// replay attempts to apply the batch in a single transaction and falls
+// back to applying the entries one at a time. The returned error is
+// reserved for failures to record the outcome.
+func (r *replayer) replay(
+	ctx context.Context, batch []*Entry,
+) (replayed, failed []*Entry, _ error) {
+	if err := r.apply(ctx, batch); err == nil {
+		return batch, nil, nil
+	} else if len(batch) == 1 {
+		log.WithError(err).Debugf("could not replay entry %s", batch[0].Event)
+		batch[0].Error = err.Error()
+		return nil, batch, r.markFailed(ctx, batch[0])
+	}
+
+	for _, e := range batch {
+		if err := r.apply(ctx, []*Entry{e}); err != nil {
+			log.WithError(err).Debugf("could not replay entry %s", e.Event)
+			e.Error = err.Error()
+			if err := r.markFailed(ctx, e); err != nil {
+				return nil, nil, err
+			}
+			failed = append(failed, e)
+			continue
+		}
+		replayed = append(replayed, e)
+	}
+	return replayed, failed, nil
+}
+
+// apply sends the entries to the acceptor within a transaction that
+// also marks the entries as replayed.
+func (r *replayer) apply(ctx context.Context, entries []*Entry) error {
+	batch := &types.MultiBatch{}
+	for _, e := range entries {
+		// Prefer the key that was recorded when the entry was enqueued.
+		// Otherwise, derive the key from the row data. A deletion
+		// carries no data, so its key must come from the before data.
+		key := e.Key
+		if len(key) == 0 {
+			source := e.Data
+			if len(source) == 0 {
+				source = e.Before
+			}
+			if len(source) == 0 {
+				return errors.Errorf("entry %s has no key or data to replay", e.Event)
+			}
+			var err error
+			key, err = r.key(source)
+			if err != nil {
+				return errors.Wrapf(err, "entry %s", e.Event)
+			}
+		}
+		// A nil Data field indicates a deletion.
+		if err := batch.Accumulate(r.table, types.Mutation{
+			Before: e.Before,
+			Data:   e.Data,
+			Key:    key,
+			Time:   e.Time,
+		}); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.queue.targetPool.BeginConnTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.acc.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{
+		TargetQuerier: tx,
+	}); err != nil {
+		return err
+	}
+	if r.opts.DryRun {
+		return nil
+	}
+	for _, e := range entries {
+		if err := r.queue.Mark(ctx, tx, e.Event, StatusReplayed, ""); err != nil {
+			return err
+		}
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+// key extracts the primary key values from the row data.
+func (r *replayer) key(data json.RawMessage) (json.RawMessage, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(data, &props); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var found ident.Map[json.RawMessage]
+	for name, value := range props {
+		found.Put(ident.New(name), value)
+	}
+	key := make([]json.RawMessage, len(r.keys))
+	for idx, col := range r.keys {
+		value, ok := found.Get(col)
+		if !ok {
+			return nil, errors.Errorf("missing primary key column %s", col)
+		}
+		key[idx] = value
+	}
+	ret, err := json.Marshal(key)
+	return ret, errors.WithStack(err)
+}
+
+// markFailed records the failure outside of the transaction that was
+// rolled back.
+func (r *replayer) markFailed(ctx context.Context, e *Entry) error {
+	if r.opts.DryRun {
+		return nil
+	}
+	return r.queue.Mark(ctx, r.queue.targetPool, e.Event, StatusFailed, e.Error)
+}
+
+// primaryKeys returns the primary key columns of the table.
+func primaryKeys(watchers types.Watchers, tbl ident.Table) ([]ident.Ident, error) {
+	watcher, err := watchers.Get(tbl.Schema())
+	if err != nil {
+		return nil, err
+	}
+	cols, ok := watcher.Get().Columns.Get(tbl)
+	if !ok {
+		return nil, errors.Errorf("unknown table %s", tbl)
+	}
+	var ret []ident.Ident
+	for _, col := range cols {
+		if col.Primary {
+			ret = append(ret, col.Name)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,568 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package history contains an acceptor that maintains an append-only
+// history of the rows in a target table. This is also known as a type 2
+// slowly-changing dimension.
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/target/load"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/crep"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const historyTableMissing = `the history table %[1]s must be created in the target database.
+Consider using the following schema:
+
+`
+
+// chunkSize limits the number of rows in a single query against the
+// history table.
+const chunkSize = 100
+
+// Acceptor writes batches of mutations into their target tables by
+// calling a delegate. If a history table has been configured for the
+// target table via [applycfg.Config.History], each batch will also
+// append versions of the rows to the history table. The history table
+// is written to in the same transaction as the target table, so the
+// two will always be consistent.
+//
+// Each version records the row's key, the values of the tracked
+// columns, the kind of operation, the source timestamp, and the
+// interval for which the version was valid. The versions are derived
+// from the rows that were written to the target table, after any
+// merge, compare-and-set, or filtering behaviors have been applied.
+// Since only the latest mutation of a row within a batch is applied,
+// each batch creates at most one version of a row. The tracked columns
+// are recorded using the representation of values loaded from the
+// target (see [crep.Canonical]). When a new version is appended, the
+// valid_to column of the previous version is set. A mutation which
+// does not change any tracked column, relative to the current version,
+// does not create a new version. A deletion creates a tombstone
+// version that is closed if the row is later re-inserted.
+type Acceptor struct {
+	configs    *applycfg.Configs
+	delegate   types.TableAcceptor
+	loader     *load.Loader
+	stmts      *types.TargetStatements
+	targetPool *types.TargetPool
+	watchers   types.Watchers
+
+	mu struct {
+		sync.RWMutex
+		validated ident.TableMap[*table]
+	}
+}
+
+var _ types.TableAcceptor = (*Acceptor)(nil)
+
+// AcceptTableBatch implements [types.TableAcceptor]. If the options do
+// not provide a [types.TargetQuerier] and the target table has a history
+// table, a transaction will be opened to write to both tables.
+func (a *Acceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	cfg, _ := a.configs.Get(batch.Table).Get()
+	if cfg.History.Empty() {
+		return a.delegate.AcceptTableBatch(ctx, batch, opts)
+	}
+	if opts == nil {
+		opts = &types.AcceptOptions{}
+	}
+	if opts.TargetQuerier != nil {
+		if err := a.delegate.AcceptTableBatch(ctx, batch, opts); err != nil {
+			return err
+		}
+		return a.record(ctx, opts.TargetQuerier, batch, cfg)
+	}
+
+	tx, err := a.targetPool.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	opts = opts.Copy()
+	opts.TargetQuerier = tx
+	if err := a.delegate.AcceptTableBatch(ctx, batch, opts); err != nil {
+		return err
+	}
+	if err := a.record(ctx, tx, batch, cfg); err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+// A version of a row.
+type version struct {
+	data string // Canonical JSON of the tracked columns.
+	key  string // Canonical JSON of the row's key.
+	op   string
+	time hlc.Time
+}
+
+// record appends versions of the mutated rows to the history table.
+func (a *Acceptor) record(
+	ctx context.Context, tq types.TargetQuerier, batch *types.TableBatch, cfg *applycfg.Config,
+) error {
+	hist, err := a.validate(ctx, ident.NewTable(batch.Table.Schema(), cfg.History))
+	if err != nil {
+		return err
+	}
+	var tracked ident.Map[struct{}]
+	for _, col := range cfg.HistoryCols {
+		tracked.Put(col, struct{}{})
+	}
+
+	// Find the latest mutation of each row, since that is the one which
+	// will have been applied to the target.
+	latest := make(map[string]types.Mutation, len(batch.Data))
+	var keys []string
+	for _, mut := range batch.Data {
+		if len(mut.Key) == 0 {
+			return errors.Errorf("history for %s requires mutations to have a key", batch.Table)
+		}
+		key, err := canonical(mut.Key)
+		if err != nil {
+			return errors.Wrapf(err, "could not decode key %s", string(mut.Key))
+		}
+		prev, ok := latest[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		if !ok || hlc.Compare(mut.Time, prev.Time) >= 0 {
+			latest[key] = mut
+		}
+	}
+	sort.Strings(keys)
+
+	applied, err := a.applied(ctx, tq, batch.Table, keys, latest, &tracked)
+	if err != nil {
+		return err
+	}
+	open, err := a.openVersions(ctx, tq, hist, keys)
+	if err != nil {
+		return err
+	}
+
+	// Previous versions are closed at the time of the next version.
+	// The rows in a batch will usually share a single timestamp.
+	closeAt := make(map[hlc.Time][]string)
+	var inserts []*version
+	var unchanged int
+	for _, next := range applied {
+		if prev, ok := open[next.key]; ok {
+			// Ignore re-delivered or stale mutations and those which
+			// don't modify any tracked columns.
+			if hlc.Compare(next.time, prev.time) <= 0 ||
+				(prev.op == next.op && prev.data == next.data) {
+				unchanged++
+				continue
+			}
+			closeAt[next.time] = append(closeAt[next.time], next.key)
+		}
+		inserts = append(inserts, next)
+	}
+	closeTimes := make([]hlc.Time, 0, len(closeAt))
+	for ts := range closeAt {
+		closeTimes = append(closeTimes, ts)
+	}
+	sort.Slice(closeTimes, func(i, j int) bool {
+		return hlc.Compare(closeTimes[i], closeTimes[j]) < 0
+	})
+	for _, ts := range closeTimes {
+		if err := hist.close(ctx, a.stmts, tq, ts, closeAt[ts]); err != nil {
+			return err
+		}
+	}
+	if err := hist.insert(ctx, a.stmts, tq, inserts); err != nil {
+		return err
+	}
+
+	labels := metrics.TableValues(batch.Table)
+	historyVersions.WithLabelValues(labels...).Add(float64(len(inserts)))
+	historyUnchanged.WithLabelValues(labels...).Add(float64(unchanged))
+	log.WithFields(log.Fields{
+		"appended":  len(inserts),
+		"history":   hist.name,
+		"target":    batch.Table,
+		"unchanged": unchanged,
+	}).Debug("recorded row history")
+	return nil
+}
+
+// applied returns a version for each key, derived from the row that was
+// written to the target table. The rows may differ from the mutations
+// if they were merged, filtered, or discarded by a compare-and-set
+// operation. The versions are returned in the order of the keys.
+func (a *Acceptor) applied(
+	ctx context.Context,
+	tq types.TargetQuerier,
+	tbl ident.Table,
+	keys []string,
+	latest map[string]types.Mutation,
+	tracked *ident.Map[struct{}],
+) ([]*version, error) {
+	watcher, err := a.watchers.Get(tbl.Schema())
+	if err != nil {
+		return nil, err
+	}
+	schemaData := watcher.Get()
+	target, ok := schemaData.OriginalName(tbl)
+	if !ok {
+		return nil, errors.Errorf("unknown table %s", tbl)
+	}
+	var cols []types.ColData
+	var pks []ident.Ident
+	for _, col := range schemaData.Columns.GetZero(target) {
+		if col.Primary {
+			pks = append(pks, col.Name)
+		} else if col.Ignored {
+			continue
+		}
+		cols = append(cols, col)
+	}
+
+	// Create a property bag containing only the key of each row, so
+	// that the loader will populate the remaining columns.
+	spec := &merge.BagSpec{Columns: cols}
+	bags := make([]*merge.Bag, len(keys))
+	for idx, key := range keys {
+		// Use the same representation as the values that are loaded.
+		decoded, err := crep.Unmarshal([]byte(key))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decode key %s", key)
+		}
+		values, ok := decoded.([]crep.Value)
+		if !ok || len(values) != len(pks) {
+			return nil, errors.Errorf("key %s does not match the primary key of %s", key, target)
+		}
+		bag := merge.NewBag(spec)
+		for pkIdx, value := range values {
+			bag.Put(pks[pkIdx], value)
+		}
+		bags[idx] = bag
+	}
+	res, err := a.loader.Load(ctx, tq, target, bags)
+	if err != nil {
+		return nil, err
+	}
+	missing := make(map[*merge.Bag]struct{}, len(res.NotFound))
+	for _, bag := range res.NotFound {
+		missing[bag] = struct{}{}
+	}
+
+	ret := make([]*version, len(keys))
+	for idx, key := range keys {
+		mut := latest[key]
+		next := &version{key: key, op: opUpsert, time: mut.Time}
+		// A row which is retained after a deletion has been
+		// soft-deleted.
+		if _, gone := missing[bags[idx]]; gone || mut.IsDelete() {
+			next.data = "null"
+			next.op = opDelete
+		} else {
+			data, err := json.Marshal(bags[idx])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if next.data, err = project(data, tracked); err != nil {
+				return nil, errors.Wrapf(err, "could not encode row data for key %s", key)
+			}
+		}
+		ret[idx] = next
+	}
+	return ret, nil
+}
+
+// openVersions returns the open versions of the rows with the given
+// keys.
+func (a *Acceptor) openVersions(
+	ctx context.Context, tq types.TargetQuerier, hist *table, keys []string,
+) (map[string]*version, error) {
+	ret := make(map[string]*version, len(keys))
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), chunkSize)]
+		keys = keys[len(chunk):]
+
+		stmt, err := a.stmts.Prepare(ctx, tq,
+			fmt.Sprintf("history-open-%s-%d", hist.name, len(chunk)),
+			func() (string, error) { return hist.openQuery(len(chunk)), nil })
+		if err != nil {
+			return nil, err
+		}
+		args := make([]any, len(chunk))
+		for idx, key := range chunk {
+			args[idx] = key
+		}
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for rows.Next() {
+			var data, key, op string
+			var logical int
+			var nanos int64
+			if err := rows.Scan(&key, &op, &nanos, &logical, &data); err != nil {
+				_ = rows.Close()
+				return nil, errors.WithStack(err)
+			}
+			// The database may have reformatted the JSON data.
+			data, err = canonical([]byte(data))
+			if err != nil {
+				_ = rows.Close()
+				return nil, errors.Wrapf(err, "could not decode history data for key %s", key)
+			}
+			ret[key] = &version{data: data, op: op, time: hlc.New(nanos, logical)}
+		}
+		if err := rows.Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return ret, nil
+}
+
+// validate performs a one-time validation that the history table has
+// been defined in the target schema.
+func (a *Acceptor) validate(ctx context.Context, tbl ident.Table) (*table, error) {
+	a.mu.RLock()
+	found, ok := a.mu.validated.Get(tbl)
+	a.mu.RUnlock()
+	if ok {
+		return found, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Double-check idiom.
+	if found, ok := a.mu.validated.Get(tbl); ok {
+		return found, nil
+	}
+
+	watcher, err := a.watchers.Get(tbl.Schema())
+	if err != nil {
+		return nil, err
+	}
+	cols, ok := watcher.Get().Columns.Get(tbl)
+	if !ok {
+		msg := historyTableMissing + BasicSchemas[a.targetPool.Product]
+		return nil, errors.Errorf(msg, tbl)
+	}
+
+	var known ident.Map[struct{}]
+	for _, col := range cols {
+		known.Put(col.Name, struct{}{})
+	}
+	var missing []string
+	for _, name := range expectedColumns {
+		if _, found := known.Get(name); !found {
+			missing = append(missing, name.Raw())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.Errorf(
+			"history table %s was found, but it is missing the following columns: %s",
+			tbl, strings.Join(missing, ", "))
+	}
+
+	ret := &table{name: tbl}
+	switch a.targetPool.Product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		ret.param = func(idx int) string { return fmt.Sprintf("$%d", idx) }
+	case types.ProductOracle:
+		ret.param = func(idx int) string { return fmt.Sprintf(":%d", idx) }
+	case types.ProductMariaDB, types.ProductMySQL, types.ProductSQLite:
+		ret.param = func(int) string { return "?" }
+	default:
+		return nil, errors.Errorf("history unimplemented for product %s", a.targetPool.Product)
+	}
+	ret.product = a.targetPool.Product
+
+	a.mu.validated.Put(tbl, ret)
+	return ret, nil
+}
+
+// A table holds the queries for a validated history table.
+type table struct {
+	name    ident.Table
+	param   func(idx int) string // Product-specific parameter syntax.
+	product types.Product
+}
+
+// close sets the valid_to column of the open versions of the rows.
+func (t *table) close(
+	ctx context.Context,
+	stmts *types.TargetStatements,
+	tq types.TargetQuerier,
+	ts hlc.Time,
+	keys []string,
+) error {
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), chunkSize)]
+		keys = keys[len(chunk):]
+
+		args := make([]any, 0, len(chunk)+1)
+		args = append(args, wallTime(ts))
+		for _, key := range chunk {
+			args = append(args, key)
+		}
+		if err := t.exec(ctx, stmts, tq, t.closeQuery(len(chunk)), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exec executes a cached statement.
+func (t *table) exec(
+	ctx context.Context, stmts *types.TargetStatements, tq types.TargetQuerier, q string, args ...any,
+) error {
+	stmt, err := stmts.Prepare(ctx, tq, q, func() (string, error) { return q, nil })
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx, args...)
+	return errors.Wrap(err, q)
+}
+
+// insert appends the versions to the history table.
+func (t *table) insert(
+	ctx context.Context, stmts *types.TargetStatements, tq types.TargetQuerier, versions []*version,
+) error {
+	for len(versions) > 0 {
+		chunk := versions[:min(len(versions), chunkSize)]
+		versions = versions[len(chunk):]
+
+		args := make([]any, 0, len(chunk)*insertParams)
+		for _, v := range chunk {
+			args = append(args,
+				v.key, v.op, v.time.Nanos(), v.time.Logical(), v.data, wallTime(v.time))
+		}
+		if err := t.exec(ctx, stmts, tq, t.insertQuery(len(chunk)), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeQuery returns a statement that closes the open versions of
+// count rows. The first parameter is the closing time.
+func (t *table) closeQuery(count int) string {
+	return fmt.Sprintf(qClose, t.name, t.param(1), t.params(2, count))
+}
+
+// insertQuery returns a statement that inserts count versions.
+func (t *table) insertQuery(count int) string {
+	var rows strings.Builder
+	for idx := range count {
+		base := idx*insertParams + 1
+		switch t.product {
+		case types.ProductOracle:
+			// Oracle doesn't support multi-row VALUES clauses.
+			if idx > 0 {
+				rows.WriteString(" UNION ALL ")
+			}
+			fmt.Fprintf(&rows, qOraRow, t.param(base), t.param(base+1), t.param(base+2),
+				t.param(base+3), t.param(base+4), t.param(base+5))
+		default:
+			if idx == 0 {
+				rows.WriteString("VALUES ")
+			} else {
+				rows.WriteString(", ")
+			}
+			fmt.Fprintf(&rows, "(%s)", t.params(base, insertParams))
+		}
+	}
+	return fmt.Sprintf(qInsert, t.name, rows.String())
+}
+
+// openQuery returns a query that selects the open versions of count
+// rows.
+func (t *table) openQuery(count int) string {
+	return fmt.Sprintf(qOpen, t.name, t.params(1, count))
+}
+
+// params returns a comma-separated list of count parameters, starting
+// at the given (1-based) index.
+func (t *table) params(start, count int) string {
+	var sb strings.Builder
+	for idx := range count {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(t.param(start + idx))
+	}
+	return sb.String()
+}
+
+// canonical re-encodes the JSON data so that it may be compared.
+func canonical(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return "", errors.WithStack(err)
+	}
+	ret, err := json.Marshal(value)
+	return string(ret), errors.WithStack(err)
+}
+
+// project returns the canonical JSON encoding of the tracked
+// properties in the mutation data. All properties are tracked if the
+// tracked map is empty.
+func project(data json.RawMessage, tracked *ident.Map[struct{}]) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if tracked.Len() > 0 {
+		for key := range doc {
+			if _, ok := tracked.Get(ident.New(key)); !ok {
+				delete(doc, key)
+			}
+		}
+	}
+	ret, err := json.Marshal(doc)
+	return string(ret), errors.WithStack(err)
+}
+
+// wallTime converts the HLC time to a wall time.
+func wallTime(ts hlc.Time) time.Time {
+	return time.Unix(0, ts.Nanos()).UTC()
+}
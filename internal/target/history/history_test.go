@@ -0,0 +1,201 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/sinktest/sqlitetest"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+type historyRow struct {
+	key       string
+	op        string
+	nanos     int64
+	data      string
+	validFrom time.Time
+	validTo   sql.NullTime
+}
+
+func TestHistory(t *testing.T) {
+	r := require.New(t)
+	histTbl := ident.NewTable(ident.MustSchema(ident.New("main")), ident.New("kv_history"))
+	fixture, err := sqlitetest.NewFixture(t, nil,
+		`CREATE TABLE kv (pk INTEGER PRIMARY KEY, val INTEGER, note TEXT)`,
+		fmt.Sprintf(BasicSchemas[types.ProductSQLite], histTbl),
+	)
+	r.NoError(err)
+	ctx := fixture.Context
+	pool := fixture.TargetPool
+	configs := fixture.Configs
+	watchers := fixture.Watchers
+	schema := fixture.Schema
+	tbl := fixture.Table("kv")
+	acc := ProvideAcceptor(fixture.Acceptor, configs, fixture.Loader, fixture.Statements, pool, watchers)
+
+	cfg := applycfg.NewConfig()
+	cfg.History = histTbl.Table()
+	cfg.HistoryCols = applycfg.TargetColumns{ident.New("val")}
+	r.NoError(configs.Set(tbl, cfg))
+
+	// Use a nil TargetQuerier so that the acceptor opens its own
+	// transaction.
+	accept := func(muts ...types.Mutation) {
+		batch := &types.TableBatch{Data: muts, Table: tbl, Time: muts[len(muts)-1].Time}
+		r.NoError(acc.AcceptTableBatch(ctx, batch, nil))
+	}
+	upsert := func(nanos int64, data string) types.Mutation {
+		return types.Mutation{Data: []byte(data), Key: []byte(`[1]`), Time: hlc.New(nanos, 0)}
+	}
+	wall := func(nanos int64) time.Time { return time.Unix(0, nanos).UTC() }
+	readHistory := func(histTbl ident.Table) []historyRow {
+		rows, err := pool.QueryContext(ctx, fmt.Sprintf(
+			`SELECT row_key, op, source_nanos, row_data, valid_from, valid_to FROM %s ORDER BY version`,
+			histTbl))
+		r.NoError(err)
+		defer rows.Close()
+		var ret []historyRow
+		for rows.Next() {
+			var row historyRow
+			r.NoError(rows.Scan(&row.key, &row.op, &row.nanos, &row.data, &row.validFrom, &row.validTo))
+			ret = append(ret, row)
+		}
+		r.NoError(rows.Err())
+		return ret
+	}
+
+	accept(upsert(1, `{"pk":1,"val":10,"note":"a"}`))
+	// Changing an untracked column does not create a new version.
+	accept(upsert(2, `{"pk":1,"val":10,"note":"b"}`))
+	// Only the version of the row that is applied is recorded.
+	accept(
+		upsert(4, `{"pk":1,"val":30,"note":"b"}`),
+		upsert(3, `{"pk":1,"val":20,"note":"b"}`),
+	)
+	// Re-delivery of a stale mutation is ignored.
+	accept(upsert(3, `{"pk":1,"val":20,"note":"b"}`))
+	accept(types.Mutation{Key: []byte(`[1]`), Time: hlc.New(5, 0)})
+	accept(upsert(6, `{"pk":1,"val":30,"note":"c"}`))
+
+	// The current state is unaffected.
+	var note string
+	r.NoError(pool.QueryRowContext(ctx, "SELECT note FROM kv WHERE pk = 1").Scan(&note))
+	r.Equal("c", note)
+
+	type expectedRow struct {
+		key   string
+		op    string
+		nanos int64
+		data  string
+		to    int64
+	}
+	checkHistory := func(histTbl ident.Table, expected []expectedRow) {
+		hist := readHistory(histTbl)
+		r.Len(hist, len(expected))
+		for idx, exp := range expected {
+			row := hist[idx]
+			r.Equal(exp.key, row.key)
+			r.Equal(exp.op, row.op)
+			r.Equal(exp.nanos, row.nanos)
+			r.Equal(exp.data, row.data)
+			r.True(wall(exp.nanos).Equal(row.validFrom))
+			if exp.to == 0 {
+				r.False(row.validTo.Valid)
+			} else {
+				r.True(row.validTo.Valid)
+				r.True(wall(exp.to).Equal(row.validTo.Time))
+			}
+		}
+	}
+	// Values are recorded as they are loaded from the target.
+	expected := []expectedRow{
+		{`[1]`, opUpsert, 1, `{"val":"10"}`, 4},
+		{`[1]`, opUpsert, 4, `{"val":"30"}`, 5},
+		{`[1]`, opDelete, 5, `null`, 6},
+		{`[1]`, opUpsert, 6, `{"val":"30"}`, 0},
+	}
+	checkHistory(histTbl, expected)
+
+	// Rows in a batch are closed and appended together.
+	multi := func(nanos int64, pk, val int) types.Mutation {
+		return types.Mutation{
+			Data: []byte(fmt.Sprintf(`{"pk":%d,"val":%d}`, pk, val)),
+			Key:  []byte(fmt.Sprintf(`[%d]`, pk)),
+			Time: hlc.New(nanos, 0),
+		}
+	}
+	accept(multi(8, 2, 1), multi(8, 3, 1))
+	accept(multi(9, 2, 2), multi(9, 3, 2))
+	checkHistory(histTbl, append(expected,
+		expectedRow{`[2]`, opUpsert, 8, `{"val":"1"}`, 9},
+		expectedRow{`[3]`, opUpsert, 8, `{"val":"1"}`, 9},
+		expectedRow{`[2]`, opUpsert, 9, `{"val":"2"}`, 0},
+		expectedRow{`[3]`, opUpsert, 9, `{"val":"2"}`, 0},
+	))
+
+	// A mutation that is discarded by a compare-and-set operation does
+	// not create a version.
+	casTbl := ident.NewTable(schema, ident.New("kv_cas"))
+	casHistTbl := ident.NewTable(schema, ident.New("kv_cas_history"))
+	_, err = pool.ExecContext(ctx, `CREATE TABLE kv_cas (pk INTEGER PRIMARY KEY, val INTEGER)`)
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, fmt.Sprintf(BasicSchemas[types.ProductSQLite], casHistTbl))
+	r.NoError(err)
+	watcher, err := watchers.Get(schema)
+	r.NoError(err)
+	r.NoError(watcher.Refresh(ctx, pool))
+	casCfg := applycfg.NewConfig()
+	casCfg.CASColumns = applycfg.TargetColumns{ident.New("val")}
+	casCfg.History = casHistTbl.Table()
+	r.NoError(configs.Set(casTbl, casCfg))
+	for _, mut := range []types.Mutation{
+		upsert(1, `{"pk":1,"val":30}`),
+		upsert(2, `{"pk":1,"val":25}`), // Discarded.
+		upsert(3, `{"pk":1,"val":40}`),
+	} {
+		r.NoError(acc.AcceptTableBatch(ctx,
+			&types.TableBatch{Data: []types.Mutation{mut}, Table: casTbl, Time: mut.Time}, nil))
+	}
+	checkHistory(casHistTbl, []expectedRow{
+		{`[1]`, opUpsert, 1, `{"pk":"1","val":"30"}`, 3},
+		{`[1]`, opUpsert, 3, `{"pk":"1","val":"40"}`, 0},
+	})
+
+	// A missing history table reports a suggested schema.
+	cfg = cfg.Copy()
+	cfg.History = ident.New("missing_history")
+	r.NoError(configs.Set(tbl, cfg))
+	err = acc.AcceptTableBatch(ctx, &types.TableBatch{
+		Data:  []types.Mutation{upsert(10, `{"pk":1,"val":40}`)},
+		Table: tbl,
+		Time:  hlc.New(10, 0),
+	}, nil)
+	r.ErrorContains(err, "CREATE TABLE")
+
+	// The failed batch must not have modified the target table.
+	var val int
+	r.NoError(pool.QueryRowContext(ctx, "SELECT val FROM kv WHERE pk = 1").Scan(&val))
+	r.Equal(30, val)
+}
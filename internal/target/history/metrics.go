@@ -0,0 +1,34 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	historyUnchanged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "history_unchanged_total",
+		Help: "the number of mutations that did not create a new version in a history table",
+	}, metrics.TableLabels)
+	historyVersions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "history_versions_total",
+		Help: "the number of versions appended to a history table",
+	}, metrics.TableLabels)
+)
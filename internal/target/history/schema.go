@@ -0,0 +1,126 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+)
+
+// Replicator doesn't create history tables. As with the DLQ table, we
+// validate that the table contains a minimum set of expected columns.
+// The user is free to add other columns (e.g. a surrogate key or
+// indexes on the validity interval) to suit their reporting needs.
+var expectedColumns = []ident.Ident{
+	ident.New("row_key"),
+	ident.New("op"),
+	ident.New("source_nanos"),
+	ident.New("source_logical"),
+	ident.New("row_data"),
+	ident.New("valid_from"),
+	ident.New("valid_to"),
+}
+
+// Values for the op column.
+const (
+	opDelete = "delete"
+	opUpsert = "upsert"
+)
+
+// The %[1]s verb in these queries is the history table. The remaining
+// verbs are substitution parameters or, for qInsert, the rows to
+// insert.
+const (
+	qClose  = `UPDATE %[1]s SET valid_to = %[2]s WHERE valid_to IS NULL AND row_key IN (%[3]s)`
+	qInsert = `INSERT INTO %[1]s (row_key, op, source_nanos, source_logical, row_data, valid_from) %[2]s`
+	qOpen   = `SELECT row_key, op, source_nanos, source_logical, row_data FROM %[1]s ` +
+		`WHERE valid_to IS NULL AND row_key IN (%[2]s)`
+	// An Oracle row to insert, using the column types in the suggested
+	// schema.
+	qOraRow = `SELECT CAST(%s AS VARCHAR(512)), CAST(%s AS VARCHAR(16)), CAST(%s AS INTEGER), ` +
+		`CAST(%s AS INTEGER), TO_CLOB(%s), CAST(%s AS TIMESTAMP) FROM DUAL`
+)
+
+// insertParams is the number of parameters for each row in qInsert.
+const insertParams = 6
+
+// These constants define a plausible reference schema that can be used
+// to create a history table.
+const (
+	basicCRDBSchema = `CREATE TABLE %[1]s (
+version UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+row_key STRING NOT NULL,
+op STRING NOT NULL,
+source_nanos INT8 NOT NULL,
+source_logical INT8 NOT NULL,
+row_data JSONB NOT NULL,
+valid_from TIMESTAMPTZ NOT NULL,
+valid_to TIMESTAMPTZ,
+INDEX (row_key) WHERE valid_to IS NULL
+)`
+	basicMySQLSchema = `CREATE TABLE %[1]s (
+version binary(16) DEFAULT (uuid()) PRIMARY KEY,
+row_key VARCHAR(512) NOT NULL,
+op VARCHAR(16) NOT NULL,
+source_nanos INT8 NOT NULL,
+source_logical INT8 NOT NULL,
+row_data JSON NOT NULL,
+valid_from DATETIME(6) NOT NULL,
+valid_to DATETIME(6),
+INDEX (row_key, valid_to)
+)`
+	basicOraSchema = `CREATE TABLE %[1]s (
+version INTEGER GENERATED ALWAYS AS IDENTITY,
+row_key VARCHAR(512) NOT NULL,
+op VARCHAR(16) NOT NULL,
+source_nanos INTEGER NOT NULL,
+source_logical INTEGER NOT NULL,
+row_data CLOB NOT NULL,
+valid_from TIMESTAMP NOT NULL,
+valid_to TIMESTAMP
+)`
+	basicPGSchema = `CREATE TABLE %[1]s (
+version SERIAL PRIMARY KEY,
+row_key TEXT NOT NULL,
+op TEXT NOT NULL,
+source_nanos INT8 NOT NULL,
+source_logical INT8 NOT NULL,
+row_data JSONB NOT NULL,
+valid_from TIMESTAMPTZ NOT NULL,
+valid_to TIMESTAMPTZ
+)`
+	basicSQLiteSchema = `CREATE TABLE %[1]s (
+version INTEGER PRIMARY KEY AUTOINCREMENT,
+row_key TEXT NOT NULL,
+op TEXT NOT NULL,
+source_nanos INTEGER NOT NULL,
+source_logical INTEGER NOT NULL,
+row_data TEXT NOT NULL,
+valid_from TIMESTAMP NOT NULL,
+valid_to TIMESTAMP
+)`
+)
+
+// BasicSchemas is a collection of suggested schemas for history tables.
+var BasicSchemas = map[types.Product]string{
+	types.ProductCockroachDB: basicCRDBSchema,
+	types.ProductMariaDB:     basicMySQLSchema,
+	types.ProductMySQL:       basicMySQLSchema,
+	types.ProductOracle:      basicOraSchema,
+	types.ProductPostgreSQL:  basicPGSchema,
+	types.ProductSQLite:      basicSQLiteSchema,
+}
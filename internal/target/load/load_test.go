@@ -17,14 +17,20 @@
 package load_test
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/crep"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/cockroachdb/replicator/internal/util/stmtcache"
 	"github.com/stretchr/testify/require"
 )
 
@@ -161,3 +167,50 @@ func TestLoad(t *testing.T) {
 		r.True(crep.Equal(expectedVal, sparse.GetZero(valCol)))
 	})
 }
+
+// TestLoadSQLite uses a SQLite database, since that requires no external
+// services.
+func TestLoadSQLite(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	pool, err := stdpool.OpenTarget(ctx, "sqlite://"+filepath.Join(t.TempDir(), "target.db"))
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, `CREATE TABLE tbl (
+pk0 INTEGER, pk1 TEXT, val TEXT, other INTEGER, PRIMARY KEY (pk0, pk1))`)
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, `INSERT INTO tbl VALUES (1, 'a', 'one', 10), (2, 'b', 'two', 20)`)
+	r.NoError(err)
+
+	loader, err := load.ProvideLoader(
+		&types.TargetStatements{Cache: stmtcache.New[string](ctx, pool.DB, 16)}, pool)
+	r.NoError(err)
+
+	cols := []types.ColData{
+		{Name: ident.New("pk0"), Primary: true, Type: "INTEGER"},
+		{Name: ident.New("pk1"), Primary: true, Type: "TEXT"},
+		{Name: ident.New("val"), Type: "TEXT"},
+		{Name: ident.New("other"), Type: "INTEGER"},
+	}
+	pk0, pk1 := ident.New("pk0"), ident.New("pk1")
+	other, val := ident.New("other"), ident.New("val")
+	table := ident.NewTable(ident.MustSchema(ident.New("main")), ident.New("tbl"))
+
+	first := merge.NewBagOf(cols, nil, pk0, 1, pk1, "a")
+	// The JSON decoder produces numbers as strings.
+	second := merge.NewBagOf(cols, nil, pk0, "2", pk1, "b", other, -42)
+	missing := merge.NewBagOf(cols, nil, pk0, 3, pk1, "c")
+
+	result, err := loader.Load(ctx, pool, table, []*merge.Bag{first, second, missing})
+	r.NoError(err)
+	r.ElementsMatch([]*merge.Bag{first, second}, result.Dirty)
+	r.Equal([]*merge.Bag{missing}, result.NotFound)
+	r.Empty(result.Unmodified)
+
+	r.True(crep.Equal("one", first.GetZero(val)))
+	r.True(crep.Equal(int64(10), first.GetZero(other)))
+	r.True(crep.Equal("two", second.GetZero(val)))
+	// Valid values are not overwritten.
+	r.Equal(-42, second.GetZero(other))
+}
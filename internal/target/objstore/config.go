@@ -0,0 +1,96 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	objstoreSource "github.com/cockroachdb/replicator/internal/source/objstore"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Defaults for flag bindings.
+const (
+	DefaultFileSize      = 16 << 20 // 16 MiB
+	DefaultFlushInterval = time.Minute
+)
+
+// Config controls the behavior of the object-storage target.
+type Config struct {
+	FileSize        int                            // Roll files once they are at least this many bytes.
+	FlushInterval   time.Duration                  // Retry writing resolved timestamps this often.
+	PartitionFormat objstoreSource.PartitionFormat // Directory layout of the output.
+	StorageURL      string                         // A file:// or s3:// URL.
+
+	// The following are computed.
+	local  string     // Root directory for file:// URLs.
+	prefix string     // Path within an S3 bucket.
+	s3     *s3.Config // Connection details for s3:// URLs.
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.IntVar(&c.FileSize, "objstoreFileSize", DefaultFileSize,
+		"roll output files once they reach this many bytes")
+	f.DurationVar(&c.FlushInterval, "objstoreFlushInterval", DefaultFlushInterval,
+		"how often to retry writing a resolved timestamp")
+	f.Var(&c.PartitionFormat, "objstorePartitionFormat",
+		"how output files are partitioned: "+strings.Join(objstoreSource.PartitionFormats(), ","))
+	f.StringVar(&c.StorageURL, "objstoreURL", "",
+		"the destination for changefeed files; file:///path or s3://bucket/path")
+}
+
+// Enabled returns true if a storage URL has been configured.
+func (c *Config) Enabled() bool {
+	return c.StorageURL != ""
+}
+
+// Preflight ensures that the configuration has sane defaults.
+func (c *Config) Preflight() error {
+	if c.StorageURL == "" {
+		return errors.New("objstoreURL must be specified")
+	}
+	if c.FileSize <= 0 {
+		c.FileSize = DefaultFileSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	u, err := url.Parse(c.StorageURL)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse objstoreURL %q", c.StorageURL)
+	}
+	switch objstoreSource.Providers[u.Scheme] {
+	case objstoreSource.LocalStorage:
+		if u.Path == "" {
+			return errors.New("missing path in URL. Must be file:///path")
+		}
+		c.local = u.Path
+	case objstoreSource.S3Storage:
+		c.s3, c.prefix, err = s3.FromURL(u)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unknown scheme %q", u.Scheme)
+	}
+	return nil
+}
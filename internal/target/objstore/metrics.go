@@ -0,0 +1,47 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	resolvedTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "objstore_target_resolved_timestamp_seconds",
+		Help: "the last resolved timestamp written to the object store",
+	})
+	writeBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "objstore_target_bytes_total",
+		Help: "the number of bytes written to the object store",
+	})
+	writeDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "objstore_target_write_duration_seconds",
+		Help:    "the length of time it took to write a file to the object store",
+		Buckets: metrics.LatencyBuckets,
+	})
+	writeErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "objstore_target_write_errors_total",
+		Help: "the number of failed writes to the object store",
+	})
+	writeMutations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "objstore_target_mutations_total",
+		Help: "the number of mutations written to the object store",
+	})
+)
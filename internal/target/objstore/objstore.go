@@ -0,0 +1,289 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package objstore contains a target that writes mutations to an object
+// store, using the same file layout as a CockroachDB cloud-storage
+// changefeed. The files that it produces can be consumed by the
+// objstore source, or by any tool that understands changefeed files.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	objstoreSource "github.com/cockroachdb/replicator/internal/source/objstore"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ndjsonSuffix   = ".ndjson"
+	resolvedSuffix = ".RESOLVED"
+	// schemaID is a placeholder for the descriptor version that
+	// CockroachDB includes in file names.
+	schemaID = "1"
+)
+
+// line is the encoding of a single mutation within an ndjson file.
+type line struct {
+	After   json.RawMessage `json:"after"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	Key     json.RawMessage `json:"key"`
+	Updated string          `json:"updated"`
+}
+
+// pending accumulates the lines for a single output file.
+type pending struct {
+	buf   bytes.Buffer
+	count int
+	min   hlc.Time // The minimum mutation time, used to name the file.
+	topic string
+}
+
+// Acceptor writes mutations to an object store. The mutations in each
+// batch are written to per-table files before the batch is
+// acknowledged, so that a checkpoint cannot advance past data which
+// has not been written. Files are rolled once they reach
+// [Config.FileSize] bytes. Each file is written atomically, so a reader
+// will never observe a partial file.
+//
+// Files are named using the layout
+// [date]/[hour]/[timestamp]-[uniquer]-[topic]-[schema-id].ndjson, where
+// the partitioning directories are determined by
+// [Config.PartitionFormat] and the timestamp is that of the earliest
+// mutation in the file. The uniquer is derived from the contents of the
+// file, so re-delivering a batch after a failed write will replace the
+// files that were already written, rather than duplicating their lines.
+//
+// Resolved timestamps are written by [Acceptor.Resolved] as
+// <timestamp>.RESOLVED files. Callers must not provide mutations at or
+// before a timestamp that has been resolved. This ensures that a
+// lexicographic scan of the bucket will encounter all data files before
+// the resolved timestamp that covers them.
+//
+// The Acceptor ignores [types.AcceptOptions.TargetQuerier], so it may
+// be used as the delegate of a Sequencer in place of a database-backed
+// acceptor.
+type Acceptor struct {
+	bucket bucket.Bucket
+	cfg    *Config
+
+	mu struct {
+		sync.Mutex
+		resolved hlc.Time
+	}
+}
+
+var _ types.MultiAcceptor = (*Acceptor)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *Acceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, _ *types.AcceptOptions,
+) error {
+	if batch.Count() == 0 {
+		return nil
+	}
+
+	var keys []string // Write tables in the order they were seen.
+	files := make(map[string][]*pending)
+	for table, mut := range batch.Mutations() {
+		data, err := json.Marshal(&line{
+			After:   mutationAfter(mut),
+			Before:  mut.Before,
+			Key:     mut.Key,
+			Updated: mut.Time.String(),
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		key := table.Raw()
+		tableFiles, ok := files[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		var file *pending
+		if len(tableFiles) > 0 && tableFiles[len(tableFiles)-1].buf.Len() < a.cfg.FileSize {
+			file = tableFiles[len(tableFiles)-1]
+		} else {
+			file = &pending{
+				min:   mut.Time,
+				topic: table.Table().Raw(),
+			}
+			files[key] = append(tableFiles, file)
+		}
+		if hlc.Compare(mut.Time, file.min) < 0 {
+			file.min = mut.Time
+		}
+		file.buf.Write(data)
+		file.buf.WriteByte('\n')
+		file.count++
+	}
+
+	for _, key := range keys {
+		for idx, file := range files[key] {
+			if err := a.write(ctx, idx, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (a *Acceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	multi := &types.MultiBatch{}
+	if err := types.Apply(batch.Mutations(), multi.Accumulate); err != nil {
+		return err
+	}
+	return a.AcceptMultiBatch(ctx, multi, opts)
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (a *Acceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	multi := &types.MultiBatch{}
+	if err := types.Apply(batch.Mutations(), multi.Accumulate); err != nil {
+		return err
+	}
+	return a.AcceptMultiBatch(ctx, multi, opts)
+}
+
+// Resolved writes a resolved-timestamp file. Calls with a timestamp
+// that is not greater than the last-written resolved timestamp are
+// ignored.
+func (a *Acceptor) Resolved(ctx context.Context, ts hlc.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if hlc.Compare(ts, a.mu.resolved) <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(struct {
+		Resolved string `json:"resolved"`
+	}{ts.String()})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	name := a.objectPath(ts, formatTime(ts)+resolvedSuffix)
+	if err := a.put(ctx, name, data); err != nil {
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+	a.mu.resolved = ts
+	resolvedTimestamp.Set(float64(ts.Nanos()) / 1e9)
+	log.Tracef("wrote resolved timestamp %s", name)
+	return nil
+}
+
+// ResolvedFrom starts a goroutine that will write a resolved timestamp
+// whenever the minimum of the bounds advances. All mutations before the
+// minimum of the bounds are expected to have been passed to the
+// Acceptor. Errors are logged and the resolved timestamp will be
+// retried after [Config.FlushInterval].
+func (a *Acceptor) ResolvedFrom(ctx *stopper.Context, bounds *notify.Var[hlc.Range]) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChangedOrInterval(ctx,
+			hlc.RangeEmpty(), bounds, a.cfg.FlushInterval,
+			func(ctx *stopper.Context, _, next hlc.Range) error {
+				if hlc.Compare(next.Min(), hlc.Zero()) == 0 {
+					return nil
+				}
+				if err := a.Resolved(ctx, next.Min().Before()); err != nil {
+					log.WithError(err).Warn("could not write resolved timestamp; will retry")
+				}
+				return nil
+			})
+		return err
+	})
+}
+
+// write stores the file in the object store. The index distinguishes
+// the files for a table that were rolled from a single batch.
+func (a *Acceptor) write(ctx context.Context, idx int, file *pending) error {
+	sum := sha256.Sum256(file.buf.Bytes())
+	name := a.objectPath(file.min, fmt.Sprintf("%s-%s-1-1-%08x-%s-%s%s",
+		formatTime(file.min), hex.EncodeToString(sum[:8]), idx, file.topic, schemaID, ndjsonSuffix))
+
+	start := time.Now()
+	if err := a.put(ctx, name, file.buf.Bytes()); err != nil {
+		writeErrors.Inc()
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+	writeDurations.Observe(time.Since(start).Seconds())
+	writeBytes.Add(float64(file.buf.Len()))
+	writeMutations.Add(float64(file.count))
+	log.WithFields(log.Fields{
+		"bytes":     file.buf.Len(),
+		"duration":  time.Since(start),
+		"mutations": file.count,
+	}).Tracef("wrote %s", name)
+	return nil
+}
+
+// put writes the data to the named object.
+func (a *Acceptor) put(ctx context.Context, name string, data []byte) error {
+	// The bucket API requires a stopper, so we'll create one that
+	// is scoped to this call.
+	stop := stopper.WithContext(ctx)
+	defer stop.Stop(0)
+	return a.bucket.Put(stop, name, bytes.NewReader(data))
+}
+
+// objectPath returns the full path of the named object, in the
+// partition for the given timestamp.
+func (a *Acceptor) objectPath(ts hlc.Time, name string) string {
+	t := time.Unix(0, ts.Nanos()).UTC()
+	switch a.cfg.PartitionFormat {
+	case objstoreSource.Daily:
+		return path.Join(a.cfg.prefix, t.Format("2006-01-02"), name)
+	case objstoreSource.Hourly:
+		return path.Join(a.cfg.prefix, t.Format("2006-01-02"), t.Format("15"), name)
+	default:
+		return path.Join(a.cfg.prefix, name)
+	}
+}
+
+// formatTime encodes the timestamp in the same way as a CockroachDB
+// cloud-storage changefeed: the wall time to the second, followed by
+// nine digits of nanoseconds and ten digits of logical time.
+func formatTime(ts hlc.Time) string {
+	t := time.Unix(0, ts.Nanos()).UTC()
+	return fmt.Sprintf("%s%09d%010d", t.Format("20060102150405"), t.Nanosecond(), ts.Logical())
+}
+
+// mutationAfter returns the after block for the mutation, which is
+// null for deletions.
+func mutationAfter(mut types.Mutation) json.RawMessage {
+	if mut.IsDelete() {
+		return json.RawMessage("null")
+	}
+	return mut.Data
+}
@@ -0,0 +1,446 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sinktest/recorder"
+	objstoreSource "github.com/cockroachdb/replicator/internal/source/objstore"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/eventproc"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	base     = time.Date(2024, 5, 3, 15, 53, 36, 0, time.UTC)
+	dataFile = regexp.MustCompile(`^2024-05-03/15/20240503155336\d{19}-[0-9a-f]{16}-1-1-[0-9a-f]{8}-tbl[12]-1\.ndjson$`)
+	schema   = ident.MustSchema(ident.New("my_db"), ident.Public)
+)
+
+// newTestAcceptor creates an Acceptor that writes to a temporary
+// directory.
+func newTestAcceptor(t *testing.T, cfg *Config) (*stopper.Context, *Acceptor, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg.StorageURL = "file://" + dir
+	stop := stopper.WithContext(context.Background())
+	t.Cleanup(func() {
+		stop.Stop(time.Second)
+		_ = stop.Wait()
+	})
+	acc, err := ProvideAcceptor(stop, cfg)
+	require.NoError(t, err)
+	return stop, acc, dir
+}
+
+// testBatch creates count mutations, alternating between two tables.
+func testBatch(t *testing.T, count int) *types.MultiBatch {
+	t.Helper()
+	batch := &types.MultiBatch{}
+	for i := range count {
+		table := ident.NewTable(schema, ident.New(fmt.Sprintf("tbl%d", i%2+1)))
+		mut := types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d}`, i)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, i)),
+			Time: hlc.New(base.UnixNano()+int64(i), 0),
+		}
+		// Include a deletion to verify the encoding.
+		if i == count-1 {
+			mut.Data = nil
+		}
+		require.NoError(t, batch.Accumulate(table, mut))
+	}
+	return batch
+}
+
+// listFiles returns the names of all objects in the directory.
+func listFiles(t *testing.T, ctx *stopper.Context, b bucket.Bucket) []string {
+	t.Helper()
+	var ret []string
+	require.NoError(t, b.Walk(ctx, "", &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, file string) error {
+			ret = append(ret, file)
+			return nil
+		}))
+	return ret
+}
+
+// memBucket is an in-memory [bucket.Bucket] that can be configured to
+// reject writes.
+type memBucket struct {
+	mu struct {
+		sync.Mutex
+		allow   int // Writes that will succeed before failing.
+		fail    bool
+		objects map[string][]byte
+		puts    int
+	}
+}
+
+var _ bucket.Bucket = (*memBucket)(nil)
+
+func newMemBucket() *memBucket {
+	ret := &memBucket{}
+	ret.mu.objects = make(map[string][]byte)
+	return ret
+}
+
+// Open implements [bucket.Bucket].
+func (b *memBucket) Open(_ *stopper.Context, path string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.mu.objects[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Put implements [bucket.Bucket].
+func (b *memBucket) Put(_ *stopper.Context, path string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mu.puts++
+	if b.mu.fail {
+		if b.mu.allow <= 0 {
+			return bucket.ErrTransient
+		}
+		b.mu.allow--
+	}
+	b.mu.objects[path] = data
+	return nil
+}
+
+// Walk implements [bucket.Bucket].
+func (b *memBucket) Walk(
+	ctx *stopper.Context,
+	prefix string,
+	_ *bucket.WalkOptions,
+	f func(*stopper.Context, string) error,
+) error {
+	b.mu.Lock()
+	var names []string
+	for name := range b.mu.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	b.mu.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		if err := f(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFail controls whether writes to the bucket will fail.
+func (b *memBucket) setFail(fail bool) {
+	b.failAfter(fail, 0)
+}
+
+// failAfter controls whether writes to the bucket will fail once the
+// given number of writes have succeeded.
+func (b *memBucket) failAfter(fail bool, allow int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mu.allow = allow
+	b.mu.fail = fail
+}
+
+// newMemAcceptor creates an Acceptor that writes to a memBucket.
+func newMemAcceptor(cfg *Config) (*Acceptor, *memBucket) {
+	b := newMemBucket()
+	return &Acceptor{bucket: b, cfg: cfg}, b
+}
+
+// countLines returns the number of ndjson lines in each named object.
+func countLines(b *memBucket, names []string) []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ret := make([]int, len(names))
+	for i, name := range names {
+		ret[i] = bytes.Count(b.mu.objects[name], []byte("\n"))
+	}
+	return ret
+}
+
+// TestRoundTrip verifies that the files that are written can be read
+// back by the objstore source.
+func TestRoundTrip(t *testing.T) {
+	r := require.New(t)
+	const count = 10
+
+	ctx, acc, dir := newTestAcceptor(t, &Config{
+		FileSize:        100, // Roll after about two mutations.
+		FlushInterval:   time.Hour,
+		PartitionFormat: objstoreSource.Hourly,
+	})
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, count), nil))
+	resolved := hlc.New(base.UnixNano()+count, 0)
+	r.NoError(acc.Resolved(ctx, resolved))
+	// Duplicate or older resolved timestamps are elided.
+	r.NoError(acc.Resolved(ctx, resolved))
+	r.NoError(acc.Resolved(ctx, hlc.New(base.UnixNano(), 0)))
+
+	b, err := local.New(os.DirFS(dir))
+	r.NoError(err)
+	files := listFiles(t, ctx, b)
+	r.Greater(len(files), 3)
+
+	// The resolved file must sort after all data files.
+	last := files[len(files)-1]
+	r.Equal("2024-05-03/15/202405031553360000000100000000000"+resolvedSuffix, last)
+	parser, err := cdcjson.New(bufio.MaxScanTokenSize)
+	r.NoError(err)
+	rd, err := b.Open(ctx, last)
+	r.NoError(err)
+	ts, err := parser.Resolved(rd)
+	r.NoError(rd.Close())
+	r.NoError(err)
+	r.Equal(resolved, ts)
+
+	// Process the data files in the same way as the objstore source.
+	rec := &recorder.Recorder{}
+	proc := eventproc.NewLocal(rec, b, parser, schema)
+	for _, file := range files[:len(files)-1] {
+		r.Regexp(dataFile, file)
+		r.NoError(proc.Process(ctx, file))
+	}
+	r.Equal(count, rec.Count())
+
+	seen := make(map[int64]types.Mutation)
+	for _, call := range rec.Calls() {
+		for _, mut := range call.Multi.Mutations() {
+			seen[mut.Time.Nanos()-base.UnixNano()] = mut
+		}
+	}
+	r.Len(seen, count)
+	for i := range int64(count) {
+		mut := seen[i]
+		r.Equal(fmt.Sprintf(`[%d]`, i), string(mut.Key))
+		if i == count-1 {
+			r.True(mut.IsDelete())
+		} else {
+			r.Equal(fmt.Sprintf(`{"pk":%d}`, i), string(mut.Data))
+		}
+	}
+}
+
+// TestRedelivery verifies that mutations are written before they are
+// acknowledged, so that dropping the Acceptor does not lose data, and
+// that a batch which is re-delivered after a failed write does not
+// duplicate the lines that had already been written.
+func TestRedelivery(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	// Each mutation is about 70 bytes, so each table will be written
+	// as three files.
+	cfg := &Config{FileSize: 100}
+	acc, b := newMemAcceptor(cfg)
+	batch := testBatch(t, 10)
+	b.failAfter(true, 2)
+	r.ErrorIs(acc.AcceptMultiBatch(ctx, batch, nil), bucket.ErrTransient)
+	r.Len(listFiles(t, ctx, b), 2)
+
+	// Drop the acceptor, as though the process had been restarted, and
+	// re-deliver the unacknowledged batch to a new instance.
+	b.setFail(false)
+	acc = &Acceptor{bucket: b, cfg: cfg}
+	r.NoError(acc.AcceptMultiBatch(ctx, batch, nil))
+	files := listFiles(t, ctx, b)
+	r.Len(files, 6)
+	r.Equal(10, sum(countLines(b, files)))
+
+	// Once acknowledged, every mutation has been written, without
+	// any further calls to the acceptor.
+	seen := make(map[string]bool)
+	b.mu.Lock()
+	for _, name := range files {
+		for _, data := range bytes.Split(bytes.TrimSpace(b.mu.objects[name]), []byte("\n")) {
+			var decoded line
+			r.NoError(json.Unmarshal(data, &decoded))
+			seen[string(decoded.Key)] = true
+		}
+	}
+	b.mu.Unlock()
+	r.Len(seen, 10)
+}
+
+// TestKeyLayout verifies the names of the objects that are written for
+// each partition format.
+func TestKeyLayout(t *testing.T) {
+	ts := hlc.New(base.UnixNano()+123, 4)
+	const data = "202405031553360000001230000000004-18edb0ebec79e613-1-1-00000000-tbl1-1.ndjson"
+	const resolved = "202405031553360000001230000000004.RESOLVED"
+
+	tcs := []struct {
+		format objstoreSource.PartitionFormat
+		prefix string
+		dir    string
+	}{
+		{format: objstoreSource.Flat},
+		{format: objstoreSource.Flat, prefix: "path/to", dir: "path/to/"},
+		{format: objstoreSource.Daily, dir: "2024-05-03/"},
+		{format: objstoreSource.Daily, prefix: "path/to", dir: "path/to/2024-05-03/"},
+		{format: objstoreSource.Hourly, dir: "2024-05-03/15/"},
+		{format: objstoreSource.Hourly, prefix: "path/to", dir: "path/to/2024-05-03/15/"},
+	}
+	for idx, tc := range tcs {
+		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
+			r := require.New(t)
+			ctx := stopper.WithContext(context.Background())
+			defer ctx.Stop(0)
+
+			acc, b := newMemAcceptor(&Config{
+				FileSize:        DefaultFileSize,
+				PartitionFormat: tc.format,
+				prefix:          tc.prefix,
+			})
+			batch := &types.MultiBatch{}
+			r.NoError(batch.Accumulate(ident.NewTable(schema, ident.New("tbl1")), types.Mutation{
+				Data: json.RawMessage(`{"pk":1}`),
+				Key:  json.RawMessage(`[1]`),
+				Time: ts,
+			}))
+			r.NoError(acc.AcceptMultiBatch(ctx, batch, nil))
+			r.NoError(acc.Resolved(ctx, ts))
+
+			r.Equal([]string{
+				tc.dir + data,
+				tc.dir + resolved,
+			}, listFiles(t, ctx, b))
+		})
+	}
+}
+
+// TestBatching verifies that mutations are grouped into one file per
+// table and that files are rolled once they reach the configured size.
+func TestBatching(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	// Each batch is written before it is acknowledged.
+	acc, b := newMemAcceptor(&Config{FileSize: DefaultFileSize})
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 6), nil))
+	files := listFiles(t, ctx, b)
+	r.Len(files, 2)
+	r.Equal([]int{3, 3}, countLines(b, files))
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 4), nil))
+	files = listFiles(t, ctx, b)
+	r.Len(files, 4)
+	r.Equal(10, sum(countLines(b, files)))
+	for _, table := range []string{"-tbl1-", "-tbl2-"} {
+		count := 0
+		for _, file := range files {
+			if strings.Contains(file, table) {
+				count++
+			}
+		}
+		r.Equal(2, count, table)
+	}
+
+	// Each mutation is about 70 bytes, so a file is written after
+	// every second mutation to a table.
+	acc, b = newMemAcceptor(&Config{FileSize: 100})
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 10), nil))
+	files = listFiles(t, ctx, b)
+	r.Len(files, 6)
+	counts := countLines(b, files)
+	sort.Ints(counts)
+	r.Equal([]int{1, 1, 2, 2, 2, 2}, counts)
+}
+
+// TestRetry verifies that a failed write is reported to the caller and
+// that the resolved timestamp is not advanced until it has been
+// written.
+func TestRetry(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	acc, b := newMemAcceptor(&Config{FileSize: DefaultFileSize})
+	b.setFail(true)
+	r.ErrorIs(acc.AcceptMultiBatch(ctx, testBatch(t, 4), nil), bucket.ErrTransient)
+	resolved := hlc.New(base.UnixNano()+4, 0)
+	r.ErrorIs(acc.Resolved(ctx, resolved), bucket.ErrTransient)
+	r.Empty(listFiles(t, ctx, b))
+	r.Equal(hlc.Zero(), acc.mu.resolved)
+
+	// Once the bucket recovers, the re-delivered batch and the
+	// resolved timestamp are written.
+	b.setFail(false)
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 4), nil))
+	r.NoError(acc.Resolved(ctx, resolved))
+	files := listFiles(t, ctx, b)
+	r.Len(files, 3)
+	r.Equal([]int{2, 2}, countLines(b, files[:2]))
+	r.True(strings.HasSuffix(files[2], resolvedSuffix))
+	r.Equal(resolved, acc.mu.resolved)
+}
+
+// sum returns the sum of the values.
+func sum(values []int) int {
+	ret := 0
+	for _, v := range values {
+		ret += v
+	}
+	return ret
+}
+
+func TestPreflight(t *testing.T) {
+	r := require.New(t)
+
+	r.ErrorContains((&Config{}).Preflight(), "objstoreURL")
+	r.ErrorContains((&Config{StorageURL: "ftp://example.com"}).Preflight(), "scheme")
+	r.ErrorContains((&Config{StorageURL: "s3:///path"}).Preflight(), "bucket")
+
+	cfg := &Config{StorageURL: "s3://bucket/path/to?AWS_ENDPOINT=http://localhost:9000"}
+	r.NoError(cfg.Preflight())
+	r.Equal(DefaultFileSize, cfg.FileSize)
+	r.Equal(DefaultFlushInterval, cfg.FlushInterval)
+	r.Equal("path/to", cfg.prefix)
+	r.Equal("localhost:9000", cfg.s3.Endpoint)
+	r.True(cfg.s3.Insecure)
+}
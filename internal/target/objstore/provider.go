@@ -0,0 +1,64 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"os"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
+	"github.com/google/wire"
+	"github.com/pkg/errors"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(ProvideAcceptor)
+
+// ProvideAcceptor is called by Wire. This provider will return nil if
+// no storage URL has been configured.
+func ProvideAcceptor(_ *stopper.Context, cfg *Config) (*Acceptor, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	b, err := newBucket(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Acceptor{
+		bucket: b,
+		cfg:    cfg,
+	}, nil
+}
+
+func newBucket(cfg *Config) (bucket.Bucket, error) {
+	switch {
+	case cfg.local != "":
+		if err := os.MkdirAll(cfg.local, 0755); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return local.NewDir(cfg.local)
+	case cfg.s3 != nil:
+		return s3.New(cfg.s3)
+	default:
+		return nil, errors.New("invalid configuration. Missing bucket specification")
+	}
+}
@@ -20,7 +20,9 @@ package target
 
 import (
 	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/ddl"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/history"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/google/wire"
@@ -30,7 +32,9 @@ import (
 // sub-packages.
 var Set = wire.NewSet(
 	apply.Set,
+	ddl.Set,
 	dlq.Set,
+	history.Set,
 	load.Set,
 	schemawatch.Set,
 )
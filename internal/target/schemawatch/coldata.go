@@ -264,6 +264,21 @@ WHERE (OWNER = :owner AND TABLE_NAME = :tbl_name)
 ORDER BY POSITION, COLUMN_NAME
 `
 
+// Retrieve the primary key columns in their index-order, followed by
+// the remaining columns.
+//
+// The pragma_table_xinfo function reports a non-zero pk value for the
+// one-based position of a column within the primary key. The hidden
+// column is 1 for the hidden columns of virtual tables, which we
+// exclude, and 2 or 3 for generated columns, which are ignored.
+// https://www.sqlite.org/pragma.html#pragma_table_xinfo
+const sqlColumnsQuerySQLite = `
+  SELECT name, pk > 0, type, dflt_value, hidden IN (2, 3)
+    FROM pragma_table_xinfo(?1, ?2)
+   WHERE hidden != 1
+ORDER BY CASE WHEN pk > 0 THEN pk ELSE 2048 END, name
+`
+
 // Retrieve the primary key columns in their index-order, then append
 // any remaining non-generated columns.
 //
@@ -378,6 +393,16 @@ func getColumns(
 			sql.Named("owner", parts[0].Raw()),
 			sql.Named("tbl_name", parts[1].Raw()),
 		}
+	case types.ProductSQLite:
+		parts := table.Idents(make([]ident.Ident, 0, 2))
+		if len(parts) != 2 {
+			return nil, errors.Errorf("expecting two table name parts, had %d", len(parts))
+		}
+		stmt = sqlColumnsQuerySQLite
+		args = []any{
+			parts[1].Raw(),
+			parts[0].Raw(),
+		}
 	default:
 		return nil, errors.Errorf("unimplemented: %s", tx.Product)
 	}
@@ -454,7 +479,7 @@ func getColumns(
 				if defaultExpr.String != "NULL" && defaultExpr.Valid {
 					column.DefaultExpr = defaultExpr.String
 				}
-			case types.ProductMySQL:
+			case types.ProductMySQL, types.ProductSQLite:
 				if defaultExpr.Valid {
 					column.DefaultExpr = defaultExpr.String
 				}
@@ -217,6 +217,46 @@ WITH RECURSIVE
 SELECT * FROM refs UNION ALL SELECT * FROM seeds
 `
 
+// depOrderTemplateSQLite is similar to the other templates, however
+// SQLite does not allow foreign keys to refer to tables in another
+// schema, so there is no need to recursively chase references.
+//
+//   - tables: All ordinary tables within the schema, excluding SQLite's
+//     internal tables.
+//   - refs: Joins each table against the foreign keys that it declares,
+//     filtering out any table self-references.
+//   - seeds: Emits a dummy row for all tables in the schema.
+//   - top level: Union of refs and seeds.
+//
+// The catalog columns are always empty strings.
+const depOrderTemplateSQLite = `
+WITH
+  tables
+    AS (
+      SELECT schema, name
+        FROM pragma_table_list
+       WHERE lower(schema) = lower(?1)
+         AND type = 'table'
+         AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+    ),
+  refs
+    AS (
+      SELECT DISTINCT
+        '' AS child_catalog, t.schema AS child_schema, t.name AS child_table_name,
+        '' AS parent_catalog, t.schema AS parent_schema, fk."table" AS parent_table_name
+      FROM tables t, pragma_foreign_key_list(t.name, t.schema) fk
+      WHERE lower(fk."table") != lower(t.name)
+    ),
+  seeds
+    AS (
+      SELECT
+        NULL child_catalog, NULL child_schema, NULL child_table_name,
+        '' parent_catalog, schema, name
+      FROM tables
+    )
+SELECT * FROM refs UNION ALL SELECT * FROM seeds
+`
+
 // getDependencyRefs returns a map describing the parent-to-children
 // relationships of tables. That is, the map values are the tables that
 // have some immediate dependency on the key. Tables with no
@@ -259,6 +299,14 @@ func getDependencyRefs(
 	case types.ProductOracle:
 		stmt = depOrderTemplateOra
 		args = []any{sql.Named("owner", db.Raw())}
+
+	case types.ProductSQLite:
+		parts := db.Idents(make([]ident.Ident, 0, 1))
+		if len(parts) != 1 {
+			return nil, errors.Errorf("expecting one schema parts, had %d", len(parts))
+		}
+		stmt = depOrderTemplateSQLite
+		args = []any{parts[0].Raw()}
 	default:
 		return nil, errors.Errorf("getDependencyOrder unimplemented product: %s", tx.Product)
 	}
@@ -31,6 +31,7 @@ import (
 	"encoding/json"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/types"
@@ -49,6 +50,11 @@ var (
 			// as a 16-byte raw value in the destination.
 			pattern: regexp.MustCompile(`^RAW\(16\)$`),
 			parser: func(a any) (any, error) {
+				// Values read from the target, e.g. when resolving
+				// merge conflicts, are already in the driver format.
+				if b, ok := a.([]byte); ok && len(b) == 16 {
+					return b, nil
+				}
 				s, ok := a.(string)
 				if !ok {
 					return nil, errors.Errorf("expecting string, got %T", a)
@@ -68,6 +74,9 @@ var (
 		{
 			pattern: regexp.MustCompile(`^TIMESTAMP\(\d+\) WITH TIME ZONE$`),
 			parser: func(a any) (any, error) {
+				if t, ok := a.(time.Time); ok {
+					return t, nil
+				}
 				s, ok := a.(string)
 				if !ok {
 					return nil, errors.Errorf("expecting string, got %T", a)
@@ -79,6 +88,9 @@ var (
 			// Try parsing with and without a timezone specifier.
 			pattern: regexp.MustCompile(`^TIMESTAMP\(\d+\)$`),
 			parser: func(a any) (any, error) {
+				if t, ok := a.(time.Time); ok {
+					return t, nil
+				}
 				s, ok := a.(string)
 				if !ok {
 					return nil, errors.Errorf("expecting string, got %T", a)
@@ -92,6 +104,9 @@ var (
 		{
 			pattern: regexp.MustCompile(`^DATE$`),
 			parser: func(a any) (any, error) {
+				if t, ok := a.(time.Time); ok {
+					return t, nil
+				}
 				s, ok := a.(string)
 				if !ok {
 					return nil, errors.Errorf("expecting string, got %T", a)
@@ -102,17 +117,25 @@ var (
 	}
 )
 
-// coerce a bit-string into a integer
+// coerce a bit-string into a integer. Values that were read from the
+// target, e.g. when resolving merge conflicts, are passed through.
 func coerceInt(a any) (any, error) {
-	s, ok := a.(string)
-	if !ok {
+	switch t := a.(type) {
+	case []byte, int64:
+		return t, nil
+	case string:
+		return strconv.ParseInt(t, 2, 64)
+	default:
 		return nil, errors.Errorf("expecting a string, got %T", a)
 	}
-	return strconv.ParseInt(s, 2, 64)
 }
 
-// coerce a hex-string to its binary representation, after stripping the "\x" prefix
+// coerce a hex-string to its binary representation, after stripping the "\x" prefix.
+// Byte slices read from the target are passed through.
 func coerceHexString(a any) (any, error) {
+	if b, ok := a.([]byte); ok {
+		return b, nil
+	}
 	s, ok := a.(string)
 	if !ok || len(s) < 2 {
 		return nil, errors.Errorf("expecting a hex encoded string, got %T", a)
@@ -120,9 +143,17 @@ func coerceHexString(a any) (any, error) {
 	return hex.DecodeString(s[2:])
 }
 
-// coerce to json
+// coerce to json. Byte slices read from the target are assumed to
+// already contain a JSON document.
 func coerceJSON(a any) (any, error) {
-	return json.Marshal(a)
+	switch t := a.(type) {
+	case []byte:
+		return t, nil
+	case json.RawMessage:
+		return []byte(t), nil
+	default:
+		return json.Marshal(a)
+	}
 }
 
 func parseHelper(product types.Product, typeName string) func(any) (any, error) {
@@ -147,6 +178,18 @@ func parseHelper(product types.Product, typeName string) func(any) (any, error)
 				return helper.parser
 			}
 		}
+	case types.ProductSQLite:
+		// Declared types are free-form, so we match BLOB in the same
+		// way that SQLite determines a column's affinity.
+		upper := strings.ToUpper(typeName)
+		switch {
+		case upper == "JSON" || upper == "JSONB":
+			return coerceJSON
+		case strings.Contains(upper, "BLOB"):
+			return coerceHexString
+		default:
+			return nil
+		}
 	}
 	return nil
 }
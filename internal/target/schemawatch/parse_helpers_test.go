@@ -65,6 +65,17 @@ func TestOraParseHelpers(t *testing.T) {
 			input:    now.Format("2006-01-02"),
 			expected: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
 		},
+		// Values read from the target are passed through.
+		{
+			typ:      "RAW(16)",
+			input:    exampleUUID[:],
+			expected: exampleUUID[:],
+		},
+		{
+			typ:      "TIMESTAMP(9)",
+			input:    now,
+			expected: now,
+		},
 	}
 
 	for idx, tc := range tcs {
@@ -98,6 +109,22 @@ func TestMyParseHelpers(t *testing.T) {
 			input:    []int{1, 2, 3},
 			expected: []byte(`[1,2,3]`),
 		},
+		// Values read from the target are passed through.
+		{
+			typ:      "json",
+			input:    []byte(`{"k":"a"}`),
+			expected: []byte(`{"k":"a"}`),
+		},
+		{
+			typ:      "blob",
+			input:    []byte{1, 2, 3},
+			expected: []byte{1, 2, 3},
+		},
+		{
+			typ:      "bit",
+			input:    []byte{5},
+			expected: []byte{5},
+		},
 	}
 	for idx, tc := range tcs {
 		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
@@ -110,3 +137,47 @@ func TestMyParseHelpers(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteParseHelpers(t *testing.T) {
+	tcs := []struct {
+		typ      string
+		input    any
+		expected []byte // A nil value means no helper is expected.
+	}{
+		{
+			typ:      "json",
+			input:    map[string]any{"k": "a"},
+			expected: []byte(`{"k":"a"}`),
+		},
+		{
+			typ:      "BLOB",
+			input:    `\x010203`,
+			expected: []byte{1, 2, 3},
+		},
+		{
+			typ:      "MEDIUMBLOB",
+			input:    `\x04`,
+			expected: []byte{4},
+		},
+		{
+			typ: "TEXT",
+		},
+		{
+			typ: "",
+		},
+	}
+	for idx, tc := range tcs {
+		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
+			r := require.New(t)
+			helper := parseHelper(types.ProductSQLite, tc.typ)
+			if tc.expected == nil {
+				r.Nil(helper)
+				return
+			}
+			r.NotNil(helper)
+			ret, err := helper(tc.input)
+			r.NoError(err)
+			r.Equal(tc.expected, ret)
+		})
+	}
+}
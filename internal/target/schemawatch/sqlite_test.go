@@ -0,0 +1,116 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schemawatch
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSQLite validates the schema queries against a SQLite database,
+// which requires no external services.
+func TestSQLite(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	pool, err := stdpool.OpenTarget(ctx, "sqlite://"+filepath.Join(t.TempDir(), "target.db"))
+	r.NoError(err)
+
+	for _, stmt := range []string{
+		`CREATE TABLE parent (pk INTEGER PRIMARY KEY)`,
+		`CREATE TABLE child (
+  val TEXT DEFAULT 'hello',
+  pk1 TEXT,
+  pk0 INTEGER,
+  parent INTEGER REFERENCES parent(pk),
+  doubled INTEGER GENERATED ALWAYS AS (pk0 * 2) VIRTUAL,
+  data BLOB,
+  doc JSON,
+  PRIMARY KEY (pk0, pk1)
+)`,
+		`CREATE TABLE grandchild (
+  pk INTEGER PRIMARY KEY,
+  pk0 INTEGER,
+  pk1 TEXT,
+  FOREIGN KEY (pk0, pk1) REFERENCES child(pk0, pk1)
+)`,
+		`CREATE TABLE self (pk INTEGER PRIMARY KEY, ref INTEGER REFERENCES self(pk))`,
+		`CREATE TABLE no_pk (val TEXT)`,
+		`CREATE VIEW ignored AS SELECT * FROM parent`,
+	} {
+		_, err := pool.ExecContext(ctx, stmt)
+		r.NoError(err, stmt)
+	}
+
+	sch := ident.MustSchema(ident.New("main"))
+	w := &watcher{schema: sch}
+	data, err := w.getTables(ctx, pool)
+	r.NoError(err)
+
+	tbl := func(name string) ident.Table { return ident.NewTable(sch, ident.New(name)) }
+	r.Equal(5, data.Columns.Len())
+	_, found := data.Columns.Get(tbl("ignored"))
+	r.False(found)
+
+	// Primary key columns appear first, in their key order.
+	cols, ok := data.Columns.Get(tbl("child"))
+	r.True(ok)
+	// The BLOB and JSON columns have parse helpers, which can't be
+	// compared for equality.
+	r.NotNil(cols[2].Parse)
+	r.NotNil(cols[3].Parse)
+	r.Nil(cols[0].Parse)
+	stripped := slices.Clone(cols)
+	for i := range stripped {
+		stripped[i].Parse = nil
+	}
+	r.Equal([]types.ColData{
+		{Name: ident.New("pk0"), Primary: true, Type: "INTEGER"},
+		{Name: ident.New("pk1"), Primary: true, Type: "TEXT"},
+		{Name: ident.New("data"), Type: "BLOB"},
+		{Name: ident.New("doc"), Type: "JSON"},
+		{Name: ident.New("doubled"), Ignored: true, Type: "INTEGER"},
+		{Name: ident.New("parent"), Type: "INTEGER"},
+		{Name: ident.New("val"), DefaultExpr: "'hello'", Type: "TEXT"},
+	}, stripped)
+
+	// A table without a primary key has a synthetic rowid.
+	cols, ok = data.Columns.Get(tbl("no_pk"))
+	r.True(ok)
+	r.Len(cols, 2)
+	r.Equal(ident.New("rowid"), cols[0].Name)
+	r.True(cols[0].Primary)
+
+	// Parents must be ordered before their children.
+	order := data.Entire.Order
+	idx := func(name string) int {
+		return slices.IndexFunc(order, func(t ident.Table) bool { return ident.Equal(t, tbl(name)) })
+	}
+	r.Len(order, 5)
+	r.Less(idx("parent"), idx("child"))
+	r.Less(idx("child"), idx("grandchild"))
+	r.GreaterOrEqual(idx("self"), 0)
+}
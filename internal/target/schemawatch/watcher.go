@@ -190,6 +190,12 @@ WHERE upper(table_schema) = upper(?)
 AND table_type = 'BASE TABLE'`
 	tableTemplateOracle = `
 SELECT OWNER, NULL, TABLE_NAME FROM ALL_TABLES WHERE UPPER(OWNER) = UPPER(:owner)`
+	tableTemplateSQLite = `
+SELECT schema, NULL, name
+FROM pragma_table_list
+WHERE lower(schema) = lower(?)
+AND type = 'table'
+AND name NOT LIKE 'sqlite\_%' ESCAPE '\'`
 )
 
 func (w *watcher) getTables(ctx context.Context, tx *types.TargetPool) (*types.SchemaData, error) {
@@ -225,6 +231,8 @@ func (w *watcher) getTables(ctx context.Context, tx *types.TargetPool) (*types.S
 			rows, err = tx.QueryContext(ctx, tableTemplateMySQL, w.schema.Raw())
 		case types.ProductOracle:
 			rows, err = tx.QueryContext(ctx, tableTemplateOracle, w.schema.Raw())
+		case types.ProductSQLite:
+			rows, err = tx.QueryContext(ctx, tableTemplateSQLite, w.schema.Raw())
 
 		default:
 			return errors.Errorf("unimplemented product: %s", tx.Product)
@@ -0,0 +1,53 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sink allows mutations to be delivered to a webhook or to an
+// object store instead of to the tables in the target database.
+package sink
+
+import (
+	"github.com/cockroachdb/replicator/internal/target/objstore"
+	"github.com/cockroachdb/replicator/internal/target/webhook"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Config selects the destination for mutations. If neither sink is
+// configured, mutations are applied to the target database.
+type Config struct {
+	ObjStore objstore.Config
+	Webhook  webhook.Config
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.ObjStore.Bind(f)
+	c.Webhook.Bind(f)
+}
+
+// Preflight ensures that at most one sink has been configured.
+func (c *Config) Preflight() error {
+	switch {
+	case c.ObjStore.Enabled() && c.Webhook.Enabled():
+		return errors.New("only one of objstoreURL or webhookURL may be specified")
+	case c.ObjStore.Enabled():
+		return c.ObjStore.Preflight()
+	case c.Webhook.Enabled():
+		return c.Webhook.Preflight()
+	default:
+		return nil
+	}
+}
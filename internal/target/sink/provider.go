@@ -0,0 +1,61 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/target/objstore"
+	"github.com/cockroachdb/replicator/internal/target/webhook"
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(
+	ProvideObjStoreConfig,
+	ProvideSink,
+	ProvideWebhookConfig,
+	objstore.Set,
+	webhook.Set,
+)
+
+// ProvideObjStoreConfig is called by Wire.
+func ProvideObjStoreConfig(cfg *Config) *objstore.Config {
+	return &cfg.ObjStore
+}
+
+// ProvideSink is called by Wire. This provider will return nil if
+// mutations should be applied to the target database.
+func ProvideSink(
+	cfg *Config, store *objstore.Acceptor, hook *webhook.Acceptor,
+) (conveyor.Sink, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	switch {
+	case store != nil:
+		return store, nil
+	case hook != nil:
+		return hook, nil
+	default:
+		return nil, nil
+	}
+}
+
+// ProvideWebhookConfig is called by Wire.
+func ProvideWebhookConfig(cfg *Config) *webhook.Config {
+	return &cfg.Webhook
+}
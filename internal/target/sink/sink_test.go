@@ -0,0 +1,71 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/target/objstore"
+	"github.com/cockroachdb/replicator/internal/target/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvideSink(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	// Apply to the target database by default.
+	cfg := &Config{}
+	store, err := objstore.ProvideAcceptor(ctx, ProvideObjStoreConfig(cfg))
+	r.NoError(err)
+	r.Nil(store)
+	hook, err := webhook.ProvideAcceptor(ProvideWebhookConfig(cfg))
+	r.NoError(err)
+	r.Nil(hook)
+	found, err := ProvideSink(cfg, store, hook)
+	r.NoError(err)
+	r.Nil(found)
+
+	// Write to an object store.
+	cfg = &Config{}
+	cfg.ObjStore.StorageURL = "file://" + t.TempDir()
+	store, err = objstore.ProvideAcceptor(ctx, ProvideObjStoreConfig(cfg))
+	r.NoError(err)
+	r.NotNil(store)
+	found, err = ProvideSink(cfg, store, nil)
+	r.NoError(err)
+	r.Same(store, found)
+
+	// Send to a webhook.
+	cfg = &Config{}
+	cfg.Webhook.URLs = []string{"https://example.com/db/public"}
+	hook, err = webhook.ProvideAcceptor(ProvideWebhookConfig(cfg))
+	r.NoError(err)
+	r.NotNil(hook)
+	found, err = ProvideSink(cfg, nil, hook)
+	r.NoError(err)
+	r.Same(hook, found)
+
+	// Only one sink may be configured.
+	cfg.ObjStore.StorageURL = "file://" + t.TempDir()
+	r.ErrorContains(cfg.Preflight(), "only one of")
+	_, err = ProvideSink(cfg, nil, hook)
+	r.Error(err)
+}
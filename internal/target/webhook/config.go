@@ -0,0 +1,117 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/secure"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Defaults for flag bindings.
+const (
+	DefaultParallelism    = 4
+	DefaultPayloadSize    = 1_000
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultRetryInitial   = 100 * time.Millisecond
+	DefaultRetryMax       = 5 * time.Minute
+)
+
+// Config controls the behavior of the webhook target.
+type Config struct {
+	Parallelism    int           // Maximum number of concurrent HTTP requests.
+	PayloadSize    int           // Maximum number of lines in a single payload.
+	RequestTimeout time.Duration // Timeout for an individual HTTP request.
+	RetryInitial   time.Duration // Initial delay before retrying a request.
+	RetryMax       time.Duration // Give up retrying a request after this long.
+	TLS            secure.Config // Client certificates and CA validation.
+	Token          string        // If present, sent as a bearer token.
+	URLs           []string      // The downstream endpoints.
+
+	// The following are computed.
+	parsed []*url.URL
+}
+
+// Bind adds configuration flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.IntVar(&c.Parallelism, "webhookParallelism", DefaultParallelism,
+		"the maximum number of concurrent requests to send to webhook endpoints")
+	f.IntVar(&c.PayloadSize, "webhookPayloadSize", DefaultPayloadSize,
+		"the maximum number of mutations to send in a single webhook payload")
+	f.DurationVar(&c.RequestTimeout, "webhookRequestTimeout", DefaultRequestTimeout,
+		"the timeout for an individual webhook request")
+	f.DurationVar(&c.RetryInitial, "webhookRetryInitial", DefaultRetryInitial,
+		"the initial delay before retrying a failed webhook request")
+	f.DurationVar(&c.RetryMax, "webhookRetryMax", DefaultRetryMax,
+		"the maximum amount of time to spend retrying a failed webhook request")
+	f.StringVar(&c.TLS.CaCert, "webhookTLSCACertificate", "",
+		"the path of the base64-encoded CA file used to validate webhook endpoints")
+	f.StringVar(&c.TLS.ClientCert, "webhookTLSCertificate", "",
+		"the path of the base64-encoded client certificate to present to webhook endpoints")
+	f.StringVar(&c.TLS.ClientKey, "webhookTLSPrivateKey", "",
+		"the path of the base64-encoded client private key")
+	f.BoolVar(&c.TLS.SkipVerify, "webhookInsecureSkipVerify", false,
+		"disable validation of webhook endpoint certificates")
+	f.StringVar(&c.Token, "webhookToken", "",
+		"a bearer token to include in webhook requests")
+	f.StringSliceVar(&c.URLs, "webhookURL", nil,
+		"one or more webhook endpoints to deliver mutations to; may be repeated")
+}
+
+// Enabled returns true if any webhook URLs have been configured.
+func (c *Config) Enabled() bool {
+	return len(c.URLs) > 0
+}
+
+// Preflight ensures that the configuration has sane defaults.
+func (c *Config) Preflight() error {
+	if len(c.URLs) == 0 {
+		return errors.New("at least one webhookURL must be specified")
+	}
+	c.parsed = make([]*url.URL, len(c.URLs))
+	for idx, raw := range c.URLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse webhook URL %q", raw)
+		}
+		switch u.Scheme {
+		case "http", "https":
+		default:
+			return errors.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+		}
+		c.parsed[idx] = u
+	}
+	if c.Parallelism <= 0 {
+		c.Parallelism = DefaultParallelism
+	}
+	if c.PayloadSize <= 0 {
+		c.PayloadSize = DefaultPayloadSize
+	}
+	if c.RequestTimeout <= 0 {
+		c.RequestTimeout = DefaultRequestTimeout
+	}
+	if c.RetryInitial <= 0 {
+		c.RetryInitial = DefaultRetryInitial
+	}
+	if c.RetryMax <= 0 {
+		c.RetryMax = DefaultRetryMax
+	}
+	return c.TLS.Preflight()
+}
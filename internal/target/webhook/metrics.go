@@ -0,0 +1,54 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var endpointLabels = []string{"endpoint"}
+
+var (
+	payloadDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "webhook_target_payload_duration_seconds",
+		Help:    "the length of time it took for all endpoints to acknowledge a batch",
+		Buckets: metrics.LatencyBuckets,
+	})
+	payloadMutations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_target_mutations_total",
+		Help: "the number of mutations delivered to webhook endpoints",
+	})
+	requestDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webhook_target_request_duration_seconds",
+		Help:    "the length of time it took to receive a response from an endpoint",
+		Buckets: metrics.LatencyBuckets,
+	}, endpointLabels)
+	requestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_target_request_errors_total",
+		Help: "the number of failed webhook requests",
+	}, endpointLabels)
+	requestRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_target_request_retries_total",
+		Help: "the number of times a webhook request was retried",
+	}, endpointLabels)
+	resolvedTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_target_resolved_timestamp_seconds",
+		Help: "the last resolved timestamp acknowledged by all endpoints",
+	})
+)
@@ -0,0 +1,49 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(ProvideAcceptor)
+
+// ProvideAcceptor is called by Wire. This provider will return nil if
+// no webhook URLs have been configured.
+func ProvideAcceptor(cfg *Config) (*Acceptor, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig := cfg.TLS.AsTLSConfig(); tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &Acceptor{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.RequestTimeout,
+			Transport: transport,
+		},
+		sem: make(chan struct{}, cfg.Parallelism),
+	}, nil
+}
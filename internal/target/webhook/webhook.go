@@ -0,0 +1,266 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webhook contains a target that delivers mutations to one or
+// more HTTP endpoints, using the same payload format as a CockroachDB
+// webhook changefeed. This allows Replicator instances to be chained
+// together or to feed arbitrary services.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/source/cdc"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// Acceptor delivers mutations to the configured endpoints. Each batch
+// is split into payloads of at most [Config.PayloadSize] lines. The
+// payloads for a batch are delivered to each endpoint in order, while
+// endpoints are contacted in parallel. A call to an accept method will
+// not return until every endpoint has acknowledged every payload.
+//
+// Resolved timestamps are delivered by [Acceptor.Resolved]. A resolved
+// timestamp is sent only after all payloads that were accepted before
+// it have been acknowledged and only if it is greater than any
+// previously-delivered resolved timestamp.
+//
+// The Acceptor ignores [types.AcceptOptions.TargetQuerier], so it may
+// be used as the delegate of a Sequencer in place of a database-backed
+// acceptor.
+type Acceptor struct {
+	cfg    *Config
+	client *http.Client
+	sem    chan struct{} // Limits the number of concurrent requests.
+
+	mu struct {
+		// Payload deliveries hold a read lock, while resolved
+		// deliveries hold a write lock. This acts as a barrier to
+		// ensure that resolved timestamps are sent only after all
+		// preceding data has been acknowledged.
+		sync.RWMutex
+		resolved hlc.Time
+	}
+}
+
+var _ types.MultiAcceptor = (*Acceptor)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *Acceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, _ *types.AcceptOptions,
+) error {
+	if batch.Count() == 0 {
+		return nil
+	}
+	payload, err := cdc.NewWebhookPayload(batch)
+	if err != nil {
+		return err
+	}
+
+	// Pre-encode the payloads, since they'll be sent to each endpoint.
+	var bodies [][]byte
+	for lines := payload.Payload; len(lines) > 0; {
+		chunk := lines[:min(len(lines), a.cfg.PayloadSize)]
+		lines = lines[len(chunk):]
+		data, err := json.Marshal(&cdc.WebhookPayload{
+			Payload: chunk,
+			Length:  len(chunk),
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		bodies = append(bodies, data)
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	start := time.Now()
+	if err := a.broadcast(ctx, bodies); err != nil {
+		return err
+	}
+	payloadDurations.Observe(time.Since(start).Seconds())
+	payloadMutations.Add(float64(payload.Length))
+	log.WithFields(log.Fields{
+		"duration":  time.Since(start),
+		"mutations": payload.Length,
+		"payloads":  len(bodies),
+	}).Trace("delivered webhook payloads")
+	return nil
+}
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (a *Acceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	multi := &types.MultiBatch{}
+	if err := types.Apply(batch.Mutations(), multi.Accumulate); err != nil {
+		return err
+	}
+	return a.AcceptMultiBatch(ctx, multi, opts)
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (a *Acceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	multi := &types.MultiBatch{}
+	if err := types.Apply(batch.Mutations(), multi.Accumulate); err != nil {
+		return err
+	}
+	return a.AcceptMultiBatch(ctx, multi, opts)
+}
+
+// Resolved delivers a resolved-timestamp payload to all endpoints. This
+// method will wait for any in-flight payloads to be acknowledged. Calls
+// with a timestamp that is not greater than the last-delivered resolved
+// timestamp are ignored.
+func (a *Acceptor) Resolved(ctx context.Context, ts hlc.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if hlc.Compare(ts, a.mu.resolved) <= 0 {
+		return nil
+	}
+	payload, err := cdc.NewWebhookResolved(ts)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := a.broadcast(ctx, [][]byte{data}); err != nil {
+		return err
+	}
+	a.mu.resolved = ts
+	resolvedTimestamp.Set(float64(ts.Nanos()) / 1e9)
+	log.Tracef("delivered webhook resolved timestamp %s", ts)
+	return nil
+}
+
+// ResolvedFrom starts a goroutine that will deliver a resolved
+// timestamp whenever the minimum of the bounds advances. All mutations
+// before the minimum of the bounds are expected to have been passed to
+// the Acceptor. Delivery errors are logged and the resolved timestamp
+// will be retried on the next update.
+func (a *Acceptor) ResolvedFrom(ctx *stopper.Context, bounds *notify.Var[hlc.Range]) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChangedOrInterval(ctx,
+			hlc.RangeEmpty(), bounds, a.cfg.RequestTimeout,
+			func(ctx *stopper.Context, _, next hlc.Range) error {
+				if hlc.Compare(next.Min(), hlc.Zero()) == 0 {
+					return nil
+				}
+				if err := a.Resolved(ctx, next.Min().Before()); err != nil {
+					log.WithError(err).Warn("could not deliver webhook resolved timestamp; will retry")
+				}
+				return nil
+			})
+		return err
+	})
+}
+
+// broadcast delivers the bodies, in order, to every endpoint.
+func (a *Acceptor) broadcast(ctx context.Context, bodies [][]byte) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, u := range a.cfg.parsed {
+		eg.Go(func() error {
+			for _, body := range bodies {
+				if err := a.send(egCtx, u, body); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// send makes a single POST request, retrying transient failures.
+func (a *Acceptor) send(ctx context.Context, u *url.URL, body []byte) error {
+	labels := []string{u.Redacted()}
+	attempt := func() error {
+		select {
+		case a.sem <- struct{}{}:
+			defer func() { <-a.sem }()
+		case <-ctx.Done():
+			return backoff.Permanent(ctx.Err())
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(errors.WithStack(err))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if a.cfg.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+		}
+
+		start := time.Now()
+		resp, err := a.client.Do(req)
+		if err != nil {
+			requestErrors.WithLabelValues(labels...).Inc()
+			return errors.WithStack(err)
+		}
+		defer resp.Body.Close()
+		requestDurations.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			// Drain the body to allow connection reuse.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		requestErrors.WithLabelValues(labels...).Inc()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = errors.Errorf("webhook %s returned %s: %s",
+			u.Redacted(), resp.Status, bytes.TrimSpace(msg))
+		// Client errors won't get better by retrying, except for
+		// timeouts and rate-limiting.
+		switch {
+		case resp.StatusCode == http.StatusRequestTimeout,
+			resp.StatusCode == http.StatusTooManyRequests,
+			resp.StatusCode >= 500:
+			return err
+		default:
+			return backoff.Permanent(err)
+		}
+	}
+
+	notify := func(err error, delay time.Duration) {
+		requestRetries.WithLabelValues(labels...).Inc()
+		log.WithError(err).Warnf("webhook request failed; retrying in %s", delay)
+	}
+	expBackoff := backoff.NewExponentialBackOff()
+	expBackoff.InitialInterval = a.cfg.RetryInitial
+	expBackoff.MaxElapsedTime = a.cfg.RetryMax
+	return backoff.RetryNotify(attempt, backoff.WithContext(expBackoff, ctx), notify)
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/source/cdc"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is an http.Handler that records decoded payloads.
+type recorder struct {
+	failures int // Return a 500 error this many times.
+	status   int // If non-zero, always return this status code.
+
+	mu struct {
+		sync.Mutex
+		payloads []*cdc.WebhookPayload
+		tokens   []string
+	}
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != 0 {
+		http.Error(w, "nope", r.status)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "try again", http.StatusInternalServerError)
+		return
+	}
+	var payload cdc.WebhookPayload
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.payloads = append(r.mu.payloads, &payload)
+	r.mu.tokens = append(r.mu.tokens, req.Header.Get("Authorization"))
+}
+
+func (r *recorder) payloads() []*cdc.WebhookPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*cdc.WebhookPayload(nil), r.mu.payloads...)
+}
+
+func newTestAcceptor(t *testing.T, cfg *Config, handlers ...http.Handler) *Acceptor {
+	t.Helper()
+	for _, h := range handlers {
+		svr := httptest.NewServer(h)
+		t.Cleanup(svr.Close)
+		cfg.URLs = append(cfg.URLs, svr.URL+"/my_db/public")
+	}
+	if cfg.RetryInitial == 0 {
+		cfg.RetryInitial = time.Millisecond
+	}
+	if cfg.RetryMax == 0 {
+		cfg.RetryMax = 5 * time.Second
+	}
+	acc, err := ProvideAcceptor(cfg)
+	require.NoError(t, err)
+	return acc
+}
+
+func testBatch(t *testing.T, count int) *types.MultiBatch {
+	t.Helper()
+	table := ident.NewTable(ident.MustSchema(ident.New("my_db"), ident.Public), ident.New("tbl"))
+	batch := &types.MultiBatch{}
+	for i := range count {
+		mut := types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d}`, i)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, i)),
+			Time: hlc.New(int64(i+1), 0),
+		}
+		// Include a deletion to verify the encoding.
+		if i == count-1 {
+			mut.Data = nil
+		}
+		require.NoError(t, batch.Accumulate(table, mut))
+	}
+	return batch
+}
+
+func TestDelivery(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	first := &recorder{}
+	second := &recorder{}
+	acc := newTestAcceptor(t, &Config{
+		PayloadSize: 4,
+		Token:       "sekrit",
+	}, first, second)
+
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 10), nil))
+	r.NoError(acc.Resolved(ctx, hlc.New(10, 0)))
+	// Duplicate or older resolved timestamps are elided.
+	r.NoError(acc.Resolved(ctx, hlc.New(10, 0)))
+	r.NoError(acc.Resolved(ctx, hlc.New(5, 0)))
+
+	for _, rec := range []*recorder{first, second} {
+		payloads := rec.payloads()
+		// Three data payloads (4, 4, 2), followed by a resolved.
+		r.Len(payloads, 4)
+		r.Equal(4, payloads[0].Length)
+		r.Equal(4, payloads[1].Length)
+		r.Equal(2, payloads[2].Length)
+
+		// Verify order within the payloads.
+		var lines []cdc.WebhookPayloadLine
+		for _, p := range payloads[:3] {
+			lines = append(lines, p.Payload...)
+		}
+		for idx, line := range lines {
+			r.Equal(hlc.New(int64(idx+1), 0).String(), line.Updated)
+			r.Equal("my_db.public.tbl", line.Topic)
+		}
+		r.Equal("null", string(lines[9].After))
+
+		resolved, err := hlc.Parse(payloads[3].Resolved)
+		r.NoError(err)
+		r.Equal(hlc.New(10, 0), resolved)
+
+		for _, tkn := range rec.mu.tokens {
+			r.Equal("Bearer sekrit", tkn)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	flaky := &recorder{failures: 3}
+	acc := newTestAcceptor(t, &Config{}, flaky)
+
+	r.NoError(acc.AcceptMultiBatch(ctx, testBatch(t, 3), nil))
+	r.Len(flaky.payloads(), 1)
+}
+
+func TestPermanentFailure(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	good := &recorder{}
+	bad := &recorder{status: http.StatusForbidden}
+	acc := newTestAcceptor(t, &Config{}, good, bad)
+
+	err := acc.AcceptMultiBatch(ctx, testBatch(t, 3), nil)
+	r.ErrorContains(err, "403")
+
+	// A failed resolved delivery should not advance the timestamp.
+	r.Error(acc.Resolved(ctx, hlc.New(1, 0)))
+	r.True(hlc.Compare(acc.mu.resolved, hlc.Zero()) == 0)
+}
+
+func TestPreflight(t *testing.T) {
+	r := require.New(t)
+
+	r.ErrorContains((&Config{}).Preflight(), "webhookURL")
+	r.ErrorContains((&Config{URLs: []string{"ftp://example.com"}}).Preflight(), "scheme")
+
+	cfg := &Config{URLs: []string{"https://example.com/db/public"}}
+	r.NoError(cfg.Preflight())
+	r.Equal(DefaultParallelism, cfg.Parallelism)
+	r.Equal(DefaultPayloadSize, cfg.PayloadSize)
+}
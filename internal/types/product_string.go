@@ -14,11 +14,12 @@ func _() {
 	_ = x[ProductMySQL-3]
 	_ = x[ProductOracle-4]
 	_ = x[ProductPostgreSQL-5]
+	_ = x[ProductSQLite-6]
 }
 
-const _Product_name = "UnknownCockroachDBMariaDBMySQLOraclePostgreSQL"
+const _Product_name = "UnknownCockroachDBMariaDBMySQLOraclePostgreSQLSQLite"
 
-var _Product_index = [...]uint8{0, 7, 18, 25, 30, 36, 46}
+var _Product_index = [...]uint8{0, 7, 18, 25, 30, 36, 46, 52}
 
 func (i Product) String() string {
 	if i < 0 || i >= Product(len(_Product_index)-1) {
@@ -81,6 +81,16 @@ func TestProductExpand(t *testing.T) {
 			product: ProductMariaDB,
 			err:     "expecting exactly one schema part",
 		},
+		{
+			input:    ident.MustSchema(ident.New("main")),
+			product:  ProductSQLite,
+			expected: ident.MustSchema(ident.New("main")),
+		},
+		{
+			input:   ident.MustSchema(ident.New("foo"), ident.New("bar")),
+			product: ProductSQLite,
+			err:     "expecting exactly one schema part",
+		},
 	}
 
 	for idx, tc := range tcs {
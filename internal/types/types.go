@@ -52,7 +52,10 @@ type Deadlines = *ident.Map[time.Duration]
 // A DLQ is a dead-letter queue that allows mutations to be written
 // to the target for offline reconciliation.
 type DLQ interface {
-	Enqueue(ctx context.Context, tx TargetQuerier, mut Mutation) error
+	// Enqueue records a mutation that could not be applied to the
+	// target table. The cause may be nil if the mutation was sent to
+	// the queue by a merge function.
+	Enqueue(ctx context.Context, tx TargetQuerier, table ident.Table, mut Mutation, cause error) error
 }
 
 // DLQs provides named dead-letter queues in the target schema.
@@ -180,6 +183,12 @@ type Stager interface {
 	// should be skipped.
 	MarkApplied(ctx context.Context, db StagingQuerier, muts []Mutation) error
 
+	// ReadApplied calls fn with pages of the applied mutations whose
+	// timestamps fall within the inclusive range, in time order. Stub
+	// entries created by MarkApplied are omitted. This is used to
+	// archive mutations before they are retired.
+	ReadApplied(ctx context.Context, db StagingQuerier, from, end hlc.Time, fn func([]Mutation) error) error
+
 	// Retire will delete staged mutations whose timestamp is less than
 	// or equal to the given end time. Note that this call may take an
 	// arbitrarily long amount of time to complete and its effects may
@@ -190,6 +199,14 @@ type Stager interface {
 	// idempotent.
 	Stage(ctx context.Context, db StagingQuerier, muts []Mutation) error
 
+	// Unapply marks staged mutations whose timestamp is after the given
+	// time as not having been applied. It returns the number of
+	// mutations that were modified. Stub entries created by MarkApplied
+	// are not modified, since there is no data to apply. This is used
+	// to re-apply staged mutations after a checkpoint has been rewound.
+	// The caller is responsible for retrying the operation.
+	Unapply(ctx context.Context, db StagingQuerier, after hlc.Time) (int64, error)
+
 	// StageIfExists will stage a mutation only if there is already a
 	// mutation staged for its key. It returns a filtered copy of the
 	// mutations that were not staged. This method is used to implement
@@ -239,6 +256,7 @@ const (
 	ProductMySQL
 	ProductOracle
 	ProductPostgreSQL
+	ProductSQLite
 )
 
 // ExpandSchema validates a Schema against the expected form used by the
@@ -265,7 +283,7 @@ func (p Product) ExpandSchema(s ident.Schema) (ident.Schema, error) {
 			return ident.Schema{}, errors.Errorf("unexpected number of schema parts: %d", numParts)
 		}
 
-	case ProductMySQL, ProductMariaDB, ProductOracle:
+	case ProductMySQL, ProductMariaDB, ProductOracle, ProductSQLite:
 		if numParts != 1 {
 			return ident.Schema{}, errors.Errorf("expecting exactly one schema part, had %d", numParts)
 		}
@@ -380,6 +398,52 @@ type TargetTx interface {
 
 var _ TargetTx = (*sql.Tx)(nil)
 
+// ConnTx is a [TargetTx] that is bound to a dedicated connection. The
+// underlying driver connection is available from the Raw method, which
+// allows driver-specific features, such as the PostgreSQL COPY
+// protocol, to be used within the transaction.
+type ConnTx struct {
+	*sql.Tx
+	conn *sql.Conn
+}
+
+var _ TargetTx = (*ConnTx)(nil)
+
+// BeginConnTx starts a transaction on a connection that is reserved
+// from the pool. The connection will be returned to the pool when the
+// transaction is committed or rolled back.
+func (p *TargetPool) BeginConnTx(ctx context.Context, opts *sql.TxOptions) (*ConnTx, error) {
+	conn, err := p.Conn(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.WithStack(err)
+	}
+	return &ConnTx{Tx: tx, conn: conn}, nil
+}
+
+// Commit commits the transaction and releases the connection.
+func (t *ConnTx) Commit() error {
+	defer func() { _ = t.conn.Close() }()
+	return t.Tx.Commit()
+}
+
+// Raw invokes the callback with the underlying driver connection. The
+// callback must not use the ConnTx.
+func (t *ConnTx) Raw(fn func(driverConn any) error) error {
+	return t.conn.Raw(fn)
+}
+
+// Rollback aborts the transaction and releases the connection. It is
+// safe to call Rollback after Commit.
+func (t *ConnTx) Rollback() error {
+	defer func() { _ = t.conn.Close() }()
+	return t.Tx.Rollback()
+}
+
 // Watcher allows table metadata to be observed.
 //
 // The methods in this type return column data such that primary key
@@ -17,6 +17,8 @@
 package applycfg
 
 import (
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/util/cmap"
@@ -33,6 +35,16 @@ import (
 // tables.
 const DefaultRowLimit = 100
 
+// DefaultCopyThreshold is the minimum number of rows in a batch before
+// a bulk-copy strategy will be used, for targets that support one. A
+// bulk copy has a higher fixed cost than a single statement, but it
+// is not subject to bind-variable limits. The bulk copy requires a
+// target transaction with a dedicated connection, so it is used only
+// in the consistent modes and by DLQ replay. The immediate and
+// best-effort modes, which apply without a target transaction, always
+// use statements.
+const DefaultCopyThreshold = 1_000
+
 // SubstitutionToken contains the string that we'll use to substitute in
 // the actual parameter index into the generated SQL.
 const SubstitutionToken = "$0"
@@ -52,23 +64,37 @@ type (
 // A Config contains per-target-table configuration.
 type Config struct {
 	// NB: Update TestCopyEquals if adding new fields.
-	CASColumns  TargetColumns             // The columns for compare-and-set operations.
-	Deadlines   *ident.Map[time.Duration] // Deadline-based operation.
-	Exprs       *ident.Map[string]        // Synthetic or replacement SQL expressions.
-	Extras      TargetColumn              // JSONB column to store unmapped values in.
-	Ignore      *ident.Map[bool]          // Source column names to ignore.
-	Merger      merge.Merger              // Conflict resolution.
-	RowLimit    int                       // Adjust if hitting limits on bind variables.
-	SourceNames *ident.Map[SourceColumn]  // Look for alternate name in the incoming data.
+	CASColumns    TargetColumns             // The columns for compare-and-set operations.
+	Casts         *ident.Map[string]        // Convert incoming values to a native type.
+	Constants     *ident.Map[any]           // Values that replace any incoming value.
+	CopyThreshold int                       // Batch size to use bulk copies; negative to disable.
+	Deadlines     *ident.Map[time.Duration] // Deadline-based operation.
+	Defaults      *ident.Map[any]           // Values to use if a column is absent or null.
+	Exprs         *ident.Map[string]        // Synthetic or replacement SQL expressions.
+	Extras        TargetColumn              // JSONB column to store unmapped values in.
+	Filters       []string                  // Templates which must evaluate to true to upsert a row.
+	History       ident.Ident               // Append row versions to this table in the target schema.
+	HistoryCols   TargetColumns             // Columns to record in the history table; all if empty.
+	Ignore        *ident.Map[bool]          // Source column names to ignore.
+	Merger        merge.Merger              // Conflict resolution.
+	RowLimit      int                       // Adjust if hitting limits on bind variables.
+	SoftDelete    TargetColumn              // Mark deleted rows in this column instead of deleting them.
+	SourceNames   *ident.Map[SourceColumn]  // Look for alternate name in the incoming data.
+	Templates     *ident.Map[string]        // Text templates that compute a column's value.
+	UpdateChanged bool                      // Update only changed columns if a before value is present.
 }
 
 // NewConfig constructs a Config with all map fields populated.
 func NewConfig() *Config {
 	return &Config{
+		Casts:       &ident.Map[string]{},
+		Constants:   &ident.Map[any]{},
 		Deadlines:   &ident.Map[time.Duration]{},
+		Defaults:    &ident.Map[any]{},
 		Exprs:       &ident.Map[string]{},
 		Ignore:      &ident.Map[bool]{},
 		SourceNames: &ident.Map[SourceColumn]{},
+		Templates:   &ident.Map[string]{},
 	}
 }
 
@@ -76,13 +102,23 @@ func NewConfig() *Config {
 func (c *Config) Copy() *Config {
 	ret := NewConfig()
 	ret.CASColumns = append(ret.CASColumns, c.CASColumns...)
+	c.Casts.CopyInto(ret.Casts)
+	c.Constants.CopyInto(ret.Constants)
+	ret.CopyThreshold = c.CopyThreshold
 	c.Deadlines.CopyInto(ret.Deadlines)
+	c.Defaults.CopyInto(ret.Defaults)
 	c.Exprs.CopyInto(ret.Exprs)
 	ret.Extras = c.Extras
+	ret.Filters = append(ret.Filters, c.Filters...)
+	ret.History = c.History
+	ret.HistoryCols = append(ret.HistoryCols, c.HistoryCols...)
 	c.Ignore.CopyInto(ret.Ignore)
 	ret.Merger = c.Merger
 	ret.RowLimit = c.RowLimit
+	ret.SoftDelete = c.SoftDelete
 	c.SourceNames.CopyInto(ret.SourceNames)
+	c.Templates.CopyInto(ret.Templates)
+	ret.UpdateChanged = c.UpdateChanged
 
 	return ret
 }
@@ -98,41 +134,78 @@ func (c *Config) Equal(o *Config) bool {
 		(c != nil) && (o != nil) &&
 			// Not all implementations of Acceptor are comparable.
 			c.CASColumns.Equal(o.CASColumns) &&
+			c.Casts.Equal(o.Casts, cmap.Comparator[string]()) &&
+			c.Constants.Equal(o.Constants, deepEqual) &&
+			c.CopyThreshold == o.CopyThreshold &&
 			c.Deadlines.Equal(o.Deadlines, cmap.Comparator[time.Duration]()) &&
+			c.Defaults.Equal(o.Defaults, deepEqual) &&
 			c.Exprs.Equal(o.Exprs, cmap.Comparator[string]()) &&
 			ident.Equal(c.Extras, o.Extras) &&
+			slices.Equal(c.Filters, o.Filters) &&
+			ident.Equal(c.History, o.History) &&
+			c.HistoryCols.Equal(o.HistoryCols) &&
 			c.Ignore.Equal(o.Ignore, cmap.Comparator[bool]()) &&
 			// Not all implementations of Merger are comparable: merge.Func or similar.
 			c.RowLimit == o.RowLimit &&
-			c.SourceNames.Equal(o.SourceNames, ident.Comparator[ident.Ident]())
+			ident.Equal(c.SoftDelete, o.SoftDelete) &&
+			c.SourceNames.Equal(o.SourceNames, ident.Comparator[ident.Ident]()) &&
+			c.Templates.Equal(o.Templates, cmap.Comparator[string]()) &&
+			c.UpdateChanged == o.UpdateChanged
 }
 
 // IsZero returns true if the Config represents the absence of a
 // configuration.
 func (c *Config) IsZero() bool {
 	return len(c.CASColumns) == 0 &&
+		c.Casts.Len() == 0 &&
+		c.Constants.Len() == 0 &&
+		c.CopyThreshold == 0 &&
 		c.Deadlines.Len() == 0 &&
+		c.Defaults.Len() == 0 &&
 		c.Exprs.Len() == 0 &&
 		c.Extras.Empty() &&
+		len(c.Filters) == 0 &&
+		c.History.Empty() &&
+		len(c.HistoryCols) == 0 &&
 		c.Ignore.Len() == 0 &&
 		c.Merger == nil &&
 		c.RowLimit == 0 &&
-		c.SourceNames.Len() == 0
+		c.SoftDelete.Empty() &&
+		c.SourceNames.Len() == 0 &&
+		c.Templates.Len() == 0 &&
+		!c.UpdateChanged
 }
 
 // Patch applies any non-empty fields from another Config to the
 // receiver and returns the receiver.
 func (c *Config) Patch(other *Config) *Config {
 	c.CASColumns = append(c.CASColumns, other.CASColumns...)
+	if other.Casts != nil {
+		other.Casts.CopyInto(c.Casts)
+	}
+	if other.Constants != nil {
+		other.Constants.CopyInto(c.Constants)
+	}
+	if other.CopyThreshold != 0 {
+		c.CopyThreshold = other.CopyThreshold
+	}
 	if other.Deadlines != nil {
 		other.Deadlines.CopyInto(c.Deadlines)
 	}
+	if other.Defaults != nil {
+		other.Defaults.CopyInto(c.Defaults)
+	}
 	if other.Exprs != nil {
 		other.Exprs.CopyInto(c.Exprs)
 	}
 	if !other.Extras.Empty() {
 		c.Extras = other.Extras
 	}
+	c.Filters = append(c.Filters, other.Filters...)
+	if !other.History.Empty() {
+		c.History = other.History
+	}
+	c.HistoryCols = append(c.HistoryCols, other.HistoryCols...)
 	if other.Ignore != nil {
 		other.Ignore.CopyInto(c.Ignore)
 	}
@@ -142,8 +215,23 @@ func (c *Config) Patch(other *Config) *Config {
 	if other.RowLimit != 0 {
 		c.RowLimit = other.RowLimit
 	}
+	if !other.SoftDelete.Empty() {
+		c.SoftDelete = other.SoftDelete
+	}
 	if other.SourceNames != nil {
 		other.SourceNames.CopyInto(c.SourceNames)
 	}
+	if other.Templates != nil {
+		other.Templates.CopyInto(c.Templates)
+	}
+	if other.UpdateChanged {
+		c.UpdateChanged = true
+	}
 	return c
 }
+
+// deepEqual compares loosely-typed values, such as those decoded from
+// a configuration file, which may not be comparable.
+func deepEqual(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
@@ -30,16 +30,26 @@ func TestCopyEquals(t *testing.T) {
 	a := assert.New(t)
 
 	cfg := &Config{
-		CASColumns: TargetColumns{ident.New("cas")},
-		Deadlines:  ident.MapOf[time.Duration](ident.New("dl"), time.Hour),
-		Exprs:      ident.MapOf[string]("expr", "foo"),
-		Extras:     ident.New("extras"),
-		RowLimit:   42,
-		Ignore:     ident.MapOf[bool]("ign", true),
+		CASColumns:    TargetColumns{ident.New("cas")},
+		Casts:         ident.MapOf[string]("cast", "int"),
+		Constants:     ident.MapOf[any]("const", map[string]any{"foo": "bar"}),
+		CopyThreshold: 5_000,
+		Deadlines:     ident.MapOf[time.Duration](ident.New("dl"), time.Hour),
+		Defaults:      ident.MapOf[any]("default", 42),
+		Exprs:         ident.MapOf[string]("expr", "foo"),
+		Extras:        ident.New("extras"),
+		Filters:       []string{`{{ ne .status "test" }}`},
+		History:       ident.New("history"),
+		HistoryCols:   TargetColumns{ident.New("tracked")},
+		RowLimit:      42,
+		Ignore:        ident.MapOf[bool]("ign", true),
 		Merger: merge.Func(func(context.Context, *merge.Conflict) (*merge.Resolution, error) {
 			panic("unused")
 		}),
-		SourceNames: ident.MapOf[SourceColumn](ident.New("new"), ident.New("old")),
+		SoftDelete:    ident.New("deleted_at"),
+		SourceNames:   ident.MapOf[SourceColumn](ident.New("new"), ident.New("old")),
+		Templates:     ident.MapOf[string]("tmpl", "{{ .a }}-{{ .b }}"),
+		UpdateChanged: true,
 	}
 
 	a.True(cfg.Equal(cfg))
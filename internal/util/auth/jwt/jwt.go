@@ -177,12 +177,12 @@ const (
 	ensureKeysTemplate = `
 CREATE TABLE IF NOT EXISTS %s (
 	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
-	public_key STRING NOT NULL,
+	public_key TEXT NOT NULL,
 	active BOOL NOT NULL DEFAULT true
 )`
 	ensureRevokedTemplate = `
 CREATE TABLE IF NOT EXISTS %s (
-	id STRING NOT NULL PRIMARY KEY
+	id TEXT NOT NULL PRIMARY KEY
 )`
 	selectKeysTemplate    = `SELECT id, public_key FROM %s WHERE active`
 	selectRevokedTemplate = `SELECT id FROM %s`
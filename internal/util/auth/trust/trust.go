@@ -35,6 +35,12 @@ func New() types.Authenticator {
 
 var _ types.Authenticator = (*authenticator)(nil)
 
+// Is returns true if the Authenticator was constructed by [New].
+func Is(auth types.Authenticator) bool {
+	_, ok := auth.(*authenticator)
+	return ok
+}
+
 // Check always returns true.
 func (a *authenticator) Check(context.Context, ident.Schema, string) (bool, error) {
 	return true, nil
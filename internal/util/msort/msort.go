@@ -32,7 +32,8 @@ import (
 // FoldByKey implements a folding approach to removing mutations with
 // duplicate keys from the input slice. If two mutations share the same
 // Key, their data blocks will be merged on a key-by-key basis.
-// Mutations will be processed in time order.
+// Mutations will be processed in time order. A folded mutation retains
+// the Before value of the earliest mutation that was folded into it.
 //
 // This function will not modify the input slice, but may return it if
 // all mutations were applied to unique keys.
@@ -54,6 +55,7 @@ func FoldByKey(x []types.Mutation) ([]types.Mutation, error) {
 	}
 	exemplars := make(map[string]*types.Mutation, len(x))
 	keyData := make(map[string]map[string]json.RawMessage, len(x))
+	befores := make(map[string]json.RawMessage)
 
 	for idx, mut := range x {
 		if len(mut.Key) == 0 {
@@ -92,6 +94,7 @@ func FoldByKey(x []types.Mutation) ([]types.Mutation, error) {
 		if !exemplarUnpacked {
 			acc = make(map[string]json.RawMessage)
 			keyData[key] = acc
+			befores[key] = exemplar.Before
 
 			dec := json.NewDecoder(bytes.NewReader(exemplar.Data))
 			dec.UseNumber()
@@ -124,6 +127,7 @@ func FoldByKey(x []types.Mutation) ([]types.Mutation, error) {
 		}
 		var err error
 		mut := *exemplar // Don't modify elements in original slice.
+		mut.Before = befores[key]
 		mut.Data, err = json.Marshal(merged)
 		if err != nil {
 			return nil, errors.WithStack(err)
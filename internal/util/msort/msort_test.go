@@ -138,6 +138,31 @@ func TestFoldByKey(t *testing.T) {
 				},
 			},
 		},
+		// The earliest before value is retained.
+		{
+			data: []types.Mutation{
+				{
+					Before: json.RawMessage(`{"k":1,"a":0,"b":0}`),
+					Data:   json.RawMessage(`{"k":1,"a":1,"b":0}`),
+					Key:    json.RawMessage(`[1]`),
+					Time:   hlc.New(1, 0),
+				},
+				{
+					Before: json.RawMessage(`{"k":1,"a":1,"b":0}`),
+					Data:   json.RawMessage(`{"k":1,"a":1,"b":2}`),
+					Key:    json.RawMessage(`[1]`),
+					Time:   hlc.New(2, 0),
+				},
+			},
+			expected: []types.Mutation{
+				{
+					Before: json.RawMessage(`{"k":1,"a":0,"b":0}`),
+					Data:   json.RawMessage(`{"a":1,"b":2,"k":1}`),
+					Key:    json.RawMessage(`[1]`),
+					Time:   hlc.New(2, 0),
+				},
+			},
+		},
 	}
 
 	for idx, tc := range tcs {
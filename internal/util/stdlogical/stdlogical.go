@@ -52,11 +52,22 @@ func init() {
 // MetricsAddrFlag is a global flag that will start an HTTP server.
 const MetricsAddrFlag = "metricsAddr"
 
+// AdminPrefix is the path under which the handler supplied by
+// [HasAdminHandler] is served.
+const AdminPrefix = "/_/admin"
+
 // Config is our standard protocol for configuration objects.
 type Config interface {
 	Bind(set *pflag.FlagSet)
 }
 
+// HasAdminHandler allows the object to supply an [http.Handler] for
+// administrative endpoints. The handler is responsible for checking
+// that the request is authorized.
+type HasAdminHandler interface {
+	GetAdminHandler(auth types.Authenticator) http.Handler
+}
+
 // HasAuthenticator allows the object to supply a [types.Authenticator].
 type HasAuthenticator interface {
 	GetAuthenticator() types.Authenticator
@@ -130,14 +141,20 @@ func New(t *Template) *cobra.Command {
 				diags = diag.New(stopper.From(cmd.Context()))
 			}
 
+			var admin http.Handler
+			if x, ok := started.(HasAdminHandler); ok {
+				admin = AdminHandler(x, auth)
+			}
+
 			// Start metrics on a separate port or bind to an existing mux.
 			if metricsAddr != "" {
-				cancelServer, err := MetricsServer(auth, metricsAddr, diags)
+				cancelServer, err := MetricsServer(auth, metricsAddr, diags, admin)
 				if err != nil {
 					return err
 				}
 				defer cancelServer()
 			} else if x, ok := started.(HasServeMux); ok {
+				AddAdminHandler(x.GetServeMux(), admin)
 				AddHandlers(auth, x.GetServeMux(), diags)
 			}
 
@@ -158,6 +175,27 @@ func New(t *Template) *cobra.Command {
 	return cmd
 }
 
+// AdminHandler returns the administrative handler, unless the
+// authenticator allows all requests. The administrative endpoints can
+// pause replication or change its mode, so they are not served if
+// authentication has not been configured.
+func AdminHandler(x HasAdminHandler, auth types.Authenticator) http.Handler {
+	if trust.Is(auth) {
+		log.Warn("the admin API is disabled because requests are not authenticated")
+		return nil
+	}
+	return x.GetAdminHandler(auth)
+}
+
+// AddAdminHandler binds the handler to [AdminPrefix]. This function is
+// a no-op if the handler is nil.
+func AddAdminHandler(mux *http.ServeMux, admin http.Handler) {
+	if admin == nil {
+		return
+	}
+	mux.Handle(AdminPrefix+"/", http.StripPrefix(AdminPrefix, admin))
+}
+
 // AddHandlers populates the ServeMux with diagnostic endpoints.
 func AddHandlers(auth types.Authenticator, mux *http.ServeMux, diags *diag.Diagnostics) {
 	// The pprof handlers attach themselves to the system-default mux.
@@ -178,10 +216,12 @@ func AddHandlers(auth types.Authenticator, mux *http.ServeMux, diags *diag.Diagn
 }
 
 // MetricsServer starts a trivial HTTP server which runs until canceled.
+// The admin handler may be nil.
 func MetricsServer(
-	auth types.Authenticator, bindAddr string, diags *diag.Diagnostics,
+	auth types.Authenticator, bindAddr string, diags *diag.Diagnostics, admin http.Handler,
 ) (func(), error) {
 	mux := &http.ServeMux{}
+	AddAdminHandler(mux, admin)
 	AddHandlers(auth, mux, diags)
 	mux.HandleFunc("/_/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -24,11 +24,28 @@ import (
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/auth/reject"
+	"github.com/cockroachdb/replicator/internal/util/auth/trust"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
 
+type adminFunc func(auth types.Authenticator) http.Handler
+
+func (f adminFunc) GetAdminHandler(auth types.Authenticator) http.Handler { return f(auth) }
+
+// TestAdminHandler verifies that the admin handler is not served if
+// requests are not authenticated.
+func TestAdminHandler(t *testing.T) {
+	r := require.New(t)
+	x := adminFunc(func(types.Authenticator) http.Handler { return http.NotFoundHandler() })
+
+	r.Nil(AdminHandler(x, trust.New()))
+	r.NotNil(AdminHandler(x, reject.New()))
+}
+
 func TestSmoke(t *testing.T) {
 	r := require.New(t)
 
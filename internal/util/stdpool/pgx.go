@@ -88,6 +88,7 @@ func OpenPgxAsStaging(
 ) (*types.StagingPool, error) {
 	db, err := openPgx(ctx, connectString, options,
 		func(ctx *stopper.Context, cfg *pgxpool.Config) (*pgxpool.Pool, error) {
+			cfg.AfterConnect = serializableStaging(cfg.AfterConnect)
 			impl, err := pgxpool.NewWithConfig(ctx, cfg)
 			if err != nil {
 				return nil, errors.WithStack(err)
@@ -103,7 +104,6 @@ func OpenPgxAsStaging(
 		Pool: db,
 		PoolInfo: types.PoolInfo{
 			ConnectionString: connectString,
-			Product:          types.ProductCockroachDB,
 			ErrCode:          pgErrCode,
 			IsDeferrable:     pgErrDeferrable,
 			ShouldRetry:      pgErrRetryable,
@@ -116,8 +116,13 @@ func OpenPgxAsStaging(
 		return nil, errors.Wrap(err, "could not determine cluster version")
 	}
 
-	if !strings.HasPrefix(ret.Version, "CockroachDB") {
-		return nil, errors.Errorf("only CockroachDB is supported as a staging server; saw %q", ret.Version)
+	switch {
+	case strings.HasPrefix(ret.Version, "CockroachDB"):
+		ret.Product = types.ProductCockroachDB
+	case strings.HasPrefix(ret.Version, "PostgreSQL"):
+		ret.Product = types.ProductPostgreSQL
+	default:
+		return nil, errors.Errorf("only CockroachDB or PostgreSQL are supported as a staging server; saw %q", ret.Version)
 	}
 	if err := setTableHint(ret.Info()); err != nil {
 		return nil, err
@@ -129,6 +134,26 @@ func OpenPgxAsStaging(
 	return ret, err
 }
 
+// serializableStaging wraps an AfterConnect callback to ensure that
+// PostgreSQL staging connections use SERIALIZABLE isolation. The
+// staging queries rely upon CockroachDB's default isolation level.
+func serializableStaging(
+	next func(context.Context, *pgx.Conn) error,
+) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		if next != nil {
+			if err := next(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if conn.PgConn().ParameterStatus("crdb_version") != "" {
+			return nil
+		}
+		_, err := conn.Exec(ctx, "SET default_transaction_isolation = 'serializable'")
+		return errors.WithStack(err)
+	}
+}
+
 // OpenPgxAsTarget uses pgx to open a database connection, returning it as a
 // stdlib pool.
 func OpenPgxAsTarget(
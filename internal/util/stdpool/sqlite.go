@@ -0,0 +1,137 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stdpool
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteMemory is the special filename for an in-memory database.
+const sqliteMemory = ":memory:"
+
+// See also:
+// https://www.sqlite.org/rescode.html
+func sqliteErrCode(err error) (string, bool) {
+	if liteErr := (*sqlite.Error)(nil); errors.As(err, &liteErr) {
+		return strconv.Itoa(liteErr.Code()), true
+	}
+	return "", false
+}
+
+func sqliteErrDeferrable(err error) bool {
+	liteErr := (*sqlite.Error)(nil)
+	if !errors.As(err, &liteErr) {
+		return false
+	}
+	// FOREIGN KEY constraint failed
+	return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+func sqliteErrRetryable(err error) bool {
+	liteErr := (*sqlite.Error)(nil)
+	if !errors.As(err, &liteErr) {
+		return false
+	}
+	// Mask off the extended result code.
+	switch liteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenSQLiteAsTarget opens a SQLite database file and returns it as a
+// [types.TargetPool]. The database is identified by URLs such as
+// sqlite:///absolute/path.db, sqlite:relative/path.db, or
+// sqlite::memory:. Any query parameters are passed to the driver.
+//
+// Foreign key enforcement is enabled and transactions acquire the
+// database write-lock when they begin, to avoid lock-upgrade failures
+// between concurrent writers. An in-memory database exists only within
+// a single connection, so the pool will be limited to one connection.
+func OpenSQLiteAsTarget(
+	ctx *stopper.Context, connectString string, u *url.URL, options ...Option,
+) (*types.TargetPool, error) {
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.Path
+	}
+	if path == "" {
+		return nil, errors.New("a SQLite database path must be specified")
+	}
+
+	params := u.Query()
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	if !params.Has("_txlock") {
+		params.Set("_txlock", "immediate")
+	}
+	dsn := "file:" + path + "?" + params.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret := &types.TargetPool{
+		DB: db,
+		PoolInfo: types.PoolInfo{
+			ConnectionString: connectString,
+			Product:          types.ProductSQLite,
+
+			ErrCode:      sqliteErrCode,
+			IsDeferrable: sqliteErrDeferrable,
+			ShouldRetry:  sqliteErrRetryable,
+		},
+	}
+	ctx.Defer(func() { _ = ret.Close() })
+
+	if err := ret.Ping(); err != nil {
+		return nil, errors.Wrap(err, "could not ping the database")
+	}
+	if err := ret.QueryRow("SELECT sqlite_version()").Scan(&ret.Version); err != nil {
+		return nil, errors.Wrap(err, "could not query version")
+	}
+	ret.Version = "SQLite " + ret.Version
+	log.Infof("Version %s.", ret.Version)
+	if err := setTableHint(ret.Info()); err != nil {
+		return nil, err
+	}
+	if err := attachOptions(ctx, ret.DB, options); err != nil {
+		return nil, err
+	}
+	if err := attachOptions(ctx, &ret.PoolInfo, options); err != nil {
+		return nil, err
+	}
+	if path == sqliteMemory {
+		ret.SetMaxOpenConns(1)
+		// Closing the last connection would discard the database.
+		ret.SetConnMaxIdleTime(0)
+		ret.SetConnMaxLifetime(0)
+		ret.SetMaxIdleConns(1)
+	}
+	return ret, nil
+}
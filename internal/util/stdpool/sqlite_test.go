@@ -0,0 +1,104 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stdpool
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	path := filepath.Join(t.TempDir(), "target.db")
+	pool, err := OpenTarget(ctx, "sqlite://"+path)
+	r.NoError(err)
+	r.Equal(types.ProductSQLite, pool.Product)
+	r.True(strings.HasPrefix(pool.Version, "SQLite 3."), pool.Version)
+
+	// Verify that foreign keys are enforced and that the error is
+	// classified as deferrable.
+	_, err = pool.ExecContext(ctx, `CREATE TABLE parent (pk INTEGER PRIMARY KEY)`)
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx,
+		`CREATE TABLE child (pk INTEGER PRIMARY KEY, parent INTEGER REFERENCES parent(pk))`)
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, `INSERT INTO child VALUES (1, 1)`)
+	r.Error(err)
+	r.True(pool.IsDeferrable(err))
+	r.False(pool.ShouldRetry(err))
+	code, ok := pool.ErrCode(err)
+	r.True(ok)
+	r.Equal("787", code)
+}
+
+func TestOpenSQLiteMemory(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	pool, err := OpenTarget(ctx, "sqlite::memory:")
+	r.NoError(err)
+	r.Equal(1, pool.Stats().MaxOpenConnections)
+
+	// The table must be visible to subsequent uses of the pool.
+	_, err = pool.ExecContext(ctx, `CREATE TABLE tbl (pk INTEGER PRIMARY KEY)`)
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, `INSERT INTO tbl VALUES (1)`)
+	r.NoError(err)
+
+	_, err = OpenTarget(ctx, "sqlite:")
+	r.ErrorContains(err, "path")
+}
+
+// TestConnTx verifies that the driver connection is available while a
+// transaction is open on a dedicated connection.
+func TestConnTx(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	pool, err := OpenTarget(ctx, "sqlite://"+filepath.Join(t.TempDir(), "target.db"))
+	r.NoError(err)
+	_, err = pool.ExecContext(ctx, `CREATE TABLE tbl (pk INTEGER PRIMARY KEY)`)
+	r.NoError(err)
+
+	tx, err := pool.BeginConnTx(ctx, nil)
+	r.NoError(err)
+	_, err = tx.ExecContext(ctx, `INSERT INTO tbl VALUES (1)`)
+	r.NoError(err)
+	r.NoError(tx.Raw(func(driverConn any) error {
+		r.NotNil(driverConn)
+		return nil
+	}))
+	r.NoError(tx.Commit())
+	// Rollback after commit is a no-op that reports the tx as done.
+	r.ErrorIs(tx.Rollback(), sql.ErrTxDone)
+
+	var count int
+	r.NoError(pool.QueryRowContext(ctx, `SELECT count(*) FROM tbl`).Scan(&count))
+	r.Equal(1, count)
+}
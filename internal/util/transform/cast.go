@@ -0,0 +1,220 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Supported values for [applycfg.Config.Casts].
+const (
+	CastBool      = "bool"
+	CastFloat     = "float"
+	CastInt       = "int"
+	CastJSON      = "json"
+	CastString    = "string"
+	CastTimestamp = "timestamp"
+)
+
+// A caster converts a reified value to some native type. Casters are
+// never called with nil values.
+type caster func(value any) (any, error)
+
+var casters = map[string]caster{
+	CastBool:      castBool,
+	CastFloat:     castFloat,
+	CastInt:       castInt,
+	CastJSON:      castJSON,
+	CastString:    castString,
+	CastTimestamp: castTimestamp,
+}
+
+// timestampLayouts are attempted, in order, when casting a string to
+// a timestamp.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.DateOnly,
+}
+
+// castCompatible returns true if the output of the named cast may be
+// stored in a column of the given type. This is a coarse check that is
+// intended to catch obvious configuration errors at startup; the
+// target database remains the final arbiter.
+func castCompatible(kind string, colType string) bool {
+	typ := strings.ToLower(colType)
+	temporal := strings.Contains(typ, "time") || strings.Contains(typ, "date")
+	switch kind {
+	case CastBool:
+		return !temporal
+	case CastFloat, CastInt:
+		return !temporal && !strings.Contains(typ, "bool")
+	case CastTimestamp:
+		return temporal ||
+			strings.Contains(typ, "char") ||
+			strings.Contains(typ, "string") ||
+			strings.Contains(typ, "text")
+	case CastJSON, CastString:
+		return true
+	default:
+		return false
+	}
+}
+
+func castBool(value any) (any, error) {
+	switch t := value.(type) {
+	case bool:
+		return t, nil
+	case string:
+		ret, err := strconv.ParseBool(strings.TrimSpace(t))
+		return ret, errors.WithStack(err)
+	}
+	f, err := castFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	return f.(float64) != 0, nil
+}
+
+func castFloat(value any) (any, error) {
+	switch t := value.(type) {
+	case bool:
+		if t {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case json.Number:
+		ret, err := t.Float64()
+		return ret, errors.WithStack(err)
+	case string:
+		ret, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return ret, errors.WithStack(err)
+	default:
+		return nil, errors.Errorf("cannot cast %T to %s", value, CastFloat)
+	}
+}
+
+func castInt(value any) (any, error) {
+	switch t := value.(type) {
+	case int:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case json.Number:
+		return parseInt(string(t))
+	case string:
+		return parseInt(strings.TrimSpace(t))
+	}
+	// Accept floating-point values only if they are integral.
+	f, err := castFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	if math.Trunc(f.(float64)) != f.(float64) {
+		return nil, errors.Errorf("cannot cast non-integral value %v to %s", value, CastInt)
+	}
+	return int64(f.(float64)), nil
+}
+
+// parseInt falls back to parsing the string as a float in order to
+// accept values such as 1.0 or 1e3.
+func parseInt(s string) (any, error) {
+	if ret, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ret, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return castInt(f)
+}
+
+// castJSON decodes strings which contain a JSON document. All other
+// values are returned as-is.
+func castJSON(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	var ret any
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&ret); err != nil {
+		return nil, errors.Wrapf(err, "cannot cast %q to %s", s, CastJSON)
+	}
+	return ret, nil
+}
+
+func castString(value any) (any, error) {
+	switch t := value.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool, float64, int, int64:
+		return fmt.Sprint(t), nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(value); err != nil {
+			return nil, errors.Wrapf(err, "cannot cast %T to %s", value, CastString)
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+}
+
+// castTimestamp accepts strings in common layouts or numeric values,
+// which are interpreted as seconds since the Unix epoch.
+func castTimestamp(value any) (any, error) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		t = strings.TrimSpace(t)
+		for _, layout := range timestampLayouts {
+			if ret, err := time.Parse(layout, t); err == nil {
+				return ret, nil
+			}
+		}
+		return nil, errors.Errorf("cannot cast %q to %s", t, CastTimestamp)
+	}
+	f, err := castFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	sec, frac := math.Modf(f.(float64))
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC(), nil
+}
@@ -0,0 +1,292 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transform compiles the declarative column transforms in an
+// [applycfg.Config] into native functions that are applied to rows
+// before they are written to the target.
+package transform
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/pkg/errors"
+)
+
+// funcs are made available to templates and filters, in addition to
+// the text/template builtins.
+var funcs = template.FuncMap{
+	"coalesce": coalesce,
+	"lower":    stringFn(strings.ToLower),
+	"md5":      hashFn(md5Sum),
+	"replace":  replace,
+	"sha256":   hashFn(sha256Sum),
+	"trim":     stringFn(strings.TrimSpace),
+	"upper":    stringFn(strings.ToUpper),
+}
+
+// Transforms contains the compiled form of the declarative transforms
+// for a single table. The transforms are applied in a fixed order:
+// constants, defaults, templates, casts, and then filters. Templates
+// observe the row after constants and defaults have been applied and
+// filters observe the row after casts have been applied.
+type Transforms struct {
+	casts     ident.Map[caster]
+	constants ident.Map[any]
+	defaults  ident.Map[any]
+	filters   []*template.Template
+	templates ident.Map[*template.Template]
+}
+
+// Compile validates the declarative transforms in the configuration
+// against the table's schema. This function will return nil if the
+// configuration does not define any transforms.
+func Compile(cfg *applycfg.Config, colData []types.ColData) (*Transforms, error) {
+	if cfg.Casts.Len()+cfg.Constants.Len()+cfg.Defaults.Len()+
+		len(cfg.Filters)+cfg.Templates.Len() == 0 {
+		return nil, nil
+	}
+
+	var cols ident.Map[*types.ColData]
+	for idx := range colData {
+		cols.Put(colData[idx].Name, &colData[idx])
+	}
+	lookup := func(kind string, name ident.Ident) error {
+		col, ok := cols.Get(name)
+		if !ok {
+			return errors.Errorf("%s column %s not found", kind, name)
+		}
+		// Deletions are applied using the key sent by the source, so
+		// changing the value of a key column would prevent a deletion
+		// from finding the row that was upserted.
+		if col.Primary {
+			return errors.Errorf("%s column %s is part of the primary key", kind, name)
+		}
+		return nil
+	}
+
+	ret := &Transforms{}
+	for name, kind := range cfg.Casts.All() {
+		if err := lookup("cast", name); err != nil {
+			return nil, err
+		}
+		fn, ok := casters[kind]
+		if !ok {
+			return nil, errors.Errorf("unknown cast %q for column %s", kind, name)
+		}
+		col, _ := cols.Get(name)
+		if !castCompatible(kind, col.Type) {
+			return nil, errors.Errorf("cannot cast column %s of type %s to %s",
+				name, col.Type, kind)
+		}
+		ret.casts.Put(name, fn)
+	}
+	for name, value := range cfg.Constants.All() {
+		if err := lookup("constant", name); err != nil {
+			return nil, err
+		}
+		ret.constants.Put(name, value)
+	}
+	for name, value := range cfg.Defaults.All() {
+		if err := lookup("default", name); err != nil {
+			return nil, err
+		}
+		ret.defaults.Put(name, value)
+	}
+	for idx, text := range cfg.Filters {
+		tmpl, err := parse("filter"+strconv.Itoa(idx), text)
+		if err != nil {
+			return nil, err
+		}
+		ret.filters = append(ret.filters, tmpl)
+	}
+	for name, text := range cfg.Templates.All() {
+		if err := lookup("template", name); err != nil {
+			return nil, err
+		}
+		tmpl, err := parse(name.Raw(), text)
+		if err != nil {
+			return nil, err
+		}
+		ret.templates.Put(name, tmpl)
+	}
+	return ret, nil
+}
+
+// Apply transforms the row in place. It returns false if the row
+// should be discarded because it did not pass all filters.
+func (t *Transforms) Apply(bag *merge.Bag) (keep bool, _ error) {
+	for name, value := range t.constants.All() {
+		set(bag, name, value)
+	}
+	for name, value := range t.defaults.All() {
+		if existing, ok := bag.Mapped.Get(name); !ok || !existing.Valid || existing.Value == nil {
+			set(bag, name, value)
+		}
+	}
+	if t.templates.Len() > 0 {
+		// Evaluate all templates against the same data, so that the
+		// order in which they are applied doesn't matter.
+		data := templateData(bag)
+		for name, tmpl := range t.templates.All() {
+			value, err := execute(tmpl, data)
+			if err != nil {
+				return false, err
+			}
+			set(bag, name, value)
+		}
+	}
+	for name, fn := range t.casts.All() {
+		entry, ok := bag.Mapped.Get(name)
+		if !ok || !entry.Valid || entry.Value == nil {
+			continue
+		}
+		next, err := fn(entry.Value)
+		if err != nil {
+			return false, errors.Wrapf(err, "column %s", name)
+		}
+		entry.Value = next
+	}
+	if len(t.filters) > 0 {
+		data := templateData(bag)
+		for _, filter := range t.filters {
+			out, err := execute(filter, data)
+			if err != nil {
+				return false, err
+			}
+			keep, err := strconv.ParseBool(strings.TrimSpace(out))
+			if err != nil {
+				return false, errors.Wrapf(err, "filter %q must produce a boolean", filter.Root)
+			}
+			if !keep {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// coalesce returns the first non-nil, non-empty argument.
+func coalesce(values ...any) any {
+	for _, value := range values {
+		if value != nil && value != "" {
+			return value
+		}
+	}
+	return nil
+}
+
+// execute evaluates the template and returns its output.
+func execute(tmpl *template.Template, data map[string]any) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return sb.String(), nil
+}
+
+// hashFn returns a template function that returns the hex-encoded hash
+// of the string form of its argument.
+func hashFn(hash func([]byte) []byte) func(any) (string, error) {
+	return func(value any) (string, error) {
+		s, err := toString(value)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash([]byte(s))), nil
+	}
+}
+
+func md5Sum(b []byte) []byte {
+	sum := md5.Sum(b)
+	return sum[:]
+}
+
+// parse compiles the text into a template which will produce an error
+// if an unknown column is referenced.
+func parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	return tmpl, errors.Wrapf(err, "could not parse template %q", text)
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// replace is a loosely-typed version of strings.ReplaceAll.
+func replace(value any, old, replacement string) (string, error) {
+	s, err := toString(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(s, old, replacement), nil
+}
+
+// set assigns a value to a mapped column in the bag.
+func set(bag *merge.Bag, name ident.Ident, value any) {
+	if entry, ok := bag.Mapped.Get(name); ok {
+		entry.Valid = true
+		entry.Value = value
+	}
+}
+
+// stringFn adapts a string function to accept loosely-typed values.
+func stringFn(fn func(string) string) func(any) (string, error) {
+	return func(value any) (string, error) {
+		s, err := toString(value)
+		if err != nil {
+			return "", err
+		}
+		return fn(s), nil
+	}
+}
+
+// templateData returns the contents of the bag, using the raw column
+// names as keys. Mapped columns that are absent from the row are
+// present as nil values, so that only references to unknown columns
+// will produce an error.
+func templateData(bag *merge.Bag) map[string]any {
+	ret := make(map[string]any, bag.Mapped.Len()+bag.Unmapped.Len())
+	for k, entry := range bag.Mapped.All() {
+		ret[k.Raw()] = entry.Value
+	}
+	for k, v := range bag.Unmapped.All() {
+		ret[k.Raw()] = v
+	}
+	return ret
+}
+
+// toString returns the string form of a value, treating nil as an
+// empty string.
+func toString(value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	s, err := castString(value)
+	if err != nil {
+		return "", err
+	}
+	return s.(string), nil
+}
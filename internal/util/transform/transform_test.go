@@ -0,0 +1,218 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/stretchr/testify/require"
+)
+
+var testCols = []types.ColData{
+	{Name: ident.New("pk"), Primary: true, Type: "INT8"},
+	{Name: ident.New("qty"), Type: "INT8"},
+	{Name: ident.New("first"), Type: "STRING"},
+	{Name: ident.New("last"), Type: "STRING"},
+	{Name: ident.New("full_name"), Type: "STRING"},
+	{Name: ident.New("hash"), Type: "STRING"},
+	{Name: ident.New("region"), Type: "STRING"},
+	{Name: ident.New("status"), Type: "STRING"},
+	{Name: ident.New("updated_at"), Type: "TIMESTAMPTZ"},
+}
+
+func TestCompile(t *testing.T) {
+	r := require.New(t)
+
+	empty, err := Compile(applycfg.NewConfig(), testCols)
+	r.NoError(err)
+	r.Nil(empty)
+
+	tcs := []struct {
+		name string
+		cfg  func(cfg *applycfg.Config)
+		err  string
+	}{
+		{
+			name: "unknown cast",
+			cfg:  func(cfg *applycfg.Config) { cfg.Casts.Put(ident.New("qty"), "decimal") },
+			err:  `unknown cast "decimal"`,
+		},
+		{
+			name: "incompatible cast",
+			cfg:  func(cfg *applycfg.Config) { cfg.Casts.Put(ident.New("updated_at"), CastInt) },
+			err:  "cannot cast column",
+		},
+		{
+			name: "missing cast column",
+			cfg:  func(cfg *applycfg.Config) { cfg.Casts.Put(ident.New("nope"), CastInt) },
+			err:  "cast column",
+		},
+		{
+			name: "missing constant column",
+			cfg:  func(cfg *applycfg.Config) { cfg.Constants.Put(ident.New("nope"), 1) },
+			err:  "constant column",
+		},
+		{
+			name: "missing default column",
+			cfg:  func(cfg *applycfg.Config) { cfg.Defaults.Put(ident.New("nope"), 1) },
+			err:  "default column",
+		},
+		{
+			name: "missing template column",
+			cfg:  func(cfg *applycfg.Config) { cfg.Templates.Put(ident.New("nope"), "x") },
+			err:  "template column",
+		},
+		{
+			name: "primary key cast",
+			cfg:  func(cfg *applycfg.Config) { cfg.Casts.Put(ident.New("pk"), CastInt) },
+			err:  `cast column "pk" is part of the primary key`,
+		},
+		{
+			name: "primary key constant",
+			cfg:  func(cfg *applycfg.Config) { cfg.Constants.Put(ident.New("pk"), 1) },
+			err:  `constant column "pk" is part of the primary key`,
+		},
+		{
+			name: "primary key default",
+			cfg:  func(cfg *applycfg.Config) { cfg.Defaults.Put(ident.New("pk"), 1) },
+			err:  `default column "pk" is part of the primary key`,
+		},
+		{
+			name: "primary key template",
+			cfg:  func(cfg *applycfg.Config) { cfg.Templates.Put(ident.New("pk"), "{{ .hash }}") },
+			err:  `template column "pk" is part of the primary key`,
+		},
+		{
+			name: "bad template",
+			cfg:  func(cfg *applycfg.Config) { cfg.Templates.Put(ident.New("hash"), "{{ .pk ") },
+			err:  "could not parse template",
+		},
+		{
+			name: "bad filter",
+			cfg:  func(cfg *applycfg.Config) { cfg.Filters = []string{"{{ unknownFn }}"} },
+			err:  "could not parse template",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := applycfg.NewConfig()
+			tc.cfg(cfg)
+			_, err := Compile(cfg, testCols)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	r := require.New(t)
+
+	cfg := applycfg.NewConfig()
+	cfg.Casts.Put(ident.New("qty"), CastInt)
+	cfg.Casts.Put(ident.New("updated_at"), CastTimestamp)
+	cfg.Constants.Put(ident.New("region"), "us-east")
+	cfg.Defaults.Put(ident.New("status"), "new")
+	cfg.Filters = []string{`{{ ne .status "test" }}`, `{{ gt .qty 0 }}`}
+	cfg.Templates.Put(ident.New("full_name"), `{{ .first }} {{ upper .last }}`)
+	cfg.Templates.Put(ident.New("hash"), `{{ sha256 .pk }}`)
+
+	tx, err := Compile(cfg, testCols)
+	r.NoError(err)
+	r.NotNil(tx)
+
+	bag := merge.NewBagOf(testCols, nil,
+		"pk", json.Number("1"),
+		"qty", "5",
+		"first", "Jane",
+		"last", "Doe",
+		"region", "ignored",
+		"updated_at", json.Number("1700000000.5"),
+	)
+	keep, err := tx.Apply(bag)
+	r.NoError(err)
+	r.True(keep)
+	r.Equal(int64(5), bag.GetZero(ident.New("qty")))
+	r.Equal("Jane DOE", bag.GetZero(ident.New("full_name")))
+	r.Equal("6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+		bag.GetZero(ident.New("hash")))
+	r.Equal("us-east", bag.GetZero(ident.New("region")))
+	r.Equal("new", bag.GetZero(ident.New("status")))
+	r.Equal(time.Unix(1700000000, int64(500*time.Millisecond)).UTC(),
+		bag.GetZero(ident.New("updated_at")))
+
+	// Rows which don't pass all filters are discarded.
+	bag = merge.NewBagOf(testCols, nil, "pk", 2, "qty", 1, "status", "test")
+	keep, err = tx.Apply(bag)
+	r.NoError(err)
+	r.False(keep)
+
+	bag = merge.NewBagOf(testCols, nil, "pk", 3, "qty", 0)
+	keep, err = tx.Apply(bag)
+	r.NoError(err)
+	r.False(keep)
+
+	// Values that can't be cast produce an error.
+	bag = merge.NewBagOf(testCols, nil, "pk", 4, "qty", "many")
+	_, err = tx.Apply(bag)
+	r.ErrorContains(err, `column "qty"`)
+}
+
+func TestCasts(t *testing.T) {
+	tcs := []struct {
+		kind     string
+		input    any
+		expected any
+		err      bool
+	}{
+		{CastBool, "true", true, false},
+		{CastBool, json.Number("0"), false, false},
+		{CastBool, "maybe", nil, true},
+		{CastFloat, "1.5", 1.5, false},
+		{CastFloat, json.Number("2"), float64(2), false},
+		{CastFloat, true, float64(1), false},
+		{CastInt, json.Number("1e3"), int64(1000), false},
+		{CastInt, " 42 ", int64(42), false},
+		{CastInt, 1.5, nil, true},
+		{CastJSON, `{"a":1}`, map[string]any{"a": json.Number("1")}, false},
+		{CastJSON, 1, 1, false},
+		{CastJSON, "{", nil, true},
+		{CastString, json.Number("1.50"), "1.50", false},
+		{CastString, true, "true", false},
+		{CastString, map[string]any{"a": "<b>"}, `{"a":"<b>"}`, false},
+		{CastTimestamp, "2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{CastTimestamp, "2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{CastTimestamp, 0, time.Unix(0, 0).UTC(), false},
+		{CastTimestamp, "yesterday", nil, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.kind, func(t *testing.T) {
+			r := require.New(t)
+			out, err := casters[tc.kind](tc.input)
+			if tc.err {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, out)
+		})
+	}
+}
@@ -29,6 +29,10 @@ import (
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/cmd/checkpoint"
+	"github.com/cockroachdb/replicator/internal/cmd/ddl"
+	"github.com/cockroachdb/replicator/internal/cmd/delay"
+	"github.com/cockroachdb/replicator/internal/cmd/dlq"
 	"github.com/cockroachdb/replicator/internal/cmd/dumphelp"
 	"github.com/cockroachdb/replicator/internal/cmd/dumptemplates"
 	"github.com/cockroachdb/replicator/internal/cmd/kafka"
@@ -38,6 +42,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/cmd/objstore"
 	"github.com/cockroachdb/replicator/internal/cmd/pglogical"
 	"github.com/cockroachdb/replicator/internal/cmd/preflight"
+	"github.com/cockroachdb/replicator/internal/cmd/stagekeys"
 	"github.com/cockroachdb/replicator/internal/cmd/start"
 	"github.com/cockroachdb/replicator/internal/cmd/version"
 	"github.com/cockroachdb/replicator/internal/cmd/workload"
@@ -108,6 +113,10 @@ func main() {
 	f.CountVarP(&verbosity, "verbose", "v", "increase logging verbosity to debug; repeat for trace")
 
 	root.AddCommand(
+		checkpoint.Command(),
+		ddl.Command(),
+		delay.Command(),
+		dlq.Command(),
 		dumphelp.Command(),
 		dumptemplates.Command(),
 		kafka.Command(),
@@ -118,6 +127,7 @@ func main() {
 		pglogical.Command(),
 		preflight.Command(),
 		script.HelpCommand(),
+		stagekeys.Command(),
 		start.Command(),
 		workload.Command(),
 		version.Command(),
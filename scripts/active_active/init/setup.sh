@@ -46,7 +46,12 @@ dlq_name TEXT NOT NULL,
 source_nanos INT8 NOT NULL,
 source_logical INT8 NOT NULL,
 data_after JSONB NOT NULL,
-data_before JSONB NOT NULL
+data_before JSONB NOT NULL,
+data_key JSONB,
+target_table TEXT,
+apply_error TEXT,
+replay_status TEXT,
+replay_error TEXT
 );
 !
 